@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/bridge"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/health"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/metrics"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/netsetup"
+	"github.com/spf13/cobra"
+)
+
+// runBridge runs in bridge mode, linking the two configured IPSC systems
+// directly instead of connecting to MMDVM masters.
+func runBridge(cmd *cobra.Command, cfg *config.Config, m *metrics.Metrics, metricsSrv *http.Server) error {
+	br, err := bridge.New(&cfg.Bridge, m)
+	if err != nil {
+		return fmt.Errorf("failed to create bridge: %w", err)
+	}
+	if cfg.LogLevel == config.LogLevelDebug {
+		br.SetUnknownBurstHandler(dumpUnknownBurst)
+	}
+
+	err = br.Start()
+	if err != nil {
+		if errors.Is(err, netsetup.ErrInsufficientPrivileges) {
+			// The error already explains how to fix it; usage output would only bury it.
+			cmd.SilenceUsage = true
+		}
+		return fmt.Errorf("failed to start bridge: %w", err)
+	}
+
+	var healthSrv *http.Server
+	if cfg.Health.Enabled {
+		checker := health.NewChecker(ipscHealth("ipsc/a", br.A()), ipscHealth("ipsc/b", br.B()))
+		healthSrv = startHealthServer(cfg.Health.Address, checker)
+	}
+
+	return waitForShutdown(cfg, func(reason string) {
+		slog.Info("shutting down...", "reason", reason)
+
+		if metricsSrv != nil {
+			if err := metricsSrv.Shutdown(context.Background()); err != nil {
+				slog.Error("Error shutting down metrics server", "error", err)
+			}
+		}
+		if healthSrv != nil {
+			if err := healthSrv.Shutdown(context.Background()); err != nil {
+				slog.Error("Error shutting down health server", "error", err)
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), ipscShutdownTimeout)
+		defer cancel()
+		if err := br.Shutdown(ctx); err != nil {
+			slog.Error("Error shutting down bridge", "error", err)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/USA-RedDragon/configulator"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/spf13/cobra"
+)
+
+type ipscSectionKey struct{}
+
+// WithIPSCSection returns a copy of ctx carrying the configulator of the
+// ipsc section, which is loaded along with the rest of the config.
+func WithIPSCSection(ctx context.Context, c *configulator.Configulator[config.IPSCSection]) context.Context {
+	return context.WithValue(ctx, ipscSectionKey{}, c)
+}
+
+// loadConfig loads and validates the config. The ipsc section is read from
+// the config file named by the root command's config flag.
+func loadConfig(cmd *cobra.Command) (*config.Config, error) {
+	ctx := cmd.Context()
+	c, err := configulator.FromContext[config.Config](ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config from context")
+	}
+	ipsc, ok := ctx.Value(ipscSectionKey{}).(*configulator.Configulator[config.IPSCSection])
+	if !ok {
+		return nil, fmt.Errorf("failed to get ipsc config from context")
+	}
+
+	cfg, err := c.LoadWithoutValidation()
+	if err != nil {
+		return nil, err
+	}
+	var path string
+	if f := cmd.Root().Flags().Lookup(configulator.ConfigFileKey); f != nil {
+		path = f.Value.String()
+	}
+	if err := cfg.LoadIPSC(path, ipsc); err != nil {
+		return nil, fmt.Errorf("failed to load ipsc section: %w", err)
+	}
+	return cfg, cfg.Validate()
+}
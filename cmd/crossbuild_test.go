@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestCrossBuild checks that the whole module, including the platform
+// fallbacks, compiles for every platform releases are built for.
+func TestCrossBuild(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.Skip("cross-compiling is slow")
+	}
+	goTool := filepath.Join(runtime.GOROOT(), "bin", "go")
+	targets := []struct{ goos, goarch string }{
+		{"linux", "amd64"},
+		{"linux", "arm64"},
+		{"windows", "amd64"},
+		{"darwin", "arm64"},
+	}
+	for _, target := range targets {
+		t.Run(target.goos+"/"+target.goarch, func(t *testing.T) {
+			t.Parallel()
+			build := exec.Command(goTool, "vet", "github.com/USA-RedDragon/ipsc2mmdvm/...")
+			build.Env = append(os.Environ(), "GOOS="+target.goos, "GOARCH="+target.goarch, "CGO_ENABLED=0")
+			if out, err := build.CombinedOutput(); err != nil {
+				t.Fatalf("go vet for %s/%s failed: %v\n%s", target.goos, target.goarch, err, out)
+			}
+		})
+	}
+}
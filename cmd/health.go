@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/health"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/ipsc"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm"
+)
+
+// ipscHealth reports an IPSC server as down unless its socket is bound and
+// its receive loop is running.
+func ipscHealth(name string, server *ipsc.IPSCServer) health.Component {
+	return func() health.Check {
+		if !server.Running() {
+			return health.Check{Name: name, Status: health.StatusDown, Detail: "receive loop not running"}
+		}
+		return health.Check{Name: name, Status: health.StatusOK, Detail: fmt.Sprintf("listening on %s", server.Addr())}
+	}
+}
+
+// mmdvmHealth reports an MMDVM client as ok once logged in, degraded while
+// it is (re)connecting, and down if it was never started or gave up.
+func mmdvmHealth(client *mmdvm.MMDVMClient) health.Component {
+	name := "mmdvm/" + client.Name()
+	return func() health.Check {
+		stats := client.Stats()
+		switch stats.State {
+		case mmdvm.STATE_READY:
+			return health.Check{Name: name, Status: health.StatusOK, Detail: fmt.Sprintf("logged in to %s", stats.ActiveMaster)}
+		case mmdvm.STATE_IDLE:
+			return health.Check{Name: name, Status: health.StatusDown, Detail: "not started"}
+		case mmdvm.STATE_AUTH_FAILED:
+			return health.Check{Name: name, Status: health.StatusDown, Detail: fmt.Sprintf("gave up: %v", client.LastFailure())}
+		case mmdvm.STATE_CLOSED:
+			return health.Check{Name: name, Status: health.StatusDown, Detail: "closed"}
+		default:
+			return health.Check{Name: name, Status: health.StatusDegraded, Detail: fmt.Sprintf("%s with %s", stats.State, stats.ActiveMaster)}
+		}
+	}
+}
+
+// startHealthServer serves the checker's endpoints on address.
+func startHealthServer(address string, checker *health.Checker) *http.Server {
+	srv := &http.Server{
+		Addr:              address,
+		Handler:           checker.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		slog.Info("Starting health server", "address", address)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("Health server error", "error", err)
+		}
+	}()
+	return srv
+}
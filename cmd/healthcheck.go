@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/health"
+	"github.com/spf13/cobra"
+)
+
+const defaultHealthAddress = "127.0.0.1:9101"
+
+func newHealthcheckCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "healthcheck",
+		Short: "Probe the local health endpoint and exit non-zero if unhealthy",
+		Long: "Requests /healthz (or /readyz with --ready) from a running instance and exits 0\n" +
+			"if it answers 200. A degraded instance passes the liveness probe with a warning\n" +
+			"but fails the readiness probe. Suitable for a container HEALTHCHECK.",
+		RunE:          runHealthcheck,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	cmd.Flags().String("address", "", "Health endpoint host:port (defaults to health.address from the config)")
+	cmd.Flags().Bool("ready", false, "Probe readiness (/readyz) instead of liveness (/healthz)")
+	cmd.Flags().Duration("timeout", 5*time.Second, "Maximum time to wait for a response")
+	return cmd
+}
+
+func runHealthcheck(cmd *cobra.Command, _ []string) error {
+	address, err := cmd.Flags().GetString("address")
+	if err != nil {
+		return fmt.Errorf("failed to read address flag: %w", err)
+	}
+	ready, err := cmd.Flags().GetBool("ready")
+	if err != nil {
+		return fmt.Errorf("failed to read ready flag: %w", err)
+	}
+	timeout, err := cmd.Flags().GetDuration("timeout")
+	if err != nil {
+		return fmt.Errorf("failed to read timeout flag: %w", err)
+	}
+
+	if address == "" {
+		address = configuredHealthAddress(cmd)
+	}
+
+	path := "/healthz"
+	if ready {
+		path = "/readyz"
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+	defer cancel()
+
+	report, err := health.Probe(ctx, "http://"+probeAddress(address)+path)
+	if err != nil {
+		if errors.Is(err, health.ErrUnhealthy) {
+			printHealthReport(cmd, report)
+		}
+		return err
+	}
+	printHealthReport(cmd, report)
+	return nil
+}
+
+// configuredHealthAddress returns health.address from the config, or the
+// default if the config cannot be loaded.
+func configuredHealthAddress(cmd *cobra.Command) string {
+	cfg, err := loadConfig(cmd)
+	if err != nil || cfg.Health.Address == "" {
+		return defaultHealthAddress
+	}
+	return cfg.Health.Address
+}
+
+// probeAddress turns a listen address into one that can be dialed
+// locally, replacing an unspecified host with loopback.
+func probeAddress(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	if host == "" || net.ParseIP(host).IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+func printHealthReport(cmd *cobra.Command, report health.Report) {
+	out := cmd.OutOrStdout()
+	fmt.Fprintln(out, report.Status)
+	for _, check := range report.Checks {
+		fmt.Fprintf(out, "  %s: %s %s\n", check.Name, check.Status, check.Detail)
+	}
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/health"
+)
+
+func TestHealthcheckCommand(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		status     health.Status
+		ready      bool
+		wantFailed bool
+	}{
+		{"liveness ok", health.StatusOK, false, false},
+		{"liveness degraded", health.StatusDegraded, false, false},
+		{"liveness down", health.StatusDown, false, true},
+		{"readiness ok", health.StatusOK, true, false},
+		{"readiness degraded", health.StatusDegraded, true, true},
+		{"readiness down", health.StatusDown, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			checker := health.NewChecker(func() health.Check {
+				return health.Check{Name: "mmdvm/test", Status: tt.status}
+			})
+			srv := httptest.NewServer(checker.Handler())
+			defer srv.Close()
+
+			cmd := newHealthcheckCommand()
+			var out bytes.Buffer
+			cmd.SetOut(&out)
+			args := []string{"--address", strings.TrimPrefix(srv.URL, "http://")}
+			if tt.ready {
+				args = append(args, "--ready")
+			}
+			cmd.SetArgs(args)
+
+			err := cmd.ExecuteContext(context.Background())
+			if tt.wantFailed && !errors.Is(err, health.ErrUnhealthy) {
+				t.Fatalf("expected %v, got %v", health.ErrUnhealthy, err)
+			}
+			if !tt.wantFailed && err != nil {
+				t.Fatalf("expected success, got %v", err)
+			}
+			if !strings.HasPrefix(out.String(), string(tt.status)) {
+				t.Fatalf("expected report to start with %q, got %q", tt.status, out.String())
+			}
+		})
+	}
+}
+
+func TestHealthcheckUnreachable(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(nil)
+	address := strings.TrimPrefix(srv.URL, "http://")
+	srv.Close()
+
+	cmd := newHealthcheckCommand()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--address", address})
+	err := cmd.ExecuteContext(context.Background())
+	if err == nil || errors.Is(err, health.ErrUnhealthy) {
+		t.Fatalf("expected a connection error, got %v", err)
+	}
+}
+
+func TestProbeAddress(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		in, want string
+	}{
+		{":9101", "127.0.0.1:9101"},
+		{"0.0.0.0:9101", "127.0.0.1:9101"},
+		{"[::]:9101", "127.0.0.1:9101"},
+		{"10.0.0.5:9101", "10.0.0.5:9101"},
+		{"localhost:9101", "localhost:9101"},
+	}
+	for _, tt := range tests {
+		if got := probeAddress(tt.in); got != tt.want {
+			t.Errorf("probeAddress(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
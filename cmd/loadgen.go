@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/loadgen"
+	"github.com/lmittmann/tint"
+	"github.com/spf13/cobra"
+)
+
+func newLoadgenCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "loadgen",
+		Short: "Generate synthetic IPSC calls and report delivery, loss, and latency",
+		Long: "Registers fake IPSC peers and places concurrent synthetic group calls through\n" +
+			"the bridge to a fake MMDVM master, then reports how many frames arrived and how\n" +
+			"long they took. By default the bridge is started in-process. To load a running\n" +
+			"instance, pass its IPSC address with --target and point its MMDVM network at\n" +
+			"--master-address.",
+		RunE:          runLoadgen,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	cmd.Flags().Int("peers", 1, "Number of fake IPSC peers to register")
+	cmd.Flags().Int("calls", 1, "Number of concurrent calls")
+	cmd.Flags().Int("rounds", 1, "Number of calls each concurrent caller places back to back")
+	cmd.Flags().Duration("duration", 3*time.Second, "Air time of each call")
+	cmd.Flags().Duration("gap", time.Second, "Pause between a caller's consecutive calls")
+	cmd.Flags().String("target", "", "IPSC address of a running instance (default: start one in-process)")
+	cmd.Flags().String("master-address", "", "Address for the fake MMDVM master to listen on; required with --target")
+	cmd.Flags().String("profile", "", "Directory to write CPU and heap profiles of the run to")
+	cmd.Flags().Bool("verbose", false, "Show debug logs from the bridge components")
+	cmd.Flags().Duration("timeout", 10*time.Minute, "Maximum time the run may take")
+	return cmd
+}
+
+func runLoadgen(cmd *cobra.Command, _ []string) error {
+	var opts loadgen.Options
+	var err error
+	flags := cmd.Flags()
+	if opts.Peers, err = flags.GetInt("peers"); err != nil {
+		return fmt.Errorf("failed to read peers flag: %w", err)
+	}
+	if opts.Calls, err = flags.GetInt("calls"); err != nil {
+		return fmt.Errorf("failed to read calls flag: %w", err)
+	}
+	if opts.Rounds, err = flags.GetInt("rounds"); err != nil {
+		return fmt.Errorf("failed to read rounds flag: %w", err)
+	}
+	if opts.CallDuration, err = flags.GetDuration("duration"); err != nil {
+		return fmt.Errorf("failed to read duration flag: %w", err)
+	}
+	if opts.Gap, err = flags.GetDuration("gap"); err != nil {
+		return fmt.Errorf("failed to read gap flag: %w", err)
+	}
+	if opts.Target, err = flags.GetString("target"); err != nil {
+		return fmt.Errorf("failed to read target flag: %w", err)
+	}
+	if opts.MasterAddress, err = flags.GetString("master-address"); err != nil {
+		return fmt.Errorf("failed to read master-address flag: %w", err)
+	}
+	profileDir, err := flags.GetString("profile")
+	if err != nil {
+		return fmt.Errorf("failed to read profile flag: %w", err)
+	}
+	verbose, err := flags.GetBool("verbose")
+	if err != nil {
+		return fmt.Errorf("failed to read verbose flag: %w", err)
+	}
+	timeout, err := flags.GetDuration("timeout")
+	if err != nil {
+		return fmt.Errorf("failed to read timeout flag: %w", err)
+	}
+
+	level := slog.LevelWarn
+	if verbose {
+		level = slog.LevelDebug
+	}
+	slog.SetDefault(slog.New(tint.NewHandler(os.Stderr, &tint.Options{Level: level})))
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+	defer cancel()
+
+	var report *loadgen.Report
+	run := func() error {
+		report, err = loadgen.Run(ctx, opts)
+		return err
+	}
+	if profileDir != "" {
+		err = profile(profileDir, run)
+	} else {
+		err = run()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run load generator: %w", err)
+	}
+	if _, err := report.WriteTo(cmd.OutOrStdout()); err != nil {
+		return fmt.Errorf("failed to write load report: %w", err)
+	}
+	return nil
+}
+
+// profile runs fn under the CPU profiler and writes cpu.pprof and, once
+// fn returns, heap.pprof to dir.
+func profile(dir string, fn func() error) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	cpu, err := os.Create(filepath.Join(dir, "cpu.pprof"))
+	if err != nil {
+		return fmt.Errorf("failed to create CPU profile: %w", err)
+	}
+	defer cpu.Close()
+	if err := pprof.StartCPUProfile(cpu); err != nil {
+		return fmt.Errorf("failed to start CPU profile: %w", err)
+	}
+	runErr := fn()
+	pprof.StopCPUProfile()
+
+	heap, err := os.Create(filepath.Join(dir, "heap.pprof"))
+	if err != nil {
+		return fmt.Errorf("failed to create heap profile: %w", err)
+	}
+	defer heap.Close()
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(heap); err != nil {
+		return fmt.Errorf("failed to write heap profile: %w", err)
+	}
+	return runErr
+}
@@ -2,22 +2,30 @@ package cmd
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log/slog"
-	"net"
 	"net/http"
 	"os"
-	"syscall"
+	"slices"
+	"sync"
 
-	"github.com/USA-RedDragon/configulator"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/capture"
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/health"
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/ipsc"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/loopdetect"
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/metrics"
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/netsetup"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/parrot"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/specialid"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/supervisor"
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/timeslot"
 	"github.com/lmittmann/tint"
 	"github.com/spf13/cobra"
-	"github.com/ztrue/shutdown"
 )
 
 func NewCommand(version, commit string) *cobra.Command {
@@ -32,19 +40,16 @@ func NewCommand(version, commit string) *cobra.Command {
 		SilenceErrors:     true,
 		DisableAutoGenTag: true,
 	}
+	cmd.AddCommand(newSelfTestCommand())
+	cmd.AddCommand(newHealthcheckCommand())
+	cmd.AddCommand(newLoadgenCommand())
 	return cmd
 }
 
 func runRoot(cmd *cobra.Command, _ []string) error {
-	ctx := cmd.Context()
 	fmt.Printf("ipsc2mmdvm - %s (%s)\n", cmd.Annotations["version"], cmd.Annotations["commit"])
 
-	c, err := configulator.FromContext[config.Config](ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get config from context")
-	}
-
-	cfg, err := c.Load()
+	cfg, err := loadConfig(cmd)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
@@ -81,72 +86,243 @@ func runRoot(cmd *cobra.Command, _ []string) error {
 		}()
 	}
 
+	if cfg.Bridge.Enabled {
+		return runBridge(cmd, cfg, m, metricsSrv)
+	}
+
 	// Create one MMDVM client per configured network (DMR master).
 	// All clients share a single outbound timeslot manager so that
 	// only one master can feed a given timeslot toward IPSC at a time.
 	outboundTSMgr := timeslot.NewManager()
+	outboundTSMgr.SetBusyPolicyFromConfig(cfg.IPSC)
 	if m != nil {
 		outboundTSMgr.SetMetrics(m, "outbound")
 	}
+	// The clients also share a loop detector, which needs to see calls
+	// in both directions across every network.
+	loops := loopdetect.New(loopdetect.DefaultWindow)
+	if m != nil {
+		loops.SetMetrics(m)
+	}
+	// Each component runs in its own failure domain: a panic restarts
+	// only that component, until it exhausts its restart budget.
+	sup := supervisor.New(supervisor.Options{
+		MaxRestarts: int(cfg.Supervisor.MaxRestarts), //nolint:gosec // Small config value
+	})
 	mmdvmClients := make([]*mmdvm.MMDVMClient, 0, len(cfg.MMDVM))
 	for i := range cfg.MMDVM {
-		client := mmdvm.NewMMDVMClient(&cfg.MMDVM[i], m)
-		client.SetOutboundTSManager(outboundTSMgr)
-		err = client.Start()
-		if err != nil {
-			return fmt.Errorf("failed to start MMDVM client %q: %w", cfg.MMDVM[i].Name, err)
+		opts := mmdvm.TranslatorOptionsFromConfig(cfg.IPSC)
+		if cfg.LogLevel == config.LogLevelDebug {
+			opts.UnknownBurstHandler = dumpUnknownBurst
 		}
+		client := mmdvm.NewMMDVMClient(&cfg.MMDVM[i], m, opts)
+		client.SetOutboundTSManager(outboundTSMgr)
+		client.SetLoopDetector(loops)
+		client.SetStateHandler(logAvailability(client))
+		sup.Add("mmdvm/"+cfg.MMDVM[i].Name, client)
 		mmdvmClients = append(mmdvmClients, client)
 	}
 
-	ipscServer := ipsc.NewIPSCServer(cfg, m)
+	// Calls to special IDs are dealt with ahead of the rewrite rules on
+	// both sides: from the repeater ahead of the router, and from each
+	// master ahead of IPSC.
+	specialIDs := specialid.New(cfg)
+	if cfg.Parrot.Enabled {
+		echo := parrot.New(cfg.Parrot)
+		specialIDs.Register(config.SpecialIDHandlerParrot, func(side string, pkt proto.Packet, send func(proto.Packet) bool) bool {
+			return echo.Handle(side, pkt, send)
+		})
+	}
+	for _, client := range mmdvmClients {
+		client.SetSpecialIDs(specialIDs)
+	}
 
-	ipscServer.SetBurstHandler(func(packetType byte, data []byte, addr *net.UDPAddr) {
-		for _, client := range mmdvmClients {
-			if client.MatchesRules(packetType, data, false) {
-				dataCopy := make([]byte, len(data))
-				copy(dataCopy, data)
-				client.HandleIPSCBurst(packetType, dataCopy, addr)
-				return
+	// Each IPSC server exchanges calls with the MMDVM networks routed to
+	// it. The first is the one configured in ipsc.
+	var ipscServers []*ipsc.IPSCServer
+	var packetCaptures []*capture.Writer
+	closeCaptures := func() {
+		for _, packetCapture := range packetCaptures {
+			if err := packetCapture.Close(); err != nil {
+				slog.Error("Error closing IPSC capture", "error", err)
 			}
 		}
-		for _, client := range mmdvmClients {
-			if client.MatchesRules(packetType, data, true) {
-				dataCopy := make([]byte, len(data))
-				copy(dataCopy, data)
-				client.HandleIPSCBurst(packetType, dataCopy, addr)
-				return
+	}
+	toIPSC := make(map[*mmdvm.MMDVMClient][]*ipsc.IPSCServer, len(mmdvmClients))
+	for _, instance := range cfg.IPSCConfigs() {
+		server := ipsc.NewIPSCServer(instance, m)
+		if instance.IPSC.Capture.Path != "" {
+			packetCapture, err := capture.New(instance.IPSC.Capture)
+			if err != nil {
+				closeCaptures()
+				return fmt.Errorf("failed to start IPSC capture: %w", err)
 			}
+			server.SetCapture(packetCapture)
+			packetCaptures = append(packetCaptures, packetCapture)
 		}
-	})
 
-	// Wire all MMDVM clients' inbound data to the IPSC server.
+		routed := routedClients(cfg, instance.IPSC.Networks, mmdvmClients)
+		router := mmdvm.NewDefaultBurstRouter(routed, defaultClient(instance.IPSC.DefaultNetwork, routed))
+		burstHandler, err := mmdvm.NewSpecialIDBurstHandler(specialIDs, server, cfg.MMDVM[0].ID, instance.IPSC.RTP, router)
+		if err != nil {
+			closeCaptures()
+			return err
+		}
+		server.SetBurstHandler(burstHandler)
+		server.SetPeerLostHandler(mmdvm.NewPeerLostRouter(routed))
+		for _, client := range routed {
+			toIPSC[client] = append(toIPSC[client], server)
+		}
+		ipscServers = append(ipscServers, server)
+	}
+	restoreState(cfg, ipscServers, mmdvmClients)
+
+	// Wire each MMDVM client's inbound data to its IPSC servers.
 	for _, client := range mmdvmClients {
-		client.SetIPSCHandler(ipscServer.SendUserPacket)
+		client.SetIPSCHandler(sendToIPSC(toIPSC[client]))
 	}
 
-	err = ipscServer.Start()
+	for _, server := range ipscServers {
+		sup.Add(server.Name(), server)
+	}
+	err = sup.Start()
 	if err != nil {
-		return fmt.Errorf("failed to start IPSC server: %w", err)
+		if errors.Is(err, netsetup.ErrInsufficientPrivileges) {
+			// The error already explains how to fix it; usage output would only bury it.
+			cmd.SilenceUsage = true
+		}
+		return err
 	}
 
-	stop := func(sig os.Signal) {
-		slog.Info("received signal, shutting down...", "signal", sig.String())
+	var healthSrv *http.Server
+	if cfg.Health.Enabled {
+		components := make([]health.Component, 0, len(ipscServers)+len(mmdvmClients))
+		for _, server := range ipscServers {
+			components = append(components, ipscHealth(server.Name(), server))
+		}
+		for _, client := range mmdvmClients {
+			components = append(components, mmdvmHealth(client))
+		}
+		healthSrv = startHealthServer(cfg.Health.Address, health.NewChecker(components...))
+	}
+
+	stateDone := make(chan struct{})
+	go saveStatePeriodically(cfg, ipscServers, mmdvmClients, stateDone)
 
-		if metricsSrv != nil {
-			if err := metricsSrv.Shutdown(context.Background()); err != nil {
-				slog.Error("Error shutting down metrics server", "error", err)
+	var teardownOnce sync.Once
+	teardown := func() {
+		teardownOnce.Do(func() {
+			if metricsSrv != nil {
+				if err := metricsSrv.Shutdown(context.Background()); err != nil {
+					slog.Error("Error shutting down metrics server", "error", err)
+				}
 			}
+			if healthSrv != nil {
+				if err := healthSrv.Shutdown(context.Background()); err != nil {
+					slog.Error("Error shutting down health server", "error", err)
+				}
+			}
+
+			close(stateDone)
+			// Peers are told the servers are going away before the
+			// supervisor stops everything else.
+			ctx, cancel := context.WithTimeout(context.Background(), ipscShutdownTimeout)
+			for _, server := range ipscServers {
+				if err := server.Shutdown(ctx); err != nil {
+					slog.Error("Error shutting down IPSC server", "ipsc", server.Name(), "error", err)
+				}
+			}
+			cancel()
+			// Masters are logged out of before the clients stop, so
+			// they end the sessions at once.
+			ctx, cancel = context.WithTimeout(context.Background(), mmdvmCloseTimeout)
+			for _, client := range mmdvmClients {
+				if err := client.Close(ctx); err != nil {
+					slog.Error("Error closing MMDVM client", "network", client.Name(), "error", err)
+				}
+			}
+			cancel()
+			sup.Stop()
+			closeCaptures()
+			saveState(cfg, ipscServers, mmdvmClients)
+		})
+	}
+
+	// A component that keeps crashing takes the process down with a
+	// failure status so the service manager can intervene.
+	go func() {
+		err := <-sup.Fatal()
+		slog.Error("Component keeps crashing, shutting down", "error", err)
+		teardown()
+		os.Exit(1)
+	}()
+
+	return waitForShutdown(cfg, func(reason string) {
+		slog.Info("shutting down...", "reason", reason)
+		teardown()
+	})
+}
+
+// routedClients returns the clients of the MMDVM networks named in
+// networks, or every client when it is empty.
+func routedClients(cfg *config.Config, networks []string, clients []*mmdvm.MMDVMClient) []*mmdvm.MMDVMClient {
+	if len(networks) == 0 {
+		return clients
+	}
+	routed := make([]*mmdvm.MMDVMClient, 0, len(networks))
+	for i, client := range clients {
+		if slices.Contains(networks, cfg.MMDVM[i].Name) {
+			routed = append(routed, client)
 		}
+	}
+	return routed
+}
 
-		ipscServer.Stop()
-		for _, client := range mmdvmClients {
-			client.Stop()
+// defaultClient returns the client of the named network among clients,
+// or nil if name is empty.
+func defaultClient(name string, clients []*mmdvm.MMDVMClient) *mmdvm.MMDVMClient {
+	if name == "" {
+		return nil
+	}
+	for _, client := range clients {
+		if client.Name() == name {
+			return client
 		}
 	}
+	return nil
+}
 
-	shutdown.AddWithParam(stop)
-	shutdown.Listen(syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
+// sendToIPSC returns the handler sending an MMDVM client's inbound data to
+// each of servers.
+func sendToIPSC(servers []*ipsc.IPSCServer) func(data []byte) {
+	return func(data []byte) {
+		for _, server := range servers {
+			server.SendUserPacket(data)
+		}
+	}
+}
 
-	return nil
+// logAvailability returns a state handler logging when client stops or
+// resumes carrying calls between IPSC and its master, such as when the
+// master announces it is closing.
+func logAvailability(client *mmdvm.MMDVMClient) func(from, to mmdvm.State) {
+	return func(from, to mmdvm.State) {
+		switch {
+		case to == mmdvm.STATE_CLOSED:
+			// Shutting down, which is logged already.
+		case to == mmdvm.STATE_READY:
+			slog.Info("MMDVM network available, carrying calls", "network", client.Name(), "master", client.ActiveMaster())
+		case to == mmdvm.STATE_AUTH_FAILED:
+			slog.Error("MMDVM network unavailable until restarted", "network", client.Name(), "reason", client.LastFailure())
+		case from == mmdvm.STATE_READY:
+			slog.Warn("MMDVM network unavailable, calls are not carried", "network", client.Name(), "state", to)
+		}
+	}
+}
+
+// dumpUnknownBurst logs data, an IPSC packet of a burst type the
+// translator does not know, for study.
+func dumpUnknownBurst(data []byte) {
+	slog.Debug("Dropped IPSC packet of unknown burst type",
+		"burstType", fmt.Sprintf("0x%02X", data[30]), "packet", "\n"+hex.Dump(data))
 }
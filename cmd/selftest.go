@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/selftest"
+	"github.com/lmittmann/tint"
+	"github.com/spf13/cobra"
+)
+
+var errSelfTestFailed = errors.New("self-test failed")
+
+func newSelfTestCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "selftest",
+		Short: "Run an in-process loopback test of the IPSC and MMDVM paths",
+		Long: "Starts the IPSC server, translator, and MMDVM client against a fake IPSC peer\n" +
+			"and a fake MMDVM master on loopback, runs a call in each direction, and prints\n" +
+			"a pass/fail report. No configuration or network access is required.",
+		RunE:          runSelfTest,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	cmd.Flags().Bool("verbose", false, "Show debug logs from the bridge components")
+	cmd.Flags().Duration("timeout", 30*time.Second, "Maximum time to wait for the self-test to complete")
+	return cmd
+}
+
+func runSelfTest(cmd *cobra.Command, _ []string) error {
+	verbose, err := cmd.Flags().GetBool("verbose")
+	if err != nil {
+		return fmt.Errorf("failed to read verbose flag: %w", err)
+	}
+	timeout, err := cmd.Flags().GetDuration("timeout")
+	if err != nil {
+		return fmt.Errorf("failed to read timeout flag: %w", err)
+	}
+
+	level := slog.LevelWarn
+	if verbose {
+		level = slog.LevelDebug
+	}
+	slog.SetDefault(slog.New(tint.NewHandler(os.Stderr, &tint.Options{Level: level})))
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+	defer cancel()
+
+	report, err := selftest.Run(ctx, selftest.Options{})
+	if err != nil {
+		return fmt.Errorf("failed to run self-test: %w", err)
+	}
+	if _, err := report.WriteTo(cmd.OutOrStdout()); err != nil {
+		return fmt.Errorf("failed to write self-test report: %w", err)
+	}
+	if !report.Passed() {
+		return errSelfTestFailed
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/ztrue/shutdown"
+)
+
+// serviceName is the name ipsc2mmdvm is registered under with the Windows
+// service control manager.
+const serviceName = "ipsc2mmdvm"
+
+// ipscShutdownTimeout bounds how long shutting down waits for the IPSC
+// packets being handled.
+const ipscShutdownTimeout = 5 * time.Second
+
+// mmdvmCloseTimeout bounds how long shutting down waits to send the
+// traffic queued for the MMDVM masters before logging out.
+const mmdvmCloseTimeout = 2 * time.Second
+
+// waitForShutdown blocks until the process is asked to stop, by a signal
+// or, in service mode, by the service control manager, and then calls
+// stop with the reason.
+func waitForShutdown(cfg *config.Config, stop func(reason string)) error {
+	if cfg.Service {
+		return runService(stop)
+	}
+	shutdown.AddWithParam(func(sig os.Signal) {
+		stop(sig.String())
+	})
+	shutdown.Listen(syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+var errServiceUnsupported = errors.New("service mode is only supported on Windows")
+
+// runService reports that there is no service control manager to run
+// under. Use systemd or another supervisor on this platform.
+func runService(func(reason string)) error {
+	return fmt.Errorf("%w (%s)", errServiceUnsupported, runtime.GOOS)
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestServiceModeUnsupported(t *testing.T) {
+	t.Parallel()
+	if err := runService(func(string) { t.Fatal("stop must not be called") }); !errors.Is(err, errServiceUnsupported) {
+		t.Fatalf("expected %v, got %v", errServiceUnsupported, err)
+	}
+}
@@ -0,0 +1,45 @@
+//go:build windows
+
+package cmd
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+// serviceHandler maps service control requests onto the process
+// lifecycle: the bridge is already running when the dispatcher starts,
+// and a stop or system shutdown request tears it down.
+type serviceHandler struct {
+	stop func(reason string)
+}
+
+func (h *serviceHandler) Execute(_ []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for req := range requests {
+		switch req.Cmd {
+		case svc.Interrogate:
+			status <- req.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			status <- svc.Status{State: svc.StopPending}
+			reason := "service stop"
+			if req.Cmd == svc.Shutdown {
+				reason = "system shutdown"
+			}
+			h.stop(reason)
+			return false, 0
+		}
+	}
+	return false, 0
+}
+
+// runService hands control to the service control manager and returns
+// once the service has been stopped.
+func runService(stop func(reason string)) error {
+	if err := svc.Run(serviceName, &serviceHandler{stop: stop}); err != nil {
+		return fmt.Errorf("failed to run as a Windows service: %w", err)
+	}
+	return nil
+}
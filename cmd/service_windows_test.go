@@ -0,0 +1,53 @@
+//go:build windows
+
+package cmd
+
+import (
+	"testing"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+func TestServiceHandlerStops(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		cmd        svc.Cmd
+		wantReason string
+	}{
+		{svc.Stop, "service stop"},
+		{svc.Shutdown, "system shutdown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wantReason, func(t *testing.T) {
+			t.Parallel()
+			var reason string
+			h := &serviceHandler{stop: func(r string) { reason = r }}
+			requests := make(chan svc.ChangeRequest, 2)
+			status := make(chan svc.Status, 8)
+
+			requests <- svc.ChangeRequest{Cmd: svc.Interrogate, CurrentStatus: svc.Status{State: svc.Running}}
+			requests <- svc.ChangeRequest{Cmd: tt.cmd}
+			if _, code := h.Execute(nil, requests, status); code != 0 {
+				t.Fatalf("expected exit code 0, got %d", code)
+			}
+			if reason != tt.wantReason {
+				t.Fatalf("expected reason %q, got %q", tt.wantReason, reason)
+			}
+
+			close(status)
+			var states []svc.State
+			for s := range status {
+				states = append(states, s.State)
+			}
+			want := []svc.State{svc.StartPending, svc.Running, svc.Running, svc.StopPending}
+			if len(states) != len(want) {
+				t.Fatalf("expected states %v, got %v", want, states)
+			}
+			for i := range want {
+				if states[i] != want[i] {
+					t.Fatalf("expected states %v, got %v", want, states)
+				}
+			}
+		})
+	}
+}
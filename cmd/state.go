@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/ipsc"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/state"
+)
+
+// restoreState loads the state snapshot, if configured, and seeds each
+// IPSC server's peers and each client's call-control ring and calls from
+// IPSC from it. Missing, corrupt, or stale snapshots are skipped.
+func restoreState(cfg *config.Config, servers []*ipsc.IPSCServer, clients []*mmdvm.MMDVMClient) {
+	if cfg.State.Path == "" {
+		return
+	}
+
+	maxAge := time.Duration(cfg.State.MaxAge) * time.Second
+	snap, err := state.Load(cfg.State.Path, maxAge)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			slog.Info("No state snapshot to restore", "path", cfg.State.Path)
+			return
+		}
+		slog.Warn("Ignoring state snapshot", "path", cfg.State.Path, "error", err)
+		return
+	}
+
+	restored := 0
+	for i, server := range servers {
+		saved := snap.Peers
+		if i > 0 {
+			saved = snap.ServerPeers[server.Name()]
+		}
+		peers := make([]ipsc.Peer, 0, len(saved))
+		for _, p := range saved {
+			addr, err := net.ResolveUDPAddr("udp", p.Addr)
+			if err != nil {
+				slog.Warn("Ignoring peer with invalid address in state snapshot", "ipsc", server.Name(), "peerID", p.ID, "addr", p.Addr, "error", err)
+				continue
+			}
+			peers = append(peers, ipsc.Peer{ID: p.ID, Addr: addr, Mode: p.Mode, Flags: p.Flags})
+		}
+		server.RestorePeers(peers)
+		restored += len(peers)
+	}
+
+	for _, client := range clients {
+		if ids, ok := snap.CallControls[client.Name()]; ok {
+			client.SeedCallControls(ids)
+		}
+		streams := make([]ipsc.ReverseStream, 0, len(snap.Streams[client.Name()]))
+		for _, stream := range snap.Streams[client.Name()] {
+			streams = append(streams, ipsc.ReverseStream{
+				CallControl: stream.CallControl,
+				StreamID:    stream.StreamID,
+				Seq:         stream.Seq,
+				HeaderSent:  stream.HeaderSent,
+				PeerID:      stream.PeerID,
+				Src:         stream.Src,
+				Dst:         stream.Dst,
+				GroupCall:   stream.GroupCall,
+				Slot:        stream.Slot,
+			})
+		}
+		client.SeedReverseStreams(streams)
+	}
+
+	slog.Info("Restored state snapshot", "path", cfg.State.Path, "peers", restored, "savedAt", snap.SavedAt)
+}
+
+// saveState writes the current peers of each IPSC server, call-control
+// rings, and calls from IPSC to the configured snapshot path.
+func saveState(cfg *config.Config, servers []*ipsc.IPSCServer, clients []*mmdvm.MMDVMClient) {
+	if cfg.State.Path == "" {
+		return
+	}
+
+	snap := &state.Snapshot{
+		CallControls: make(map[string][]uint32, len(clients)),
+		Streams:      make(map[string][]state.Stream, len(clients)),
+	}
+	for i, server := range servers {
+		var peers []state.Peer
+		for _, peer := range server.Peers() {
+			if peer.Addr == nil {
+				continue
+			}
+			peers = append(peers, state.Peer{
+				ID:    peer.ID,
+				Addr:  peer.Addr.String(),
+				Mode:  peer.Mode,
+				Flags: peer.Flags,
+			})
+		}
+		if i == 0 {
+			snap.Peers = peers
+			continue
+		}
+		if snap.ServerPeers == nil {
+			snap.ServerPeers = make(map[string][]state.Peer, len(servers)-1)
+		}
+		snap.ServerPeers[server.Name()] = peers
+	}
+	for _, client := range clients {
+		snap.CallControls[client.Name()] = client.RecentCallControls()
+		for _, stream := range client.ReverseStreams() {
+			snap.Streams[client.Name()] = append(snap.Streams[client.Name()], state.Stream{
+				CallControl: stream.CallControl,
+				StreamID:    stream.StreamID,
+				Seq:         stream.Seq,
+				HeaderSent:  stream.HeaderSent,
+				PeerID:      stream.PeerID,
+				Src:         stream.Src,
+				Dst:         stream.Dst,
+				GroupCall:   stream.GroupCall,
+				Slot:        stream.Slot,
+			})
+		}
+	}
+
+	if err := state.Save(cfg.State.Path, snap); err != nil {
+		slog.Error("Error saving state snapshot", "path", cfg.State.Path, "error", err)
+	}
+}
+
+// saveStatePeriodically writes a snapshot every configured interval until
+// done is closed.
+func saveStatePeriodically(cfg *config.Config, servers []*ipsc.IPSCServer, clients []*mmdvm.MMDVMClient, done <-chan struct{}) {
+	if cfg.State.Path == "" || cfg.State.Interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(cfg.State.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			saveState(cfg, servers, clients)
+		case <-done:
+			return
+		}
+	}
+}
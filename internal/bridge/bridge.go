@@ -0,0 +1,283 @@
+// Package bridge links two IPSC systems back-to-back. The bridge acts as
+// IPSC master on both sides; calls received from peers on one side are
+// decoded into the internal packet representation, run through the
+// rewrite rules and timeslot arbitration, and re-encoded toward the peers
+// on the other side with the bridge's own peer ID, call control, and RTP
+// state.
+package bridge
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/ipsc"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/metrics"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/rewrite"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/timeslot"
+)
+
+// DMR frame type and data type values used to detect call termination
+// and reverse-channel bursts.
+const (
+	frameTypeDataSync       uint = 2
+	frameTypeReverseChannel uint = 3
+	dtypeTerminatorWithLC   uint = 2
+)
+
+// side is one IPSC system attached to the bridge.
+type side struct {
+	name   string
+	server *ipsc.IPSCServer
+	// rx decodes packets received from this side's peers and tx encodes
+	// packets sent to them. They are kept separate so per-stream state
+	// for the two directions never collides.
+	rx *ipsc.IPSCTranslator
+	tx *ipsc.IPSCTranslator
+	// tsMgr arbitrates calls being sent out this side.
+	tsMgr *timeslot.Manager
+}
+
+// Bridge forwards calls between two IPSC systems.
+type Bridge struct {
+	peerID uint32
+	a, b   *side
+
+	// Rewrite rules: RF rules apply from A to B (with PassAll as
+	// fallback), Net rules from B to A. passThrough is set when no rules
+	// are configured at all.
+	rules       rewrite.Set
+	passThrough bool
+}
+
+// New creates a bridge from cfg. The IPSC servers are created but not
+// started.
+func New(cfg *config.Bridge, m *metrics.Metrics) (*Bridge, error) {
+	a, err := newSide("a", cfg.A, cfg.PeerID, m)
+	if err != nil {
+		return nil, err
+	}
+	b, err := newSide("b", cfg.B, cfg.PeerID, m)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := rewrite.NewSet("bridge", rewrite.Config{
+		TGRewrites:   cfg.TGRewrites,
+		PCRewrites:   cfg.PCRewrites,
+		TypeRewrites: cfg.TypeRewrites,
+		SrcRewrites:  cfg.SrcRewrites,
+		PassAllTG:    cfg.PassAllTG,
+		PassAllPC:    cfg.PassAllPC,
+	})
+
+	br := &Bridge{
+		peerID:      cfg.PeerID,
+		a:           a,
+		b:           b,
+		rules:       rules,
+		passThrough: rules.Empty(),
+	}
+	a.server.SetBurstHandler(func(packetType byte, data []byte, addr *net.UDPAddr) {
+		br.forward(br.a, br.b, br.rewriteAToB, packetType, data, addr)
+	})
+	b.server.SetBurstHandler(func(packetType byte, data []byte, addr *net.UDPAddr) {
+		br.forward(br.b, br.a, br.rewriteBToA, packetType, data, addr)
+	})
+	a.server.SetPeerLostHandler(func(peerID uint32) {
+		br.deliver(br.a, br.b, br.rewriteAToB, br.a.rx.TerminatePeerStreams(peerID))
+	})
+	b.server.SetPeerLostHandler(func(peerID uint32) {
+		br.deliver(br.b, br.a, br.rewriteBToA, br.b.rx.TerminatePeerStreams(peerID))
+	})
+	if m != nil {
+		a.tsMgr.SetMetrics(m, "bridge-b-to-a")
+		b.tsMgr.SetMetrics(m, "bridge-a-to-b")
+	}
+	return br, nil
+}
+
+func newSide(name string, cfg config.IPSC, peerID uint32, m *metrics.Metrics) (*side, error) {
+	rx, err := ipsc.NewIPSCTranslator()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create IPSC translator for bridge side %s: %w", name, err)
+	}
+	tx, err := ipsc.NewIPSCTranslator()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create IPSC translator for bridge side %s: %w", name, err)
+	}
+	tx.SetPeerID(peerID)
+	tx.SetRTP(cfg.RTP)
+	rx.SetSwapSlots(cfg.SwapSlots)
+	tx.SetSwapSlots(cfg.SwapSlots)
+	rx.SetReverseChannel(cfg.ReverseChannel)
+	tx.SetReverseChannel(cfg.ReverseChannel)
+	tx.SetWakeUp(time.Duration(cfg.WakeUpIdle) * time.Second)
+	tx.SetHeaderRepeats(cfg.HeaderRepeats)
+	rx.SetMaxStreams(cfg.MaxStreams)
+	// Every burst crosses one rx translator, so commands are policed
+	// once, by the side they arrive on.
+	rx.SetRemoteCommands(cfg.RemoteCommands)
+	if m != nil {
+		rx.SetMetrics(m)
+		tx.SetMetrics(m)
+	}
+
+	server := ipsc.NewIPSCServer(&config.Config{IPSC: cfg}, m)
+	server.SetLocalID(peerID)
+
+	tsMgr := timeslot.NewManager()
+	tsMgr.SetBusyPolicyFromConfig(cfg)
+
+	return &side{
+		name:   name,
+		server: server,
+		rx:     rx,
+		tx:     tx,
+		tsMgr:  tsMgr,
+	}, nil
+}
+
+// Start starts the IPSC servers for both sides.
+func (br *Bridge) Start() error {
+	if err := br.a.server.Start(); err != nil {
+		return fmt.Errorf("failed to start bridge side a: %w", err)
+	}
+	if err := br.b.server.Start(); err != nil {
+		br.a.server.Stop()
+		return fmt.Errorf("failed to start bridge side b: %w", err)
+	}
+	slog.Info("Bridge started", "a", br.a.server.Addr(), "b", br.b.server.Addr(), "peerID", br.peerID)
+	return nil
+}
+
+// SetUnknownBurstHandler sets a function to receive each packet from
+// either side dropped for an unknown burst type. It must be called before
+// Start.
+func (br *Bridge) SetUnknownBurstHandler(handler func(data []byte)) {
+	br.a.rx.SetUnknownBurstHandler(handler)
+	br.b.rx.SetUnknownBurstHandler(handler)
+}
+
+// Stop stops both sides.
+func (br *Bridge) Stop() {
+	br.a.server.Stop()
+	br.b.server.Stop()
+}
+
+// Shutdown stops both sides gracefully, telling their peers. See
+// ipsc.IPSCServer.Shutdown.
+func (br *Bridge) Shutdown(ctx context.Context) error {
+	return errors.Join(br.a.server.Shutdown(ctx), br.b.server.Shutdown(ctx))
+}
+
+// A returns the IPSC server for side A.
+func (br *Bridge) A() *ipsc.IPSCServer {
+	return br.a.server
+}
+
+// B returns the IPSC server for side B.
+func (br *Bridge) B() *ipsc.IPSCServer {
+	return br.b.server
+}
+
+func (br *Bridge) rewriteAToB(pkt *proto.Packet) bool {
+	if br.passThrough {
+		return true
+	}
+	return rewrite.Apply(br.rules.RF, pkt) || rewrite.Apply(br.rules.PassAll, pkt)
+}
+
+func (br *Bridge) rewriteBToA(pkt *proto.Packet) bool {
+	if br.passThrough {
+		return true
+	}
+	return rewrite.Apply(br.rules.Net, pkt)
+}
+
+// forward carries one IPSC burst received on from over to the peers of to.
+func (br *Bridge) forward(from, to *side, rules func(*proto.Packet) bool, packetType byte, data []byte, addr *net.UDPAddr) {
+	// A burst stamped with our own peer ID is one we sent out, reflected
+	// back by a peer linked to both sides. Forwarding it would loop.
+	if len(data) >= 5 && binary.BigEndian.Uint32(data[1:5]) == br.peerID {
+		slog.Debug("Bridge dropped looped burst", "side", from.name, "from", addr)
+		return
+	}
+
+	br.deliver(from, to, rules, from.rx.TranslateToMMDVM(packetType, data))
+}
+
+// deliver rewrites and arbitrates packets decoded from the peers of from
+// and sends them to the peers of to.
+func (br *Bridge) deliver(from, to *side, rules func(*proto.Packet) bool, packets []proto.Packet) {
+	for _, pkt := range packets {
+		if !rules(&pkt) {
+			slog.Debug("Bridge dropped burst (no rewrite rule matched)", "side", from.name, "src", pkt.Src, "dst", pkt.Dst)
+			continue
+		}
+
+		// An interrupt belongs to a call already holding the slot.
+		if pkt.FrameType == frameTypeReverseChannel {
+			br.send(to, pkt)
+			continue
+		}
+
+		// Timeslot arbitration: buffer competing calls, deliver FIFO.
+		if !to.tsMgr.Submit(pkt.Slot, pkt.StreamID, from.name, pkt) {
+			slog.Debug("Bridge buffered burst (timeslot busy)", "side", to.name, "slot", pkt.Slot, "streamID", pkt.StreamID)
+			continue
+		}
+
+		br.send(to, pkt)
+
+		if isTerminator(pkt) {
+			br.drainPending(to, pkt.Slot, pkt.StreamID)
+		}
+	}
+}
+
+// send encodes pkt for the peers of to and transmits it.
+func (br *Bridge) send(to *side, pkt proto.Packet) {
+	for _, data := range to.tx.TranslateToIPSC(pkt) {
+		to.server.SendUserPacket(data)
+	}
+}
+
+// drainPending delivers calls that were buffered behind the stream that
+// just ended on slot, chaining through any that have already ended too.
+func (br *Bridge) drainPending(to *side, slot bool, streamID uint) {
+	currentStreamID := streamID
+	for {
+		buffered := to.tsMgr.Release(slot, currentStreamID)
+		if len(buffered) == 0 {
+			return
+		}
+		var nextStreamID uint
+		hasTerminator := false
+		for _, item := range buffered {
+			pkt, ok := item.(proto.Packet)
+			if !ok {
+				continue
+			}
+			br.send(to, pkt)
+			if isTerminator(pkt) {
+				hasTerminator = true
+				nextStreamID = pkt.StreamID
+			}
+		}
+		if !hasTerminator {
+			return
+		}
+		currentStreamID = nextStreamID
+	}
+}
+
+func isTerminator(pkt proto.Packet) bool {
+	return pkt.FrameType == frameTypeDataSync && pkt.DTypeOrVSeq == dtypeTerminatorWithLC
+}
@@ -0,0 +1,198 @@
+package bridge
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/USA-RedDragon/dmrgo/dmr/enums"
+	"github.com/USA-RedDragon/dmrgo/dmr/layer2"
+	"github.com/USA-RedDragon/dmrgo/dmr/layer2/elements"
+	"github.com/USA-RedDragon/dmrgo/dmr/layer2/pdu"
+	l3elements "github.com/USA-RedDragon/dmrgo/dmr/layer3/elements"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/ipsc"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/selftest"
+)
+
+const (
+	testBridgeID = 311860
+	testPeerA    = 311861
+	testPeerB    = 311862
+
+	testSrc = 3120001
+	testDst = 91
+)
+
+func newTestBridge(t *testing.T, cfg config.Bridge) *Bridge {
+	t.Helper()
+	cfg.PeerID = testBridgeID
+	cfg.A = config.IPSC{IP: "127.0.0.1"}
+	cfg.B = config.IPSC{IP: "127.0.0.1"}
+	br, err := New(&cfg, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := br.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(br.Stop)
+	return br
+}
+
+func newRegisteredPeer(t *testing.T, id uint32, server *ipsc.IPSCServer) *selftest.FakePeer {
+	t.Helper()
+	peer, err := selftest.NewFakePeer(id, server.Addr())
+	if err != nil {
+		t.Fatalf("NewFakePeer: %v", err)
+	}
+	t.Cleanup(func() { _ = peer.Close() })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := peer.Register(ctx); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return peer
+}
+
+// encodeCall returns a voice LC header and terminator for a group call,
+// encoded as IPSC packets stamped with peerID.
+func encodeCall(t *testing.T, peerID uint32, dst uint) [][]byte {
+	t.Helper()
+	flc := pdu.FullLinkControl{
+		FLCO:           enums.FLCOGroupVoiceChannelUser,
+		FeatureSetID:   enums.StandardizedFID,
+		ServiceOptions: l3elements.ServiceOptions{},
+		GroupAddress:   int(dst), //nolint:gosec // test IDs are 24-bit
+		SourceAddress:  testSrc,
+	}
+	var lc [12]byte
+	encoded, err := flc.Encode()
+	if err != nil {
+		t.Fatalf("encode LC: %v", err)
+	}
+	copy(lc[:], encoded)
+
+	base := proto.Packet{
+		Signature: "DMRD",
+		Src:       testSrc,
+		Dst:       dst,
+		Slot:      true,
+		GroupCall: true,
+		FrameType: frameTypeDataSync,
+		StreamID:  0x1001,
+	}
+	header := base
+	header.DTypeOrVSeq = uint(elements.DataTypeVoiceLCHeader)
+	header.DMRData = layer2.BuildLCDataBurst(lc, elements.DataTypeVoiceLCHeader, 1)
+	terminator := base
+	terminator.Seq = 1
+	terminator.DTypeOrVSeq = uint(elements.DataTypeTerminatorWithLC)
+	terminator.DMRData = layer2.BuildLCDataBurst(lc, elements.DataTypeTerminatorWithLC, 1)
+
+	encoder, err := ipsc.NewIPSCTranslator()
+	if err != nil {
+		t.Fatalf("NewIPSCTranslator: %v", err)
+	}
+	encoder.SetPeerID(peerID)
+	var out [][]byte
+	for _, pkt := range []proto.Packet{header, terminator} {
+		out = append(out, encoder.TranslateToIPSC(pkt)...)
+	}
+	return out
+}
+
+func sendAll(t *testing.T, peer *selftest.FakePeer, packets [][]byte) {
+	t.Helper()
+	for _, data := range packets {
+		if err := peer.Send(data); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// collect gathers packets from peer until a terminator arrives or the
+// timeout elapses.
+func collect(peer *selftest.FakePeer, timeout time.Duration) (received [][]byte, sawTerminator bool) {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case data := <-peer.Packets():
+			received = append(received, data)
+			if len(data) > 30 && data[30] == 0x02 && data[17]&0x40 != 0 {
+				return received, true
+			}
+		case <-deadline:
+			return received, false
+		}
+	}
+}
+
+func TestBridgeCarriesCallAToB(t *testing.T) {
+	t.Parallel()
+	br := newTestBridge(t, config.Bridge{})
+	peerA := newRegisteredPeer(t, testPeerA, br.A())
+	peerB := newRegisteredPeer(t, testPeerB, br.B())
+
+	sendAll(t, peerA, encodeCall(t, testPeerA, testDst))
+
+	received, sawTerminator := collect(peerB, 5*time.Second)
+	if !sawTerminator {
+		t.Fatalf("expected a terminator on side B, got %d packets", len(received))
+	}
+	for i, data := range received {
+		if got := binary.BigEndian.Uint32(data[1:5]); got != testBridgeID {
+			t.Fatalf("packet %d: expected bridge peer ID %d, got %d", i, testBridgeID, got)
+		}
+		src := uint(data[6])<<16 | uint(data[7])<<8 | uint(data[8])
+		dst := uint(data[9])<<16 | uint(data[10])<<8 | uint(data[11])
+		if src != testSrc || dst != testDst {
+			t.Fatalf("packet %d: expected src=%d dst=%d, got src=%d dst=%d", i, testSrc, testDst, src, dst)
+		}
+	}
+}
+
+func TestBridgeAppliesRewriteRules(t *testing.T) {
+	t.Parallel()
+	br := newTestBridge(t, config.Bridge{
+		TGRewrites: []config.TGRewriteConfig{{FromSlot: 2, FromTG: testDst, ToSlot: 2, ToTG: 3100, Range: 1}},
+	})
+	peerA := newRegisteredPeer(t, testPeerA, br.A())
+	peerB := newRegisteredPeer(t, testPeerB, br.B())
+
+	sendAll(t, peerA, encodeCall(t, testPeerA, testDst))
+
+	received, sawTerminator := collect(peerB, 5*time.Second)
+	if !sawTerminator {
+		t.Fatalf("expected a terminator on side B, got %d packets", len(received))
+	}
+	for i, data := range received {
+		dst := uint(data[9])<<16 | uint(data[10])<<8 | uint(data[11])
+		if dst != 3100 {
+			t.Fatalf("packet %d: expected rewritten dst 3100, got %d", i, dst)
+		}
+	}
+
+	// A TG without a matching rule is not carried.
+	sendAll(t, peerA, encodeCall(t, testPeerA, 92))
+	if received, _ := collect(peerB, 300*time.Millisecond); len(received) != 0 {
+		t.Fatalf("expected unmatched TG to be dropped, got %d packets", len(received))
+	}
+}
+
+func TestBridgeSuppressesLoops(t *testing.T) {
+	t.Parallel()
+	br := newTestBridge(t, config.Bridge{})
+	peerA := newRegisteredPeer(t, testPeerA, br.A())
+	peerB := newRegisteredPeer(t, testPeerB, br.B())
+
+	// A call stamped with the bridge's own peer ID is one the bridge sent.
+	sendAll(t, peerA, encodeCall(t, testBridgeID, testDst))
+
+	if received, _ := collect(peerB, 300*time.Millisecond); len(received) != 0 {
+		t.Fatalf("expected looped call to be dropped, got %d packets", len(received))
+	}
+}
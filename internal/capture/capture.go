@@ -0,0 +1,216 @@
+// Package capture records IPSC datagrams to files for diagnosing
+// interoperability problems, with the context a packet capture on the
+// interface lacks: the peer ID and what the server made of each packet.
+// Files are written as pcapng, with each datagram wrapped in synthetic IP
+// and UDP headers, or as JSON lines with the datagram in hex.
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+)
+
+// Direction is whether a datagram was received or sent.
+type Direction string
+
+const (
+	Inbound  Direction = "in"
+	Outbound Direction = "out"
+)
+
+// Record is one captured datagram.
+type Record struct {
+	Time      time.Time
+	Direction Direction
+	Src       *net.UDPAddr
+	Dst       *net.UDPAddr
+	// PeerID is the ID of the peer at the other end, if known.
+	PeerID uint32
+	// Verdict is what the server did with the datagram, such as
+	// accepted, ignored, or the error it was refused with.
+	Verdict string
+	Data    []byte
+}
+
+// encoder writes records in one file format.
+type encoder interface {
+	// header returns the bytes every file starts with.
+	header() []byte
+	// encode returns the bytes of r.
+	encode(r Record) ([]byte, error)
+}
+
+// ErrRecordTooLarge is returned for a record larger than a whole capture
+// file may be.
+var ErrRecordTooLarge = errors.New("capture record larger than the maximum file size")
+
+// Writer writes records to capture files, starting a new file once one
+// reaches the maximum file size and deleting the oldest files to keep
+// them all under the maximum total size. It is safe for concurrent use.
+type Writer struct {
+	mu  sync.Mutex
+	enc encoder
+	// dir, stem, and ext make up the capture file names,
+	// <stem>-<time>-<seq><ext> in dir.
+	dir, stem, ext string
+	maxFileSize    int64
+	maxTotalSize   int64
+	now            func() time.Time
+
+	file *os.File
+	size int64
+	seq  int
+}
+
+// New creates the capture directory if needed and opens the first
+// capture file.
+func New(cfg config.IPSCCapture) (*Writer, error) {
+	var enc encoder
+	switch cfg.Format {
+	case "", config.CaptureFormatPcapng:
+		enc = pcapngEncoder{}
+	case config.CaptureFormatJSONL:
+		enc = jsonlEncoder{}
+	default:
+		return nil, fmt.Errorf("unknown capture format %q", cfg.Format)
+	}
+	ext := filepath.Ext(cfg.Path)
+	w := &Writer{
+		enc:          enc,
+		dir:          filepath.Dir(cfg.Path),
+		stem:         strings.TrimSuffix(filepath.Base(cfg.Path), ext),
+		ext:          ext,
+		maxFileSize:  int64(cfg.MaxFileSize),  //nolint:gosec // G115: a file size
+		maxTotalSize: int64(cfg.MaxTotalSize), //nolint:gosec // G115: a file size
+		now:          time.Now,
+	}
+	if err := os.MkdirAll(w.dir, 0o750); err != nil {
+		return nil, fmt.Errorf("error creating capture directory: %w", err)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.rotate(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Record writes r to the current capture file, starting a new one first
+// if r would take it past the maximum file size.
+func (w *Writer) Record(r Record) error {
+	data, err := w.enc.encode(r)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	if w.size+int64(len(data)) > w.maxFileSize {
+		if int64(len(w.enc.header())+len(data)) > w.maxFileSize {
+			return ErrRecordTooLarge
+		}
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := w.file.Write(data)
+	w.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("error writing capture file: %w", err)
+	}
+	return nil
+}
+
+// Close closes the current capture file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// rotate closes the current capture file, if any, opens a new one, and
+// deletes the oldest files over the maximum total size. The caller must
+// hold w.mu.
+func (w *Writer) rotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			slog.Warn("error closing capture file", "file", w.file.Name(), "error", err)
+		}
+		w.file = nil
+	}
+	w.seq++
+	name := filepath.Join(w.dir, fmt.Sprintf("%s-%s-%04d%s",
+		w.stem, w.now().UTC().Format("20060102T150405.000000000Z"), w.seq, w.ext))
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return fmt.Errorf("error creating capture file: %w", err)
+	}
+	header := w.enc.header()
+	if _, err := file.Write(header); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("error writing capture file: %w", err)
+	}
+	w.file = file
+	w.size = int64(len(header))
+	slog.Info("Capturing IPSC traffic", "file", name)
+
+	w.prune()
+	return nil
+}
+
+// prune deletes the oldest capture files until those left, each at its
+// maximum size, fit in the maximum total size. The current file is
+// never deleted. The caller must hold w.mu.
+func (w *Writer) prune() {
+	files, err := filepath.Glob(filepath.Join(w.dir, w.stem+"-*"+w.ext))
+	if err != nil {
+		return
+	}
+	// The names sort by the time they were started.
+	slices.Sort(files)
+	type oldFile struct {
+		name string
+		size int64
+	}
+	var old []oldFile
+	total := w.maxFileSize // room for the current file to grow
+	for _, name := range files {
+		if name == w.file.Name() {
+			continue
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		old = append(old, oldFile{name, info.Size()})
+		total += info.Size()
+	}
+	for _, f := range old {
+		if total <= w.maxTotalSize {
+			return
+		}
+		if err := os.Remove(f.name); err != nil {
+			slog.Warn("error deleting old capture file", "file", f.name, "error", err)
+			continue
+		}
+		total -= f.size
+	}
+}
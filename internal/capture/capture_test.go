@@ -0,0 +1,154 @@
+package capture
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+)
+
+func testRecord(verdict string, size int) Record {
+	return Record{
+		Time:      time.Unix(1_700_000_000, 0),
+		Direction: Inbound,
+		Src:       &net.UDPAddr{IP: net.IPv4(10, 10, 250, 2), Port: 50000},
+		Dst:       &net.UDPAddr{IP: net.IPv4(10, 10, 250, 1), Port: 50001},
+		PeerID:    8000,
+		Verdict:   verdict,
+		Data:      make([]byte, size),
+	}
+}
+
+// captureFiles returns the capture files in dir, oldest first.
+func captureFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "ipsc-*.pcapng"))
+	if err != nil {
+		t.Fatalf("glob: %v", err)
+	}
+	slices.Sort(files)
+	return files
+}
+
+func TestWriterJSONL(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	w, err := New(config.IPSCCapture{
+		Path: filepath.Join(dir, "ipsc.jsonl"), Format: config.CaptureFormatJSONL,
+		MaxFileSize: 1 << 20, MaxTotalSize: 1 << 20,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	r := testRecord("ignored", 5)
+	r.Data = []byte{0x96, 0x00, 0x00, 0x1F, 0x40}
+	if err := w.Record(r); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "ipsc-*.jsonl"))
+	if len(files) != 1 {
+		t.Fatalf("expected one capture file, got %v", files)
+	}
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	var lines []jsonlRecord
+	for scanner.Scan() {
+		var line jsonlRecord
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("unmarshal %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	want := jsonlRecord{
+		Time: r.Time, Direction: Inbound, Src: "10.10.250.2:50000", Dst: "10.10.250.1:50001",
+		PeerID: 8000, Type: "0x96", Verdict: "ignored", Length: 5, Hex: "9600001f40",
+	}
+	if len(lines) != 1 || !lines[0].Time.Equal(want.Time) {
+		t.Fatalf("expected %+v, got %+v", want, lines)
+	}
+	lines[0].Time = want.Time
+	if lines[0] != want {
+		t.Fatalf("expected %+v, got %+v", want, lines[0])
+	}
+}
+
+func TestWriterRotatesAndCapsDiskUsage(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	record, _ := pcapngEncoder{}.encode(testRecord("accepted", 100))
+	header := pcapngEncoder{}.header()
+	// Room for three records a file, and three files in all.
+	maxFile := len(header) + 3*len(record)
+	w, err := New(config.IPSCCapture{
+		Path: filepath.Join(dir, "ipsc.pcapng"), Format: config.CaptureFormatPcapng,
+		MaxFileSize: uint(maxFile), MaxTotalSize: uint(3 * maxFile),
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer w.Close()
+
+	for range 3 {
+		if err := w.Record(testRecord("accepted", 100)); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	if files := captureFiles(t, dir); len(files) != 1 {
+		t.Fatalf("expected one file until it is full, got %v", files)
+	}
+	first := captureFiles(t, dir)[0]
+	info, err := os.Stat(first)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Size() != int64(maxFile) {
+		t.Fatalf("expected the file filled to %d bytes, got %d", maxFile, info.Size())
+	}
+
+	// Each full file starts another, and past three the oldest go.
+	for range 9 {
+		if err := w.Record(testRecord("accepted", 100)); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	files := captureFiles(t, dir)
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files kept, got %v", files)
+	}
+	if slices.Contains(files, first) {
+		t.Fatalf("expected the oldest file %s deleted, got %v", first, files)
+	}
+	for _, name := range files {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if len(data) > maxFile {
+			t.Fatalf("%s: expected at most %d bytes, got %d", name, maxFile, len(data))
+		}
+		// Every file is a complete capture of its own.
+		blocks := readBlocks(t, data)
+		if len(blocks) < 2 {
+			t.Fatalf("%s: expected the pcapng header, got %d blocks", name, len(blocks))
+		}
+	}
+
+	if err := w.Record(testRecord("accepted", maxFile)); !errors.Is(err, ErrRecordTooLarge) {
+		t.Fatalf("expected ErrRecordTooLarge, got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package capture
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// jsonlRecord is a record as one line of a JSON lines capture file.
+type jsonlRecord struct {
+	Time      time.Time `json:"time"`
+	Direction Direction `json:"direction"`
+	Src       string    `json:"src"`
+	Dst       string    `json:"dst"`
+	PeerID    uint32    `json:"peerID"`
+	Type      string    `json:"type"`
+	Verdict   string    `json:"verdict"`
+	Length    int       `json:"length"`
+	Hex       string    `json:"hex"`
+}
+
+// jsonlEncoder writes one JSON object per line, with the datagram in hex.
+type jsonlEncoder struct{}
+
+func (jsonlEncoder) header() []byte {
+	return nil
+}
+
+func (jsonlEncoder) encode(r Record) ([]byte, error) {
+	line := jsonlRecord{
+		Time:      r.Time,
+		Direction: r.Direction,
+		Src:       r.Src.String(),
+		Dst:       r.Dst.String(),
+		PeerID:    r.PeerID,
+		Verdict:   r.Verdict,
+		Length:    len(r.Data),
+		Hex:       hex.EncodeToString(r.Data),
+	}
+	if len(r.Data) > 0 {
+		line.Type = fmt.Sprintf("0x%02X", r.Data[0])
+	}
+	data, err := json.Marshal(line)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding capture record: %w", err)
+	}
+	return append(data, '\n'), nil
+}
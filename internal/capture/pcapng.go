@@ -0,0 +1,179 @@
+package capture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// pcapng block types, options, and values, from the pcapng
+// specification.
+const (
+	blockSectionHeader       uint32 = 0x0A0D0D0A
+	blockInterfaceDesc       uint32 = 0x00000001
+	blockEnhancedPacket      uint32 = 0x00000006
+	byteOrderMagic           uint32 = 0x1A2B3C4D
+	optEndOfOpt              uint16 = 0
+	optComment               uint16 = 1
+	optEPBFlags              uint16 = 2
+	epbFlagInbound           uint32 = 1
+	epbFlagOutbound          uint32 = 2
+	linkTypeRaw              uint16 = 101 // raw IPv4 or IPv6
+	ipv4HeaderSize                  = 20
+	ipv6HeaderSize                  = 40
+	udpHeaderSize                   = 8
+	protocolUDP                     = 17
+	defaultTTL                      = 64
+	sectionLengthUnspecified uint64 = 0xFFFFFFFFFFFFFFFF
+)
+
+// pcapngEncoder writes a pcapng file with one interface carrying raw IP
+// packets. Each datagram is wrapped in IP and UDP headers built from its
+// addresses, and the peer ID and verdict go in the packet comment.
+type pcapngEncoder struct{}
+
+func (pcapngEncoder) header() []byte {
+	shb := block(blockSectionHeader, func(b []byte) []byte {
+		b = binary.LittleEndian.AppendUint32(b, byteOrderMagic)
+		b = binary.LittleEndian.AppendUint16(b, 1) // major version
+		b = binary.LittleEndian.AppendUint16(b, 0) // minor version
+		return binary.LittleEndian.AppendUint64(b, sectionLengthUnspecified)
+	})
+	idb := block(blockInterfaceDesc, func(b []byte) []byte {
+		b = binary.LittleEndian.AppendUint16(b, linkTypeRaw)
+		// Reserved, then no snapshot length.
+		b = binary.LittleEndian.AppendUint16(b, 0)
+		return binary.LittleEndian.AppendUint32(b, 0)
+	})
+	return append(shb, idb...)
+}
+
+func (pcapngEncoder) encode(r Record) ([]byte, error) {
+	packet, err := encapsulate(r.Src, r.Dst, r.Data)
+	if err != nil {
+		return nil, err
+	}
+	// Timestamps are in microseconds, the default resolution.
+	ts := uint64(r.Time.UnixMicro()) //nolint:gosec // G115: times after 1970
+	flags := epbFlagInbound
+	if r.Direction == Outbound {
+		flags = epbFlagOutbound
+	}
+	comment := fmt.Sprintf("peer %d: %s", r.PeerID, r.Verdict)
+	return block(blockEnhancedPacket, func(b []byte) []byte {
+		b = binary.LittleEndian.AppendUint32(b, 0) // interface ID
+		b = binary.LittleEndian.AppendUint32(b, uint32(ts>>32))
+		b = binary.LittleEndian.AppendUint32(b, uint32(ts)) //nolint:gosec // G115: the low half
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(packet)))
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(packet)))
+		b = pad(append(b, packet...))
+		b = option(b, optEPBFlags, binary.LittleEndian.AppendUint32(nil, flags))
+		b = option(b, optComment, []byte(comment))
+		return option(b, optEndOfOpt, nil)
+	}), nil
+}
+
+// block returns a pcapng block of type blockType with the body body
+// appends, framed by its total length.
+func block(blockType uint32, body func([]byte) []byte) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, blockType)
+	b = binary.LittleEndian.AppendUint32(b, 0) // total length, filled in below
+	b = body(b)
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	length := uint32(len(b)) //nolint:gosec // G115: blocks are small
+	binary.LittleEndian.PutUint32(b[4:8], length)
+	binary.LittleEndian.PutUint32(b[len(b)-4:], length)
+	return b
+}
+
+// option appends a pcapng option, padded to 32 bits.
+func option(b []byte, code uint16, value []byte) []byte {
+	b = binary.LittleEndian.AppendUint16(b, code)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(value))) //nolint:gosec // G115: options are small
+	return pad(append(b, value...))
+}
+
+// pad pads b with zeros to a multiple of 32 bits.
+func pad(b []byte) []byte {
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// encapsulate wraps payload in the IPv4, or if either address is IPv6,
+// IPv6 and UDP headers of a datagram from src to dst.
+func encapsulate(src, dst *net.UDPAddr, payload []byte) ([]byte, error) {
+	if src == nil || dst == nil {
+		return nil, fmt.Errorf("capture record without both addresses")
+	}
+	udp := make([]byte, udpHeaderSize, udpHeaderSize+len(payload))
+	binary.BigEndian.PutUint16(udp[0:2], uint16(src.Port)) //nolint:gosec // G115: a port
+	binary.BigEndian.PutUint16(udp[2:4], uint16(dst.Port)) //nolint:gosec // G115: a port
+	binary.BigEndian.PutUint16(udp[4:6], uint16(udpHeaderSize+len(payload)))
+	udp = append(udp, payload...)
+
+	src4, dst4 := src.IP.To4(), dst.IP.To4()
+	if src4 != nil && dst4 != nil {
+		ip := make([]byte, ipv4HeaderSize, ipv4HeaderSize+len(udp))
+		// Version 4, with 5 words of header.
+		ip[0] = 0x45
+		binary.BigEndian.PutUint16(ip[2:4], uint16(len(ip)+len(udp))) //nolint:gosec // G115: datagrams are small
+		ip[8] = defaultTTL
+		ip[9] = protocolUDP
+		copy(ip[12:16], src4)
+		copy(ip[16:20], dst4)
+		binary.BigEndian.PutUint16(ip[10:12], checksum(0, ip))
+		binary.BigEndian.PutUint16(udp[6:8], udpChecksum(src4, dst4, udp))
+		return append(ip, udp...), nil
+	}
+
+	src16, dst16 := src.IP.To16(), dst.IP.To16()
+	if src16 == nil || dst16 == nil {
+		return nil, fmt.Errorf("capture record with an invalid address")
+	}
+	ip := make([]byte, ipv6HeaderSize, ipv6HeaderSize+len(udp))
+	// Version 6.
+	ip[0] = 0x60
+	binary.BigEndian.PutUint16(ip[4:6], uint16(len(udp))) //nolint:gosec // G115: datagrams are small
+	ip[6] = protocolUDP
+	ip[7] = defaultTTL
+	copy(ip[8:24], src16)
+	copy(ip[24:40], dst16)
+	binary.BigEndian.PutUint16(udp[6:8], udpChecksum(src16, dst16, udp))
+	return append(ip, udp...), nil
+}
+
+// udpChecksum returns the checksum of udp, its checksum field zero, over
+// the pseudo-header of src and dst.
+func udpChecksum(src, dst net.IP, udp []byte) uint16 {
+	var sum uint32
+	sum = sumWords(sum, src)
+	sum = sumWords(sum, dst)
+	sum += protocolUDP + uint32(len(udp)) //nolint:gosec // G115: datagrams are small
+	c := checksum(sum, udp)
+	if c == 0 {
+		// Zero means no checksum.
+		return 0xFFFF
+	}
+	return c
+}
+
+// checksum returns the Internet checksum of b, starting from sum.
+func checksum(sum uint32, b []byte) uint16 {
+	sum = sumWords(sum, b)
+	for sum > 0xFFFF {
+		sum = sum>>16 + sum&0xFFFF
+	}
+	return ^uint16(sum) //nolint:gosec // G115: folded to 16 bits
+}
+
+func sumWords(sum uint32, b []byte) uint32 {
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	return sum
+}
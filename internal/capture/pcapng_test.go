@@ -0,0 +1,155 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readBlocks splits a pcapng file into its blocks, checking that each is
+// framed by the same total length, a multiple of 32 bits.
+func readBlocks(t *testing.T, data []byte) [][]byte {
+	t.Helper()
+	var blocks [][]byte
+	for len(data) > 0 {
+		if len(data) < 12 {
+			t.Fatalf("truncated block: % X", data)
+		}
+		length := binary.LittleEndian.Uint32(data[4:8])
+		if length%4 != 0 || int(length) > len(data) {
+			t.Fatalf("bad block length %d with %d bytes left", length, len(data))
+		}
+		if trailing := binary.LittleEndian.Uint32(data[length-4 : length]); trailing != length {
+			t.Fatalf("block length %d, trailing length %d", length, trailing)
+		}
+		blocks = append(blocks, data[:length])
+		data = data[length:]
+	}
+	return blocks
+}
+
+func TestPcapngHeader(t *testing.T) {
+	t.Parallel()
+	blocks := readBlocks(t, pcapngEncoder{}.header())
+	if len(blocks) != 2 {
+		t.Fatalf("expected a section header and an interface description, got %d blocks", len(blocks))
+	}
+	shb, idb := blocks[0], blocks[1]
+	if got := binary.LittleEndian.Uint32(shb[0:4]); got != blockSectionHeader {
+		t.Fatalf("expected a section header block, got type 0x%08X", got)
+	}
+	if got := binary.LittleEndian.Uint32(shb[8:12]); got != byteOrderMagic {
+		t.Fatalf("expected the byte order magic, got 0x%08X", got)
+	}
+	if major, minor := binary.LittleEndian.Uint16(shb[12:14]), binary.LittleEndian.Uint16(shb[14:16]); major != 1 || minor != 0 {
+		t.Fatalf("expected version 1.0, got %d.%d", major, minor)
+	}
+	if got := binary.LittleEndian.Uint32(idb[0:4]); got != blockInterfaceDesc {
+		t.Fatalf("expected an interface description block, got type 0x%08X", got)
+	}
+	if got := binary.LittleEndian.Uint16(idb[8:10]); got != linkTypeRaw {
+		t.Fatalf("expected link type %d, got %d", linkTypeRaw, got)
+	}
+}
+
+func TestPcapngPacketBlock(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		src, dst  *net.UDPAddr
+		direction Direction
+		ipHeader  int
+	}{
+		{"IPv4 in", &net.UDPAddr{IP: net.IPv4(10, 10, 250, 2), Port: 50000}, &net.UDPAddr{IP: net.IPv4(10, 10, 250, 1), Port: 50001}, Inbound, ipv4HeaderSize},
+		{"IPv6 out", &net.UDPAddr{IP: net.ParseIP("fd00::1"), Port: 50001}, &net.UDPAddr{IP: net.ParseIP("fd00::2"), Port: 50000}, Outbound, ipv6HeaderSize},
+	}
+	payload := []byte{0x90, 0x00, 0x00, 0x1F, 0x40, 0x6A, 0x00, 0x00, 0x00, 0x0D, 0xAB}
+	when := time.Unix(1_700_000_000, 123_456_000)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data, err := pcapngEncoder{}.encode(Record{
+				Time: when, Direction: tt.direction, Src: tt.src, Dst: tt.dst,
+				PeerID: 8000, Verdict: "accepted", Data: payload,
+			})
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			blocks := readBlocks(t, data)
+			if len(blocks) != 1 {
+				t.Fatalf("expected one block, got %d", len(blocks))
+			}
+			epb := blocks[0]
+			if got := binary.LittleEndian.Uint32(epb[0:4]); got != blockEnhancedPacket {
+				t.Fatalf("expected an enhanced packet block, got type 0x%08X", got)
+			}
+			ts := uint64(binary.LittleEndian.Uint32(epb[12:16]))<<32 | uint64(binary.LittleEndian.Uint32(epb[16:20]))
+			if want := uint64(when.UnixMicro()); ts != want { //nolint:gosec // G115: a recent time
+				t.Fatalf("expected timestamp %d, got %d", want, ts)
+			}
+			captured := binary.LittleEndian.Uint32(epb[20:24])
+			if original := binary.LittleEndian.Uint32(epb[24:28]); captured != original {
+				t.Fatalf("expected the whole packet captured, got %d of %d bytes", captured, original)
+			}
+			if want := uint32(tt.ipHeader + udpHeaderSize + len(payload)); captured != want { //nolint:gosec // G115: small
+				t.Fatalf("expected %d bytes captured, got %d", want, captured)
+			}
+			packet := epb[28 : 28+captured]
+
+			udp := packet[tt.ipHeader:]
+			if src, dst := binary.BigEndian.Uint16(udp[0:2]), binary.BigEndian.Uint16(udp[2:4]); int(src) != tt.src.Port || int(dst) != tt.dst.Port {
+				t.Fatalf("expected ports %d to %d, got %d to %d", tt.src.Port, tt.dst.Port, src, dst)
+			}
+			if !bytes.Equal(udp[udpHeaderSize:], payload) {
+				t.Fatalf("expected the payload after the UDP header, got % X", udp[udpHeaderSize:])
+			}
+			var src, dst net.IP
+			if tt.ipHeader == ipv4HeaderSize {
+				if checksum(0, packet[:ipv4HeaderSize]) != 0 {
+					t.Fatal("bad IPv4 header checksum")
+				}
+				src, dst = packet[12:16], packet[16:20]
+			} else {
+				src, dst = packet[8:24], packet[24:40]
+			}
+			if !src.Equal(tt.src.IP) || !dst.Equal(tt.dst.IP) {
+				t.Fatalf("expected addresses %s to %s, got %s to %s", tt.src.IP, tt.dst.IP, src, dst)
+			}
+			pseudo := sumWords(sumWords(0, src), dst) + protocolUDP + uint32(len(udp)) //nolint:gosec // G115: small
+			if checksum(pseudo, udp) != 0 {
+				t.Fatal("bad UDP checksum")
+			}
+
+			options := epb[28+(int(captured)+3)/4*4 : len(epb)-4]
+			wantFlags := epbFlagInbound
+			if tt.direction == Outbound {
+				wantFlags = epbFlagOutbound
+			}
+			var gotFlags uint32
+			var comment string
+			for len(options) >= 4 {
+				code, length := binary.LittleEndian.Uint16(options[0:2]), int(binary.LittleEndian.Uint16(options[2:4]))
+				value := options[4 : 4+length]
+				switch code {
+				case optEPBFlags:
+					gotFlags = binary.LittleEndian.Uint32(value)
+				case optComment:
+					comment = string(value)
+				}
+				options = options[4+(length+3)/4*4:]
+				if code == optEndOfOpt {
+					break
+				}
+			}
+			if gotFlags != wantFlags {
+				t.Fatalf("expected flags %d, got %d", wantFlags, gotFlags)
+			}
+			if !strings.Contains(comment, "8000") || !strings.Contains(comment, "accepted") {
+				t.Fatalf("expected the peer ID and verdict in the comment, got %q", comment)
+			}
+		})
+	}
+}
@@ -2,10 +2,17 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"net"
+	"reflect"
 	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"time"
 
-	"github.com/vishvananda/netlink"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dscp"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/netsetup"
 )
 
 type LogLevel string
@@ -20,8 +27,21 @@ const (
 type Config struct {
 	LogLevel LogLevel `name:"log-level" description:"Logging level for the application. One of debug, info, warn, or error" default:"info"`
 	Metrics  Metrics  `name:"metrics" description:"Configuration for Prometheus metrics"`
+	Health   Health   `name:"health" description:"Configuration for the liveness and readiness endpoints"`
 	MMDVM    []MMDVM  `name:"mmdvm" description:"Configuration for MMDVM clients (multiple DMR masters)"`
-	IPSC     IPSC     `name:"ipsc" description:"Configuration for the IPSC server"`
+	// IPSC is the IPSC server this config is for: the first of
+	// IPSCServers, unless IPSCConfigs made the config for another.
+	// IPSCServers are those of the ipsc section. The section is one
+	// server or a list of them, so it is loaded by LoadIPSC rather than
+	// with the rest.
+	IPSC        IPSC
+	IPSCServers []IPSC
+	State       State       `name:"state" description:"Configuration for persisting runtime state across restarts"`
+	Bridge      Bridge      `name:"bridge" description:"Configuration for bridge mode, linking two IPSC systems without MMDVM"`
+	Supervisor  Supervisor  `name:"supervisor" description:"Configuration for restarting components that crash"`
+	Parrot      Parrot      `name:"parrot" description:"Configuration for the built-in parrot (echo) service"`
+	SpecialIDs  []SpecialID `name:"special-ids" description:"Policy for calls to reserved network IDs, evaluated before rewrite rules. Replaces the built-in defaults when set"`
+	Service     bool        `name:"service" description:"Run under the Windows service control manager"`
 }
 
 type Metrics struct {
@@ -29,18 +49,320 @@ type Metrics struct {
 	Address string `name:"address" description:"Address to serve Prometheus metrics on" default:":9100"`
 }
 
+// Health configures the HTTP listener serving /healthz and /readyz, which
+// the healthcheck subcommand probes.
+type Health struct {
+	Enabled bool   `name:"enabled" description:"Whether to serve the /healthz and /readyz endpoints"`
+	Address string `name:"address" description:"Address to serve the health endpoints on" default:"127.0.0.1:9101"`
+}
+
+// Supervisor configures how the IPSC server and MMDVM clients are
+// restarted after a panic.
+type Supervisor struct {
+	MaxRestarts uint `name:"max-restarts" description:"Restarts allowed per component per hour before the process exits" default:"5"`
+}
+
+// Parrot configures the echo service, which records calls addressed to it
+// and plays them back to the caller.
+type Parrot struct {
+	Enabled     bool   `name:"enabled" description:"Play calls addressed to the parrot back to the caller"`
+	ID          uint32 `name:"id" description:"Private ID, or talkgroup if group is set, that the parrot answers on" default:"9990"`
+	Group       bool   `name:"group" description:"Answer group calls to the id talkgroup instead of private calls to the id"`
+	MaxDuration uint   `name:"max-duration" description:"Maximum seconds of a call the parrot records and plays back" default:"30"`
+}
+
+// SpecialIDAction is what happens to a call to a special ID.
+type SpecialIDAction string
+
+const (
+	SpecialIDActionBlock SpecialIDAction = "block"
+	SpecialIDActionPass  SpecialIDAction = "pass"
+	SpecialIDActionLocal SpecialIDAction = "local"
+)
+
+// SpecialIDDirection limits a special-ID rule to calls travelling one
+// way.
+type SpecialIDDirection string
+
+const (
+	SpecialIDDirectionBoth     SpecialIDDirection = "both"
+	SpecialIDDirectionToIPSC   SpecialIDDirection = "to-ipsc"
+	SpecialIDDirectionToMaster SpecialIDDirection = "to-master"
+)
+
+// SpecialIDHandlerParrot names the parrot as the handler of a local rule.
+const SpecialIDHandlerParrot = "parrot"
+
+// SpecialID is one rule of the special-ID policy table. It applies to
+// calls whose destination is between FromID and ToID inclusive.
+type SpecialID struct {
+	FromID    uint32             `name:"from-id" description:"First destination ID the rule applies to"`
+	ToID      uint32             `name:"to-id" description:"Last destination ID the rule applies to. Defaults to from-id"`
+	Direction SpecialIDDirection `name:"direction" description:"Calls the rule applies to: both, to-ipsc, or to-master" default:"both"`
+	Action    SpecialIDAction    `name:"action" description:"What to do with matching calls: block, pass, or local"`
+	Handler   string             `name:"handler" description:"Local service that answers matching calls when the action is local. Currently only parrot"`
+}
+
+// State configures the optional snapshot of IPSC peers and call bookkeeping
+// that is written on shutdown and periodically, and restored on startup.
+type State struct {
+	Path     string `name:"path" description:"File to persist IPSC peers and call bookkeeping to. Persistence is disabled when empty"`
+	Interval uint   `name:"interval" description:"Seconds between periodic state snapshots" default:"60"`
+	MaxAge   uint   `name:"max-age" description:"Maximum age in seconds of a snapshot restored on startup" default:"600"`
+}
+
 // IPSC creates a virtual network interface and listens for IPSC packets on it.
 type IPSC struct {
-	Interface  string   `name:"interface" description:"Interface to listen for IPSC packets on"`
-	Port       uint16   `name:"port" description:"Port to listen for IPSC packets on"`
-	IP         string   `name:"ip" description:"IP address to listen for IPSC packets on" default:"10.10.250.1"`
-	SubnetMask int      `name:"subnet-mask" description:"Subnet mask for the virtual network interface created for IPSC packets" default:"24"`
-	Auth       IPSCAuth `name:"auth" description:"Authentication configuration for the IPSC server"`
+	Name                   string               `name:"name" description:"Name of the IPSC server in logs. Required for each server after the first when ipsc is a list"`
+	Networks               []string             `name:"networks" description:"Names of the MMDVM networks the IPSC server exchanges calls with. All of them when empty"`
+	DefaultNetwork         string               `name:"default-network" description:"Name of the MMDVM network that gets calls matching no network's rewrite rules, unchanged. They are dropped when empty"`
+	Role                   IPSCRole             `name:"role" description:"Part played in the IPSC network. One of master, or peer to register with the master at master-address" default:"master"`
+	MasterAddress          string               `name:"master-address" description:"host:port of the IPSC master to register with in the peer role"`
+	Interface              string               `name:"interface" description:"Interface to listen for IPSC packets on"`
+	Port                   uint16               `name:"port" description:"Port to listen for IPSC packets on"`
+	IP                     string               `name:"ip" description:"IP address to listen for IPSC packets on" default:"10.10.250.1"`
+	SubnetMask             int                  `name:"subnet-mask" description:"Subnet mask for the virtual network interface created for IPSC packets" default:"24"`
+	BindOnly               bool                 `name:"bind-only" description:"Skip interface configuration and only bind to the IP address, which must already be assigned to the interface"`
+	ExistingInterface      bool                 `name:"existing-interface" description:"Require the interface to exist already instead of creating it as a dummy interface, deleted again on shutdown, when it is missing"`
+	DSCP                   uint8                `name:"dscp" description:"DSCP code point marked on the packets sent to IPSC peers, such as 46 for expedited forwarding. Zero leaves them unmarked"`
+	BindAddress            string               `name:"bind-address" description:"IP address to listen on, such as 0.0.0.0, instead of ip. When set, no interface is configured, and interface, ip, and subnet-mask are not needed"`
+	SwapSlots              bool                 `name:"swap-slots" description:"Exchange TS1 and TS2 between the IPSC and MMDVM sides, for repeaters that carry network traffic on the opposite slot"`
+	PeerTimeout            uint                 `name:"peer-timeout" description:"Seconds without hearing from a peer before it is dropped and its calls are ended. Zero keeps peers forever" default:"60"`
+	ProbeInterval          uint                 `name:"probe-interval" description:"Seconds between alive probes sent to each registered peer. A peer that leaves probes unanswered for the peer timeout is dropped. Zero sends none"`
+	RepeatToPeers          bool                 `name:"repeat-to-peers" description:"Repeat voice and data from each peer to the other registered peers, so repeaters hear each other as well as the MMDVM masters. Only in the master role"`
+	MaxPeers               uint                 `name:"max-peers" description:"Peers registered at once. Registrations from further peer IDs are refused until one leaves or times out. Zero is unlimited"`
+	AllowedPeers           []uint32             `name:"allowed-peers" description:"Peer IDs allowed to register. Any peer not denied may register when empty"`
+	DeniedPeers            []uint32             `name:"denied-peers" description:"Peer IDs refused registration and traffic, even if allowed"`
+	AddressTakeover        AddressTakeover      `name:"address-takeover" description:"When a registered peer ID may move to a new address. One of immediate, stale, or authenticated" default:"immediate"`
+	AddressStaleAfter      uint                 `name:"address-stale-after" description:"Seconds without hearing from a peer's address before the stale address takeover policy lets its ID move" default:"30"`
+	AllowUnregistered      bool                 `name:"allow-unregistered" description:"Answer keepalives and peer list requests from peers that have not registered, for repeaters that skip registration after a reboot"`
+	IgnorePeerCapabilities bool                 `name:"ignore-peer-capabilities" description:"Send all traffic to every peer regardless of the mode and flags it advertised at registration"`
+	Auth                   IPSCAuth             `name:"auth" description:"Authentication configuration for the IPSC server"`
+	ARS                    IPSCARS              `name:"ars" description:"Handling of ARS registrations from radios"`
+	RadioCheck             IPSCRadioCheck       `name:"radio-check" description:"Handling of radio check requests"`
+	RemoteCommands         IPSCRemoteCommands   `name:"remote-commands" description:"Handling of radio stun, revive, and kill commands"`
+	RTP                    IPSCRTP              `name:"rtp" description:"RTP header values used in packets sent to IPSC peers"`
+	ReverseChannel         ReverseChannelPolicy `name:"reverse-channel" description:"What to do with reverse-channel (transmit interrupt) bursts. One of forward or drop" default:"forward"`
+	BusyPolicy             BusyPolicy           `name:"busy-policy" description:"What to do with a call toward the repeater on a slot already carrying one. One of buffer, reject, or queue" default:"buffer"`
+	BusyQueueTimeout       uint                 `name:"busy-queue-timeout" description:"Seconds the queue busy policy holds a waiting call before rejecting it" default:"10"`
+	WakeUpIdle             uint                 `name:"wake-up-idle" description:"Seconds without traffic toward the repeater after which a call starts with a repeater wake-up packet. Zero never sends one" default:"0"`
+	HeaderRepeats          uint                 `name:"header-repeats" description:"Copies of each voice header sent to IPSC peers, from 1 to 5" default:"3"`
+	MaxStreams             uint                 `name:"max-streams" description:"Calls from IPSC peers tracked at once. Past it, the least recently active call is dropped" default:"64"`
+	PeerListMaxPayload     uint                 `name:"peer-list-max-payload" description:"Largest UDP payload of a peer list reply, in bytes. Longer peer lists are split over several replies" default:"1400"`
+	ReceiveWorkers         uint                 `name:"receive-workers" description:"Goroutines handling received packets. Packets from one address are always handled by the same one, in order" default:"4"`
+	CallMonitor            IPSCCallMonitor      `name:"call-monitor" description:"Repeater call monitoring (RCM) packets for RDAC and other monitoring tools"`
+	Capabilities           IPSCCapabilities     `name:"capabilities" description:"Mode and capability flags advertised to peers when registering and in keepalives"`
+	RateLimit              IPSCRateLimit        `name:"rate-limit" description:"Limit on the packets accepted from each source address"`
+	Capture                IPSCCapture          `name:"capture" description:"Capture of every IPSC packet sent and received, for diagnosing interoperability problems"`
+}
+
+// Bridge links two IPSC systems back-to-back. When enabled, the MMDVM and
+// IPSC sections are ignored and the bridge acts as master for both sides.
+type Bridge struct {
+	Enabled bool   `name:"enabled" description:"Run in bridge mode, forwarding calls between two IPSC systems instead of to MMDVM"`
+	PeerID  uint32 `name:"peer-id" description:"IPSC peer ID the bridge uses on both sides"`
+	A       IPSC   `name:"a" description:"Configuration for the first IPSC side"`
+	B       IPSC   `name:"b" description:"Configuration for the second IPSC side"`
+
+	// Rewrite rules applied between the two sides. Forward rules apply
+	// from side A to side B and reverse rules from side B to side A, in the
+	// same way as the RF→Net and Net→RF directions of an MMDVM network.
+	// With no rules configured, all traffic passes unchanged.
+	TGRewrites   []TGRewriteConfig   `name:"tg-rewrite" description:"Talkgroup rewrite rules"`
+	PCRewrites   []PCRewriteConfig   `name:"pc-rewrite" description:"Private call rewrite rules"`
+	TypeRewrites []TypeRewriteConfig `name:"type-rewrite" description:"Type rewrite rules (group TG to private call)"`
+	SrcRewrites  []SrcRewriteConfig  `name:"src-rewrite" description:"Source rewrite rules (private call by source to group TG)"`
+	PassAllPC    []int               `name:"pass-all-pc" description:"Timeslots on which all private calls pass through unchanged (e.g. [1, 2])"`
+	PassAllTG    []int               `name:"pass-all-tg" description:"Timeslots on which all group calls pass through unchanged (e.g. [1, 2])"`
 }
 
 type IPSCAuth struct {
-	Enabled bool   `name:"enabled" description:"Whether to require authentication for IPSC clients"`
-	Key     string `name:"key" description:"Authentication key for IPSC clients. Required if auth is enabled"`
+	Enabled        bool          `name:"enabled" description:"Whether to require authentication for IPSC clients"`
+	Key            string        `name:"key" description:"Authentication key for IPSC clients. Required if auth is enabled, unless every peer has a key of its own"`
+	PeerKeys       []IPSCPeerKey `name:"peer-keys" description:"Authentication keys of individual peers, used in place of key"`
+	RequirePeerKey bool          `name:"require-peer-key" description:"Refuse peers without a key in peer-keys instead of falling back to key"`
+	BanThreshold   uint          `name:"ban-threshold" description:"Authentication failures from an address within ban-duration seconds of each other before its packets are ignored. Zero never ignores an address" default:"10"`
+	BanDuration    uint          `name:"ban-duration" description:"Seconds an address is ignored after too many authentication failures, and the window in which its failures are counted" default:"60"`
+	KeyGrace       uint          `name:"key-grace" description:"Seconds the previous key is still accepted after the key is changed at runtime, so peers can be re-keyed one at a time"`
+}
+
+// IPSCPeerKey is the authentication key of one IPSC peer.
+type IPSCPeerKey struct {
+	PeerID uint32 `name:"peer-id" description:"ID of the peer"`
+	Key    string `name:"key" description:"Hex authentication key of the peer (up to 40 chars)"`
+}
+
+// ARSPolicy is what the IPSC server does with ARS registrations.
+type ARSPolicy string
+
+const (
+	// ARSPolicyForward passes registrations to the masters like any other
+	// data call.
+	ARSPolicyForward ARSPolicy = "forward"
+	// ARSPolicyDrop discards registrations. Radios keep retrying.
+	ARSPolicyDrop ARSPolicy = "drop"
+	// ARSPolicyAckLocally discards registrations and acknowledges them
+	// toward the radio so it stops retrying.
+	ARSPolicyAckLocally ARSPolicy = "ack-locally"
+)
+
+// IPSCARS configures how ARS (automatic registration service) data calls,
+// which Mototrbo radios send to a configured ID on power-up, are handled.
+type IPSCARS struct {
+	Policy ARSPolicy `name:"policy" description:"What to do with ARS registrations. One of forward, drop, or ack-locally" default:"forward"`
+	ID     uint32    `name:"id" description:"Radio ID that radios send ARS registrations to. Required unless the policy is forward"`
+}
+
+// RTPSSRCMode selects how the RTP SSRC of packets sent to IPSC peers is
+// chosen.
+type RTPSSRCMode string
+
+const (
+	// RTPSSRCFixed uses the configured SSRC for every call.
+	RTPSSRCFixed RTPSSRCMode = "fixed"
+	// RTPSSRCPeerID uses the local peer ID.
+	RTPSSRCPeerID RTPSSRCMode = "peer-id"
+	// RTPSSRCRandom picks a new random SSRC for each call.
+	RTPSSRCRandom RTPSSRCMode = "random"
+	// RTPSSRCPerStream numbers the calls from the configured SSRC up, so
+	// calls in progress at once never share one.
+	RTPSSRCPerStream RTPSSRCMode = "per-stream"
+)
+
+// IPSCRTP configures the RTP header of packets sent to IPSC peers. The
+// defaults match what common Mototrbo firmware expects; other firmware
+// versions may need different values.
+type IPSCRTP struct {
+	PayloadType           uint8       `name:"payload-type" description:"RTP payload type of voice headers, voice bursts, and data (0-127)" default:"93"`
+	TerminatorPayloadType uint8       `name:"terminator-payload-type" description:"RTP payload type of voice terminators (0-127)" default:"94"`
+	SSRCMode              RTPSSRCMode `name:"ssrc-mode" description:"How the RTP SSRC is chosen. One of fixed, peer-id, random, or per-stream" default:"fixed"`
+	SSRC                  uint32      `name:"ssrc" description:"RTP SSRC used when ssrc-mode is fixed, and the first one when it is per-stream" default:"0"`
+}
+
+// IPSCRole is the part ipsc2mmdvm plays in the IPSC network.
+type IPSCRole string
+
+const (
+	// IPSCRoleMaster has repeaters register with ipsc2mmdvm.
+	IPSCRoleMaster IPSCRole = "master"
+	// IPSCRolePeer registers ipsc2mmdvm with an existing master, as a
+	// repeater would.
+	IPSCRolePeer IPSCRole = "peer"
+)
+
+// ReverseChannelPolicy is what happens to reverse-channel bursts, which
+// carry transmit interrupt requests during a call.
+type ReverseChannelPolicy string
+
+const (
+	// ReverseChannelForward carries them across as part of the call they
+	// interrupt.
+	ReverseChannelForward ReverseChannelPolicy = "forward"
+	// ReverseChannelDrop discards and counts them, for far sides that
+	// cannot represent them.
+	ReverseChannelDrop ReverseChannelPolicy = "drop"
+)
+
+// AddressTakeover is when a peer ID registered at one address may be
+// taken over from another, as when a repeater's NAT mapping changes.
+type AddressTakeover string
+
+const (
+	// AddressTakeoverImmediate moves the peer to any new address at once.
+	AddressTakeoverImmediate AddressTakeover = "immediate"
+	// AddressTakeoverStale moves it only once the old address has not
+	// been heard from for address-stale-after seconds.
+	AddressTakeoverStale AddressTakeover = "stale"
+	// AddressTakeoverAuthenticated moves it only for authenticated
+	// packets, and needs authentication enabled.
+	AddressTakeoverAuthenticated AddressTakeover = "authenticated"
+)
+
+// BusyPolicy is what happens to a call toward the repeater that arrives
+// on a slot already carrying another call.
+type BusyPolicy string
+
+const (
+	// BusyPolicyBuffer buffers waiting calls in full and plays them out
+	// one after another.
+	BusyPolicyBuffer BusyPolicy = "buffer"
+	// BusyPolicyReject drops and counts the waiting call.
+	BusyPolicyReject BusyPolicy = "reject"
+	// BusyPolicyQueue holds one waiting call and starts it live when the
+	// slot frees, rejecting it if the wait is too long.
+	BusyPolicyQueue BusyPolicy = "queue"
+)
+
+// IPSCRadioCheck configures which radio checks the IPSC server answers
+// itself instead of forwarding them to the masters.
+type IPSCRadioCheck struct {
+	LocalIDs []uint32 `name:"local-ids" description:"Radio IDs whose radio checks are acknowledged locally, such as the bridge's own ID"`
+}
+
+// IPSCRemoteCommands configures whether the stun, revive, and kill
+// commands that disable radios over the air are carried. Every command
+// seen is written to the audit log either way.
+type IPSCRemoteCommands struct {
+	Policy            RemoteCommandPolicy `name:"policy" description:"What to do with remote radio commands. One of block or allow" default:"block"`
+	AuthorizedSources []uint32            `name:"authorized-sources" description:"Radio IDs whose commands are carried under the block policy"`
+}
+
+// RemoteCommandPolicy is what happens to remote radio commands.
+type RemoteCommandPolicy string
+
+const (
+	// RemoteCommandBlock drops commands unless they come from an
+	// authorized source.
+	RemoteCommandBlock RemoteCommandPolicy = "block"
+	// RemoteCommandAllow carries every command.
+	RemoteCommandAllow RemoteCommandPolicy = "allow"
+)
+
+// IPSCCallMonitor configures repeater call monitoring. Call monitoring
+// packets from peers are always accepted and recorded; sending them is
+// optional.
+type IPSCCallMonitor struct {
+	Send bool `name:"send" description:"Send call start and end packets to peers that registered for repeater call monitoring"`
+}
+
+// IPSCCapabilities selects what the server advertises it can carry in the
+// mode byte and flags of its registration and keepalive packets. Some
+// peers only send what the other side advertises. Left unset, both
+// timeslots, voice, and data calls are advertised.
+type IPSCCapabilities struct {
+	Slots       byte `name:"slots" description:"Timeslots advertised as active bitmask (1=TS1, 2=TS2, 3=both)" default:"3"`
+	VoiceOnly   bool `name:"voice-only" description:"Advertise voice calls only, without data call support"`
+	CSBK        bool `name:"csbk" description:"Advertise CSBK support"`
+	CallMonitor bool `name:"call-monitor" description:"Advertise repeater call monitoring, so peers send their call monitoring packets"`
+}
+
+// IPSCCapture configures capturing IPSC traffic to files. Each file is
+// named after path with the time it was started added, and a new one is
+// started once it reaches max-file-size.
+type IPSCCapture struct {
+	Path         string        `name:"path" description:"Capture file path, such as /var/log/ipsc2mmdvm/ipsc.pcapng. Capturing is off when empty"`
+	Format       CaptureFormat `name:"format" description:"Capture file format. One of pcapng, or jsonl for one JSON object per packet with its bytes in hex" default:"pcapng"`
+	MaxFileSize  uint          `name:"max-file-size" description:"Bytes a capture file grows to before another is started" default:"10485760"`
+	MaxTotalSize uint          `name:"max-total-size" description:"Bytes all capture files may take up together. The oldest are deleted to stay under it" default:"104857600"`
+}
+
+// CaptureFormat is the file format of IPSC captures.
+type CaptureFormat string
+
+const (
+	// CaptureFormatPcapng writes pcapng files Wireshark can open, with
+	// each packet's peer ID and verdict in its comment.
+	CaptureFormatPcapng CaptureFormat = "pcapng"
+	// CaptureFormatJSONL writes one JSON object per packet and line.
+	CaptureFormatJSONL CaptureFormat = "jsonl"
+)
+
+// IPSCRateLimit limits the packets accepted from each source IP address,
+// so one device cannot flood the server. Voice and data from registered
+// peers are not limited.
+type IPSCRateLimit struct {
+	Rate  uint `name:"rate" description:"Packets per second accepted from each source address. Zero disables the limit" default:"50"`
+	Burst uint `name:"burst" description:"Packets a source address may send at once above the rate" default:"100"`
 }
 
 type MMDVM struct {
@@ -67,6 +389,24 @@ type MMDVM struct {
 	Slots        byte   `name:"slots" description:"Active timeslots bitmask (1=TS1, 2=TS2, 3=both)" default:"3"`
 	MasterServer string `name:"master-server" description:"Master server for the MMDVM connection"`
 	Password     string `name:"password" description:"Password for the MMDVM connection"`
+	DSCP         uint8  `name:"dscp" description:"DSCP code point marked on the packets sent to the master, such as 46 for expedited forwarding. Zero leaves them unmarked"`
+	// BindAddress is ip or ip:port; see LocalAddr.
+	BindAddress string `name:"bind-address" description:"Local address, ip or ip:port, to send to the master from, for hosts with several addresses. The kernel picks when empty"`
+	// Options is sent to the master as is once it accepts the
+	// configuration, for masters that take static talkgroups and such.
+	Options string `name:"options" description:"Options string sent to the master after the configuration, such as TS2_1=3100;TS2_2=91 for DMRGateway-style masters. Empty sends none"`
+	// MaxMissedPongs is how many pings in a row may go unanswered before
+	// the master is considered lost.
+	MaxMissedPongs uint `name:"max-missed-pongs" description:"Pings in a row the master may leave unanswered before the client reconnects" default:"3"`
+	// KeepAlive and Timeout are in seconds; see KeepAliveInterval and
+	// SessionTimeout.
+	KeepAlive uint `name:"keepalive" description:"Seconds between pings to the master" default:"5"`
+	Timeout   uint `name:"timeout" description:"Seconds without a pong, or to complete the login, before the client reconnects. Must be longer than keepalive" default:"15"`
+
+	// Masters is an ordered list of master servers for hot-standby failover.
+	// The first entry is the primary. When set, it takes precedence over MasterServer.
+	Masters  []string       `name:"masters" description:"Ordered list of master servers for hot-standby failover (overrides master-server)"`
+	Failover FailoverConfig `name:"failover" description:"Failover policy used when multiple masters are configured"`
 
 	// Rewrite rules for routing DMR data to/from this network.
 	TGRewrites   []TGRewriteConfig   `name:"tg-rewrite" description:"Talkgroup rewrite rules"`
@@ -79,6 +419,68 @@ type MMDVM struct {
 	PassAllTG []int `name:"pass-all-tg" description:"Timeslots on which all group calls pass through unchanged (e.g. [1, 2])"`
 }
 
+// FailoverConfig controls switching between the masters of one MMDVM network.
+type FailoverConfig struct {
+	FailbackInterval uint `name:"failback-interval" description:"Seconds between probes of a higher-priority master while failed over" default:"60"`
+	MinHold          uint `name:"min-hold" description:"Minimum seconds to stay on a master before failing back" default:"120"`
+	NAKThreshold     uint `name:"nak-threshold" description:"Consecutive NAKs from a master before failing over" default:"3"`
+}
+
+// MasterServers returns the ordered list of masters for this network,
+// falling back to MasterServer when Masters is not set.
+func (m *MMDVM) MasterServers() []string {
+	if len(m.Masters) > 0 {
+		return m.Masters
+	}
+	return []string{m.MasterServer}
+}
+
+// Defaults for the MMDVM keepalive and timeout, used when the config
+// leaves them unset, as in list entries, which get no defaults.
+const (
+	DefaultMMDVMKeepAlive = 5 * time.Second
+	DefaultMMDVMTimeout   = 15 * time.Second
+)
+
+// KeepAliveInterval returns the time between pings to the master.
+func (m *MMDVM) KeepAliveInterval() time.Duration {
+	if m.KeepAlive == 0 {
+		return DefaultMMDVMKeepAlive
+	}
+	return time.Duration(m.KeepAlive) * time.Second
+}
+
+// SessionTimeout returns how long the master may go without answering a
+// ping, or the login take, before the client reconnects.
+func (m *MMDVM) SessionTimeout() time.Duration {
+	if m.Timeout == 0 {
+		return DefaultMMDVMTimeout
+	}
+	return time.Duration(m.Timeout) * time.Second
+}
+
+// LocalAddr returns the local address to send to the master from, or nil
+// to let the kernel pick. A bind address without a port leaves the port to
+// the kernel.
+func (m *MMDVM) LocalAddr() (*net.UDPAddr, error) {
+	if m.BindAddress == "" {
+		return nil, nil
+	}
+	host, port := m.BindAddress, "0"
+	if h, p, err := net.SplitHostPort(m.BindAddress); err == nil {
+		host, port = h, p
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidMMDVMBindAddress, m.BindAddress)
+	}
+	n, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidMMDVMBindAddress, m.BindAddress)
+	}
+	return &net.UDPAddr{IP: ip, Port: int(n)}, nil
+}
+
 // TGRewriteConfig maps group TG calls from one slot/TG to another.
 // Modeled after DMRGateway's TGRewrite: fromSlot, fromTG, toSlot, toTG, range.
 type TGRewriteConfig struct {
@@ -119,23 +521,62 @@ type SrcRewriteConfig struct {
 }
 
 var (
-	ErrInvalidLogLevel          = errors.New("invalid log level provided")
-	ErrNoMMDVMNetworks          = errors.New("at least one MMDVM network must be configured")
-	ErrInvalidMMDVMName         = errors.New("invalid MMDVM network name provided")
-	ErrDuplicateMMDVMName       = errors.New("duplicate MMDVM network name provided")
-	ErrInvalidMMDVMCallsign     = errors.New("invalid MMDVM callsign provided")
-	ErrInvalidMMDVMColorCode    = errors.New("invalid MMDVM color code provided")
-	ErrInvalidMMDVMLongitude    = errors.New("invalid MMDVM longitude provided")
-	ErrInvalidMMDVMLatitude     = errors.New("invalid MMDVM latitude provided")
-	ErrInvalidMMDVMMasterServer = errors.New("invalid MMDVM master server provided")
-	ErrInvalidMMDVMPassword     = errors.New("invalid MMDVM password provided")
-	ErrInvalidRewriteSlot       = errors.New("invalid rewrite slot (must be 1 or 2)")
-	ErrInvalidRewriteRange      = errors.New("invalid rewrite range (must be >= 1)")
-	ErrInvalidIPSCInterface     = errors.New("invalid IPSC interface provided")
-	ErrInvalidIPSCIP            = errors.New("invalid IPSC IP address provided")
-	ErrInvalidIPSCSubnetMask    = errors.New("invalid IPSC subnet mask provided")
-	ErrInvalidIPSCAuthKey       = errors.New("invalid IPSC authentication key provided")
-	ErrInvalidMetricsAddress    = errors.New("invalid metrics address provided")
+	ErrInvalidLogLevel           = errors.New("invalid log level provided")
+	ErrNoMMDVMNetworks           = errors.New("at least one MMDVM network must be configured")
+	ErrInvalidMMDVMName          = errors.New("invalid MMDVM network name provided")
+	ErrDuplicateMMDVMName        = errors.New("duplicate MMDVM network name provided")
+	ErrInvalidMMDVMCallsign      = errors.New("invalid MMDVM callsign provided")
+	ErrInvalidMMDVMColorCode     = errors.New("invalid MMDVM color code provided")
+	ErrInvalidMMDVMLongitude     = errors.New("invalid MMDVM longitude provided")
+	ErrInvalidMMDVMLatitude      = errors.New("invalid MMDVM latitude provided")
+	ErrInvalidMMDVMMasterServer  = errors.New("invalid MMDVM master server provided")
+	ErrInvalidMMDVMPassword      = errors.New("invalid MMDVM password provided")
+	ErrInvalidMMDVMTimeout       = errors.New("MMDVM timeout must be longer than the keepalive")
+	ErrInvalidMMDVMBindAddress   = errors.New("invalid MMDVM bind address provided")
+	ErrInvalidRewriteSlot        = errors.New("invalid rewrite slot (must be 1 or 2)")
+	ErrInvalidRewriteRange       = errors.New("invalid rewrite range (must be >= 1)")
+	ErrInvalidIPSCInterface      = errors.New("invalid IPSC interface provided")
+	ErrInvalidIPSCIP             = errors.New("invalid IPSC IP address provided")
+	ErrInvalidIPSCBindAddress    = errors.New("invalid IPSC bind address provided")
+	ErrInvalidIPSCSubnetMask     = errors.New("invalid IPSC subnet mask provided")
+	ErrInvalidIPSCAuthKey        = errors.New("invalid IPSC authentication key provided")
+	ErrInvalidIPSCPeerKey        = errors.New("IPSC peer keys need a peer ID, a valid key, and one entry per peer")
+	ErrInvalidIPSCRole           = errors.New("invalid IPSC role provided")
+	ErrInvalidIPSCMasterAddress  = errors.New("invalid IPSC master address provided")
+	ErrInvalidDSCP               = errors.New("DSCP code points must be between 0 and 63")
+	ErrInvalidIPSCPeerID         = errors.New("IPSC allowed and denied peer IDs must not be zero")
+	ErrInvalidARSPolicy          = errors.New("invalid ARS policy provided")
+	ErrInvalidARSID              = errors.New("an ARS ID is required unless the ARS policy is forward")
+	ErrInvalidRadioCheckID       = errors.New("radio check local IDs must be between 1 and 16777215")
+	ErrInvalidParrotID           = errors.New("parrot ID must be between 1 and 16777215")
+	ErrInvalidParrotMaxDuration  = errors.New("parrot max duration must be greater than zero")
+	ErrInvalidRTPPayloadType     = errors.New("RTP payload types must be between 0 and 127")
+	ErrInvalidRTPSSRCMode        = errors.New("invalid RTP SSRC mode provided")
+	ErrInvalidReverseChannel     = errors.New("invalid reverse channel policy provided")
+	ErrInvalidAddressTakeover    = errors.New("invalid address takeover policy provided")
+	ErrInvalidCapture            = errors.New("IPSC capture needs a valid format and a max file size no larger than the max total size")
+	ErrInvalidBusyPolicy         = errors.New("invalid busy policy provided")
+	ErrInvalidBusyQueueTimeout   = errors.New("busy queue timeout must be greater than 0 with the queue busy policy")
+	ErrInvalidHeaderRepeats      = errors.New("header repeats must be between 1 and 5")
+	ErrInvalidPeerListMaxPayload = errors.New("peer list max payload must be between 28 and 65507 bytes")
+	ErrInvalidAdvertisedSlots    = errors.New("advertised IPSC slots must be 1, 2, or 3")
+	ErrInvalidRemoteCommands     = errors.New("invalid remote command policy provided")
+	ErrInvalidRemoteCommandID    = errors.New("remote command authorized sources must be between 1 and 16777215")
+	ErrInvalidSpecialIDRange     = errors.New("special ID rules need a from-id between 1 and 16777215 and a to-id no lower than it")
+	ErrInvalidSpecialIDAction    = errors.New("invalid special ID action provided")
+	ErrInvalidSpecialIDDirection = errors.New("invalid special ID direction provided")
+	ErrInvalidSpecialIDHandler   = errors.New("local special ID rules need an enabled handler")
+	ErrInvalidMetricsAddress     = errors.New("invalid metrics address provided")
+	ErrInvalidHealthAddress      = errors.New("invalid health address provided")
+	ErrInvalidBridgePeerID       = errors.New("invalid bridge peer ID provided")
+	ErrDuplicateBridgeEndpoint   = errors.New("bridge sides must listen on different addresses")
+	ErrServiceUnsupported        = errors.New("service mode is only supported on Windows")
+	ErrInvalidIPSCName           = errors.New("IPSC servers after the first need a unique name")
+	ErrInvalidIPSCSection        = errors.New("the ipsc section must be an IPSC server or a list of them")
+	ErrIPSCSettingNotShared      = errors.New("swap-slots, rtp, reverse-channel, remote-commands, busy-policy, busy-queue-timeout, wake-up-idle, header-repeats, and max-streams apply to every IPSC server and are only set on the first")
+	ErrDuplicateIPSCListener     = errors.New("IPSC servers must listen on different interface and port pairs")
+	ErrUnknownIPSCNetwork        = errors.New("IPSC networks must name configured MMDVM networks")
+	ErrUnknownDefaultNetwork     = errors.New("the IPSC default network must name an MMDVM network the server is routed to")
 )
 
 func (c Config) Validate() error {
@@ -145,6 +586,10 @@ func (c Config) Validate() error {
 		return ErrInvalidLogLevel
 	}
 
+	if c.Service && runtime.GOOS != "windows" {
+		return ErrServiceUnsupported
+	}
+
 	if c.Metrics.Enabled && c.Metrics.Address != "" {
 		_, _, err := net.SplitHostPort(c.Metrics.Address)
 		if err != nil {
@@ -152,6 +597,16 @@ func (c Config) Validate() error {
 		}
 	}
 
+	if c.Health.Enabled {
+		if _, _, err := net.SplitHostPort(c.Health.Address); err != nil {
+			return ErrInvalidHealthAddress
+		}
+	}
+
+	if c.Bridge.Enabled {
+		return c.Bridge.validate()
+	}
+
 	if len(c.MMDVM) == 0 {
 		return ErrNoMMDVMNetworks
 	}
@@ -160,71 +615,356 @@ func (c Config) Validate() error {
 	for i := range c.MMDVM {
 		h := &c.MMDVM[i]
 
-		// Default name to "Network N" if empty
-		if h.Name == "" {
-			return ErrInvalidMMDVMName
-		}
-
 		if _, ok := names[h.Name]; ok {
 			return ErrDuplicateMMDVMName
 		}
 		names[h.Name] = struct{}{}
 
-		if h.Callsign == "" {
-			return ErrInvalidMMDVMCallsign
+		if err := h.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Parrot.Enabled {
+		if c.Parrot.ID == 0 || c.Parrot.ID > 0xFFFFFF {
+			return ErrInvalidParrotID
+		}
+		if c.Parrot.MaxDuration == 0 {
+			return ErrInvalidParrotMaxDuration
+		}
+	}
+
+	for _, rule := range c.SpecialIDs {
+		if err := c.validateSpecialID(rule); err != nil {
+			return err
 		}
+	}
+
+	return c.validateIPSCServers(names)
+}
 
-		if h.ColorCode > 15 {
-			return ErrInvalidMMDVMColorCode
+// validateIPSCServers checks each IPSC server, that those after the first
+// share its MMDVM-side settings, and that no two of them listen in the
+// same place. networks are the names of the MMDVM networks.
+func (c Config) validateIPSCServers(networks map[string]struct{}) error {
+	servers := c.ipscServers()
+	instanceNames := make(map[string]struct{}, len(servers))
+	listeners := make(map[string]struct{}, len(servers))
+	for i, ipsc := range servers {
+		if ipsc.Name != "" {
+			if _, dup := instanceNames[ipsc.Name]; dup {
+				return ErrInvalidIPSCName
+			}
+			instanceNames[ipsc.Name] = struct{}{}
+		} else if i > 0 {
+			return ErrInvalidIPSCName
 		}
 
-		if h.Longitude < -180 || h.Longitude > 180 {
-			return ErrInvalidMMDVMLongitude
+		if err := validateIPSC(&ipsc); err != nil {
+			if i == 0 {
+				return err
+			}
+			return fmt.Errorf("IPSC server %s: %w", ipsc.Name, err)
+		}
+		if i > 0 && !reflect.DeepEqual(ipsc.shared(), servers[0].shared()) {
+			return fmt.Errorf("IPSC server %s: %w", ipsc.Name, ErrIPSCSettingNotShared)
 		}
 
-		if h.Latitude < -90 || h.Latitude > 90 {
-			return ErrInvalidMMDVMLatitude
+		for _, network := range ipsc.Networks {
+			if _, ok := networks[network]; !ok {
+				return ErrUnknownIPSCNetwork
+			}
+		}
+		if ipsc.DefaultNetwork != "" {
+			if _, ok := networks[ipsc.DefaultNetwork]; !ok {
+				return ErrUnknownDefaultNetwork
+			}
+			if len(ipsc.Networks) > 0 && !slices.Contains(ipsc.Networks, ipsc.DefaultNetwork) {
+				return ErrUnknownDefaultNetwork
+			}
 		}
 
-		if h.MasterServer == "" {
-			return ErrInvalidMMDVMMasterServer
+		listener := ipscListener(&ipsc)
+		if _, dup := listeners[listener]; dup {
+			return ErrDuplicateIPSCListener
 		}
+		listeners[listener] = struct{}{}
+	}
+	return nil
+}
+
+// ipscListener identifies where an IPSC server listens: its bind address,
+// or else its interface, and its port.
+func ipscListener(ipsc *IPSC) string {
+	host := ipsc.Interface
+	if ipsc.BindAddress != "" {
+		host = ipsc.BindAddress
+	}
+	return net.JoinHostPort(host, strconv.FormatUint(uint64(ipsc.Port), 10))
+}
+
+// IPSCConfigs returns a config for each IPSC server to run, in the order
+// of the ipsc section, with IPSC set to it.
+func (c *Config) IPSCConfigs() []*Config {
+	servers := c.ipscServers()
+	configs := make([]*Config, 0, len(servers))
+	for _, ipsc := range servers {
+		instance := *c
+		instance.IPSC = ipsc
+		configs = append(configs, &instance)
+	}
+	return configs
+}
 
-		if h.Password == "" {
-			return ErrInvalidMMDVMPassword
+func (c Config) validateSpecialID(rule SpecialID) error {
+	if rule.FromID == 0 || rule.FromID > 0xFFFFFF || rule.ToID > 0xFFFFFF || (rule.ToID != 0 && rule.ToID < rule.FromID) {
+		return ErrInvalidSpecialIDRange
+	}
+	switch rule.Direction {
+	case "", SpecialIDDirectionBoth, SpecialIDDirectionToIPSC, SpecialIDDirectionToMaster:
+	default:
+		return ErrInvalidSpecialIDDirection
+	}
+	switch rule.Action {
+	case SpecialIDActionBlock, SpecialIDActionPass:
+	case SpecialIDActionLocal:
+		if rule.Handler != SpecialIDHandlerParrot || !c.Parrot.Enabled {
+			return ErrInvalidSpecialIDHandler
 		}
+	default:
+		return ErrInvalidSpecialIDAction
+	}
+	return nil
+}
 
-		if err := validateRewrites(h); err != nil {
-			return err
+// Validate checks the settings of a single MMDVM network. Name
+// uniqueness across networks is checked by Config.Validate.
+func (h *MMDVM) Validate() error {
+	if h.Name == "" {
+		return ErrInvalidMMDVMName
+	}
+
+	if h.Callsign == "" {
+		return ErrInvalidMMDVMCallsign
+	}
+
+	if h.ColorCode > 15 {
+		return ErrInvalidMMDVMColorCode
+	}
+
+	if h.Longitude < -180 || h.Longitude > 180 {
+		return ErrInvalidMMDVMLongitude
+	}
+
+	if h.Latitude < -90 || h.Latitude > 90 {
+		return ErrInvalidMMDVMLatitude
+	}
+
+	for _, master := range h.MasterServers() {
+		if master == "" {
+			return ErrInvalidMMDVMMasterServer
 		}
 	}
 
-	if c.IPSC.Interface == "" {
-		return ErrInvalidIPSCInterface
+	if h.Password == "" {
+		return ErrInvalidMMDVMPassword
 	}
 
-	_, err := netlink.LinkByName(c.IPSC.Interface)
-	if err != nil {
+	if h.DSCP > dscp.MaxCode {
+		return ErrInvalidDSCP
+	}
+
+	if h.SessionTimeout() <= h.KeepAliveInterval() {
+		return ErrInvalidMMDVMTimeout
+	}
+
+	if _, err := h.LocalAddr(); err != nil {
+		return err
+	}
+
+	return validateRewrites(h.TGRewrites, h.PCRewrites, h.TypeRewrites, h.SrcRewrites)
+}
+
+func (b *Bridge) validate() error {
+	if b.PeerID == 0 {
+		return ErrInvalidBridgePeerID
+	}
+
+	if err := validateIPSC(&b.A); err != nil {
+		return fmt.Errorf("bridge side a: %w", err)
+	}
+	if err := validateIPSC(&b.B); err != nil {
+		return fmt.Errorf("bridge side b: %w", err)
+	}
+
+	if b.A.IP == b.B.IP && b.A.Port == b.B.Port {
+		return ErrDuplicateBridgeEndpoint
+	}
+
+	return validateRewrites(b.TGRewrites, b.PCRewrites, b.TypeRewrites, b.SrcRewrites)
+}
+
+// validateIPSCInterface checks the settings of the interface the IPSC
+// server configures and listens on.
+func validateIPSCInterface(ipsc *IPSC) error {
+	if ipsc.Interface == "" {
 		return ErrInvalidIPSCInterface
 	}
 
-	if c.IPSC.IP == "" {
+	// A missing interface is created at startup, unless it is expected to
+	// be there already.
+	if ipsc.ExistingInterface || ipsc.BindOnly {
+		exists, err := netsetup.New().LinkExists(ipsc.Interface)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidIPSCInterface, err)
+		}
+		if !exists {
+			return ErrInvalidIPSCInterface
+		}
+	}
+
+	if ipsc.IP == "" {
 		return ErrInvalidIPSCIP
 	}
 
-	if c.IPSC.SubnetMask < 1 || c.IPSC.SubnetMask > 32 {
+	if ipsc.SubnetMask < 1 || ipsc.SubnetMask > 32 {
 		return ErrInvalidIPSCSubnetMask
 	}
+	return nil
+}
+
+func validateIPSC(ipsc *IPSC) error {
+	// A bind address takes the place of the interface settings.
+	if ipsc.BindAddress != "" {
+		if net.ParseIP(ipsc.BindAddress) == nil {
+			return ErrInvalidIPSCBindAddress
+		}
+	} else if err := validateIPSCInterface(ipsc); err != nil {
+		return err
+	}
 
-	if c.IPSC.Auth.Enabled && c.IPSC.Auth.Key == "" {
+	perPeerOnly := ipsc.Auth.RequirePeerKey && len(ipsc.Auth.PeerKeys) > 0
+	if ipsc.Auth.Enabled && ipsc.Auth.Key == "" && !perPeerOnly {
 		return ErrInvalidIPSCAuthKey
 	}
 
-	// Check authkey is [0-9a-fA-F]{0,40} if c.IPSC.Auth.Enabled {
+	// Check authkey is [0-9a-fA-F]{0,40} if ipsc.Auth.Enabled {
 	regexp := regexp.MustCompile(`^[0-9a-fA-F]{0,40}$`)
-	if !regexp.MatchString(c.IPSC.Auth.Key) {
+	if !regexp.MatchString(ipsc.Auth.Key) {
 		return ErrInvalidIPSCAuthKey
 	}
+	peerKeys := make(map[uint32]struct{}, len(ipsc.Auth.PeerKeys))
+	for _, pk := range ipsc.Auth.PeerKeys {
+		if _, dup := peerKeys[pk.PeerID]; dup || pk.PeerID == 0 || pk.Key == "" || !regexp.MatchString(pk.Key) {
+			return ErrInvalidIPSCPeerKey
+		}
+		peerKeys[pk.PeerID] = struct{}{}
+	}
+
+	if ipsc.DSCP > dscp.MaxCode {
+		return ErrInvalidDSCP
+	}
+
+	switch ipsc.Role {
+	case "", IPSCRoleMaster:
+	case IPSCRolePeer:
+		if _, _, err := net.SplitHostPort(ipsc.MasterAddress); err != nil {
+			return ErrInvalidIPSCMasterAddress
+		}
+	default:
+		return ErrInvalidIPSCRole
+	}
+
+	switch ipsc.ARS.Policy {
+	case "", ARSPolicyForward:
+	case ARSPolicyDrop, ARSPolicyAckLocally:
+		if ipsc.ARS.ID == 0 {
+			return ErrInvalidARSID
+		}
+	default:
+		return ErrInvalidARSPolicy
+	}
+
+	if ipsc.RTP.PayloadType > 127 || ipsc.RTP.TerminatorPayloadType > 127 {
+		return ErrInvalidRTPPayloadType
+	}
+	switch ipsc.RTP.SSRCMode {
+	case "", RTPSSRCFixed, RTPSSRCPeerID, RTPSSRCRandom, RTPSSRCPerStream:
+	default:
+		return ErrInvalidRTPSSRCMode
+	}
+
+	switch ipsc.ReverseChannel {
+	case "", ReverseChannelForward, ReverseChannelDrop:
+	default:
+		return ErrInvalidReverseChannel
+	}
+
+	switch ipsc.AddressTakeover {
+	case "", AddressTakeoverImmediate, AddressTakeoverStale:
+	case AddressTakeoverAuthenticated:
+		if !ipsc.Auth.Enabled {
+			return fmt.Errorf("%w: authenticated needs authentication enabled", ErrInvalidAddressTakeover)
+		}
+	default:
+		return ErrInvalidAddressTakeover
+	}
+
+	switch ipsc.BusyPolicy {
+	case "", BusyPolicyBuffer, BusyPolicyReject:
+	case BusyPolicyQueue:
+		if ipsc.BusyQueueTimeout == 0 {
+			return ErrInvalidBusyQueueTimeout
+		}
+	default:
+		return ErrInvalidBusyPolicy
+	}
+
+	if ipsc.HeaderRepeats > 5 {
+		return ErrInvalidHeaderRepeats
+	}
+
+	// Room for the reply header, one peer, and the digest, and no more
+	// than fits in a UDP datagram.
+	if ipsc.PeerListMaxPayload != 0 && (ipsc.PeerListMaxPayload < 28 || ipsc.PeerListMaxPayload > 65507) {
+		return ErrInvalidPeerListMaxPayload
+	}
+
+	if ipsc.Capabilities.Slots > 3 {
+		return ErrInvalidAdvertisedSlots
+	}
+
+	switch ipsc.Capture.Format {
+	case "", CaptureFormatPcapng, CaptureFormatJSONL:
+	default:
+		return ErrInvalidCapture
+	}
+	if ipsc.Capture.Path != "" && (ipsc.Capture.MaxFileSize == 0 || ipsc.Capture.MaxTotalSize < ipsc.Capture.MaxFileSize) {
+		return ErrInvalidCapture
+	}
+
+	switch ipsc.RemoteCommands.Policy {
+	case "", RemoteCommandBlock, RemoteCommandAllow:
+	default:
+		return ErrInvalidRemoteCommands
+	}
+	for _, id := range ipsc.RemoteCommands.AuthorizedSources {
+		if id == 0 || id > 0xFFFFFF {
+			return ErrInvalidRemoteCommandID
+		}
+	}
+
+	for _, id := range ipsc.RadioCheck.LocalIDs {
+		if id == 0 || id > 0xFFFFFF {
+			return ErrInvalidRadioCheckID
+		}
+	}
+
+	for _, ids := range [][]uint32{ipsc.AllowedPeers, ipsc.DeniedPeers} {
+		if slices.Contains(ids, 0) {
+			return ErrInvalidIPSCPeerID
+		}
+	}
 
 	return nil
 }
@@ -233,8 +973,8 @@ func validateSlot(slot uint) bool {
 	return slot == 1 || slot == 2
 }
 
-func validateRewrites(h *MMDVM) error {
-	for _, r := range h.TGRewrites {
+func validateRewrites(tg []TGRewriteConfig, pc []PCRewriteConfig, typ []TypeRewriteConfig, src []SrcRewriteConfig) error {
+	for _, r := range tg {
 		if !validateSlot(r.FromSlot) || !validateSlot(r.ToSlot) {
 			return ErrInvalidRewriteSlot
 		}
@@ -242,7 +982,7 @@ func validateRewrites(h *MMDVM) error {
 			return ErrInvalidRewriteRange
 		}
 	}
-	for _, r := range h.PCRewrites {
+	for _, r := range pc {
 		if !validateSlot(r.FromSlot) || !validateSlot(r.ToSlot) {
 			return ErrInvalidRewriteSlot
 		}
@@ -250,7 +990,7 @@ func validateRewrites(h *MMDVM) error {
 			return ErrInvalidRewriteRange
 		}
 	}
-	for _, r := range h.TypeRewrites {
+	for _, r := range typ {
 		if !validateSlot(r.FromSlot) || !validateSlot(r.ToSlot) {
 			return ErrInvalidRewriteSlot
 		}
@@ -258,7 +998,7 @@ func validateRewrites(h *MMDVM) error {
 			return ErrInvalidRewriteRange
 		}
 	}
-	for _, r := range h.SrcRewrites {
+	for _, r := range src {
 		if !validateSlot(r.FromSlot) || !validateSlot(r.ToSlot) {
 			return ErrInvalidRewriteSlot
 		}
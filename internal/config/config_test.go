@@ -2,12 +2,15 @@ package config
 
 import (
 	"errors"
+	"runtime"
 	"testing"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/netsetup"
 )
 
 // validConfig returns a minimal Config that passes all validation checks
-// that don't depend on OS state (netlink). Because Validate() calls
-// netlink.LinkByName we can only exercise the checks that run *before*
+// that don't depend on OS state (netsetup). Because Validate() calls
+// netsetup.LinkExists we can only exercise the checks that run *before*
 // the interface lookup or the auth-key regex check.
 func validConfig() Config {
 	return Config{
@@ -65,7 +68,7 @@ func TestValidateLogLevel(t *testing.T) {
 					t.Fatalf("expected %v, got %v", tt.wantErr, err)
 				}
 			}
-			// Valid levels may still fail on netlink lookup,
+			// Valid levels may still fail on the interface lookup,
 			// so we only assert that the log-level error is NOT returned.
 			if !tt.hasError && errors.Is(err, ErrInvalidLogLevel) {
 				t.Fatalf("did not expect %v, got %v", ErrInvalidLogLevel, err)
@@ -180,6 +183,31 @@ func TestValidateMMDVMMasterServer(t *testing.T) {
 	}
 }
 
+func TestValidateMMDVMMastersOverrideMasterServer(t *testing.T) {
+	t.Parallel()
+	c := validConfig()
+	c.MMDVM[0].MasterServer = ""
+	c.MMDVM[0].Masters = []string{"primary.example.com:62031", "secondary.example.com:62031"}
+	err := c.Validate()
+	if errors.Is(err, ErrInvalidMMDVMMasterServer) {
+		t.Fatalf("did not expect %v", ErrInvalidMMDVMMasterServer)
+	}
+	got := c.MMDVM[0].MasterServers()
+	if len(got) != 2 || got[0] != "primary.example.com:62031" {
+		t.Fatalf("expected masters list to be used, got %v", got)
+	}
+}
+
+func TestValidateMMDVMMastersEmptyEntry(t *testing.T) {
+	t.Parallel()
+	c := validConfig()
+	c.MMDVM[0].Masters = []string{"primary.example.com:62031", ""}
+	err := c.Validate()
+	if !errors.Is(err, ErrInvalidMMDVMMasterServer) {
+		t.Fatalf("expected %v, got %v", ErrInvalidMMDVMMasterServer, err)
+	}
+}
+
 func TestValidateMMDVMPassword(t *testing.T) {
 	t.Parallel()
 	c := validConfig()
@@ -190,6 +218,78 @@ func TestValidateMMDVMPassword(t *testing.T) {
 	}
 }
 
+func TestValidateMMDVMTimeout(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		keepAlive uint
+		timeout   uint
+		want      error
+	}{
+		{"defaults", 0, 0, nil},
+		{"custom", 30, 90, nil},
+		{"fast", 1, 3, nil},
+		{"timeout equal to keepalive", 10, 10, ErrInvalidMMDVMTimeout},
+		{"timeout shorter than keepalive", 30, 15, ErrInvalidMMDVMTimeout},
+		{"keepalive past the default timeout", 20, 0, ErrInvalidMMDVMTimeout},
+		{"timeout under the default keepalive", 0, 4, ErrInvalidMMDVMTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			c.MMDVM[0].KeepAlive = tt.keepAlive
+			c.MMDVM[0].Timeout = tt.timeout
+			err := c.MMDVM[0].Validate()
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestMMDVMLocalAddr(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		bindAddress string
+		want        string
+		wantErr     error
+	}{
+		{"unset", "", "", nil},
+		{"address only", "192.0.2.10", "192.0.2.10:0", nil},
+		{"address and port", "192.0.2.10:62031", "192.0.2.10:62031", nil},
+		{"IPv6", "2001:db8::10", "[2001:db8::10]:0", nil},
+		{"IPv6 and port", "[2001:db8::10]:62031", "[2001:db8::10]:62031", nil},
+		{"host name", "gateway.example.com", "", ErrInvalidMMDVMBindAddress},
+		{"bad port", "192.0.2.10:65536", "", ErrInvalidMMDVMBindAddress},
+		{"no address", ":62031", "", ErrInvalidMMDVMBindAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			c.MMDVM[0].BindAddress = tt.bindAddress
+			if err := c.MMDVM[0].Validate(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate: expected %v, got %v", tt.wantErr, err)
+			}
+			local, err := c.MMDVM[0].LocalAddr()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if tt.want == "" {
+				if local != nil {
+					t.Fatalf("expected no local address, got %v", local)
+				}
+				return
+			}
+			if local.String() != tt.want {
+				t.Fatalf("expected %s, got %v", tt.want, local)
+			}
+		})
+	}
+}
+
 func TestValidateIPSCInterface(t *testing.T) {
 	t.Parallel()
 	c := validConfig()
@@ -200,6 +300,77 @@ func TestValidateIPSCInterface(t *testing.T) {
 	}
 }
 
+func TestValidateIPSCMissingInterface(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		existing bool
+		bindOnly bool
+		wantErr  bool
+	}{
+		{"created at startup", false, false, false},
+		{"existing interface", true, false, true},
+		{"bind only", false, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			c.IPSC.Interface = "does-not-exist0"
+			c.IPSC.ExistingInterface = tt.existing
+			c.IPSC.BindOnly = tt.bindOnly
+			err := c.Validate()
+			if got := errors.Is(err, ErrInvalidIPSCInterface); got != tt.wantErr {
+				t.Fatalf("expected ErrInvalidIPSCInterface %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateIPSCBindAddress(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		modify  func(*IPSC)
+		wantErr error
+	}{
+		{"interface only", func(*IPSC) {}, nil},
+		{"bind address replaces interface", func(ipsc *IPSC) {
+			ipsc.BindAddress = "0.0.0.0"
+			ipsc.Interface = ""
+			ipsc.IP = ""
+			ipsc.SubnetMask = 0
+		}, nil},
+		{"bind address with missing interface", func(ipsc *IPSC) {
+			ipsc.BindAddress = "127.0.0.1"
+			ipsc.Interface = "does-not-exist0"
+		}, nil},
+		{"IPv6 bind address", func(ipsc *IPSC) { ipsc.BindAddress = "::" }, nil},
+		{"invalid bind address", func(ipsc *IPSC) { ipsc.BindAddress = "not-an-ip" }, ErrInvalidIPSCBindAddress},
+		{"no bind address needs interface", func(ipsc *IPSC) { ipsc.Interface = "" }, ErrInvalidIPSCInterface},
+		{"no bind address needs subnet mask", func(ipsc *IPSC) { ipsc.SubnetMask = 0 }, ErrInvalidIPSCSubnetMask},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			tt.modify(&c.IPSC)
+			err := c.Validate()
+			if tt.wantErr == nil {
+				for _, bad := range []error{ErrInvalidIPSCBindAddress, ErrInvalidIPSCInterface, ErrInvalidIPSCIP, ErrInvalidIPSCSubnetMask} {
+					if errors.Is(err, bad) {
+						t.Fatalf("did not expect %v", err)
+					}
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
 func TestValidateIPSCSubnetMask(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -264,6 +435,492 @@ func TestValidateIPSCAuthKeyValid(t *testing.T) {
 	}
 }
 
+func TestValidateIPSCPeerKeys(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		auth    IPSCAuth
+		wantErr error
+	}{
+		{"with global key", IPSCAuth{Enabled: true, Key: "deadbeef", PeerKeys: []IPSCPeerKey{{PeerID: 1, Key: "cafe"}}}, nil},
+		{"required without global key", IPSCAuth{Enabled: true, RequirePeerKey: true, PeerKeys: []IPSCPeerKey{{PeerID: 1, Key: "cafe"}}}, nil},
+		{"optional without global key", IPSCAuth{Enabled: true, PeerKeys: []IPSCPeerKey{{PeerID: 1, Key: "cafe"}}}, ErrInvalidIPSCAuthKey},
+		{"required with no peer keys", IPSCAuth{Enabled: true, RequirePeerKey: true}, ErrInvalidIPSCAuthKey},
+		{"zero peer ID", IPSCAuth{Enabled: true, Key: "deadbeef", PeerKeys: []IPSCPeerKey{{Key: "cafe"}}}, ErrInvalidIPSCPeerKey},
+		{"empty peer key", IPSCAuth{Enabled: true, Key: "deadbeef", PeerKeys: []IPSCPeerKey{{PeerID: 1}}}, ErrInvalidIPSCPeerKey},
+		{"bad hex", IPSCAuth{Enabled: true, Key: "deadbeef", PeerKeys: []IPSCPeerKey{{PeerID: 1, Key: "ZZZZ"}}}, ErrInvalidIPSCPeerKey},
+		{"duplicate peer", IPSCAuth{Enabled: true, Key: "deadbeef", PeerKeys: []IPSCPeerKey{{PeerID: 1, Key: "cafe"}, {PeerID: 1, Key: "beef"}}}, ErrInvalidIPSCPeerKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			c.IPSC.Auth = tt.auth
+			err := c.Validate()
+			if tt.wantErr == nil {
+				if errors.Is(err, ErrInvalidIPSCAuthKey) || errors.Is(err, ErrInvalidIPSCPeerKey) {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateIPSCARS(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		ars     IPSCARS
+		wantErr error
+	}{
+		{"unset", IPSCARS{}, nil},
+		{"forward", IPSCARS{Policy: ARSPolicyForward}, nil},
+		{"drop", IPSCARS{Policy: ARSPolicyDrop, ID: 9999}, nil},
+		{"ack locally", IPSCARS{Policy: ARSPolicyAckLocally, ID: 9999}, nil},
+		{"drop without ID", IPSCARS{Policy: ARSPolicyDrop}, ErrInvalidARSID},
+		{"ack locally without ID", IPSCARS{Policy: ARSPolicyAckLocally}, ErrInvalidARSID},
+		{"unknown policy", IPSCARS{Policy: "reply"}, ErrInvalidARSPolicy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			c.IPSC.ARS = tt.ars
+			err := c.Validate()
+			if tt.wantErr == nil {
+				if errors.Is(err, ErrInvalidARSPolicy) || errors.Is(err, ErrInvalidARSID) {
+					t.Fatalf("did not expect %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateIPSCPeerLists(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		allowed []uint32
+		denied  []uint32
+		wantErr bool
+	}{
+		{"unset", nil, nil, false},
+		{"valid", []uint32{3120101, 3120102}, []uint32{3120103}, false},
+		{"zero allowed", []uint32{3120101, 0}, nil, true},
+		{"zero denied", nil, []uint32{0}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			c.IPSC.AllowedPeers = tt.allowed
+			c.IPSC.DeniedPeers = tt.denied
+			err := c.Validate()
+			if got := errors.Is(err, ErrInvalidIPSCPeerID); got != tt.wantErr {
+				t.Fatalf("expected invalid=%t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateIPSCRadioCheck(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		ids     []uint32
+		wantErr bool
+	}{
+		{"unset", nil, false},
+		{"valid", []uint32{3120101, 0xFFFFFF}, false},
+		{"zero", []uint32{3120101, 0}, true},
+		{"too large", []uint32{0x1000000}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			c.IPSC.RadioCheck.LocalIDs = tt.ids
+			err := c.Validate()
+			if got := errors.Is(err, ErrInvalidRadioCheckID); got != tt.wantErr {
+				t.Fatalf("expected invalid=%t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateIPSCRTP(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		rtp     IPSCRTP
+		wantErr error
+	}{
+		{"defaults", IPSCRTP{PayloadType: 93, TerminatorPayloadType: 94, SSRCMode: RTPSSRCFixed}, nil},
+		{"unset", IPSCRTP{}, nil},
+		{"peer id", IPSCRTP{PayloadType: 93, TerminatorPayloadType: 94, SSRCMode: RTPSSRCPeerID}, nil},
+		{"random", IPSCRTP{PayloadType: 127, TerminatorPayloadType: 0, SSRCMode: RTPSSRCRandom}, nil},
+		{"per stream", IPSCRTP{PayloadType: 93, TerminatorPayloadType: 94, SSRCMode: RTPSSRCPerStream, SSRC: 1000}, nil},
+		{"payload type too large", IPSCRTP{PayloadType: 128, TerminatorPayloadType: 94}, ErrInvalidRTPPayloadType},
+		{"terminator payload type too large", IPSCRTP{PayloadType: 93, TerminatorPayloadType: 200}, ErrInvalidRTPPayloadType},
+		{"unknown ssrc mode", IPSCRTP{PayloadType: 93, TerminatorPayloadType: 94, SSRCMode: "sequential"}, ErrInvalidRTPSSRCMode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			c.IPSC.RTP = tt.rtp
+			err := c.Validate()
+			if tt.wantErr == nil {
+				if errors.Is(err, ErrInvalidRTPPayloadType) || errors.Is(err, ErrInvalidRTPSSRCMode) {
+					t.Fatalf("did not expect %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateReverseChannel(t *testing.T) {
+	t.Parallel()
+	for _, policy := range []ReverseChannelPolicy{"", ReverseChannelForward, ReverseChannelDrop} {
+		c := validConfig()
+		c.IPSC.ReverseChannel = policy
+		if err := c.Validate(); errors.Is(err, ErrInvalidReverseChannel) {
+			t.Fatalf("policy %q: did not expect %v", policy, err)
+		}
+	}
+	c := validConfig()
+	c.IPSC.ReverseChannel = "translate"
+	if err := c.Validate(); !errors.Is(err, ErrInvalidReverseChannel) {
+		t.Fatalf("expected %v, got %v", ErrInvalidReverseChannel, err)
+	}
+}
+
+func TestValidateAddressTakeover(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		policy  AddressTakeover
+		auth    bool
+		wantErr bool
+	}{
+		{"unset", "", false, false},
+		{"immediate", AddressTakeoverImmediate, false, false},
+		{"stale", AddressTakeoverStale, false, false},
+		{"authenticated", AddressTakeoverAuthenticated, true, false},
+		{"authenticated without auth", AddressTakeoverAuthenticated, false, true},
+		{"unknown", "never", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			c.IPSC.AddressTakeover = tt.policy
+			if tt.auth {
+				c.IPSC.Auth = IPSCAuth{Enabled: true, Key: "deadbeef"}
+			}
+			err := c.Validate()
+			if got := errors.Is(err, ErrInvalidAddressTakeover); got != tt.wantErr {
+				t.Fatalf("expected ErrInvalidAddressTakeover %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateCapture(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		capture IPSCCapture
+		wantErr bool
+	}{
+		{"off", IPSCCapture{}, false},
+		{"pcapng", IPSCCapture{Path: "ipsc.pcapng", Format: CaptureFormatPcapng, MaxFileSize: 100, MaxTotalSize: 1000}, false},
+		{"jsonl", IPSCCapture{Path: "ipsc.jsonl", Format: CaptureFormatJSONL, MaxFileSize: 100, MaxTotalSize: 100}, false},
+		{"unknown format", IPSCCapture{Path: "ipsc.pcap", Format: "pcap", MaxFileSize: 100, MaxTotalSize: 1000}, true},
+		{"no max file size", IPSCCapture{Path: "ipsc.pcapng", MaxTotalSize: 1000}, true},
+		{"total below file size", IPSCCapture{Path: "ipsc.pcapng", MaxFileSize: 1000, MaxTotalSize: 100}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			c.IPSC.Capture = tt.capture
+			err := c.Validate()
+			if got := errors.Is(err, ErrInvalidCapture); got != tt.wantErr {
+				t.Fatalf("expected ErrInvalidCapture %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateBusyPolicy(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		policy  BusyPolicy
+		timeout uint
+		wantErr error
+	}{
+		{"unset", "", 0, nil},
+		{"buffer", BusyPolicyBuffer, 0, nil},
+		{"reject", BusyPolicyReject, 0, nil},
+		{"queue", BusyPolicyQueue, 10, nil},
+		{"queue without timeout", BusyPolicyQueue, 0, ErrInvalidBusyQueueTimeout},
+		{"unknown", "preempt", 10, ErrInvalidBusyPolicy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			c.IPSC.BusyPolicy = tt.policy
+			c.IPSC.BusyQueueTimeout = tt.timeout
+			err := c.Validate()
+			if tt.wantErr == nil {
+				if errors.Is(err, ErrInvalidBusyPolicy) || errors.Is(err, ErrInvalidBusyQueueTimeout) {
+					t.Fatalf("did not expect %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateIPSCRole(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		role    IPSCRole
+		master  string
+		wantErr error
+	}{
+		{"unset", "", "", nil},
+		{"master", IPSCRoleMaster, "", nil},
+		{"peer", IPSCRolePeer, "192.0.2.1:50000", nil},
+		{"peer without master", IPSCRolePeer, "", ErrInvalidIPSCMasterAddress},
+		{"peer without port", IPSCRolePeer, "192.0.2.1", ErrInvalidIPSCMasterAddress},
+		{"unknown", "client", "192.0.2.1:50000", ErrInvalidIPSCRole},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			c.IPSC.Role = tt.role
+			c.IPSC.MasterAddress = tt.master
+			err := c.Validate()
+			if tt.wantErr == nil {
+				if errors.Is(err, ErrInvalidIPSCRole) || errors.Is(err, ErrInvalidIPSCMasterAddress) {
+					t.Fatalf("did not expect %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateHeaderRepeats(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		repeats uint
+		wantErr bool
+	}{
+		{"unset", 0, false},
+		{"one", 1, false},
+		{"default", 3, false},
+		{"five", 5, false},
+		{"six", 6, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			c.IPSC.HeaderRepeats = tt.repeats
+			err := c.Validate()
+			if got := errors.Is(err, ErrInvalidHeaderRepeats); got != tt.wantErr {
+				t.Fatalf("expected ErrInvalidHeaderRepeats %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateAdvertisedSlots(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		slots   byte
+		wantErr bool
+	}{
+		{"unset", 0, false},
+		{"TS1", 1, false},
+		{"TS2", 2, false},
+		{"both", 3, false},
+		{"too many", 4, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			c.IPSC.Capabilities.Slots = tt.slots
+			err := c.Validate()
+			if got := errors.Is(err, ErrInvalidAdvertisedSlots); got != tt.wantErr {
+				t.Fatalf("expected ErrInvalidAdvertisedSlots %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidatePeerListMaxPayload(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		payload uint
+		wantErr bool
+	}{
+		{"unset", 0, false},
+		{"too small", 27, true},
+		{"smallest", 28, false},
+		{"default", 1400, false},
+		{"largest", 65507, false},
+		{"too large", 65508, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			c.IPSC.PeerListMaxPayload = tt.payload
+			err := c.Validate()
+			if got := errors.Is(err, ErrInvalidPeerListMaxPayload); got != tt.wantErr {
+				t.Fatalf("expected ErrInvalidPeerListMaxPayload %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateRemoteCommands(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		cmds    IPSCRemoteCommands
+		wantErr error
+	}{
+		{"unset", IPSCRemoteCommands{}, nil},
+		{"block", IPSCRemoteCommands{Policy: RemoteCommandBlock, AuthorizedSources: []uint32{3120101}}, nil},
+		{"allow", IPSCRemoteCommands{Policy: RemoteCommandAllow}, nil},
+		{"unknown policy", IPSCRemoteCommands{Policy: "audit"}, ErrInvalidRemoteCommands},
+		{"zero source", IPSCRemoteCommands{AuthorizedSources: []uint32{0}}, ErrInvalidRemoteCommandID},
+		{"source too large", IPSCRemoteCommands{AuthorizedSources: []uint32{0x1000000}}, ErrInvalidRemoteCommandID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			c.IPSC.RemoteCommands = tt.cmds
+			err := c.Validate()
+			if tt.wantErr == nil {
+				if errors.Is(err, ErrInvalidRemoteCommands) || errors.Is(err, ErrInvalidRemoteCommandID) {
+					t.Fatalf("did not expect %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateParrot(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		parrot  Parrot
+		wantErr error
+	}{
+		{"disabled", Parrot{}, nil},
+		{"private", Parrot{Enabled: true, ID: 9990, MaxDuration: 30}, nil},
+		{"group", Parrot{Enabled: true, ID: 9990, Group: true, MaxDuration: 30}, nil},
+		{"no ID", Parrot{Enabled: true, MaxDuration: 30}, ErrInvalidParrotID},
+		{"ID too large", Parrot{Enabled: true, ID: 0x1000000, MaxDuration: 30}, ErrInvalidParrotID},
+		{"no max duration", Parrot{Enabled: true, ID: 9990}, ErrInvalidParrotMaxDuration},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			c.Parrot = tt.parrot
+			err := c.Validate()
+			if tt.wantErr == nil {
+				if errors.Is(err, ErrInvalidParrotID) || errors.Is(err, ErrInvalidParrotMaxDuration) {
+					t.Fatalf("did not expect %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateSpecialIDs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		rule    SpecialID
+		parrot  bool
+		wantErr error
+	}{
+		{"block range", SpecialID{FromID: 4000, ToID: 5000, Direction: SpecialIDDirectionToIPSC, Action: SpecialIDActionBlock}, false, nil},
+		{"pass single", SpecialID{FromID: 9, Action: SpecialIDActionPass}, false, nil},
+		{"local parrot", SpecialID{FromID: 9990, Action: SpecialIDActionLocal, Handler: SpecialIDHandlerParrot}, true, nil},
+		{"no from", SpecialID{Action: SpecialIDActionBlock}, false, ErrInvalidSpecialIDRange},
+		{"from too large", SpecialID{FromID: 0x1000000, Action: SpecialIDActionBlock}, false, ErrInvalidSpecialIDRange},
+		{"to below from", SpecialID{FromID: 5000, ToID: 4000, Action: SpecialIDActionBlock}, false, ErrInvalidSpecialIDRange},
+		{"bad direction", SpecialID{FromID: 9, Direction: "sideways", Action: SpecialIDActionBlock}, false, ErrInvalidSpecialIDDirection},
+		{"bad action", SpecialID{FromID: 9, Action: "reject"}, false, ErrInvalidSpecialIDAction},
+		{"local without handler", SpecialID{FromID: 9, Action: SpecialIDActionLocal}, true, ErrInvalidSpecialIDHandler},
+		{"local parrot disabled", SpecialID{FromID: 9990, Action: SpecialIDActionLocal, Handler: SpecialIDHandlerParrot}, false, ErrInvalidSpecialIDHandler},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			c.Parrot = Parrot{Enabled: tt.parrot, ID: 9990, MaxDuration: 30}
+			c.SpecialIDs = []SpecialID{tt.rule}
+			err := c.Validate()
+			if tt.wantErr == nil {
+				for _, bad := range []error{ErrInvalidSpecialIDRange, ErrInvalidSpecialIDDirection, ErrInvalidSpecialIDAction, ErrInvalidSpecialIDHandler} {
+					if errors.Is(err, bad) {
+						t.Fatalf("did not expect %v", err)
+					}
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
 func TestLogLevelConstants(t *testing.T) {
 	t.Parallel()
 	if LogLevelDebug != "debug" {
@@ -309,3 +966,227 @@ func TestValidateMetricsAddress(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateHealthAddress(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		enabled bool
+		addr    string
+		wantErr bool
+	}{
+		{"loopback", true, "127.0.0.1:9101", false},
+		{"all interfaces", true, ":9101", false},
+		{"missing port", true, "localhost", true},
+		{"empty", true, "", true},
+		{"disabled ignores address", false, "localhost", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			c.Health.Enabled = tt.enabled
+			c.Health.Address = tt.addr
+			err := c.Validate()
+			if tt.wantErr && !errors.Is(err, ErrInvalidHealthAddress) {
+				t.Fatalf("expected %v, got %v", ErrInvalidHealthAddress, err)
+			}
+			if !tt.wantErr && errors.Is(err, ErrInvalidHealthAddress) {
+				t.Fatalf("did not expect %v", ErrInvalidHealthAddress)
+			}
+		})
+	}
+}
+
+func TestValidateService(t *testing.T) {
+	t.Parallel()
+	c := validConfig()
+	c.Service = true
+	err := c.Validate()
+	if runtime.GOOS == "windows" {
+		if errors.Is(err, ErrServiceUnsupported) {
+			t.Fatalf("did not expect %v on Windows", ErrServiceUnsupported)
+		}
+		return
+	}
+	if !errors.Is(err, ErrServiceUnsupported) {
+		t.Fatalf("expected %v, got %v", ErrServiceUnsupported, err)
+	}
+}
+
+// validBridgeConfig returns a Config with bridge mode enabled and no MMDVM
+// networks, using loopback for both sides.
+func validBridgeConfig() Config {
+	side := IPSC{
+		Interface:  "lo",
+		IP:         "127.0.0.1",
+		SubnetMask: 8,
+	}
+	c := Config{LogLevel: LogLevelInfo}
+	c.Bridge.Enabled = true
+	c.Bridge.PeerID = 311860
+	c.Bridge.A = side
+	c.Bridge.A.Port = 50000
+	c.Bridge.B = side
+	c.Bridge.B.Port = 50001
+	return c
+}
+
+func TestValidateBridge(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr error
+	}{
+		{"valid", func(_ *Config) {}, nil},
+		{"no MMDVM networks required", func(c *Config) { c.MMDVM = nil }, nil},
+		{"missing peer ID", func(c *Config) { c.Bridge.PeerID = 0 }, ErrInvalidBridgePeerID},
+		{"side a subnet mask", func(c *Config) { c.Bridge.A.SubnetMask = 0 }, ErrInvalidIPSCSubnetMask},
+		{"side b auth key", func(c *Config) {
+			c.Bridge.B.Auth.Enabled = true
+			c.Bridge.B.Auth.Key = ""
+		}, ErrInvalidIPSCAuthKey},
+		{"same endpoint", func(c *Config) { c.Bridge.B.Port = c.Bridge.A.Port }, ErrDuplicateBridgeEndpoint},
+		{"bad rewrite slot", func(c *Config) {
+			c.Bridge.TGRewrites = []TGRewriteConfig{{FromSlot: 3, FromTG: 1, ToSlot: 1, ToTG: 1, Range: 1}}
+		}, ErrInvalidRewriteSlot},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validBridgeConfig()
+			tt.modify(&c)
+			err := c.Validate()
+			if tt.wantErr == nil {
+				// The interface lookup needs netlink, which only exists on Linux.
+				if err != nil && !errors.Is(err, netsetup.ErrUnsupportedPlatform) {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateIPSCServers(t *testing.T) {
+	t.Parallel()
+	second := func(c *Config) *IPSC {
+		instance := c.IPSC
+		instance.Name = "second"
+		instance.Port = c.IPSC.Port + 1
+		c.IPSCServers = []IPSC{c.IPSC, instance}
+		return &c.IPSCServers[1]
+	}
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr error
+	}{
+		{"valid", func(c *Config) { second(c) }, nil},
+		{"routed to a network", func(c *Config) { second(c).Networks = []string{"BM"} }, nil},
+		{"missing name", func(c *Config) { second(c).Name = "" }, ErrInvalidIPSCName},
+		{"duplicate name", func(c *Config) {
+			c.IPSC.Name = "first"
+			second(c).Name = "first"
+		}, ErrInvalidIPSCName},
+		{"same interface and port", func(c *Config) { second(c).Port = c.IPSC.Port }, ErrDuplicateIPSCListener},
+		{"same bind address and port", func(c *Config) {
+			c.IPSC.BindAddress = "127.0.0.1"
+			instance := second(c)
+			instance.BindAddress = "127.0.0.1"
+			instance.Port = c.IPSC.Port
+		}, ErrDuplicateIPSCListener},
+		{"unknown network", func(c *Config) { second(c).Networks = []string{"TGIF"} }, ErrUnknownIPSCNetwork},
+		{"default network", func(c *Config) { second(c).DefaultNetwork = "BM" }, nil},
+		{"unknown default network", func(c *Config) { second(c).DefaultNetwork = "TGIF" }, ErrUnknownDefaultNetwork},
+		{"default network not routed", func(c *Config) {
+			c.MMDVM = append(c.MMDVM, c.MMDVM[0])
+			c.MMDVM[1].Name = "TGIF"
+			instance := second(c)
+			instance.Networks = []string{"BM"}
+			instance.DefaultNetwork = "TGIF"
+		}, ErrUnknownDefaultNetwork},
+		{"invalid instance", func(c *Config) { second(c).SubnetMask = 0 }, ErrInvalidIPSCSubnetMask},
+		{"own header repeats", func(c *Config) { second(c).HeaderRepeats = 5 }, ErrIPSCSettingNotShared},
+		{"own swap slots", func(c *Config) { second(c).SwapSlots = true }, ErrIPSCSettingNotShared},
+		{"own busy policy", func(c *Config) { second(c).BusyPolicy = BusyPolicyReject }, ErrIPSCSettingNotShared},
+		{"own remote commands", func(c *Config) {
+			second(c).RemoteCommands.AuthorizedSources = []uint32{3120000}
+		}, ErrIPSCSettingNotShared},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			tt.modify(&c)
+			err := c.Validate()
+			if tt.wantErr == nil {
+				// The interface lookup needs netlink, which only exists on Linux.
+				if err != nil && !errors.Is(err, netsetup.ErrUnsupportedPlatform) {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestIPSCConfigs(t *testing.T) {
+	t.Parallel()
+	c := validConfig()
+	c.IPSCServers = []IPSC{c.IPSC, {Name: "second", Port: 50001}}
+	configs := c.IPSCConfigs()
+	if len(configs) != 2 {
+		t.Fatalf("expected 2 configs, got %d", len(configs))
+	}
+	if configs[0].IPSC.Port != 50000 || configs[1].IPSC.Name != "second" || configs[1].IPSC.Port != 50001 {
+		t.Fatalf("expected the ipsc section then the instance, got %+v and %+v", configs[0].IPSC, configs[1].IPSC)
+	}
+	if len(configs[1].MMDVM) != 1 || configs[1].MMDVM[0].Name != "BM" {
+		t.Fatalf("expected the rest of the config kept, got %+v", configs[1].MMDVM)
+	}
+	if c.IPSC.Name != "" {
+		t.Fatalf("expected the config untouched, got ipsc named %q", c.IPSC.Name)
+	}
+}
+
+func TestValidateDSCP(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr error
+	}{
+		{"unmarked", func(_ *Config) {}, nil},
+		{"ipsc expedited forwarding", func(c *Config) { c.IPSC.DSCP = 46 }, nil},
+		{"ipsc highest", func(c *Config) { c.IPSC.DSCP = 63 }, nil},
+		{"ipsc out of range", func(c *Config) { c.IPSC.DSCP = 64 }, ErrInvalidDSCP},
+		{"mmdvm expedited forwarding", func(c *Config) { c.MMDVM[0].DSCP = 46 }, nil},
+		{"mmdvm out of range", func(c *Config) { c.MMDVM[0].DSCP = 255 }, ErrInvalidDSCP},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := validConfig()
+			tt.modify(&c)
+			err := c.Validate()
+			if tt.wantErr == nil {
+				// The interface lookup needs netlink, which only exists on Linux.
+				if err != nil && !errors.Is(err, netsetup.ErrUnsupportedPlatform) {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/USA-RedDragon/configulator"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// IPSCSection is the ipsc section as configulator loads it, for one IPSC
+// server. In the config file the section is one server or a list of them,
+// which configulator cannot read, so LoadIPSC loads each entry on its own.
+type IPSCSection struct {
+	IPSC IPSC `name:"ipsc" description:"Configuration for the IPSC server, or the first of several"`
+}
+
+// Validate does nothing; the servers are checked with the rest of the
+// config by Config.Validate.
+func (IPSCSection) Validate() error {
+	return nil
+}
+
+// LoadIPSC sets the IPSC servers from the ipsc section of the config file
+// at path, one server or a list of them. Each entry is loaded by a
+// configulator of its own, from a file holding just that entry, so it is
+// read as a lone ipsc section would be. first loads the first server, and
+// so takes its environment variables and flags over its entry, as
+// configulator does for the rest of the config. Those after the first
+// start from defaults and take the first's MMDVM-side settings. Without a
+// file or section, first alone sets the one server.
+func (c *Config) LoadIPSC(path string, first *configulator.Configulator[IPSCSection]) error {
+	entries, err := readIPSCSection(path)
+	if err != nil {
+		return err
+	}
+	dir := ""
+	if len(entries) > 0 {
+		dir, err = os.MkdirTemp("", "ipsc-section-")
+		if err != nil {
+			return fmt.Errorf("failed to split ipsc section: %w", err)
+		}
+		defer os.RemoveAll(dir) //nolint:errcheck // only temporary files
+	}
+
+	defaults, err := first.Default()
+	if err != nil {
+		return fmt.Errorf("failed to get ipsc defaults: %w", err)
+	}
+	servers := make([]IPSC, max(len(entries), 1))
+	for i := range servers {
+		section := first
+		if i > 0 {
+			// configulator needs flags to load a file; these are never set.
+			section = configulator.New[IPSCSection]().
+				WithPFlags(pflag.NewFlagSet("ipsc", pflag.ContinueOnError), nil)
+		}
+		var file *configulator.FileOptions
+		if i < len(entries) {
+			entryPath := filepath.Join(dir, fmt.Sprintf("ipsc-%d.yaml", i))
+			if err := os.WriteFile(entryPath, entries[i], 0o600); err != nil {
+				return fmt.Errorf("failed to split ipsc section: %w", err)
+			}
+			file = &configulator.FileOptions{Paths: []string{entryPath}}
+		}
+		loaded, err := section.WithFile(file).LoadWithoutValidation()
+		if err != nil {
+			return fmt.Errorf("%w: entry %d: %w", ErrInvalidIPSCSection, i, err)
+		}
+		servers[i] = loaded.IPSC
+		if i > 0 {
+			if !reflect.DeepEqual(servers[i].shared(), defaults.IPSC.shared()) {
+				return fmt.Errorf("IPSC server %s: %w", servers[i].Name, ErrIPSCSettingNotShared)
+			}
+			servers[i].setShared(servers[0].shared())
+		}
+	}
+	c.IPSC = servers[0]
+	c.IPSCServers = servers
+	return nil
+}
+
+// ipscServers returns the IPSC servers to run: those of the ipsc section,
+// or IPSC alone in a config not loaded with LoadIPSC.
+func (c *Config) ipscServers() []IPSC {
+	if len(c.IPSCServers) == 0 {
+		return []IPSC{c.IPSC}
+	}
+	return c.IPSCServers
+}
+
+// ipscShared are the IPSC settings applied on the MMDVM side, by the
+// translator and slot manager of each network, which serve every IPSC
+// server alike.
+type ipscShared struct {
+	SwapSlots        bool
+	RTP              IPSCRTP
+	ReverseChannel   ReverseChannelPolicy
+	RemoteCommands   IPSCRemoteCommands
+	BusyPolicy       BusyPolicy
+	BusyQueueTimeout uint
+	WakeUpIdle       uint
+	HeaderRepeats    uint
+	MaxStreams       uint
+}
+
+func (i *IPSC) shared() ipscShared {
+	return ipscShared{
+		SwapSlots:        i.SwapSlots,
+		RTP:              i.RTP,
+		ReverseChannel:   i.ReverseChannel,
+		RemoteCommands:   i.RemoteCommands,
+		BusyPolicy:       i.BusyPolicy,
+		BusyQueueTimeout: i.BusyQueueTimeout,
+		WakeUpIdle:       i.WakeUpIdle,
+		HeaderRepeats:    i.HeaderRepeats,
+		MaxStreams:       i.MaxStreams,
+	}
+}
+
+func (i *IPSC) setShared(s ipscShared) {
+	i.SwapSlots = s.SwapSlots
+	i.RTP = s.RTP
+	i.ReverseChannel = s.ReverseChannel
+	i.RemoteCommands = s.RemoteCommands
+	i.BusyPolicy = s.BusyPolicy
+	i.BusyQueueTimeout = s.BusyQueueTimeout
+	i.WakeUpIdle = s.WakeUpIdle
+	i.HeaderRepeats = s.HeaderRepeats
+	i.MaxStreams = s.MaxStreams
+}
+
+// readIPSCSection returns each entry of the ipsc section of the config
+// file at path as a config file of its own, none if there is no file or
+// section.
+func readIPSCSection(path string) ([][]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	var file struct {
+		IPSC any `yaml:"ipsc"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode config file: %w", err)
+	}
+	var entries []any
+	switch section := file.IPSC.(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		entries = []any{section}
+	case []any:
+		entries = section
+	default:
+		return nil, ErrInvalidIPSCSection
+	}
+	files := make([][]byte, 0, len(entries))
+	for i, entry := range entries {
+		if _, ok := entry.(map[string]any); !ok {
+			return nil, fmt.Errorf("%w: entry %d is not an object", ErrInvalidIPSCSection, i)
+		}
+		data, err := yaml.Marshal(map[string]any{"ipsc": entry})
+		if err != nil {
+			return nil, fmt.Errorf("failed to split ipsc section: %w", err)
+		}
+		files = append(files, data)
+	}
+	return files, nil
+}
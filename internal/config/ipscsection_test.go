@@ -0,0 +1,179 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/USA-RedDragon/configulator"
+	"github.com/spf13/pflag"
+)
+
+// ipscLoader returns a configulator of the ipsc section as the command
+// sets one up, with args given as its flags.
+func ipscLoader(t *testing.T, args ...string) *configulator.Configulator[IPSCSection] {
+	t.Helper()
+	flags := pflag.NewFlagSet("ipsc", pflag.ContinueOnError)
+	loader := configulator.New[IPSCSection]().WithPFlags(flags, nil)
+	if err := flags.Parse(args); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return loader
+}
+
+// writeConfigFile writes data to a config file and returns its path.
+func writeConfigFile(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestLoadIPSCSingleServer(t *testing.T) {
+	t.Parallel()
+	path := writeConfigFile(t, `
+ipsc:
+  interface: ipsc0
+  port: 50001
+  auth:
+    enabled: true
+    key: "1234"
+    peer-keys:
+      - peer-id: 100
+        key: "abcd"
+`)
+	var c Config
+	if err := c.LoadIPSC(path, ipscLoader(t)); err != nil {
+		t.Fatalf("LoadIPSC: %v", err)
+	}
+	if len(c.IPSCServers) != 1 {
+		t.Fatalf("expected one server, got %d", len(c.IPSCServers))
+	}
+	got := c.IPSC
+	if got.Interface != "ipsc0" || got.Port != 50001 {
+		t.Fatalf("expected the file's settings, got %+v", got)
+	}
+	if !got.Auth.Enabled || got.Auth.Key != "1234" || len(got.Auth.PeerKeys) != 1 || got.Auth.PeerKeys[0].PeerID != 100 || got.Auth.PeerKeys[0].Key != "abcd" {
+		t.Fatalf("expected the nested auth settings, got %+v", got.Auth)
+	}
+	if got.PeerTimeout != 60 || got.IP != "10.10.250.1" || got.HeaderRepeats != 3 {
+		t.Fatalf("expected defaults for the rest, got %+v", got)
+	}
+}
+
+func TestLoadIPSCList(t *testing.T) {
+	t.Parallel()
+	path := writeConfigFile(t, `
+ipsc:
+  - interface: ipsc0
+    port: 50000
+    header-repeats: 5
+    swap-slots: true
+  - name: second
+    interface: ipsc1
+    port: 50001
+`)
+	var c Config
+	if err := c.LoadIPSC(path, ipscLoader(t)); err != nil {
+		t.Fatalf("LoadIPSC: %v", err)
+	}
+	if len(c.IPSCServers) != 2 {
+		t.Fatalf("expected two servers, got %d", len(c.IPSCServers))
+	}
+	if c.IPSC.Interface != "ipsc0" || c.IPSCServers[0].Interface != "ipsc0" {
+		t.Fatalf("expected the first server to be the config's, got %+v", c.IPSC)
+	}
+	second := c.IPSCServers[1]
+	if second.Name != "second" || second.Interface != "ipsc1" || second.Port != 50001 {
+		t.Fatalf("expected the second entry's settings, got %+v", second)
+	}
+	if second.PeerTimeout != 60 || second.ReceiveWorkers != 4 || second.AddressTakeover != AddressTakeoverImmediate {
+		t.Fatalf("expected defaults for the second server, got %+v", second)
+	}
+	if second.HeaderRepeats != 5 || !second.SwapSlots {
+		t.Fatalf("expected the first server's MMDVM-side settings, got header repeats %d swap slots %t", second.HeaderRepeats, second.SwapSlots)
+	}
+}
+
+func TestLoadIPSCFlagsOverrideFirstServer(t *testing.T) {
+	t.Parallel()
+	path := writeConfigFile(t, `
+ipsc:
+  - interface: ipsc0
+    port: 50000
+  - name: second
+    interface: ipsc1
+    port: 50001
+`)
+	var c Config
+	if err := c.LoadIPSC(path, ipscLoader(t, "--ipsc.port=50009", "--ipsc.auth.key=5678")); err != nil {
+		t.Fatalf("LoadIPSC: %v", err)
+	}
+	if c.IPSC.Port != 50009 || c.IPSC.Auth.Key != "5678" || c.IPSC.Interface != "ipsc0" {
+		t.Fatalf("expected the first server overridden, got %+v", c.IPSC)
+	}
+	if second := c.IPSCServers[1]; second.Port != 50001 || second.Auth.Key != "" {
+		t.Fatalf("expected the second server left alone, got %+v", second)
+	}
+}
+
+func TestLoadIPSCWithoutSection(t *testing.T) {
+	t.Parallel()
+	for name, path := range map[string]string{
+		"no file":    filepath.Join(t.TempDir(), "missing.yaml"),
+		"no section": writeConfigFile(t, "log-level: debug\n"),
+	} {
+		var c Config
+		if err := c.LoadIPSC(path, ipscLoader(t, "--ipsc.port=50009")); err != nil {
+			t.Fatalf("%s: LoadIPSC: %v", name, err)
+		}
+		if len(c.IPSCServers) != 1 || c.IPSC.Port != 50009 || c.IPSC.PeerTimeout != 60 {
+			t.Fatalf("%s: expected one server from defaults and set, got %+v", name, c.IPSCServers)
+		}
+	}
+}
+
+func TestLoadIPSCInvalid(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"scalar", "ipsc: 5\n"},
+		{"list of scalars", "ipsc: [5]\n"},
+		{"wrong type", "ipsc:\n  port: fifty\n"},
+		{"not a bool", "ipsc:\n  auth:\n    enabled: maybe\n"},
+		{"not a list", "ipsc:\n  allowed-peers: 100\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var c Config
+			err := c.LoadIPSC(writeConfigFile(t, tt.data), ipscLoader(t))
+			if !errors.Is(err, ErrInvalidIPSCSection) {
+				t.Fatalf("expected %v, got %v", ErrInvalidIPSCSection, err)
+			}
+		})
+	}
+}
+
+func TestLoadIPSCSharedSettingOnLaterServer(t *testing.T) {
+	t.Parallel()
+	path := writeConfigFile(t, `
+ipsc:
+  - interface: ipsc0
+    port: 50000
+  - name: second
+    interface: ipsc1
+    port: 50001
+    header-repeats: 5
+`)
+	var c Config
+	err := c.LoadIPSC(path, ipscLoader(t))
+	if !errors.Is(err, ErrIPSCSettingNotShared) {
+		t.Fatalf("expected %v, got %v", ErrIPSCSettingNotShared, err)
+	}
+}
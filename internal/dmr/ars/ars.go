@@ -0,0 +1,212 @@
+// Package ars recognizes and builds the messages of the Motorola ARS
+// (automatic registration service), which Mototrbo radios send to a
+// registration server over DMR IP data on power-up and retry until they
+// are acknowledged.
+//
+// Motorola does not publish the ARS message format. The acknowledgment
+// built here is the minimal one used by open-source ARS responders, which
+// radios accept as the end of their registration.
+package ars
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/crc"
+)
+
+// Port is the UDP port ARS messages are sent to and from.
+const Port = 4005
+
+// BlockSize is the payload of one rate 1/2 data block or data header.
+const BlockSize = 12
+
+// Block is the payload of one DMR data header or rate 1/2 data block.
+type Block [BlockSize]byte
+
+var (
+	// ErrBadHeader is returned for a data header whose CRC does not
+	// verify.
+	ErrBadHeader = errors.New("data header CRC mismatch")
+	// ErrNotUDP is returned for a datagram that is not UDP over IPv4.
+	ErrNotUDP = errors.New("not a UDP over IPv4 datagram")
+	// ErrTruncated is returned when too little of a datagram is known to
+	// read its UDP header.
+	ErrTruncated = errors.New("datagram truncated before its UDP header")
+)
+
+// SAPIP is the service access point of IP based packet data.
+const SAPIP = 4
+
+const (
+	// caiNetwork is the first octet of the IP address of every radio on
+	// the Mototrbo common air interface network; the other three are
+	// its radio ID.
+	caiNetwork = 12
+	// dpfUnconfirmed and dpfConfirmed are the data packet formats of
+	// unconfirmed and confirmed data.
+	dpfUnconfirmed = 0x2
+	dpfConfirmed   = 0x3
+	// confirmedBlockHeader is the serial number and CRC-9 that open each
+	// block of confirmed data.
+	confirmedBlockHeader = 2
+	// crcSize is the CRC-32 that closes a data message.
+	crcSize = 4
+)
+
+// registrationAck is the ARS payload acknowledging a device registration:
+// the length of the rest, then a PDU header of 0xBF (registration
+// acknowledgment, with an extension octet) and the extension.
+//
+//nolint:gochecknoglobals
+var registrationAck = []byte{0x00, 0x02, 0xBF, 0x01}
+
+// RadioIP returns the address of radio id on the CAI network.
+func RadioIP(id uint32) net.IP {
+	return net.IPv4(caiNetwork, byte(id>>16), byte(id>>8), byte(id))
+}
+
+// DataHeader is the part of a DMR data header needed to send or follow a
+// data message.
+type DataHeader struct {
+	Group          bool
+	Confirmed      bool
+	SAP            uint8
+	PadOctets      uint8
+	Dst, Src       uint32
+	BlocksToFollow uint8
+}
+
+// Encode returns the header as a data header with its CRC-CCITT.
+func (h DataHeader) Encode() Block {
+	var body [BlockSize - 2]byte
+	dpf := byte(dpfUnconfirmed)
+	if h.Confirmed {
+		dpf = dpfConfirmed
+	}
+	body[0] = dpf | h.PadOctets&0x10 // pad octet count MSB
+	if h.Group {
+		body[0] |= 0x80
+	}
+	body[1] = h.SAP<<4 | h.PadOctets&0x0F
+	body[2], body[3], body[4] = byte(h.Dst>>16), byte(h.Dst>>8), byte(h.Dst)
+	body[5], body[6], body[7] = byte(h.Src>>16), byte(h.Src>>8), byte(h.Src)
+	// A single, complete message.
+	body[8] = 0x80 | h.BlocksToFollow&0x7F
+	return Block(crc.AppendCCITT16(body[:], crc.MaskDataHeader))
+}
+
+// ParseDataHeader decodes a data header. Only the fields shared by the
+// confirmed and unconfirmed formats are meaningful for confirmed data.
+func ParseDataHeader(b Block) (DataHeader, error) {
+	if !crc.CheckCCITT16(b[:], crc.MaskDataHeader) {
+		return DataHeader{}, ErrBadHeader
+	}
+	return DataHeader{
+		Group:          b[0]&0x80 != 0,
+		Confirmed:      b[0]&0x0F == dpfConfirmed,
+		SAP:            b[1] >> 4,
+		PadOctets:      b[0]&0x10 | b[1]&0x0F,
+		Dst:            uint32(b[2])<<16 | uint32(b[3])<<8 | uint32(b[4]),
+		Src:            uint32(b[5])<<16 | uint32(b[6])<<8 | uint32(b[7]),
+		BlocksToFollow: b[8] & 0x7F,
+	}, nil
+}
+
+// UserData returns the user octets of a data block as carried in a call
+// with the given header: confirmed data opens every block with a serial
+// number and CRC, which are not part of the message.
+func (h DataHeader) UserData(block []byte) []byte {
+	if !h.Confirmed {
+		return block
+	}
+	if len(block) < confirmedBlockHeader {
+		return nil
+	}
+	return block[confirmedBlockHeader:]
+}
+
+// DestinationPort returns the UDP destination port of the IPv4 datagram
+// at the start of datagram, which need not be complete. It returns
+// ErrTruncated while the UDP header is not yet known and ErrNotUDP if
+// the datagram is not UDP over IPv4.
+func DestinationPort(datagram []byte) (uint16, error) {
+	const minIPHeaderLen, udpProtocol = 20, 17
+	if len(datagram) == 0 {
+		return 0, ErrTruncated
+	}
+	ihl := int(datagram[0]&0x0F) * 4
+	if datagram[0]>>4 != 4 || ihl < minIPHeaderLen {
+		return 0, ErrNotUDP
+	}
+	if len(datagram) < ihl+4 {
+		return 0, ErrTruncated
+	}
+	if datagram[9] != udpProtocol {
+		return 0, ErrNotUDP
+	}
+	return binary.BigEndian.Uint16(datagram[ihl+2:]), nil
+}
+
+// Ack returns the data header and rate 1/2 blocks of an unconfirmed data
+// message acknowledging the registration of radio to the ARS server at
+// ID server. ipID is the IPv4 identification of the datagram.
+func Ack(server, radio uint32, ipID uint16) (Block, []Block) {
+	datagram := udpDatagram(RadioIP(server), RadioIP(radio), ipID, registrationAck)
+
+	blocks := (len(datagram) + crcSize + BlockSize - 1) / BlockSize
+	pad := blocks*BlockSize - len(datagram) - crcSize
+	message := make([]byte, len(datagram), blocks*BlockSize)
+	copy(message, datagram)
+	message = append(message, make([]byte, pad)...)
+	message = crc.AppendCRC32(message)
+
+	header := DataHeader{
+		SAP:            SAPIP,
+		PadOctets:      uint8(pad), //nolint:gosec // < BlockSize
+		Dst:            radio,
+		Src:            server,
+		BlocksToFollow: uint8(blocks), //nolint:gosec // A handful of blocks
+	}
+	out := make([]Block, blocks)
+	for i := range out {
+		copy(out[i][:], message[i*BlockSize:])
+	}
+	return header.Encode(), out
+}
+
+// udpDatagram wraps payload in UDP and IPv4 headers from src to dst, both
+// on Port. The UDP checksum is left out, which IPv4 allows.
+func udpDatagram(src, dst net.IP, ipID uint16, payload []byte) []byte {
+	const ipHeaderLen, udpHeaderLen = 20, 8
+	total := ipHeaderLen + udpHeaderLen + len(payload)
+	buf := make([]byte, total)
+
+	buf[0] = 0x45                                       // IPv4, 5-word header
+	binary.BigEndian.PutUint16(buf[2:4], uint16(total)) //nolint:gosec // Small datagram
+	binary.BigEndian.PutUint16(buf[4:6], ipID)
+	buf[8] = 64 // TTL
+	buf[9] = 17 // UDP
+	copy(buf[12:16], src.To4())
+	copy(buf[16:20], dst.To4())
+	binary.BigEndian.PutUint16(buf[10:12], ipChecksum(buf[:ipHeaderLen]))
+
+	udp := buf[ipHeaderLen:]
+	binary.BigEndian.PutUint16(udp[0:2], Port)
+	binary.BigEndian.PutUint16(udp[2:4], Port)
+	binary.BigEndian.PutUint16(udp[4:6], uint16(udpHeaderLen+len(payload))) //nolint:gosec // Small datagram
+	copy(udp[udpHeaderLen:], payload)
+	return buf
+}
+
+func ipChecksum(header []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(header); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(header[i:]))
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xFFFF + sum>>16
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,138 @@
+package ars
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/crc"
+)
+
+func TestRadioIP(t *testing.T) {
+	t.Parallel()
+	if got := RadioIP(3120101); !got.Equal(net.IPv4(12, 0x2F, 0x9B, 0xE5)) {
+		t.Fatalf("expected 12.47.155.229, got %s", got)
+	}
+}
+
+func TestDataHeaderRoundTrip(t *testing.T) {
+	t.Parallel()
+	want := DataHeader{Group: true, SAP: SAPIP, PadOctets: 0x13, Dst: 9, Src: 3120101, BlocksToFollow: 5}
+	for _, confirmed := range []bool{false, true} {
+		want.Confirmed = confirmed
+		got, err := ParseDataHeader(want.Encode())
+		if err != nil {
+			t.Fatalf("ParseDataHeader: %v", err)
+		}
+		if got != want {
+			t.Fatalf("expected %+v, got %+v", want, got)
+		}
+	}
+
+	corrupted := want.Encode()
+	corrupted[3] ^= 0x01
+	if _, err := ParseDataHeader(corrupted); !errors.Is(err, ErrBadHeader) {
+		t.Fatalf("expected %v, got %v", ErrBadHeader, err)
+	}
+}
+
+func TestUserData(t *testing.T) {
+	t.Parallel()
+	block := []byte{0xA1, 0xB2, 0x45, 0x00}
+	if got := (DataHeader{}).UserData(block); !bytes.Equal(got, block) {
+		t.Fatalf("expected unconfirmed data as is, got % X", got)
+	}
+	if got := (DataHeader{Confirmed: true}).UserData(block); !bytes.Equal(got, block[2:]) {
+		t.Fatalf("expected the serial number and CRC stripped, got % X", got)
+	}
+}
+
+func TestDestinationPort(t *testing.T) {
+	t.Parallel()
+	datagram := udpDatagram(RadioIP(3120101), RadioIP(9999), 1, registrationAck)
+	withOptions := append([]byte{0x46}, datagram[1:20]...)
+	withOptions = append(withOptions, 0x01, 0x01, 0x01, 0x00)
+	withOptions = append(withOptions, datagram[20:]...)
+	tcp := bytes.Clone(datagram)
+	tcp[9] = 6
+
+	tests := []struct {
+		name     string
+		datagram []byte
+		want     uint16
+		wantErr  error
+	}{
+		{"udp", datagram, Port, nil},
+		{"udp header only", datagram[:24], Port, nil},
+		{"ip options", withOptions, Port, nil},
+		{"empty", nil, 0, ErrTruncated},
+		{"ip header only", datagram[:20], 0, ErrTruncated},
+		{"options truncated", withOptions[:24], 0, ErrTruncated},
+		{"tcp", tcp, 0, ErrNotUDP},
+		{"ipv6", []byte{0x60, 0x00}, 0, ErrNotUDP},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := DestinationPort(tt.datagram)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Fatalf("expected port %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAck(t *testing.T) {
+	t.Parallel()
+	const server, radio = 9999, 3120101
+	headerBlock, blocks := Ack(server, radio, 7)
+
+	header, err := ParseDataHeader(headerBlock)
+	if err != nil {
+		t.Fatalf("ParseDataHeader: %v", err)
+	}
+	if headerBlock[0]&0x0F != dpfUnconfirmed {
+		t.Fatalf("expected unconfirmed data, got DPF %#x", headerBlock[0]&0x0F)
+	}
+	if header.Group || header.SAP != SAPIP || header.Src != server || header.Dst != radio {
+		t.Fatalf("unexpected header %+v", header)
+	}
+	if int(header.BlocksToFollow) != len(blocks) {
+		t.Fatalf("expected %d blocks to follow, got %d", len(blocks), header.BlocksToFollow)
+	}
+
+	var message []byte
+	for _, b := range blocks {
+		message = append(message, b[:]...)
+	}
+	if !crc.CheckCRC32(message) {
+		t.Fatal("expected the message CRC-32 to verify")
+	}
+	datagram := message[:len(message)-crcSize-int(header.PadOctets)]
+
+	ip := datagram[:20]
+	if ip[0] != 0x45 || ip[9] != 17 {
+		t.Fatalf("expected an IPv4 UDP datagram, got % X", ip)
+	}
+	if ipChecksum(ip) != 0 {
+		t.Fatal("expected the IPv4 header checksum to verify")
+	}
+	if int(binary.BigEndian.Uint16(ip[2:4])) != len(datagram) {
+		t.Fatalf("expected total length %d, got %d", len(datagram), binary.BigEndian.Uint16(ip[2:4]))
+	}
+	if !net.IP(ip[12:16]).Equal(RadioIP(server)) || !net.IP(ip[16:20]).Equal(RadioIP(radio)) {
+		t.Fatalf("expected %s → %s, got %s → %s", RadioIP(server), RadioIP(radio), net.IP(ip[12:16]), net.IP(ip[16:20]))
+	}
+	udp := datagram[20:]
+	if binary.BigEndian.Uint16(udp[0:2]) != Port || binary.BigEndian.Uint16(udp[2:4]) != Port {
+		t.Fatalf("expected port %d both ways, got % X", Port, udp[:4])
+	}
+	if !bytes.Equal(udp[8:], registrationAck) {
+		t.Fatalf("expected ARS payload % X, got % X", registrationAck, udp[8:])
+	}
+}
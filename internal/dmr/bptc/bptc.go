@@ -0,0 +1,345 @@
+// Package bptc implements the block product turbo codes of ETSI TS
+// 102 361-1 Annex B.1: coding the information octets of data bursts
+// with BPTC(196,96), such as CSBKs and link control headers, decoding
+// them with single-bit error correction, and coding link control into
+// the BPTC(128,72) fragments embedded in voice bursts B to E.
+package bptc
+
+import "github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/crc"
+
+// BurstSize is the length of a DMR burst in octets.
+const BurstSize = 33
+
+// DataSize is the number of information octets a BPTC(196,96) burst
+// carries.
+const DataSize = 12
+
+const (
+	codedBits = 196
+	// halfBits is the number of coded bits on each side of the 68 bits
+	// of slot type and sync in the middle of the burst.
+	halfBits = 98
+	// secondHalf is the first burst bit after the slot type and sync.
+	secondHalf = 166
+	// interleaveStep is the step of the burst interleaver: coded bit i
+	// is sent as bit i*181 mod 196.
+	interleaveStep = 181
+)
+
+// The coded bits after bit 0 form a matrix of 13 rows of 15 columns.
+const (
+	rows     = 13
+	cols     = 15
+	dataRows = 9
+	dataCols = 11
+)
+
+// Extract returns the 96 information bits of burst as octets. The
+// Hamming parity is not checked, so errors on the air are not corrected;
+// callers verify the CRC of what they extract.
+func Extract(burst [BurstSize]byte) [DataSize]byte {
+	coded := deinterleave(burst)
+	return information(&coded)
+}
+
+// Decode returns the information octets of burst, correcting a single
+// bit error in any row or column of the matrix. It reports false if
+// errors remain that it could not correct.
+func Decode(burst [BurstSize]byte) ([DataSize]byte, bool) {
+	coded := deinterleave(burst)
+	ok := correct(&coded)
+	return information(&coded), ok
+}
+
+// Insert codes data with BPTC(196,96) into the information bits of
+// burst, leaving its slot type and sync as they are.
+func Insert(burst *[BurstSize]byte, data [DataSize]byte) {
+	var coded [codedBits]byte
+	for n, pos := range dataPositions() {
+		coded[pos] = data[n/8] >> (7 - n%8) & 1
+	}
+	for row := range dataRows {
+		hamming15113(coded[1+row*cols : 1+(row+1)*cols])
+	}
+	for col := range cols {
+		var c [rows]byte
+		for row := range dataRows {
+			c[row] = coded[1+row*cols+col]
+		}
+		hamming1393(c[:])
+		for row := dataRows; row < rows; row++ {
+			coded[1+row*cols+col] = c[row]
+		}
+	}
+
+	for i := range codedBits {
+		j := i * interleaveStep % codedBits
+		if j >= halfBits {
+			j += secondHalf - halfBits
+		}
+		if coded[i] != 0 {
+			burst[j/8] |= 0x80 >> (j % 8)
+		} else {
+			burst[j/8] &^= 0x80 >> (j % 8)
+		}
+	}
+}
+
+// deinterleave returns the coded bits of burst in matrix order.
+func deinterleave(burst [BurstSize]byte) [codedBits]byte {
+	var raw [codedBits]byte
+	for i := range halfBits {
+		raw[i] = bit(burst, i)
+		raw[halfBits+i] = bit(burst, secondHalf+i)
+	}
+
+	var coded [codedBits]byte
+	for i := range codedBits {
+		coded[i] = raw[i*interleaveStep%codedBits]
+	}
+	return coded
+}
+
+// information returns the information bits of coded as octets.
+func information(coded *[codedBits]byte) [DataSize]byte {
+	var out [DataSize]byte
+	for n, pos := range dataPositions() {
+		if coded[pos] != 0 {
+			out[n/8] |= 0x80 >> (n % 8)
+		}
+	}
+	return out
+}
+
+// correct fixes single-bit errors in the columns and rows of coded,
+// repeating while that makes progress since fixing a column can make a
+// row correctable and the other way round. It reports whether every row
+// and column ends up a valid codeword.
+func correct(coded *[codedBits]byte) bool {
+	for range 5 {
+		fixed := false
+		for col := range cols {
+			var c [rows]byte
+			for row := range rows {
+				c[row] = coded[1+row*cols+col]
+			}
+			if pos, ok := syndromeBit(c[:], hamming1393, hamming1393Syndromes); ok {
+				coded[1+pos*cols+col] ^= 1
+				fixed = true
+			}
+		}
+		for row := range dataRows {
+			if pos, ok := syndromeBit(coded[1+row*cols:1+(row+1)*cols], hamming15113, hamming15113Syndromes); ok {
+				coded[1+row*cols+pos] ^= 1
+				fixed = true
+			}
+		}
+		if !fixed {
+			break
+		}
+	}
+
+	for col := range cols {
+		var c [rows]byte
+		for row := range rows {
+			c[row] = coded[1+row*cols+col]
+		}
+		if syndrome(c[:], hamming1393) != 0 {
+			return false
+		}
+	}
+	for row := range dataRows {
+		if syndrome(coded[1+row*cols:1+(row+1)*cols], hamming15113) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// syndrome returns the parity bits encode computes for the data bits of
+// word XORed with the parity bits word carries, packed into an integer.
+// A valid codeword has a zero syndrome.
+func syndrome(word []byte, encode func([]byte)) int {
+	check := make([]byte, len(word))
+	copy(check, word)
+	encode(check)
+	s := 0
+	for i := range word {
+		s = s<<1 | int(check[i]^word[i])
+	}
+	return s
+}
+
+// syndromeBit returns the bit of word to flip to correct a single error,
+// if its syndrome identifies one.
+func syndromeBit(word []byte, encode func([]byte), syndromes map[int]int) (int, bool) {
+	s := syndrome(word, encode)
+	if s == 0 {
+		return 0, false
+	}
+	pos, ok := syndromes[s]
+	return pos, ok
+}
+
+// singleErrorSyndromes maps the syndrome of each single-bit error in an
+// n-bit codeword of the code encode implements to the bit in error.
+func singleErrorSyndromes(n int, encode func([]byte)) map[int]int {
+	syndromes := make(map[int]int, n)
+	for pos := range n {
+		word := make([]byte, n)
+		word[pos] = 1
+		syndromes[syndrome(word, encode)] = pos
+	}
+	return syndromes
+}
+
+var (
+	hamming15113Syndromes = singleErrorSyndromes(cols, hamming15113)
+	hamming1393Syndromes  = singleErrorSyndromes(rows, hamming1393)
+)
+
+// hamming15113 sets the four parity bits of a Hamming(15,11,3) row from
+// its eleven data bits.
+func hamming15113(d []byte) {
+	d[11] = d[0] ^ d[1] ^ d[2] ^ d[3] ^ d[5] ^ d[7] ^ d[8]
+	d[12] = d[1] ^ d[2] ^ d[3] ^ d[4] ^ d[6] ^ d[8] ^ d[9]
+	d[13] = d[2] ^ d[3] ^ d[4] ^ d[5] ^ d[7] ^ d[9] ^ d[10]
+	d[14] = d[0] ^ d[1] ^ d[2] ^ d[4] ^ d[6] ^ d[7] ^ d[10]
+}
+
+// hamming1393 sets the four parity bits of a Hamming(13,9,3) column from
+// its nine data bits.
+func hamming1393(d []byte) {
+	d[9] = d[0] ^ d[1] ^ d[3] ^ d[5] ^ d[6]
+	d[10] = d[0] ^ d[1] ^ d[2] ^ d[4] ^ d[6] ^ d[7]
+	d[11] = d[0] ^ d[1] ^ d[2] ^ d[3] ^ d[5] ^ d[7] ^ d[8]
+	d[12] = d[0] ^ d[2] ^ d[4] ^ d[5] ^ d[8]
+}
+
+// dataPositions returns the positions of the information bits among the
+// deinterleaved coded bits, in order. Bit 0 is unused and the 13 by 15
+// matrix follows it. The first nine rows each carry eleven bits and four
+// of Hamming parity, except that the first row opens with three reserved
+// bits and so carries eight. The last four rows are column parity.
+func dataPositions() []int {
+	positions := make([]int, 0, DataSize*8)
+	for pos := 4; pos <= 11; pos++ {
+		positions = append(positions, pos)
+	}
+	for row := 1; row < 9; row++ {
+		for col := range 11 {
+			positions = append(positions, row*15+1+col)
+		}
+	}
+	return positions
+}
+
+func bit(burst [BurstSize]byte, i int) byte {
+	return burst[i/8] >> (7 - i%8) & 1
+}
+
+// LCSize is the length of a full link control without its parity.
+const LCSize = 9
+
+// FragmentSize is the length of one embedded link control fragment.
+const FragmentSize = 4
+
+// Fragments is a link control embedded across voice bursts B to E, one
+// fragment per burst.
+type Fragments [4][FragmentSize]byte
+
+const (
+	embeddedBits = 128
+	embeddedRows = 8
+	embeddedCols = 16
+)
+
+// embeddedChecksumPositions are where the five checksum bits sit in the
+// embedded matrix, most significant first.
+var embeddedChecksumPositions = [5]int{42, 58, 74, 90, 106}
+
+// embeddedDataPositions returns the positions of the 72 link control bits
+// in the embedded matrix, in order: eleven bits on each of the first two
+// rows and ten, beside a checksum bit, on each of the next five.
+func embeddedDataPositions() []int {
+	positions := make([]int, 0, LCSize*8)
+	for row := range 7 {
+		width := 10
+		if row < 2 {
+			width = 11
+		}
+		for col := range width {
+			positions = append(positions, row*embeddedCols+col)
+		}
+	}
+	return positions
+}
+
+// EncodeEmbeddedLC codes lc for embedding in a voice superframe. Each of
+// the first seven rows of the matrix gets Hamming(16,11,4) parity, the
+// last row is column parity, and the matrix is sent down its columns.
+func EncodeEmbeddedLC(lc [LCSize]byte) Fragments {
+	var m [embeddedBits]byte
+	for n, pos := range embeddedDataPositions() {
+		m[pos] = lc[n/8] >> (7 - n%8) & 1
+	}
+	checksum := crc.LC5BitChecksum(lc)
+	for i, pos := range embeddedChecksumPositions {
+		m[pos] = checksum >> (4 - i) & 1
+	}
+	for row := range embeddedRows - 1 {
+		hamming16114(m[row*embeddedCols : (row+1)*embeddedCols])
+	}
+	for col := range embeddedCols {
+		var parity byte
+		for row := range embeddedRows - 1 {
+			parity ^= m[row*embeddedCols+col]
+		}
+		m[(embeddedRows-1)*embeddedCols+col] = parity
+	}
+
+	var out Fragments
+	for i := range embeddedBits {
+		if m[columnOrder(i)] != 0 {
+			out[i/32][i%32/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
+
+// DecodeEmbeddedLC reassembles the link control embedded in f. It
+// reports false if the checksum does not match. Errors are not
+// corrected.
+func DecodeEmbeddedLC(f Fragments) ([LCSize]byte, bool) {
+	var m [embeddedBits]byte
+	for i := range embeddedBits {
+		m[columnOrder(i)] = f[i/32][i%32/8] >> (7 - i%8) & 1
+	}
+	var lc [LCSize]byte
+	for n, pos := range embeddedDataPositions() {
+		if m[pos] != 0 {
+			lc[n/8] |= 0x80 >> (n % 8)
+		}
+	}
+	var checksum uint8
+	for _, pos := range embeddedChecksumPositions {
+		checksum = checksum<<1 | m[pos]
+	}
+	return lc, crc.CheckLC5BitChecksum(lc, checksum)
+}
+
+// columnOrder returns the matrix position of the i-th transmitted bit:
+// bits are sent down each column in turn.
+func columnOrder(i int) int {
+	return i%embeddedRows*embeddedCols + i/embeddedRows
+}
+
+// hamming16114 sets the five parity bits of a Hamming(16,11,4) row from
+// its eleven data bits.
+func hamming16114(d []byte) {
+	d[11] = d[0] ^ d[1] ^ d[2] ^ d[3] ^ d[5] ^ d[7] ^ d[8]
+	d[12] = d[1] ^ d[2] ^ d[3] ^ d[4] ^ d[6] ^ d[8] ^ d[9]
+	d[13] = d[2] ^ d[3] ^ d[4] ^ d[5] ^ d[7] ^ d[9] ^ d[10]
+	d[14] = d[0] ^ d[1] ^ d[2] ^ d[4] ^ d[6] ^ d[7] ^ d[10]
+	d[15] = d[0] ^ d[2] ^ d[5] ^ d[6] ^ d[8] ^ d[9] ^ d[10]
+}
@@ -0,0 +1,221 @@
+package bptc
+
+import "testing"
+
+// interleave places data in a burst the way a BPTC(196,96) encoder does,
+// leaving the parity bits clear.
+func interleave(data [DataSize]byte) [BurstSize]byte {
+	var coded [codedBits]byte
+	for n, pos := range dataPositions() {
+		coded[pos] = data[n/8] >> (7 - n%8) & 1
+	}
+	var burst [BurstSize]byte
+	for i := range codedBits {
+		j := interleaved(i)
+		if coded[i] != 0 {
+			burst[j/8] |= 0x80 >> (j % 8)
+		}
+	}
+	return burst
+}
+
+func TestExtract(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		data [DataSize]byte
+	}{
+		{"zero", [DataSize]byte{}},
+		{"ones", [DataSize]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}},
+		{"csbk", [DataSize]byte{0xA4, 0x10, 0x00, 0x7E, 0x2F, 0x9C, 0xE5, 0x2F, 0x9C, 0xE6, 0x12, 0x34}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := Extract(interleave(tt.data)); got != tt.data {
+				t.Fatalf("expected % X, got % X", tt.data, got)
+			}
+		})
+	}
+}
+
+func TestExtractIgnoresSyncAndSlotType(t *testing.T) {
+	t.Parallel()
+	data := [DataSize]byte{0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC, 0xDE, 0xF0, 0x0F, 0xED, 0xCB, 0xA9}
+	burst := interleave(data)
+	for i := halfBits; i < secondHalf; i++ {
+		burst[i/8] |= 0x80 >> (i % 8)
+	}
+	if got := Extract(burst); got != data {
+		t.Fatalf("expected % X, got % X", data, got)
+	}
+}
+
+func TestDataPositions(t *testing.T) {
+	t.Parallel()
+	positions := dataPositions()
+	if len(positions) != DataSize*8 {
+		t.Fatalf("expected %d positions, got %d", DataSize*8, len(positions))
+	}
+	seen := map[int]bool{}
+	for _, pos := range positions {
+		if pos < 4 || pos >= codedBits || seen[pos] {
+			t.Fatalf("position %d is reserved, out of range, or repeated", pos)
+		}
+		seen[pos] = true
+	}
+}
+
+var codedVectors = []struct {
+	name string
+	data [DataSize]byte
+}{
+	{"zero", [DataSize]byte{}},
+	{"ones", [DataSize]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}},
+	{"csbk", [DataSize]byte{0xA4, 0x10, 0x00, 0x7E, 0x2F, 0x9C, 0xE5, 0x2F, 0x9C, 0xE6, 0x12, 0x34}},
+	{"voice lc header", [DataSize]byte{0x00, 0x00, 0x20, 0x00, 0x0C, 0x30, 0x2F, 0x9B, 0xE5, 0x96, 0x6A, 0x3F}},
+}
+
+func TestInsertDecode(t *testing.T) {
+	t.Parallel()
+	for _, tt := range codedVectors {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var burst [BurstSize]byte
+			for i := halfBits; i < secondHalf; i++ {
+				burst[i/8] |= 0x80 >> (i % 8)
+			}
+			Insert(&burst, tt.data)
+			got, ok := Decode(burst)
+			if !ok || got != tt.data {
+				t.Fatalf("expected % X, got % X (ok %v)", tt.data, got, ok)
+			}
+			if got := Extract(burst); got != tt.data {
+				t.Fatalf("expected Extract to agree, got % X", got)
+			}
+			for i := halfBits; i < secondHalf; i++ {
+				if bit(burst, i) == 0 {
+					t.Fatalf("expected slot type and sync bit %d to be left set", i)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeCorrectsSingleBitErrors(t *testing.T) {
+	t.Parallel()
+	for _, tt := range codedVectors {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var clean [BurstSize]byte
+			Insert(&clean, tt.data)
+			for i := range codedBits {
+				j := i
+				if j >= halfBits {
+					j += secondHalf - halfBits
+				}
+				burst := clean
+				burst[j/8] ^= 0x80 >> (j % 8)
+				got, ok := Decode(burst)
+				if !ok || got != tt.data {
+					t.Fatalf("bit %d: expected % X, got % X (ok %v)", j, tt.data, got, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeReportsUncorrectableErrors(t *testing.T) {
+	t.Parallel()
+	var burst [BurstSize]byte
+	Insert(&burst, codedVectors[2].data)
+	// Twenty errors spread through the matrix put several in most rows
+	// and columns.
+	for k := range 20 {
+		j := interleaved(1 + k*37%(codedBits-1))
+		burst[j/8] ^= 0x80 >> (j % 8)
+	}
+	if _, ok := Decode(burst); ok {
+		t.Fatal("expected uncorrectable errors to be reported")
+	}
+}
+
+// interleaved returns the burst bit carrying coded bit i.
+func interleaved(i int) int {
+	j := i * interleaveStep % codedBits
+	if j >= halfBits {
+		j += secondHalf - halfBits
+	}
+	return j
+}
+
+func TestSingleErrorSyndromesAreDistinct(t *testing.T) {
+	t.Parallel()
+	if len(hamming15113Syndromes) != cols {
+		t.Fatalf("expected %d distinct row syndromes, got %d", cols, len(hamming15113Syndromes))
+	}
+	if len(hamming1393Syndromes) != rows {
+		t.Fatalf("expected %d distinct column syndromes, got %d", rows, len(hamming1393Syndromes))
+	}
+}
+
+func TestEmbeddedLCRoundTrip(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		lc   [LCSize]byte
+	}{
+		{"zero", [LCSize]byte{}},
+		{"group voice", [LCSize]byte{0x00, 0x00, 0x20, 0x00, 0x0C, 0x30, 0x2F, 0x9B, 0xE5}},
+		{"private voice", [LCSize]byte{0x03, 0x00, 0x20, 0x2F, 0x9C, 0xE6, 0x2F, 0x9B, 0xE5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			lc, ok := DecodeEmbeddedLC(EncodeEmbeddedLC(tt.lc))
+			if !ok {
+				t.Fatal("expected the checksum to verify")
+			}
+			if lc != tt.lc {
+				t.Fatalf("expected % X, got % X", tt.lc, lc)
+			}
+		})
+	}
+}
+
+func TestEmbeddedLCParity(t *testing.T) {
+	t.Parallel()
+	f := EncodeEmbeddedLC([LCSize]byte{0x00, 0x00, 0x20, 0x00, 0x0C, 0x30, 0x2F, 0x9B, 0xE5})
+	var m [embeddedBits]byte
+	for i := range embeddedBits {
+		m[columnOrder(i)] = f[i/32][i%32/8] >> (7 - i%8) & 1
+	}
+	for row := range embeddedRows - 1 {
+		got := m[row*embeddedCols : (row+1)*embeddedCols]
+		want := make([]byte, embeddedCols)
+		copy(want, got[:11])
+		hamming16114(want)
+		if string(got) != string(want) {
+			t.Fatalf("row %d: expected Hamming parity %v, got %v", row, want[11:], got[11:])
+		}
+	}
+	for col := range embeddedCols {
+		var parity byte
+		for row := range embeddedRows {
+			parity ^= m[row*embeddedCols+col]
+		}
+		if parity != 0 {
+			t.Fatalf("column %d: expected even parity", col)
+		}
+	}
+}
+
+func TestEmbeddedLCChecksumMismatch(t *testing.T) {
+	t.Parallel()
+	f := EncodeEmbeddedLC([LCSize]byte{0x00, 0x00, 0x20, 0x00, 0x0C, 0x30, 0x2F, 0x9B, 0xE5})
+	// The first transmitted bit is the first bit of the link control.
+	f[0][0] ^= 0x80
+	if _, ok := DecodeEmbeddedLC(f); ok {
+		t.Fatal("expected a corrupted link control to fail its checksum")
+	}
+}
@@ -0,0 +1,222 @@
+// Package crc implements the checksums of ETSI TS 102 361-1 Annex B:
+// the CRC-CCITT used by CSBKs and data headers, the CRC-9 of confirmed
+// data blocks, the CRC-32 that closes a data message, the Reed-Solomon
+// (12,9) parity of full link control, and the 5-bit checksum of embedded
+// link control. Each
+// variant is XORed with a mask that identifies the kind of block it
+// protects, so a block of one kind never verifies as another.
+package crc
+
+// Masks for the 16-bit CRC-CCITT (ETSI TS 102 361-1 Table B.21).
+const (
+	MaskPIHeader      uint16 = 0x6969
+	MaskCSBK          uint16 = 0xA5A5
+	MaskMBCHeader     uint16 = 0xAAAA
+	MaskDataHeader    uint16 = 0xCCCC
+	MaskUDTHeader     uint16 = 0x3333
+	MaskUnifiedSingle uint16 = 0x9999
+)
+
+// Masks for the CRC-9 of confirmed data blocks, by coding rate.
+const (
+	MaskRate12Data uint16 = 0x0F0
+	MaskRate34Data uint16 = 0x1FF
+	MaskRate1Data  uint16 = 0x10F
+)
+
+// Masks XORed over the three Reed-Solomon (12,9) parity octets of a full
+// link control, which distinguish a voice LC header from a terminator.
+const (
+	MaskVoiceHeader uint32 = 0x969696
+	MaskTerminator  uint32 = 0x999999
+)
+
+const (
+	ccittPoly = 0x1021
+	crc9Poly  = 0x059
+	crc9Bits  = 0x1FF
+	crc32Poly = 0x04C11DB7
+	// gfPoly is the primitive polynomial x^8+x^4+x^3+x^2+1 of the
+	// Reed-Solomon field.
+	gfPoly = 0x11D
+)
+
+// CCITT16 returns the DMR CRC-CCITT of data: polynomial
+// x^16+x^12+x^5+1, zero initial value, inverted, then XORed with mask.
+func CCITT16(data []byte, mask uint16) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ ccittPoly
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return ^crc ^ mask
+}
+
+// AppendCCITT16 returns block with its CRC-CCITT appended big-endian, the
+// way CSBKs and data headers carry it in their last two octets.
+func AppendCCITT16(block []byte, mask uint16) []byte {
+	crc := CCITT16(block, mask)
+	return append(block, byte(crc>>8), byte(crc))
+}
+
+// CheckCCITT16 reports whether the last two octets of block are the
+// CRC-CCITT of the rest under mask.
+func CheckCCITT16(block []byte, mask uint16) bool {
+	if len(block) < 2 {
+		return false
+	}
+	n := len(block) - 2
+	return CCITT16(block[:n], mask) == uint16(block[n])<<8|uint16(block[n+1])
+}
+
+// CRC9 returns the 9-bit CRC of a confirmed data block: polynomial
+// x^9+x^6+x^4+x^3+1 over the data octets followed by the 7-bit data block
+// serial number, zero initial value, inverted, then XORed with mask.
+func CRC9(data []byte, serial uint8, mask uint16) uint16 {
+	var crc uint16
+	bit := func(b uint16) {
+		if (crc>>8)&1^b != 0 {
+			crc = (crc<<1 ^ crc9Poly) & crc9Bits
+		} else {
+			crc = crc << 1 & crc9Bits
+		}
+	}
+	for _, b := range data {
+		for i := 7; i >= 0; i-- {
+			bit(uint16(b>>i) & 1)
+		}
+	}
+	for i := 6; i >= 0; i-- {
+		bit(uint16(serial>>i) & 1)
+	}
+	return (^crc ^ mask) & crc9Bits
+}
+
+// CheckCRC9 reports whether crc is the CRC-9 of data and serial under
+// mask.
+func CheckCRC9(data []byte, serial uint8, mask, crc uint16) bool {
+	return CRC9(data, serial, mask) == crc&crc9Bits
+}
+
+// CRC32 returns the CRC-32 carried in the last block of a data message:
+// polynomial 0x04C11DB7, zero initial value, no inversion, computed over
+// the message octets taken in pairs with the two octets of each pair
+// swapped. It is sent least significant octet first.
+func CRC32(data []byte) uint32 {
+	var crc uint32
+	feed := func(b byte) {
+		crc ^= uint32(b) << 24
+		for range 8 {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ crc32Poly
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	for i := 0; i+1 < len(data); i += 2 {
+		feed(data[i+1])
+		feed(data[i])
+	}
+	if len(data)%2 == 1 {
+		feed(data[len(data)-1])
+	}
+	return crc
+}
+
+// AppendCRC32 returns message with its CRC-32 appended least significant
+// octet first.
+func AppendCRC32(message []byte) []byte {
+	crc := CRC32(message)
+	return append(message, byte(crc), byte(crc>>8), byte(crc>>16), byte(crc>>24))
+}
+
+// CheckCRC32 reports whether the last four octets of message are the
+// CRC-32 of the rest.
+func CheckCRC32(message []byte) bool {
+	if len(message) < 4 {
+		return false
+	}
+	n := len(message) - 4
+	got := uint32(message[n]) | uint32(message[n+1])<<8 | uint32(message[n+2])<<16 | uint32(message[n+3])<<24
+	return CRC32(message[:n]) == got
+}
+
+// LC5BitChecksum returns the 5-bit checksum carried with an embedded link
+// control: the sum of its nine octets modulo 31.
+func LC5BitChecksum(lc [9]byte) uint8 {
+	var sum uint
+	for _, b := range lc {
+		sum += uint(b)
+	}
+	return uint8(sum % 31)
+}
+
+// CheckLC5BitChecksum reports whether checksum matches lc.
+func CheckLC5BitChecksum(lc [9]byte, checksum uint8) bool {
+	return LC5BitChecksum(lc) == checksum
+}
+
+// rs129Generator holds the coefficients of the Reed-Solomon (12,9)
+// generator polynomial (x+α)(x+α²)(x+α³), lowest degree first, without
+// its leading 1.
+var rs129Generator = [3]byte{0x40, 0x38, 0x0E}
+
+// RS129 returns the Reed-Solomon (12,9) parity of a full link control
+// XORed with mask, in the order it follows the nine link control octets.
+func RS129(lc [9]byte, mask uint32) [3]byte {
+	var parity [3]byte
+	for _, b := range lc {
+		feedback := b ^ parity[2]
+		parity[2] = parity[1] ^ gfMul(rs129Generator[2], feedback)
+		parity[1] = parity[0] ^ gfMul(rs129Generator[1], feedback)
+		parity[0] = gfMul(rs129Generator[0], feedback)
+	}
+	return [3]byte{
+		parity[2] ^ byte(mask>>16),
+		parity[1] ^ byte(mask>>8),
+		parity[0] ^ byte(mask),
+	}
+}
+
+// AppendRS129 returns the 12 octets of a full link control: lc followed
+// by its parity under mask.
+func AppendRS129(lc [9]byte, mask uint32) [12]byte {
+	var flc [12]byte
+	copy(flc[:9], lc[:])
+	parity := RS129(lc, mask)
+	copy(flc[9:], parity[:])
+	return flc
+}
+
+// CheckRS129 reports whether the last three octets of flc are the
+// Reed-Solomon (12,9) parity of the rest under mask. Errors are detected,
+// not corrected.
+func CheckRS129(flc [12]byte, mask uint32) bool {
+	var lc [9]byte
+	copy(lc[:], flc[:9])
+	return AppendRS129(lc, mask) == flc
+}
+
+// gfMul multiplies a and b in GF(2^8).
+func gfMul(a, b byte) byte {
+	var p byte
+	for b != 0 {
+		if b&1 != 0 {
+			p ^= a
+		}
+		carry := a&0x80 != 0
+		a <<= 1
+		if carry {
+			a ^= gfPoly & 0xFF
+		}
+		b >>= 1
+	}
+	return p
+}
@@ -0,0 +1,245 @@
+package crc
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestCCITT16Vectors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		data []byte
+		mask uint16
+		want uint16
+	}{
+		// Check value of CRC-16/GSM, which is the same algorithm before
+		// masking.
+		{"catalogue check", []byte("123456789"), 0, 0xCE3C},
+		{"csbk mask", []byte("123456789"), MaskCSBK, 0xCE3C ^ 0xA5A5},
+		{"data header mask", []byte("123456789"), MaskDataHeader, 0xCE3C ^ 0xCCCC},
+		{"empty", nil, 0, 0xFFFF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := CCITT16(tt.data, tt.mask); got != tt.want {
+				t.Fatalf("expected %#04x, got %#04x", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCCITT16RoundTrip(t *testing.T) {
+	t.Parallel()
+	// A 10-octet CSBK body, which the CRC extends to a 12-octet block.
+	block := AppendCCITT16([]byte{0x38, 0x00, 0x00, 0x00, 0x00, 0x09, 0x2F, 0x9B, 0xE5, 0x00}, MaskCSBK)
+	if len(block) != 12 {
+		t.Fatalf("expected a 12-octet CSBK, got %d", len(block))
+	}
+	if !CheckCCITT16(block, MaskCSBK) {
+		t.Fatal("expected the CSBK to verify")
+	}
+	if CheckCCITT16(block, MaskDataHeader) {
+		t.Fatal("expected a CSBK not to verify as a data header")
+	}
+	if CheckCCITT16(block[:1], MaskCSBK) {
+		t.Fatal("expected a short block not to verify")
+	}
+}
+
+func TestCRC9Vectors(t *testing.T) {
+	t.Parallel()
+	// Computed with an independent bitwise implementation.
+	tests := []struct {
+		name   string
+		data   []byte
+		serial uint8
+		mask   uint16
+		want   uint16
+	}{
+		{"ten octets, serial 5", []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 5, 0, 0x0FE},
+		{"rate 3/4 mask", []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 5, MaskRate34Data, 0x0FE ^ 0x1FF},
+		{"rate 1/2 mask", []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 5, MaskRate12Data, 0x0FE ^ 0x0F0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := CRC9(tt.data, tt.serial, tt.mask)
+			if got != tt.want {
+				t.Fatalf("expected %#03x, got %#03x", tt.want, got)
+			}
+			if !CheckCRC9(tt.data, tt.serial, tt.mask, got) {
+				t.Fatal("expected the CRC to verify")
+			}
+		})
+	}
+}
+
+func TestCRC9CoversSerial(t *testing.T) {
+	t.Parallel()
+	data := []byte{0xDE, 0xAD, 0xBE, 0xEF}
+	if CRC9(data, 1, MaskRate34Data) == CRC9(data, 2, MaskRate34Data) {
+		t.Fatal("expected the serial number to affect the CRC")
+	}
+}
+
+func TestCRC32(t *testing.T) {
+	t.Parallel()
+	// With its octet pairs swapped back, "214365879" is "123456789",
+	// whose CRC under this polynomial and initial value is the CRC-32/CKSUM
+	// check value 0x765E7680 before that variant's final inversion.
+	if got := CRC32([]byte("214365879")); got != 0x89A1897F {
+		t.Fatalf("expected 0x89A1897F, got %#08x", got)
+	}
+	if CRC32([]byte{0x01, 0x02}) == CRC32([]byte{0x02, 0x01}) {
+		t.Fatal("expected octet order within a pair to matter")
+	}
+
+	message := AppendCRC32([]byte{0x45, 0x00, 0x00, 0x20})
+	if len(message) != 8 {
+		t.Fatalf("expected 8 octets, got %d", len(message))
+	}
+	crc := CRC32(message[:4])
+	if message[4] != byte(crc) || message[7] != byte(crc>>24) {
+		t.Fatalf("expected the CRC least significant octet first, got % X", message[4:])
+	}
+	if !CheckCRC32(message) {
+		t.Fatal("expected the message to verify")
+	}
+	if CheckCRC32(message[:3]) {
+		t.Fatal("expected a short message not to verify")
+	}
+}
+
+func TestLC5BitChecksum(t *testing.T) {
+	t.Parallel()
+	// Group voice LC for TG 9 from 3120101.
+	lc := [9]byte{0x00, 0x00, 0x20, 0x00, 0x00, 0x09, 0x2F, 0x9B, 0xE5}
+	if got := LC5BitChecksum(lc); got != 7 {
+		t.Fatalf("expected 7, got %d", got)
+	}
+	if !CheckLC5BitChecksum(lc, 7) {
+		t.Fatal("expected the checksum to verify")
+	}
+	if CheckLC5BitChecksum([9]byte{}, 1) {
+		t.Fatal("expected a wrong checksum not to verify")
+	}
+}
+
+func TestRS129(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		lc   [9]byte
+	}{
+		{"zero", [9]byte{}},
+		// Group voice LC for TG 9 from 3120101.
+		{"group voice", [9]byte{0x00, 0x00, 0x20, 0x00, 0x00, 0x09, 0x2F, 0x9B, 0xE5}},
+		{"private voice", [9]byte{0x03, 0x00, 0x20, 0x2F, 0x9C, 0xE6, 0x2F, 0x9B, 0xE5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			// Without a mask the link control and parity form a codeword,
+			// whose polynomial has the generator's roots α, α², and α³.
+			codeword := AppendRS129(tt.lc, 0)
+			root := byte(1)
+			for k := 1; k <= 3; k++ {
+				root = gfMul(root, 2)
+				var v byte
+				for _, c := range codeword {
+					v = gfMul(v, root) ^ c
+				}
+				if v != 0 {
+					t.Fatalf("expected α^%d to be a root of % X, got %02X", k, codeword, v)
+				}
+			}
+
+			header := AppendRS129(tt.lc, MaskVoiceHeader)
+			terminator := AppendRS129(tt.lc, MaskTerminator)
+			if header[9] != codeword[9]^0x96 || terminator[9] != codeword[9]^0x99 {
+				t.Fatalf("expected the masks over the parity, got % X and % X", header[9:], terminator[9:])
+			}
+			if !CheckRS129(header, MaskVoiceHeader) || !CheckRS129(terminator, MaskTerminator) {
+				t.Fatal("expected the parity to verify under its own mask")
+			}
+			if CheckRS129(header, MaskTerminator) || CheckRS129(terminator, MaskVoiceHeader) {
+				t.Fatal("expected a header not to verify as a terminator or the other way round")
+			}
+		})
+	}
+}
+
+func TestGFMul(t *testing.T) {
+	t.Parallel()
+	// α^8 reduces by the primitive polynomial to x^4+x^3+x^2+1.
+	if got := gfMul(0x80, 0x02); got != 0x1D {
+		t.Fatalf("expected 0x1D, got 0x%02X", got)
+	}
+	// The generator's coefficients are products of α, α², and α³.
+	if got := gfMul(gfMul(0x02, 0x04), 0x08); got != rs129Generator[0] {
+		t.Fatalf("expected 0x%02X, got 0x%02X", rs129Generator[0], got)
+	}
+}
+
+// flipEachBit calls fn with a copy of data for every single-bit corruption.
+func flipEachBit(data []byte, fn func(corrupted []byte, bit int)) {
+	for bit := range len(data) * 8 {
+		corrupted := append([]byte(nil), data...)
+		corrupted[bit/8] ^= 0x80 >> (bit % 8)
+		fn(corrupted, bit)
+	}
+}
+
+func randomBytes(r *rand.Rand, n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(r.UintN(256))
+	}
+	return b
+}
+
+func TestDetectsSingleBitCorruption(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewPCG(1, 2)) //nolint:gosec // deterministic test data
+
+	for range 100 {
+		block := AppendCCITT16(randomBytes(r, 10), MaskCSBK)
+		flipEachBit(block, func(corrupted []byte, bit int) {
+			if CheckCCITT16(corrupted, MaskCSBK) {
+				t.Fatalf("CRC-CCITT missed a flip of bit %d in % X", bit, block)
+			}
+		})
+
+		data := randomBytes(r, 16)
+		serial := uint8(r.UintN(128)) //nolint:gosec // < 128
+		crc := CRC9(data, serial, MaskRate34Data)
+		flipEachBit(data, func(corrupted []byte, bit int) {
+			if CheckCRC9(corrupted, serial, MaskRate34Data, crc) {
+				t.Fatalf("CRC-9 missed a flip of bit %d in % X", bit, data)
+			}
+		})
+		for bit := range 9 {
+			if CheckCRC9(data, serial, MaskRate34Data, crc^1<<bit) {
+				t.Fatalf("CRC-9 missed a flip of CRC bit %d", bit)
+			}
+		}
+
+		message := AppendCRC32(randomBytes(r, 32))
+		flipEachBit(message, func(corrupted []byte, bit int) {
+			if CheckCRC32(corrupted) {
+				t.Fatalf("CRC-32 missed a flip of bit %d in % X", bit, message)
+			}
+		})
+
+		var lc [9]byte
+		copy(lc[:], randomBytes(r, 9))
+		checksum := LC5BitChecksum(lc)
+		flipEachBit(lc[:], func(corrupted []byte, bit int) {
+			if CheckLC5BitChecksum([9]byte(corrupted), checksum) {
+				t.Fatalf("5-bit checksum missed a flip of bit %d in % X", bit, lc)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// Package csbk parses and builds the DMR control signalling blocks
+// (CSBKs) that ipsc2mmdvm acts on. A CSBK is ten octets of content
+// followed by a CRC-CCITT masked with crc.MaskCSBK.
+package csbk
+
+import (
+	"errors"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/crc"
+)
+
+// Size is the length of a CSBK including its CRC.
+const Size = 12
+
+// Block is one CSBK including its CRC.
+type Block [Size]byte
+
+var (
+	// ErrBadCRC is returned for a CSBK whose CRC does not verify.
+	ErrBadCRC = errors.New("CSBK CRC mismatch")
+	// ErrUnexpectedOpcode is returned when a CSBK is not of the type
+	// being parsed.
+	ErrUnexpectedOpcode = errors.New("unexpected CSBK opcode")
+)
+
+const (
+	// OpcodeExtFunction is the extended function CSBK (Ext_Fnct), which
+	// carries radio check, radio inhibit and uninhibit, and their
+	// acknowledgments.
+	OpcodeExtFunction byte = 0x24
+	// OpcodeCallAlert pages the target radio.
+	OpcodeCallAlert byte = 0x1F
+	// OpcodeBSOutboundActivation wakes a repeater to key up its
+	// outbound channel.
+	OpcodeBSOutboundActivation byte = 0x38
+
+	// FunctionRadioCheck asks the target whether it is reachable.
+	FunctionRadioCheck byte = 0x00
+	// FunctionRadioKill disables the target permanently.
+	FunctionRadioKill byte = 0x7D
+	// FunctionRadioInhibit stuns the target, which stops transmitting
+	// and ignores its user until it is revived.
+	FunctionRadioInhibit byte = 0x7E
+	// FunctionRadioUninhibit revives a stunned target.
+	FunctionRadioUninhibit byte = 0x7F
+
+	// lastBlock is the LB bit of octet 0, set on every single-block CSBK.
+	lastBlock = 0x80
+	// opcodeMask selects the CSBK opcode from octet 0.
+	opcodeMask = 0x3F
+	// classResponse marks an extended function CSBK as the target's
+	// acknowledgment rather than the request.
+	classResponse = 0x80
+)
+
+// Opcode returns the opcode of a CSBK without checking its CRC.
+func Opcode(b Block) byte {
+	return b[0] & opcodeMask
+}
+
+// ExtFunction is an extended function CSBK.
+type ExtFunction struct {
+	// FID is the feature set ID, 0x00 for ETSI standard features and
+	// 0x10 for Motorola's.
+	FID byte
+	// Response is set on the acknowledgment sent back by the target.
+	Response bool
+	// Function selects the operation, such as FunctionRadioCheck.
+	Function byte
+	// Dst is the radio the function is aimed at and Src the requester,
+	// on the request; the acknowledgment swaps them.
+	Dst, Src uint32
+}
+
+// ParseExtFunction decodes b as an extended function CSBK.
+func ParseExtFunction(b Block) (ExtFunction, error) {
+	if !crc.CheckCCITT16(b[:], crc.MaskCSBK) {
+		return ExtFunction{}, ErrBadCRC
+	}
+	if Opcode(b) != OpcodeExtFunction {
+		return ExtFunction{}, ErrUnexpectedOpcode
+	}
+	return ExtFunction{
+		FID:      b[1],
+		Response: b[2]&classResponse != 0,
+		Function: b[3],
+		Dst:      uint32(b[4])<<16 | uint32(b[5])<<8 | uint32(b[6]),
+		Src:      uint32(b[7])<<16 | uint32(b[8])<<8 | uint32(b[9]),
+	}, nil
+}
+
+// IsRadioCheck reports whether f is a radio check request.
+func (f ExtFunction) IsRadioCheck() bool {
+	return f.Function == FunctionRadioCheck && !f.Response
+}
+
+// Ack returns the acknowledgment the target of f sends back.
+func (f ExtFunction) Ack() ExtFunction {
+	return ExtFunction{
+		FID:      f.FID,
+		Response: true,
+		Function: f.Function,
+		Dst:      f.Src,
+		Src:      f.Dst,
+	}
+}
+
+// Encode returns f as a single CSBK with its CRC.
+func (f ExtFunction) Encode() Block {
+	var body [Size - 2]byte
+	body[0] = lastBlock | OpcodeExtFunction
+	body[1] = f.FID
+	if f.Response {
+		body[2] = classResponse
+	}
+	body[3] = f.Function
+	body[4], body[5], body[6] = byte(f.Dst>>16), byte(f.Dst>>8), byte(f.Dst)
+	body[7], body[8], body[9] = byte(f.Src>>16), byte(f.Src>>8), byte(f.Src)
+	return Block(crc.AppendCCITT16(body[:], crc.MaskCSBK))
+}
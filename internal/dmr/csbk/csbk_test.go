@@ -0,0 +1,69 @@
+package csbk
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/crc"
+)
+
+func TestExtFunctionRoundTrip(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		f    ExtFunction
+	}{
+		{"radio check", ExtFunction{FID: 0x10, Function: FunctionRadioCheck, Dst: 3120101, Src: 3120102}},
+		{"radio check ack", ExtFunction{FID: 0x10, Response: true, Function: FunctionRadioCheck, Dst: 3120102, Src: 3120101}},
+		{"other function", ExtFunction{Function: 0x7E, Dst: 1, Src: 0xFFFFFF}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			b := tt.f.Encode()
+			if b[0]&lastBlock == 0 {
+				t.Fatal("expected the last block bit to be set")
+			}
+			if Opcode(b) != OpcodeExtFunction {
+				t.Fatalf("expected opcode %#x, got %#x", OpcodeExtFunction, Opcode(b))
+			}
+			got, err := ParseExtFunction(b)
+			if err != nil {
+				t.Fatalf("ParseExtFunction: %v", err)
+			}
+			if got != tt.f {
+				t.Fatalf("expected %+v, got %+v", tt.f, got)
+			}
+		})
+	}
+}
+
+func TestParseExtFunctionErrors(t *testing.T) {
+	t.Parallel()
+	corrupted := ExtFunction{Dst: 1, Src: 2}.Encode()
+	corrupted[5] ^= 0x01
+	if _, err := ParseExtFunction(corrupted); !errors.Is(err, ErrBadCRC) {
+		t.Fatalf("expected %v, got %v", ErrBadCRC, err)
+	}
+
+	// A well-formed CSBK of another type, a BS outbound activation.
+	other := Block(crc.AppendCCITT16([]byte{0xB8, 0, 0, 0, 0, 0, 0, 0, 0, 1}, crc.MaskCSBK))
+	if _, err := ParseExtFunction(other); !errors.Is(err, ErrUnexpectedOpcode) {
+		t.Fatalf("expected %v, got %v", ErrUnexpectedOpcode, err)
+	}
+}
+
+func TestAck(t *testing.T) {
+	t.Parallel()
+	req := ExtFunction{FID: 0x10, Function: FunctionRadioCheck, Dst: 3120101, Src: 3120102}
+	if !req.IsRadioCheck() {
+		t.Fatal("expected a radio check request")
+	}
+	ack := req.Ack()
+	if ack.IsRadioCheck() {
+		t.Fatal("expected the acknowledgment not to be a request")
+	}
+	if !ack.Response || ack.Src != req.Dst || ack.Dst != req.Src || ack.FID != req.FID || ack.Function != req.Function {
+		t.Fatalf("unexpected acknowledgment %+v", ack)
+	}
+}
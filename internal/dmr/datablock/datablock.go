@@ -0,0 +1,223 @@
+// Package datablock codes the information octets of DMR data continuation
+// blocks that are not BPTC coded: rate 3/4 blocks, protected by the
+// trellis code of ETSI TS 102 361-1 Annex B.2, and uncoded rate 1 blocks.
+// Rate 1/2 blocks and data headers use BPTC(196,96) like CSBKs.
+package datablock
+
+// BurstSize is the length of a DMR burst in octets.
+const BurstSize = 33
+
+// Rate34Size is the number of information octets of a rate 3/4 block.
+const Rate34Size = 18
+
+// Rate1Size is the number of information octets of a rate 1 block.
+const Rate1Size = 24
+
+const (
+	// halfBits is the number of information bits on each side of the 68
+	// bits of slot type and sync in the middle of the burst.
+	halfBits = 98
+	// secondHalf is the first burst bit after the slot type and sync.
+	secondHalf = 166
+	// dibits is the number of dibits the information bits of a burst
+	// carry.
+	dibits = halfBits
+	// tribits is the number of trellis input symbols, the last of which
+	// flushes the encoder back to state 0.
+	tribits = dibits / 2
+)
+
+// encodeTable gives the constellation point sent for a tribit, indexed by
+// the previous tribit (the encoder state) times 8 plus the tribit.
+var encodeTable = [64]byte{
+	0, 8, 4, 12, 2, 10, 6, 14,
+	4, 12, 2, 10, 6, 14, 0, 8,
+	1, 9, 5, 13, 3, 11, 7, 15,
+	5, 13, 3, 11, 7, 15, 1, 9,
+	3, 11, 7, 15, 1, 9, 5, 13,
+	7, 15, 1, 9, 5, 13, 3, 11,
+	2, 10, 6, 14, 0, 8, 4, 12,
+	6, 14, 0, 8, 4, 12, 2, 10,
+}
+
+// pointDibits maps each constellation point to its pair of dibit
+// symbols, -3, -1, +1, and +3.
+var pointDibits = [16][2]int8{
+	{+1, -1}, {-1, -1}, {+3, -3}, {-3, -3},
+	{-3, -1}, {+3, -1}, {-1, -3}, {+1, -3},
+	{-3, +3}, {+3, +3}, {-1, +1}, {+1, +1},
+	{+1, +3}, {-1, +3}, {+3, +1}, {-3, +1},
+}
+
+// interleaveTable gives, for each dibit sent in the burst, its position in
+// the coded sequence.
+var interleaveTable = [dibits]int{
+	0, 1, 8, 9, 16, 17, 24, 25, 32, 33, 40, 41, 48, 49, 56, 57, 64, 65, 72, 73, 80, 81, 88, 89, 96, 97,
+	2, 3, 10, 11, 18, 19, 26, 27, 34, 35, 42, 43, 50, 51, 58, 59, 66, 67, 74, 75, 82, 83, 90, 91,
+	4, 5, 12, 13, 20, 21, 28, 29, 36, 37, 44, 45, 52, 53, 60, 61, 68, 69, 76, 77, 84, 85, 92, 93,
+	6, 7, 14, 15, 22, 23, 30, 31, 38, 39, 46, 47, 54, 55, 62, 63, 70, 71, 78, 79, 86, 87, 94, 95,
+}
+
+// ExtractRate34 returns the information octets of a rate 3/4 burst. It
+// reports false if the coded bits are not a valid trellis path. Errors
+// are detected but not corrected.
+func ExtractRate34(burst [BurstSize]byte) ([Rate34Size]byte, bool) {
+	var coded [dibits]int8
+	for i := range dibits {
+		coded[interleaveTable[i]] = symbol(infoBit(burst, 2*i), infoBit(burst, 2*i+1))
+	}
+
+	var out [Rate34Size]byte
+	state := byte(0)
+	for i := range tribits {
+		point, ok := dibitPoint(coded[2*i], coded[2*i+1])
+		if !ok {
+			return [Rate34Size]byte{}, false
+		}
+		tribit, ok := decodeTribit(state, point)
+		if !ok {
+			return [Rate34Size]byte{}, false
+		}
+		if i == tribits-1 {
+			return out, tribit == 0
+		}
+		for b := range 3 {
+			if tribit>>(2-b)&1 != 0 {
+				n := i*3 + b
+				out[n/8] |= 0x80 >> (n % 8)
+			}
+		}
+		state = tribit
+	}
+	return out, true
+}
+
+// InsertRate34 trellis codes data into the information bits of burst,
+// leaving its slot type and sync as they are.
+func InsertRate34(burst *[BurstSize]byte, data [Rate34Size]byte) {
+	var coded [dibits]int8
+	state := byte(0)
+	for i := range tribits {
+		var tribit byte
+		if i < tribits-1 {
+			for b := range 3 {
+				n := i*3 + b
+				tribit = tribit<<1 | data[n/8]>>(7-n%8)&1
+			}
+		}
+		point := encodeTable[state*8+tribit]
+		coded[2*i], coded[2*i+1] = pointDibits[point][0], pointDibits[point][1]
+		state = tribit
+	}
+
+	for i := range dibits {
+		hi, lo := symbolBits(coded[interleaveTable[i]])
+		setInfoBit(burst, 2*i, hi)
+		setInfoBit(burst, 2*i+1, lo)
+	}
+}
+
+// ExtractRate1 returns the information octets of a rate 1 burst. The 192
+// bits fill the first 96 information bits on each side of the slot type
+// and sync; the last two bits of each side are reserved.
+func ExtractRate1(burst [BurstSize]byte) [Rate1Size]byte {
+	var out [Rate1Size]byte
+	for n := range Rate1Size * 8 {
+		if infoBit(burst, rate1Position(n)) != 0 {
+			out[n/8] |= 0x80 >> (n % 8)
+		}
+	}
+	return out
+}
+
+// InsertRate1 places data in the information bits of burst, leaving its
+// slot type and sync as they are and clearing the reserved bits.
+func InsertRate1(burst *[BurstSize]byte, data [Rate1Size]byte) {
+	for i := range 2 * halfBits {
+		setInfoBit(burst, i, 0)
+	}
+	for n := range Rate1Size * 8 {
+		setInfoBit(burst, rate1Position(n), data[n/8]>>(7-n%8)&1)
+	}
+}
+
+// rate1Position returns the information bit carrying bit n of a rate 1
+// block.
+func rate1Position(n int) int {
+	const perSide = Rate1Size * 8 / 2
+	if n < perSide {
+		return n
+	}
+	return halfBits + n - perSide
+}
+
+// decodeTribit returns the tribit that moves the encoder out of state by
+// sending point.
+func decodeTribit(state, point byte) (byte, bool) {
+	for tribit := range byte(8) {
+		if encodeTable[state*8+tribit] == point {
+			return tribit, true
+		}
+	}
+	return 0, false
+}
+
+// dibitPoint returns the constellation point sent as the symbols a and b.
+func dibitPoint(a, b int8) (byte, bool) {
+	for point, pair := range pointDibits {
+		if pair[0] == a && pair[1] == b {
+			return byte(point), true //nolint:gosec // G115: point is in [0,15]
+		}
+	}
+	return 0, false
+}
+
+// symbol returns the dibit symbol sent as the bits hi and lo.
+func symbol(hi, lo byte) int8 {
+	switch {
+	case hi == 0 && lo == 1:
+		return +3
+	case hi == 0 && lo == 0:
+		return +1
+	case hi == 1 && lo == 0:
+		return -1
+	default:
+		return -3
+	}
+}
+
+// symbolBits returns the bits that send the dibit symbol s.
+func symbolBits(s int8) (byte, byte) {
+	switch s {
+	case +3:
+		return 0, 1
+	case +1:
+		return 0, 0
+	case -1:
+		return 1, 0
+	default:
+		return 1, 1
+	}
+}
+
+// burstBit returns the burst bit holding information bit i.
+func burstBit(i int) int {
+	if i >= halfBits {
+		return i - halfBits + secondHalf
+	}
+	return i
+}
+
+func infoBit(burst [BurstSize]byte, i int) byte {
+	j := burstBit(i)
+	return burst[j/8] >> (7 - j%8) & 1
+}
+
+func setInfoBit(burst *[BurstSize]byte, i int, v byte) {
+	j := burstBit(i)
+	if v != 0 {
+		burst[j/8] |= 0x80 >> (j % 8)
+	} else {
+		burst[j/8] &^= 0x80 >> (j % 8)
+	}
+}
@@ -0,0 +1,76 @@
+package datablock
+
+import "testing"
+
+// syncAndSlotTypeOf returns burst with every slot type and sync bit set.
+// Coding into a burst built this way must leave it unchanged.
+func syncAndSlotTypeOf(burst [BurstSize]byte) [BurstSize]byte {
+	for i := halfBits; i < secondHalf; i++ {
+		burst[i/8] |= 0x80 >> (i % 8)
+	}
+	return burst
+}
+
+func TestRate34RoundTrip(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		data [Rate34Size]byte
+	}{
+		{"zero", [Rate34Size]byte{}},
+		{"ones", [Rate34Size]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}},
+		{"sms", [Rate34Size]byte{0x00, 0x48, 0x00, 0x65, 0x00, 0x6C, 0x00, 0x6C, 0x00, 0x6F, 0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC, 0xDE, 0xF0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			burst := syncAndSlotTypeOf([BurstSize]byte{})
+			InsertRate34(&burst, tt.data)
+			got, ok := ExtractRate34(burst)
+			if !ok {
+				t.Fatal("expected a valid trellis path")
+			}
+			if got != tt.data {
+				t.Fatalf("expected % X, got % X", tt.data, got)
+			}
+			if burst != syncAndSlotTypeOf(burst) {
+				t.Fatal("expected slot type and sync to be left as they were")
+			}
+		})
+	}
+}
+
+func TestRate34DetectsErrors(t *testing.T) {
+	t.Parallel()
+	var burst [BurstSize]byte
+	InsertRate34(&burst, [Rate34Size]byte{0x12, 0x34, 0x56})
+	// Flip the bits of one dibit so it sends a different symbol.
+	burst[4] ^= 0xC0
+	if _, ok := ExtractRate34(burst); ok {
+		t.Fatal("expected a corrupted burst to be rejected")
+	}
+}
+
+func TestRate1RoundTrip(t *testing.T) {
+	t.Parallel()
+	var data [Rate1Size]byte
+	for i := range data {
+		data[i] = byte(i*37 + 1)
+	}
+	burst := syncAndSlotTypeOf([BurstSize]byte{})
+	for i := range halfBits {
+		burst[i/8] |= 0x80 >> (i % 8)
+	}
+	InsertRate1(&burst, data)
+	if got := ExtractRate1(burst); got != data {
+		t.Fatalf("expected % X, got % X", data, got)
+	}
+	if burst != syncAndSlotTypeOf(burst) {
+		t.Fatal("expected slot type and sync to be left as they were")
+	}
+	for _, i := range []int{96, 97, 262, 263} {
+		if burst[i/8]>>(7-i%8)&1 != 0 {
+			t.Fatalf("expected reserved bit %d to be cleared", i)
+		}
+	}
+}
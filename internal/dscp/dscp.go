@@ -0,0 +1,66 @@
+// Package dscp marks the datagrams sent on a UDP socket with a DSCP code
+// point, so congested links can give voice priority.
+package dscp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// MaxCode is the largest DSCP code point.
+const MaxCode = 63
+
+var (
+	// ErrRefused is returned when the operating system refuses to mark a
+	// socket.
+	ErrRefused = errors.New("failed to set DSCP marking on socket")
+	// ErrUnsupportedPlatform is returned by SocketSetter where sockets
+	// cannot be marked.
+	ErrUnsupportedPlatform = errors.New("DSCP marking is unsupported on this platform")
+)
+
+// Setter sets the traffic class byte of a socket: the ToS byte of an IPv4
+// socket, and the traffic class of an IPv6 one.
+type Setter interface {
+	SetTOS(conn syscall.RawConn, tos int) error
+	SetTrafficClass(conn syscall.RawConn, class int) error
+}
+
+// SocketSetter is the Setter that calls setsockopt.
+type SocketSetter struct{}
+
+// Mark marks the datagrams sent on conn with code through setter, or
+// through SocketSetter if setter is nil. Code 0, the default class, leaves
+// conn as it is. An IPv6 socket is given the IPv4 ToS as well, where the
+// system allows it, for the IPv4 peers of a dual-stack socket.
+func Mark(setter Setter, conn net.Conn, code uint8) error {
+	if code == 0 {
+		return nil
+	}
+	if setter == nil {
+		setter = SocketSetter{}
+	}
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return fmt.Errorf("%w: DSCP %d: %T has no file descriptor", ErrRefused, code, conn)
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("%w: DSCP %d: %w", ErrRefused, code, err)
+	}
+
+	class := int(code) << 2
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok && addr.IP.To4() == nil {
+		if err := setter.SetTrafficClass(raw, class); err != nil {
+			return fmt.Errorf("%w: DSCP %d: %w", ErrRefused, code, err)
+		}
+		_ = setter.SetTOS(raw, class) //nolint:errcheck // only some systems mark IPv4 on IPv6 sockets
+		return nil
+	}
+	if err := setter.SetTOS(raw, class); err != nil {
+		return fmt.Errorf("%w: DSCP %d: %w", ErrRefused, code, err)
+	}
+	return nil
+}
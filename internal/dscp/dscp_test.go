@@ -0,0 +1,72 @@
+package dscp
+
+import (
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+)
+
+// fakeSetter records the marks set through it.
+type fakeSetter struct {
+	tos, class int
+	err        error
+}
+
+func (f *fakeSetter) SetTOS(_ syscall.RawConn, tos int) error {
+	f.tos = tos
+	return f.err
+}
+
+func (f *fakeSetter) SetTrafficClass(_ syscall.RawConn, class int) error {
+	f.class = class
+	return f.err
+}
+
+func listen(t *testing.T, ip net.IP) *net.UDPConn {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: ip})
+	if err != nil {
+		t.Skipf("listen on %v: %v", ip, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestMark(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		ip        net.IP
+		code      uint8
+		wantTOS   int
+		wantClass int
+	}{
+		{"ipv4 expedited forwarding", net.IPv4(127, 0, 0, 1), 46, 0xB8, 0},
+		{"ipv4 class selector", net.IPv4(127, 0, 0, 1), 8, 0x20, 0},
+		{"ipv6 expedited forwarding", net.IPv6loopback, 46, 0xB8, 0xB8},
+		{"default class untouched", net.IPv4(127, 0, 0, 1), 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			setter := &fakeSetter{}
+			if err := Mark(setter, listen(t, tt.ip), tt.code); err != nil {
+				t.Fatalf("Mark: %v", err)
+			}
+			if setter.tos != tt.wantTOS || setter.class != tt.wantClass {
+				t.Fatalf("expected ToS 0x%02X and traffic class 0x%02X, got 0x%02X and 0x%02X",
+					tt.wantTOS, tt.wantClass, setter.tos, setter.class)
+			}
+		})
+	}
+}
+
+func TestMarkRefused(t *testing.T) {
+	t.Parallel()
+	refusal := errors.New("operation not permitted")
+	err := Mark(&fakeSetter{err: refusal}, listen(t, net.IPv4(127, 0, 0, 1)), 46)
+	if !errors.Is(err, ErrRefused) || !errors.Is(err, refusal) {
+		t.Fatalf("expected the refusal wrapped in ErrRefused, got %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package dscp
+
+import "syscall"
+
+// SetTOS returns ErrUnsupportedPlatform.
+func (SocketSetter) SetTOS(syscall.RawConn, int) error {
+	return ErrUnsupportedPlatform
+}
+
+// SetTrafficClass returns ErrUnsupportedPlatform.
+func (SocketSetter) SetTrafficClass(syscall.RawConn, int) error {
+	return ErrUnsupportedPlatform
+}
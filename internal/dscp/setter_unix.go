@@ -0,0 +1,25 @@
+//go:build unix
+
+package dscp
+
+import "syscall"
+
+// SetTOS sets IP_TOS on conn.
+func (SocketSetter) SetTOS(conn syscall.RawConn, tos int) error {
+	return setsockopt(conn, syscall.IPPROTO_IP, syscall.IP_TOS, tos)
+}
+
+// SetTrafficClass sets IPV6_TCLASS on conn.
+func (SocketSetter) SetTrafficClass(conn syscall.RawConn, class int) error {
+	return setsockopt(conn, syscall.IPPROTO_IPV6, syscall.IPV6_TCLASS, class)
+}
+
+func setsockopt(conn syscall.RawConn, level, opt, value int) error {
+	var sockErr error
+	if err := conn.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), level, opt, value)
+	}); err != nil {
+		return err
+	}
+	return sockErr
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package dscp
+
+import (
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestSocketSetterMarksSocket(t *testing.T) {
+	t.Parallel()
+	conn := listen(t, net.IPv4(127, 0, 0, 1))
+	if err := Mark(nil, conn, 46); err != nil {
+		t.Fatalf("Mark: %v", err)
+	}
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		t.Fatalf("SyscallConn: %v", err)
+	}
+	var tos int
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		tos, sockErr = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TOS)
+	}); err != nil || sockErr != nil {
+		t.Fatalf("getsockopt: %v %v", err, sockErr)
+	}
+	if tos != 46<<2 {
+		t.Fatalf("expected ToS 0x%02X, got 0x%02X", 46<<2, tos)
+	}
+}
@@ -0,0 +1,106 @@
+// Package expiry provides a concurrency-safe map whose entries expire a
+// fixed time after they were last set.
+package expiry
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepInterval bounds how often Set scans for expired entries, so
+// entries that are never read again do not accumulate.
+const sweepInterval = time.Second
+
+type entry[V any] struct {
+	value    V
+	deadline time.Time
+}
+
+// Map is a map whose entries expire. The zero value is not usable; create
+// one with New.
+type Map[K comparable, V any] struct {
+	mu        sync.Mutex
+	entries   map[K]entry[V]
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// New returns an empty Map.
+func New[K comparable, V any]() *Map[K, V] {
+	return &Map[K, V]{
+		entries: map[K]entry[V]{},
+		now:     time.Now,
+	}
+}
+
+// Set stores value under key until ttl has passed, replacing any earlier
+// value and deadline.
+func (m *Map[K, V]) Set(key K, value V, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		m.sweep(now)
+	}
+	m.entries[key] = entry[V]{value: value, deadline: now.Add(ttl)}
+}
+
+// Get returns the value stored under key if it has not expired.
+func (m *Map[K, V]) Get(key K) (V, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	if !m.now().Before(e.deadline) {
+		delete(m.entries, key)
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// Take returns the value stored under key if it has not expired, and
+// removes it.
+func (m *Map[K, V]) Take(key K) (V, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	delete(m.entries, key)
+	if !m.now().Before(e.deadline) {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// Delete removes key.
+func (m *Map[K, V]) Delete(key K) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+}
+
+// Len returns the number of entries that have not expired.
+func (m *Map[K, V]) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(m.now())
+	return len(m.entries)
+}
+
+// sweep removes expired entries. Must be called with m.mu held.
+func (m *Map[K, V]) sweep(now time.Time) {
+	for key, e := range m.entries {
+		if !now.Before(e.deadline) {
+			delete(m.entries, key)
+		}
+	}
+	m.lastSweep = now
+}
@@ -0,0 +1,105 @@
+package expiry
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestMap() (*Map[string, int], *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	m := New[string, int]()
+	m.now = clock.Now
+	return m, clock
+}
+
+func TestGetExpires(t *testing.T) {
+	t.Parallel()
+	m, clock := newTestMap()
+	m.Set("a", 1, 2*time.Second)
+
+	clock.Advance(time.Second)
+	if v, ok := m.Get("a"); !ok || v != 1 {
+		t.Fatalf("expected 1 before the deadline, got %d %t", v, ok)
+	}
+	clock.Advance(time.Second)
+	if _, ok := m.Get("a"); ok {
+		t.Fatal("expected the entry to expire at its deadline")
+	}
+	if _, ok := m.Get("missing"); ok {
+		t.Fatal("expected a missing key not to be found")
+	}
+}
+
+func TestSetReplacesDeadline(t *testing.T) {
+	t.Parallel()
+	m, clock := newTestMap()
+	m.Set("a", 1, time.Second)
+	clock.Advance(900 * time.Millisecond)
+	m.Set("a", 2, time.Second)
+	clock.Advance(900 * time.Millisecond)
+	if v, ok := m.Get("a"); !ok || v != 2 {
+		t.Fatalf("expected the refreshed value 2, got %d %t", v, ok)
+	}
+}
+
+func TestTakeAndDelete(t *testing.T) {
+	t.Parallel()
+	m, clock := newTestMap()
+	m.Set("a", 1, time.Second)
+	m.Set("b", 2, time.Second)
+	m.Set("c", 3, time.Second)
+
+	if v, ok := m.Take("a"); !ok || v != 1 {
+		t.Fatalf("expected to take 1, got %d %t", v, ok)
+	}
+	if _, ok := m.Get("a"); ok {
+		t.Fatal("expected a taken entry to be gone")
+	}
+	m.Delete("b")
+	if _, ok := m.Get("b"); ok {
+		t.Fatal("expected a deleted entry to be gone")
+	}
+	clock.Advance(time.Second)
+	if _, ok := m.Take("c"); ok {
+		t.Fatal("expected an expired entry not to be taken")
+	}
+}
+
+func TestSweepOnSet(t *testing.T) {
+	t.Parallel()
+	m, clock := newTestMap()
+	for i, key := range []string{"a", "b", "c"} {
+		m.Set(key, i, time.Second)
+	}
+	clock.Advance(2 * time.Second)
+	m.Set("d", 4, time.Second)
+
+	m.mu.Lock()
+	stored := len(m.entries)
+	m.mu.Unlock()
+	if stored != 1 {
+		t.Fatalf("expected Set to sweep expired entries, %d stored", stored)
+	}
+	if got := m.Len(); got != 1 {
+		t.Fatalf("expected 1 live entry, got %d", got)
+	}
+}
@@ -0,0 +1,139 @@
+// Package health serves liveness and readiness probes for container
+// orchestrators and provides the client side used by the healthcheck
+// subcommand.
+//
+// Liveness (/healthz) fails only when a component is down. A degraded
+// component, such as an MMDVM client reconnecting to its master, still
+// answers 200 but reports a warning. Readiness (/readyz) requires every
+// component to be fully up.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Status is the health of a component or of the whole process.
+type Status string
+
+const (
+	StatusOK       Status = "ok"
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+// Check is the health of one component.
+type Check struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report is the response body of both endpoints.
+type Report struct {
+	Status Status  `json:"status"`
+	Checks []Check `json:"checks"`
+}
+
+// Component reports the current health of one long-lived component.
+type Component func() Check
+
+// Checker aggregates the health of a set of components.
+type Checker struct {
+	components []Component
+}
+
+// NewChecker returns a Checker over components.
+func NewChecker(components ...Component) *Checker {
+	return &Checker{components: components}
+}
+
+// Report evaluates every component. The overall status is the worst
+// status of any component.
+func (c *Checker) Report() Report {
+	report := Report{Status: StatusOK, Checks: make([]Check, 0, len(c.components))}
+	for _, component := range c.components {
+		check := component()
+		report.Checks = append(report.Checks, check)
+		switch check.Status {
+		case StatusDown:
+			report.Status = StatusDown
+		case StatusDegraded:
+			if report.Status == StatusOK {
+				report.Status = StatusDegraded
+			}
+		}
+	}
+	return report
+}
+
+// Handler returns a handler serving /healthz and /readyz.
+func (c *Checker) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", c.serveLiveness)
+	mux.HandleFunc("/readyz", c.serveReadiness)
+	return mux
+}
+
+func (c *Checker) serveLiveness(w http.ResponseWriter, _ *http.Request) {
+	report := c.Report()
+	code := http.StatusOK
+	if report.Status == StatusDown {
+		code = http.StatusServiceUnavailable
+	}
+	if report.Status == StatusDegraded {
+		w.Header().Set("Warning", `199 - "degraded"`)
+	}
+	writeReport(w, code, report)
+}
+
+func (c *Checker) serveReadiness(w http.ResponseWriter, _ *http.Request) {
+	report := c.Report()
+	code := http.StatusOK
+	if report.Status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	writeReport(w, code, report)
+}
+
+func writeReport(w http.ResponseWriter, code int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// ErrUnhealthy is returned by Probe when the endpoint answers with a
+// non-200 status.
+var ErrUnhealthy = errors.New("unhealthy")
+
+// Probe requests url and returns the decoded report. It returns an error
+// wrapping ErrUnhealthy if the endpoint did not answer 200, and a plain
+// error if it could not be reached or answered garbage.
+func Probe(ctx context.Context, url string) (Report, error) {
+	var report Report
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return report, fmt.Errorf("failed to create health request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return report, fmt.Errorf("failed to reach health endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return report, fmt.Errorf("failed to read health response: %w", err)
+	}
+	if err := json.Unmarshal(body, &report); err != nil {
+		return report, fmt.Errorf("failed to decode health response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return report, fmt.Errorf("%w: %s returned %d (%s)", ErrUnhealthy, url, resp.StatusCode, report.Status)
+	}
+	return report, nil
+}
@@ -0,0 +1,91 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func component(name string, status Status) Component {
+	return func() Check {
+		return Check{Name: name, Status: status}
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		status        Status
+		wantLiveness  int
+		wantReadiness int
+		wantWarning   bool
+	}{
+		{"ok", StatusOK, http.StatusOK, http.StatusOK, false},
+		{"degraded", StatusDegraded, http.StatusOK, http.StatusServiceUnavailable, true},
+		{"down", StatusDown, http.StatusServiceUnavailable, http.StatusServiceUnavailable, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			checker := NewChecker(component("ipsc", StatusOK), component("mmdvm", tt.status))
+			srv := httptest.NewServer(checker.Handler())
+			defer srv.Close()
+
+			resp, err := http.Get(srv.URL + "/healthz")
+			if err != nil {
+				t.Fatalf("GET /healthz: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantLiveness {
+				t.Fatalf("/healthz: expected %d, got %d", tt.wantLiveness, resp.StatusCode)
+			}
+			if got := resp.Header.Get("Warning") != ""; got != tt.wantWarning {
+				t.Fatalf("/healthz: expected warning=%t, got %t", tt.wantWarning, got)
+			}
+
+			report, err := Probe(context.Background(), srv.URL+"/readyz")
+			if tt.wantReadiness == http.StatusOK && err != nil {
+				t.Fatalf("/readyz: expected ready, got %v", err)
+			}
+			if tt.wantReadiness != http.StatusOK && !errors.Is(err, ErrUnhealthy) {
+				t.Fatalf("/readyz: expected %v, got %v", ErrUnhealthy, err)
+			}
+			if report.Status != tt.status {
+				t.Fatalf("/readyz: expected status %s, got %s", tt.status, report.Status)
+			}
+			if len(report.Checks) != 2 {
+				t.Fatalf("/readyz: expected 2 checks, got %d", len(report.Checks))
+			}
+		})
+	}
+}
+
+func TestReportWorstStatusWins(t *testing.T) {
+	t.Parallel()
+	checker := NewChecker(
+		component("a", StatusDegraded),
+		component("b", StatusDown),
+		component("c", StatusDegraded),
+	)
+	if got := checker.Report().Status; got != StatusDown {
+		t.Fatalf("expected down, got %s", got)
+	}
+	if got := NewChecker().Report().Status; got != StatusOK {
+		t.Fatalf("expected ok with no components, got %s", got)
+	}
+}
+
+func TestProbeUnreachable(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	_, err := Probe(context.Background(), url+"/healthz")
+	if err == nil || errors.Is(err, ErrUnhealthy) {
+		t.Fatalf("expected a connection error, got %v", err)
+	}
+}
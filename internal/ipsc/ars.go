@@ -0,0 +1,248 @@
+package ipsc
+
+import (
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/USA-RedDragon/dmrgo/dmr/layer2/elements"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/ars"
+)
+
+// arsCallTimeout is how long an unfinished data call to the ARS ID is
+// remembered. Bursts still held for an undecided call are dropped with
+// it.
+const arsCallTimeout = 30 * time.Second
+
+// arsFilter intercepts ARS registrations, which radios send as UDP
+// datagrams to port 4005 in data calls to the configured ARS ID, and
+// drops or acknowledges them according to the policy instead of passing
+// them to the masters. Other data calls to the ARS ID are forwarded.
+type arsFilter struct {
+	policy config.ARSPolicy
+	id     uint32
+
+	mu    sync.Mutex
+	calls map[uint32]*arsCall // by call control
+	ipID  uint16
+}
+
+// arsCallState is what is known about a data call to the ARS ID.
+type arsCallState int
+
+const (
+	// arsCallUndecided calls are held until their UDP header is known.
+	arsCallUndecided arsCallState = iota
+	arsCallRegistration
+	arsCallOther
+)
+
+// arsCall follows one data call to the ARS ID. Bursts are handled in the
+// order they arrived, which over UDP need not be the order they were
+// sent; the RTP sequence number places each data block relative to the
+// header.
+type arsCall struct {
+	state     arsCallState
+	started   time.Time
+	header    *ars.DataHeader
+	headerSeq uint16
+	unusable  bool              // the header could not be read
+	blocks    map[uint16][]byte // information octets, by RTP sequence number
+	held      [][]byte          // bursts received while undecided
+}
+
+// arsResult is what to do with a burst of a data call to the ARS ID.
+type arsResult struct {
+	// forward holds the bursts of a call that turned out not to be a
+	// registration, in the order they arrived.
+	forward [][]byte
+	// recognized is set for the burst that identified a registration,
+	// and complete for the one that finished it.
+	recognized bool
+	complete   bool
+}
+
+// newARSFilter returns nil for the forward policy, which needs no
+// interception.
+func newARSFilter(cfg config.IPSCARS) *arsFilter {
+	switch cfg.Policy {
+	case config.ARSPolicyDrop, config.ARSPolicyAckLocally:
+		return &arsFilter{
+			policy: cfg.Policy,
+			id:     cfg.ID,
+			calls:  map[uint32]*arsCall{},
+		}
+	default:
+		return nil
+	}
+}
+
+// matches reports whether an IPSC user packet is part of a data call to
+// the ARS ID.
+func (f *arsFilter) matches(packetType PacketType, data []byte) bool {
+	if packetType != PacketType_PrivateData || len(data) < 38 {
+		return false
+	}
+	dst := uint32(data[9])<<16 | uint32(data[10])<<8 | uint32(data[11])
+	return dst == f.id
+}
+
+// classify records one burst of a data call to the ARS ID. Bursts are
+// held until the call's UDP destination port is known, and released for
+// forwarding if it is not a registration.
+func (f *arsFilter) classify(data []byte) arsResult {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	callControl := binary.BigEndian.Uint32(data[13:17])
+	now := time.Now()
+	for cc, call := range f.calls {
+		if now.Sub(call.started) > arsCallTimeout {
+			delete(f.calls, cc)
+		}
+	}
+
+	call, ok := f.calls[callControl]
+	if !ok {
+		call = &arsCall{started: now, blocks: map[uint16][]byte{}}
+		f.calls[callControl] = call
+	}
+	call.observe(data)
+	done := data[17]&0x40 != 0 || call.received()
+
+	var result arsResult
+	switch call.state {
+	case arsCallOther:
+		result.forward = [][]byte{data}
+	case arsCallRegistration:
+		// Dropped, or acknowledged once complete.
+	case arsCallUndecided:
+		call.held = append(call.held, slices.Clone(data))
+		switch port, err := call.destinationPort(); {
+		case err == nil && port == ars.Port:
+			call.state = arsCallRegistration
+			call.held = nil
+			result.recognized = true
+		case err == nil, !errors.Is(err, ars.ErrTruncated), done:
+			call.state = arsCallOther
+			result.forward = call.held
+			call.held = nil
+		}
+	}
+
+	if done {
+		result.complete = call.state == arsCallRegistration
+		delete(f.calls, callControl)
+	}
+	return result
+}
+
+// observe records the data header or the information octets of a data
+// block.
+func (c *arsCall) observe(data []byte) {
+	seq := binary.BigEndian.Uint16(data[20:22])
+	switch elements.DataType(data[30]) {
+	case elements.DataTypeDataHeader:
+		if c.header != nil {
+			return
+		}
+		var block ars.Block
+		if len(data) < 38+ars.BlockSize {
+			c.unusable = true
+			return
+		}
+		copy(block[:], data[38:])
+		header, err := ars.ParseDataHeader(block)
+		if err != nil {
+			slog.Debug("Data call to the ARS ID with unreadable data header", "error", err)
+			c.unusable = true
+			return
+		}
+		c.header = &header
+		c.headerSeq = seq
+	case elements.DataTypeRate12, elements.DataTypeRate34, elements.DataTypeRate1:
+		size := int(binary.BigEndian.Uint16(data[36:38])) / 8
+		c.blocks[seq] = slices.Clone(data[38:min(38+size, len(data))])
+	}
+}
+
+// received reports whether every block the header announced has arrived.
+func (c *arsCall) received() bool {
+	return c.header != nil && len(c.blocks) >= int(c.header.BlocksToFollow)
+}
+
+// destinationPort returns the UDP destination port of the datagram the
+// call carries, or ars.ErrTruncated while the blocks holding it have not
+// all arrived.
+func (c *arsCall) destinationPort() (uint16, error) {
+	if c.unusable {
+		return 0, ars.ErrNotUDP
+	}
+	if c.header == nil {
+		return 0, ars.ErrTruncated
+	}
+	if c.header.SAP != ars.SAPIP {
+		return 0, ars.ErrNotUDP
+	}
+	var datagram []byte
+	for i := range uint16(c.header.BlocksToFollow) {
+		block, ok := c.blocks[c.headerSeq+1+i]
+		if !ok {
+			break
+		}
+		datagram = append(datagram, c.header.UserData(block)...)
+	}
+	return ars.DestinationPort(datagram)
+}
+
+// nextIPID returns the IPv4 identification for the next acknowledgment.
+func (f *arsFilter) nextIPID() uint16 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ipID++
+	return f.ipID
+}
+
+// handleARS applies the ARS policy to a burst matched by the filter.
+func (s *IPSCServer) handleARS(packetType PacketType, peerID uint32, data []byte, addr *net.UDPAddr) {
+	result := s.ars.classify(data)
+	for _, burst := range result.forward {
+		s.forwardBurst(packetType, peerID, burst, addr)
+	}
+
+	if result.recognized && s.ars.policy == config.ARSPolicyDrop {
+		if s.metrics != nil {
+			s.metrics.IPSCARSRegistrations.WithLabelValues("dropped").Inc()
+		}
+		s.log.Debug("Dropped ARS registration", "peer", addr)
+	}
+	if !result.complete || s.ars.policy != config.ARSPolicyAckLocally {
+		return
+	}
+
+	radio := uint32(data[6])<<16 | uint32(data[7])<<8 | uint32(data[8])
+	slot := data[17]&0x20 != 0
+	header, blocks := ars.Ack(s.ars.id, radio, s.ars.nextIPID())
+	call := make([]DataBlock, 0, 1+len(blocks))
+	call = append(call, DataBlock{Type: elements.DataTypeDataHeader, Payload: header})
+	for _, block := range blocks {
+		call = append(call, DataBlock{Type: elements.DataTypeRate12, Payload: block})
+	}
+
+	s.log.Info("Acknowledging ARS registration", "radio", radio, "peer", addr)
+	for _, packet := range s.localTranslator.BuildDataCall(uint(s.ars.id), uint(radio), false, slot, call) {
+		s.pacePeer(peerID)
+		if err := s.sendPacket(&Packet{data: packet}, addr); err != nil {
+			s.log.Warn("failed sending ARS acknowledgment", "peer", addr, "error", err)
+			return
+		}
+	}
+	if s.metrics != nil {
+		s.metrics.IPSCARSRegistrations.WithLabelValues("acknowledged").Inc()
+	}
+}
@@ -0,0 +1,297 @@
+package ipsc
+
+import (
+	"encoding/binary"
+	"net"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/USA-RedDragon/dmrgo/dmr/layer2/elements"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/ars"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/crc"
+)
+
+const (
+	testARSID     = 9999
+	testARSRadio  = 3120101
+	testARSPeerID = 311001
+)
+
+// arsRegistration returns the bursts of an ARS registration from
+// testARSRadio as a repeater forwards them: a data header, then the rate
+// 1/2 blocks carrying the start of the datagram.
+func arsRegistration(slot bool) [][]byte {
+	return arsDataCall(ars.Port, false, slot)
+}
+
+// arsDataCall returns the bursts of an IP data call from testARSRadio to
+// the ARS ID, carrying the IPv4 and UDP headers of a datagram to port.
+func arsDataCall(port uint16, confirmed, slot bool) [][]byte {
+	datagram := []byte{0x45, 0x00, 0x00, 0x1C, 0x00, 0x01, 0x00, 0x00, 0x40, 0x11, 0x00, 0x00}
+	datagram = append(datagram, ars.RadioIP(testARSRadio).To4()...)
+	datagram = append(datagram, ars.RadioIP(testARSID).To4()...)
+	datagram = binary.BigEndian.AppendUint16(datagram, ars.Port)
+	datagram = binary.BigEndian.AppendUint16(datagram, port)
+
+	// Confirmed blocks open with a serial number and CRC.
+	offset := 0
+	if confirmed {
+		offset = 2
+	}
+	var blocks []ars.Block
+	for i := 0; i < len(datagram); i += ars.BlockSize - offset {
+		var block ars.Block
+		copy(block[offset:], datagram[i:])
+		blocks = append(blocks, block)
+	}
+
+	header := ars.DataHeader{
+		Confirmed:      confirmed,
+		SAP:            ars.SAPIP,
+		Dst:            testARSID,
+		Src:            testARSRadio,
+		BlocksToFollow: uint8(len(blocks)), //nolint:gosec // A handful of blocks
+	}
+	bursts := [][]byte{arsBurst(elements.DataTypeDataHeader, header.Encode(), 0, slot)}
+	for i, block := range blocks {
+		bursts = append(bursts, arsBurst(elements.DataTypeRate12, block, uint16(i+1), slot)) //nolint:gosec // A handful of blocks
+	}
+	return bursts
+}
+
+func arsBurst(dataType elements.DataType, payload ars.Block, seq uint16, slot bool) []byte {
+	data := make([]byte, 54)
+	data[0] = byte(PacketType_PrivateData)
+	binary.BigEndian.PutUint32(data[1:5], testARSPeerID)
+	radio, id := uint32(testARSRadio), uint32(testARSID)
+	data[6], data[7], data[8] = byte(radio>>16), byte(radio>>8), byte(radio)
+	data[9], data[10], data[11] = byte(id>>16), byte(id>>8), byte(id)
+	data[12] = 0x01
+	binary.BigEndian.PutUint32(data[13:17], 0x1234)
+	if slot {
+		data[17] = 0x20
+	}
+	data[18] = 0x80
+	binary.BigEndian.PutUint16(data[20:22], seq)
+	data[30] = byte(dataType)
+	binary.BigEndian.PutUint16(data[36:38], 0x0060)
+	copy(data[38:50], payload[:])
+	return data
+}
+
+func arsConfig(policy config.ARSPolicy) *config.Config {
+	cfg := testConfig(false, "")
+	cfg.IPSC.ARS = config.IPSCARS{Policy: policy, ID: testARSID}
+	return cfg
+}
+
+func TestARSPolicyForwardAndDrop(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		policy      config.ARSPolicy
+		wantHandled int32
+	}{
+		{config.ARSPolicyForward, 3},
+		{config.ARSPolicyDrop, 0},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			t.Parallel()
+			s := NewIPSCServer(arsConfig(tt.policy), nil)
+			var handled atomic.Int32
+			done := make(chan struct{}, 3)
+			s.SetBurstHandler(func(byte, []byte, *net.UDPAddr) {
+				handled.Add(1)
+				done <- struct{}{}
+			})
+
+			addr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 50000}
+			for _, burst := range arsRegistration(false) {
+				if _, err := s.handlePacket(burst, addr); err != nil {
+					t.Fatalf("handlePacket: %v", err)
+				}
+			}
+			for range tt.wantHandled {
+				select {
+				case <-done:
+				case <-time.After(time.Second):
+					t.Fatal("timed out waiting for forwarded bursts")
+				}
+			}
+			// Give anything wrongly forwarded a moment to show up.
+			time.Sleep(20 * time.Millisecond)
+			if got := handled.Load(); got != tt.wantHandled {
+				t.Fatalf("expected %d bursts forwarded, got %d", tt.wantHandled, got)
+			}
+		})
+	}
+}
+
+func TestARSPolicyForwardsOtherDataCalls(t *testing.T) {
+	t.Parallel()
+	s := NewIPSCServer(arsConfig(config.ARSPolicyDrop), nil)
+	done := make(chan struct{}, 1)
+	s.SetBurstHandler(func(byte, []byte, *net.UDPAddr) { done <- struct{}{} })
+
+	burst := arsRegistration(false)[0]
+	burst[11] = 0x01 // another destination
+	if _, err := s.handlePacket(burst, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 50000}); err != nil {
+		t.Fatalf("handlePacket: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected a data call to another ID to be forwarded")
+	}
+}
+
+func TestARSPolicyForwardsOtherPorts(t *testing.T) {
+	t.Parallel()
+	s := NewIPSCServer(arsConfig(config.ARSPolicyDrop), nil)
+	bursts := arsDataCall(ars.Port+1, false, false)
+	done := make(chan struct{}, len(bursts))
+	s.SetBurstHandler(func(byte, []byte, *net.UDPAddr) { done <- struct{}{} })
+
+	addr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 50000}
+	for _, burst := range bursts {
+		if _, err := s.handlePacket(burst, addr); err != nil {
+			t.Fatalf("handlePacket: %v", err)
+		}
+	}
+	for range bursts {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("expected a data call to another port to be forwarded")
+		}
+	}
+}
+
+func TestARSFilterClassify(t *testing.T) {
+	t.Parallel()
+	reversed := func(bursts [][]byte) [][]byte {
+		slices.Reverse(bursts)
+		return bursts
+	}
+	lastFlagged := func(bursts [][]byte) [][]byte {
+		bursts[0][17] |= 0x40
+		return bursts[:1]
+	}
+	tests := []struct {
+		name           string
+		bursts         [][]byte
+		wantRecognized int
+		wantForwarded  int
+	}{
+		{"registration", arsRegistration(false), 1, 0},
+		{"blocks before header", reversed(arsRegistration(false)), 1, 0},
+		{"confirmed", arsDataCall(ars.Port, true, false), 1, 0},
+		{"other port", arsDataCall(ars.Port+1, false, false), 0, 3},
+		{"other port out of order", reversed(arsDataCall(ars.Port+1, false, false)), 0, 3},
+		{"ends before the port is known", lastFlagged(arsRegistration(false)), 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			f := newARSFilter(config.IPSCARS{Policy: config.ARSPolicyDrop, ID: testARSID})
+			var recognized, forwarded, complete int
+			for _, burst := range tt.bursts {
+				result := f.classify(burst)
+				forwarded += len(result.forward)
+				if result.recognized {
+					recognized++
+				}
+				if result.complete {
+					complete++
+				}
+			}
+			if recognized != tt.wantRecognized {
+				t.Fatalf("expected the registration recognized %d times, got %d", tt.wantRecognized, recognized)
+			}
+			if complete != tt.wantRecognized {
+				t.Fatalf("expected the registration completed %d times, got %d", tt.wantRecognized, complete)
+			}
+			if forwarded != tt.wantForwarded {
+				t.Fatalf("expected %d bursts forwarded, got %d", tt.wantForwarded, forwarded)
+			}
+			if len(f.calls) != 0 {
+				t.Fatalf("expected the call forgotten, %d remembered", len(f.calls))
+			}
+		})
+	}
+}
+
+func TestARSPolicyAckLocally(t *testing.T) {
+	t.Parallel()
+	for _, slot := range []bool{false, true} {
+		s, srvAddr := newTestServerWithConfig(t, arsConfig(config.ARSPolicyAckLocally))
+		s.localTranslator.SetPeerID(s.localID)
+		s.SetBurstHandler(func(byte, []byte, *net.UDPAddr) {
+			t.Error("expected the registration not to be forwarded")
+		})
+
+		repeater, err := net.DialUDP("udp", nil, srvAddr)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer repeater.Close()
+		repeaterAddr, ok := repeater.LocalAddr().(*net.UDPAddr)
+		if !ok {
+			t.Fatal("expected *net.UDPAddr from LocalAddr")
+		}
+
+		for _, burst := range arsRegistration(slot) {
+			if _, err := s.handlePacket(burst, repeaterAddr); err != nil {
+				t.Fatalf("handlePacket: %v", err)
+			}
+		}
+
+		// The acknowledgment is a data call back to the radio: a data
+		// header followed by the rate 1/2 blocks it announces.
+		first := readUDP(t, repeater)
+		if first[0] != byte(PacketType_PrivateData) || first[30] != byte(elements.DataTypeDataHeader) {
+			t.Fatalf("expected a private data header, got type 0x%02X burst 0x%02X", first[0], first[30])
+		}
+		if got := binary.BigEndian.Uint32(first[1:5]); got != s.localID {
+			t.Fatalf("expected our peer ID %d, got %d", s.localID, got)
+		}
+		src := uint32(first[6])<<16 | uint32(first[7])<<8 | uint32(first[8])
+		dst := uint32(first[9])<<16 | uint32(first[10])<<8 | uint32(first[11])
+		if src != testARSID || dst != testARSRadio {
+			t.Fatalf("expected %d → %d, got %d → %d", testARSID, testARSRadio, src, dst)
+		}
+		if got := first[17]&0x20 != 0; got != slot {
+			t.Fatalf("expected the acknowledgment on TS2=%t", slot)
+		}
+		var headerBlock ars.Block
+		copy(headerBlock[:], first[38:50])
+		header, err := ars.ParseDataHeader(headerBlock)
+		if err != nil {
+			t.Fatalf("ParseDataHeader: %v", err)
+		}
+		if header.Dst != testARSRadio || header.Src != testARSID {
+			t.Fatalf("unexpected data header %+v", header)
+		}
+
+		var message []byte
+		for range header.BlocksToFollow {
+			block := readUDP(t, repeater)
+			if block[30] != byte(elements.DataTypeRate12) {
+				t.Fatalf("expected a rate 1/2 block, got burst 0x%02X", block[30])
+			}
+			if binary.BigEndian.Uint32(block[13:17]) != binary.BigEndian.Uint32(first[13:17]) {
+				t.Fatal("expected every block under the header's call control")
+			}
+			message = append(message, block[38:50]...)
+		}
+		if !crc.CheckCRC32(message) {
+			t.Fatal("expected the acknowledgment's CRC-32 to verify")
+		}
+		if udpPort := binary.BigEndian.Uint16(message[22:24]); udpPort != ars.Port {
+			t.Fatalf("expected the acknowledgment sent to port %d, got %d", ars.Port, udpPort)
+		}
+	}
+}
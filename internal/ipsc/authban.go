@@ -0,0 +1,103 @@
+package ipsc
+
+import (
+	"net"
+	"time"
+)
+
+// authFailures tracks the consecutive authentication failures from one
+// source address since firstFailure. Its fields are guarded by the
+// server's mu.
+type authFailures struct {
+	count        uint
+	firstFailure time.Time
+	bannedUntil  time.Time
+}
+
+// authBanned reports whether packets from addr are ignored after too many
+// authentication failures. A ban past its cooldown is lifted.
+func (s *IPSCServer) authBanned(addr *net.UDPAddr) bool {
+	if addr == nil || s.cfg.IPSC.Auth.BanThreshold == 0 {
+		return false
+	}
+	key := addr.String()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	failures, ok := s.authFailures[key]
+	if !ok || failures.bannedUntil.IsZero() {
+		return false
+	}
+	if s.now().Before(failures.bannedUntil) {
+		return true
+	}
+	delete(s.authFailures, key)
+	s.log.Warn("Lifting ban on IPSC source after authentication failures", "source", key)
+	return false
+}
+
+// authBanWindow is both how long a source is banned and how close
+// together its failures must be to count toward a ban.
+func (s *IPSCServer) authBanWindow() time.Duration {
+	return time.Duration(s.cfg.IPSC.Auth.BanDuration) * time.Second
+}
+
+// noteAuthFailure counts an authentication failure from addr, banning it
+// once the failures within the ban window reach the threshold. Failures
+// further apart start the count again.
+func (s *IPSCServer) noteAuthFailure(addr *net.UDPAddr) {
+	threshold := s.cfg.IPSC.Auth.BanThreshold
+	if addr == nil || threshold == 0 {
+		return
+	}
+	key := addr.String()
+	window := s.authBanWindow()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	failures, ok := s.authFailures[key]
+	if !ok {
+		failures = &authFailures{}
+		s.authFailures[key] = failures
+	}
+	if failures.bannedUntil.IsZero() && now.Sub(failures.firstFailure) >= window {
+		failures.count = 0
+		failures.firstFailure = now
+	}
+	failures.count++
+	if failures.count >= threshold && failures.bannedUntil.IsZero() {
+		failures.bannedUntil = now.Add(window)
+		s.log.Warn("Ignoring IPSC source after repeated authentication failures",
+			"source", key, "failures", failures.count, "cooldown", window)
+	}
+}
+
+// noteAuthSuccess clears the failures counted for addr.
+func (s *IPSCServer) noteAuthSuccess(addr *net.UDPAddr) {
+	if addr == nil || s.cfg.IPSC.Auth.BanThreshold == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.authFailures, addr.String())
+}
+
+// liftAuthBans lifts the bans past their cooldown and forgets failures
+// too old to lead to one, so addresses that stop sending are not
+// remembered forever.
+func (s *IPSCServer) liftAuthBans() {
+	window := s.authBanWindow()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for key, failures := range s.authFailures {
+		switch {
+		case failures.bannedUntil.IsZero():
+			if now.Sub(failures.firstFailure) >= window {
+				delete(s.authFailures, key)
+			}
+		case !now.Before(failures.bannedUntil):
+			delete(s.authFailures, key)
+			s.log.Warn("Lifting ban on IPSC source after authentication failures", "source", key)
+		}
+	}
+}
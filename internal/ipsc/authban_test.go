@@ -0,0 +1,181 @@
+package ipsc
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+const banTestKey = "0000000000000000000000000000000000001234"
+
+// newBanTestServer returns a server that bans a source after three
+// authentication failures for a minute, with its clock at *clock.
+func newBanTestServer(t *testing.T, clock *time.Time) *IPSCServer {
+	t.Helper()
+	cfg := testConfig(true, "1234")
+	cfg.IPSC.Auth.BanThreshold = 3
+	cfg.IPSC.Auth.BanDuration = 60
+	s, _ := newTestServerWithConfig(t, cfg)
+	s.now = func() time.Time { return *clock }
+	return s
+}
+
+func badlySigned(peerID uint32) []byte {
+	data := makeControlPacket(PacketType_MasterRegisterRequest, peerID)
+	return append(data, make([]byte, authDigestSize)...)
+}
+
+func (s *IPSCServer) authFailureCount(addr *net.UDPAddr) uint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if failures, ok := s.authFailures[addr.String()]; ok {
+		return failures.count
+	}
+	return 0
+}
+
+func TestAuthFailuresBanSource(t *testing.T) {
+	t.Parallel()
+	clock := time.Unix(1_700_000_000, 0)
+	s := newBanTestServer(t, &clock)
+	_, addr := listenPeer(t)
+
+	for range 3 {
+		if _, err := s.handlePacket(badlySigned(8000), addr); err == nil || errors.Is(err, ErrPacketIgnored) {
+			t.Fatalf("expected an authentication failure, got %v", err)
+		}
+	}
+
+	// Once banned, packets are dropped before their digest is checked,
+	// however they are signed.
+	for _, data := range [][]byte{
+		badlySigned(8000),
+		signPacket(t, makeControlPacket(PacketType_MasterRegisterRequest, 8000), banTestKey),
+	} {
+		if _, err := s.handlePacket(data, addr); !errors.Is(err, ErrPacketIgnored) {
+			t.Fatalf("expected the packet ignored, got %v", err)
+		}
+	}
+	if got := s.authFailureCount(addr); got != 3 {
+		t.Fatalf("expected the failures left at 3 while banned, got %d", got)
+	}
+	if s.peerCount() != 0 {
+		t.Fatalf("expected no peers, got %d", s.peerCount())
+	}
+
+	// Other sources are unaffected.
+	_, other := listenPeer(t)
+	if _, err := s.handlePacket(signPacket(t, makeControlPacket(PacketType_MasterRegisterRequest, 9000), banTestKey), other); err != nil {
+		t.Fatalf("expected another source accepted, got %v", err)
+	}
+}
+
+func TestAuthBanExpires(t *testing.T) {
+	t.Parallel()
+	clock := time.Unix(1_700_000_000, 0)
+	s := newBanTestServer(t, &clock)
+	_, addr := listenPeer(t)
+
+	for range 3 {
+		_, _ = s.handlePacket(badlySigned(8000), addr)
+	}
+	good := signPacket(t, makeControlPacket(PacketType_MasterRegisterRequest, 8000), banTestKey)
+	clock = clock.Add(59 * time.Second)
+	if _, err := s.handlePacket(append([]byte(nil), good...), addr); !errors.Is(err, ErrPacketIgnored) {
+		t.Fatalf("expected the packet ignored during the cooldown, got %v", err)
+	}
+
+	// With its key fixed, the peer registers after the cooldown.
+	clock = clock.Add(time.Second)
+	if _, err := s.handlePacket(good, addr); err != nil {
+		t.Fatalf("expected the registration accepted after the cooldown, got %v", err)
+	}
+	if s.peerCount() != 1 {
+		t.Fatalf("expected the peer registered, got %d peers", s.peerCount())
+	}
+	if got := s.authFailureCount(addr); got != 0 {
+		t.Fatalf("expected the failures cleared, got %d", got)
+	}
+}
+
+func TestAuthSuccessResetsFailures(t *testing.T) {
+	t.Parallel()
+	clock := time.Unix(1_700_000_000, 0)
+	s := newBanTestServer(t, &clock)
+	_, addr := listenPeer(t)
+
+	good := signPacket(t, makeControlPacket(PacketType_MasterRegisterRequest, 8000), banTestKey)
+	for range 2 {
+		for range 2 {
+			_, _ = s.handlePacket(badlySigned(8000), addr)
+		}
+		if _, err := s.handlePacket(append([]byte(nil), good...), addr); err != nil {
+			t.Fatalf("expected failures short of the threshold not to ban, got %v", err)
+		}
+	}
+}
+
+func TestAuthFailuresOutsideWindowDoNotBan(t *testing.T) {
+	t.Parallel()
+	clock := time.Unix(1_700_000_000, 0)
+	s := newBanTestServer(t, &clock)
+	_, addr := listenPeer(t)
+
+	// A failure a minute, as from a repeater retrying a mistyped key
+	// slowly, never reaches three within the window.
+	for range 5 {
+		_, _ = s.handlePacket(badlySigned(8000), addr)
+		clock = clock.Add(30 * time.Second)
+		_, _ = s.handlePacket(badlySigned(8000), addr)
+		clock = clock.Add(30 * time.Second)
+	}
+	good := signPacket(t, makeControlPacket(PacketType_MasterRegisterRequest, 8000), banTestKey)
+	if _, err := s.handlePacket(good, addr); err != nil {
+		t.Fatalf("expected failures spread past the window not to ban, got %v", err)
+	}
+}
+
+func TestLiftAuthBansForgetsExpiredBans(t *testing.T) {
+	t.Parallel()
+	clock := time.Unix(1_700_000_000, 0)
+	s := newBanTestServer(t, &clock)
+	_, addr := listenPeer(t)
+
+	for range 3 {
+		_, _ = s.handlePacket(badlySigned(8000), addr)
+	}
+	s.liftAuthBans()
+	if got := s.authFailureCount(addr); got != 3 {
+		t.Fatalf("expected the ban kept during the cooldown, got %d failures", got)
+	}
+	clock = clock.Add(time.Minute)
+	s.liftAuthBans()
+	s.mu.RLock()
+	n := len(s.authFailures)
+	s.mu.RUnlock()
+	if n != 0 {
+		t.Fatalf("expected the expired ban forgotten, got %d sources", n)
+	}
+}
+
+func TestLiftAuthBansForgetsStaleFailures(t *testing.T) {
+	t.Parallel()
+	clock := time.Unix(1_700_000_000, 0)
+	s := newBanTestServer(t, &clock)
+	_, addr := listenPeer(t)
+
+	_, _ = s.handlePacket(badlySigned(8000), addr)
+	s.liftAuthBans()
+	if got := s.authFailureCount(addr); got != 1 {
+		t.Fatalf("expected the failure kept within the window, got %d", got)
+	}
+	clock = clock.Add(time.Minute)
+	s.liftAuthBans()
+	s.mu.RLock()
+	n := len(s.authFailures)
+	s.mu.RUnlock()
+	if n != 0 {
+		t.Fatalf("expected the stale failure forgotten, got %d sources", n)
+	}
+}
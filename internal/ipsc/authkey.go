@@ -0,0 +1,47 @@
+package ipsc
+
+import (
+	"time"
+)
+
+// SetAuthKey replaces the global authentication key without restarting
+// the server, so registered peers stay registered. Packets are signed
+// with the new key at once. With ipsc.auth.key-grace set, packets signed
+// with the previous key are still accepted for that many seconds, so
+// peers can be re-keyed one at a time; peers with a key of their own are
+// unaffected.
+func (s *IPSCServer) SetAuthKey(hexKey string) error {
+	key, err := parseAuthKey(hexKey)
+	if err != nil {
+		return err
+	}
+	grace := time.Duration(s.cfg.IPSC.Auth.KeyGrace) * time.Second
+
+	s.keyMu.Lock()
+	if grace > 0 {
+		s.previousKey = s.authKey
+		s.previousKeyUntil = s.now().Add(grace)
+	} else {
+		s.previousKey = nil
+	}
+	s.authKey = key
+	s.keyMu.Unlock()
+
+	s.log.Info("IPSC authentication key changed", "grace", grace)
+	return nil
+}
+
+// previousAuthKey returns the global key SetAuthKey replaced while its
+// grace window lasts, or nil.
+func (s *IPSCServer) previousAuthKey() []byte {
+	s.keyMu.Lock()
+	defer s.keyMu.Unlock()
+	if s.previousKey == nil {
+		return nil
+	}
+	if !s.now().Before(s.previousKeyUntil) {
+		s.previousKey = nil
+		return nil
+	}
+	return s.previousKey
+}
@@ -0,0 +1,107 @@
+package ipsc
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+)
+
+func TestSetAuthKeyInvalid(t *testing.T) {
+	t.Parallel()
+	s := NewIPSCServer(testConfig(true, "1234"), nil)
+	before := append([]byte(nil), s.authKey...)
+	for _, key := range []string{"xyz", "12345678901234567890123456789012345678901"} {
+		if err := s.SetAuthKey(key); !errors.Is(err, ErrInvalidAuthKey) {
+			t.Fatalf("%q: expected ErrInvalidAuthKey, got %v", key, err)
+		}
+	}
+	if !bytes.Equal(s.authKey, before) {
+		t.Fatalf("expected the key unchanged, got % X", s.authKey)
+	}
+}
+
+func TestSetAuthKeyGraceWindow(t *testing.T) {
+	t.Parallel()
+	const (
+		oldKey = "0000000000000000000000000000000000001234"
+		newKey = "0000000000000000000000000000000000005678"
+	)
+	tests := []struct {
+		name  string
+		grace uint
+	}{
+		{"no grace", 0},
+		{"grace", 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			clock := time.Unix(1_700_000_000, 0)
+			cfg := testConfig(true, "1234")
+			cfg.IPSC.Auth.KeyGrace = tt.grace
+			cfg.IPSC.Auth.PeerKeys = []config.IPSCPeerKey{{PeerID: 8000, Key: "beef"}}
+			s, _ := newTestServerWithConfig(t, cfg)
+			s.now = func() time.Time { return clock }
+			peer, peerAddr := listenPeer(t)
+
+			register := signPacket(t, makeControlPacket(PacketType_MasterRegisterRequest, 9000), oldKey)
+			if _, err := s.handlePacket(register, peerAddr); err != nil {
+				t.Fatalf("register: %v", err)
+			}
+			readPacketType(t, peer, PacketType_MasterRegisterReply)
+
+			if err := s.SetAuthKey("5678"); err != nil {
+				t.Fatalf("SetAuthKey: %v", err)
+			}
+			if s.peerCount() != 1 {
+				t.Fatalf("expected the peer kept registered, got %d peers", s.peerCount())
+			}
+
+			alive := func(peerID uint32, key string) error {
+				t.Helper()
+				_, err := s.handlePacket(signPacket(t, makeControlPacket(PacketType_MasterAliveRequest, peerID), key), peerAddr)
+				return err
+			}
+
+			// The new key is accepted at once, and replies use it
+			// whichever key the request was signed with.
+			if err := alive(9000, newKey); err != nil {
+				t.Fatalf("new key: %v", err)
+			}
+			readPacketType(t, peer, PacketType_MasterAliveReply)
+			err := alive(9000, oldKey)
+			if tt.grace == 0 {
+				if err == nil {
+					t.Fatal("expected the old key refused without a grace window")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("old key in the grace window: %v", err)
+				}
+				reply, _ := readPacketType(t, peer, PacketType_MasterAliveReply)
+				payload := reply[:len(reply)-authDigestSize]
+				if want := signPacket(t, append([]byte(nil), payload...), newKey); !bytes.Equal(reply, want) {
+					t.Fatalf("expected the reply signed with the new key, got % X", reply)
+				}
+			}
+
+			// Peers with their own key never fall back to the old
+			// global key.
+			if err := alive(8000, oldKey); err == nil || errors.Is(err, ErrPacketIgnored) {
+				t.Fatalf("expected the old global key failing authentication for a peer with its own key, got %v", err)
+			}
+
+			clock = clock.Add(time.Duration(tt.grace) * time.Second)
+			if err := alive(9000, oldKey); err == nil {
+				t.Fatal("expected the old key refused once the grace window ends")
+			}
+			if err := alive(9000, newKey); err != nil {
+				t.Fatalf("new key after the grace window: %v", err)
+			}
+			readPacketType(t, peer, PacketType_MasterAliveReply)
+		})
+	}
+}
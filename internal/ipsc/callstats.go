@@ -0,0 +1,105 @@
+package ipsc
+
+import "time"
+
+// callHistorySize is how many finished calls the translator remembers.
+const callHistorySize = 32
+
+// CallStats describes one call the translator carried, in progress or
+// finished.
+type CallStats struct {
+	StreamID uint32
+	// Direction is "mmdvm_to_ipsc" or "ipsc_to_mmdvm".
+	Direction string
+	Src       uint
+	Dst       uint
+	GroupCall bool
+	Slot      bool // true = TS2, as the master sees it
+	Start     time.Time
+	// End is when the call finished, zero while it is in progress.
+	End time.Time
+	// Duration is how long the call lasted, or has lasted so far.
+	Duration time.Duration
+	// Frames counts the packets of the call that were translated, and
+	// Dropped those that were not.
+	Frames  int
+	Dropped int
+	// Lost counts RTP sequence numbers skipped between packets received
+	// from IPSC. It is always zero for calls toward IPSC.
+	Lost int
+}
+
+// count records whether a packet of the call was translated.
+func (s *CallStats) count(translated bool) {
+	if translated {
+		s.Frames++
+	} else {
+		s.Dropped++
+	}
+}
+
+// snapshot returns a copy of s with its duration as of now.
+func (s *CallStats) snapshot(now time.Time) CallStats {
+	c := *s
+	if c.End.IsZero() {
+		c.Duration = now.Sub(c.Start)
+	} else {
+		c.Duration = c.End.Sub(c.Start)
+	}
+	return c
+}
+
+// finishCall marks the call s as ended and moves it to the history of
+// finished calls. Must be called with t.mu held.
+func (t *IPSCTranslator) finishCall(s *CallStats) {
+	s.End = t.now()
+	t.callHistory = append(t.callHistory, s)
+	if len(t.callHistory) > callHistorySize {
+		t.callHistory = t.callHistory[len(t.callHistory)-callHistorySize:]
+	}
+}
+
+// Snapshot returns the statistics of the calls in progress in both
+// directions, followed by the last finished calls, newest first.
+func (t *IPSCTranslator) Snapshot() []CallStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	calls := make([]CallStats, 0, len(t.streams)+len(t.reverseStreams)+len(t.callHistory))
+	for _, ss := range t.streams {
+		calls = append(calls, ss.stats.snapshot(now))
+	}
+	for _, rss := range t.reverseStreams {
+		calls = append(calls, rss.stats.snapshot(now))
+	}
+	for i := len(t.callHistory) - 1; i >= 0; i-- {
+		calls = append(calls, t.callHistory[i].snapshot(now))
+	}
+	return calls
+}
+
+// StreamStats returns the statistics of the call with streamID: the
+// master's stream ID for calls toward IPSC, and the one the translator
+// assigned for calls from IPSC. A call in progress is preferred over a
+// finished one, and a recent finished call over an older one.
+func (t *IPSCTranslator) StreamStats(streamID uint32) (CallStats, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if ss, ok := t.streams[streamID]; ok {
+		return ss.stats.snapshot(now), true
+	}
+	for _, rss := range t.reverseStreams {
+		if rss.streamID == streamID {
+			return rss.stats.snapshot(now), true
+		}
+	}
+	for i := len(t.callHistory) - 1; i >= 0; i-- {
+		if t.callHistory[i].StreamID == streamID {
+			return t.callHistory[i].snapshot(now), true
+		}
+	}
+	return CallStats{}, false
+}
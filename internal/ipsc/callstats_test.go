@@ -0,0 +1,134 @@
+package ipsc
+
+import (
+	"testing"
+	"time"
+)
+
+// newClockedTranslator returns a translator reading the time from the
+// returned clock.
+func newClockedTranslator(t *testing.T) (*IPSCTranslator, *time.Time) {
+	t.Helper()
+	tr := newTestTranslator(t)
+	clock := time.Unix(1_700_000_000, 0)
+	tr.now = func() time.Time { return clock }
+	return tr, &clock
+}
+
+func TestCallStatsToIPSC(t *testing.T) {
+	t.Parallel()
+	tr, clock := newClockedTranslator(t)
+	start := *clock
+
+	tr.TranslateToIPSC(makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 1))
+	*clock = clock.Add(time.Second)
+	sendVoiceFrames(t, tr, 6)
+	// An idle burst is not translated.
+	tr.TranslateToIPSC(makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 9))
+
+	stats, ok := tr.StreamStats(0x1234)
+	if !ok {
+		t.Fatal("expected statistics for the call in progress")
+	}
+	if !stats.End.IsZero() || stats.Duration != time.Second {
+		t.Fatalf("expected a call in progress for 1s, got end %v duration %v", stats.End, stats.Duration)
+	}
+
+	*clock = clock.Add(2 * time.Second)
+	tr.TranslateToIPSC(makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 2))
+
+	stats, ok = tr.StreamStats(0x1234)
+	if !ok {
+		t.Fatal("expected statistics for the finished call")
+	}
+	want := CallStats{
+		StreamID:  0x1234,
+		Direction: "mmdvm_to_ipsc",
+		Src:       100,
+		Dst:       200,
+		GroupCall: true,
+		Start:     start,
+		End:       start.Add(3 * time.Second),
+		Duration:  3 * time.Second,
+		Frames:    8,
+		Dropped:   1,
+	}
+	if stats != want {
+		t.Fatalf("expected %+v, got %+v", want, stats)
+	}
+}
+
+func TestCallStatsToMMDVM(t *testing.T) {
+	t.Parallel()
+	tr, clock := newClockedTranslator(t)
+
+	send := func(burstType byte, seq uint16) {
+		data := makeSequencedIPSCPacket(burstType, seq)
+		tr.TranslateToMMDVM(data[0], data)
+	}
+	send(ipscBurstVoiceHead, 1)
+	send(ipscBurstVoiceHead, 2) // a repeat of the header
+	send(ipscBurstSlot1, 3)
+	send(ipscBurstSlot1, 3) // a duplicate
+	send(ipscBurstSlot1, 6) // two packets lost before it
+	*clock = clock.Add(1500 * time.Millisecond)
+	send(ipscBurstVoiceTerm, 7)
+
+	calls := tr.Snapshot()
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 finished call, got %d", len(calls))
+	}
+	stats := calls[0]
+	if stats.Direction != "ipsc_to_mmdvm" || stats.Src != 100 || stats.Dst != 200 || !stats.GroupCall || stats.Slot {
+		t.Fatalf("unexpected call %+v", stats)
+	}
+	if stats.Frames != 4 || stats.Dropped != 2 || stats.Lost != 2 {
+		t.Fatalf("expected 4 frames, 2 dropped, and 2 lost, got %+v", stats)
+	}
+	if stats.Duration != 1500*time.Millisecond || stats.End.IsZero() {
+		t.Fatalf("expected a finished call of 1.5s, got %+v", stats)
+	}
+	if got, ok := tr.StreamStats(stats.StreamID); !ok || got != stats {
+		t.Fatalf("expected lookup by stream ID to find %+v, got %+v", stats, got)
+	}
+}
+
+func TestCallStatsHistory(t *testing.T) {
+	t.Parallel()
+	tr, _ := newClockedTranslator(t)
+	for i := range callHistorySize + 5 {
+		header := makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 1)
+		header.StreamID = uint(i + 1)
+		tr.TranslateToIPSC(header)
+		term := header
+		term.DTypeOrVSeq = 2
+		tr.TranslateToIPSC(term)
+	}
+	// One call still in progress comes first.
+	tr.TranslateToIPSC(makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 1))
+
+	calls := tr.Snapshot()
+	if len(calls) != callHistorySize+1 {
+		t.Fatalf("expected %d calls, got %d", callHistorySize+1, len(calls))
+	}
+	if calls[0].StreamID != 0x1234 || !calls[0].End.IsZero() {
+		t.Fatalf("expected the call in progress first, got %+v", calls[0])
+	}
+	if calls[1].StreamID != callHistorySize+5 || calls[len(calls)-1].StreamID != 6 {
+		t.Fatalf("expected finished calls 37 down to 6, got %d down to %d", calls[1].StreamID, calls[len(calls)-1].StreamID)
+	}
+	if _, ok := tr.StreamStats(5); ok {
+		t.Fatal("expected the oldest calls to have been forgotten")
+	}
+}
+
+func TestCallStatsCleanupStream(t *testing.T) {
+	t.Parallel()
+	tr, _ := newClockedTranslator(t)
+	tr.TranslateToIPSC(makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 1))
+	tr.CleanupStream(0x1234)
+	stats, ok := tr.StreamStats(0x1234)
+	if !ok || stats.End.IsZero() {
+		t.Fatalf("expected a timed-out call to be finished, got %+v", stats)
+	}
+}
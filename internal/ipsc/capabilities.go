@@ -0,0 +1,109 @@
+package ipsc
+
+import (
+	"strconv"
+)
+
+// Mode byte and flag bits a peer advertises when it registers.
+const (
+	modePeerOperational byte = 0b01000000
+	modePeerMask        byte = 0b00110000
+	modePeerDigital     byte = 0b00100000
+	modeTS1On           byte = 0b00001000
+	modeTS2On           byte = 0b00000010
+
+	// flags[2]
+	flagCSBK byte = 0b10000000
+	// flags[3]
+	flagAuth       byte = 0b00010000
+	flagDataCall   byte = 0b00001000
+	flagVoiceCall  byte = 0b00000100
+	flagMasterPeer byte = 0b00000001
+)
+
+// skipReason is why a packet was not sent to a peer.
+type skipReason string
+
+const (
+	skipNotDigital skipReason = "not_digital"
+	skipNoVoice    skipReason = "no_voice"
+	skipNoData     skipReason = "no_data"
+	skipNoCSBK     skipReason = "no_csbk"
+)
+
+// packetClass is the kind of traffic a user packet carries, as far as
+// peer capabilities are concerned.
+type packetClass int
+
+const (
+	classOther packetClass = iota
+	classVoice
+	classData
+	classCSBK
+)
+
+// classifyUserPacket returns the class of an IPSC packet about to be sent
+// to peers. Anything that is not a voice or data packet is classOther and
+// goes to every peer.
+func classifyUserPacket(data []byte) packetClass {
+	if len(data) == 0 {
+		return classOther
+	}
+	switch PacketType(data[0]) {
+	case PacketType_GroupVoice, PacketType_PrivateVoice:
+		return classVoice
+	case PacketType_GroupData, PacketType_PrivateData:
+		if len(data) > 30 && data[30] == ipscBurstCSBK {
+			return classCSBK
+		}
+		return classData
+	default:
+		return classOther
+	}
+}
+
+// acceptsClass reports whether peer advertised the capabilities needed
+// for class, and if not, why. A peer whose mode does not mark it
+// operational, such as one restored without its mode, has advertised
+// nothing to go by and accepts everything. The repeater call monitoring
+// flag is not consulted here; call monitoring packets are sent on their
+// own, by sendCallMonitor.
+func (peer *Peer) acceptsClass(class packetClass) (skipReason, bool) {
+	if class == classOther || peer.Mode&modePeerOperational == 0 {
+		return "", true
+	}
+	if peer.Mode&modePeerMask != modePeerDigital {
+		return skipNotDigital, false
+	}
+	switch class {
+	case classVoice:
+		if peer.Flags[3]&flagVoiceCall == 0 {
+			return skipNoVoice, false
+		}
+	case classData:
+		if peer.Flags[3]&flagDataCall == 0 {
+			return skipNoData, false
+		}
+	case classCSBK:
+		if peer.Flags[2]&flagCSBK == 0 {
+			return skipNoCSBK, false
+		}
+	}
+	return "", true
+}
+
+// noteSkip counts a packet not sent to peer and logs the first skip for
+// each reason. The caller must hold s.mu for writing.
+func (s *IPSCServer) noteSkip(peer *Peer, reason skipReason) {
+	if peer.skipped == nil {
+		peer.skipped = map[skipReason]uint64{}
+	}
+	if peer.skipped[reason] == 0 {
+		s.log.Debug("Not sending traffic to IPSC peer that does not advertise support for it",
+			"peerID", peer.ID, "reason", reason, "mode", peer.Mode, "flags", peer.Flags)
+	}
+	peer.skipped[reason]++
+	if s.metrics != nil {
+		s.metrics.IPSCPeerPacketsSkipped.WithLabelValues(strconv.FormatUint(uint64(peer.ID), 10), string(reason)).Inc()
+	}
+}
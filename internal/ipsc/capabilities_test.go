@@ -0,0 +1,152 @@
+package ipsc
+
+import (
+	"net"
+	"slices"
+	"testing"
+)
+
+// capabilityPackets are one packet of each class, in the order they are
+// sent, named by the class they carry.
+func capabilityPackets() map[string][]byte {
+	voice := make([]byte, 54)
+	voice[0] = byte(PacketType_GroupVoice)
+	voice[30] = 0x01
+	data := make([]byte, 54)
+	data[0] = byte(PacketType_PrivateData)
+	data[30] = 0x06
+	control := make([]byte, 54)
+	control[0] = byte(PacketType_PrivateData)
+	control[30] = ipscBurstCSBK
+	return map[string][]byte{"voice": voice, "data": data, "csbk": control}
+}
+
+// receivedClasses reads from conn until the end marker and returns the
+// names of the packets that arrived before it.
+func receivedClasses(t *testing.T, conn *net.UDPConn, packets map[string][]byte) []string {
+	t.Helper()
+	var got []string
+	for {
+		pkt := readUDP(t, conn)
+		if string(pkt) == "end" {
+			return got
+		}
+		for name, sent := range packets {
+			if string(sent) == string(pkt) {
+				got = append(got, name)
+			}
+		}
+	}
+}
+
+func TestSendUserPacketFiltersByCapabilities(t *testing.T) {
+	t.Parallel()
+	const (
+		modeDigital = 0x6A
+		modeAnalog  = 0x5A
+	)
+	peers := []struct {
+		name  string
+		mode  byte
+		flags [4]byte
+		want  []string
+	}{
+		{"full", modeDigital, [4]byte{0, 0, 0x80, 0x0D}, []string{"csbk", "data", "voice"}},
+		{"no csbk", modeDigital, [4]byte{0, 0, 0, 0x0D}, []string{"data", "voice"}},
+		{"voice only", modeDigital, [4]byte{0, 0, 0, 0x04}, []string{"voice"}},
+		{"analog", modeAnalog, [4]byte{0, 0, 0x80, 0x0D}, nil},
+		{"not operational", 0, [4]byte{}, []string{"csbk", "data", "voice"}},
+	}
+	everything := []string{"csbk", "data", "voice"}
+
+	for _, ignore := range []bool{false, true} {
+		cfg := testConfig(false, "")
+		cfg.IPSC.IgnorePeerCapabilities = ignore
+		s, _ := newTestServerWithConfig(t, cfg)
+
+		conns := make([]*net.UDPConn, len(peers))
+		for i, p := range peers {
+			conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+			if err != nil {
+				t.Fatalf("listen: %v", err)
+			}
+			defer conn.Close()
+			conns[i] = conn
+			addr, ok := conn.LocalAddr().(*net.UDPAddr)
+			if !ok {
+				t.Fatal("expected *net.UDPAddr from LocalAddr")
+			}
+			s.upsertPeer(uint32(i+1), addr, p.mode, p.flags)
+		}
+
+		packets := capabilityPackets()
+		for _, name := range []string{"voice", "data", "csbk"} {
+			s.SendUserPacket(packets[name])
+		}
+		s.SendUserPacket([]byte("end"))
+
+		for i, p := range peers {
+			want := p.want
+			if ignore {
+				want = everything
+			}
+			got := receivedClasses(t, conns[i], packets)
+			slices.Sort(got)
+			if !slices.Equal(got, want) {
+				t.Errorf("ignore=%t, peer %s: expected %v, got %v", ignore, p.name, want, got)
+			}
+		}
+	}
+}
+
+func TestCapabilitySkipsCountedPerPeer(t *testing.T) {
+	t.Parallel()
+	s, _ := newTestServerWithUDP(t, false, "")
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9}
+	s.upsertPeer(1, addr, 0x6A, [4]byte{0, 0, 0, 0x04})
+
+	packets := capabilityPackets()
+	s.SendUserPacket(packets["data"])
+	s.SendUserPacket(packets["data"])
+	s.SendUserPacket(packets["csbk"])
+
+	s.mu.RLock()
+	skipped := s.peers[1].skipped
+	s.mu.RUnlock()
+	if skipped[skipNoData] != 2 || skipped[skipNoCSBK] != 1 {
+		t.Fatalf("expected 2 data and 1 CSBK skips, got %v", skipped)
+	}
+
+	// Registering again may advertise different capabilities.
+	s.upsertPeer(1, addr, 0x6A, [4]byte{0, 0, 0, 0x0D})
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.peers[1].skipped) != 0 {
+		t.Fatalf("expected skips to reset on registration, got %v", s.peers[1].skipped)
+	}
+}
+
+func TestClassifyUserPacket(t *testing.T) {
+	t.Parallel()
+	packets := capabilityPackets()
+	tests := []struct {
+		name string
+		data []byte
+		want packetClass
+	}{
+		{"group voice", packets["voice"], classVoice},
+		{"private data", packets["data"], classData},
+		{"csbk", packets["csbk"], classCSBK},
+		{"short data", []byte{byte(PacketType_GroupData)}, classData},
+		{"control", []byte{byte(PacketType_MasterAliveRequest)}, classOther},
+		{"empty", nil, classOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := classifyUserPacket(tt.data); got != tt.want {
+				t.Fatalf("expected class %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
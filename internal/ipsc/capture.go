@@ -0,0 +1,82 @@
+package ipsc
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/capture"
+)
+
+// SetCapture records every packet the server receives and sends to w,
+// with the peer ID and what became of it. It must be called before
+// Start; w is left for the caller to close.
+func (s *IPSCServer) SetCapture(w *capture.Writer) {
+	s.capture = w
+}
+
+// captureInbound records data, received from addr at receivedAt, with
+// err, what came of handling it.
+func (s *IPSCServer) captureInbound(receivedAt time.Time, data []byte, addr *net.UDPAddr, err error) {
+	if s.capture == nil {
+		return
+	}
+	var peerID uint32
+	if len(data) >= 5 {
+		peerID = binary.BigEndian.Uint32(data[1:5])
+	}
+	verdict := "accepted"
+	switch {
+	case errors.Is(err, ErrPacketIgnored):
+		verdict = "ignored"
+	case err != nil:
+		verdict = "error: " + err.Error()
+	}
+	s.record(capture.Record{
+		Time:      receivedAt,
+		Direction: capture.Inbound,
+		Src:       addr,
+		Dst:       s.Addr(),
+		PeerID:    peerID,
+		Verdict:   verdict,
+		Data:      data,
+	})
+}
+
+// captureOutbound records data, sent to addr with err as the result.
+// Sent packets carry the server's own ID, so the peer ID is that of the
+// peer at addr.
+func (s *IPSCServer) captureOutbound(data []byte, addr *net.UDPAddr, err error) {
+	if s.capture == nil {
+		return
+	}
+	var peerID uint32
+	s.mu.RLock()
+	for _, peer := range s.peers {
+		if peer.Addr != nil && peer.Addr.String() == addr.String() {
+			peerID = peer.ID
+			break
+		}
+	}
+	s.mu.RUnlock()
+	verdict := "sent"
+	if err != nil {
+		verdict = "error: " + err.Error()
+	}
+	s.record(capture.Record{
+		Time:      s.now(),
+		Direction: capture.Outbound,
+		Src:       s.Addr(),
+		Dst:       addr,
+		PeerID:    peerID,
+		Verdict:   verdict,
+		Data:      data,
+	})
+}
+
+func (s *IPSCServer) record(r capture.Record) {
+	if err := s.capture.Record(r); err != nil {
+		s.log.Warn("error capturing IPSC packet", "error", err)
+	}
+}
@@ -0,0 +1,106 @@
+package ipsc
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/capture"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+)
+
+func TestCaptureRecordsTrafficWithVerdicts(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	w, err := capture.New(config.IPSCCapture{
+		Path: filepath.Join(dir, "ipsc.jsonl"), Format: config.CaptureFormatJSONL,
+		MaxFileSize: 1 << 20, MaxTotalSize: 1 << 20,
+	})
+	if err != nil {
+		t.Fatalf("capture.New: %v", err)
+	}
+	cfg := testConfig(false, "")
+	cfg.IPSC.IP = "127.0.0.1"
+	s := NewIPSCServer(cfg, nil)
+	s.SetCapture(w)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	client, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer client.Close()
+	for _, data := range [][]byte{
+		makeControlPacket(PacketType_MasterRegisterRequest, 8000),
+		makeControlPacket(PacketType_DeRegisterReply, 8000),
+		{0xFF},
+	} {
+		if _, err := client.WriteToUDP(data, s.Addr()); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	readPacketType(t, client, PacketType_MasterRegisterReply)
+	s.Stop()
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "ipsc-*.jsonl"))
+	if len(files) != 1 {
+		t.Fatalf("expected one capture file, got %v", files)
+	}
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+	type line struct {
+		Direction string `json:"direction"`
+		Src       string `json:"src"`
+		Dst       string `json:"dst"`
+		PeerID    uint32 `json:"peerID"`
+		Type      string `json:"type"`
+		Verdict   string `json:"verdict"`
+	}
+	got := map[string]line{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var l line
+		if err := json.Unmarshal(scanner.Bytes(), &l); err != nil {
+			t.Fatalf("unmarshal %q: %v", scanner.Text(), err)
+		}
+		got[l.Direction+" "+l.Type] = l
+	}
+
+	clientAddr := client.LocalAddr().String()
+	serverAddr := s.Addr().String()
+	tests := []struct {
+		key     string
+		src     string
+		dst     string
+		verdict string
+	}{
+		{"in 0x90", clientAddr, serverAddr, "accepted"},
+		{"out 0x91", serverAddr, clientAddr, "sent"},
+		{"in 0x9B", clientAddr, serverAddr, "ignored"},
+		{"in 0xFF", clientAddr, serverAddr, "error: "},
+	}
+	for _, tt := range tests {
+		l, ok := got[tt.key]
+		if !ok {
+			t.Fatalf("%s: not captured, got %+v", tt.key, got)
+		}
+		if l.Src != tt.src || l.Dst != tt.dst || !strings.HasPrefix(l.Verdict, tt.verdict) {
+			t.Fatalf("%s: expected %s to %s %q, got %+v", tt.key, tt.src, tt.dst, tt.verdict, l)
+		}
+		if tt.key != "in 0xFF" && l.PeerID != 8000 {
+			t.Fatalf("%s: expected peer 8000, got %d", tt.key, l.PeerID)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package ipsc
+
+import (
+	"fmt"
+	"net"
+)
+
+// packetEnvelope is a received packet as its handler sees it: rate
+// limited, authenticated, and with any digest stripped.
+type packetEnvelope struct {
+	Type PacketType
+	// PeerID is the ID the packet carries. It is zero for handlers
+	// registered with anyLength, which parse the packet themselves.
+	PeerID uint32
+	// Data is the whole packet, type byte included, without the digest.
+	Data []byte
+	Addr *net.UDPAddr
+}
+
+// packetHandlerFunc handles a packet and returns the replies to send back
+// to where it came from, in order.
+type packetHandlerFunc func(env packetEnvelope) ([][]byte, error)
+
+// packetHandler is how the server handles one packet type.
+type packetHandler struct {
+	// label counts the packet in ipsc_packets_received_total, if set.
+	label string
+	// anyLength passes packets too short for a peer ID to the handler
+	// instead of refusing them.
+	anyLength bool
+	// untranslated packets are reported ignored once handled, as they
+	// are never translated.
+	untranslated bool
+	handle       packetHandlerFunc
+}
+
+// packetHandlers returns the handler of each packet type the server
+// understands.
+func (s *IPSCServer) packetHandlers() map[PacketType]packetHandler {
+	return map[PacketType]packetHandler{
+		PacketType_GroupVoice:            {label: "group_voice", handle: s.handleUserPacket},
+		PacketType_PrivateVoice:          {label: "private_voice", handle: s.handleUserPacket},
+		PacketType_GroupData:             {label: "group_data", handle: s.handleUserPacket},
+		PacketType_PrivateData:           {label: "private_data", handle: s.handleUserPacket},
+		PacketType_RepeaterWakeUp:        {label: "wake_up", handle: s.handleRepeaterWakeUp},
+		PacketType_MasterRegisterRequest: {label: "register", handle: s.handleMasterRegisterRequest},
+		PacketType_MasterAliveRequest:    {label: "alive", handle: s.handleMasterAliveRequest},
+		PacketType_PeerListRequest:       {label: "peer_list", handle: s.handlePeerListRequest},
+		PacketType_DeRegisterRequest:     {label: "deregister", handle: s.handleDeRegisterRequest},
+		PacketType_PeerAliveReply:        {label: "peer_alive", handle: s.handlePeerAliveReply},
+		PacketType_PeerAliveRequest:      {label: "peer_alive", handle: s.handlePeerAliveRequest},
+		PacketType_PeerRegisterRequest:   {label: "peer_register", handle: s.handlePeerRegisterRequest},
+		PacketType_PeerRegisterReply:     {label: "peer_register", handle: s.handlePeerRegisterReply},
+
+		// Call monitoring is never translated.
+		PacketType_CallMonitorStatus:   {label: "call_monitor_status", anyLength: true, untranslated: true, handle: s.handleCallMonitor},
+		PacketType_CallMonitorRepeater: {label: "call_monitor_repeater", anyLength: true, untranslated: true, handle: s.handleCallMonitor},
+		PacketType_CallMonitorNack:     {label: "call_monitor_nack", anyLength: true, untranslated: true, handle: s.handleCallMonitor},
+
+		// Replies from the master, when registered with one as a peer.
+		PacketType_MasterRegisterReply: {anyLength: true, handle: s.handleMasterReply},
+		PacketType_PeerListReply:       {anyLength: true, handle: s.handleMasterReply},
+		PacketType_MasterAliveReply:    {anyLength: true, handle: s.handleMasterReply},
+		// These are reply packets, we shouldn't receive them as a server,
+		// keeping quiet.
+		PacketType_DeRegisterReply: {anyLength: true, untranslated: true, handle: func(packetEnvelope) ([][]byte, error) {
+			return nil, nil
+		}},
+	}
+}
+
+func (s *IPSCServer) handlePacket(data []byte, addr *net.UDPAddr) (*Packet, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("packet too short")
+	}
+
+	packetType := PacketType(data[0])
+	wireSize := len(data)
+	// replyKey is the key that authenticated the packet, which its
+	// replies are signed with.
+	var replyKey []byte
+
+	if s.rateLimited(data, addr) {
+		if s.metrics != nil {
+			s.metrics.IPSCPacketsRateLimited.Inc()
+		}
+		return nil, ErrPacketIgnored
+	}
+
+	if s.cfg.IPSC.Auth.Enabled {
+		if s.authBanned(addr) {
+			return nil, ErrPacketIgnored
+		}
+		if len(data) <= authDigestSize {
+			return nil, fmt.Errorf("packet too short for authentication")
+		}
+		peerID, err := parsePeerID(data[:len(data)-authDigestSize])
+		if err != nil {
+			return nil, err
+		}
+		key, ok := s.keyFor(peerID)
+		if !ok {
+			if s.metrics != nil {
+				s.metrics.IPSCAuthFailures.Inc()
+			}
+			s.noteAuthFailure(addr)
+			s.countAuthFailure(peerID)
+			return nil, fmt.Errorf("peer %d: %w", peerID, ErrNoPeerKey)
+		}
+		if !s.auth(peerID, data) {
+			if s.metrics != nil {
+				s.metrics.IPSCAuthFailures.Inc()
+			}
+			s.noteAuthFailure(addr)
+			s.countAuthFailure(peerID)
+			return nil, fmt.Errorf("authentication failed")
+		}
+		// Strip the digest, so everything past this point, the
+		// translator included, sees the same packet as without
+		// authentication.
+		s.noteAuthSuccess(addr)
+		replyKey = key
+		data = data[:len(data)-authDigestSize]
+	}
+	// Counted once handled, so a registration counts against its peer.
+	defer s.countReceived(data, wireSize)
+
+	handler, ok := s.handlers[packetType]
+	if !ok {
+		// Capacity Plus and Linked Capacity Plus beacon and rest-channel
+		// opcodes are not published, so that traffic lands here too
+		// rather than being matched against guessed values.
+		if s.metrics != nil {
+			s.metrics.IPSCPacketsReceived.WithLabelValues("other").Inc()
+		}
+		return nil, fmt.Errorf("unknown packet type: %d", packetType)
+	}
+	if s.metrics != nil && handler.label != "" {
+		s.metrics.IPSCPacketsReceived.WithLabelValues(handler.label).Inc()
+	}
+
+	env := packetEnvelope{Type: packetType, Data: data, Addr: addr}
+	if !handler.anyLength {
+		peerID, err := parsePeerID(data)
+		if err != nil {
+			return nil, err
+		}
+		env.PeerID = peerID
+		// Peers kept out by ipsc.allowed-peers or ipsc.denied-peers are
+		// refused before anything of theirs is recorded or forwarded.
+		if !s.peerAllowed(packetType, peerID, addr) {
+			return nil, ErrPacketIgnored
+		}
+	}
+	replies, err := handler.handle(env)
+	if err != nil {
+		return nil, err
+	}
+	for _, reply := range replies {
+		if err := s.sendReply(&Packet{data: reply}, addr, replyKey); err != nil {
+			return nil, fmt.Errorf("error sending reply to packet type 0x%02X: %w", byte(packetType), err)
+		}
+	}
+	if handler.untranslated {
+		return nil, ErrPacketIgnored
+	}
+	return &Packet{data: data}, nil
+}
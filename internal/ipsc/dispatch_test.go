@@ -0,0 +1,53 @@
+package ipsc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHandlePacketUnknownTypesError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		auth bool
+	}{
+		{"no auth", false},
+		{"auth", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s, _ := newTestServerWithUDP(t, tt.auth, "1234")
+			conn, addr := listenPeer(t)
+			for _, packetType := range []PacketType{0x00, 0x70, 0x86, 0x9C, 0xFF} {
+				if _, ok := s.handlers[packetType]; ok {
+					t.Fatalf("0x%02X: expected no handler", byte(packetType))
+				}
+				data := makeControlPacket(packetType, 8000)
+				if tt.auth {
+					data = signPacket(t, data, banTestKey)
+				}
+				_, err := s.handlePacket(data, addr)
+				if err == nil || errors.Is(err, ErrPacketIgnored) || !strings.Contains(err.Error(), "unknown packet type") {
+					t.Fatalf("0x%02X: expected an unknown packet type error, got %v", byte(packetType), err)
+				}
+			}
+			assertNothingReceived(t, conn)
+		})
+	}
+}
+
+func TestHandlePacketShortPacketsNeedPeerID(t *testing.T) {
+	t.Parallel()
+	s, _ := newTestServerWithUDP(t, false, "")
+	_, addr := listenPeer(t)
+	for packetType, handler := range s.handlers {
+		if handler.anyLength {
+			continue
+		}
+		if _, err := s.handlePacket([]byte{byte(packetType), 0, 0}, addr); err == nil || errors.Is(err, ErrPacketIgnored) {
+			t.Fatalf("0x%02X: expected a packet too short for a peer ID refused, got %v", byte(packetType), err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package ipsc
+
+import (
+	"testing"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+)
+
+func TestInstancesKeepSeparatePeers(t *testing.T) {
+	t.Parallel()
+	const (
+		keyA = "0000000000000000000000000000000000001234"
+		keyB = "0000000000000000000000000000000000005678"
+	)
+	cfg := testConfig(true, keyA)
+	cfg.IPSC.Name = "a"
+	cfg.IPSCServers = []config.IPSC{cfg.IPSC, {
+		Name: "b",
+		Auth: config.IPSCAuth{Enabled: true, Key: keyB},
+	}}
+	configs := cfg.IPSCConfigs()
+	a, _ := newTestServerWithConfig(t, configs[0])
+	b, _ := newTestServerWithConfig(t, configs[1])
+	if a.Name() != "ipsc/a" || b.Name() != "ipsc/b" {
+		t.Fatalf("expected the servers named after their instances, got %q and %q", a.Name(), b.Name())
+	}
+	conn, addr := listenPeer(t)
+
+	register := func(peerID uint32, key string) []byte {
+		return signPacket(t, makeControlPacketWithModeFlags(PacketType_MasterRegisterRequest, peerID, 0x6A, [4]byte{0, 0, 0, 0x0D}), key)
+	}
+	if _, err := a.handlePacket(register(100, keyA), addr); err != nil {
+		t.Fatalf("register with a: %v", err)
+	}
+	readPacketType(t, conn, PacketType_MasterRegisterReply)
+
+	// Each instance has its own key.
+	if _, err := b.handlePacket(register(100, keyA), addr); err == nil {
+		t.Fatal("expected b to refuse a packet signed with a's key")
+	}
+	assertNothingReceived(t, conn)
+	if _, err := b.handlePacket(register(200, keyB), addr); err != nil {
+		t.Fatalf("register with b: %v", err)
+	}
+	readPacketType(t, conn, PacketType_MasterRegisterReply)
+
+	for name, tc := range map[string]struct {
+		server *IPSCServer
+		want   uint32
+	}{
+		"a": {a, 100},
+		"b": {b, 200},
+	} {
+		snapshot := tc.server.PeersSnapshot()
+		if len(snapshot) != 1 || snapshot[0].ID != tc.want {
+			t.Fatalf("%s: expected only peer %d, got %+v", name, tc.want, snapshot)
+		}
+	}
+}
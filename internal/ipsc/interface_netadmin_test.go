@@ -0,0 +1,59 @@
+//go:build linux && netadmin
+
+package ipsc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/netsetup"
+)
+
+// These tests create and delete real interfaces, so they need
+// CAP_NET_ADMIN and only run with the netadmin build tag:
+//
+//	sudo go test -tags netadmin ./internal/ipsc -run Interface
+
+func TestInterfaceCreatedAndDeleted(t *testing.T) {
+	const name = "ipsctest0"
+	cfg := testConfig(false, "")
+	cfg.IPSC.Interface = name
+	cfg.IPSC.IP = "10.250.250.1"
+	cfg.IPSC.SubnetMask = 24
+	s := NewIPSCServer(cfg, nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if exists, err := netsetup.New().LinkExists(name); err != nil || !exists {
+		t.Fatalf("expected %s created, got exists %v, error %v", name, exists, err)
+	}
+	s.Stop()
+	if exists, err := netsetup.New().LinkExists(name); err != nil || exists {
+		t.Fatalf("expected %s deleted, got exists %v, error %v", name, exists, err)
+	}
+}
+
+func TestInterfaceAdoptedAndKept(t *testing.T) {
+	const name = "ipsctest1"
+	netw := netsetup.New()
+	if err := netw.CreateDummy(name); err != nil {
+		t.Fatalf("CreateDummy: %v", err)
+	}
+	t.Cleanup(func() { _ = netw.Delete(name) })
+	if err := netw.CreateDummy(name); !errors.Is(err, netsetup.ErrLinkExists) {
+		t.Fatalf("expected %v creating it twice, got %v", netsetup.ErrLinkExists, err)
+	}
+
+	cfg := testConfig(false, "")
+	cfg.IPSC.Interface = name
+	cfg.IPSC.IP = "10.250.251.1"
+	cfg.IPSC.SubnetMask = 24
+	s := NewIPSCServer(cfg, nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	s.Stop()
+	if exists, err := netw.LinkExists(name); err != nil || !exists {
+		t.Fatalf("expected %s left in place, got exists %v, error %v", name, exists, err)
+	}
+}
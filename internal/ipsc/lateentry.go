@@ -0,0 +1,97 @@
+package ipsc
+
+import (
+	"log/slog"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/bptc"
+	mmdvm "github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+)
+
+// Voice link control opcodes, ETSI TS 102 361-2 7.1.1.1.
+const (
+	flcoGroupVoice      byte = 0x00
+	flcoUnitToUnitVoice byte = 0x03
+)
+
+// lateEntryLC reassembles the link control the master embeds in voice
+// bursts B to E, for calls from the master joined after their voice
+// header went by.
+type lateEntryLC struct {
+	fragments bptc.Fragments
+	seen      uint8             // bit n-1 is set once burst n's fragment is in
+	lc        [bptc.LCSize]byte // the last voice link control reassembled
+}
+
+// note records the fragment of voice burst burstIdx, 1 to 4 for B to E,
+// and reports whether it completed a voice link control.
+func (l *lateEntryLC) note(burstIdx int, fragment [bptc.FragmentSize]byte) bool {
+	if burstIdx == 1 {
+		// A new superframe; fragments of the last one don't carry over.
+		l.seen = 0
+	}
+	l.fragments[burstIdx-1] = fragment
+	l.seen |= 1 << (burstIdx - 1)
+	if burstIdx != 4 || l.seen != 0x0F {
+		return false
+	}
+	lc, ok := bptc.DecodeEmbeddedLC(l.fragments)
+	if !ok {
+		return false
+	}
+	if flco := lc[0] & 0x3F; flco != flcoGroupVoice && flco != flcoUnitToUnitVoice {
+		return false
+	}
+	// Blank embedded signalling decodes to an all-zero link control with
+	// a good checksum. It addresses nobody, so it is not the call's.
+	if lcAddress(lc[3:6]) == 0 || lcAddress(lc[6:9]) == 0 {
+		return false
+	}
+	l.lc = lc
+	return true
+}
+
+// apply sets the call type and addressing of pkt from the link control.
+func (l *lateEntryLC) apply(pkt *mmdvm.Packet) {
+	pkt.GroupCall = l.lc[0]&0x3F == flcoGroupVoice
+	pkt.Dst = lcAddress(l.lc[3:6])
+	pkt.Src = lcAddress(l.lc[6:9])
+}
+
+// lcAddress returns the 24-bit address in the three octets b.
+func lcAddress(b []byte) uint {
+	return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+}
+
+// lateEntryHeaders returns the voice headers for a call from the master
+// whose own header never arrived, once the link control embedded in a
+// full superframe of its voice has been reassembled. The headers carry
+// the call type and addressing of that link control, so a repeater
+// joining mid-call starts it the way the master described it. Must be
+// called with t.mu held, before the burst is built.
+func (t *IPSCTranslator) lateEntryHeaders(pkt mmdvm.Packet, ss *streamState) [][]byte {
+	if ss.headersSent > 0 || pkt.FrameType != mmdvmFrameTypeVoice {
+		return nil
+	}
+	burstIdx := ss.burstIndex % 6
+	if burstIdx < 1 || burstIdx > 4 {
+		return nil
+	}
+	t.burst.DecodeFromBytes(pkt.DMRData)
+	if t.burst.IsData || !t.burst.HasEmbeddedSignalling {
+		return nil
+	}
+	if !ss.lateLC.note(burstIdx, t.burst.PackEmbeddedSignallingData()) {
+		return nil
+	}
+
+	header := pkt
+	ss.lateLC.apply(&header)
+	slog.Debug("IPSCTranslator: late entry, recovered link control from embedded signalling",
+		"src", header.Src, "dst", header.Dst, "groupCall", header.GroupCall)
+	headers := make([][]byte, 0, t.headerRepeats)
+	for range t.headerRepeats {
+		headers = append(headers, t.buildVoiceHeader(header, ss, false))
+	}
+	ss.headersSent = t.headerRepeats
+	return headers
+}
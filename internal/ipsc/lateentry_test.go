@@ -0,0 +1,145 @@
+package ipsc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/USA-RedDragon/dmrgo/dmr/enums"
+	"github.com/USA-RedDragon/dmrgo/dmr/layer2"
+	"github.com/USA-RedDragon/dmrgo/dmr/layer2/elements"
+	"github.com/USA-RedDragon/dmrgo/dmr/layer2/pdu"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/bptc"
+)
+
+// lateEntryBurst returns voice burst pos, 1 to 4 for B to E, carrying
+// the fragment of an embedded link control.
+func lateEntryBurst(pos int, fragment [bptc.FragmentSize]byte) [33]byte {
+	lcss := enums.ContinuationFragmentLCorCSBK
+	switch pos {
+	case 1:
+		lcss = enums.FirstFragmentLC
+	case 4:
+		lcss = enums.LastFragmentLCorCSBK
+	}
+	burst := layer2.Burst{
+		SyncPattern:           enums.EmbeddedSignallingPattern,
+		VoiceBurst:            enums.VoiceBurstB,
+		HasEmbeddedSignalling: true,
+		EmbeddedSignalling:    pdu.EmbeddedSignalling{LCSS: lcss, ParityOK: true},
+	}
+	burst.UnpackEmbeddedSignallingData(fragment[:])
+	return burst.Encode()
+}
+
+func TestLateEntryRecoversLinkControl(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+
+	// A private call from 3120001 to 3120002, although the DMRD header
+	// of the frames says a group call from 100 to 200.
+	call := makeTestMMDVMPacket(false, false, mmdvmFrameTypeVoice, 0)
+	call.Src, call.Dst = 3120001, 3120002
+	flc := extractFullLCBytes(call, elements.DataTypeVoiceLCHeader)
+	var lc [bptc.LCSize]byte
+	copy(lc[:], flc[:bptc.LCSize])
+	fragments := bptc.EncodeEmbeddedLC(lc)
+
+	var results [][]byte
+	for pos := 1; pos <= 4; pos++ {
+		pkt := makeTestMMDVMPacket(true, false, mmdvmFrameTypeVoice, uint(pos))
+		pkt.DMRData = lateEntryBurst(pos, fragments[pos-1])
+		out := tr.TranslateToIPSC(pkt)
+		if pos < 4 && len(out) != 1 {
+			t.Fatalf("burst %d: expected only the burst before the link control is complete, got %d packets", pos, len(out))
+		}
+		results = append(results, out...)
+	}
+
+	ss := tr.streams[0x1234]
+	if ss.lateLC.lc != lc {
+		t.Fatalf("expected link control % X reconstructed, got % X", lc, ss.lateLC.lc)
+	}
+	// Bursts B-D, then three headers ahead of burst E.
+	if len(results) != 7 {
+		t.Fatalf("expected 7 packets, got %d", len(results))
+	}
+	for i, header := range results[3:6] {
+		if header[30] != ipscBurstVoiceHead {
+			t.Fatalf("packet %d: expected a voice header, got burst type 0x%02X", i+3, header[30])
+		}
+		if !bytes.Equal(header[38:38+bptc.LCSize], lc[:]) {
+			t.Fatalf("header %d: expected link control % X, got % X", i, lc, header[38:38+bptc.LCSize])
+		}
+		if header[12] != 0x01 {
+			t.Fatalf("header %d: expected a private call, got call type 0x%02X", i, header[12])
+		}
+		if src := uint(header[6])<<16 | uint(header[7])<<8 | uint(header[8]); src != 3120001 {
+			t.Fatalf("header %d: expected src 3120001, got %d", i, src)
+		}
+		if dst := uint(header[9])<<16 | uint(header[10])<<8 | uint(header[11]); dst != 3120002 {
+			t.Fatalf("header %d: expected dst 3120002, got %d", i, dst)
+		}
+	}
+
+	// The call has its headers now, so the next superframe adds none.
+	for pos := 1; pos <= 4; pos++ {
+		pkt := makeTestMMDVMPacket(true, false, mmdvmFrameTypeVoice, uint(pos))
+		pkt.DMRData = lateEntryBurst(pos, fragments[pos-1])
+		if out := tr.TranslateToIPSC(pkt); len(out) != 1 {
+			t.Fatalf("burst %d: expected no more headers, got %d packets", pos, len(out))
+		}
+	}
+}
+
+func TestLateEntryNeedsWholeSuperframe(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+
+	call := makeTestMMDVMPacket(true, false, mmdvmFrameTypeVoice, 0)
+	flc := extractFullLCBytes(call, elements.DataTypeVoiceLCHeader)
+	var lc [bptc.LCSize]byte
+	copy(lc[:], flc[:bptc.LCSize])
+	fragments := bptc.EncodeEmbeddedLC(lc)
+
+	// Joined at burst C, so burst B's fragment is missing.
+	for pos := 2; pos <= 4; pos++ {
+		pkt := makeTestMMDVMPacket(true, false, mmdvmFrameTypeVoice, uint(pos))
+		pkt.DMRData = lateEntryBurst(pos, fragments[pos-1])
+		if out := tr.TranslateToIPSC(pkt); len(out) != 1 {
+			t.Fatalf("burst %d: expected no headers from a partial superframe, got %d packets", pos, len(out))
+		}
+	}
+}
+
+func TestLateEntryIgnoresBlankSignalling(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+
+	var blank [bptc.FragmentSize]byte
+	for pos := 1; pos <= 4; pos++ {
+		pkt := makeTestMMDVMPacket(true, false, mmdvmFrameTypeVoice, uint(pos))
+		pkt.DMRData = lateEntryBurst(pos, blank)
+		if out := tr.TranslateToIPSC(pkt); len(out) != 1 {
+			t.Fatalf("burst %d: expected no headers from blank signalling, got %d packets", pos, len(out))
+		}
+	}
+}
+
+func TestLateEntrySkippedAfterHeader(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	tr.TranslateToIPSC(makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 1))
+
+	call := makeTestMMDVMPacket(true, false, mmdvmFrameTypeVoice, 0)
+	flc := extractFullLCBytes(call, elements.DataTypeVoiceLCHeader)
+	var lc [bptc.LCSize]byte
+	copy(lc[:], flc[:bptc.LCSize])
+	fragments := bptc.EncodeEmbeddedLC(lc)
+	for pos := 1; pos <= 4; pos++ {
+		pkt := makeTestMMDVMPacket(true, false, mmdvmFrameTypeVoice, uint(pos))
+		pkt.DMRData = lateEntryBurst(pos, fragments[pos-1])
+		if out := tr.TranslateToIPSC(pkt); len(out) != 1 {
+			t.Fatalf("burst %d: expected no headers after the call's own, got %d packets", pos, len(out))
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package ipsc
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/metrics"
+)
+
+// scrapeMetrics returns the text exposition of the metrics served by m.
+func scrapeMetrics(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+	srv := httptest.NewServer(m.Handler())
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/metrics") //nolint:noctx // a test scrape of a local server
+	if err != nil {
+		t.Fatalf("scrape: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return string(body)
+}
+
+func TestMetricsAfterTraffic(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig(true, "1234")
+	cfg.IPSC.IP = "127.0.0.1"
+	cfg.IPSC.RateLimit.Rate = 0
+	m := metrics.NewMetrics()
+	s := NewIPSCServer(cfg, m)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(s.Stop)
+
+	client, err := net.DialUDP("udp", nil, s.Addr())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer client.Close()
+	const key = "0000000000000000000000000000000000001234"
+	for _, data := range [][]byte{
+		signPacket(t, makeControlPacketWithModeFlags(PacketType_MasterRegisterRequest, 8000, 0x6A, [4]byte{0, 0, 0, 0x0D}), key),
+		signPacket(t, makeControlPacket(PacketType_MasterAliveRequest, 8000), key),
+		badlySigned(8000),
+		signPacket(t, makeControlPacket(PacketType_DeRegisterReply, 8000), key),
+	} {
+		if _, err := client.Write(data); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	want := []string{
+		"ipsc_peers_registered 1",
+		`ipsc_packets_received_total{type="register"} 1`,
+		`ipsc_packets_received_total{type="alive"} 1`,
+		"ipsc_auth_failures_total 1",
+		`ipsc_packets_dropped_total{reason="ignored"} 1`,
+		`ipsc_packets_dropped_total{reason="invalid"} 1`,
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		text := scrapeMetrics(t, m)
+		missing := ""
+		for _, line := range want {
+			if !strings.Contains(text, line+"\n") {
+				missing = line
+				break
+			}
+		}
+		if missing == "" {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %q in the scrape, got:\n%s", missing, text)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,139 @@
+package ipsc
+
+import (
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/metrics"
+	mmdvm "github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+)
+
+// MetricsSink receives the counts of an IPSCTranslator. Directions are
+// "mmdvm_to_ipsc" and "ipsc_to_mmdvm". The translator calls it with its
+// lock held, so a sink must not call back into the translator.
+type MetricsSink interface {
+	// IncTranslated counts a packet of kind sent on in direction, such
+	// as "voice_header", "voice", "voice_terminator", "pi_header", "csbk",
+	// or "data".
+	IncTranslated(direction, kind string)
+	// SetActiveStreams reports the number of calls in progress in
+	// direction.
+	SetActiveStreams(direction string, n int)
+	// IncDropped counts a packet refused in direction for reason, such as
+	// "duplicate" or "invalid_id".
+	IncDropped(direction, reason string)
+}
+
+// noopSink is the MetricsSink of a translator given none.
+type noopSink struct{}
+
+func (noopSink) IncTranslated(string, string) {}
+func (noopSink) SetActiveStreams(string, int) {}
+func (noopSink) IncDropped(string, string)    {}
+
+// prometheusSink is the MetricsSink SetMetrics installs. Several
+// translators share its gauge of active streams, so each adds the change
+// in its own count.
+type prometheusSink struct {
+	m      *metrics.Metrics
+	active map[string]int
+}
+
+func newPrometheusSink(m *metrics.Metrics) *prometheusSink {
+	return &prometheusSink{m: m, active: map[string]int{}}
+}
+
+func (s *prometheusSink) IncTranslated(direction, _ string) {
+	s.m.TranslatorPackets.WithLabelValues(direction).Inc()
+}
+
+func (s *prometheusSink) SetActiveStreams(direction string, n int) {
+	if delta := n - s.active[direction]; delta != 0 {
+		s.m.TranslatorActiveStreams.WithLabelValues(direction).Add(float64(delta))
+		s.active[direction] = n
+	}
+}
+
+func (s *prometheusSink) IncDropped(direction, reason string) {
+	s.m.TranslatorPacketsDropped.WithLabelValues(direction, reason).Inc()
+}
+
+// SetMetricsSink makes the translator report its counts to sink. A nil
+// sink discards them.
+func (t *IPSCTranslator) SetMetricsSink(sink MetricsSink) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if sink == nil {
+		sink = noopSink{}
+	}
+	t.sink = sink
+}
+
+// reportActiveStreams reports the calls in progress in each direction.
+// Must be called with t.mu held.
+func (t *IPSCTranslator) reportActiveStreams() {
+	t.sink.SetActiveStreams("mmdvm_to_ipsc", len(t.streams))
+	t.sink.SetActiveStreams("ipsc_to_mmdvm", len(t.reverseStreams))
+}
+
+// countToIPSC counts the packets translated toward IPSC. Must be called
+// with t.mu held.
+func (t *IPSCTranslator) countToIPSC(results [][]byte) {
+	for _, data := range results {
+		t.sink.IncTranslated("mmdvm_to_ipsc", ipscPacketKind(data))
+	}
+}
+
+// countToMMDVM counts the packets translated toward the master. Must be
+// called with t.mu held.
+func (t *IPSCTranslator) countToMMDVM(results []mmdvm.Packet) {
+	for _, pkt := range results {
+		t.sink.IncTranslated("ipsc_to_mmdvm", mmdvmPacketKind(pkt))
+	}
+}
+
+// ipscPacketKind names the kind of IPSC user packet data is.
+func ipscPacketKind(data []byte) string {
+	if PacketType(data[0]) == PacketType_RepeaterWakeUp {
+		return "wake_up"
+	}
+	if len(data) <= 30 {
+		return "data"
+	}
+	isData := PacketType(data[0]) == PacketType_GroupData || PacketType(data[0]) == PacketType_PrivateData
+	switch burstType := data[30]; {
+	case isReverseChannelBurst(burstType):
+		return "reverse_channel"
+	case burstType == ipscBurstCSBK:
+		return "csbk"
+	case burstType == ipscBurstPIHeader:
+		return "pi_header"
+	case isData:
+		return "data"
+	case burstType == ipscBurstVoiceHead:
+		return "voice_header"
+	case burstType == ipscBurstVoiceTerm:
+		return "voice_terminator"
+	default:
+		return "voice"
+	}
+}
+
+// mmdvmPacketKind names the kind of DMRD packet pkt is.
+func mmdvmPacketKind(pkt mmdvm.Packet) string {
+	switch pkt.FrameType {
+	case mmdvmFrameTypeVoice, mmdvmFrameTypeVoiceSync:
+		return "voice"
+	case mmdvmFrameTypeReverseChannel:
+		return "reverse_channel"
+	}
+	switch pkt.DTypeOrVSeq {
+	case 0:
+		return "pi_header"
+	case 1:
+		return "voice_header"
+	case 2:
+		return "voice_terminator"
+	case 3:
+		return "csbk"
+	default:
+		return "data"
+	}
+}
@@ -0,0 +1,92 @@
+package ipsc
+
+import (
+	"maps"
+	"testing"
+)
+
+// recordingSink is a MetricsSink that keeps what it is told.
+type recordingSink struct {
+	translated map[string]int // by direction/kind
+	active     map[string]int // by direction
+	dropped    map[string]int // by direction/reason
+}
+
+func newRecordingSink() *recordingSink {
+	return &recordingSink{translated: map[string]int{}, active: map[string]int{}, dropped: map[string]int{}}
+}
+
+func (s *recordingSink) IncTranslated(direction, kind string) {
+	s.translated[direction+"/"+kind]++
+}
+
+func (s *recordingSink) SetActiveStreams(direction string, n int) {
+	s.active[direction] = n
+}
+
+func (s *recordingSink) IncDropped(direction, reason string) {
+	s.dropped[direction+"/"+reason]++
+}
+
+func TestMetricsSinkToIPSC(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	sink := newRecordingSink()
+	tr.SetMetricsSink(sink)
+
+	tr.TranslateToIPSC(makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 1))
+	sendVoiceFrames(t, tr, 6)
+	if got := sink.active["mmdvm_to_ipsc"]; got != 1 {
+		t.Fatalf("expected 1 active stream during the call, got %d", got)
+	}
+	tr.TranslateToIPSC(makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 2))
+
+	want := map[string]int{
+		"mmdvm_to_ipsc/voice_header":     defaultHeaderRepeats,
+		"mmdvm_to_ipsc/voice":            6,
+		"mmdvm_to_ipsc/voice_terminator": 1,
+	}
+	if !maps.Equal(sink.translated, want) {
+		t.Fatalf("expected translated %v, got %v", want, sink.translated)
+	}
+	if got := sink.active["mmdvm_to_ipsc"]; got != 0 {
+		t.Fatalf("expected no active streams after the call, got %d", got)
+	}
+	if len(sink.dropped) != 0 {
+		t.Fatalf("expected no drops, got %v", sink.dropped)
+	}
+}
+
+func TestMetricsSinkToMMDVM(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	sink := newRecordingSink()
+	tr.SetMetricsSink(sink)
+
+	tr.TranslateToMMDVM(0x80, makeSequencedIPSCPacket(ipscBurstVoiceHead, 1))
+	for pos := range 6 {
+		tr.TranslateToMMDVM(0x80, makeIPSCVoiceBurst(pos, uint16(2+pos))) //nolint:gosec // G115: pos is in [0,5]
+	}
+	// A retransmission of the last burst.
+	tr.TranslateToMMDVM(0x80, makeIPSCVoiceBurst(5, 7))
+	if got := sink.active["ipsc_to_mmdvm"]; got != 1 {
+		t.Fatalf("expected 1 active stream during the call, got %d", got)
+	}
+	terminator := makeSequencedIPSCPacket(ipscBurstVoiceTerm, 8)
+	tr.TranslateToMMDVM(0x80, terminator)
+
+	want := map[string]int{
+		"ipsc_to_mmdvm/voice_header":     1,
+		"ipsc_to_mmdvm/voice":            6,
+		"ipsc_to_mmdvm/voice_terminator": 1,
+	}
+	if !maps.Equal(sink.translated, want) {
+		t.Fatalf("expected translated %v, got %v", want, sink.translated)
+	}
+	if got := sink.active["ipsc_to_mmdvm"]; got != 0 {
+		t.Fatalf("expected no active streams after the call, got %d", got)
+	}
+	if want := map[string]int{"ipsc_to_mmdvm/duplicate": 1}; !maps.Equal(sink.dropped, want) {
+		t.Fatalf("expected dropped %v, got %v", want, sink.dropped)
+	}
+}
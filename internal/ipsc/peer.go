@@ -0,0 +1,221 @@
+package ipsc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/jitter"
+)
+
+// defaultMasterKeepalive is how often a server in the peer role sends the
+// master a keepalive, or retries registering while it is not registered.
+const defaultMasterKeepalive = 5 * time.Second
+
+// masterKeepalivesMissed is how many keepalive intervals may pass without
+// hearing from the master before the server registers again.
+const masterKeepalivesMissed = 3
+
+// peerListEntrySize is the size of each peer in a peer list reply: its ID,
+// IPv4 address, port, and mode.
+const peerListEntrySize = 11
+
+// masterLink is the registration with the master of a server in the peer
+// role. Its fields are guarded by the server's mu.
+type masterLink struct {
+	addr       *net.UDPAddr
+	id         uint32
+	registered bool
+	lastHeard  time.Time
+}
+
+// masterLoop keeps the server registered with the master at addr until
+// stop is closed.
+func (s *IPSCServer) masterLoop(addr *net.UDPAddr, stop <-chan struct{}) {
+	defer s.wg.Done()
+	defer s.recoverPanic()
+	ticker := jitter.NewTicker(s.masterKeepalive, jitter.DefaultFraction, s.jitter)
+	defer ticker.Stop()
+	for {
+		s.contactMaster(addr)
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// contactMaster sends the master a keepalive, or a registration request
+// if the server is not registered or has not heard from it for too long.
+func (s *IPSCServer) contactMaster(addr *net.UDPAddr) {
+	s.mu.Lock()
+	link := s.master
+	if link.registered && s.now().Sub(link.lastHeard) > masterKeepalivesMissed*s.masterKeepalive {
+		s.log.Warn("Lost contact with IPSC master, registering again", "master", addr, "masterID", link.id)
+		link.registered = false
+	}
+	packetType := PacketType_MasterRegisterRequest
+	if link.registered {
+		packetType = PacketType_MasterAliveRequest
+	}
+	s.mu.Unlock()
+
+	if err := s.sendPacket(&Packet{data: s.buildMasterRequest(packetType)}, addr); err != nil {
+		s.log.Warn("failed contacting IPSC master", "master", addr, "error", err)
+	}
+}
+
+// leaveMaster de-registers from the master, if registered with one.
+func (s *IPSCServer) leaveMaster() {
+	if s.master == nil || s.conn() == nil {
+		return
+	}
+	s.mu.RLock()
+	registered := s.master.registered
+	s.mu.RUnlock()
+	if !registered {
+		return
+	}
+	packet := &Packet{data: append([]byte{byte(PacketType_DeRegisterRequest)}, s.localIDBytes()...)}
+	if err := s.sendPacket(packet, s.master.addr); err != nil {
+		s.log.Warn("failed de-registering from IPSC master", "master", s.master.addr, "error", err)
+	}
+}
+
+// masterLeft notes the de-registration of peerID from addr. In the peer
+// role, a master going away leaves the server to register again on its
+// next keepalive; de-registrations from anywhere but the master are
+// ignored.
+func (s *IPSCServer) masterLeft(peerID uint32, addr *net.UDPAddr) {
+	if s.master == nil || addr == nil || addr.String() != s.master.addr.String() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.master.registered && s.master.id == peerID {
+		s.master.registered = false
+		s.log.Warn("IPSC master de-registered, registering again", "master", s.master.addr, "masterID", peerID)
+	}
+}
+
+// handleMasterReply takes a reply from the master to a registration,
+// keepalive, or peer list request. Replies are ignored outside the peer
+// role and from anywhere but the master.
+func (s *IPSCServer) handleMasterReply(env packetEnvelope) ([][]byte, error) {
+	data, addr := env.Data, env.Addr
+	if s.master == nil || addr == nil || addr.String() != s.master.addr.String() {
+		return nil, ErrPacketIgnored
+	}
+	masterID, err := parsePeerID(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch env.Type {
+	case PacketType_MasterRegisterReply:
+		if len(data) < 10 {
+			return nil, fmt.Errorf("master register reply too short")
+		}
+		var flags [4]byte
+		copy(flags[:], data[6:10])
+		s.mu.Lock()
+		s.master.id = masterID
+		s.master.registered = true
+		s.master.lastHeard = s.now()
+		s.mu.Unlock()
+		// The master takes traffic like any other peer.
+		s.upsertPeer(masterID, addr, data[5], flags)
+		s.log.Info("Registered with IPSC master", "master", addr, "masterID", masterID)
+
+		return [][]byte{append([]byte{byte(PacketType_PeerListRequest)}, s.localIDBytes()...)}, nil
+	case PacketType_MasterAliveReply:
+		s.mu.Lock()
+		s.master.lastHeard = s.now()
+		s.mu.Unlock()
+		s.keepPeerAlive(masterID, addr, data)
+	case PacketType_PeerListReply:
+		s.joinPeers(data)
+	}
+	return nil, nil
+}
+
+// joinPeers asks each peer in a peer list reply from the master to
+// register the server, so they exchange traffic directly.
+func (s *IPSCServer) joinPeers(data []byte) {
+	if len(data) < 7 {
+		return
+	}
+	list := data[7:]
+	if n := int(binary.BigEndian.Uint16(data[5:7])); n < len(list) {
+		list = list[:n]
+	}
+	request := append([]byte{byte(PacketType_PeerRegisterRequest)}, s.localIDBytes()...)
+	request = append(request, ipscVersion...)
+	for ; len(list) >= peerListEntrySize; list = list[peerListEntrySize:] {
+		peerID := binary.BigEndian.Uint32(list[0:4])
+		s.mu.RLock()
+		known := peerID == s.localID || peerID == s.master.id
+		s.mu.RUnlock()
+		if known {
+			continue
+		}
+		addr := &net.UDPAddr{
+			IP:   net.IP(append([]byte(nil), list[4:8]...)),
+			Port: int(binary.BigEndian.Uint16(list[8:10])),
+		}
+		packet := &Packet{data: append([]byte(nil), request...)}
+		if err := s.sendPacket(packet, addr); err != nil {
+			s.log.Warn("failed sending IPSC peer registration", "peer", addr, "peerID", peerID, "error", err)
+		}
+	}
+}
+
+// handlePeerRegisterRequest registers a peer that wants to exchange
+// traffic directly, as peers of the same master do. Peer registrations
+// carry no mode or flags, so the defaults stand in for them, as for a
+// short registration with the master.
+func (s *IPSCServer) handlePeerRegisterRequest(env packetEnvelope) ([][]byte, error) {
+	if !s.upsertPeer(env.PeerID, env.Addr, s.defaultModeByte(), s.defaultFlagsBytes()) {
+		return nil, ErrPacketIgnored
+	}
+
+	reply := append([]byte{byte(PacketType_PeerRegisterReply)}, s.localIDBytes()...)
+	return [][]byte{append(reply, ipscVersion...)}, nil
+}
+
+// handlePeerRegisterReply records a peer that accepted the server's
+// registration.
+func (s *IPSCServer) handlePeerRegisterReply(env packetEnvelope) ([][]byte, error) {
+	if !s.upsertPeer(env.PeerID, env.Addr, s.defaultModeByte(), s.defaultFlagsBytes()) {
+		return nil, ErrPacketIgnored
+	}
+	s.log.Info("Registered with IPSC peer", "peer", env.Addr, "peerID", env.PeerID)
+	return nil, nil
+}
+
+// handlePeerAliveRequest answers a keepalive from a peer.
+func (s *IPSCServer) handlePeerAliveRequest(env packetEnvelope) ([][]byte, error) {
+	if !s.registeredSender(env) {
+		return nil, ErrPacketIgnored
+	}
+	s.keepPeerAlive(env.PeerID, env.Addr, env.Data)
+
+	reply := s.buildPeerAliveRequest()
+	reply[0] = byte(PacketType_PeerAliveReply)
+	return [][]byte{reply}, nil
+}
+
+// buildMasterRequest builds a registration or keepalive request to the
+// master, advertising the server's mode and flags.
+func (s *IPSCServer) buildMasterRequest(packetType PacketType) []byte {
+	packet := make([]byte, 0, 1+4+5+4)
+	packet = append(packet, byte(packetType))
+	packet = append(packet, s.localIDBytes()...)
+	packet = append(packet, s.defaultModeByte())
+	flags := s.defaultFlagsBytes()
+	packet = append(packet, flags[:]...)
+	packet = append(packet, ipscVersion...)
+	return packet
+}
@@ -0,0 +1,165 @@
+package ipsc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+)
+
+// startPeerRoleServer starts a server in the peer role, registering with
+// the master at masterAddr on loopback.
+func startPeerRoleServer(t *testing.T, masterAddr *net.UDPAddr) *IPSCServer {
+	t.Helper()
+	cfg := testConfig(false, "")
+	cfg.IPSC.IP = "127.0.0.1"
+	cfg.IPSC.Role = config.IPSCRolePeer
+	cfg.IPSC.MasterAddress = masterAddr.String()
+	s := NewIPSCServer(cfg, nil)
+	s.masterKeepalive = 50 * time.Millisecond
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(s.Stop)
+	return s
+}
+
+// readPacketType reads datagrams from conn until one of packetType
+// arrives, and returns it and where it came from.
+func readPacketType(t *testing.T, conn *net.UDPConn, packetType PacketType) ([]byte, *net.UDPAddr) {
+	t.Helper()
+	if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("SetReadDeadline: %v", err)
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			t.Fatalf("waiting for packet type 0x%02X: %v", packetType, err)
+		}
+		if PacketType(buf[0]) == packetType {
+			return append([]byte(nil), buf[:n]...), addr
+		}
+	}
+}
+
+func writeUDP(t *testing.T, conn *net.UDPConn, data []byte, addr *net.UDPAddr) {
+	t.Helper()
+	if _, err := conn.WriteToUDP(data, addr); err != nil {
+		t.Fatalf("WriteToUDP: %v", err)
+	}
+}
+
+func TestPeerRoleRegistersWithMaster(t *testing.T) {
+	t.Parallel()
+	const masterID = 7000
+	master, masterAddr := listenPeer(t)
+	other, otherAddr := listenPeer(t)
+	s := startPeerRoleServer(t, masterAddr)
+
+	// The server registers, advertising its mode and flags.
+	request, serverAddr := readPacketType(t, master, PacketType_MasterRegisterRequest)
+	if got := binary.BigEndian.Uint32(request[1:5]); got != s.localID {
+		t.Fatalf("expected peer ID %d, got %d", s.localID, got)
+	}
+	flags := s.defaultFlagsBytes()
+	if request[5] != s.defaultModeByte() || !bytes.Equal(request[6:10], flags[:]) {
+		t.Fatalf("expected mode and flags % X % X, got % X", s.defaultModeByte(), flags, request[5:10])
+	}
+
+	reply := makeControlPacketWithModeFlags(PacketType_MasterRegisterReply, masterID, 0x6A, [4]byte{0, 0, 0, 0x0D})
+	reply = append(reply, 0x00, 0x01)
+	reply = append(reply, ipscVersion...)
+	writeUDP(t, master, reply, serverAddr)
+
+	// Once registered, it asks for the peers and joins each one.
+	readPacketType(t, master, PacketType_PeerListRequest)
+	list := binary.BigEndian.AppendUint32(nil, 8000)
+	list = append(list, otherAddr.IP.To4()...)
+	list = binary.BigEndian.AppendUint16(list, uint16(otherAddr.Port)) //nolint:gosec // G115: a port
+	list = append(list, 0x6A)
+	peerList := makeControlPacket(PacketType_PeerListReply, masterID)
+	peerList = binary.BigEndian.AppendUint16(peerList, uint16(len(list))) //nolint:gosec // G115: one entry
+	writeUDP(t, master, append(peerList, list...), serverAddr)
+
+	join, _ := readPacketType(t, other, PacketType_PeerRegisterRequest)
+	if got := binary.BigEndian.Uint32(join[1:5]); got != s.localID {
+		t.Fatalf("expected peer ID %d in the peer registration, got %d", s.localID, got)
+	}
+	writeUDP(t, other, makeControlPacket(PacketType_PeerRegisterReply, 8000), serverAddr)
+
+	// Keepalives follow the registration.
+	readPacketType(t, master, PacketType_MasterAliveRequest)
+
+	// User traffic goes to the master and the joined peer.
+	deadline := time.Now().Add(2 * time.Second)
+	for s.peerCount() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	burst := makeTestIPSCPacket(0x80, ipscBurstVoiceHead, true, false)
+	s.SendUserPacket(burst)
+	for _, conn := range []*net.UDPConn{master, other} {
+		if got, _ := readPacketType(t, conn, PacketType_GroupVoice); !bytes.Equal(got, burst) {
+			t.Fatalf("expected the burst % X, got % X", burst, got)
+		}
+	}
+}
+
+func TestPeerRoleIgnoresRepliesFromOthers(t *testing.T) {
+	t.Parallel()
+	master, masterAddr := listenPeer(t)
+	s := startPeerRoleServer(t, masterAddr)
+	readPacketType(t, master, PacketType_MasterRegisterRequest)
+
+	impostor := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: masterAddr.Port + 1}
+	reply := makeControlPacketWithModeFlags(PacketType_MasterRegisterReply, 7000, 0x6A, [4]byte{})
+	if _, err := s.handlePacket(reply, impostor); err == nil {
+		t.Fatal("expected a register reply from elsewhere than the master to be ignored")
+	}
+	if s.peerCount() != 0 {
+		t.Fatalf("expected no peers, got %d", s.peerCount())
+	}
+}
+
+func TestPeerAliveRequestIsAnswered(t *testing.T) {
+	t.Parallel()
+	s, srvAddr := newTestServerWithUDP(t, false, "")
+	peer, peerAddr := listenPeer(t)
+	s.upsertPeer(8000, peerAddr, 0x6A, [4]byte{})
+
+	if _, err := s.handlePacket(makeControlPacket(PacketType_PeerAliveRequest, 8000), peerAddr); err != nil {
+		t.Fatalf("handlePacket error: %v", err)
+	}
+	reply, from := readPacketType(t, peer, PacketType_PeerAliveReply)
+	if from.Port != srvAddr.Port {
+		t.Fatalf("expected the reply from %v, got %v", srvAddr, from)
+	}
+	if got := binary.BigEndian.Uint32(reply[1:5]); got != s.localID {
+		t.Fatalf("expected local ID %d in the reply, got %d", s.localID, got)
+	}
+	if s.peerCount() != 1 {
+		t.Fatalf("expected the peer recorded, got %d peers", s.peerCount())
+	}
+}
+
+func TestPeerRoleRegistersAgainAfterMasterDeRegisters(t *testing.T) {
+	t.Parallel()
+	const masterID = 7000
+	master, masterAddr := listenPeer(t)
+	s := startPeerRoleServer(t, masterAddr)
+
+	_, serverAddr := readPacketType(t, master, PacketType_MasterRegisterRequest)
+	reply := makeControlPacketWithModeFlags(PacketType_MasterRegisterReply, masterID, 0x6A, [4]byte{})
+	writeUDP(t, master, reply, serverAddr)
+	readPacketType(t, master, PacketType_MasterAliveRequest)
+
+	writeUDP(t, master, makeControlPacket(PacketType_DeRegisterRequest, masterID), serverAddr)
+	readPacketType(t, master, PacketType_DeRegisterReply)
+	readPacketType(t, master, PacketType_MasterRegisterRequest)
+	if s.peerCount() != 0 {
+		t.Fatalf("expected the master removed, got %d peers", s.peerCount())
+	}
+}
@@ -0,0 +1,78 @@
+package ipsc
+
+import "net"
+
+// PeerEventType is what happened to a peer.
+type PeerEventType int
+
+const (
+	// PeerRegistered is a peer registering, or being heard from for the
+	// first time.
+	PeerRegistered PeerEventType = iota
+	// PeerKeepAlive is a keepalive, or a registration that changed
+	// nothing, from a known peer.
+	PeerKeepAlive
+	// PeerUpdated is a known peer registering again with a different
+	// address, mode, or flags, or turning up at a different address.
+	PeerUpdated
+	// PeerLost is a peer de-registering, timing out, or being replaced by
+	// a registration of its ID from another address.
+	PeerLost
+)
+
+func (t PeerEventType) String() string {
+	switch t {
+	case PeerRegistered:
+		return "registered"
+	case PeerKeepAlive:
+		return "keepalive"
+	case PeerUpdated:
+		return "updated"
+	case PeerLost:
+		return "lost"
+	default:
+		return "unknown"
+	}
+}
+
+// PeerEvent describes a change in a peer's lifecycle. For PeerLost, the
+// address, mode, and flags are the peer's last known ones.
+type PeerEvent struct {
+	Type   PeerEventType
+	PeerID uint32
+	Addr   *net.UDPAddr
+	Mode   byte
+	Flags  [4]byte
+}
+
+// SetPeerEventHandler registers fn to be called as peers register, send
+// keepalives, change, and go away. It is called without the server's
+// locks held, so it may call back into the server, but it holds up the
+// packet being handled and should return quickly. It must be called
+// before Start.
+func (s *IPSCServer) SetPeerEventHandler(fn func(event PeerEvent)) {
+	s.peerEventHandler = fn
+}
+
+// peerEvent builds the event of type t for peer. Must be called with s.mu
+// held.
+func peerEvent(t PeerEventType, peer *Peer) PeerEvent {
+	return PeerEvent{
+		Type:   t,
+		PeerID: peer.ID,
+		Addr:   cloneUDPAddr(peer.Addr),
+		Mode:   peer.Mode,
+		Flags:  peer.Flags,
+	}
+}
+
+// firePeerEvents passes events to the peer event handler. Must be called
+// without s.mu held.
+func (s *IPSCServer) firePeerEvents(events ...PeerEvent) {
+	if s.peerEventHandler == nil {
+		return
+	}
+	for _, event := range events {
+		s.peerEventHandler(event)
+	}
+}
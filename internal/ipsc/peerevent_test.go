@@ -0,0 +1,111 @@
+package ipsc
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordPeerEvents sets a peer event handler on s that records the events
+// it is passed. The handler calls back into the server, as a handler may.
+func recordPeerEvents(s *IPSCServer) func() []PeerEvent {
+	var mu sync.Mutex
+	var events []PeerEvent
+	s.SetPeerEventHandler(func(event PeerEvent) {
+		_ = s.Peers()
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+	return func() []PeerEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]PeerEvent(nil), events...)
+	}
+}
+
+func TestPeerEventsLifecycle(t *testing.T) {
+	t.Parallel()
+	clock := time.Unix(1_700_000_000, 0)
+	s, _ := newTestServerWithUDP(t, false, "")
+	s.now = func() time.Time { return clock }
+	events := recordPeerEvents(s)
+	_, addr := listenPeer(t)
+
+	flags := [4]byte{0, 0, 0, 0x0D}
+	for _, data := range [][]byte{
+		makeControlPacketWithModeFlags(PacketType_MasterRegisterRequest, 8000, 0x6A, flags),
+		makeControlPacket(PacketType_MasterAliveRequest, 8000),
+		makeControlPacket(PacketType_MasterAliveRequest, 8000),
+	} {
+		if _, err := s.handlePacket(data, addr); err != nil {
+			t.Fatalf("handlePacket: %v", err)
+		}
+	}
+	// Voice is not a keepalive.
+	voice := makeTestIPSCPacket(0x80, ipscBurstSlot1, true, false)
+	binary.BigEndian.PutUint32(voice[1:5], 8000)
+	if _, err := s.handlePacket(voice, addr); err != nil {
+		t.Fatalf("handlePacket: %v", err)
+	}
+	clock = clock.Add(time.Minute)
+	s.prunePeers(clock.Add(-time.Second))
+
+	want := []PeerEventType{PeerRegistered, PeerKeepAlive, PeerKeepAlive, PeerLost}
+	got := events()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), got)
+	}
+	for i, event := range got {
+		if event.Type != want[i] || event.PeerID != 8000 || event.Addr.String() != addr.String() ||
+			event.Mode != 0x6A || event.Flags != flags {
+			t.Fatalf("event %d: expected %v for peer 8000 at %v, got %+v", i, want[i], addr, event)
+		}
+	}
+}
+
+func TestPeerEventsUpdateAndDeRegister(t *testing.T) {
+	t.Parallel()
+	s, _ := newTestServerWithUDP(t, false, "")
+	events := recordPeerEvents(s)
+	_, addr := listenPeer(t)
+	_, moved := listenPeer(t)
+	voice := makeTestIPSCPacket(0x80, ipscBurstSlot1, true, false)
+	binary.BigEndian.PutUint32(voice[1:5], 8000)
+
+	steps := []struct {
+		data []byte
+		from *net.UDPAddr
+	}{
+		{makeControlPacketWithModeFlags(PacketType_MasterRegisterRequest, 8000, 0x6A, [4]byte{0, 0, 0, 0x0D}), addr},
+		{makeControlPacketWithModeFlags(PacketType_MasterRegisterRequest, 8000, 0x6A, [4]byte{0, 0, 0, 0x0D}), addr},
+		{makeControlPacketWithModeFlags(PacketType_MasterRegisterRequest, 8000, 0x6A, [4]byte{0, 0, 0, 0x09}), addr},
+		{voice, moved},
+		{makeControlPacket(PacketType_DeRegisterRequest, 8000), moved},
+	}
+	for _, step := range steps {
+		if _, err := s.handlePacket(step.data, step.from); err != nil {
+			t.Fatalf("handlePacket: %v", err)
+		}
+	}
+
+	want := []PeerEvent{
+		{Type: PeerRegistered, PeerID: 8000, Addr: addr, Mode: 0x6A, Flags: [4]byte{0, 0, 0, 0x0D}},
+		{Type: PeerKeepAlive, PeerID: 8000, Addr: addr, Mode: 0x6A, Flags: [4]byte{0, 0, 0, 0x0D}},
+		{Type: PeerUpdated, PeerID: 8000, Addr: addr, Mode: 0x6A, Flags: [4]byte{0, 0, 0, 0x09}},
+		{Type: PeerUpdated, PeerID: 8000, Addr: moved, Mode: 0x6A, Flags: [4]byte{0, 0, 0, 0x09}},
+		{Type: PeerLost, PeerID: 8000, Addr: moved, Mode: 0x6A, Flags: [4]byte{0, 0, 0, 0x09}},
+	}
+	got := events()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i].Type != want[i].Type || got[i].PeerID != want[i].PeerID || got[i].Addr.String() != want[i].Addr.String() ||
+			got[i].Mode != want[i].Mode || got[i].Flags != want[i].Flags {
+			t.Fatalf("event %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package ipsc
+
+import (
+	"encoding/binary"
+	"maps"
+	"net"
+	"time"
+)
+
+// TrafficCount counts packets of one type and their bytes on the wire,
+// authentication digests included.
+type TrafficCount struct {
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+// PeerStats counts the traffic exchanged with one peer since it was first
+// heard from.
+type PeerStats struct {
+	// KeepAliveReceived counts the packets that kept the peer alive:
+	// keepalives, wake-ups, and voice and data.
+	KeepAliveReceived uint64 `json:"keepAliveReceived"`
+	// KeepAliveSent counts the keepalive requests and replies sent to it.
+	KeepAliveSent uint64 `json:"keepAliveSent"`
+	// AuthFailures counts the packets with its ID that failed
+	// authentication.
+	AuthFailures uint64 `json:"authFailures"`
+	// LastVoice is when a voice packet last went to or came from it.
+	LastVoice time.Time                   `json:"lastVoice"`
+	In        map[PacketType]TrafficCount `json:"in"`
+	Out       map[PacketType]TrafficCount `json:"out"`
+}
+
+// PeerSnapshot is a copy of a peer's state that is safe to keep and
+// serialize.
+type PeerSnapshot struct {
+	ID          uint32    `json:"id"`
+	Addr        string    `json:"addr"`
+	Mode        byte      `json:"mode"`
+	Flags       [4]byte   `json:"flags"`
+	LastSeen    time.Time `json:"lastSeen"`
+	Registered  bool      `json:"registered"`
+	Provisional bool      `json:"provisional"`
+	Stats       PeerStats `json:"stats"`
+}
+
+// clone returns a copy of st that shares nothing with it.
+func (st PeerStats) clone() PeerStats {
+	st.In = maps.Clone(st.In)
+	st.Out = maps.Clone(st.Out)
+	return st
+}
+
+// count adds a packet of size bytes to counts.
+func count(counts *map[PacketType]TrafficCount, packetType PacketType, size int) {
+	if *counts == nil {
+		*counts = make(map[PacketType]TrafficCount)
+	}
+	c := (*counts)[packetType]
+	c.Packets++
+	c.Bytes += uint64(size) //nolint:gosec // G115: a packet length is never negative
+	(*counts)[packetType] = c
+}
+
+func isKeepAlive(packetType PacketType) bool {
+	switch packetType {
+	case PacketType_MasterAliveRequest, PacketType_MasterAliveReply,
+		PacketType_PeerAliveRequest, PacketType_PeerAliveReply:
+		return true
+	}
+	return false
+}
+
+func isVoice(packetType PacketType) bool {
+	return packetType == PacketType_GroupVoice || packetType == PacketType_PrivateVoice
+}
+
+// countReceived counts data, size bytes on the wire, against the known
+// peer whose ID it carries.
+func (s *IPSCServer) countReceived(data []byte, size int) {
+	if len(data) < 5 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	peer, ok := s.peers[binary.BigEndian.Uint32(data[1:5])]
+	if !ok {
+		return
+	}
+	packetType := PacketType(data[0])
+	count(&peer.In, packetType, size)
+	if isVoice(packetType) {
+		peer.LastVoice = s.now()
+	}
+}
+
+// countSent counts data, as sent on the wire, against the known peer at
+// addr.
+func (s *IPSCServer) countSent(data []byte, addr *net.UDPAddr) {
+	if len(data) == 0 || addr == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, peer := range s.peers {
+		if peer.Addr == nil || !peer.Addr.IP.Equal(addr.IP) || peer.Addr.Port != addr.Port {
+			continue
+		}
+		packetType := PacketType(data[0])
+		count(&peer.Out, packetType, len(data))
+		if isKeepAlive(packetType) {
+			peer.KeepAliveSent++
+		}
+		if isVoice(packetType) {
+			peer.LastVoice = s.now()
+		}
+		return
+	}
+}
+
+// countAuthFailure counts a packet with peerID that failed authentication
+// against that peer, if it is known.
+func (s *IPSCServer) countAuthFailure(peerID uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if peer, ok := s.peers[peerID]; ok {
+		peer.AuthFailures++
+	}
+}
+
+// PeersSnapshot returns a copy of the state and traffic statistics of the
+// known peers.
+func (s *IPSCServer) PeersSnapshot() []PeerSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := make([]PeerSnapshot, 0, len(s.peers))
+	for _, peer := range s.peers {
+		var addr string
+		if peer.Addr != nil {
+			addr = peer.Addr.String()
+		}
+		snapshot = append(snapshot, PeerSnapshot{
+			ID:          peer.ID,
+			Addr:        addr,
+			Mode:        peer.Mode,
+			Flags:       peer.Flags,
+			LastSeen:    peer.LastSeen,
+			Registered:  peer.RegistrationStatus,
+			Provisional: peer.Provisional,
+			Stats:       peer.PeerStats.clone(),
+		})
+	}
+	return snapshot
+}
@@ -0,0 +1,96 @@
+package ipsc
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPeerStatsAfterExchange(t *testing.T) {
+	t.Parallel()
+	clock := time.Unix(1_700_000_000, 0)
+	s, _ := newTestServerWithUDP(t, true, "1234")
+	s.now = func() time.Time { return clock }
+	conn, addr := listenPeer(t)
+	const key = "0000000000000000000000000000000000001234"
+
+	register := signPacket(t, makeControlPacketWithModeFlags(PacketType_MasterRegisterRequest, 8000, 0x6A, [4]byte{0, 0, 0, 0x0D}), key)
+	if _, err := s.handlePacket(register, addr); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	registerReply, _ := readPacketType(t, conn, PacketType_MasterRegisterReply)
+
+	alive := signPacket(t, makeControlPacket(PacketType_MasterAliveRequest, 8000), key)
+	if _, err := s.handlePacket(alive, addr); err != nil {
+		t.Fatalf("keepalive: %v", err)
+	}
+	aliveReply, _ := readPacketType(t, conn, PacketType_MasterAliveReply)
+
+	voice := makeTestIPSCPacket(0x80, ipscBurstSlot1, true, false)
+	binary.BigEndian.PutUint32(voice[1:5], 8000)
+	voice = signPacket(t, voice, key)
+	if _, err := s.handlePacket(voice, addr); err != nil {
+		t.Fatalf("voice: %v", err)
+	}
+	if _, err := s.handlePacket(badlySigned(8000), addr); err == nil {
+		t.Fatal("expected the badly signed packet refused")
+	}
+
+	clock = clock.Add(time.Second)
+	s.SendUserPacket(makeTestIPSCPacket(0x80, ipscBurstSlot1, true, false))
+	sentVoice, _ := readPacketType(t, conn, PacketType_GroupVoice)
+
+	snapshot := s.PeersSnapshot()
+	if len(snapshot) != 1 {
+		t.Fatalf("expected 1 peer, got %d", len(snapshot))
+	}
+	stats := snapshot[0].Stats
+	wantIn := map[PacketType]TrafficCount{
+		PacketType_MasterRegisterRequest: {Packets: 1, Bytes: uint64(len(register))},
+		PacketType_MasterAliveRequest:    {Packets: 1, Bytes: uint64(len(alive))},
+		PacketType_GroupVoice:            {Packets: 1, Bytes: uint64(len(voice))},
+	}
+	wantOut := map[PacketType]TrafficCount{
+		PacketType_MasterRegisterReply: {Packets: 1, Bytes: uint64(len(registerReply))},
+		PacketType_MasterAliveReply:    {Packets: 1, Bytes: uint64(len(aliveReply))},
+		PacketType_GroupVoice:          {Packets: 1, Bytes: uint64(len(sentVoice))},
+	}
+	for name, tc := range map[string]struct{ got, want map[PacketType]TrafficCount }{
+		"in":  {stats.In, wantIn},
+		"out": {stats.Out, wantOut},
+	} {
+		if len(tc.got) != len(tc.want) {
+			t.Fatalf("%s: expected %v, got %v", name, tc.want, tc.got)
+		}
+		for packetType, want := range tc.want {
+			if got := tc.got[packetType]; got != want {
+				t.Fatalf("%s 0x%02X: expected %+v, got %+v", name, byte(packetType), want, got)
+			}
+		}
+	}
+	if stats.KeepAliveReceived != 2 {
+		t.Fatalf("expected 2 keepalives received, got %d", stats.KeepAliveReceived)
+	}
+	if stats.KeepAliveSent != 1 {
+		t.Fatalf("expected 1 keepalive sent, got %d", stats.KeepAliveSent)
+	}
+	if stats.AuthFailures != 1 {
+		t.Fatalf("expected 1 authentication failure, got %d", stats.AuthFailures)
+	}
+	if !stats.LastVoice.Equal(clock) {
+		t.Fatalf("expected the last voice at %v, got %v", clock, stats.LastVoice)
+	}
+	if snapshot[0].Addr != addr.String() || !snapshot[0].Registered {
+		t.Fatalf("expected peer registered at %v, got %+v", addr, snapshot[0])
+	}
+
+	// The snapshot is a copy and serializes.
+	stats.In[PacketType_GroupVoice] = TrafficCount{}
+	if got := s.PeersSnapshot()[0].Stats.In[PacketType_GroupVoice]; got != wantIn[PacketType_GroupVoice] {
+		t.Fatalf("expected the server's counters untouched, got %+v", got)
+	}
+	if _, err := json.Marshal(snapshot); err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+}
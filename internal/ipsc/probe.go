@@ -0,0 +1,101 @@
+package ipsc
+
+import (
+	"net"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/jitter"
+)
+
+// heardFrom records that the peer is evidently alive, which answers any
+// probe outstanding. Must be called with the server's mu held.
+func (p *Peer) heardFrom() {
+	p.ProbesMissed = 0
+	p.probePending = false
+}
+
+func (s *IPSCServer) probeLoop(interval time.Duration, stop <-chan struct{}) {
+	defer s.wg.Done()
+	defer s.recoverPanic()
+	ticker := jitter.NewTicker(interval, jitter.DefaultFraction, s.jitter)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.probePeers()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// maxProbeMisses returns how many probes in a row a peer may leave
+// unanswered before it is dropped: as many as span the peer timeout.
+// Zero never drops a peer for them.
+func (s *IPSCServer) maxProbeMisses() int {
+	timeout, interval := s.cfg.IPSC.PeerTimeout, s.cfg.IPSC.ProbeInterval
+	if timeout == 0 || interval == 0 {
+		return 0
+	}
+	return int((timeout + interval - 1) / interval) //nolint:gosec // Small config values
+}
+
+// probePeers sends each registered peer an alive request. A peer whose
+// previous probe is still unanswered has missed it, and one that has
+// missed too many in a row is dropped, as a silent peer is. It returns
+// the IDs of the peers dropped.
+func (s *IPSCServer) probePeers() []uint32 {
+	maxMisses := s.maxProbeMisses()
+	s.mu.Lock()
+	for _, peer := range s.peers {
+		if peer.probePending {
+			peer.ProbesMissed++
+		}
+	}
+	s.mu.Unlock()
+
+	dropped := s.dropPeers("IPSC peer stopped answering alive probes", func(peer *Peer) bool {
+		return maxMisses > 0 && peer.ProbesMissed >= maxMisses
+	})
+
+	s.mu.Lock()
+	addrs := make([]*net.UDPAddr, 0, len(s.peers))
+	for _, peer := range s.peers {
+		if peer.Addr == nil {
+			continue
+		}
+		peer.probePending = true
+		addrs = append(addrs, cloneUDPAddr(peer.Addr))
+	}
+	s.mu.Unlock()
+
+	for _, addr := range addrs {
+		if err := s.sendPacket(&Packet{data: s.buildPeerAliveRequest()}, addr); err != nil {
+			s.log.Warn("failed sending IPSC alive probe", "peer", addr, "error", err)
+		}
+	}
+	return dropped
+}
+
+// handlePeerAliveReply takes a peer's answer to an alive probe.
+func (s *IPSCServer) handlePeerAliveReply(env packetEnvelope) ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	peer, ok := s.peers[env.PeerID]
+	if !ok {
+		return nil, ErrPacketIgnored
+	}
+	peer.LastSeen = s.now()
+	peer.heardFrom()
+	return nil, nil
+}
+
+func (s *IPSCServer) buildPeerAliveRequest() []byte {
+	packet := make([]byte, 0, 1+4+5)
+	packet = append(packet, byte(PacketType_PeerAliveRequest))
+	packet = append(packet, s.localIDBytes()...)
+	packet = append(packet, s.defaultModeByte())
+	flags := s.defaultFlagsBytes()
+	packet = append(packet, flags[:]...)
+	return packet
+}
@@ -0,0 +1,119 @@
+package ipsc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+// listenPeer returns a loopback socket standing in for a peer.
+func listenPeer(t *testing.T) (*net.UDPConn, *net.UDPAddr) {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+	if err != nil {
+		t.Fatalf("peer listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	addr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		t.Fatal("expected *net.UDPAddr from LocalAddr")
+	}
+	return conn, addr
+}
+
+func TestPeerAliveProbeWireFormat(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		auth bool
+	}{
+		{"plain", false},
+		{"authenticated", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			const hexKey = "0000000000000000000000000000000000001234"
+			s, _ := newTestServerWithUDP(t, tt.auth, "1234")
+			peer, peerAddr := listenPeer(t)
+			s.upsertPeer(55555, peerAddr, 0x6A, [4]byte{})
+
+			s.probePeers()
+			got := readUDP(t, peer)
+
+			want := []byte{byte(PacketType_PeerAliveRequest)}
+			want = binary.BigEndian.AppendUint32(want, s.localID)
+			want = append(want, s.defaultModeByte())
+			flags := s.defaultFlagsBytes()
+			want = append(want, flags[:]...)
+			if tt.auth {
+				want = signPacket(t, want, hexKey)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("expected probe % X, got % X", want, got)
+			}
+		})
+	}
+}
+
+func TestPeerAliveProbeMisses(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig(false, "")
+	cfg.IPSC.PeerTimeout = 30
+	cfg.IPSC.ProbeInterval = 10
+	s, _ := newTestServerWithConfig(t, cfg)
+	lost := recordPeerLost(s)
+	peer, peerAddr := listenPeer(t)
+	const peerID = 55555
+	s.upsertPeer(peerID, peerAddr, 0x6A, [4]byte{})
+
+	missed := func() int {
+		t.Helper()
+		for _, p := range s.Peers() {
+			if p.ID == peerID {
+				return p.ProbesMissed
+			}
+		}
+		t.Fatal("peer is gone")
+		return 0
+	}
+
+	// The first probe has nothing before it to miss.
+	s.probePeers()
+	readUDP(t, peer)
+	if got := missed(); got != 0 {
+		t.Fatalf("expected no misses, got %d", got)
+	}
+	s.probePeers()
+	readUDP(t, peer)
+	if got := missed(); got != 1 {
+		t.Fatalf("expected 1 miss, got %d", got)
+	}
+
+	// An answer clears the count.
+	if _, err := s.handlePacket(makeControlPacket(PacketType_PeerAliveReply, peerID), peerAddr); err != nil {
+		t.Fatalf("handlePacket error: %v", err)
+	}
+	if got := missed(); got != 0 {
+		t.Fatalf("expected the reply to clear misses, got %d", got)
+	}
+
+	// Three probes span the 30 second timeout; the peer is dropped on
+	// missing the third.
+	for range 3 {
+		if dropped := s.probePeers(); len(dropped) != 0 {
+			t.Fatalf("expected the peer kept, dropped %v", dropped)
+		}
+		readUDP(t, peer)
+	}
+	if dropped := s.probePeers(); len(dropped) != 1 || dropped[0] != peerID {
+		t.Fatalf("expected peer %d dropped, got %v", peerID, dropped)
+	}
+	if got := lost(); len(got) != 1 || got[0] != peerID {
+		t.Fatalf("expected peer %d reported lost, got %v", peerID, got)
+	}
+	if s.peerCount() != 0 {
+		t.Fatalf("expected no peers left, got %d", s.peerCount())
+	}
+}
@@ -0,0 +1,74 @@
+package ipsc
+
+import (
+	"encoding/binary"
+	"net"
+
+	"github.com/USA-RedDragon/dmrgo/dmr/layer2/elements"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/csbk"
+)
+
+// RadioCheck returns the radio check request carried by an IPSC user
+// packet, if it carries one.
+func RadioCheck(packetType byte, data []byte) (csbk.ExtFunction, bool) {
+	f, ok := extFunction(packetType, data)
+	if !ok || !f.IsRadioCheck() {
+		return csbk.ExtFunction{}, false
+	}
+	return f, true
+}
+
+// RadioCheckAck returns the radio check acknowledgment carried by an IPSC
+// user packet, if it carries one.
+func RadioCheckAck(packetType byte, data []byte) (csbk.ExtFunction, bool) {
+	f, ok := extFunction(packetType, data)
+	if !ok || !f.Response || f.Function != csbk.FunctionRadioCheck {
+		return csbk.ExtFunction{}, false
+	}
+	return f, true
+}
+
+// extFunction decodes the extended function CSBK of a private data
+// packet.
+func extFunction(packetType byte, data []byte) (csbk.ExtFunction, bool) {
+	if PacketType(packetType) != PacketType_PrivateData || len(data) < 50 || data[30] != ipscBurstCSBK {
+		return csbk.ExtFunction{}, false
+	}
+	var block csbk.Block
+	copy(block[:], data[38:50])
+	f, err := csbk.ParseExtFunction(block)
+	if err != nil {
+		return csbk.ExtFunction{}, false
+	}
+	return f, true
+}
+
+// answerRadioCheck acknowledges a radio check aimed at one of the local
+// IDs back toward the requester and reports whether it did. Anything
+// else is left to be forwarded.
+func (s *IPSCServer) answerRadioCheck(packetType PacketType, data []byte, addr *net.UDPAddr) bool {
+	check, ok := RadioCheck(byte(packetType), data)
+	if !ok {
+		return false
+	}
+	if _, local := s.radioCheckIDs[check.Dst]; !local {
+		return false
+	}
+
+	peerID := binary.BigEndian.Uint32(data[1:5])
+	slot := data[17]&0x20 != 0
+	ack := check.Ack()
+	s.log.Info("Answering radio check", "radio", check.Src, "target", check.Dst, "peer", addr)
+	call := []DataBlock{{Type: elements.DataTypeCSBK, Payload: ack.Encode()}}
+	for _, packet := range s.localTranslator.BuildDataCall(uint(ack.Src), uint(ack.Dst), false, slot, call) {
+		s.pacePeer(peerID)
+		if err := s.sendPacket(&Packet{data: packet}, addr); err != nil {
+			s.log.Warn("failed sending radio check acknowledgment", "peer", addr, "error", err)
+			return true
+		}
+	}
+	if s.metrics != nil {
+		s.metrics.IPSCRadioChecksAnswered.Inc()
+	}
+	return true
+}
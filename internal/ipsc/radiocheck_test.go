@@ -0,0 +1,164 @@
+package ipsc
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/csbk"
+)
+
+const (
+	testRadioCheckLocalID = 311000
+	testRadioCheckRadio   = 3120101
+)
+
+// radioCheckBurst returns a private data packet carrying f as a repeater
+// forwards it.
+func radioCheckBurst(f csbk.ExtFunction, slot bool) []byte {
+	data := make([]byte, 54)
+	data[0] = byte(PacketType_PrivateData)
+	binary.BigEndian.PutUint32(data[1:5], testARSPeerID)
+	data[6], data[7], data[8] = byte(f.Src>>16), byte(f.Src>>8), byte(f.Src)
+	data[9], data[10], data[11] = byte(f.Dst>>16), byte(f.Dst>>8), byte(f.Dst)
+	data[12] = 0x01
+	binary.BigEndian.PutUint32(data[13:17], 0x5678)
+	data[17] = 0x40 // a CSBK is a call of its own
+	if slot {
+		data[17] |= 0x20
+	}
+	data[18] = 0x80
+	data[30] = ipscBurstCSBK
+	block := f.Encode()
+	copy(data[38:50], block[:])
+	return data
+}
+
+func radioCheckTo(dst uint32) csbk.ExtFunction {
+	return csbk.ExtFunction{FID: 0x10, Function: csbk.FunctionRadioCheck, Dst: dst, Src: testRadioCheckRadio}
+}
+
+func TestRadioCheckAnsweredLocally(t *testing.T) {
+	t.Parallel()
+	for _, slot := range []bool{false, true} {
+		cfg := testConfig(false, "")
+		cfg.IPSC.RadioCheck.LocalIDs = []uint32{testRadioCheckLocalID}
+		s, srvAddr := newTestServerWithConfig(t, cfg)
+		s.localTranslator.SetPeerID(s.localID)
+		s.SetBurstHandler(func(byte, []byte, *net.UDPAddr) {
+			t.Error("expected the radio check not to be forwarded")
+		})
+
+		repeater, err := net.DialUDP("udp", nil, srvAddr)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer repeater.Close()
+		repeaterAddr, ok := repeater.LocalAddr().(*net.UDPAddr)
+		if !ok {
+			t.Fatal("expected *net.UDPAddr from LocalAddr")
+		}
+
+		check := radioCheckTo(testRadioCheckLocalID)
+		if _, err := s.handlePacket(radioCheckBurst(check, slot), repeaterAddr); err != nil {
+			t.Fatalf("handlePacket: %v", err)
+		}
+
+		reply := readUDP(t, repeater)
+		if got := binary.BigEndian.Uint32(reply[1:5]); got != s.localID {
+			t.Fatalf("expected our peer ID %d, got %d", s.localID, got)
+		}
+		ack, ok := RadioCheckAck(reply[0], reply)
+		if !ok {
+			t.Fatalf("expected a radio check acknowledgment, got type 0x%02X burst 0x%02X", reply[0], reply[30])
+		}
+		if ack != check.Ack() {
+			t.Fatalf("expected %+v, got %+v", check.Ack(), ack)
+		}
+		src := uint32(reply[6])<<16 | uint32(reply[7])<<8 | uint32(reply[8])
+		dst := uint32(reply[9])<<16 | uint32(reply[10])<<8 | uint32(reply[11])
+		if src != testRadioCheckLocalID || dst != testRadioCheckRadio {
+			t.Fatalf("expected %d → %d, got %d → %d", testRadioCheckLocalID, testRadioCheckRadio, src, dst)
+		}
+		if got := reply[17]&0x20 != 0; got != slot {
+			t.Fatalf("expected the acknowledgment on TS2=%t", slot)
+		}
+	}
+}
+
+func TestRadioCheckToOtherIDForwarded(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig(false, "")
+	cfg.IPSC.RadioCheck.LocalIDs = []uint32{testRadioCheckLocalID}
+	s := NewIPSCServer(cfg, nil)
+	forwarded := make(chan []byte, 1)
+	s.SetBurstHandler(func(_ byte, data []byte, _ *net.UDPAddr) { forwarded <- data })
+
+	check := radioCheckTo(testRadioCheckLocalID + 1)
+	if _, err := s.handlePacket(radioCheckBurst(check, false), &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 50000}); err != nil {
+		t.Fatalf("handlePacket: %v", err)
+	}
+	select {
+	case data := <-forwarded:
+		if got, ok := RadioCheck(data[0], data); !ok || got != check {
+			t.Fatalf("expected the radio check forwarded unchanged, got %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a radio check to another ID to be forwarded")
+	}
+}
+
+func TestRadioCheckRoundTripThroughTranslator(t *testing.T) {
+	t.Parallel()
+	// The request crosses from this site to the master and arrives at the
+	// far site, whose answer takes the same path back.
+	near, err := NewIPSCTranslator()
+	if err != nil {
+		t.Fatalf("NewIPSCTranslator: %v", err)
+	}
+	near.SetPeerID(311001)
+	far, err := NewIPSCTranslator()
+	if err != nil {
+		t.Fatalf("NewIPSCTranslator: %v", err)
+	}
+	far.SetPeerID(311002)
+
+	check := radioCheckTo(3120102)
+	crossed := crossTranslators(t, near, far, radioCheckBurst(check, true))
+	got, ok := RadioCheck(crossed[0], crossed)
+	if !ok || got != check {
+		t.Fatalf("expected %+v at the far site, got %+v", check, got)
+	}
+	if crossed[17]&0x20 == 0 {
+		t.Fatal("expected the request to stay on TS2")
+	}
+
+	back := crossTranslators(t, far, near, radioCheckBurst(check.Ack(), true))
+	ack, ok := RadioCheckAck(back[0], back)
+	if !ok || ack != check.Ack() {
+		t.Fatalf("expected %+v back at this site, got %+v", check.Ack(), ack)
+	}
+	dst := uint32(back[9])<<16 | uint32(back[10])<<8 | uint32(back[11])
+	if dst != testRadioCheckRadio {
+		t.Fatalf("expected the acknowledgment addressed to %d, got %d", testRadioCheckRadio, dst)
+	}
+}
+
+// crossTranslators translates an IPSC packet to MMDVM with from and back
+// to IPSC with to, and returns the one resulting packet.
+func crossTranslators(t *testing.T, from, to *IPSCTranslator, data []byte) []byte {
+	t.Helper()
+	pkts := from.TranslateToMMDVM(data[0], data)
+	if len(pkts) != 1 {
+		t.Fatalf("expected 1 MMDVM packet, got %d", len(pkts))
+	}
+	if pkts[0].GroupCall {
+		t.Fatal("expected a private call")
+	}
+	out := to.TranslateToIPSC(pkts[0])
+	if len(out) != 1 {
+		t.Fatalf("expected 1 IPSC packet, got %d", len(out))
+	}
+	return out[0]
+}
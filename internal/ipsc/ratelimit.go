@@ -0,0 +1,74 @@
+package ipsc
+
+import (
+	"encoding/binary"
+	"net"
+	"time"
+)
+
+// tokenBucket is the rate limit state of one source address. Its fields
+// are guarded by the server's mu.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimited reports whether a packet from addr goes over the rate limit
+// and must be dropped, taking a token from its source's bucket if not.
+// Voice and data from the address of a registered peer are never limited.
+func (s *IPSCServer) rateLimited(data []byte, addr *net.UDPAddr) bool {
+	limit := s.cfg.IPSC.RateLimit
+	if limit.Rate == 0 || addr == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fromRegisteredPeer(data, addr) {
+		return false
+	}
+
+	key := addr.IP.String()
+	now := s.now()
+	burst := float64(max(limit.Burst, 1))
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst, last: now}
+		s.buckets[key] = bucket
+	}
+	bucket.tokens = min(burst, bucket.tokens+now.Sub(bucket.last).Seconds()*float64(limit.Rate))
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return true
+	}
+	bucket.tokens--
+	return false
+}
+
+// fromRegisteredPeer reports whether data is a user packet from a
+// registered peer at addr. Must be called with s.mu held.
+func (s *IPSCServer) fromRegisteredPeer(data []byte, addr *net.UDPAddr) bool {
+	if len(data) < 5 || classifyUserPacket(data) == classOther {
+		return false
+	}
+	peer, ok := s.peers[binary.BigEndian.Uint32(data[1:5])]
+	return ok && peer.RegistrationStatus && peer.Addr != nil && peer.Addr.String() == addr.String()
+}
+
+// forgetFullBuckets drops the buckets that have refilled, so sources that
+// stop sending are not remembered forever.
+func (s *IPSCServer) forgetFullBuckets() {
+	limit := s.cfg.IPSC.RateLimit
+	if limit.Rate == 0 {
+		return
+	}
+	burst := float64(max(limit.Burst, 1))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for key, bucket := range s.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*float64(limit.Rate) >= burst {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package ipsc
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newRateLimitTestServer returns a server accepting 10 packets a second
+// from each source, in bursts of up to 5, with its clock at *clock.
+func newRateLimitTestServer(t *testing.T, clock *time.Time) *IPSCServer {
+	t.Helper()
+	cfg := testConfig(false, "")
+	cfg.IPSC.RateLimit.Rate = 10
+	cfg.IPSC.RateLimit.Burst = 5
+	s, _ := newTestServerWithConfig(t, cfg)
+	s.now = func() time.Time { return *clock }
+	return s
+}
+
+func TestRateLimitDropsRegistrationFlood(t *testing.T) {
+	t.Parallel()
+	clock := time.Unix(1_700_000_000, 0)
+	s := newRateLimitTestServer(t, &clock)
+	_, addr := listenPeer(t)
+
+	register := makeControlPacket(PacketType_MasterRegisterRequest, 8000)
+	for i := range 5 {
+		if _, err := s.handlePacket(register, addr); err != nil {
+			t.Fatalf("expected registration %d within the burst accepted, got %v", i+1, err)
+		}
+	}
+	for range 20 {
+		if _, err := s.handlePacket(register, addr); !errors.Is(err, ErrPacketIgnored) {
+			t.Fatalf("expected the flood dropped, got %v", err)
+		}
+	}
+
+	// Tokens come back at the configured rate.
+	clock = clock.Add(100 * time.Millisecond)
+	if _, err := s.handlePacket(register, addr); err != nil {
+		t.Fatalf("expected a registration accepted once a token is back, got %v", err)
+	}
+	if _, err := s.handlePacket(register, addr); !errors.Is(err, ErrPacketIgnored) {
+		t.Fatalf("expected the next registration dropped, got %v", err)
+	}
+}
+
+func TestRateLimitExemptsRegisteredPeerVoice(t *testing.T) {
+	t.Parallel()
+	clock := time.Unix(1_700_000_000, 0)
+	s := newRateLimitTestServer(t, &clock)
+	_, addr := listenPeer(t)
+
+	if _, err := s.handlePacket(makeControlPacket(PacketType_MasterRegisterRequest, 8000), addr); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	voice := makeTestIPSCPacket(0x80, ipscBurstSlot1, true, false)
+	binary.BigEndian.PutUint32(voice[1:5], 8000)
+	for i := range 50 {
+		if _, err := s.handlePacket(voice, addr); err != nil {
+			t.Fatalf("expected voice packet %d from the registered peer accepted, got %v", i+1, err)
+		}
+	}
+
+	// The peer's control traffic is still limited.
+	register := makeControlPacket(PacketType_MasterRegisterRequest, 8000)
+	for range 4 {
+		if _, err := s.handlePacket(register, addr); err != nil {
+			t.Fatalf("expected registration within the burst accepted, got %v", err)
+		}
+	}
+	if _, err := s.handlePacket(register, addr); !errors.Is(err, ErrPacketIgnored) {
+		t.Fatalf("expected the registration over the limit dropped, got %v", err)
+	}
+
+	// Voice from a peer that never registered is not exempt.
+	_, other := listenPeer(t)
+	other.IP = []byte{127, 0, 0, 2}
+	unregistered := append([]byte(nil), voice...)
+	binary.BigEndian.PutUint32(unregistered[1:5], 9000)
+	for range 5 {
+		_, _ = s.handlePacket(unregistered, other)
+	}
+	if _, err := s.handlePacket(unregistered, other); !errors.Is(err, ErrPacketIgnored) {
+		t.Fatalf("expected voice from an unregistered peer limited, got %v", err)
+	}
+}
+
+func TestForgetFullBuckets(t *testing.T) {
+	t.Parallel()
+	clock := time.Unix(1_700_000_000, 0)
+	s := newRateLimitTestServer(t, &clock)
+	_, addr := listenPeer(t)
+
+	s.handlePacket(makeControlPacket(PacketType_MasterAliveRequest, 8000), addr) //nolint:errcheck // only the bucket matters
+	s.forgetFullBuckets()
+	if got := len(s.buckets); got != 1 {
+		t.Fatalf("expected the partly used bucket kept, got %d", got)
+	}
+	clock = clock.Add(time.Second)
+	s.forgetFullBuckets()
+	if got := len(s.buckets); got != 0 {
+		t.Fatalf("expected the refilled bucket forgotten, got %d", got)
+	}
+}
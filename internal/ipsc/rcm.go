@@ -0,0 +1,245 @@
+package ipsc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// Repeater call monitoring (RCM) packets report call activity to RDAC and
+// other monitoring tools. They are never translated. The layouts below
+// follow DMRlink's decoding of them.
+const (
+	PacketType_CallMonitorStatus   PacketType = 0x61
+	PacketType_CallMonitorRepeater PacketType = 0x62
+	PacketType_CallMonitorNack     PacketType = 0x63
+)
+
+// flagRepeaterMonitor in flags[2] marks a peer that wants call monitoring
+// packets.
+const flagRepeaterMonitor byte = 0b01000000
+
+// Call states in a call status packet.
+const (
+	rcmStatusActive byte = 0x01
+	rcmStatusEnd    byte = 0x02
+)
+
+// Call types in a call status packet.
+const (
+	rcmCallGroupVoice   byte = 0x4F
+	rcmCallPrivateVoice byte = 0x50
+)
+
+// rcmCallStatusLength is the length of a call status packet: opcode, peer
+// ID, IPSC source, sequence number, timeslot, a reserved byte, status,
+// source, destination, call type, priority, and security.
+const rcmCallStatusLength = 1 + 4 + 4 + 4 + 1 + 1 + 1 + 3 + 3 + 1 + 1 + 1
+
+// rcmRepeaterStatusLength is the length of a repeater status packet:
+// opcode, peer ID, and the state of each timeslot.
+const rcmRepeaterStatusLength = 1 + 4 + 1 + 1
+
+// RCMCallStatus is a decoded call status packet.
+type RCMCallStatus struct {
+	PeerID   uint32
+	IPSCSrc  uint32
+	Sequence uint32
+	Slot     int // 1 or 2
+	Status   byte
+	Src      uint32
+	Dst      uint32
+	CallType byte
+	Priority byte
+	Security byte
+}
+
+// RCMRepeaterStatus is a decoded repeater status packet.
+type RCMRepeaterStatus struct {
+	PeerID    uint32
+	SlotState [2]byte
+}
+
+// RCMSlotState is what a peer last reported about one timeslot through
+// call monitoring.
+type RCMSlotState struct {
+	Status   byte
+	Src      uint32
+	Dst      uint32
+	CallType byte
+	Repeater byte
+}
+
+// rcmCall is a call on one timeslot reported to call monitoring peers.
+type rcmCall struct {
+	active   bool
+	src, dst uint32
+	callType byte
+}
+
+func parseRCMCallStatus(data []byte) (RCMCallStatus, error) {
+	if len(data) < rcmCallStatusLength {
+		return RCMCallStatus{}, fmt.Errorf("call monitor status too short: %d bytes", len(data))
+	}
+	return RCMCallStatus{
+		PeerID:   binary.BigEndian.Uint32(data[1:5]),
+		IPSCSrc:  binary.BigEndian.Uint32(data[5:9]),
+		Sequence: binary.BigEndian.Uint32(data[9:13]),
+		Slot:     int(data[13]&0x01) + 1,
+		Status:   data[15],
+		Src:      uint32(data[16])<<16 | uint32(data[17])<<8 | uint32(data[18]),
+		Dst:      uint32(data[19])<<16 | uint32(data[20])<<8 | uint32(data[21]),
+		CallType: data[22],
+		Priority: data[23],
+		Security: data[24],
+	}, nil
+}
+
+func parseRCMRepeaterStatus(data []byte) (RCMRepeaterStatus, error) {
+	if len(data) < rcmRepeaterStatusLength {
+		return RCMRepeaterStatus{}, fmt.Errorf("call monitor repeater status too short: %d bytes", len(data))
+	}
+	return RCMRepeaterStatus{
+		PeerID:    binary.BigEndian.Uint32(data[1:5]),
+		SlotState: [2]byte{data[5], data[6]},
+	}, nil
+}
+
+// encode builds the call status packet for st.
+func (st RCMCallStatus) encode() []byte {
+	packet := make([]byte, rcmCallStatusLength)
+	packet[0] = byte(PacketType_CallMonitorStatus)
+	binary.BigEndian.PutUint32(packet[1:5], st.PeerID)
+	binary.BigEndian.PutUint32(packet[5:9], st.IPSCSrc)
+	binary.BigEndian.PutUint32(packet[9:13], st.Sequence)
+	packet[13] = byte(st.Slot - 1) //nolint:gosec // G115: slot is 1 or 2
+	packet[15] = st.Status
+	packet[16], packet[17], packet[18] = byte(st.Src>>16), byte(st.Src>>8), byte(st.Src)
+	packet[19], packet[20], packet[21] = byte(st.Dst>>16), byte(st.Dst>>8), byte(st.Dst)
+	packet[22] = st.CallType
+	packet[23] = st.Priority
+	packet[24] = st.Security
+	return packet
+}
+
+// handleCallMonitor records the call monitoring state a registered peer
+// reports. Packets from unknown peers are dropped.
+func (s *IPSCServer) handleCallMonitor(env packetEnvelope) ([][]byte, error) {
+	data, addr := env.Data, env.Addr
+	switch env.Type {
+	case PacketType_CallMonitorStatus:
+		st, err := parseRCMCallStatus(data)
+		if err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		peer, ok := s.peers[st.PeerID]
+		if !ok {
+			return nil, ErrPacketIgnored
+		}
+		slot := &peer.CallMonitor[st.Slot-1]
+		slot.Status, slot.Src, slot.Dst, slot.CallType = st.Status, st.Src, st.Dst, st.CallType
+		s.log.Debug("IPSC call monitor status", "peer", addr, "peerID", st.PeerID, "slot", st.Slot,
+			"status", st.Status, "src", st.Src, "dst", st.Dst, "callType", st.CallType)
+	case PacketType_CallMonitorRepeater:
+		st, err := parseRCMRepeaterStatus(data)
+		if err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		peer, ok := s.peers[st.PeerID]
+		if !ok {
+			return nil, ErrPacketIgnored
+		}
+		peer.CallMonitor[0].Repeater = st.SlotState[0]
+		peer.CallMonitor[1].Repeater = st.SlotState[1]
+		s.log.Debug("IPSC call monitor repeater status", "peer", addr, "peerID", st.PeerID,
+			"slot1", st.SlotState[0], "slot2", st.SlotState[1])
+	}
+	return nil, nil
+}
+
+// noteCallMonitor follows the voice calls sent to peers and, with call
+// monitoring enabled, returns the call status packets announcing each
+// call's start and end.
+func (s *IPSCServer) noteCallMonitor(data []byte) [][]byte {
+	if !s.cfg.IPSC.CallMonitor.Send || len(data) < 18 {
+		return nil
+	}
+	var callType byte
+	switch PacketType(data[0]) {
+	case PacketType_GroupVoice:
+		callType = rcmCallGroupVoice
+	case PacketType_PrivateVoice:
+		callType = rcmCallPrivateVoice
+	default:
+		return nil
+	}
+	slot := 1
+	if data[17]&0x20 != 0 {
+		slot = 2
+	}
+	src := uint32(data[6])<<16 | uint32(data[7])<<8 | uint32(data[8])
+	dst := uint32(data[9])<<16 | uint32(data[10])<<8 | uint32(data[11])
+	end := data[17]&0x40 != 0
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	call := &s.rcmCalls[slot-1]
+	var packets [][]byte
+	if call.active && (call.src != src || call.dst != dst || call.callType != callType) {
+		packets = append(packets, s.rcmStatus(slot, rcmStatusEnd, *call))
+		call.active = false
+	}
+	if !call.active {
+		*call = rcmCall{active: true, src: src, dst: dst, callType: callType}
+		packets = append(packets, s.rcmStatus(slot, rcmStatusActive, *call))
+	}
+	if end {
+		packets = append(packets, s.rcmStatus(slot, rcmStatusEnd, *call))
+		call.active = false
+	}
+	return packets
+}
+
+// rcmStatus builds a call status packet for call on slot. Must be called
+// with s.mu held.
+func (s *IPSCServer) rcmStatus(slot int, status byte, call rcmCall) []byte {
+	s.rcmSequence++
+	return RCMCallStatus{
+		PeerID:   s.localID,
+		IPSCSrc:  s.localID,
+		Sequence: s.rcmSequence,
+		Slot:     slot,
+		Status:   status,
+		Src:      call.src,
+		Dst:      call.dst,
+		CallType: call.callType,
+	}.encode()
+}
+
+// sendCallMonitor sends packets to the peers that registered for call
+// monitoring.
+func (s *IPSCServer) sendCallMonitor(packets [][]byte) {
+	if len(packets) == 0 {
+		return
+	}
+	s.mu.RLock()
+	addrs := make([]*net.UDPAddr, 0, len(s.peers))
+	for _, peer := range s.peers {
+		if peer.Addr != nil && peer.Flags[2]&flagRepeaterMonitor != 0 {
+			addrs = append(addrs, cloneUDPAddr(peer.Addr))
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, addr := range addrs {
+		for _, packet := range packets {
+			if err := s.sendPacket(&Packet{data: append([]byte(nil), packet...)}, addr); err != nil {
+				s.log.Warn("failed sending IPSC call monitor status", "peer", addr, "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package ipsc
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// rcmActiveFrame is a call status frame as DMRlink decodes it: peer 12
+// reports a group voice call from 3258940 to 3103 active on TS2.
+const rcmActiveFrame = "610000000c0000000c0000021a01000131ba3c000c1f4f0000"
+
+func TestParseRCMCallStatus(t *testing.T) {
+	t.Parallel()
+	st, err := parseRCMCallStatus(mustDecodeHex(t, rcmActiveFrame))
+	if err != nil {
+		t.Fatalf("parseRCMCallStatus: %v", err)
+	}
+	want := RCMCallStatus{
+		PeerID:   12,
+		IPSCSrc:  12,
+		Sequence: 538,
+		Slot:     2,
+		Status:   rcmStatusActive,
+		Src:      3258940,
+		Dst:      3103,
+		CallType: rcmCallGroupVoice,
+	}
+	if st != want {
+		t.Fatalf("expected %+v, got %+v", want, st)
+	}
+	if got := st.encode(); !bytes.Equal(got, mustDecodeHex(t, rcmActiveFrame)) {
+		t.Fatalf("expected the frame to encode back to %s, got %X", rcmActiveFrame, got)
+	}
+
+	if _, err := parseRCMCallStatus(mustDecodeHex(t, rcmActiveFrame)[:rcmCallStatusLength-1]); err == nil {
+		t.Fatal("expected a short frame refused")
+	}
+}
+
+func TestCallMonitorUpdatesPeerState(t *testing.T) {
+	t.Parallel()
+	s := NewIPSCServer(testConfig(false, ""), nil)
+	addr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 50000}
+
+	// Until the peer registers, its call monitoring is dropped.
+	if _, err := s.handlePacket(mustDecodeHex(t, rcmActiveFrame), addr); !errors.Is(err, ErrPacketIgnored) {
+		t.Fatalf("expected the packet ignored, got %v", err)
+	}
+	s.upsertPeer(12, addr, 0x6A, [4]byte{})
+
+	if _, err := s.handlePacket(mustDecodeHex(t, rcmActiveFrame), addr); !errors.Is(err, ErrPacketIgnored) {
+		t.Fatalf("expected the packet recorded and not translated, got %v", err)
+	}
+	if _, err := s.handlePacket([]byte{byte(PacketType_CallMonitorRepeater), 0, 0, 0, 12, 0x00, 0x01}, addr); !errors.Is(err, ErrPacketIgnored) {
+		t.Fatalf("expected the packet recorded and not translated, got %v", err)
+	}
+
+	peers := s.Peers()
+	if len(peers) != 1 {
+		t.Fatalf("expected 1 peer, got %d", len(peers))
+	}
+	want := [2]RCMSlotState{
+		{Repeater: 0x00},
+		{Status: rcmStatusActive, Src: 3258940, Dst: 3103, CallType: rcmCallGroupVoice, Repeater: 0x01},
+	}
+	if peers[0].CallMonitor != want {
+		t.Fatalf("expected call monitor state %+v, got %+v", want, peers[0].CallMonitor)
+	}
+}
+
+func TestCallMonitorSentOnCallStartAndEnd(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		enabled bool
+	}{
+		{"enabled", true},
+		{"disabled", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := testConfig(false, "")
+			cfg.IPSC.CallMonitor.Send = tt.enabled
+			s, _ := newTestServerWithConfig(t, cfg)
+			monitor, monitorAddr := listenPeer(t)
+			plain, plainAddr := listenPeer(t)
+			s.upsertPeer(100, monitorAddr, 0x6A, [4]byte{0, 0, flagRepeaterMonitor, 0x0D})
+			s.upsertPeer(200, plainAddr, 0x6A, [4]byte{0, 0, 0, 0x0D})
+
+			header := makeTestIPSCPacket(0x80, ipscBurstVoiceHead, true, true)
+			s.SendUserPacket(header)
+			s.SendUserPacket(makeTestIPSCPacket(0x80, ipscBurstSlot1, true, true))
+			terminator := makeTestIPSCPacket(0x80, ipscBurstVoiceTerm, true, true)
+			terminator[17] |= 0x40
+			s.SendUserPacket(terminator)
+
+			if !tt.enabled {
+				assertNoCallMonitor(t, monitor)
+				return
+			}
+			for i, wantStatus := range []byte{rcmStatusActive, rcmStatusEnd} {
+				data, _ := readPacketType(t, monitor, PacketType_CallMonitorStatus)
+				st, err := parseRCMCallStatus(data)
+				if err != nil {
+					t.Fatalf("parseRCMCallStatus: %v", err)
+				}
+				want := RCMCallStatus{
+					PeerID:   s.localID,
+					IPSCSrc:  s.localID,
+					Sequence: uint32(i + 1), //nolint:gosec // G115: i is 0 or 1
+					Slot:     2,
+					Status:   wantStatus,
+					Src:      100,
+					Dst:      200,
+					CallType: rcmCallGroupVoice,
+				}
+				if st != want {
+					t.Fatalf("expected %+v, got %+v", want, st)
+				}
+			}
+			assertNoCallMonitor(t, plain)
+		})
+	}
+}
+
+// assertNoCallMonitor fails if a call monitoring packet arrives on conn
+// among what has been sent to it.
+func assertNoCallMonitor(t *testing.T, conn *net.UDPConn) {
+	t.Helper()
+	if err := conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond)); err != nil {
+		t.Fatalf("SetReadDeadline: %v", err)
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		if n > 0 && PacketType(buf[0]) == PacketType_CallMonitorStatus {
+			t.Fatalf("expected no call monitoring packets, got % X", buf[:n])
+		}
+	}
+}
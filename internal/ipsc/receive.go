@@ -0,0 +1,105 @@
+package ipsc
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// maxPacketSize is the largest datagram read from the socket.
+	maxPacketSize = 1500
+	// defaultReceiveWorkers handle received packets when
+	// ipsc.receive-workers is not set.
+	defaultReceiveWorkers = 4
+	// receiveQueueSize is how many packets wait for each receive worker
+	// before the socket is no longer read.
+	receiveQueueSize = 64
+)
+
+// packetBuffers are the buffers packets are read into, reused once a
+// packet has been handled.
+var packetBuffers = sync.Pool{
+	New: func() any {
+		buf := make([]byte, maxPacketSize)
+		return &buf
+	},
+}
+
+func getPacketBuffer() *[]byte {
+	buf, _ := packetBuffers.Get().(*[]byte)
+	return buf
+}
+
+// receivedPacket is a packet read into buf, waiting for a receive worker.
+type receivedPacket struct {
+	buf  *[]byte
+	n    int
+	addr *net.UDPAddr
+	at   time.Time
+}
+
+// startReceiveWorkers starts the receive workers and returns their
+// queues. Each worker stops once its queue is closed.
+func (s *IPSCServer) startReceiveWorkers() []chan receivedPacket {
+	workers := int(s.cfg.IPSC.ReceiveWorkers) //nolint:gosec // G115: a worker count
+	if workers <= 0 {
+		workers = defaultReceiveWorkers
+	}
+	queues := make([]chan receivedPacket, workers)
+	for i := range queues {
+		queues[i] = make(chan receivedPacket, receiveQueueSize)
+		go s.receiveWorker(queues[i])
+	}
+	return queues
+}
+
+// receiveWorkerFor returns the worker that handles the packets from addr.
+// Every packet from one address goes to the same worker, so a peer's
+// packets are handled in the order they arrived while different peers
+// are handled at once.
+func receiveWorkerFor(addr *net.UDPAddr, workers int) int {
+	// FNV-1a over the address and port.
+	hash := uint32(2166136261)
+	for _, b := range addr.IP {
+		hash = (hash ^ uint32(b)) * 16777619
+	}
+	hash = (hash ^ uint32(addr.Port&0xFF)) * 16777619
+	hash = (hash ^ uint32(addr.Port>>8&0xFF)) * 16777619
+	return int(hash % uint32(workers)) //nolint:gosec // G115: workers is positive
+}
+
+// countDropped counts a received packet that was not passed on.
+func (s *IPSCServer) countDropped(reason string) {
+	if s.metrics != nil {
+		s.metrics.IPSCPacketsDropped.WithLabelValues(reason).Inc()
+	}
+}
+
+func (s *IPSCServer) receiveWorker(queue <-chan receivedPacket) {
+	for packet := range queue {
+		s.receive(packet)
+	}
+}
+
+// receive handles one packet and returns its buffer to the pool.
+func (s *IPSCServer) receive(packet receivedPacket) {
+	defer s.inflight.Done()
+	defer packetBuffers.Put(packet.buf)
+	defer s.recoverPanic()
+	data := (*packet.buf)[:packet.n]
+	handled, err := s.handlePacket(data, packet.addr)
+	s.captureInbound(packet.at, data, packet.addr, err)
+	if err != nil {
+		if errors.Is(err, ErrPacketIgnored) {
+			s.countDropped("ignored")
+			return
+		}
+		s.countDropped("invalid")
+		s.log.Warn("error parsing packet", "peer", packet.addr, "error", err, "length", len(data), "packet", data)
+		return
+	}
+
+	s.log.Debug("received packet", "peer", packet.addr, "length", len(data), "packet", handled)
+}
@@ -0,0 +1,143 @@
+package ipsc
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startReceiveTestServer starts a server on loopback whose burst handler
+// calls fn.
+func startReceiveTestServer(tb testing.TB, fn func(packetType byte, data []byte, addr *net.UDPAddr)) *IPSCServer {
+	tb.Helper()
+	cfg := testConfig(false, "")
+	cfg.IPSC.IP = "127.0.0.1"
+	cfg.IPSC.RateLimit.Rate = 0
+	s := NewIPSCServer(cfg, nil)
+	s.SetBurstHandler(fn)
+	if err := s.Start(); err != nil {
+		tb.Fatalf("Start: %v", err)
+	}
+	tb.Cleanup(s.Stop)
+	return s
+}
+
+// sequencedVoice returns a group voice packet from peerID carrying seq.
+func sequencedVoice(peerID, seq uint32) []byte {
+	data := make([]byte, 54)
+	data[0] = byte(PacketType_GroupVoice)
+	binary.BigEndian.PutUint32(data[1:5], peerID)
+	binary.BigEndian.PutUint32(data[50:54], seq)
+	return data
+}
+
+func TestReceiveKeepsPeerOrder(t *testing.T) {
+	t.Parallel()
+	const (
+		peers   = 8
+		packets = 200
+	)
+	var mu sync.Mutex
+	received := map[uint32][]uint32{}
+	cfg := testConfig(false, "")
+	cfg.IPSC.RateLimit.Rate = 0
+	s := NewIPSCServer(cfg, nil)
+	s.SetBurstHandler(func(_ byte, data []byte, _ *net.UDPAddr) {
+		peerID := binary.BigEndian.Uint32(data[1:5])
+		mu.Lock()
+		received[peerID] = append(received[peerID], binary.BigEndian.Uint32(data[50:54]))
+		mu.Unlock()
+	})
+
+	// Packets go straight onto the worker queues, as the handler would
+	// queue them, so none are lost to a full socket buffer.
+	queues := s.startReceiveWorkers()
+	var senders sync.WaitGroup
+	for peer := range uint32(peers) {
+		addr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, byte(peer)), Port: 50000 + int(peer)}
+		queue := queues[receiveWorkerFor(addr, len(queues))]
+		senders.Add(1)
+		go func() {
+			defer senders.Done()
+			for seq := range uint32(packets) {
+				buf := getPacketBuffer()
+				n := copy(*buf, sequencedVoice(100+peer, seq))
+				s.inflight.Add(1)
+				queue <- receivedPacket{buf: buf, n: n, addr: addr}
+			}
+		}()
+	}
+	senders.Wait()
+	for _, queue := range queues {
+		close(queue)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected every packet handled")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != peers {
+		t.Fatalf("expected packets from %d peers, got %d", peers, len(received))
+	}
+	for peerID, seqs := range received {
+		if len(seqs) != packets {
+			t.Fatalf("peer %d: expected %d packets, got %d", peerID, packets, len(seqs))
+		}
+		for i, seq := range seqs {
+			if seq != uint32(i) { //nolint:gosec // G115: i is below packets
+				t.Fatalf("peer %d: expected packet %d in position %d, got %v", peerID, i, i, seqs)
+			}
+		}
+	}
+}
+
+func TestReceiveWorkerForIsStable(t *testing.T) {
+	t.Parallel()
+	a := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 50000}
+	b := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 50000}
+	for _, workers := range []int{1, 4, 7} {
+		w := receiveWorkerFor(a, workers)
+		if w < 0 || w >= workers {
+			t.Fatalf("%d workers: worker %d out of range", workers, w)
+		}
+		if got := receiveWorkerFor(b, workers); got != w {
+			t.Fatalf("%d workers: expected the same address on worker %d, got %d", workers, w, got)
+		}
+	}
+}
+
+// BenchmarkReceive times a voice packet from the socket to the burst
+// handler, allocations included.
+func BenchmarkReceive(b *testing.B) {
+	handled := make(chan struct{}, 1)
+	s := startReceiveTestServer(b, func(byte, []byte, *net.UDPAddr) {
+		handled <- struct{}{}
+	})
+	client, err := net.DialUDP("udp", nil, s.Addr())
+	if err != nil {
+		b.Fatalf("dial: %v", err)
+	}
+	defer client.Close()
+	packet := sequencedVoice(100, 0)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if _, err := client.Write(packet); err != nil {
+			b.Fatalf("write: %v", err)
+		}
+		// Wait for each packet so none are lost to a full socket buffer.
+		<-handled
+	}
+}
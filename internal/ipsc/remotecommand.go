@@ -0,0 +1,119 @@
+package ipsc
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/bptc"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/csbk"
+	mmdvm "github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+)
+
+// remoteCommandNames names the extended functions that disable and
+// re-enable radios over the air.
+var remoteCommandNames = map[byte]string{
+	csbk.FunctionRadioInhibit:   "stun",
+	csbk.FunctionRadioUninhibit: "revive",
+	csbk.FunctionRadioKill:      "kill",
+}
+
+// remoteCommandPolicy decides which remote radio commands are carried.
+type remoteCommandPolicy struct {
+	allowAll   bool
+	authorized map[uint32]struct{}
+}
+
+// SetRemoteCommands enables the remote command policy in cfg. Until it is
+// called, the translator carries remote commands without inspecting them.
+func (t *IPSCTranslator) SetRemoteCommands(cfg config.IPSCRemoteCommands) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	policy := &remoteCommandPolicy{
+		allowAll:   cfg.Policy == config.RemoteCommandAllow,
+		authorized: make(map[uint32]struct{}, len(cfg.AuthorizedSources)),
+	}
+	for _, id := range cfg.AuthorizedSources {
+		policy.authorized[id] = struct{}{}
+	}
+	t.remoteCommands = policy
+}
+
+// SetAuditLogger sets where remote commands are recorded. The default is
+// the default slog logger.
+func (t *IPSCTranslator) SetAuditLogger(logger *slog.Logger) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.audit = logger
+}
+
+// remoteCommand returns the remote command carried by block, if it is a
+// request for one.
+func remoteCommand(block csbk.Block) (csbk.ExtFunction, string, bool) {
+	f, err := csbk.ParseExtFunction(block)
+	if err != nil || f.Response {
+		return csbk.ExtFunction{}, "", false
+	}
+	name, ok := remoteCommandNames[f.Function]
+	return f, name, ok
+}
+
+// admitRemoteCommand applies the remote command policy to the CSBK block
+// travelling in direction. It returns the block to send, re-encoded if it
+// is a remote command, and false if the block must be dropped. Every
+// remote command is audited. Must be called with t.mu held.
+func (t *IPSCTranslator) admitRemoteCommand(direction string, block csbk.Block) (csbk.Block, bool) {
+	if t.remoteCommands == nil {
+		return block, true
+	}
+	f, name, ok := remoteCommand(block)
+	if !ok {
+		return block, true
+	}
+
+	_, authorized := t.remoteCommands.authorized[f.Src]
+	allowed := t.remoteCommands.allowAll || authorized
+	disposition := "blocked"
+	level := slog.LevelWarn
+	if allowed {
+		disposition = "allowed"
+		level = slog.LevelInfo
+	}
+	audit := t.audit
+	if audit == nil {
+		audit = slog.Default()
+	}
+	audit.Log(context.Background(), level, "Remote radio command",
+		"audit", true, "command", name, "src", f.Src, "target", f.Dst,
+		"direction", direction, "disposition", disposition)
+
+	if !allowed {
+		if t.metrics != nil {
+			t.metrics.TranslatorCommandsBlocked.WithLabelValues(direction, name).Inc()
+		}
+		return block, false
+	}
+	return f.Encode(), true
+}
+
+// admitRemoteCommandToIPSC applies the remote command policy to a CSBK
+// from the master. It returns the block to send if it is a remote
+// command, and false if the packet must be dropped. Must be called with
+// t.mu held.
+func (t *IPSCTranslator) admitRemoteCommandToIPSC(pkt mmdvm.Packet) (*csbk.Block, bool) {
+	if t.remoteCommands == nil {
+		return nil, true
+	}
+	// Damaged blocks are judged on their best correction, so a command
+	// cannot slip past the policy by arriving with errors.
+	data, _ := bptc.Decode(pkt.DMRData)
+	block := csbk.Block(data)
+	if _, _, ok := remoteCommand(block); !ok {
+		return nil, true
+	}
+	block, ok := t.admitRemoteCommand("mmdvm_to_ipsc", block)
+	if !ok {
+		return nil, false
+	}
+	return &block, true
+}
@@ -0,0 +1,180 @@
+package ipsc
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/USA-RedDragon/dmrgo/dmr/layer2"
+	"github.com/USA-RedDragon/dmrgo/dmr/layer2/elements"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/bptc"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/csbk"
+)
+
+const (
+	commandOperator = 3120101
+	commandTarget   = 3120102
+)
+
+// newAuditedTranslator returns a translator applying cmds and the buffer
+// its audit log is written to.
+func newAuditedTranslator(t *testing.T, cmds config.IPSCRemoteCommands) (*IPSCTranslator, *bytes.Buffer) {
+	t.Helper()
+	tr := newTestTranslator(t)
+	tr.SetRemoteCommands(cmds)
+	var buf bytes.Buffer
+	tr.SetAuditLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+	return tr, &buf
+}
+
+// auditEntries decodes the audit log written to buf.
+func auditEntries(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var entries []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var entry map[string]any
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatalf("decoding audit log: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func commandCSBK(function byte, src uint32) csbk.Block {
+	return csbk.ExtFunction{FID: 0x10, Function: function, Dst: commandTarget, Src: src}.Encode()
+}
+
+// makeCommandIPSCPacket returns block as an IPSC private data CSBK.
+func makeCommandIPSCPacket(block csbk.Block) []byte {
+	data := makeTestIPSCPacket(byte(PacketType_PrivateData), ipscBurstCSBK, false, false)
+	copy(data[38:50], block[:])
+	return data
+}
+
+// checkAudit verifies buf holds one entry recording command with
+// disposition.
+func checkAudit(t *testing.T, buf *bytes.Buffer, command, direction, disposition string, src uint32) {
+	t.Helper()
+	entries := auditEntries(t, buf)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 audit entry, got %d", len(entries))
+	}
+	e := entries[0]
+	if e["command"] != command || e["direction"] != direction || e["disposition"] != disposition {
+		t.Fatalf("unexpected audit entry %v", e)
+	}
+	if e["src"] != float64(src) || e["target"] != float64(commandTarget) {
+		t.Fatalf("expected src %d and target %d, got %v", src, commandTarget, e)
+	}
+}
+
+var remoteCommandTests = []struct {
+	command  string
+	function byte
+}{
+	{"stun", csbk.FunctionRadioInhibit},
+	{"revive", csbk.FunctionRadioUninhibit},
+	{"kill", csbk.FunctionRadioKill},
+}
+
+func TestRemoteCommandsToMMDVM(t *testing.T) {
+	t.Parallel()
+	policy := config.IPSCRemoteCommands{
+		Policy:            config.RemoteCommandBlock,
+		AuthorizedSources: []uint32{commandOperator},
+	}
+	for _, tt := range remoteCommandTests {
+		t.Run(tt.command, func(t *testing.T) {
+			t.Parallel()
+			tr, buf := newAuditedTranslator(t, policy)
+			unauthorized := makeCommandIPSCPacket(commandCSBK(tt.function, commandTarget+1))
+			if result := tr.TranslateToMMDVM(unauthorized[0], unauthorized); result != nil {
+				t.Fatalf("expected command from an unauthorized source to be blocked, got %d packets", len(result))
+			}
+			if len(tr.reverseStreams) != 0 {
+				t.Fatalf("expected a blocked command not to start a stream, got %d", len(tr.reverseStreams))
+			}
+			checkAudit(t, buf, tt.command, "ipsc_to_mmdvm", "blocked", commandTarget+1)
+
+			authorized := makeCommandIPSCPacket(commandCSBK(tt.function, commandOperator))
+			result := tr.TranslateToMMDVM(authorized[0], authorized)
+			if len(result) != 1 {
+				t.Fatalf("expected command from an authorized source to pass, got %d packets", len(result))
+			}
+			checkAudit(t, buf, tt.command, "ipsc_to_mmdvm", "allowed", commandOperator)
+			f, err := csbk.ParseExtFunction(csbk.Block(bptc.Extract(result[0].DMRData)))
+			if err != nil {
+				t.Fatalf("expected a valid CSBK toward the master: %v", err)
+			}
+			if f.Function != tt.function || f.Src != commandOperator || f.Dst != commandTarget {
+				t.Fatalf("unexpected CSBK toward the master: %+v", f)
+			}
+		})
+	}
+}
+
+func TestRemoteCommandsToIPSC(t *testing.T) {
+	t.Parallel()
+	for _, tt := range remoteCommandTests {
+		t.Run(tt.command, func(t *testing.T) {
+			t.Parallel()
+			pkt := makeTestMMDVMPacket(false, false, mmdvmFrameTypeDataSync, uint(elements.DataTypeCSBK))
+			command := commandCSBK(tt.function, commandOperator)
+			pkt.DMRData = layer2.BuildLCDataBurst(command, elements.DataTypeCSBK, 0)
+			bptc.Insert(&pkt.DMRData, command)
+
+			tr, buf := newAuditedTranslator(t, config.IPSCRemoteCommands{Policy: config.RemoteCommandBlock})
+			if result := tr.TranslateToIPSC(pkt); result != nil {
+				t.Fatalf("expected command to be blocked by default, got %d packets", len(result))
+			}
+			checkAudit(t, buf, tt.command, "mmdvm_to_ipsc", "blocked", commandOperator)
+
+			tr, buf = newAuditedTranslator(t, config.IPSCRemoteCommands{Policy: config.RemoteCommandAllow})
+			result := tr.TranslateToIPSC(pkt)
+			if len(result) != 1 {
+				t.Fatalf("expected command to pass under the allow policy, got %d packets", len(result))
+			}
+			checkAudit(t, buf, tt.command, "mmdvm_to_ipsc", "allowed", commandOperator)
+			var block csbk.Block
+			copy(block[:], result[0][38:50])
+			f, err := csbk.ParseExtFunction(block)
+			if err != nil {
+				t.Fatalf("expected a valid CSBK toward IPSC: %v", err)
+			}
+			if f.Function != tt.function || f.Src != commandOperator || f.Dst != commandTarget {
+				t.Fatalf("unexpected CSBK toward IPSC: %+v", f)
+			}
+		})
+	}
+}
+
+func TestRemoteCommandsIgnoreOtherCSBKs(t *testing.T) {
+	t.Parallel()
+	tr, buf := newAuditedTranslator(t, config.IPSCRemoteCommands{Policy: config.RemoteCommandBlock})
+	for _, block := range []csbk.Block{
+		commandCSBK(csbk.FunctionRadioCheck, commandOperator),
+		// The target acknowledging a stun is not itself a command.
+		csbk.ExtFunction{FID: 0x10, Function: csbk.FunctionRadioInhibit, Response: true, Dst: commandOperator, Src: commandTarget}.Encode(),
+	} {
+		data := makeCommandIPSCPacket(block)
+		if result := tr.TranslateToMMDVM(data[0], data); len(result) != 1 {
+			t.Fatalf("expected CSBK % X to pass, got %d packets", block, len(result))
+		}
+	}
+	if entries := auditEntries(t, buf); len(entries) != 0 {
+		t.Fatalf("expected no audit entries, got %v", entries)
+	}
+}
+
+func TestRemoteCommandsUncheckedByDefault(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	data := makeCommandIPSCPacket(commandCSBK(csbk.FunctionRadioKill, commandOperator))
+	if result := tr.TranslateToMMDVM(data[0], data); len(result) != 1 {
+		t.Fatalf("expected commands to pass without a policy, got %d packets", len(result))
+	}
+}
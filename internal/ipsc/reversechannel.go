@@ -0,0 +1,150 @@
+package ipsc
+
+import (
+	"encoding/binary"
+	"log/slog"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	mmdvm "github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+)
+
+// Reverse-channel bursts carry transmit interrupt signalling to the radio
+// holding a channel. IPSC marks them with the slot bit of the voice burst
+// types. HBRP has no frame for them, so they travel in the frame type it
+// leaves unused, with the 12 signalling octets at the start of the burst.
+const (
+	ipscBurstReverseChannel1     byte = 0x0B
+	ipscBurstReverseChannel2     byte = 0x8B
+	mmdvmFrameTypeReverseChannel uint = 3
+)
+
+func isReverseChannelBurst(burstType byte) bool {
+	return burstType == ipscBurstReverseChannel1 || burstType == ipscBurstReverseChannel2
+}
+
+// SetReverseChannel sets whether reverse-channel bursts are forwarded or
+// dropped. The zero value forwards them.
+func (t *IPSCTranslator) SetReverseChannel(policy config.ReverseChannelPolicy) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if policy == "" {
+		policy = config.ReverseChannelForward
+	}
+	t.reverseChannel = policy
+}
+
+// reverseChannelToMMDVM translates a reverse-channel burst from IPSC. The
+// burst joins the call it interrupts: the call with its call control if
+// there is one, or else the call on its slot in either direction. It never
+// starts a stream or moves a call's voice framing. Must be called with
+// t.mu held.
+func (t *IPSCTranslator) reverseChannelToMMDVM(src, dst uint, groupCall, slot bool, key reverseStreamKey, payload []byte) []mmdvm.Packet {
+	const direction = "ipsc_to_mmdvm"
+	if t.reverseChannel == config.ReverseChannelDrop {
+		t.countReverseChannel(direction, "dropped")
+		return nil
+	}
+
+	pkt := mmdvm.Packet{
+		Signature: "DMRD",
+		Src:       src,
+		Dst:       dst,
+		Repeater:  uint(t.repeaterID),
+		Slot:      slot,
+		GroupCall: groupCall,
+		FrameType: mmdvmFrameTypeReverseChannel,
+	}
+	if rss, ok := t.reverseStreams[key]; ok {
+		pkt.StreamID = uint(rss.streamID)
+		pkt.Seq = uint(rss.seq)
+		rss.seq++
+	} else if streamID, ok := t.callOnSlot(slot); ok {
+		pkt.StreamID = uint(streamID)
+	} else {
+		slog.Debug("IPSCTranslator: reverse-channel burst outside a call", "src", src, "dst", dst, "slot", slot)
+		t.countReverseChannel(direction, "no_call")
+		return nil
+	}
+	copy(pkt.DMRData[:12], payload)
+
+	t.countReverseChannel(direction, "forwarded")
+	t.sink.IncTranslated(direction, "reverse_channel")
+	return []mmdvm.Packet{pkt}
+}
+
+// callOnSlot returns the stream ID of a call in progress on slot, as the
+// master sees it, preferring a call from the master. Must be called with
+// t.mu held.
+func (t *IPSCTranslator) callOnSlot(slot bool) (uint32, bool) {
+	for streamID, ss := range t.streams {
+		if ss.slot == slot {
+			return streamID, true
+		}
+	}
+	for _, rss := range t.reverseStreams {
+		if rss.slot == slot {
+			return rss.streamID, true
+		}
+	}
+	return 0, false
+}
+
+// reverseChannelToIPSC translates a reverse-channel frame from the master
+// into the call it belongs to, which is either a call from the master or
+// one from IPSC being interrupted by the far end. pkt.Slot is the IPSC-side
+// slot. Must be called with t.mu held.
+func (t *IPSCTranslator) reverseChannelToIPSC(pkt mmdvm.Packet) [][]byte {
+	const direction = "mmdvm_to_ipsc"
+	if t.reverseChannel == config.ReverseChannelDrop {
+		t.countReverseChannel(direction, "dropped")
+		return nil
+	}
+	streamID := uint32(pkt.StreamID) //nolint:gosec // Checked by the caller
+	ss, ok := t.streams[streamID]
+	if !ok {
+		// Interrupting a call from IPSC goes back under its call control.
+		for key, rss := range t.reverseStreams {
+			if rss.streamID == streamID {
+				ss = &streamState{callControl: key.callControl, ssrc: t.callSSRC()}
+				ok = true
+				break
+			}
+		}
+	}
+	if !ok {
+		slog.Debug("IPSCTranslator: reverse-channel frame outside a call", "streamID", pkt.StreamID)
+		t.countReverseChannel(direction, "no_call")
+		return nil
+	}
+
+	buf := make([]byte, 54)
+	t.buildIPSCHeader(buf, pkt, ss, false, false)
+	// The interrupt takes no time of its own in the call's timeline.
+	timestamp := ss.rtpTimestamp
+	t.buildRTPHeader(buf, ss, false, t.rtp.PayloadType)
+	ss.rtpTimestamp = timestamp
+
+	if pkt.Slot {
+		buf[30] = ipscBurstReverseChannel2
+		buf[35] = ipscBurstSlot2
+	} else {
+		buf[30] = ipscBurstReverseChannel1
+		buf[35] = ipscBurstSlot1
+	}
+	buf[31] = 0xC0
+	binary.BigEndian.PutUint16(buf[32:34], 0x000A)
+	buf[34] = 0x80
+	binary.BigEndian.PutUint16(buf[36:38], 0x0060)
+	copy(buf[38:50], pkt.DMRData[:12])
+	ss.ipscSeq++
+
+	t.countReverseChannel(direction, "forwarded")
+	t.sink.IncTranslated(direction, "reverse_channel")
+	return [][]byte{buf}
+}
+
+func (t *IPSCTranslator) countReverseChannel(direction, outcome string) {
+	if t.metrics != nil {
+		t.metrics.TranslatorReverseChannel.WithLabelValues(direction, outcome).Inc()
+	}
+}
@@ -0,0 +1,162 @@
+package ipsc
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/USA-RedDragon/dmrgo/dmr/layer2/elements"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+)
+
+// makeReverseChannelIPSCPacket returns a reverse-channel burst on slot
+// under callControl, carrying the octets 1 to 12.
+func makeReverseChannelIPSCPacket(slot bool, callControl uint32) []byte {
+	burstType := ipscBurstReverseChannel1
+	if slot {
+		burstType = ipscBurstReverseChannel2
+	}
+	data := makeTestIPSCPacket(0x80, burstType, true, slot)
+	binary.BigEndian.PutUint32(data[13:17], callControl)
+	for i := range 12 {
+		data[38+i] = byte(i + 1)
+	}
+	return data
+}
+
+func TestReverseChannelJoinsCallFromIPSC(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	header := tr.TranslateToMMDVM(0x80, makeTestIPSCPacket(0x80, ipscBurstVoiceHead, true, false))
+	if len(header) != 1 {
+		t.Fatalf("expected 1 header packet, got %d", len(header))
+	}
+
+	result := tr.TranslateToMMDVM(0x80, makeReverseChannelIPSCPacket(false, 0xAAAA))
+	if len(result) != 1 {
+		t.Fatalf("expected 1 reverse-channel packet, got %d", len(result))
+	}
+	pkt := result[0]
+	if pkt.FrameType != mmdvmFrameTypeReverseChannel {
+		t.Fatalf("expected frame type %d, got %d", mmdvmFrameTypeReverseChannel, pkt.FrameType)
+	}
+	if pkt.StreamID != header[0].StreamID {
+		t.Fatalf("expected stream ID %d of the call, got %d", header[0].StreamID, pkt.StreamID)
+	}
+	for i := range 12 {
+		if pkt.DMRData[i] != byte(i+1) {
+			t.Fatalf("expected signalling octets at the start of the burst, got %v", pkt.DMRData[:12])
+		}
+	}
+	if len(tr.reverseStreams) != 1 {
+		t.Fatalf("expected the call to remain the only stream, got %d", len(tr.reverseStreams))
+	}
+	if rss := tr.reverseStreams[reverseStreamKey{peerID: 99999, callControl: 0xAAAA}]; rss.burstIndex != 0 {
+		t.Fatalf("expected the voice superframe position to be unchanged, got %d", rss.burstIndex)
+	}
+}
+
+func TestReverseChannelJoinsCallFromMasterOnSlot(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	tr.TranslateToIPSC(makeTestMMDVMPacket(true, true, mmdvmFrameTypeDataSync, uint(elements.DataTypeVoiceLCHeader)))
+
+	// The repeater interrupts under a call control of its own.
+	result := tr.TranslateToMMDVM(0x80, makeReverseChannelIPSCPacket(true, 0xBBBB))
+	if len(result) != 1 {
+		t.Fatalf("expected 1 reverse-channel packet, got %d", len(result))
+	}
+	if result[0].StreamID != 0x1234 {
+		t.Fatalf("expected stream ID 0x1234 of the call, got 0x%X", result[0].StreamID)
+	}
+	if len(tr.reverseStreams) != 0 {
+		t.Fatalf("expected no stream to be started, got %d", len(tr.reverseStreams))
+	}
+}
+
+func TestReverseChannelOutsideCall(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	if result := tr.TranslateToMMDVM(0x80, makeReverseChannelIPSCPacket(false, 0xAAAA)); result != nil {
+		t.Fatalf("expected nil outside a call, got %d packets", len(result))
+	}
+	if len(tr.reverseStreams) != 0 {
+		t.Fatalf("expected no stream to be started, got %d", len(tr.reverseStreams))
+	}
+	pkt := makeTestMMDVMPacket(true, false, mmdvmFrameTypeReverseChannel, 0)
+	if result := tr.TranslateToIPSC(pkt); result != nil {
+		t.Fatalf("expected nil outside a call, got %d packets", len(result))
+	}
+	if len(tr.streams) != 0 {
+		t.Fatalf("expected no stream to be started, got %d", len(tr.streams))
+	}
+}
+
+func TestReverseChannelToIPSC(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	header := tr.TranslateToIPSC(makeTestMMDVMPacket(true, true, mmdvmFrameTypeDataSync, uint(elements.DataTypeVoiceLCHeader)))
+	if len(header) == 0 {
+		t.Fatal("expected header packets")
+	}
+	ss := tr.streams[0x1234]
+	timestamp := ss.rtpTimestamp
+
+	pkt := makeTestMMDVMPacket(true, true, mmdvmFrameTypeReverseChannel, 0)
+	for i := range 12 {
+		pkt.DMRData[i] = byte(i + 1)
+	}
+	result := tr.TranslateToIPSC(pkt)
+	if len(result) != 1 {
+		t.Fatalf("expected 1 IPSC packet, got %d", len(result))
+	}
+	data := result[0]
+	if data[30] != ipscBurstReverseChannel2 {
+		t.Fatalf("expected burst type 0x%02X, got 0x%02X", ipscBurstReverseChannel2, data[30])
+	}
+	if got, want := binary.BigEndian.Uint32(data[13:17]), binary.BigEndian.Uint32(header[0][13:17]); got != want {
+		t.Fatalf("expected call control 0x%X of the call, got 0x%X", want, got)
+	}
+	if data[38] != 1 || data[49] != 12 {
+		t.Fatalf("expected signalling octets in the payload, got %v", data[38:50])
+	}
+	if ss.rtpTimestamp != timestamp {
+		t.Fatalf("expected the RTP timestamp to be unchanged, got %d want %d", ss.rtpTimestamp, timestamp)
+	}
+}
+
+func TestReverseChannelToIPSCInterruptsCallFromIPSC(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	header := tr.TranslateToMMDVM(0x80, makeTestIPSCPacket(0x80, ipscBurstVoiceHead, true, false))
+
+	pkt := makeTestMMDVMPacket(true, false, mmdvmFrameTypeReverseChannel, 0)
+	pkt.StreamID = header[0].StreamID
+	result := tr.TranslateToIPSC(pkt)
+	if len(result) != 1 {
+		t.Fatalf("expected 1 IPSC packet, got %d", len(result))
+	}
+	if got := binary.BigEndian.Uint32(result[0][13:17]); got != 0xAAAA {
+		t.Fatalf("expected call control 0xAAAA of the interrupted call, got 0x%X", got)
+	}
+	if len(tr.streams) != 0 {
+		t.Fatalf("expected no stream to be started, got %d", len(tr.streams))
+	}
+}
+
+func TestReverseChannelDropPolicy(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	tr.SetReverseChannel(config.ReverseChannelDrop)
+	tr.TranslateToMMDVM(0x80, makeTestIPSCPacket(0x80, ipscBurstVoiceHead, true, false))
+	tr.TranslateToIPSC(makeTestMMDVMPacket(true, true, mmdvmFrameTypeDataSync, uint(elements.DataTypeVoiceLCHeader)))
+
+	if result := tr.TranslateToMMDVM(0x80, makeReverseChannelIPSCPacket(false, 0xAAAA)); result != nil {
+		t.Fatalf("expected nil with the drop policy, got %d packets", len(result))
+	}
+	if result := tr.TranslateToIPSC(makeTestMMDVMPacket(true, true, mmdvmFrameTypeReverseChannel, 0)); result != nil {
+		t.Fatalf("expected nil with the drop policy, got %d packets", len(result))
+	}
+	if len(tr.reverseStreams) != 1 || len(tr.streams) != 1 {
+		t.Fatalf("expected both calls to continue, got %d and %d streams", len(tr.reverseStreams), len(tr.streams))
+	}
+}
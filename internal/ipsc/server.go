@@ -1,6 +1,7 @@
 package ipsc
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha1" //nolint:gosec
 	"encoding/binary"
@@ -10,31 +11,112 @@ import (
 	"log/slog"
 	"math"
 	"net"
+	"runtime/debug"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/capture"
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dscp"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/jitter"
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/metrics"
-	"github.com/vishvananda/netlink"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/netsetup"
 )
 
 type IPSCServer struct {
 	cfg     *config.Config
 	metrics *metrics.Metrics
-	udp     *net.UDPConn
-	mu      sync.RWMutex
+	// log carries the server's name, when it has one.
+	log  *slog.Logger
+	netw netsetup.NetworkSetup
+	// udp is the socket, which udpMu guards once Start has returned, as
+	// a failed socket is replaced. Use conn to read it.
+	udp   packetConn
+	udpMu sync.RWMutex
+	// listen, if set, opens the socket in place of binding one, and
+	// reopenInterval is the wait between attempts to replace a failed one.
+	listen         func() (packetConn, error)
+	reopenInterval time.Duration
+
+	mu sync.RWMutex
+
+	// createdLink is the interface Start created, deleted again on Stop.
+	createdLink string
 
 	localID  uint32
 	authKey  []byte // 20-byte HMAC key decoded from hex
 	peers    map[uint32]*Peer
 	lastSend map[uint32]time.Time
-
-	burstHandler func(packetType byte, data []byte, addr *net.UDPAddr)
-
-	wg       sync.WaitGroup
-	stopped  atomic.Bool
-	stopOnce sync.Once
+	now      func() time.Time // times peer liveness and authentication bans
+	// jitter spreads the keepalives, probes, and pruning of servers
+	// started together, drawing from rand.Float64 when nil.
+	jitter jitter.Source
+	// dscpSetter marks the socket, through setsockopt when nil.
+	dscpSetter dscp.Setter
+
+	// peerKeys are the keys of peers that do not use authKey. With
+	// requirePeerKey, peers without one are refused.
+	peerKeys       map[uint32][]byte
+	requirePeerKey bool
+	// keyMu guards authKey and previousKey, which SetAuthKey swaps at
+	// runtime. previousKey is still accepted until previousKeyUntil.
+	keyMu            sync.RWMutex
+	previousKey      []byte
+	previousKeyUntil time.Time
+	// authFailures are the failed authentications by source address,
+	// buckets the rate limit of each source IP, and unregisteredLogged
+	// when a refused control request from each address was last logged.
+	authFailures       map[string]*authFailures
+	buckets            map[string]*tokenBucket
+	unregisteredLogged map[string]time.Time
+
+	// rcmCalls are the calls on each timeslot announced to call
+	// monitoring peers, and rcmSequence numbers the announcements.
+	rcmCalls    [2]rcmCall
+	rcmSequence uint32
+
+	// ars intercepts ARS registrations when they are not forwarded, and
+	// radioCheckIDs are the IDs whose radio checks are answered locally.
+	// localTranslator encodes the replies to both.
+	ars             *arsFilter
+	radioCheckIDs   map[uint32]struct{}
+	localTranslator *IPSCTranslator
+
+	// allowedPeers, when not nil, are the only peer IDs that may register,
+	// and deniedPeers may never register.
+	allowedPeers map[uint32]struct{}
+	deniedPeers  map[uint32]struct{}
+
+	// handlers handle each packet type the server understands.
+	handlers map[PacketType]packetHandler
+	// capture, if set, records every packet received and sent.
+	capture *capture.Writer
+
+	burstHandler     func(packetType byte, data []byte, addr *net.UDPAddr)
+	peerLostHandler  func(peerID uint32)
+	peerEventHandler func(event PeerEvent)
+	panicHandler     func(recovered any, stack []byte)
+
+	// lifecycleMu serializes Start and Stop so the server can be stopped
+	// and started again, as the supervisor does after a panic.
+	lifecycleMu sync.Mutex
+	wg          sync.WaitGroup
+	running     atomic.Bool
+	stopped     atomic.Bool
+	stopOnce    sync.Once
+	// inflight counts the packets being handled. inflightMu keeps a
+	// packet from being counted once shutdown has started waiting.
+	inflight   sync.WaitGroup
+	inflightMu sync.Mutex
+	// loopStop stops the loops that prune and probe peers and keep the
+	// registration with the master.
+	loopStop chan struct{}
+
+	// master is the master registered with in the peer role, and
+	// masterKeepalive how often it is sent a keepalive.
+	master          *masterLink
+	masterKeepalive time.Duration
 }
 
 type Packet struct {
@@ -47,8 +129,23 @@ type Peer struct {
 	Mode               byte
 	Flags              [4]byte
 	LastSeen           time.Time
-	KeepAliveReceived  uint64
 	RegistrationStatus bool
+	// Provisional is set on peers restored from a state snapshot. They
+	// receive traffic as usual but have not been heard from since the
+	// restart; the flag clears on their next keepalive or registration.
+	Provisional bool
+	// ProbesMissed counts the alive probes in a row the peer has left
+	// unanswered. Hearing from the peer at all resets it.
+	ProbesMissed int
+	probePending bool
+	// CallMonitor is what the peer last reported through repeater call
+	// monitoring, by timeslot.
+	CallMonitor [2]RCMSlotState
+	PeerStats
+
+	// skipped counts packets withheld because of the peer's advertised
+	// capabilities, by reason. It resets when the peer registers again.
+	skipped map[skipReason]uint64
 }
 
 type PacketType byte
@@ -63,10 +160,20 @@ const (
 	PacketType_MasterRegisterReply   PacketType = 0x91
 	PacketType_PeerListRequest       PacketType = 0x92
 	PacketType_PeerListReply         PacketType = 0x93
+	PacketType_PeerRegisterRequest   PacketType = 0x94
+	PacketType_PeerRegisterReply     PacketType = 0x95
 	PacketType_MasterAliveRequest    PacketType = 0x96
 	PacketType_MasterAliveReply      PacketType = 0x97
+	PacketType_PeerAliveRequest      PacketType = 0x98
+	PacketType_PeerAliveReply        PacketType = 0x99
+	PacketType_DeRegisterRequest     PacketType = 0x9A
+	PacketType_DeRegisterReply       PacketType = 0x9B
 )
 
+// authDigestSize is the length of the truncated HMAC-SHA1 digest that
+// ends every packet when authentication is enabled.
+const authDigestSize = 10
+
 var (
 	//nolint:gochecknoglobals
 	ipscVersion = []byte{0x04, 0x02, 0x04, 0x01}
@@ -74,22 +181,47 @@ var (
 
 var ErrPacketIgnored = errors.New("packet ignored")
 
+// ErrNoPeerKey is returned for a packet from a peer with no key of its own
+// when every peer must have one.
+var ErrNoPeerKey = errors.New("no authentication key for peer")
+
+// ErrInvalidAuthKey is returned for an authentication key that is not up
+// to 40 hex characters.
+var ErrInvalidAuthKey = errors.New("authentication key must be up to 40 hex characters")
+
+// parseAuthKey decodes a hex auth key to raw bytes. DMRlink left-pads the
+// hex key to 40 characters (20 bytes) with zeros.
+func parseAuthKey(key string) ([]byte, error) {
+	if len(key) > 40 {
+		return nil, ErrInvalidAuthKey
+	}
+	hexKey := key
+	// Left-pad with zeros to 40 hex characters (20 bytes)
+	for len(hexKey) < 40 {
+		hexKey = "0" + hexKey
+	}
+	authKey, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAuthKey, err)
+	}
+	return authKey, nil
+}
+
+// decodeAuthKey is parseAuthKey for configured keys, which have been
+// validated already.
+func decodeAuthKey(key string) []byte {
+	authKey, err := parseAuthKey(key)
+	if err != nil {
+		slog.Error("failed to decode IPSC auth key as hex, using raw string", "error", err)
+		return []byte(key)
+	}
+	return authKey
+}
+
 func NewIPSCServer(cfg *config.Config, m *metrics.Metrics) *IPSCServer {
-	// Decode the auth key from hex string to raw bytes.
-	// DMRlink left-pads the hex key to 40 characters (20 bytes) with zeros.
 	var authKey []byte
 	if cfg.IPSC.Auth.Enabled && cfg.IPSC.Auth.Key != "" {
-		hexKey := cfg.IPSC.Auth.Key
-		// Left-pad with zeros to 40 hex characters (20 bytes)
-		for len(hexKey) < 40 {
-			hexKey = "0" + hexKey
-		}
-		var err error
-		authKey, err = hex.DecodeString(hexKey)
-		if err != nil {
-			slog.Error("failed to decode IPSC auth key as hex, using raw string", "error", err)
-			authKey = []byte(cfg.IPSC.Auth.Key)
-		}
+		authKey = decodeAuthKey(cfg.IPSC.Auth.Key)
 	}
 
 	// Use the first MMDVM network's ID as the local peer identity.
@@ -98,320 +230,803 @@ func NewIPSCServer(cfg *config.Config, m *metrics.Metrics) *IPSCServer {
 		localID = cfg.MMDVM[0].ID
 	}
 
-	return &IPSCServer{
+	log := slog.Default()
+	if cfg.IPSC.Name != "" {
+		log = log.With("ipsc", cfg.IPSC.Name)
+	}
+
+	s := &IPSCServer{
 		cfg:      cfg,
 		metrics:  m,
+		log:      log,
+		netw:     netsetup.New(),
 		localID:  localID,
 		authKey:  authKey,
 		peers:    map[uint32]*Peer{},
 		lastSend: map[uint32]time.Time{},
+		now:      time.Now,
+
+		authFailures:       map[string]*authFailures{},
+		buckets:            map[string]*tokenBucket{},
+		unregisteredLogged: map[string]time.Time{},
+		ars:                newARSFilter(cfg.IPSC.ARS),
+
+		masterKeepalive: defaultMasterKeepalive,
+		reopenInterval:  defaultReopenInterval,
+	}
+	if cfg.IPSC.Auth.Enabled {
+		s.requirePeerKey = cfg.IPSC.Auth.RequirePeerKey
+		if len(cfg.IPSC.Auth.PeerKeys) > 0 {
+			s.peerKeys = make(map[uint32][]byte, len(cfg.IPSC.Auth.PeerKeys))
+			for _, pk := range cfg.IPSC.Auth.PeerKeys {
+				s.peerKeys[pk.PeerID] = decodeAuthKey(pk.Key)
+			}
+		}
+	}
+	s.handlers = s.packetHandlers()
+	s.allowedPeers = idSet(cfg.IPSC.AllowedPeers)
+	s.deniedPeers = idSet(cfg.IPSC.DeniedPeers)
+	if len(cfg.IPSC.RadioCheck.LocalIDs) > 0 {
+		s.radioCheckIDs = make(map[uint32]struct{}, len(cfg.IPSC.RadioCheck.LocalIDs))
+		for _, id := range cfg.IPSC.RadioCheck.LocalIDs {
+			s.radioCheckIDs[id] = struct{}{}
+		}
+	}
+	if (s.ars != nil && s.ars.policy == config.ARSPolicyAckLocally) || s.radioCheckIDs != nil {
+		translator, err := NewIPSCTranslator()
+		if err != nil {
+			s.log.Error("failed to create translator for local replies, dropping ARS registrations and forwarding radio checks instead", "error", err)
+			if s.ars != nil {
+				s.ars.policy = config.ARSPolicyDrop
+			}
+			s.radioCheckIDs = nil
+		} else {
+			translator.SetRTP(cfg.IPSC.RTP)
+			s.localTranslator = translator
+		}
 	}
+	return s
+}
+
+// SetLocalID overrides the peer ID the server identifies itself with.
+// It must be called before Start.
+func (s *IPSCServer) SetLocalID(id uint32) {
+	s.localID = id
 }
 
+// Start binds the socket and starts the receive loop. Calling Start on a
+// running server does nothing; a stopped server can be started again.
 func (s *IPSCServer) Start() error {
-	if err := s.netlink(); err != nil {
-		return fmt.Errorf("error configuring network: %w", err)
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+	if s.running.Load() {
+		return nil
+	}
+	s.stopOnce = sync.Once{}
+	s.stopped.Store(false)
+	if s.localTranslator != nil {
+		s.localTranslator.SetPeerID(s.localID)
 	}
 
-	var err error
-	s.udp, err = net.ListenUDP("udp", &net.UDPAddr{
-		IP:   net.ParseIP(s.cfg.IPSC.IP),
-		Port: int(s.cfg.IPSC.Port),
-	})
+	// Interface configuration is skipped when no interface is configured,
+	// which is the case for in-process harnesses like the self-test, or
+	// when the operator has configured the interface themselves or binds
+	// to an address of their choosing.
+	bindIP := s.cfg.IPSC.IP
+	if s.cfg.IPSC.BindAddress != "" {
+		bindIP = s.cfg.IPSC.BindAddress
+	} else if s.cfg.IPSC.Interface != "" && !s.cfg.IPSC.BindOnly {
+		err := s.configureNetwork()
+		switch {
+		case errors.Is(err, netsetup.ErrUnsupportedPlatform):
+			// Without interface management, fall back to bind-only mode
+			// rather than refusing to start.
+			s.log.Warn("Interface configuration is not supported on this platform, binding to the configured address only",
+				"interface", s.cfg.IPSC.Interface, "ip", s.cfg.IPSC.IP, "error", err)
+		case err != nil:
+			return fmt.Errorf("error configuring network: %w", err)
+		}
+	}
 
+	s.master = nil
+	if s.cfg.IPSC.Role == config.IPSCRolePeer {
+		addr, err := net.ResolveUDPAddr("udp", s.cfg.IPSC.MasterAddress)
+		if err != nil {
+			s.removeLink()
+			return fmt.Errorf("error resolving IPSC master address: %w", err)
+		}
+		s.master = &masterLink{addr: addr}
+	}
+
+	conn, err := s.openSocket(bindIP)
 	if err != nil {
-		return fmt.Errorf("error starting UDP listener: %w", err)
+		s.removeLink()
+		return err
 	}
+	s.setConn(conn)
+	s.log.Info("IPSC server listening", "address", conn.LocalAddr())
 
+	s.running.Store(true)
+	s.loopStop = make(chan struct{})
 	s.wg.Add(1)
-	go s.handler()
+	go s.handler(s.loopStop)
+
+	if s.cfg.IPSC.PeerTimeout > 0 {
+		s.wg.Add(1)
+		go s.pruneLoop(time.Duration(s.cfg.IPSC.PeerTimeout)*time.Second, s.loopStop)
+	}
+	if s.cfg.IPSC.ProbeInterval > 0 {
+		s.wg.Add(1)
+		go s.probeLoop(time.Duration(s.cfg.IPSC.ProbeInterval)*time.Second, s.loopStop)
+	}
+	if s.master != nil {
+		s.wg.Add(1)
+		go s.masterLoop(s.master.addr, s.loopStop)
+	}
 
 	return nil
 }
 
+// Name returns the name of the server in the supervisor and health
+// checks: ipsc, followed by the name it is configured with, if any.
+func (s *IPSCServer) Name() string {
+	if s.cfg.IPSC.Name == "" {
+		return "ipsc"
+	}
+	return "ipsc/" + s.cfg.IPSC.Name
+}
+
+// Addr returns the local address the server is listening on, or nil
+// if the server has not been started.
+func (s *IPSCServer) Addr() *net.UDPAddr {
+	conn := s.conn()
+	if conn == nil {
+		return nil
+	}
+	addr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return nil
+	}
+	return addr
+}
+
+// Running reports whether the server's socket is bound and its receive
+// loop is running.
+func (s *IPSCServer) Running() bool {
+	return s.running.Load()
+}
+
+// Stop closes the socket and waits for in-flight packets to be handled.
+// It is safe to call more than once.
 func (s *IPSCServer) Stop() {
+	_ = s.shutdown(context.Background(), false)
+}
+
+// Shutdown stops the server gracefully: it stops accepting packets, tells
+// every registered peer it is going away, waits for the packets being
+// handled until ctx is done, and closes the socket. The error is ctx's if
+// it ended the wait. Like Stop, it is safe to call more than once.
+func (s *IPSCServer) Shutdown(ctx context.Context) error {
+	return s.shutdown(ctx, true)
+}
+
+func (s *IPSCServer) shutdown(ctx context.Context, notify bool) error {
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+	var err error
 	s.stopOnce.Do(func() {
-		slog.Info("Stopping IPSC server")
+		s.log.Info("Stopping IPSC server")
+		s.inflightMu.Lock()
 		s.stopped.Store(true)
-		if s.udp != nil {
-			if err := s.udp.Close(); err != nil {
-				slog.Error("error closing UDP listener", "error", err)
+		s.inflightMu.Unlock()
+		if s.loopStop != nil {
+			close(s.loopStop)
+			s.loopStop = nil
+		}
+		s.leaveMaster()
+		if notify {
+			s.notifyPeersClosing()
+		}
+		err = s.waitInflight(ctx)
+		// A socket that failed and was not replaced is closed already.
+		if conn := s.conn(); conn != nil {
+			if err := conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+				s.log.Error("error closing UDP listener", "error", err)
 			}
 		}
+		s.removeLink()
 	})
 	s.wg.Wait()
+	return err
 }
 
-func (s *IPSCServer) netlink() error {
-	link, err := netlink.LinkByName(s.cfg.IPSC.Interface)
-	if err != nil {
-		return fmt.Errorf("cannot find interface %s: %w", s.cfg.IPSC.Interface, err)
+// notifyPeersClosing sends a de-registration request to every registered
+// peer so they drop the server at once instead of waiting for it to time
+// out. The master in the peer role is told by leaveMaster. Peers told are
+// forgotten, so they are not restored as registered on the next start.
+func (s *IPSCServer) notifyPeersClosing() {
+	if s.conn() == nil {
+		return
 	}
-
-	// Remove any existing addresses from the interface
-	existingAddrs, err := netlink.AddrList(link, netlink.FAMILY_ALL)
-	if err != nil {
-		return fmt.Errorf("cannot list addresses on interface %s: %w", s.cfg.IPSC.Interface, err)
+	s.mu.RLock()
+	addrs := make(map[uint32]*net.UDPAddr, len(s.peers))
+	for _, peer := range s.peers {
+		if peer.Addr == nil || !peer.RegistrationStatus {
+			continue
+		}
+		if s.master != nil && s.master.registered && s.master.id == peer.ID {
+			continue
+		}
+		addrs[peer.ID] = cloneUDPAddr(peer.Addr)
 	}
-	for i := range existingAddrs {
-		if err := netlink.AddrDel(link, &existingAddrs[i]); err != nil {
-			return fmt.Errorf("cannot remove address %s from interface %s: %w", existingAddrs[i].IPNet, s.cfg.IPSC.Interface, err)
+	s.mu.RUnlock()
+
+	told := make([]uint32, 0, len(addrs))
+	for peerID, addr := range addrs {
+		packet := &Packet{data: append([]byte{byte(PacketType_DeRegisterRequest)}, s.localIDBytes()...)}
+		if err := s.sendPacket(packet, addr); err != nil {
+			s.log.Warn("failed de-registering from IPSC peer", "peer", addr, "error", err)
+			continue
 		}
+		told = append(told, peerID)
 	}
 
-	if err := netlink.AddrReplace(link, &netlink.Addr{IPNet: &net.IPNet{IP: net.ParseIP(s.cfg.IPSC.IP), Mask: net.CIDRMask(s.cfg.IPSC.SubnetMask, 32)}}); err != nil {
-		return fmt.Errorf("cannot add IP address to interface %s: %w", s.cfg.IPSC.Interface, err)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, peerID := range told {
+		delete(s.peers, peerID)
+		delete(s.lastSend, peerID)
+	}
+	if s.metrics != nil {
+		s.metrics.IPSCPeersRegistered.Set(float64(len(s.peers)))
+	}
+}
+
+// waitInflight waits for the packets being handled, or for ctx to be done.
+func (s *IPSCServer) waitInflight(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.log.Warn("IPSC server stopped with packets still being handled", "error", ctx.Err())
+		return ctx.Err()
 	}
+}
 
-	if link.Attrs().Flags&net.FlagUp == 0 {
-		if err := netlink.LinkSetUp(link); err != nil {
-			return fmt.Errorf("cannot set interface up %s: %w", s.cfg.IPSC.Interface, err)
+func (s *IPSCServer) configureNetwork() error {
+	if !s.cfg.IPSC.ExistingInterface {
+		if err := s.ensureLink(s.cfg.IPSC.Interface); err != nil {
+			return err
 		}
 	}
+	if err := s.netw.EnsureAddress(s.cfg.IPSC.Interface, net.ParseIP(s.cfg.IPSC.IP), s.cfg.IPSC.SubnetMask); err != nil {
+		s.removeLink()
+		return err
+	}
+	return nil
+}
 
+// ensureLink creates the interface name as a dummy interface if it does
+// not exist. An interface that is already there, whether from the start
+// or created by someone else in the meantime, is adopted and left alone
+// on Stop.
+func (s *IPSCServer) ensureLink(name string) error {
+	exists, err := s.netw.LinkExists(name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	err = s.netw.CreateDummy(name)
+	switch {
+	case errors.Is(err, netsetup.ErrLinkExists):
+		return nil
+	case err != nil:
+		return err
+	}
+	s.createdLink = name
+	s.log.Info("Created IPSC interface", "interface", name)
 	return nil
 }
 
-func (s *IPSCServer) handler() {
+// removeLink deletes the interface Start created, if it created one.
+func (s *IPSCServer) removeLink() {
+	if s.createdLink == "" {
+		return
+	}
+	if err := s.netw.Delete(s.createdLink); err != nil {
+		s.log.Error("error deleting IPSC interface", "interface", s.createdLink, "error", err)
+	} else {
+		s.log.Info("Deleted IPSC interface", "interface", s.createdLink)
+	}
+	s.createdLink = ""
+}
+
+// SetPanicHandler registers fn to be called when one of the server's
+// goroutines panics, instead of crashing the process. fn must not block;
+// the server is left for the caller to stop and restart. It must be
+// called before Start.
+func (s *IPSCServer) SetPanicHandler(fn func(recovered any, stack []byte)) {
+	s.panicHandler = fn
+}
+
+// recoverPanic reports a panic to the panic handler. It must be deferred
+// directly. Without a handler the panic propagates as usual.
+func (s *IPSCServer) recoverPanic() {
+	if s.panicHandler == nil {
+		return
+	}
+	if r := recover(); r != nil {
+		s.panicHandler(r, debug.Stack())
+	}
+}
+
+// handler reads packets and hands them to the receive workers until the
+// socket is closed. stop cuts short waiting for a busy worker.
+func (s *IPSCServer) handler(stop <-chan struct{}) {
 	defer s.wg.Done()
-	buf := make([]byte, 1500)
+	defer s.running.Store(false)
+	defer s.recoverPanic()
+	queues := s.startReceiveWorkers()
+	defer func() {
+		for _, queue := range queues {
+			close(queue)
+		}
+	}()
+	// failures counts the reads in a row that failed.
+	failures := 0
 	for {
-		n, addr, err := s.udp.ReadFromUDP(buf)
+		conn := s.conn()
+		buf := getPacketBuffer()
+		n, addr, err := conn.ReadFromUDP(*buf)
 		if err != nil {
-			if errors.Is(err, net.ErrClosed) {
+			packetBuffers.Put(buf)
+			// Only shutdown closes the socket under the loop.
+			if s.stopped.Load() || errors.Is(err, net.ErrClosed) {
 				return
 			}
-			if s.metrics != nil {
-				s.metrics.IPSCUDPErrors.WithLabelValues("read").Inc()
-			}
-			slog.Warn("error reading from UDP", "error", err)
-			continue
-		}
-		data := make([]byte, n)
-		copy(data, buf[:n])
-
-		s.wg.Add(1)
-		go func(packetData []byte, packetAddr *net.UDPAddr) {
-			defer s.wg.Done()
-			packet, err := s.handlePacket(packetData, packetAddr)
-			if err != nil {
-				if errors.Is(err, ErrPacketIgnored) {
-					return
-				}
-				slog.Warn("error parsing packet", "peer", packetAddr, "error", err, "length", len(packetData), "packet", packetData)
+			failures++
+			if !s.readFailed(conn, err, failures, stop) {
 				return
 			}
-
-			slog.Debug("received packet", "peer", packetAddr, "length", len(packetData), "packet", packet)
-		}(data, addr)
-	}
-}
-
-func (s *IPSCServer) handlePacket(data []byte, addr *net.UDPAddr) (*Packet, error) {
-	if len(data) < 1 {
-		return nil, fmt.Errorf("packet too short")
-	}
-
-	packetType := data[0]
-
-	if s.cfg.IPSC.Auth.Enabled {
-		if len(data) <= 10 {
-			return nil, fmt.Errorf("packet too short for authentication")
-		}
-		if !s.auth(data) {
-			if s.metrics != nil {
-				s.metrics.IPSCAuthFailures.Inc()
+			if fatalReadError(err) || failures > readRetries {
+				failures = 0
 			}
-			return nil, fmt.Errorf("authentication failed")
+			continue
 		}
-		data = data[:len(data)-10] // Remove the hash from the data
-	}
+		failures = 0
 
-	switch PacketType(packetType) {
-	case PacketType_GroupVoice:
-		if s.metrics != nil {
-			s.metrics.IPSCPacketsReceived.WithLabelValues("group_voice").Inc()
-		}
-		if err := s.handleUserPacket(PacketType(packetType), data, addr); err != nil {
-			return nil, err
-		}
-	case PacketType_PrivateVoice:
-		if s.metrics != nil {
-			s.metrics.IPSCPacketsReceived.WithLabelValues("private_voice").Inc()
-		}
-		if err := s.handleUserPacket(PacketType(packetType), data, addr); err != nil {
-			return nil, err
-		}
-	case PacketType_GroupData:
-		if s.metrics != nil {
-			s.metrics.IPSCPacketsReceived.WithLabelValues("group_data").Inc()
-		}
-		if err := s.handleUserPacket(PacketType(packetType), data, addr); err != nil {
-			return nil, err
-		}
-	case PacketType_PrivateData:
-		if s.metrics != nil {
-			s.metrics.IPSCPacketsReceived.WithLabelValues("private_data").Inc()
-		}
-		if err := s.handleUserPacket(PacketType(packetType), data, addr); err != nil {
-			return nil, err
-		}
-	case PacketType_RepeaterWakeUp:
-		if s.metrics != nil {
-			s.metrics.IPSCPacketsReceived.WithLabelValues("wake_up").Inc()
-		}
-		if err := s.handleRepeaterWakeUp(data, addr); err != nil {
-			return nil, err
-		}
-	case PacketType_MasterRegisterRequest:
-		if s.metrics != nil {
-			s.metrics.IPSCPacketsReceived.WithLabelValues("register").Inc()
-		}
-		if err := s.handleMasterRegisterRequest(data, addr); err != nil {
-			return nil, err
-		}
-	case PacketType_MasterAliveRequest:
-		if s.metrics != nil {
-			s.metrics.IPSCPacketsReceived.WithLabelValues("alive").Inc()
-		}
-		if err := s.handleMasterAliveRequest(data, addr); err != nil {
-			return nil, err
-		}
-	case PacketType_PeerListRequest:
-		if s.metrics != nil {
-			s.metrics.IPSCPacketsReceived.WithLabelValues("peer_list").Inc()
-		}
-		if err := s.handlePeerListRequest(data, addr); err != nil {
-			return nil, err
+		// Once shutting down, packets are no longer accepted.
+		s.inflightMu.Lock()
+		if s.stopped.Load() {
+			s.inflightMu.Unlock()
+			packetBuffers.Put(buf)
+			continue
 		}
-	case PacketType_MasterRegisterReply, PacketType_PeerListReply, PacketType_MasterAliveReply:
-		// These are reply packets, we shouldn't receive them as a server, keeping quiet.
-		return nil, ErrPacketIgnored
-	default:
-		if s.metrics != nil {
-			s.metrics.IPSCPacketsReceived.WithLabelValues("other").Inc()
+		s.inflight.Add(1)
+		s.inflightMu.Unlock()
+		select {
+		case queues[receiveWorkerFor(addr, len(queues))] <- receivedPacket{buf: buf, n: n, addr: addr, at: s.now()}:
+		case <-stop:
+			packetBuffers.Put(buf)
+			s.inflight.Done()
 		}
-		return nil, fmt.Errorf("unknown packet type: %d", packetType)
 	}
-
-	return &Packet{data: data}, nil
 }
 
-func (s *IPSCServer) handleMasterRegisterRequest(data []byte, addr *net.UDPAddr) error {
-	peerID, err := parsePeerID(data)
-	if err != nil {
-		return err
-	}
-
+func (s *IPSCServer) handleMasterRegisterRequest(env packetEnvelope) ([][]byte, error) {
 	mode := s.defaultModeByte()
 	flags := s.defaultFlagsBytes()
-	if len(data) >= 10 {
-		mode = data[5]
-		copy(flags[:], data[6:10])
+	if len(env.Data) >= 10 {
+		mode = env.Data[5]
+		copy(flags[:], env.Data[6:10])
 	}
 
-	s.upsertPeer(peerID, addr, mode, flags)
-
-	packet := &Packet{data: s.buildMasterRegisterReply()}
-	if err := s.sendPacket(packet, addr); err != nil {
-		return fmt.Errorf("error sending master register reply: %w", err)
+	if !s.upsertPeer(env.PeerID, env.Addr, mode, flags) {
+		return nil, ErrPacketIgnored
 	}
-
-	return nil
+	return [][]byte{s.buildMasterRegisterReply()}, nil
 }
 
-func (s *IPSCServer) handleMasterAliveRequest(data []byte, addr *net.UDPAddr) error {
-	peerID, err := parsePeerID(data)
-	if err != nil {
-		return err
+// idSet returns ids as a set, or nil if there are none.
+func idSet(ids []uint32) map[uint32]struct{} {
+	if len(ids) == 0 {
+		return nil
 	}
+	set := make(map[uint32]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
 
-	s.markPeerAlive(peerID, addr)
-
-	packet := &Packet{data: s.buildMasterAliveReply()}
-	if err := s.sendPacket(packet, addr); err != nil {
-		return fmt.Errorf("error sending master alive reply: %w", err)
+// peerAllowed reports whether peerID may send a packet of packetType,
+// logging refused registrations. IPSC has no way to reject a
+// registration, so a refused peer simply gets no reply, and none of its
+// other traffic is recorded or forwarded.
+func (s *IPSCServer) peerAllowed(packetType PacketType, peerID uint32, addr *net.UDPAddr) bool {
+	_, denied := s.deniedPeers[peerID]
+	_, allowed := s.allowedPeers[peerID]
+	if !denied && (s.allowedPeers == nil || allowed) {
+		return true
 	}
+	switch packetType {
+	case PacketType_MasterRegisterRequest, PacketType_PeerRegisterRequest:
+		s.log.Warn("IPSC peer registration refused", "peer", addr, "peerID", peerID)
+	default:
+		s.log.Debug("Ignoring packet from a refused IPSC peer", "peer", addr, "peerID", peerID, "packetType", byte(packetType))
+	}
+	return false
+}
 
-	return nil
+func (s *IPSCServer) handleMasterAliveRequest(env packetEnvelope) ([][]byte, error) {
+	if !s.registeredSender(env) {
+		return nil, ErrPacketIgnored
+	}
+	s.keepPeerAlive(env.PeerID, env.Addr, env.Data)
+	return [][]byte{s.buildMasterAliveReply()}, nil
 }
 
-func (s *IPSCServer) handlePeerListRequest(data []byte, addr *net.UDPAddr) error {
-	if _, err := parsePeerID(data); err != nil {
-		return err
+func (s *IPSCServer) handleDeRegisterRequest(env packetEnvelope) ([][]byte, error) {
+	lost, ok, err := s.removePeer(env.PeerID, env.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		s.log.Info("IPSC peer de-registered", "peer", env.Addr, "peerID", env.PeerID)
+		s.peerLost(lost)
 	}
+	s.masterLeft(env.PeerID, env.Addr)
+	return [][]byte{s.buildDeRegisterReply()}, nil
+}
 
-	packet := &Packet{data: s.buildPeerListReply()}
-	if err := s.sendPacket(packet, addr); err != nil {
-		return fmt.Errorf("error sending peer list reply: %w", err)
+func (s *IPSCServer) handlePeerListRequest(env packetEnvelope) ([][]byte, error) {
+	if !s.registeredSender(env) {
+		return nil, ErrPacketIgnored
 	}
+	return s.buildPeerListReplies(), nil
+}
 
-	return nil
+func (s *IPSCServer) handleRepeaterWakeUp(env packetEnvelope) ([][]byte, error) {
+	s.markPeerAlive(env.PeerID, env.Addr)
+	s.log.Debug("repeater wake-up packet received", "peer", env.Addr, "peerID", env.PeerID, "length", len(env.Data))
+	return nil, nil
 }
 
-func (s *IPSCServer) handleRepeaterWakeUp(data []byte, addr *net.UDPAddr) error {
-	peerID, err := parsePeerID(data)
-	if err != nil {
-		return err
-	}
+func (s *IPSCServer) handleUserPacket(env packetEnvelope) ([][]byte, error) {
+	packetType, data, addr, peerID := env.Type, env.Data, env.Addr, env.PeerID
 
 	s.markPeerAlive(peerID, addr)
-	slog.Debug("repeater wake-up packet received", "peer", addr, "peerID", peerID, "length", len(data))
-	return nil
+	if s.ars != nil && s.ars.matches(packetType, data) {
+		s.handleARS(packetType, peerID, data, addr)
+		return nil, nil
+	}
+	if s.radioCheckIDs != nil && s.answerRadioCheck(packetType, data, addr) {
+		return nil, nil
+	}
+	s.forwardBurst(packetType, peerID, data, addr)
+	return nil, nil
 }
 
-func (s *IPSCServer) handleUserPacket(packetType PacketType, data []byte, addr *net.UDPAddr) error {
-	peerID, err := parsePeerID(data)
-	if err != nil {
-		return err
+// forwardBurst hands a user packet to the burst handler.
+func (s *IPSCServer) forwardBurst(packetType PacketType, peerID uint32, data []byte, addr *net.UDPAddr) {
+	s.log.Debug("IPSC burst received", "peer", addr, "peerID", peerID, "packetType", byte(packetType), "length", len(data))
+	// In the peer role, peers of the master send to each other directly.
+	if s.cfg.IPSC.RepeatToPeers && s.master == nil {
+		s.repeatToPeers(data, addr)
 	}
-
-	s.markPeerAlive(peerID, addr)
-	slog.Debug("IPSC burst received", "peer", addr, "peerID", peerID, "packetType", byte(packetType), "length", len(data))
 	if s.burstHandler != nil {
 		packetCopy := make([]byte, len(data))
 		copy(packetCopy, data)
-		go s.burstHandler(byte(packetType), packetCopy, addr)
+		// Handled in line, so the bursts of a call reach the handler
+		// in the order they arrived.
+		s.handleBurst(byte(packetType), packetCopy, addr)
 	}
-	return nil
 }
 
+func (s *IPSCServer) handleBurst(packetType byte, data []byte, addr *net.UDPAddr) {
+	defer s.recoverPanic()
+	s.burstHandler(packetType, data, addr)
+}
+
+// SetBurstHandler registers handler to be called with each voice and data
+// packet from a peer. It is called from the receive worker of the peer's
+// address, in the order the packets arrived, so it should not block.
 func (s *IPSCServer) SetBurstHandler(handler func(packetType byte, data []byte, addr *net.UDPAddr)) {
 	s.burstHandler = handler
 }
 
-func (s *IPSCServer) upsertPeer(peerID uint32, addr *net.UDPAddr, mode byte, flags [4]byte) {
+// SetPeerLostHandler registers fn to be called when a peer goes away:
+// it de-registers, stops sending keepalives for longer than the
+// configured peer timeout, or re-registers from a different address.
+// Calls the peer had in progress will not be ended by the peer itself.
+// It must be called before Start.
+func (s *IPSCServer) SetPeerLostHandler(fn func(peerID uint32)) {
+	s.peerLostHandler = fn
+}
+
+// peerLost reports lost, a snapshot of a peer that is gone, to the peer
+// lost and peer event handlers.
+func (s *IPSCServer) peerLost(lost PeerEvent) {
+	if s.peerLostHandler != nil {
+		s.peerLostHandler(lost.PeerID)
+	}
+	s.firePeerEvents(lost)
+}
+
+// upsertPeer records a registration and reports whether it was accepted.
+// A registration moving a peer to another address is refused unless the
+// address takeover policy allows it.
+func (s *IPSCServer) upsertPeer(peerID uint32, addr *net.UDPAddr, mode byte, flags [4]byte) bool {
+	event, lost, replaced, ok := s.registerPeer(peerID, addr, mode, flags)
+	if !ok {
+		return false
+	}
+	if replaced {
+		// The peer ID is now in use from another address; whatever the
+		// previous holder was doing is over.
+		s.peerLost(lost)
+	}
+	s.firePeerEvents(event)
+	return true
+}
+
+// registerPeer records a registration and returns its event. If it
+// replaced a peer with the same ID at a different address, it also
+// returns the lost event of that peer and true. It returns false, and
+// changes nothing, for a registration the address takeover policy
+// refuses.
+func (s *IPSCServer) registerPeer(peerID uint32, addr *net.UDPAddr, mode byte, flags [4]byte) (event, lost PeerEvent, replaced, ok bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	peer, ok := s.peers[peerID]
-	if !ok {
+	eventType := PeerRegistered
+	peer, known := s.peers[peerID]
+	switch {
+	case !known:
+		if s.peerTableFull(peerID, addr) {
+			return PeerEvent{}, PeerEvent{}, false, false
+		}
 		peer = &Peer{ID: peerID}
 		s.peers[peerID] = peer
+	case peer.Addr != nil && addr != nil && peer.Addr.String() != addr.String():
+		if !s.addressTakeover(peer, addr) {
+			return PeerEvent{}, PeerEvent{}, false, false
+		}
+		lost = peerEvent(PeerLost, peer)
+		replaced = true
+	case !peer.RegistrationStatus || peer.Provisional:
+	case peer.Mode != mode || peer.Flags != flags:
+		eventType = PeerUpdated
+	default:
+		eventType = PeerKeepAlive
 	}
 	peer.Addr = cloneUDPAddr(addr)
 	peer.Mode = mode
 	peer.Flags = flags
-	peer.LastSeen = time.Now()
+	peer.LastSeen = s.now()
 	peer.RegistrationStatus = true
+	peer.Provisional = false
+	peer.skipped = nil
+	peer.heardFrom()
+
+	if s.metrics != nil {
+		s.metrics.IPSCPeersRegistered.Set(float64(len(s.peers)))
+	}
+	return peerEvent(eventType, peer), lost, replaced, true
+}
+
+// removePeer forgets a peer de-registering from addr and returns its lost
+// event, reporting whether it was known. A peer registered from another
+// address is kept, so no one can de-register a peer by sending its ID,
+// and ErrPacketIgnored returned.
+func (s *IPSCServer) removePeer(peerID uint32, addr *net.UDPAddr) (PeerEvent, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	peer, ok := s.peers[peerID]
+	if !ok {
+		return PeerEvent{}, false, nil
+	}
+	if peer.Addr != nil && (addr == nil || peer.Addr.String() != addr.String()) {
+		if s.refusalLogDue(addr) {
+			s.log.Warn("Ignoring IPSC de-registration from an address the peer is not registered from",
+				"peer", addr, "peerID", peerID, "registered", peer.Addr)
+		}
+		return PeerEvent{}, false, ErrPacketIgnored
+	}
+	delete(s.peers, peerID)
+	delete(s.lastSend, peerID)
 	if s.metrics != nil {
 		s.metrics.IPSCPeersRegistered.Set(float64(len(s.peers)))
 	}
+	return peerEvent(PeerLost, peer), true, nil
+}
+
+func (s *IPSCServer) pruneLoop(timeout time.Duration, stop <-chan struct{}) {
+	defer s.wg.Done()
+	defer s.recoverPanic()
+	ticker := jitter.NewTicker(max(timeout/4, time.Second), jitter.DefaultFraction, s.jitter)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.prunePeers(s.now().Add(-timeout))
+			s.liftAuthBans()
+			s.forgetFullBuckets()
+			s.forgetUnregisteredLogs()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// peerTableFull reports whether ipsc.max-peers peers are registered, so
+// peerID may not join them. IPSC has no way to reject a registration, so
+// the refusal, logged at most once per unregisteredLogInterval per
+// address, is all the peer gets. The caller must hold s.mu.
+func (s *IPSCServer) peerTableFull(peerID uint32, addr *net.UDPAddr) bool {
+	maxPeers := s.cfg.IPSC.MaxPeers
+	if maxPeers == 0 {
+		return false
+	}
+	registered := uint(0)
+	for _, peer := range s.peers {
+		if peer.RegistrationStatus {
+			registered++
+		}
+	}
+	if registered < maxPeers {
+		return false
+	}
+	if s.metrics != nil {
+		s.metrics.IPSCPeersRefused.Inc()
+	}
+	if addr == nil || s.refusalLogDue(addr) {
+		s.log.Warn("IPSC peer registration refused, the peer table is full", "peer", addr, "peerID", peerID, "maxPeers", maxPeers)
+	}
+	return true
+}
+
+// prunePeers drops peers last heard from before cutoff and returns their
+// IDs.
+func (s *IPSCServer) prunePeers(cutoff time.Time) []uint32 {
+	return s.dropPeers("IPSC peer timed out", func(peer *Peer) bool {
+		return peer.LastSeen.Before(cutoff)
+	})
+}
+
+// dropPeers drops the peers stale reports, logging msg for each and
+// reporting it lost, and returns their IDs.
+func (s *IPSCServer) dropPeers(msg string, stale func(peer *Peer) bool) []uint32 {
+	s.mu.Lock()
+	var pruned []uint32
+	var lost []PeerEvent
+	for id, peer := range s.peers {
+		if stale(peer) {
+			pruned = append(pruned, id)
+			lost = append(lost, peerEvent(PeerLost, peer))
+			delete(s.peers, id)
+			delete(s.lastSend, id)
+		}
+	}
+	if s.metrics != nil && len(pruned) > 0 {
+		s.metrics.IPSCPeersRegistered.Set(float64(len(s.peers)))
+	}
+	s.mu.Unlock()
+
+	for _, event := range lost {
+		s.log.Info(msg, "peerID", event.PeerID)
+		s.peerLost(event)
+	}
+	return pruned
 }
 
+// markPeerAlive notes that peerID was heard from at addr, reporting it
+// registered if it is new and updated if it moved.
 func (s *IPSCServer) markPeerAlive(peerID uint32, addr *net.UDPAddr) {
+	if event, ok := s.notePeerAlive(peerID, addr, false, nil); ok {
+		s.firePeerEvents(event)
+	}
+}
+
+// keepPeerAlive is markPeerAlive for a keepalive, data, which is also
+// reported when nothing changed. The mode and flags a keepalive carries
+// replace those the peer registered with, so a repeater switching to
+// analog or losing a timeslot is reflected in the peer list.
+func (s *IPSCServer) keepPeerAlive(peerID uint32, addr *net.UDPAddr, data []byte) {
+	if event, ok := s.notePeerAlive(peerID, addr, true, data); ok {
+		s.firePeerEvents(event)
+	}
+}
+
+func (s *IPSCServer) notePeerAlive(peerID uint32, addr *net.UDPAddr, keepAlive bool, data []byte) (PeerEvent, bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	peer, ok := s.peers[peerID]
 	if !ok {
-		peer = &Peer{ID: peerID}
-		s.peers[peerID] = peer
+		// Only a registration adds a peer, so traffic claiming an
+		// unknown ID leaves no trace in the peer table.
+		return PeerEvent{}, false
+	}
+	eventType, report := PeerKeepAlive, keepAlive
+	if peer.Addr == nil || addr == nil || peer.Addr.String() != addr.String() {
+		if !s.addressTakeover(peer, addr) {
+			return PeerEvent{}, false
+		}
+		eventType, report = PeerUpdated, true
+	}
+	if len(data) >= 10 {
+		mode := data[5]
+		var flags [4]byte
+		copy(flags[:], data[6:10])
+		if peer.Mode != mode || peer.Flags != flags {
+			s.log.Info("IPSC peer changed mode or flags", "peer", addr, "peerID", peerID,
+				"mode", mode, "flags", flags[:])
+			eventType, report = PeerUpdated, true
+		}
+		peer.Mode = mode
+		peer.Flags = flags
 	}
 	peer.Addr = cloneUDPAddr(addr)
-	peer.LastSeen = time.Now()
+	peer.LastSeen = s.now()
 	peer.KeepAliveReceived++
+	peer.Provisional = false
+	peer.heardFrom()
+	if !report || s.peerEventHandler == nil {
+		return PeerEvent{}, false
+	}
+	return peerEvent(eventType, peer), true
+}
+
+// Peers returns a copy of the currently known peers.
+func (s *IPSCServer) Peers() []Peer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	peers := make([]Peer, 0, len(s.peers))
+	for _, peer := range s.peers {
+		p := *peer
+		p.Addr = cloneUDPAddr(peer.Addr)
+		p.PeerStats = peer.PeerStats.clone()
+		p.skipped = nil
+		peers = append(peers, p)
+	}
+	return peers
+}
+
+// RestorePeers adds peers from a previous run as provisional registrations
+// so they receive traffic immediately after a restart. Peers that have
+// already been heard from are left untouched.
+func (s *IPSCServer) RestorePeers(peers []Peer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, restored := range peers {
+		if _, ok := s.peers[restored.ID]; ok {
+			continue
+		}
+		s.peers[restored.ID] = &Peer{
+			ID:                 restored.ID,
+			Addr:               cloneUDPAddr(restored.Addr),
+			Mode:               restored.Mode,
+			Flags:              restored.Flags,
+			LastSeen:           s.now(),
+			RegistrationStatus: true,
+			Provisional:        true,
+		}
+	}
+
+	if s.metrics != nil {
+		s.metrics.IPSCPeersRegistered.Set(float64(len(s.peers)))
+	}
 }
 
 func (s *IPSCServer) buildMasterRegisterReply() []byte {
@@ -442,20 +1057,49 @@ func (s *IPSCServer) buildMasterAliveReply() []byte {
 	return packet
 }
 
-func (s *IPSCServer) buildPeerListReply() []byte {
-	peerList := s.buildPeerList()
-	packet := make([]byte, 0, 1+4+2+len(peerList))
-	packet = append(packet, byte(PacketType_PeerListReply))
+func (s *IPSCServer) buildDeRegisterReply() []byte {
+	packet := make([]byte, 0, 1+4)
+	packet = append(packet, byte(PacketType_DeRegisterReply))
 	packet = append(packet, s.localIDBytes()...)
-	if len(peerList) > math.MaxUint16 {
-		packet = append(packet, uint16ToBytes(math.MaxUint16)...)
-	} else {
-		packet = append(packet, uint16ToBytes(uint16(len(peerList)))...) //nolint:gosec // Bounds checked
-	}
-	packet = append(packet, peerList...)
 	return packet
 }
 
+// defaultPeerListMaxPayload is the largest peer list reply sent when none
+// is configured, leaving room under a 1500-byte MTU.
+const defaultPeerListMaxPayload = 1400
+
+// buildPeerListReplies builds the peer list replies to a peer list
+// request. A list too long for one reply of the configured size is split
+// over several, each a complete reply listing some of the peers; peers
+// take in the entries of every reply. There is always at least one reply.
+func (s *IPSCServer) buildPeerListReplies() [][]byte {
+	maxPayload := int(s.cfg.IPSC.PeerListMaxPayload) //nolint:gosec // G115: validated to fit a datagram
+	if maxPayload == 0 {
+		maxPayload = defaultPeerListMaxPayload
+	}
+	room := maxPayload - 1 - 4 - 2
+	if s.cfg.IPSC.Auth.Enabled {
+		room -= authDigestSize
+	}
+	chunkSize := max(room/peerListEntrySize, 1) * peerListEntrySize
+
+	peerList := s.buildPeerList()
+	var replies [][]byte
+	for {
+		chunk := peerList[:min(chunkSize, len(peerList))]
+		peerList = peerList[len(chunk):]
+		packet := make([]byte, 0, 1+4+2+len(chunk))
+		packet = append(packet, byte(PacketType_PeerListReply))
+		packet = append(packet, s.localIDBytes()...)
+		packet = append(packet, uint16ToBytes(uint16(len(chunk)))...) //nolint:gosec // G115: at most a datagram
+		packet = append(packet, chunk...)
+		replies = append(replies, packet)
+		if len(peerList) == 0 {
+			return replies
+		}
+	}
+}
+
 func (s *IPSCServer) buildPeerList() []byte {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -464,7 +1108,7 @@ func (s *IPSCServer) buildPeerList() []byte {
 		return nil
 	}
 
-	peerList := make([]byte, 0, len(s.peers)*11)
+	peerList := make([]byte, 0, len(s.peers)*peerListEntrySize)
 	for _, peer := range s.peers {
 		if peer.Addr == nil || peer.Addr.IP == nil {
 			continue
@@ -492,22 +1136,40 @@ func (s *IPSCServer) localIDBytes() []byte {
 	return uint32ToBytes(s.localID)
 }
 
+// defaultModeByte is the mode the server advertises: an operational
+// digital peer, on the timeslots of ipsc.capabilities.slots.
 func (s *IPSCServer) defaultModeByte() byte {
-	const (
-		peerOperational = 0b01000000
-		peerDigital     = 0b00100000
-		ts1On           = 0b00001000
-		ts2On           = 0b00000010
-	)
-	return peerOperational | peerDigital | ts1On | ts2On
+	mode := modePeerOperational | modePeerDigital
+	slots := s.cfg.IPSC.Capabilities.Slots
+	if slots == 0 {
+		slots = 3
+	}
+	if slots&1 != 0 {
+		mode |= modeTS1On
+	}
+	if slots&2 != 0 {
+		mode |= modeTS2On
+	}
+	return mode
 }
 
+// defaultFlagsBytes are the capability flags the server advertises, as
+// set in ipsc.capabilities and ipsc.auth.
 func (s *IPSCServer) defaultFlagsBytes() [4]byte {
+	caps := s.cfg.IPSC.Capabilities
 	flags := [4]byte{}
-	flags[2] = 0x00
-	flags[3] = 0x0D
+	if caps.CSBK {
+		flags[2] |= flagCSBK
+	}
+	if caps.CallMonitor {
+		flags[2] |= flagRepeaterMonitor
+	}
+	flags[3] = flagVoiceCall | flagMasterPeer
+	if !caps.VoiceOnly {
+		flags[3] |= flagDataCall
+	}
 	if s.cfg.IPSC.Auth.Enabled {
-		flags[3] |= 0x10
+		flags[3] |= flagAuth
 	}
 	return flags
 }
@@ -542,26 +1204,102 @@ func cloneUDPAddr(addr *net.UDPAddr) *net.UDPAddr {
 	return cloned
 }
 
-func (s *IPSCServer) auth(data []byte) bool {
+// keyFor returns the key peerID signs its packets with: its own, or else
+// the global key unless every peer must have its own.
+func (s *IPSCServer) keyFor(peerID uint32) ([]byte, bool) {
+	if key, ok := s.peerKeys[peerID]; ok {
+		return key, true
+	}
+	if s.requirePeerKey {
+		return nil, false
+	}
+	s.keyMu.RLock()
+	defer s.keyMu.RUnlock()
+	return s.authKey, true
+}
+
+// keyForAddr returns the key to sign packets to addr with: that of the
+// peer there, or the global key for an address with no known peer.
+func (s *IPSCServer) keyForAddr(addr *net.UDPAddr) []byte {
+	if len(s.peerKeys) > 0 && addr != nil {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		for _, peer := range s.peers {
+			if peer.Addr != nil && peer.Addr.String() == addr.String() {
+				if key, ok := s.keyFor(peer.ID); ok {
+					return key
+				}
+			}
+		}
+	}
+	s.keyMu.RLock()
+	defer s.keyMu.RUnlock()
+	return s.authKey
+}
+
+// auth checks the digest ending data against the key of peerID. Peers on
+// the global key may also use the one it replaced, during its grace
+// window.
+func (s *IPSCServer) auth(peerID uint32, data []byte) bool {
+	key, ok := s.keyFor(peerID)
+	if !ok {
+		return false
+	}
 	// Last 10 bytes are the sha hash
-	payload := data[:len(data)-10]
-	hash := data[len(data)-10:]
-	expectedHash := hmac.New(sha1.New, s.authKey)
-	expectedHash.Write(payload)
-	expectedHashSum := expectedHash.Sum(nil)[:10]
+	payload := data[:len(data)-authDigestSize]
+	hash := data[len(data)-authDigestSize:]
+	if hmac.Equal(hash, digest(payload, key)) {
+		return true
+	}
+	if _, own := s.peerKeys[peerID]; own {
+		return false
+	}
+	previous := s.previousAuthKey()
+	return previous != nil && hmac.Equal(hash, digest(payload, previous))
+}
 
-	return hmac.Equal(hash, expectedHashSum)
+// digest returns the truncated HMAC-SHA1 digest of data with key.
+func digest(data, key []byte) []byte {
+	hash := hmac.New(sha1.New, key)
+	hash.Write(data)
+	return hash.Sum(nil)[:authDigestSize]
 }
 
+// sign appends to data its digest with key, as peers check it.
+func sign(data, key []byte) []byte {
+	return append(data, digest(data, key)...)
+}
+
+// sendPacket sends packet to addr, signed with the key of the peer there
+// when authentication is on.
 func (s *IPSCServer) sendPacket(packet *Packet, addr *net.UDPAddr) error {
 	if s.cfg.IPSC.Auth.Enabled {
-		hash := hmac.New(sha1.New, s.authKey)
-		hash.Write(packet.data)
-		hashSum := hash.Sum(nil)[:10]
-		packet.data = append(packet.data, hashSum...)
+		packet.data = sign(packet.data, s.keyForAddr(addr))
 	}
+	return s.writePacket(packet, addr)
+}
 
-	n, err := s.udp.WriteToUDP(packet.data, addr)
+// sendReply sends packet to addr in reply to a packet authenticated with
+// key, signing it with the same key when authentication is on. A peer
+// that has just de-registered is no longer known by its address, but
+// still expects its own key.
+func (s *IPSCServer) sendReply(packet *Packet, addr *net.UDPAddr, key []byte) error {
+	if s.cfg.IPSC.Auth.Enabled {
+		if key == nil {
+			key = s.keyForAddr(addr)
+		}
+		packet.data = sign(packet.data, key)
+	}
+	return s.writePacket(packet, addr)
+}
+
+func (s *IPSCServer) writePacket(packet *Packet, addr *net.UDPAddr) error {
+	conn := s.conn()
+	if conn == nil {
+		return fmt.Errorf("error sending packet: %w", net.ErrClosed)
+	}
+	n, err := conn.WriteToUDP(packet.data, addr)
+	s.captureOutbound(packet.data, addr, err)
 	if err != nil {
 		if s.metrics != nil {
 			s.metrics.IPSCUDPErrors.WithLabelValues("write").Inc()
@@ -571,6 +1309,7 @@ func (s *IPSCServer) sendPacket(packet *Packet, addr *net.UDPAddr) error {
 	if n != len(packet.data) {
 		return fmt.Errorf("error sending packet: only sent %d of %d bytes", n, len(packet.data))
 	}
+	s.countSent(packet.data, addr)
 	return nil
 }
 
@@ -578,29 +1317,61 @@ func (s *IPSCServer) SendUserPacket(data []byte) {
 	if s.stopped.Load() {
 		return
 	}
-	s.mu.RLock()
-	peers := make([]*Peer, 0, len(s.peers))
-	for _, peer := range s.peers {
-		if peer.Addr != nil {
-			peers = append(peers, peer)
-		}
-	}
-	s.mu.RUnlock()
+	monitor := s.noteCallMonitor(data)
+	defer s.sendCallMonitor(monitor)
 
-	for _, peer := range peers {
+	for _, peer := range s.peersAccepting(classifyUserPacket(data), nil) {
 		s.pacePeer(peer.ID)
 		packetData := make([]byte, len(data))
 		copy(packetData, data)
 		packet := &Packet{data: packetData}
-		slog.Debug("IPSC burst sending", "peer", peer.Addr, "length", len(packet.data))
+		s.log.Debug("IPSC burst sending", "peer", peer.Addr, "length", len(packet.data))
+		if err := s.sendPacket(packet, peer.Addr); err != nil {
+			s.log.Warn("failed sending IPSC user packet", "peer", peer.Addr, "error", err)
+		} else if s.metrics != nil {
+			s.metrics.IPSCPacketsSent.Inc()
+		}
+	}
+}
+
+// repeatToPeers sends a user packet from the peer at from on to every
+// other peer, so repeaters registered here hear each other. The packets
+// already arrive at the pace of the call, so they are not paced again.
+func (s *IPSCServer) repeatToPeers(data []byte, from *net.UDPAddr) {
+	for _, peer := range s.peersAccepting(classifyUserPacket(data), from) {
+		packet := &Packet{data: append([]byte(nil), data...)}
 		if err := s.sendPacket(packet, peer.Addr); err != nil {
-			slog.Warn("failed sending IPSC user packet", "peer", peer.Addr, "error", err)
+			s.log.Warn("failed repeating IPSC user packet", "peer", peer.Addr, "error", err)
 		} else if s.metrics != nil {
 			s.metrics.IPSCPacketsSent.Inc()
 		}
 	}
 }
 
+// peersAccepting returns the peers to send a user packet of class to,
+// leaving out the one at except.
+func (s *IPSCServer) peersAccepting(class packetClass, except *net.UDPAddr) []*Peer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	peers := make([]*Peer, 0, len(s.peers))
+	for _, peer := range s.peers {
+		if peer.Addr == nil {
+			continue
+		}
+		if except != nil && peer.Addr.String() == except.String() {
+			continue
+		}
+		if !s.cfg.IPSC.IgnorePeerCapabilities {
+			if reason, ok := peer.acceptsClass(class); !ok {
+				s.noteSkip(peer, reason)
+				continue
+			}
+		}
+		peers = append(peers, peer)
+	}
+	return peers
+}
+
 func (s *IPSCServer) pacePeer(peerID uint32) {
 	const burstInterval = 30 * time.Millisecond
 
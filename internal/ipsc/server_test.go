@@ -1,10 +1,13 @@
 package ipsc
 
 import (
+	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha1" //nolint:gosec
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"net"
 	"sync"
 	"sync/atomic"
@@ -12,6 +15,9 @@ import (
 	"time"
 
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dscp"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/netsetup"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/testutil"
 )
 
 func testConfig(authEnabled bool, authKey string) *config.Config {
@@ -147,7 +153,7 @@ func TestAuth(t *testing.T) {
 	data = append(data, payload...)
 	data = append(data, hash...)
 
-	if !s.auth(data) {
+	if !s.auth(12345, data) {
 		t.Fatal("expected auth to pass")
 	}
 }
@@ -163,7 +169,7 @@ func TestAuthBadHash(t *testing.T) {
 	data = append(data, payload...)
 	data = append(data, bad...)
 
-	if s.auth(data) {
+	if s.auth(12345, data) {
 		t.Fatal("expected auth to fail with bad hash")
 	}
 }
@@ -259,6 +265,45 @@ func TestDefaultFlagsBytesWithAuth(t *testing.T) {
 	}
 }
 
+func TestAdvertisedModeAndFlags(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		auth      bool
+		caps      config.IPSCCapabilities
+		wantMode  byte
+		wantFlags [4]byte
+	}{
+		{"defaults", false, config.IPSCCapabilities{}, 0x6A, [4]byte{0, 0, 0, 0x0D}},
+		{"both slots", false, config.IPSCCapabilities{Slots: 3}, 0x6A, [4]byte{0, 0, 0, 0x0D}},
+		{"auth", true, config.IPSCCapabilities{}, 0x6A, [4]byte{0, 0, 0, 0x1D}},
+		{"TS1 only", false, config.IPSCCapabilities{Slots: 1}, 0x68, [4]byte{0, 0, 0, 0x0D}},
+		{"TS2 only", false, config.IPSCCapabilities{Slots: 2}, 0x62, [4]byte{0, 0, 0, 0x0D}},
+		{"voice only", false, config.IPSCCapabilities{VoiceOnly: true}, 0x6A, [4]byte{0, 0, 0, 0x05}},
+		{"csbk", false, config.IPSCCapabilities{CSBK: true}, 0x6A, [4]byte{0, 0, 0x80, 0x0D}},
+		{"call monitor", false, config.IPSCCapabilities{CallMonitor: true}, 0x6A, [4]byte{0, 0, 0x40, 0x0D}},
+		{"everything on TS2 with auth", true, config.IPSCCapabilities{Slots: 2, CSBK: true, CallMonitor: true}, 0x62, [4]byte{0, 0, 0xC0, 0x1D}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := testConfig(tt.auth, "1234")
+			cfg.IPSC.Capabilities = tt.caps
+			s := NewIPSCServer(cfg, nil)
+			if mode := s.defaultModeByte(); mode != tt.wantMode {
+				t.Fatalf("expected mode 0x%02X, got 0x%02X", tt.wantMode, mode)
+			}
+			if flags := s.defaultFlagsBytes(); flags != tt.wantFlags {
+				t.Fatalf("expected flags % X, got % X", tt.wantFlags, flags)
+			}
+			reply := s.buildMasterRegisterReply()
+			if reply[5] != tt.wantMode || [4]byte(reply[6:10]) != tt.wantFlags {
+				t.Fatalf("expected the registration reply to advertise them, got % X", reply)
+			}
+		})
+	}
+}
+
 func TestBuildMasterRegisterReply(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig(false, "")
@@ -290,7 +335,7 @@ func TestBuildPeerListReplyEmpty(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig(false, "")
 	s := NewIPSCServer(cfg, nil)
-	reply := s.buildPeerListReply()
+	reply := s.buildPeerListReplies()[0]
 
 	if reply[0] != byte(PacketType_PeerListReply) {
 		t.Fatalf("expected packet type 0x%02X, got 0x%02X", PacketType_PeerListReply, reply[0])
@@ -311,7 +356,7 @@ func TestBuildPeerListReplyWithPeers(t *testing.T) {
 	addr := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 100), Port: 50000}
 	s.upsertPeer(42, addr, 0x6A, [4]byte{0, 0, 0, 0x0D})
 
-	reply := s.buildPeerListReply()
+	reply := s.buildPeerListReplies()[0]
 	if reply[0] != byte(PacketType_PeerListReply) {
 		t.Fatalf("expected packet type 0x%02X, got 0x%02X", PacketType_PeerListReply, reply[0])
 	}
@@ -322,6 +367,135 @@ func TestBuildPeerListReplyWithPeers(t *testing.T) {
 	}
 }
 
+func TestKeepaliveUpdatesModeAndFlags(t *testing.T) {
+	t.Parallel()
+	s, _ := newTestServerWithUDP(t, false, "")
+	events := recordPeerEvents(s)
+	_, addr := listenPeer(t)
+
+	digital := [4]byte{0, 0, 0, 0x0D}
+	analog := [4]byte{0, 0, 0, 0x09}
+	for _, data := range [][]byte{
+		makeControlPacketWithModeFlags(PacketType_MasterRegisterRequest, 8000, 0x6A, digital),
+		// A keepalive without mode and flags leaves them alone.
+		makeControlPacket(PacketType_MasterAliveRequest, 8000),
+		makeControlPacketWithModeFlags(PacketType_MasterAliveRequest, 8000, 0x65, analog),
+		makeControlPacketWithModeFlags(PacketType_MasterAliveRequest, 8000, 0x65, analog),
+	} {
+		if _, err := s.handlePacket(data, addr); err != nil {
+			t.Fatalf("handlePacket: %v", err)
+		}
+	}
+
+	replies := s.buildPeerListReplies()
+	if len(replies) != 1 {
+		t.Fatalf("expected 1 peer list reply, got %d", len(replies))
+	}
+	entry := replies[0][7:]
+	if len(entry) != peerListEntrySize || binary.BigEndian.Uint32(entry[0:4]) != 8000 {
+		t.Fatalf("expected one entry for peer 8000, got % X", entry)
+	}
+	if entry[10] != 0x65 {
+		t.Fatalf("expected the peer list to carry mode 0x65, got 0x%02X", entry[10])
+	}
+	s.mu.RLock()
+	flags := s.peers[8000].Flags
+	s.mu.RUnlock()
+	if flags != analog {
+		t.Fatalf("expected flags % X, got % X", analog, flags)
+	}
+
+	want := []PeerEventType{PeerRegistered, PeerKeepAlive, PeerUpdated, PeerKeepAlive}
+	got := events()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i].Type != want[i] {
+			t.Fatalf("event %d: expected %v, got %+v", i, want[i], got[i])
+		}
+	}
+	if got[2].Mode != 0x65 || got[2].Flags != analog {
+		t.Fatalf("expected the update to carry the new mode and flags, got %+v", got[2])
+	}
+}
+
+func TestBuildPeerListRepliesChunked(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		auth bool
+	}{
+		{"plain", false},
+		{"with auth", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			const maxPayload = 100
+			cfg := testConfig(tt.auth, "1234")
+			cfg.IPSC.PeerListMaxPayload = maxPayload
+			s := NewIPSCServer(cfg, nil)
+			const peers = 20
+			for id := uint32(1); id <= peers; id++ {
+				s.upsertPeer(id, &net.UDPAddr{IP: net.IPv4(10, 0, 0, byte(id)), Port: 50000}, 0x6A, [4]byte{})
+			}
+
+			replies := s.buildPeerListReplies()
+			if len(replies) < 2 {
+				t.Fatalf("expected the list split over several replies, got %d", len(replies))
+			}
+			seen := map[uint32]bool{}
+			for _, reply := range replies {
+				size := len(reply)
+				if tt.auth {
+					size += authDigestSize
+				}
+				if size > maxPayload {
+					t.Fatalf("expected replies of at most %d bytes, got %d", maxPayload, size)
+				}
+				if reply[0] != byte(PacketType_PeerListReply) {
+					t.Fatalf("expected packet type 0x%02X, got 0x%02X", PacketType_PeerListReply, reply[0])
+				}
+				list := reply[7:]
+				if n := int(binary.BigEndian.Uint16(reply[5:7])); n != len(list) || n%peerListEntrySize != 0 {
+					t.Fatalf("expected a length of whole entries matching the list, got %d for %d bytes", n, len(list))
+				}
+				for ; len(list) > 0; list = list[peerListEntrySize:] {
+					id := binary.BigEndian.Uint32(list[0:4])
+					if seen[id] {
+						t.Fatalf("expected peer %d listed once", id)
+					}
+					seen[id] = true
+				}
+			}
+			if len(seen) != peers {
+				t.Fatalf("expected all %d peers listed, got %d", peers, len(seen))
+			}
+		})
+	}
+}
+
+func TestPeerListRequestSendsEveryChunk(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig(false, "")
+	cfg.IPSC.PeerListMaxPayload = 50
+	s, _ := newTestServerWithConfig(t, cfg)
+	peer, peerAddr := listenPeer(t)
+	for id := uint32(1); id <= 10; id++ {
+		s.upsertPeer(id, &net.UDPAddr{IP: net.IPv4(10, 0, 0, byte(id)), Port: 50000}, 0x6A, [4]byte{})
+	}
+	s.upsertPeer(11, peerAddr, 0x6A, [4]byte{})
+
+	if _, err := s.handlePacket(makeControlPacket(PacketType_PeerListRequest, 11), peerAddr); err != nil {
+		t.Fatalf("handlePacket: %v", err)
+	}
+	want := len(s.buildPeerListReplies())
+	for range want {
+		readPacketType(t, peer, PacketType_PeerListReply)
+	}
+}
+
 func TestUpsertPeerAndCount(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig(false, "")
@@ -360,10 +534,13 @@ func TestMarkPeerAlive(t *testing.T) {
 	addr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
 	s.markPeerAlive(100, addr)
 
-	if s.peerCount() != 1 {
-		t.Fatalf("expected 1 peer after markPeerAlive, got %d", s.peerCount())
+	if s.peerCount() != 0 {
+		t.Fatalf("expected no peer from markPeerAlive for an unregistered ID, got %d", s.peerCount())
 	}
 
+	s.upsertPeer(100, addr, 0x6A, [4]byte{})
+	s.markPeerAlive(100, addr)
+
 	// Mark alive again should increment keepalive counter
 	s.markPeerAlive(100, addr)
 	s.mu.RLock()
@@ -376,6 +553,67 @@ func TestMarkPeerAlive(t *testing.T) {
 	}
 }
 
+func TestRestorePeersProvisional(t *testing.T) {
+	t.Parallel()
+	s, _ := newTestServerWithUDP(t, false, "")
+
+	peerConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer peerConn.Close()
+	peerAddr, ok := peerConn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		t.Fatal("expected *net.UDPAddr from LocalAddr")
+	}
+
+	s.RestorePeers([]Peer{{ID: 200, Addr: peerAddr, Mode: 0x6A, Flags: [4]byte{0, 0, 0, 0x0D}}})
+
+	peers := s.Peers()
+	if len(peers) != 1 {
+		t.Fatalf("expected 1 restored peer, got %d", len(peers))
+	}
+	if !peers[0].Provisional || !peers[0].RegistrationStatus {
+		t.Fatalf("expected restored peer to be provisionally registered, got %+v", peers[0])
+	}
+
+	// Provisional peers receive traffic without waiting for a keepalive.
+	s.SendUserPacket([]byte{byte(PacketType_GroupVoice), 0x01, 0x02, 0x03})
+	if got := readUDP(t, peerConn); got[0] != byte(PacketType_GroupVoice) {
+		t.Fatalf("expected user packet at restored peer, got 0x%02X", got[0])
+	}
+
+	s.markPeerAlive(200, peerAddr)
+	if s.Peers()[0].Provisional {
+		t.Fatal("expected keepalive to clear provisional flag")
+	}
+}
+
+func TestRestorePeersKeepsLivePeers(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig(false, "")
+	s := NewIPSCServer(cfg, nil)
+
+	live := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
+	s.upsertPeer(100, live, 0x6A, [4]byte{})
+	s.RestorePeers([]Peer{
+		{ID: 100, Addr: &net.UDPAddr{IP: net.IPv4(10, 0, 0, 9), Port: 9999}},
+		{ID: 101, Addr: &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 1235}},
+	})
+
+	if s.peerCount() != 2 {
+		t.Fatalf("expected 2 peers, got %d", s.peerCount())
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.peers[100].Provisional || !s.peers[100].Addr.IP.Equal(live.IP) {
+		t.Fatal("expected the live peer not to be overwritten by the snapshot")
+	}
+	if !s.peers[101].Provisional {
+		t.Fatal("expected snapshot-only peer to be provisional")
+	}
+}
+
 func TestHandlePacketTooShort(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig(false, "")
@@ -437,6 +675,8 @@ func TestPacketTypeValues(t *testing.T) {
 		PacketType_PeerListReply:         0x93,
 		PacketType_MasterAliveRequest:    0x96,
 		PacketType_MasterAliveReply:      0x97,
+		PacketType_DeRegisterRequest:     0x9A,
+		PacketType_DeRegisterReply:       0x9B,
 	}
 	for pt, val := range expected {
 		if byte(pt) != val {
@@ -471,7 +711,11 @@ func TestUpsertPeerRegistrationStatus(t *testing.T) {
 
 func newTestServerWithUDP(t *testing.T, authEnabled bool, authKey string) (*IPSCServer, *net.UDPAddr) {
 	t.Helper()
-	cfg := testConfig(authEnabled, authKey)
+	return newTestServerWithConfig(t, testConfig(authEnabled, authKey))
+}
+
+func newTestServerWithConfig(t *testing.T, cfg *config.Config) (*IPSCServer, *net.UDPAddr) {
+	t.Helper()
 	s := NewIPSCServer(cfg, nil)
 
 	// Bind to loopback on a random port
@@ -647,6 +891,83 @@ func TestHandleMasterRegisterRequestFlow(t *testing.T) {
 	}
 }
 
+func TestHandleMasterRegisterRequestAllowedPeers(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		allowed []uint32
+		denied  []uint32
+		want    map[uint32]bool
+	}{
+		{"no lists", nil, nil, map[uint32]bool{100: true, 200: true, 300: true}},
+		{"allow only", []uint32{100, 200}, nil, map[uint32]bool{100: true, 200: true, 300: false}},
+		{"deny only", nil, []uint32{200}, map[uint32]bool{100: true, 200: false, 300: true}},
+		{"deny overrides allow", []uint32{100, 200}, []uint32{200}, map[uint32]bool{100: true, 200: false, 300: false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := testConfig(false, "")
+			cfg.IPSC.AllowedPeers = tt.allowed
+			cfg.IPSC.DeniedPeers = tt.denied
+			s, _ := newTestServerWithConfig(t, cfg)
+			for peerID, want := range tt.want {
+				conn, addr := listenPeer(t)
+				_, err := s.handlePacket(makeControlPacket(PacketType_MasterRegisterRequest, peerID), addr)
+				if !want {
+					if !errors.Is(err, ErrPacketIgnored) {
+						t.Fatalf("peer %d: expected the registration ignored, got %v", peerID, err)
+					}
+					assertNothingReceived(t, conn)
+					continue
+				}
+				if err != nil {
+					t.Fatalf("peer %d: expected the registration accepted, got %v", peerID, err)
+				}
+				readPacketType(t, conn, PacketType_MasterRegisterReply)
+			}
+			s.mu.RLock()
+			defer s.mu.RUnlock()
+			for peerID, want := range tt.want {
+				if _, ok := s.peers[peerID]; ok != want {
+					t.Fatalf("peer %d: expected registered=%t", peerID, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeniedPeerTrafficIsRefused(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig(false, "")
+	cfg.IPSC.DeniedPeers = []uint32{42}
+	s := NewIPSCServer(cfg, nil)
+	events := recordPeerEvents(s)
+	var forwarded atomic.Bool
+	s.SetBurstHandler(func(byte, []byte, *net.UDPAddr) { forwarded.Store(true) })
+
+	addr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
+	data := make([]byte, 54)
+	data[0] = byte(PacketType_GroupVoice)
+	binary.BigEndian.PutUint32(data[1:5], 42)
+	if _, err := s.handlePacket(data, addr); !errors.Is(err, ErrPacketIgnored) {
+		t.Fatalf("expected the voice packet ignored, got %v", err)
+	}
+	if _, err := s.handlePacket(makeControlPacket(PacketType_MasterAliveRequest, 42), addr); !errors.Is(err, ErrPacketIgnored) {
+		t.Fatalf("expected the keepalive ignored, got %v", err)
+	}
+
+	if forwarded.Load() {
+		t.Fatal("expected the denied peer's burst not forwarded")
+	}
+	if s.peerCount() != 0 {
+		t.Fatalf("expected the denied peer not recorded, got %d peers", s.peerCount())
+	}
+	if got := events(); len(got) != 0 {
+		t.Fatalf("expected no peer events, got %+v", got)
+	}
+}
+
 func TestHandleMasterRegisterRequestShortPacket(t *testing.T) {
 	t.Parallel()
 	s, srvAddr := newTestServerWithUDP(t, false, "")
@@ -696,6 +1017,7 @@ func TestHandleMasterAliveRequestFlow(t *testing.T) {
 	if !ok {
 		t.Fatal("expected *net.UDPAddr from LocalAddr")
 	}
+	s.upsertPeer(peerID, aliveAddr, 0x6A, [4]byte{})
 	_, err = s.handlePacket(reqData, aliveAddr)
 	if err != nil {
 		t.Fatalf("handlePacket error: %v", err)
@@ -734,6 +1056,7 @@ func TestHandlePeerListRequestFlow(t *testing.T) {
 	if !ok {
 		t.Fatal("expected *net.UDPAddr from LocalAddr")
 	}
+	s.upsertPeer(88888, peerListAddr, 0x6A, [4]byte{})
 	_, err = s.handlePacket(reqData, peerListAddr)
 	if err != nil {
 		t.Fatalf("handlePacket error: %v", err)
@@ -775,6 +1098,7 @@ func TestHandleRepeaterWakeUp(t *testing.T) {
 	addr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 5), Port: 3000}
 	peerID := uint32(11111)
 	data := makeControlPacket(PacketType_RepeaterWakeUp, peerID)
+	s.upsertPeer(peerID, addr, 0x6A, [4]byte{})
 
 	_, err := s.handlePacket(data, addr)
 	if err != nil {
@@ -841,12 +1165,9 @@ func TestHandleUserPacketCallsBurstHandler(t *testing.T) {
 		t.Fatalf("expected packet type 0x80, got 0x%02X", gotType.Load())
 	}
 
-	// Verify peer was marked alive
-	s.mu.RLock()
-	peer := s.peers[42]
-	s.mu.RUnlock()
-	if peer == nil {
-		t.Fatal("expected peer to be created")
+	// Only a registration adds a peer.
+	if s.peerCount() != 0 {
+		t.Fatalf("expected no peer for an unregistered ID, got %d", s.peerCount())
 	}
 }
 
@@ -1007,6 +1328,203 @@ func TestHandlePacketAuthEnabledSuccess(t *testing.T) {
 	}
 }
 
+func TestHandlePacketPeerKeys(t *testing.T) {
+	t.Parallel()
+	const (
+		globalKey = "0000000000000000000000000000000000001234"
+		peerKey   = "000000000000000000000000000000000000beef"
+	)
+	tests := []struct {
+		name    string
+		require bool
+		peerID  uint32
+		key     string
+		accept  bool
+		wantErr error
+	}{
+		{"own key", false, 8000, peerKey, true, nil},
+		{"global key for peer with own key", false, 8000, globalKey, false, nil},
+		{"fallback to global key", false, 9000, globalKey, true, nil},
+		{"wrong key", false, 9000, peerKey, false, nil},
+		{"required own key", true, 8000, peerKey, true, nil},
+		{"required without own key", true, 9000, globalKey, false, ErrNoPeerKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := testConfig(true, "1234")
+			cfg.IPSC.Auth.PeerKeys = []config.IPSCPeerKey{{PeerID: 8000, Key: "beef"}}
+			cfg.IPSC.Auth.RequirePeerKey = tt.require
+			s, _ := newTestServerWithConfig(t, cfg)
+			peer, peerAddr := listenPeer(t)
+
+			data := signPacket(t, makeControlPacket(PacketType_MasterRegisterRequest, tt.peerID), tt.key)
+			_, err := s.handlePacket(data, peerAddr)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+			case tt.accept && err != nil:
+				t.Fatalf("expected the registration accepted, got %v", err)
+			case !tt.accept && err == nil:
+				t.Fatal("expected the registration refused")
+			}
+			if !tt.accept {
+				if s.peerCount() != 0 {
+					t.Fatalf("expected no peers, got %d", s.peerCount())
+				}
+				return
+			}
+
+			// The reply is signed with the key the peer used.
+			reply, _ := readPacketType(t, peer, PacketType_MasterRegisterReply)
+			payload := reply[:len(reply)-authDigestSize]
+			if want := signPacket(t, append([]byte(nil), payload...), tt.key); !bytes.Equal(reply, want) {
+				t.Fatalf("expected the reply signed with %s, got % X", tt.key, reply)
+			}
+		})
+	}
+}
+
+func TestRepliesSignedWithRequestKey(t *testing.T) {
+	t.Parallel()
+	const (
+		globalKey = "0000000000000000000000000000000000001234"
+		peerKey   = "000000000000000000000000000000000000beef"
+	)
+	tests := []struct {
+		name   string
+		auth   bool
+		peerID uint32
+		key    string
+	}{
+		{"no auth", false, 9000, ""},
+		{"global key", true, 9000, globalKey},
+		{"own key", true, 8000, peerKey},
+	}
+	// The de-register request comes last, as the peer is forgotten by
+	// the time its reply goes out.
+	requests := []struct {
+		packetType PacketType
+		reply      PacketType
+	}{
+		{PacketType_MasterRegisterRequest, PacketType_MasterRegisterReply},
+		{PacketType_MasterAliveRequest, PacketType_MasterAliveReply},
+		{PacketType_PeerListRequest, PacketType_PeerListReply},
+		{PacketType_PeerRegisterRequest, PacketType_PeerRegisterReply},
+		{PacketType_PeerAliveRequest, PacketType_PeerAliveReply},
+		{PacketType_DeRegisterRequest, PacketType_DeRegisterReply},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := testConfig(tt.auth, "1234")
+			cfg.IPSC.Auth.PeerKeys = []config.IPSCPeerKey{{PeerID: 8000, Key: "beef"}}
+			s, _ := newTestServerWithConfig(t, cfg)
+			peer, peerAddr := listenPeer(t)
+
+			for _, req := range requests {
+				data := makeControlPacketWithModeFlags(req.packetType, tt.peerID, 0x6A, [4]byte{0, 0, 0, 0x0D})
+				if tt.auth {
+					data = signPacket(t, data, tt.key)
+				}
+				if _, err := s.handlePacket(data, peerAddr); err != nil {
+					t.Fatalf("0x%02X: %v", byte(req.packetType), err)
+				}
+				reply, _ := readPacketType(t, peer, req.reply)
+				if !tt.auth {
+					continue
+				}
+				payload := reply[:len(reply)-authDigestSize]
+				if want := signPacket(t, append([]byte(nil), payload...), tt.key); !bytes.Equal(reply, want) {
+					t.Fatalf("0x%02X: expected the reply signed with %s, got % X", byte(req.reply), tt.key, reply)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthenticatedBurstsTranslateLikePlain(t *testing.T) {
+	t.Parallel()
+	const hexKey = "0000000000000000000000000000000000001234"
+	header := makeSequencedIPSCPacket(ipscBurstVoiceHead, 1)
+	packets := [][]byte{header}
+	for pos := range 6 {
+		packets = append(packets, makeIPSCVoiceBurst(pos, uint16(2+pos))) //nolint:gosec // G115: pos is in [0,5]
+	}
+	terminator := makeSequencedIPSCPacket(ipscBurstVoiceTerm, 8)
+	terminator[17] |= 0x40
+	packets = append(packets, terminator)
+
+	// received returns the packets s hands its burst handler for packets,
+	// each signed first if sign is set.
+	received := func(s *IPSCServer, sign bool) [][]byte {
+		t.Helper()
+		bursts := make(chan []byte, len(packets))
+		s.SetBurstHandler(func(_ byte, data []byte, _ *net.UDPAddr) { bursts <- data })
+		addr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
+		var got [][]byte
+		for _, pkt := range packets {
+			data := append([]byte(nil), pkt...)
+			if sign {
+				data = signPacket(t, data, hexKey)
+			}
+			if _, err := s.handlePacket(data, addr); err != nil {
+				t.Fatalf("handlePacket error: %v", err)
+			}
+			select {
+			case burst := <-bursts:
+				got = append(got, burst)
+			case <-time.After(time.Second):
+				t.Fatal("timed out waiting for the burst handler")
+			}
+		}
+		return got
+	}
+	plain := received(NewIPSCServer(testConfig(false, ""), nil), false)
+	authenticated := received(NewIPSCServer(testConfig(true, "1234"), nil), true)
+
+	plainTr, authTr := newTestTranslator(t), newTestTranslator(t)
+	for i := range packets {
+		if string(authenticated[i]) != string(packets[i]) {
+			t.Fatalf("packet %d: expected the digest stripped, got %d bytes for %d", i, len(authenticated[i]), len(packets[i]))
+		}
+		want := plainTr.TranslateToMMDVM(plain[i][0], plain[i])
+		got := authTr.TranslateToMMDVM(authenticated[i][0], authenticated[i])
+		if len(want) == 0 {
+			t.Fatalf("packet %d: expected a translation", i)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("packet %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+}
+
+func TestAuthenticatedBurstTooShortAfterDigest(t *testing.T) {
+	t.Parallel()
+	s := NewIPSCServer(testConfig(true, "1234"), nil)
+	bursts := make(chan []byte, 1)
+	s.SetBurstHandler(func(_ byte, data []byte, _ *net.UDPAddr) { bursts <- data })
+
+	// A 30-byte packet of which the last 10 bytes are the digest.
+	data := signPacket(t, makeSequencedIPSCPacket(ipscBurstSlot1, 1)[:20], "0000000000000000000000000000000000001234")
+	if _, err := s.handlePacket(data, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}); err != nil {
+		t.Fatalf("handlePacket error: %v", err)
+	}
+	select {
+	case burst := <-bursts:
+		if len(burst) != 20 {
+			t.Fatalf("expected 20 bytes after the digest, got %d", len(burst))
+		}
+		if result := newTestTranslator(t).TranslateToMMDVM(burst[0], burst); result != nil {
+			t.Fatalf("expected a packet too short after the digest to be rejected, got %d packets", len(result))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the burst handler")
+	}
+}
+
 func TestAuthDisabledAlwaysPasses(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig(false, "")
@@ -1196,6 +1714,72 @@ func TestSendUserPacketDataIsCopied(t *testing.T) {
 	}
 }
 
+func TestUserPacketsRepeatedToOtherPeers(t *testing.T) {
+	t.Parallel()
+	const hexKey = "0000000000000000000000000000000000001234"
+	tests := []struct {
+		name   string
+		repeat bool
+		auth   bool
+	}{
+		{"repeated", true, false},
+		{"repeated with auth", true, true},
+		{"not repeated", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := testConfig(tt.auth, "1234")
+			cfg.IPSC.RepeatToPeers = tt.repeat
+			s, _ := newTestServerWithConfig(t, cfg)
+			sender, senderAddr := listenPeer(t)
+			other1, other1Addr := listenPeer(t)
+			other2, other2Addr := listenPeer(t)
+			flags := [4]byte{0, 0, 0, 0x0D}
+			s.upsertPeer(1, senderAddr, 0x6A, flags)
+			s.upsertPeer(2, other1Addr, 0x6A, flags)
+			s.upsertPeer(3, other2Addr, 0x6A, flags)
+
+			burst := makeTestIPSCPacket(0x80, ipscBurstVoiceHead, true, false)
+			binary.BigEndian.PutUint32(burst[1:5], 1)
+			data := append([]byte(nil), burst...)
+			if tt.auth {
+				data = signPacket(t, data, hexKey)
+			}
+			if _, err := s.handlePacket(data, senderAddr); err != nil {
+				t.Fatalf("handlePacket: %v", err)
+			}
+
+			want := burst
+			if tt.auth {
+				want = signPacket(t, append([]byte(nil), burst...), hexKey)
+			}
+			for _, conn := range []*net.UDPConn{other1, other2} {
+				if !tt.repeat {
+					assertNothingReceived(t, conn)
+					continue
+				}
+				if got := readUDP(t, conn); !bytes.Equal(got, want) {
+					t.Fatalf("expected % X, got % X", want, got)
+				}
+			}
+			assertNothingReceived(t, sender)
+		})
+	}
+}
+
+// assertNothingReceived fails if anything arrives on conn shortly.
+func assertNothingReceived(t *testing.T, conn *net.UDPConn) {
+	t.Helper()
+	if err := conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond)); err != nil {
+		t.Fatalf("SetReadDeadline: %v", err)
+	}
+	buf := make([]byte, 1500)
+	if n, _, err := conn.ReadFromUDP(buf); err == nil {
+		t.Fatalf("expected nothing, got % X", buf[:n])
+	}
+}
+
 // --- pacePeer tests ---
 
 func TestPacePeerFirstCallNoDelay(t *testing.T) {
@@ -1288,7 +1872,7 @@ func TestHandlerLoopProcessesPackets(t *testing.T) {
 
 	// Start the handler goroutine
 	s.wg.Add(1)
-	go s.handler()
+	go s.handler(nil)
 
 	// Send a wake-up packet to the server
 	client, err := net.DialUDP("udp", nil, srvAddr)
@@ -1298,28 +1882,29 @@ func TestHandlerLoopProcessesPackets(t *testing.T) {
 	defer client.Close()
 
 	peerID := uint32(22222)
+	clientAddr, ok := client.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		t.Fatal("expected *net.UDPAddr from LocalAddr")
+	}
+	s.upsertPeer(peerID, clientAddr, 0x6A, [4]byte{})
 	data := makeControlPacket(PacketType_RepeaterWakeUp, peerID)
 	if _, err := client.Write(data); err != nil {
 		t.Fatalf("write: %v", err)
 	}
 
-	// Poll for peer to appear (the handler processes asynchronously)
-	deadline := time.Now().Add(2 * time.Second)
-	for time.Now().Before(deadline) {
+	// Poll for the wake-up to be counted (the handler processes asynchronously)
+	heard := func() bool {
 		s.mu.RLock()
-		_, ok := s.peers[peerID]
-		s.mu.RUnlock()
-		if ok {
-			break
-		}
+		defer s.mu.RUnlock()
+		peer, ok := s.peers[peerID]
+		return ok && peer.KeepAliveReceived == 1
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) && !heard() {
 		time.Sleep(10 * time.Millisecond)
 	}
-
-	s.mu.RLock()
-	_, found := s.peers[peerID]
-	s.mu.RUnlock()
-	if !found {
-		t.Fatal("expected peer to be created by handler loop")
+	if !heard() {
+		t.Fatal("expected the wake-up handled by the handler loop")
 	}
 
 	// Stop cleanly
@@ -1340,7 +1925,7 @@ func TestStopIdempotent(t *testing.T) {
 	s.udp = conn
 
 	s.wg.Add(1)
-	go s.handler()
+	go s.handler(nil)
 
 	// Calling Stop multiple times should not panic
 	s.Stop()
@@ -1348,6 +1933,182 @@ func TestStopIdempotent(t *testing.T) {
 	s.Stop()
 }
 
+func TestRunningTracksReceiveLoop(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig(false, "")
+	cfg.IPSC.IP = "127.0.0.1"
+	s := NewIPSCServer(cfg, nil)
+	if s.Running() {
+		t.Fatal("expected not running before Start")
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if !s.Running() {
+		t.Fatal("expected running after Start")
+	}
+	s.Stop()
+	if s.Running() {
+		t.Fatal("expected not running after Stop")
+	}
+}
+
+func TestStartStopRestart(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig(false, "")
+	cfg.IPSC.IP = "127.0.0.1"
+	s := NewIPSCServer(cfg, nil)
+	for i := range 2 {
+		if err := s.Start(); err != nil {
+			t.Fatalf("Start #%d: %v", i+1, err)
+		}
+		// A second Start on a running server is a no-op.
+		if err := s.Start(); err != nil {
+			t.Fatalf("repeated Start #%d: %v", i+1, err)
+		}
+		if !s.Running() {
+			t.Fatalf("expected running after Start #%d", i+1)
+		}
+		s.Stop()
+		s.Stop()
+		if s.Running() {
+			t.Fatalf("expected not running after Stop #%d", i+1)
+		}
+	}
+}
+
+func TestShutdownNotifiesPeers(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig(true, "1234")
+	cfg.IPSC.IP = "127.0.0.1"
+	s := NewIPSCServer(cfg, nil)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	first, firstAddr := listenPeer(t)
+	second, secondAddr := listenPeer(t)
+	s.upsertPeer(100, firstAddr, 0x6A, [4]byte{})
+	s.upsertPeer(200, secondAddr, 0x6A, [4]byte{})
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("repeated Shutdown: %v", err)
+	}
+
+	for _, conn := range []*net.UDPConn{first, second} {
+		data, _ := readPacketType(t, conn, PacketType_DeRegisterRequest)
+		if len(data) != 5+authDigestSize {
+			t.Fatalf("expected a signed de-registration, got % X", data)
+		}
+		if got := binary.BigEndian.Uint32(data[1:5]); got != s.localID {
+			t.Fatalf("expected the local ID %d, got %d", s.localID, got)
+		}
+		if !s.auth(s.localID, data) {
+			t.Fatalf("expected a valid digest, got % X", data)
+		}
+		assertNothingReceived(t, conn)
+	}
+	if peers := s.Peers(); len(peers) != 0 {
+		t.Fatalf("expected the de-registered peers forgotten, got %+v", peers)
+	}
+
+	if s.Running() {
+		t.Fatal("expected not running after Shutdown")
+	}
+	if _, _, err := s.udp.ReadFromUDP(make([]byte, 1)); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("expected the socket closed, got %v", err)
+	}
+}
+
+func TestShutdownBoundedByContext(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig(false, "")
+	cfg.IPSC.IP = "127.0.0.1"
+	s := NewIPSCServer(cfg, nil)
+
+	handling := make(chan struct{})
+	release := make(chan struct{})
+	s.SetBurstHandler(func(byte, []byte, *net.UDPAddr) {
+		close(handling)
+		<-release
+	})
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer close(release)
+
+	client, err := net.DialUDP("udp", nil, s.Addr())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer client.Close()
+	data := make([]byte, 54)
+	data[0] = byte(PacketType_GroupVoice)
+	binary.BigEndian.PutUint32(data[1:5], 33333)
+	if _, err := client.Write(data); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	<-handling
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait cut short, got %v", err)
+	}
+	if s.Running() {
+		t.Fatal("expected not running after Shutdown")
+	}
+}
+
+func TestPanicHandlerRecoversBurstHandler(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig(false, "")
+	cfg.IPSC.IP = "127.0.0.1"
+	s := NewIPSCServer(cfg, nil)
+
+	panics := make(chan any, 1)
+	s.SetPanicHandler(func(recovered any, stack []byte) {
+		if len(stack) == 0 {
+			t.Error("expected a stack trace")
+		}
+		panics <- recovered
+	})
+	s.SetBurstHandler(func(byte, []byte, *net.UDPAddr) {
+		panic("boom")
+	})
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer s.Stop()
+
+	client, err := net.DialUDP("udp", nil, s.Addr())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer client.Close()
+
+	data := make([]byte, 54)
+	data[0] = byte(PacketType_GroupVoice)
+	binary.BigEndian.PutUint32(data[1:5], 33333)
+	if _, err := client.Write(data); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	select {
+	case got := <-panics:
+		if got != "boom" {
+			t.Fatalf("expected panic value %q, got %v", "boom", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("panic was not reported")
+	}
+	if !s.Running() {
+		t.Fatal("expected the receive loop to survive a panicking burst handler")
+	}
+}
+
 func TestStopWithNilConn(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig(false, "")
@@ -1380,7 +2141,7 @@ func TestHandlerLoopWithBurstHandler(t *testing.T) {
 	})
 
 	s.wg.Add(1)
-	go s.handler()
+	go s.handler(nil)
 
 	client, err := net.DialUDP("udp", nil, srvAddr)
 	if err != nil {
@@ -1529,17 +2290,150 @@ func TestFullRegistrationFlow(t *testing.T) {
 	}
 }
 
-// --- netlink error test ---
+// --- network configuration error test ---
 
-func TestNetlinkFailsBadInterface(t *testing.T) {
+func TestConfigureNetworkFailsBadInterface(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig(false, "")
 	cfg.IPSC.Interface = "nonexistent_iface_xyz"
+	cfg.IPSC.ExistingInterface = true
 	s := NewIPSCServer(cfg, nil)
 
-	err := s.netlink()
+	err := s.configureNetwork()
 	if err == nil {
-		t.Fatal("expected netlink error for nonexistent interface")
+		t.Fatal("expected network configuration error for nonexistent interface")
+	}
+}
+
+// stubNetworkSetup fails EnsureAddress with a fixed error.
+type stubNetworkSetup struct {
+	netsetup.NetworkSetup
+	err error
+}
+
+func (n stubNetworkSetup) EnsureAddress(string, net.IP, int) error {
+	return n.err
+}
+
+func (n stubNetworkSetup) LinkExists(string) (bool, error) {
+	return true, nil
+}
+
+func TestStartFallsBackToBindOnly(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		err     error
+		wantErr bool
+	}{
+		{"configured", nil, false},
+		{"unsupported platform", fmt.Errorf("%w (windows)", netsetup.ErrUnsupportedPlatform), false},
+		{"insufficient privileges", netsetup.ErrInsufficientPrivileges, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := testConfig(false, "")
+			cfg.IPSC.IP = "127.0.0.1"
+			cfg.IPSC.Interface = "ipsc0"
+			s := NewIPSCServer(cfg, nil)
+			s.netw = stubNetworkSetup{err: tt.err}
+
+			err := s.Start()
+			defer s.Stop()
+			if tt.wantErr {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			if !s.Running() {
+				t.Fatal("expected the server to be listening")
+			}
+		})
+	}
+}
+
+// fakeLinkSetup is a NetworkSetup that records the interfaces it is asked
+// to create and delete.
+type fakeLinkSetup struct {
+	mu        sync.Mutex
+	exists    bool
+	createErr error
+	ensureErr error
+	created   []string
+	deleted   []string
+}
+
+func (n *fakeLinkSetup) LinkExists(string) (bool, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.exists, nil
+}
+
+func (n *fakeLinkSetup) EnsureAddress(string, net.IP, int) error {
+	return n.ensureErr
+}
+
+func (n *fakeLinkSetup) CreateDummy(name string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.createErr != nil {
+		return n.createErr
+	}
+	n.created = append(n.created, name)
+	return nil
+}
+
+func (n *fakeLinkSetup) Delete(name string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.deleted = append(n.deleted, name)
+	return nil
+}
+
+func TestStartCreatesMissingInterface(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		netw        *fakeLinkSetup
+		existing    bool
+		wantErr     bool
+		wantCreated bool
+		wantDeleted bool
+	}{
+		{"missing", &fakeLinkSetup{}, false, false, true, true},
+		{"adopted", &fakeLinkSetup{exists: true}, false, false, false, false},
+		{"created meanwhile", &fakeLinkSetup{createErr: fmt.Errorf("create: %w", netsetup.ErrLinkExists)}, false, false, false, false},
+		{"existing required", &fakeLinkSetup{exists: true}, true, false, false, false},
+		{"address fails", &fakeLinkSetup{ensureErr: netsetup.ErrInsufficientPrivileges}, false, true, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := testConfig(false, "")
+			cfg.IPSC.IP = "127.0.0.1"
+			cfg.IPSC.Interface = "ipsc0"
+			cfg.IPSC.SubnetMask = 24
+			cfg.IPSC.ExistingInterface = tt.existing
+			s := NewIPSCServer(cfg, nil)
+			s.netw = tt.netw
+
+			err := s.Start()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got := len(tt.netw.created) == 1; got != tt.wantCreated {
+				t.Fatalf("expected created %v, got %v", tt.wantCreated, tt.netw.created)
+			}
+			s.Stop()
+			if got := len(tt.netw.deleted) == 1; got != tt.wantDeleted {
+				t.Fatalf("expected deleted %v, got %v", tt.wantDeleted, tt.netw.deleted)
+			}
+		})
 	}
 }
 
@@ -1646,3 +2540,297 @@ func TestErrPacketIgnoredIsSentinel(t *testing.T) {
 		t.Fatalf("expected ErrPacketIgnored, got %v", err)
 	}
 }
+
+// --- peer-lost tests ---
+
+// recordPeerLost registers a peer-lost handler and returns the IDs it saw.
+func recordPeerLost(s *IPSCServer) func() []uint32 {
+	var mu sync.Mutex
+	var lost []uint32
+	s.SetPeerLostHandler(func(peerID uint32) {
+		mu.Lock()
+		defer mu.Unlock()
+		lost = append(lost, peerID)
+	})
+	return func() []uint32 {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]uint32(nil), lost...)
+	}
+}
+
+func TestHandleDeRegisterRequestFlow(t *testing.T) {
+	t.Parallel()
+	s, srvAddr := newTestServerWithUDP(t, false, "")
+	lost := recordPeerLost(s)
+
+	client, err := net.DialUDP("udp", nil, srvAddr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer client.Close()
+	clientUDPAddr, ok := client.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		t.Fatal("expected *net.UDPAddr from LocalAddr")
+	}
+
+	peerID := uint32(55555)
+	s.upsertPeer(peerID, clientUDPAddr, 0x6A, [4]byte{})
+
+	if _, err := s.handlePacket(makeControlPacket(PacketType_DeRegisterRequest, peerID), clientUDPAddr); err != nil {
+		t.Fatalf("handlePacket error: %v", err)
+	}
+	if s.peerCount() != 0 {
+		t.Fatalf("expected the peer to be removed, got %d peers", s.peerCount())
+	}
+	if got := lost(); len(got) != 1 || got[0] != peerID {
+		t.Fatalf("expected peer %d reported lost, got %v", peerID, got)
+	}
+
+	reply := readUDP(t, client)
+	if reply[0] != byte(PacketType_DeRegisterReply) {
+		t.Fatalf("expected de-register reply type 0x%02X, got 0x%02X", PacketType_DeRegisterReply, reply[0])
+	}
+	if got := binary.BigEndian.Uint32(reply[1:5]); got != s.localID {
+		t.Fatalf("expected local ID %d in reply, got %d", s.localID, got)
+	}
+
+	// De-registering an unknown peer is acknowledged but not reported.
+	if _, err := s.handlePacket(makeControlPacket(PacketType_DeRegisterRequest, peerID), clientUDPAddr); err != nil {
+		t.Fatalf("handlePacket error: %v", err)
+	}
+	readUDP(t, client)
+	if got := lost(); len(got) != 1 {
+		t.Fatalf("expected no further peer-lost reports, got %v", got)
+	}
+}
+
+func TestDeRegisterRequestFromAnotherAddressIsIgnored(t *testing.T) {
+	t.Parallel()
+	s := NewIPSCServer(testConfig(false, ""), nil)
+	lost := recordPeerLost(s)
+
+	peerID := uint32(55555)
+	s.upsertPeer(peerID, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}, 0x6A, [4]byte{})
+
+	spoofer := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 9), Port: 1234}
+	if _, err := s.handlePacket(makeControlPacket(PacketType_DeRegisterRequest, peerID), spoofer); !errors.Is(err, ErrPacketIgnored) {
+		t.Fatalf("expected ErrPacketIgnored, got %v", err)
+	}
+	if s.peerCount() != 1 {
+		t.Fatalf("expected the peer kept, got %d peers", s.peerCount())
+	}
+	if got := lost(); len(got) != 0 {
+		t.Fatalf("expected no peer-lost reports, got %v", got)
+	}
+}
+
+func TestPrunePeers(t *testing.T) {
+	t.Parallel()
+	s := NewIPSCServer(testConfig(false, ""), nil)
+	lost := recordPeerLost(s)
+
+	addr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
+	s.upsertPeer(100, addr, 0x6A, [4]byte{})
+	s.upsertPeer(200, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 1234}, 0x6A, [4]byte{})
+	s.mu.Lock()
+	s.peers[100].LastSeen = time.Now().Add(-time.Minute)
+	s.mu.Unlock()
+
+	pruned := s.prunePeers(time.Now().Add(-30 * time.Second))
+	if len(pruned) != 1 || pruned[0] != 100 {
+		t.Fatalf("expected peer 100 pruned, got %v", pruned)
+	}
+	if got := lost(); len(got) != 1 || got[0] != 100 {
+		t.Fatalf("expected peer 100 reported lost, got %v", got)
+	}
+	if s.peerCount() != 1 {
+		t.Fatalf("expected 1 peer left, got %d", s.peerCount())
+	}
+}
+
+func TestKeepalivesKeepPeersFromTimingOut(t *testing.T) {
+	t.Parallel()
+	const timeout = time.Minute
+	s := NewIPSCServer(testConfig(false, ""), nil)
+	clock := time.Unix(1_700_000_000, 0)
+	s.now = func() time.Time { return clock }
+	lost := recordPeerLost(s)
+
+	addr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
+	s.upsertPeer(100, addr, 0x6A, [4]byte{})
+	s.upsertPeer(200, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 1234}, 0x6A, [4]byte{})
+
+	// Only peer 100 keeps sending keepalives.
+	for range 3 {
+		clock = clock.Add(timeout / 2)
+		s.markPeerAlive(100, addr)
+		if pruned := s.prunePeers(s.now().Add(-timeout)); len(pruned) > 0 && pruned[0] == 100 {
+			t.Fatal("expected peer 100 kept alive by its keepalives")
+		}
+	}
+	if got := lost(); len(got) != 1 || got[0] != 200 {
+		t.Fatalf("expected only peer 200 reported lost, got %v", got)
+	}
+
+	// Once peer 100 goes quiet too, it times out.
+	clock = clock.Add(timeout + time.Second)
+	if pruned := s.prunePeers(s.now().Add(-timeout)); len(pruned) != 1 || pruned[0] != 100 {
+		t.Fatalf("expected peer 100 pruned, got %v", pruned)
+	}
+	if s.peerCount() != 0 {
+		t.Fatalf("expected no peers left, got %d", s.peerCount())
+	}
+}
+
+func TestPruneLoopDropsSilentPeers(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig(false, "")
+	cfg.IPSC.IP = "127.0.0.1"
+	cfg.IPSC.PeerTimeout = 1
+	s := NewIPSCServer(cfg, nil)
+	lostCh := make(chan uint32, 1)
+	s.SetPeerLostHandler(func(peerID uint32) { lostCh <- peerID })
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer s.Stop()
+
+	s.upsertPeer(100, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}, 0x6A, [4]byte{})
+
+	select {
+	case id := <-lostCh:
+		if id != 100 {
+			t.Fatalf("expected peer 100 reported lost, got %d", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the silent peer to be pruned")
+	}
+}
+
+func TestReRegisterFromNewAddressReportsPeerLost(t *testing.T) {
+	t.Parallel()
+	s := NewIPSCServer(testConfig(false, ""), nil)
+	lost := recordPeerLost(s)
+
+	addr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
+	s.upsertPeer(100, addr, 0x6A, [4]byte{})
+	s.upsertPeer(100, addr, 0x6A, [4]byte{})
+	if got := lost(); len(got) != 0 {
+		t.Fatalf("expected re-registration from the same address to be quiet, got %v", got)
+	}
+
+	s.upsertPeer(100, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 9), Port: 1234}, 0x6A, [4]byte{})
+	if got := lost(); len(got) != 1 || got[0] != 100 {
+		t.Fatalf("expected peer 100 reported lost, got %v", got)
+	}
+	if s.peerCount() != 1 {
+		t.Fatalf("expected the replacement to stay registered, got %d peers", s.peerCount())
+	}
+}
+
+func TestMaxPeersRefusesNewPeers(t *testing.T) {
+	t.Parallel()
+	clock := time.Unix(1_700_000_000, 0)
+	cfg := testConfig(false, "")
+	cfg.IPSC.MaxPeers = 2
+	s, _ := newTestServerWithConfig(t, cfg)
+	s.now = func() time.Time { return clock }
+	register := func(peerID uint32, addr *net.UDPAddr) error {
+		_, err := s.handlePacket(makeControlPacket(PacketType_MasterRegisterRequest, peerID), addr)
+		return err
+	}
+
+	first, firstAddr := listenPeer(t)
+	second, secondAddr := listenPeer(t)
+	third, thirdAddr := listenPeer(t)
+	if err := register(100, firstAddr); err != nil {
+		t.Fatalf("register 100: %v", err)
+	}
+	readPacketType(t, first, PacketType_MasterRegisterReply)
+	clock = clock.Add(time.Minute)
+	if err := register(200, secondAddr); err != nil {
+		t.Fatalf("register 200: %v", err)
+	}
+	readPacketType(t, second, PacketType_MasterRegisterReply)
+
+	if err := register(300, thirdAddr); !errors.Is(err, ErrPacketIgnored) {
+		t.Fatalf("expected the third peer refused, got %v", err)
+	}
+	assertNothingReceived(t, third)
+	if s.peerCount() != 2 {
+		t.Fatalf("expected 2 peers, got %d", s.peerCount())
+	}
+
+	// Registered peers may still register again.
+	if err := register(200, secondAddr); err != nil {
+		t.Fatalf("re-register 200: %v", err)
+	}
+	readPacketType(t, second, PacketType_MasterRegisterReply)
+
+	// Once a peer times out, its place is free.
+	if dropped := s.prunePeers(clock); len(dropped) != 1 || dropped[0] != 100 {
+		t.Fatalf("expected peer 100 pruned, got %v", dropped)
+	}
+	if err := register(300, thirdAddr); err != nil {
+		t.Fatalf("register 300 after a peer timed out: %v", err)
+	}
+	readPacketType(t, third, PacketType_MasterRegisterReply)
+}
+
+func TestMaxPeersCountsOnlyRegisteredPeers(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig(false, "")
+	cfg.IPSC.MaxPeers = 1
+	s, _ := newTestServerWithConfig(t, cfg)
+	s.mu.Lock()
+	s.peers[100] = &Peer{ID: 100, LastSeen: s.now()}
+	s.mu.Unlock()
+
+	peer, peerAddr := listenPeer(t)
+	if _, err := s.handlePacket(makeControlPacket(PacketType_MasterRegisterRequest, 200), peerAddr); err != nil {
+		t.Fatalf("register 200: %v", err)
+	}
+	readPacketType(t, peer, PacketType_MasterRegisterReply)
+}
+
+func TestStartMarksDSCP(t *testing.T) {
+	t.Parallel()
+	refusal := errors.New("operation not permitted")
+	tests := []struct {
+		name    string
+		dscp    uint8
+		err     error
+		wantTOS int
+	}{
+		{"unmarked", 0, nil, 0},
+		{"expedited forwarding", 46, nil, 0xB8},
+		{"refused", 46, refusal, 0xB8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := testConfig(false, "")
+			cfg.IPSC.IP = "127.0.0.1"
+			cfg.IPSC.DSCP = tt.dscp
+			s := NewIPSCServer(cfg, nil)
+			setter := &testutil.DSCPSetter{Err: tt.err}
+			s.dscpSetter = setter
+
+			err := s.Start()
+			if tt.err != nil {
+				if !errors.Is(err, dscp.ErrRefused) || !errors.Is(err, tt.err) {
+					t.Fatalf("expected the refusal reported, got %v", err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Start: %v", err)
+				}
+				s.Stop()
+			}
+			if got := setter.TOS(); got != tt.wantTOS {
+				t.Fatalf("expected ToS 0x%02X, got 0x%02X", tt.wantTOS, got)
+			}
+		})
+	}
+}
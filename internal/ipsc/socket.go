@@ -0,0 +1,160 @@
+package ipsc
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dscp"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/netsetup"
+)
+
+const (
+	// readRetries is how many reads in a row may fail with a temporary
+	// error before the socket is replaced.
+	readRetries = 5
+	// readRetryBackoff is the wait after the first failed read, doubled
+	// after each further one.
+	readRetryBackoff = 10 * time.Millisecond
+	// defaultReopenInterval is the wait between attempts to replace a
+	// failed socket.
+	defaultReopenInterval = time.Second
+)
+
+// packetConn is the socket the server sends and receives packets on.
+type packetConn interface {
+	ReadFromUDP(b []byte) (int, *net.UDPAddr, error)
+	WriteToUDP(b []byte, addr *net.UDPAddr) (int, error)
+	LocalAddr() net.Addr
+	Close() error
+}
+
+// conn returns the socket, or nil if the server has none.
+func (s *IPSCServer) conn() packetConn {
+	s.udpMu.RLock()
+	defer s.udpMu.RUnlock()
+	return s.udp
+}
+
+func (s *IPSCServer) setConn(conn packetConn) {
+	s.udpMu.Lock()
+	defer s.udpMu.Unlock()
+	s.udp = conn
+}
+
+// openSocket binds the socket to bindIP and the configured port.
+func (s *IPSCServer) openSocket(bindIP string) (packetConn, error) {
+	if s.listen != nil {
+		return s.listen()
+	}
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{
+		IP:   net.ParseIP(bindIP),
+		Port: int(s.cfg.IPSC.Port),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error starting UDP listener: %w", err)
+	}
+	if err := dscp.Mark(s.dscpSetter, conn, s.cfg.IPSC.DSCP); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error marking UDP listener: %w", err)
+	}
+	return conn, nil
+}
+
+// reopenSocket binds the socket again in place of one that failed. On a
+// configured interface it waits for the interface to be back, and gives it
+// its address again if it lost it, but never creates it itself.
+func (s *IPSCServer) reopenSocket() (packetConn, error) {
+	bindIP := s.cfg.IPSC.IP
+	if s.cfg.IPSC.BindAddress != "" {
+		bindIP = s.cfg.IPSC.BindAddress
+	} else if s.cfg.IPSC.Interface != "" && !s.cfg.IPSC.BindOnly {
+		exists, err := s.netw.LinkExists(s.cfg.IPSC.Interface)
+		switch {
+		case errors.Is(err, netsetup.ErrUnsupportedPlatform):
+		case err != nil:
+			return nil, err
+		case !exists:
+			return nil, fmt.Errorf("interface %s is missing", s.cfg.IPSC.Interface)
+		default:
+			if err := s.netw.EnsureAddress(s.cfg.IPSC.Interface, net.ParseIP(s.cfg.IPSC.IP), s.cfg.IPSC.SubnetMask); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s.openSocket(bindIP)
+}
+
+// fatalReadError reports whether err leaves the socket unusable, as when
+// its interface is deleted, rather than failing one read.
+func fatalReadError(err error) bool {
+	for _, fatal := range []error{syscall.EBADF, syscall.ENODEV, syscall.ENETDOWN, syscall.EADDRNOTAVAIL} {
+		if errors.Is(err, fatal) {
+			return true
+		}
+	}
+	return false
+}
+
+// readFailed handles the failure of the failures-th read in a row on conn
+// with err. A temporary error is retried after a backoff, up to
+// readRetries times; past that, or after a fatal error, the socket is
+// replaced. It returns false if the server stops first.
+func (s *IPSCServer) readFailed(conn packetConn, err error, failures int, stop <-chan struct{}) bool {
+	if s.metrics != nil {
+		s.metrics.IPSCUDPErrors.WithLabelValues("read").Inc()
+	}
+	if !fatalReadError(err) && failures <= readRetries {
+		s.log.Warn("error reading from UDP", "error", err, "attempt", failures)
+		select {
+		case <-time.After(readRetryBackoff << (failures - 1)):
+			return true
+		case <-stop:
+			return false
+		}
+	}
+	return s.replaceSocket(conn, err, stop)
+}
+
+// replaceSocket closes conn, which failed with cause, and binds a new
+// socket in its place, retrying every s.reopenInterval until it succeeds or
+// the server stops. Peers stay registered throughout. It returns false if
+// the server stops first.
+func (s *IPSCServer) replaceSocket(conn packetConn, cause error, stop <-chan struct{}) bool {
+	s.log.Error("IPSC socket failed, replacing it", "error", cause)
+	// The replacement needs the port.
+	_ = conn.Close() //nolint:errcheck // the socket has already failed
+	for attempt := 1; ; attempt++ {
+		replacement, err := s.reopenSocket()
+		if err == nil {
+			// Shutdown marks the server stopped before it closes the
+			// socket, so a replacement bound after that is closed here.
+			s.udpMu.Lock()
+			if s.stopped.Load() {
+				s.udpMu.Unlock()
+				replacement.Close()
+				return false
+			}
+			s.udp = replacement
+			s.udpMu.Unlock()
+			if s.metrics != nil {
+				s.metrics.IPSCSocketRecoveries.Inc()
+			}
+			s.log.Warn("IPSC socket recovered", "address", replacement.LocalAddr(), "attempts", attempt)
+			return true
+		}
+		if attempt == 1 {
+			s.log.Warn("IPSC socket cannot be replaced yet, retrying", "error", err, "interval", s.reopenInterval)
+		}
+		select {
+		case <-time.After(s.reopenInterval):
+		case <-stop:
+			return false
+		}
+		if s.stopped.Load() {
+			return false
+		}
+	}
+}
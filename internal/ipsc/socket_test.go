@@ -0,0 +1,269 @@
+package ipsc
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/metrics"
+)
+
+type fakeRead struct {
+	data []byte
+	err  error
+}
+
+// fakeConn is a packetConn whose reads are fed by the test and whose
+// writes are collected for it.
+type fakeConn struct {
+	reads     chan fakeRead
+	writes    chan []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{
+		reads:  make(chan fakeRead, 16),
+		writes: make(chan []byte, 16),
+		closed: make(chan struct{}),
+	}
+}
+
+var fakePeerAddr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 2), Port: 50000}
+
+func (c *fakeConn) ReadFromUDP(b []byte) (int, *net.UDPAddr, error) {
+	select {
+	case r := <-c.reads:
+		if r.err != nil {
+			return 0, nil, r.err
+		}
+		return copy(b, r.data), fakePeerAddr, nil
+	case <-c.closed:
+		return 0, nil, net.ErrClosed
+	}
+}
+
+func (c *fakeConn) WriteToUDP(b []byte, _ *net.UDPAddr) (int, error) {
+	c.writes <- append([]byte(nil), b...)
+	return len(b), nil
+}
+
+func (c *fakeConn) LocalAddr() net.Addr {
+	return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 50000}
+}
+
+func (c *fakeConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *fakeConn) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// expectReply waits for the server to answer a registration on conn.
+func (c *fakeConn) expectReply(t *testing.T) {
+	t.Helper()
+	select {
+	case data := <-c.writes:
+		if PacketType(data[0]) != PacketType_MasterRegisterReply {
+			t.Fatalf("expected a registration reply, got type 0x%02X", data[0])
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a reply")
+	}
+}
+
+func registerRequest() fakeRead {
+	return fakeRead{data: makeControlPacketWithModeFlags(PacketType_MasterRegisterRequest, 8000, 0x6A, [4]byte{0, 0, 0, 0x0D})}
+}
+
+// startWithConns starts a server whose sockets are conns, in order. Once
+// they run out, opening another fails.
+func startWithConns(t *testing.T, m *metrics.Metrics, conns ...*fakeConn) (*IPSCServer, *atomic.Int32) {
+	t.Helper()
+	s := NewIPSCServer(testConfig(false, ""), m)
+	s.reopenInterval = 10 * time.Millisecond
+	opened := &atomic.Int32{}
+	s.listen = func() (packetConn, error) {
+		n := int(opened.Add(1))
+		if n > len(conns) {
+			return nil, errors.New("address in use")
+		}
+		return conns[n-1], nil
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(s.Stop)
+	return s, opened
+}
+
+func TestFatalReadError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"bad descriptor", &net.OpError{Op: "read", Err: syscall.EBADF}, true},
+		{"no device", fmt.Errorf("read: %w", syscall.ENODEV), true},
+		{"network down", syscall.ENETDOWN, true},
+		{"address gone", syscall.EADDRNOTAVAIL, true},
+		{"refused", &net.OpError{Op: "read", Err: syscall.ECONNREFUSED}, false},
+		{"no buffers", syscall.ENOBUFS, false},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := fatalReadError(tt.err); got != tt.want {
+				t.Fatalf("fatalReadError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemporaryReadErrorsAreRetried(t *testing.T) {
+	t.Parallel()
+	conn := newFakeConn()
+	s, opened := startWithConns(t, nil, conn)
+
+	refused := &net.OpError{Op: "read", Err: syscall.ECONNREFUSED}
+	conn.reads <- fakeRead{err: refused}
+	conn.reads <- fakeRead{err: refused}
+	conn.reads <- registerRequest()
+	conn.expectReply(t)
+
+	if n := opened.Load(); n != 1 {
+		t.Fatalf("expected the socket kept, opened %d", n)
+	}
+	if conn.isClosed() {
+		t.Fatal("expected the socket still open")
+	}
+	if !s.Running() {
+		t.Fatal("expected the server still running")
+	}
+}
+
+func TestPersistentReadErrorsReplaceTheSocket(t *testing.T) {
+	t.Parallel()
+	first, second := newFakeConn(), newFakeConn()
+	_, opened := startWithConns(t, nil, first, second)
+
+	for range readRetries + 1 {
+		first.reads <- fakeRead{err: syscall.ENOBUFS}
+	}
+	second.reads <- registerRequest()
+	second.expectReply(t)
+
+	if n := opened.Load(); n != 2 {
+		t.Fatalf("expected the socket replaced once, opened %d", n)
+	}
+	if !first.isClosed() {
+		t.Fatal("expected the failed socket closed")
+	}
+}
+
+func TestFatalReadErrorReplacesTheSocket(t *testing.T) {
+	t.Parallel()
+	first, second := newFakeConn(), newFakeConn()
+	m := metrics.NewMetrics()
+	s, opened := startWithConns(t, m, first, second)
+	addr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
+	s.upsertPeer(100, addr, 0x6A, [4]byte{})
+
+	first.reads <- fakeRead{err: &net.OpError{Op: "read", Err: syscall.EBADF}}
+	second.reads <- registerRequest()
+	second.expectReply(t)
+
+	if n := opened.Load(); n != 2 {
+		t.Fatalf("expected the socket replaced once, opened %d", n)
+	}
+	if !first.isClosed() {
+		t.Fatal("expected the failed socket closed")
+	}
+	if s.conn() != second {
+		t.Fatal("expected the server on the new socket")
+	}
+	s.mu.RLock()
+	_, ok := s.peers[100]
+	s.mu.RUnlock()
+	if !ok {
+		t.Fatal("expected peer 100 kept across the replacement")
+	}
+	text := scrapeMetrics(t, m)
+	if !strings.Contains(text, "ipsc_socket_recoveries_total 1\n") {
+		t.Fatalf("expected one recovery in the scrape, got:\n%s", text)
+	}
+}
+
+func TestReplacementRetriesUntilBound(t *testing.T) {
+	t.Parallel()
+	first, second := newFakeConn(), newFakeConn()
+	s := NewIPSCServer(testConfig(false, ""), nil)
+	s.reopenInterval = 10 * time.Millisecond
+	var opened atomic.Int32
+	s.listen = func() (packetConn, error) {
+		switch opened.Add(1) {
+		case 1:
+			return first, nil
+		case 2, 3:
+			return nil, errors.New("address in use")
+		default:
+			return second, nil
+		}
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(s.Stop)
+
+	first.reads <- fakeRead{err: syscall.ENETDOWN}
+	second.reads <- registerRequest()
+	second.expectReply(t)
+	if n := opened.Load(); n != 4 {
+		t.Fatalf("expected three attempts to replace the socket, got %d", n-1)
+	}
+}
+
+func TestStopDuringReplacement(t *testing.T) {
+	t.Parallel()
+	conn := newFakeConn()
+	s, opened := startWithConns(t, nil, conn)
+
+	conn.reads <- fakeRead{err: syscall.EBADF}
+	deadline := time.Now().Add(2 * time.Second)
+	for opened.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the server to keep trying to replace the socket")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop did not return while the socket was being replaced")
+	}
+	if s.Running() {
+		t.Fatal("expected not running after Stop")
+	}
+}
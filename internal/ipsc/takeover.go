@@ -0,0 +1,38 @@
+package ipsc
+
+import (
+	"net"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+)
+
+// addressTakeover reports whether peer may move from its address to addr
+// under ipsc.address-takeover, and logs the move or, at most once per
+// unregisteredLogInterval per address, its refusal. A peer restored from
+// a snapshot and not heard from since may always move, as its address
+// may well have changed across the restart. The caller must hold s.mu.
+func (s *IPSCServer) addressTakeover(peer *Peer, addr *net.UDPAddr) bool {
+	if peer.Addr == nil || addr == nil {
+		return true
+	}
+	allowed := true
+	switch s.cfg.IPSC.AddressTakeover {
+	case config.AddressTakeoverStale:
+		staleAfter := time.Duration(s.cfg.IPSC.AddressStaleAfter) * time.Second
+		allowed = peer.Provisional || s.now().Sub(peer.LastSeen) >= staleAfter
+	case config.AddressTakeoverAuthenticated:
+		// Only authenticated packets get this far with authentication
+		// enabled.
+		allowed = peer.Provisional || s.cfg.IPSC.Auth.Enabled
+	}
+	if !allowed {
+		if s.refusalLogDue(addr) {
+			s.log.Warn("Refusing to move IPSC peer to a new address", "peerID", peer.ID,
+				"oldAddr", peer.Addr, "newAddr", addr, "policy", s.cfg.IPSC.AddressTakeover)
+		}
+		return false
+	}
+	s.log.Warn("IPSC peer moved to a new address", "peerID", peer.ID, "oldAddr", peer.Addr, "newAddr", addr)
+	return true
+}
@@ -0,0 +1,128 @@
+package ipsc
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+)
+
+func TestAddressTakeoverPolicies(t *testing.T) {
+	t.Parallel()
+	const key = "0000000000000000000000000000000000001234"
+	tests := []struct {
+		name   string
+		policy config.AddressTakeover
+		auth   bool
+		// wantSoon is whether the new address takes the peer ID over
+		// while the old one is still heard from. Under every policy it
+		// does once the old one has gone quiet.
+		wantSoon bool
+	}{
+		{"unset", "", false, true},
+		{"immediate", config.AddressTakeoverImmediate, false, true},
+		{"stale", config.AddressTakeoverStale, false, false},
+		{"authenticated", config.AddressTakeoverAuthenticated, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			clock := time.Unix(1_700_000_000, 0)
+			cfg := testConfig(tt.auth, "1234")
+			cfg.IPSC.AddressTakeover = tt.policy
+			cfg.IPSC.AddressStaleAfter = 30
+			s, _ := newTestServerWithConfig(t, cfg)
+			s.now = func() time.Time { return clock }
+			oldConn, oldAddr := listenPeer(t)
+			newConn, newAddr := listenPeer(t)
+
+			send := func(data []byte, addr *net.UDPAddr) error {
+				t.Helper()
+				if tt.auth {
+					data = signPacket(t, data, key)
+				}
+				_, err := s.handlePacket(data, addr)
+				return err
+			}
+			register := func(addr *net.UDPAddr) error {
+				t.Helper()
+				return send(makeControlPacket(PacketType_MasterRegisterRequest, 8000), addr)
+			}
+			voice := makeTestIPSCPacket(0x80, ipscBurstSlot1, true, false)
+			binary.BigEndian.PutUint32(voice[1:5], 8000)
+			peerAddr := func() string {
+				t.Helper()
+				s.mu.RLock()
+				defer s.mu.RUnlock()
+				return s.peers[8000].Addr.String()
+			}
+
+			if err := register(oldAddr); err != nil {
+				t.Fatalf("register: %v", err)
+			}
+			readPacketType(t, oldConn, PacketType_MasterRegisterReply)
+
+			// Voice and registrations from the new address while the
+			// old one is fresh.
+			clock = clock.Add(5 * time.Second)
+			if err := send(voice, newAddr); err != nil {
+				t.Fatalf("voice: %v", err)
+			}
+			if tt.wantSoon {
+				if got := peerAddr(); got != newAddr.String() {
+					t.Fatalf("expected voice to move the peer to %s, got %s", newAddr, got)
+				}
+				return
+			}
+			if got := peerAddr(); got != oldAddr.String() {
+				t.Fatalf("expected the peer kept at %s, got %s", oldAddr, got)
+			}
+			if err := register(newAddr); err == nil {
+				t.Fatal("expected the registration from the new address refused")
+			}
+			assertNothingReceived(t, newConn)
+			if got := peerAddr(); got != oldAddr.String() {
+				t.Fatalf("expected the peer kept at %s, got %s", oldAddr, got)
+			}
+
+			// Once the old address has gone quiet, the new one takes
+			// over.
+			clock = clock.Add(30 * time.Second)
+			if err := register(newAddr); err != nil {
+				t.Fatalf("register from the new address: %v", err)
+			}
+			readPacketType(t, newConn, PacketType_MasterRegisterReply)
+			if got := peerAddr(); got != newAddr.String() {
+				t.Fatalf("expected the peer moved to %s, got %s", newAddr, got)
+			}
+		})
+	}
+}
+
+func TestAddressTakeoverOfRestoredPeer(t *testing.T) {
+	t.Parallel()
+	clock := time.Unix(1_700_000_000, 0)
+	cfg := testConfig(false, "")
+	cfg.IPSC.AddressTakeover = config.AddressTakeoverStale
+	cfg.IPSC.AddressStaleAfter = 30
+	s, _ := newTestServerWithConfig(t, cfg)
+	s.now = func() time.Time { return clock }
+	_, oldAddr := listenPeer(t)
+	newConn, newAddr := listenPeer(t)
+
+	// A restored peer's address is from before the restart, and may
+	// have changed since.
+	s.RestorePeers([]Peer{{ID: 8000, Addr: oldAddr, Mode: 0x6A, LastSeen: clock}})
+	if _, err := s.handlePacket(makeControlPacket(PacketType_MasterRegisterRequest, 8000), newAddr); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	readPacketType(t, newConn, PacketType_MasterRegisterReply)
+	s.mu.RLock()
+	got := s.peers[8000].Addr.String()
+	s.mu.RUnlock()
+	if got != newAddr.String() {
+		t.Fatalf("expected the restored peer moved to %s, got %s", newAddr, got)
+	}
+}
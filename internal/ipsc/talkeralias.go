@@ -0,0 +1,55 @@
+package ipsc
+
+import "github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/bptc"
+
+// Talker alias link controls, ETSI TS 102 361-2 7.1.1.1: a header and up
+// to three blocks, each sent embedded in a superframe of its own in place
+// of the voice link control.
+const (
+	flcoTalkerAliasHeader byte = 0x04
+	flcoTalkerAliasBlock3 byte = 0x07
+)
+
+// maxPendingAliases bounds the talker alias link controls waiting for a
+// superframe toward IPSC. A full alias is four of them.
+const maxPendingAliases = 8
+
+// isTalkerAlias reports whether lc is a talker alias header or block.
+func isTalkerAlias(lc [bptc.LCSize]byte) bool {
+	flco := lc[0] & 0x3F
+	return flco >= flcoTalkerAliasHeader && flco <= flcoTalkerAliasBlock3
+}
+
+// noteEmbeddedFragment records the embedded link control fragment the
+// master sent in voice burst B to E, at position burstIdx. When burst E
+// completes a talker alias link control, it is queued to be sent on to
+// IPSC.
+func (ss *streamState) noteEmbeddedFragment(burstIdx int, fragment [bptc.FragmentSize]byte) {
+	ss.masterLC[burstIdx-1] = fragment
+	if burstIdx != 4 {
+		return
+	}
+	lc, ok := bptc.DecodeEmbeddedLC(ss.masterLC)
+	if !ok || !isTalkerAlias(lc) || len(ss.aliases) >= maxPendingAliases {
+		return
+	}
+	ss.aliases = append(ss.aliases, bptc.EncodeEmbeddedLC(lc))
+}
+
+// superframeLC returns the link control to embed in bursts B to E of the
+// superframe toward IPSC that starts with burst B: the next talker alias
+// link control from the master, or else the call's own. It is picked at
+// burst B so that one superframe never mixes the two.
+func (ss *streamState) superframeLC(burstIdx int) bptc.Fragments {
+	if burstIdx == 1 {
+		ss.sendingAlias = len(ss.aliases) > 0
+		if ss.sendingAlias {
+			ss.alias = ss.aliases[0]
+			ss.aliases = ss.aliases[1:]
+		}
+	}
+	if ss.sendingAlias {
+		return ss.alias
+	}
+	return ss.embeddedLC
+}
@@ -0,0 +1,179 @@
+package ipsc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/USA-RedDragon/dmrgo/dmr/enums"
+	"github.com/USA-RedDragon/dmrgo/dmr/layer2"
+	"github.com/USA-RedDragon/dmrgo/dmr/layer2/elements"
+	"github.com/USA-RedDragon/dmrgo/dmr/layer2/pdu"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/bptc"
+	mmdvm "github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+)
+
+// talkerAliasFormatUTF8 is the talker alias data format of UTF-8 text.
+const talkerAliasFormatUTF8 = 2
+
+// encodeTalkerAlias splits alias into a talker alias header and three
+// blocks. The header carries 6 octets of 8-bit text and each block 7.
+func encodeTalkerAlias(t *testing.T, alias string) [][bptc.LCSize]byte {
+	t.Helper()
+	text := []byte(alias)
+	if len(text) > 27 {
+		t.Fatalf("alias %q is too long", alias)
+	}
+	padded := make([]byte, 27)
+	copy(padded, text)
+
+	lcs := make([][bptc.LCSize]byte, 4)
+	lcs[0][0] = flcoTalkerAliasHeader
+	lcs[0][2] = talkerAliasFormatUTF8<<6 | byte(len(text))<<1
+	copy(lcs[0][3:], padded[:6])
+	for i := 1; i < 4; i++ {
+		lcs[i][0] = flcoTalkerAliasHeader + byte(i)
+		copy(lcs[i][2:], padded[6+7*(i-1):])
+	}
+	return lcs
+}
+
+// decodeTalkerAlias reassembles the text of the talker alias link
+// controls among lcs.
+func decodeTalkerAlias(t *testing.T, lcs [][bptc.LCSize]byte) string {
+	t.Helper()
+	var header *[bptc.LCSize]byte
+	blocks := make([][]byte, 3)
+	for i := range lcs {
+		switch flco := lcs[i][0] & 0x3F; {
+		case flco == flcoTalkerAliasHeader:
+			header = &lcs[i]
+		case flco > flcoTalkerAliasHeader && flco <= flcoTalkerAliasBlock3:
+			blocks[flco-flcoTalkerAliasHeader-1] = lcs[i][2:]
+		}
+	}
+	if header == nil {
+		t.Fatal("no talker alias header received")
+	}
+	if format := header[2] >> 6; format != talkerAliasFormatUTF8 {
+		t.Fatalf("expected UTF-8 format, got %d", format)
+	}
+	text := append([]byte(nil), header[3:]...)
+	for i, block := range blocks {
+		if block == nil {
+			t.Fatalf("talker alias block %d not received", i+1)
+		}
+		text = append(text, block...)
+	}
+	return string(text[:header[2]>>1&0x1F])
+}
+
+// makeEmbeddedVoiceBurst returns a voice burst at superframe position
+// pos carrying fragment of an embedded link control.
+func makeEmbeddedVoiceBurst(pos int, fragment [bptc.FragmentSize]byte) [33]byte {
+	if pos == 0 {
+		return makeVoiceDMRData(true)
+	}
+	lcss := enums.ContinuationFragmentLCorCSBK
+	switch pos {
+	case 1:
+		lcss = enums.FirstFragmentLC
+	case 4:
+		lcss = enums.LastFragmentLCorCSBK
+	case 5:
+		lcss = enums.SingleFragmentLCorCSBK
+	}
+	burst := layer2.Burst{
+		SyncPattern:           enums.EmbeddedSignallingPattern,
+		VoiceBurst:            enums.VoiceBurstB,
+		HasEmbeddedSignalling: true,
+		EmbeddedSignalling:    pdu.EmbeddedSignalling{LCSS: lcss, ParityOK: true},
+	}
+	burst.UnpackEmbeddedSignallingData(fragment[:])
+	return burst.Encode()
+}
+
+// embeddedLCs reassembles the link controls embedded in the voice
+// bursts of packets, a superframe at a time.
+func embeddedLCs(t *testing.T, packets []mmdvm.Packet) [][bptc.LCSize]byte {
+	t.Helper()
+	var lcs [][bptc.LCSize]byte
+	var fragments bptc.Fragments
+	for _, pkt := range packets {
+		if pkt.FrameType != mmdvmFrameTypeVoice || pkt.DTypeOrVSeq < 1 || pkt.DTypeOrVSeq > 4 {
+			continue
+		}
+		burst := layer2.NewBurstFromBytes(pkt.DMRData)
+		fragments[pkt.DTypeOrVSeq-1] = burst.PackEmbeddedSignallingData()
+		if pkt.DTypeOrVSeq == 4 {
+			lc, ok := bptc.DecodeEmbeddedLC(fragments)
+			if !ok {
+				t.Fatalf("invalid embedded link control in packet %d", pkt.Seq)
+			}
+			lcs = append(lcs, lc)
+		}
+	}
+	return lcs
+}
+
+func TestTalkerAliasRoundTrip(t *testing.T) {
+	t.Parallel()
+	const alias = "N0CALL Jürgen Köln"
+	tx := newTestTranslator(t)
+	rx := newTestTranslator(t)
+
+	header := makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 1)
+	var toIPSC [][]byte
+	toIPSC = append(toIPSC, tx.TranslateToIPSC(header)...)
+
+	// The master sends the voice link control, then the alias a block a
+	// superframe, then the voice link control again.
+	voiceLC := embeddedLC(extractFullLCBytes(header, elements.DataTypeVoiceLCHeader))
+	superframes := []bptc.Fragments{voiceLC}
+	for _, lc := range encodeTalkerAlias(t, alias) {
+		superframes = append(superframes, bptc.EncodeEmbeddedLC(lc))
+	}
+	superframes = append(superframes, voiceLC, voiceLC)
+	for _, lc := range superframes {
+		for pos := range 6 {
+			pkt := makeTestMMDVMPacket(true, false, mmdvmFrameTypeVoice, uint(pos)) //nolint:gosec // G115: pos is in [0,5]
+			if pos == 0 {
+				pkt.FrameType = mmdvmFrameTypeVoiceSync
+			}
+			var fragment [bptc.FragmentSize]byte
+			if pos >= 1 && pos <= 4 {
+				fragment = lc[pos-1]
+			}
+			pkt.DMRData = makeEmbeddedVoiceBurst(pos, fragment)
+			toIPSC = append(toIPSC, tx.TranslateToIPSC(pkt)...)
+		}
+	}
+	toIPSC = append(toIPSC, tx.TranslateToIPSC(makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 2))...)
+
+	var toMMDVM []mmdvm.Packet
+	for _, data := range toIPSC {
+		toMMDVM = append(toMMDVM, rx.TranslateToMMDVM(data[0], data)...)
+	}
+	lcs := embeddedLCs(t, toMMDVM)
+	if len(lcs) != len(superframes) {
+		t.Fatalf("expected %d superframes, got %d", len(superframes), len(lcs))
+	}
+	if got := decodeTalkerAlias(t, lcs); got != alias {
+		t.Fatalf("expected alias %q, got %q", alias, got)
+	}
+
+	// The alias takes the place of the voice link control, which is
+	// sent in the other superframes.
+	aliases := 0
+	for _, lc := range lcs {
+		if isTalkerAlias(lc) {
+			aliases++
+			continue
+		}
+		if want, _ := bptc.DecodeEmbeddedLC(voiceLC); !bytes.Equal(lc[:], want[:]) {
+			t.Fatalf("expected the voice link control % X, got % X", want, lc)
+		}
+	}
+	if aliases != 4 {
+		t.Fatalf("expected 4 alias superframes, got %d", aliases)
+	}
+}
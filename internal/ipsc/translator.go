@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"log/slog"
 	"math"
+	"math/rand/v2"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/USA-RedDragon/dmrgo/dmr/enums"
 	"github.com/USA-RedDragon/dmrgo/dmr/layer2"
@@ -13,10 +16,37 @@ import (
 	"github.com/USA-RedDragon/dmrgo/dmr/layer2/pdu"
 	l3elements "github.com/USA-RedDragon/dmrgo/dmr/layer3/elements"
 	"github.com/USA-RedDragon/dmrgo/dmr/vocoder"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/bptc"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/crc"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/csbk"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/datablock"
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/metrics"
 	mmdvm "github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
 )
 
+// Translator converts calls between an MMDVM master and IPSC peers.
+// IPSCTranslator is the implementation used outside of tests.
+type Translator interface {
+	// TranslateToIPSC converts a DMRD packet from the master into the
+	// IPSC packets to send to the peers, or nil if it is dropped.
+	TranslateToIPSC(pkt mmdvm.Packet) [][]byte
+	// TranslateToMMDVM converts an IPSC packet of packetType from a peer
+	// into the DMRD packets to send to the master, or nil if it is dropped.
+	TranslateToMMDVM(packetType byte, data []byte) []mmdvm.Packet
+	// CleanupStream forgets a call toward IPSC that stopped without a
+	// terminator.
+	CleanupStream(streamID uint32)
+	// TerminatePeerStreams ends the calls toward the master that arrived
+	// from peerID, returning a terminator for each.
+	TerminatePeerStreams(peerID uint32) []mmdvm.Packet
+	// TerminateStreams ends every call toward the master, returning a
+	// terminator for each.
+	TerminateStreams() []mmdvm.Packet
+	// SetPeerID sets the peer ID the translated IPSC packets are sent as.
+	SetPeerID(peerID uint32)
+}
+
 // IPSCTranslator converts MMDVM DMRD packets into IPSC user packets.
 // It maintains per-stream state (RTP sequence, timestamp, call control)
 // and uses the dmrgo library to FEC-decode AMBE voice data from the
@@ -27,29 +57,89 @@ import (
 type IPSCTranslator struct {
 	mu             sync.Mutex
 	metrics        *metrics.Metrics
+	sink           MetricsSink
 	peerID         uint32
 	repeaterID     uint32
+	swapSlots      bool
+	rtp            config.IPSCRTP
+	reverseChannel config.ReverseChannelPolicy
+	remoteCommands *remoteCommandPolicy
+	audit          *slog.Logger
 	streams        map[uint32]*streamState
-	reverseStreams map[uint32]*reverseStreamState
+	reverseStreams map[reverseStreamKey]*reverseStreamState
 	burst          layer2.Burst // reusable burst to reduce allocations
+	headerRepeats  int          // copies of each voice header sent to IPSC
+	colorCode      uint8        // of bursts built for the master
+
+	// maxStreams bounds reverseStreams. Past it, the least recently
+	// active call from IPSC is evicted, and evictions counted.
+	maxStreams int
+	evictions  uint64
+
+	// unknownBursts counts the packets from IPSC dropped for their burst
+	// type, by burst type; unknownBurstHandler is given each of them.
+	unknownBursts       map[byte]uint64
+	unknownBurstHandler func(data []byte)
+
+	// wakeUpIdle is how long nothing must have been sent to IPSC before
+	// a new call is preceded by a repeater wake-up packet. Zero never
+	// sends one.
+	wakeUpIdle time.Duration
+	lastSent   time.Time
+	now        func() time.Time
 
 	nextCallControl uint32
 	nextStreamID    uint32
+
+	// ssrcIndex numbers the streams given an SSRC in per-stream mode.
+	ssrcIndex uint32
+
+	// recentCallControls holds the most recently allocated call-control
+	// IDs, oldest first, so new calls never reuse one a repeater may still
+	// associate with an earlier call.
+	recentCallControls []uint32
+
+	// callHistory holds the statistics of the most recently finished
+	// calls, oldest first.
+	callHistory []*CallStats
 }
 
+// recentCallControlSize is how many call-control IDs the translator remembers.
+const recentCallControlSize = 16
+
 // streamState tracks RTP sequencing and call framing for one voice stream.
 type streamState struct {
 	callControl  uint32 // random per-call
+	ssrc         uint32
+	slot         bool // as the master sees it
 	rtpSeq       uint16
 	rtpTimestamp uint32
 	ipscSeq      uint8
-	headersSent  int  // number of voice headers sent (3 required)
+	headersSent  int  // number of voice headers sent
 	burstIndex   int  // 0-5 → A-F
 	firstPacket  bool // true for the very first packet
+	lateLC       lateEntryLC
+
+	// embeddedLC is the call's link control as carried in bursts B-E,
+	// coded once per call.
+	embeddedLC    bptc.Fragments
+	hasEmbeddedLC bool
+
+	// masterLC collects the link control fragments the master embeds in
+	// bursts B-E. Talker alias link controls found in them wait in
+	// aliases for a superframe toward IPSC; alias is the one being sent
+	// while sendingAlias is set.
+	masterLC     bptc.Fragments
+	aliases      []bptc.Fragments
+	alias        bptc.Fragments
+	sendingAlias bool
+
+	stats CallStats
 }
 
 // IPSC burst data type constants (byte 30 of IPSC voice packet)
 const (
+	ipscBurstPIHeader  byte = 0x00
 	ipscBurstVoiceHead byte = 0x01
 	ipscBurstVoiceTerm byte = 0x02
 	ipscBurstCSBK      byte = 0x03
@@ -57,6 +147,20 @@ const (
 	ipscBurstSlot2     byte = 0x8A
 )
 
+// IPSC call type constants (byte 12 of IPSC user packets)
+const (
+	ipscCallTypePrivate byte = 0x01
+	ipscCallTypeGroup   byte = 0x02
+	ipscCallTypeAllCall byte = 0x03
+)
+
+// maxID is the largest DMR ID the 24-bit address fields of IPSC hold.
+const maxID = 0xFFFFFF
+
+// allCallID is the DMR all call address, ALLMSID. An all call is a group
+// call to it whatever call type it was sent as.
+const allCallID = 0xFFFFFF
+
 // MMDVM FrameType values (bits 2-3 of DMRD byte 15)
 const (
 	mmdvmFrameTypeVoice     uint = 0 // Voice data
@@ -64,61 +168,187 @@ const (
 	mmdvmFrameTypeDataSync  uint = 2 // Data sync (header / terminator)
 )
 
-// RTP timestamp increment per burst (~60ms spacing in 16.16 format)
+// RTP timestamp increment per burst: one 60ms DMR burst at the 8kHz
+// RTP clock.
 const rtpTimestampIncrement = 480
 
+// Default RTP payload types, which common Mototrbo firmware expects.
+const (
+	defaultRTPPayloadType           byte = 0x5D
+	defaultRTPTerminatorPayloadType byte = 0x5E
+)
+
+// defaultHeaderRepeats is how many copies of each voice header are sent
+// to IPSC unless configured otherwise.
+const defaultHeaderRepeats = 3
+
+// defaultMaxStreams is how many calls from IPSC are tracked at once
+// unless configured otherwise.
+const defaultMaxStreams = 64
+
 func NewIPSCTranslator() (*IPSCTranslator, error) {
 	return &IPSCTranslator{
+		rtp: config.IPSCRTP{
+			PayloadType:           defaultRTPPayloadType,
+			TerminatorPayloadType: defaultRTPTerminatorPayloadType,
+			SSRCMode:              config.RTPSSRCFixed,
+		},
+		reverseChannel: config.ReverseChannelForward,
+		headerRepeats:  defaultHeaderRepeats,
+		maxStreams:     defaultMaxStreams,
+		sink:           noopSink{},
 		streams:        make(map[uint32]*streamState),
-		reverseStreams: make(map[uint32]*reverseStreamState),
+		reverseStreams: make(map[reverseStreamKey]*reverseStreamState),
+		now:            time.Now,
 	}, nil
 }
 
-// SetMetrics configures the metrics collector for this translator.
+// SetMetrics configures the metrics collector for this translator, in
+// place of any MetricsSink set before.
 func (t *IPSCTranslator) SetMetrics(m *metrics.Metrics) {
 	t.metrics = m
+	if m == nil {
+		t.SetMetricsSink(nil)
+		return
+	}
+	t.SetMetricsSink(newPrometheusSink(m))
 }
 
 // SetPeerID sets the local peer ID used in outgoing IPSC packets.
 func (t *IPSCTranslator) SetPeerID(peerID uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.peerID = peerID
 	t.repeaterID = peerID
 }
 
+// SetSwapSlots makes the translator exchange TS1 and TS2 between the IPSC
+// and MMDVM sides, for sites whose repeaters carry network traffic on
+// the opposite slot. Packets returned by TranslateToMMDVM carry the
+// swapped slot, so everything downstream sees the MMDVM-side slot.
+func (t *IPSCTranslator) SetSwapSlots(swap bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.swapSlots = swap
+}
+
+// SetRTP sets the RTP payload types and SSRC scheme of outgoing IPSC
+// packets. The zero value, as in configs built in code rather than
+// loaded, keeps the defaults.
+func (t *IPSCTranslator) SetRTP(rtp config.IPSCRTP) {
+	if rtp == (config.IPSCRTP{}) {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rtp = rtp
+}
+
+// SetHeaderRepeats sets how many copies of each voice header are sent to
+// IPSC: fewer for low-bandwidth links, more for lossy ones. Zero keeps the
+// default of 3.
+func (t *IPSCTranslator) SetHeaderRepeats(n uint) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n == 0 {
+		t.headerRepeats = defaultHeaderRepeats
+		return
+	}
+	t.headerRepeats = int(n) //nolint:gosec // G115: validated to at most 5
+}
+
+// SetMaxStreams sets how many calls from IPSC are tracked at once. A peer
+// sending bursts under ever new call controls could otherwise grow the
+// state without limit. Zero keeps the default of 64.
+func (t *IPSCTranslator) SetMaxStreams(n uint) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n == 0 {
+		t.maxStreams = defaultMaxStreams
+		return
+	}
+	t.maxStreams = int(min(n, math.MaxInt32)) //nolint:gosec // G115: clamped to MaxInt32
+}
+
+// SetWakeUp makes a call toward IPSC start with a repeater wake-up packet
+// when nothing has been sent for idle, so a repeater keying up from idle
+// does not lose the start of the call. Zero, the default, never sends one.
+func (t *IPSCTranslator) SetWakeUp(idle time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.wakeUpIdle = idle
+}
+
+// SetColorCode sets the color code coded into the slot type and embedded
+// signalling of bursts built for the master. It defaults to 0.
+func (t *IPSCTranslator) SetColorCode(cc uint8) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.colorCode = cc
+}
+
 // TranslateToIPSC converts an MMDVM DMRD Packet into one or more IPSC
 // user packets ready to send to IPSC peers. It returns nil if the packet
 // cannot be translated (e.g. non-voice data we don't handle yet).
-func (t *IPSCTranslator) TranslateToIPSC(pkt mmdvm.Packet) [][]byte {
+func (t *IPSCTranslator) TranslateToIPSC(pkt mmdvm.Packet) (results [][]byte) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
+	masterSlot := pkt.Slot
+	if pkt.Dst == allCallID {
+		pkt.GroupCall = true
+	}
+	if t.swapSlots {
+		pkt.Slot = !pkt.Slot
+	}
+
 	streamID := pkt.StreamID
 	if streamID > math.MaxUint32 {
 		return nil
 	}
 
+	if !validIDs(pkt.Src, pkt.Dst) {
+		slog.Debug("IPSCTranslator: dropping packet with an invalid ID", "src", pkt.Src, "dst", pkt.Dst)
+		t.countDropped("mmdvm_to_ipsc", "invalid_id")
+		return nil
+	}
+
+	if pkt.FrameType == mmdvmFrameTypeReverseChannel {
+		return t.reverseChannelToIPSC(pkt)
+	}
+
+	var command *csbk.Block
+	if pkt.FrameType == mmdvmFrameTypeDataSync && pkt.DTypeOrVSeq == uint(elements.DataTypeCSBK) {
+		var ok bool
+		if command, ok = t.admitRemoteCommandToIPSC(pkt); !ok {
+			return nil
+		}
+	}
+
 	// Get or create stream state
 	ss, ok := t.streams[uint32(streamID)]
+	wakeUp := false
 	if !ok {
-		t.nextCallControl++
-		if t.nextCallControl == 0 {
-			t.nextCallControl = 1
-		}
-		ss = &streamState{
-			callControl: t.nextCallControl,
-			firstPacket: true,
+		wakeUp = t.wakeUpIdle > 0 && t.now().Sub(t.lastSent) >= t.wakeUpIdle
+		ss = t.newStreamState()
+		ss.slot = masterSlot
+		ss.stats = CallStats{
+			StreamID:  uint32(streamID),
+			Direction: "mmdvm_to_ipsc",
+			Src:       pkt.Src,
+			Dst:       pkt.Dst,
+			GroupCall: pkt.GroupCall,
+			Slot:      masterSlot,
+			Start:     t.now(),
 		}
 		t.streams[uint32(streamID)] = ss
-		if t.metrics != nil {
-			t.metrics.TranslatorActiveStreams.WithLabelValues("mmdvm_to_ipsc").Inc()
-		}
+		t.reportActiveStreams()
 	}
+	defer func() { ss.stats.count(len(results) > 0) }()
 
 	frameType := pkt.FrameType
 	dtypeOrVSeq := pkt.DTypeOrVSeq
 
-	var results [][]byte
-
 	switch frameType {
 	case mmdvmFrameTypeDataSync:
 		if dtypeOrVSeq > 255 {
@@ -128,26 +358,62 @@ func (t *IPSCTranslator) TranslateToIPSC(pkt mmdvm.Packet) [][]byte {
 		// Voice LC Header, Terminator, or Data
 		switch elements.DataType(dtypeOrVSeq) {
 		case elements.DataTypeVoiceLCHeader:
-			// Send voice header (IPSC sends 3 copies)
-			for i := 0; i < 3; i++ {
+			// Send the voice header, repeated so a lost copy doesn't
+			// lose the call
+			for i := range t.headerRepeats {
 				data := t.buildVoiceHeader(pkt, ss, i == 0 && ss.firstPacket)
 				results = append(results, data)
 			}
-			ss.headersSent = 3
+			ss.headersSent = t.headerRepeats
 			ss.firstPacket = false
 			ss.burstIndex = 0
 		case elements.DataTypeTerminatorWithLC:
+			if ss.firstPacket {
+				// A terminator is the whole call: lead it with the
+				// header IPSC peers need to start one.
+				for i := range t.headerRepeats {
+					results = append(results, t.buildVoiceHeader(pkt, ss, i == 0))
+				}
+				ss.firstPacket = false
+			}
 			data := t.buildVoiceTerminator(pkt, ss)
 			results = append(results, data)
 			// Clean up stream state
 			delete(t.streams, uint32(streamID))
-			if t.metrics != nil {
-				t.metrics.TranslatorActiveStreams.WithLabelValues("mmdvm_to_ipsc").Dec()
+			t.finishCall(&ss.stats)
+			t.reportActiveStreams()
+		case elements.DataTypeDataHeader, elements.DataTypeRate12,
+			elements.DataTypeRate34, elements.DataTypeRate1:
+			// Data call — carry the block's information octets
+			data := t.buildIPSCDataBlock(pkt, ss, elements.DataType(dtypeOrVSeq))
+			if data == nil {
+				return nil
+			}
+			results = append(results, data)
+			ss.firstPacket = false
+		case elements.DataTypeCSBK:
+			// CSBK — carry the block itself, opcode and CRC included
+			var data []byte
+			if command != nil {
+				data = t.buildIPSCDataPayload(pkt, ss, elements.DataTypeCSBK, command[:])
+			} else {
+				data = t.buildIPSCCSBK(pkt, ss)
+			}
+			if data == nil {
+				return nil
+			}
+			results = append(results, data)
+			ss.firstPacket = false
+		case elements.DataTypePIHeader:
+			// Privacy indicator header — carried in the voice call it
+			// precedes so the far end knows why the call is unreadable
+			data := t.buildIPSCPIHeader(pkt, ss)
+			if data == nil {
+				return nil
 			}
-		case elements.DataTypeCSBK, elements.DataTypePIHeader,
-			elements.DataTypeDataHeader, elements.DataTypeRate12,
-			elements.DataTypeRate34, elements.DataTypeRate1,
-			elements.DataTypeMBCHeader, elements.DataTypeMBCContinuation:
+			results = append(results, data)
+			ss.firstPacket = false
+		case elements.DataTypeMBCHeader, elements.DataTypeMBCContinuation:
 			// Data packet — build IPSC data packet
 			data := t.buildIPSCDataPacket(pkt, ss, elements.DataType(dtypeOrVSeq))
 			results = append(results, data)
@@ -161,9 +427,12 @@ func (t *IPSCTranslator) TranslateToIPSC(pkt mmdvm.Packet) [][]byte {
 
 	case mmdvmFrameTypeVoice, mmdvmFrameTypeVoiceSync:
 		// Voice burst — decode DMR data and extract AMBE
+		ss.burstIndex = voiceBurstIndex(pkt, ss.burstIndex)
+		results = append(results, t.lateEntryHeaders(pkt, ss)...)
 		data := t.buildVoiceBurst(pkt, ss)
 		if data != nil {
 			results = append(results, data)
+			ss.firstPacket = false
 		}
 		// Advance burst index (A=0 through F=5, then wrap)
 		ss.burstIndex = (ss.burstIndex + 1) % 6
@@ -173,18 +442,190 @@ func (t *IPSCTranslator) TranslateToIPSC(pkt mmdvm.Packet) [][]byte {
 		return nil
 	}
 
-	if t.metrics != nil && len(results) > 0 {
-		t.metrics.TranslatorPackets.WithLabelValues("mmdvm_to_ipsc").Add(float64(len(results)))
+	if len(results) > 0 {
+		if wakeUp {
+			results = append([][]byte{t.buildWakeUp(pkt, ss)}, results...)
+		}
+		t.lastSent = t.now()
 	}
 
+	t.countToIPSC(results)
+
+	return results
+}
+
+// DataBlock is one burst of a data call built by BuildDataCall.
+type DataBlock struct {
+	Type    elements.DataType
+	Payload [12]byte
+}
+
+// BuildDataCall encodes a complete data call originated locally as
+// IPSC user packets, one per block, under a call control of its own. The
+// first block is normally the data header. slot is the IPSC-side slot
+// and is not subject to SetSwapSlots.
+func (t *IPSCTranslator) BuildDataCall(src, dst uint, groupCall, slot bool, blocks []DataBlock) [][]byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pkt := mmdvm.Packet{Src: src, Dst: dst, GroupCall: groupCall, Slot: slot}
+	ss := t.newStreamState()
+	results := make([][]byte, 0, len(blocks))
+	for _, block := range blocks {
+		results = append(results, t.buildIPSCDataPayload(pkt, ss, block.Type, block.Payload[:]))
+		ss.firstPacket = false
+	}
+	t.countToIPSC(results)
 	return results
 }
 
+// newStreamState starts the state of a call sent to IPSC. Must be called
+// with t.mu held.
+func (t *IPSCTranslator) newStreamState() *streamState {
+	return &streamState{
+		callControl: t.allocateCallControl(),
+		ssrc:        t.callSSRC(),
+		firstPacket: true,
+	}
+}
+
+// callSSRC returns the RTP SSRC for a new call. Must be called with t.mu
+// held.
+func (t *IPSCTranslator) callSSRC() uint32 {
+	switch t.rtp.SSRCMode {
+	case config.RTPSSRCPeerID:
+		return t.peerID
+	case config.RTPSSRCRandom:
+		return rand.Uint32() //nolint:gosec // SSRCs need not be unpredictable
+	case config.RTPSSRCPerStream:
+		ssrc := t.rtp.SSRC + t.ssrcIndex
+		t.ssrcIndex++
+		return ssrc
+	default:
+		return t.rtp.SSRC
+	}
+}
+
+// allocateCallControl returns the next call-control ID that is not in the
+// recent ring and records it there. Must be called with t.mu held.
+func (t *IPSCTranslator) allocateCallControl() uint32 {
+	for {
+		t.nextCallControl++
+		if t.nextCallControl == 0 {
+			t.nextCallControl = 1
+		}
+		if !slices.Contains(t.recentCallControls, t.nextCallControl) {
+			break
+		}
+	}
+	t.recentCallControls = append(t.recentCallControls, t.nextCallControl)
+	if len(t.recentCallControls) > recentCallControlSize {
+		t.recentCallControls = t.recentCallControls[len(t.recentCallControls)-recentCallControlSize:]
+	}
+	return t.nextCallControl
+}
+
+// RecentCallControls returns the recently allocated call-control IDs,
+// oldest first.
+func (t *IPSCTranslator) RecentCallControls() []uint32 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.recentCallControls)
+}
+
+// SeedCallControls restores the recent call-control ring from a previous
+// run so allocation continues after the last ID used instead of starting
+// over. ids are expected oldest first.
+func (t *IPSCTranslator) SeedCallControls(ids []uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(ids) > recentCallControlSize {
+		ids = ids[len(ids)-recentCallControlSize:]
+	}
+	t.recentCallControls = slices.Clone(ids)
+	if len(ids) > 0 {
+		t.nextCallControl = ids[len(ids)-1]
+	}
+}
+
+// ReverseStream is a call from IPSC in progress: the peer it arrived
+// from and its addressing, the call control the repeater gave it, the
+// stream it continues toward the master, and whether its voice header
+// was already passed on, so the header repeats of a call spanning a
+// restart are still recognized as duplicates.
+type ReverseStream struct {
+	PeerID      uint32
+	CallControl uint32
+	StreamID    uint32
+	Seq         uint8
+	HeaderSent  bool
+	Src         uint
+	Dst         uint
+	GroupCall   bool
+	Slot        bool // true = TS2
+	// Duplicates is the number of retransmitted or late packets dropped.
+	Duplicates int
+}
+
+// ReverseStreams returns a snapshot of the IPSC→MMDVM calls in progress.
+func (t *IPSCTranslator) ReverseStreams() []ReverseStream {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	streams := make([]ReverseStream, 0, len(t.reverseStreams))
+	for key, rss := range t.reverseStreams {
+		streams = append(streams, ReverseStream{
+			PeerID:      key.peerID,
+			CallControl: key.callControl,
+			StreamID:    rss.streamID,
+			Seq:         rss.seq,
+			HeaderSent:  rss.started,
+			Src:         rss.src,
+			Dst:         rss.dst,
+			GroupCall:   rss.groupCall,
+			Slot:        rss.slot,
+			Duplicates:  rss.duplicates,
+		})
+	}
+	return streams
+}
+
+// SeedReverseStreams restores the calls from IPSC a previous run had in
+// progress. Calls already being tracked are left alone, and new streams
+// are numbered after the restored ones.
+func (t *IPSCTranslator) SeedReverseStreams(streams []ReverseStream) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, stream := range streams {
+		key := reverseStreamKey{peerID: stream.PeerID, callControl: stream.CallControl}
+		if _, ok := t.reverseStreams[key]; ok {
+			continue
+		}
+		t.reverseStreams[key] = &reverseStreamState{
+			streamID:  stream.StreamID,
+			seq:       stream.Seq,
+			started:   stream.HeaderSent,
+			peerID:    stream.PeerID,
+			src:       stream.Src,
+			dst:       stream.Dst,
+			groupCall: stream.GroupCall,
+			slot:      stream.Slot,
+		}
+		t.nextStreamID = max(t.nextStreamID, stream.StreamID)
+		if t.metrics != nil {
+			t.metrics.TranslatorActiveStreams.WithLabelValues("ipsc_to_mmdvm").Inc()
+		}
+	}
+}
+
 // CleanupStream removes state for a given stream (e.g. on timeout).
 func (t *IPSCTranslator) CleanupStream(streamID uint32) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	delete(t.streams, streamID)
+	if ss, ok := t.streams[streamID]; ok {
+		delete(t.streams, streamID)
+		t.finishCall(&ss.stats)
+		t.reportActiveStreams()
+	}
 }
 
 // buildIPSCHeader writes the common 18-byte IPSC header (bytes 0-17).
@@ -220,11 +661,14 @@ func (t *IPSCTranslator) buildIPSCHeader(buf []byte, pkt mmdvm.Packet, ss *strea
 	buf[10] = byte(pkt.Dst >> 8)
 	buf[11] = byte(pkt.Dst)
 
-	// Byte 12: Call type (0x02 = group call)
-	if pkt.GroupCall {
-		buf[12] = 0x02
-	} else {
-		buf[12] = 0x01
+	// Byte 12: Call type
+	switch {
+	case pkt.Dst == allCallID:
+		buf[12] = ipscCallTypeAllCall
+	case pkt.GroupCall:
+		buf[12] = ipscCallTypeGroup
+	default:
+		buf[12] = ipscCallTypePrivate
 	}
 
 	// Bytes 13-16: Call control (random per-call)
@@ -241,7 +685,11 @@ func (t *IPSCTranslator) buildIPSCHeader(buf []byte, pkt mmdvm.Packet, ss *strea
 	buf[17] = callInfo
 }
 
-// buildRTPHeader writes the 12-byte RTP header at buf[18:30].
+// buildRTPHeader writes the 12-byte RTP header at buf[18:30]. Repeaters
+// drop audio whose RTP stream is discontinuous, so each packet of a
+// stream takes the next sequence number, wrapping after 0xFFFF, and a
+// timestamp one burst after the last, under the SSRC fixed when the call
+// started.
 func (t *IPSCTranslator) buildRTPHeader(buf []byte, ss *streamState, marker bool, payloadType byte) {
 	// Byte 18: RTP version 2, no padding, no extension, 0 CSRCs
 	buf[18] = 0x80
@@ -261,8 +709,17 @@ func (t *IPSCTranslator) buildRTPHeader(buf []byte, ss *streamState, marker bool
 	binary.BigEndian.PutUint32(buf[22:26], ss.rtpTimestamp)
 	ss.rtpTimestamp += rtpTimestampIncrement
 
-	// Bytes 26-29: RTP SSRC (0)
-	binary.BigEndian.PutUint32(buf[26:30], 0)
+	// Bytes 26-29: RTP SSRC
+	binary.BigEndian.PutUint32(buf[26:30], ss.ssrc)
+}
+
+// buildWakeUp builds a repeater wake-up packet for the call ss starts: the
+// 18-byte header of its user packets under the wake-up packet type.
+func (t *IPSCTranslator) buildWakeUp(pkt mmdvm.Packet, ss *streamState) []byte {
+	buf := make([]byte, 18)
+	t.buildIPSCHeader(buf, pkt, ss, false, false)
+	buf[0] = byte(PacketType_RepeaterWakeUp)
+	return buf
 }
 
 // buildVoiceHeader builds a 54-byte IPSC voice header packet.
@@ -272,8 +729,8 @@ func (t *IPSCTranslator) buildVoiceHeader(pkt mmdvm.Packet, ss *streamState, isF
 
 	t.buildIPSCHeader(buf, pkt, ss, false, false)
 
-	// RTP header: marker on first header, payload type 0x5D
-	t.buildRTPHeader(buf, ss, isFirst, 0x5D)
+	// RTP header: marker on first header
+	t.buildRTPHeader(buf, ss, isFirst, t.rtp.PayloadType)
 
 	// RTP Payload — voice header
 	burstType := ipscBurstSlot2
@@ -296,9 +753,12 @@ func (t *IPSCTranslator) buildVoiceHeader(pkt mmdvm.Packet, ss *streamState, isF
 	// Bytes 38-49: Full LC data (12 bytes)
 	// Extract from the DMR burst data — the header burst carries a Voice LC Header
 	// which contains FLCO, FID, ServiceOpt, Dst, Src, CRC
-	t.burst.DecodeFromBytes(pkt.DMRData)
-	flcBytes := extractFullLCBytes(pkt)
+	flcBytes := fullLC(pkt, elements.DataTypeVoiceLCHeader)
 	copy(buf[38:50], flcBytes[:12])
+	if !ss.hasEmbeddedLC {
+		ss.embeddedLC = embeddedLC(flcBytes)
+		ss.hasEmbeddedLC = true
+	}
 
 	// Bytes 50-53: unknown trailing (zeros)
 	return buf
@@ -310,8 +770,8 @@ func (t *IPSCTranslator) buildVoiceTerminator(pkt mmdvm.Packet, ss *streamState)
 
 	t.buildIPSCHeader(buf, pkt, ss, true, false)
 
-	// RTP header: no marker, payload type 0x5E for terminator
-	t.buildRTPHeader(buf, ss, false, 0x5E)
+	// RTP header: no marker, terminator payload type
+	t.buildRTPHeader(buf, ss, false, t.rtp.TerminatorPayloadType)
 
 	// RTP Payload — voice terminator (same structure as header)
 	buf[30] = ipscBurstVoiceTerm
@@ -326,8 +786,7 @@ func (t *IPSCTranslator) buildVoiceTerminator(pkt mmdvm.Packet, ss *streamState)
 	binary.BigEndian.PutUint16(buf[36:38], 0x0060)
 
 	// Full LC data
-	t.burst.DecodeFromBytes(pkt.DMRData)
-	flcBytes := extractFullLCBytes(pkt)
+	flcBytes := fullLC(pkt, elements.DataTypeTerminatorWithLC)
 	copy(buf[38:50], flcBytes[:12])
 
 	ss.ipscSeq++
@@ -337,32 +796,99 @@ func (t *IPSCTranslator) buildVoiceTerminator(pkt mmdvm.Packet, ss *streamState)
 // buildIPSCDataPacket builds a 54-byte IPSC data packet for CSBK, Data Header, etc.
 // The structure is identical to voice header/terminator but with data packet types (0x83/0x84).
 func (t *IPSCTranslator) buildIPSCDataPacket(pkt mmdvm.Packet, ss *streamState, dataType elements.DataType) []byte {
-	buf := make([]byte, 54)
+	payload, ok := bptc.Decode(pkt.DMRData)
+	if !ok {
+		// Too damaged to carry as is; send a link control built from
+		// the packet fields instead
+		payload = extractFullLCBytes(pkt, dataType)
+	}
+	return t.buildIPSCDataPayload(pkt, ss, dataType, payload[:])
+}
+
+// buildIPSCCSBK builds an IPSC data packet with burst type ipscBurstCSBK
+// carrying the 12 octets of the CSBK in pkt. It returns nil if the CSBK
+// does not decode or its CRC-CCITT does not verify under the CSBK mask,
+// since a peer would discard it anyway.
+func (t *IPSCTranslator) buildIPSCCSBK(pkt mmdvm.Packet, ss *streamState) []byte {
+	block, ok := bptc.Decode(pkt.DMRData)
+	if !ok || !crc.CheckCCITT16(block[:], crc.MaskCSBK) {
+		slog.Debug("IPSCTranslator: dropping damaged CSBK", "src", pkt.Src, "dst", pkt.Dst)
+		return nil
+	}
+	return t.buildIPSCDataPayload(pkt, ss, elements.DataTypeCSBK, block[:])
+}
+
+// buildIPSCPIHeader builds an IPSC packet carrying the privacy indicator
+// header in pkt, under the voice packet type of the call it belongs to.
+// It returns nil if the header does not decode or its CRC-CCITT does not
+// verify under the PI header mask.
+func (t *IPSCTranslator) buildIPSCPIHeader(pkt mmdvm.Packet, ss *streamState) []byte {
+	block, ok := bptc.Decode(pkt.DMRData)
+	if !ok || !crc.CheckCCITT16(block[:], crc.MaskPIHeader) {
+		slog.Debug("IPSCTranslator: dropping damaged PI header", "src", pkt.Src, "dst", pkt.Dst)
+		return nil
+	}
+	buf := t.buildIPSCDataPayload(pkt, ss, elements.DataTypePIHeader, block[:])
+	buf[0] = byte(PacketType_PrivateVoice)
+	if pkt.GroupCall {
+		buf[0] = byte(PacketType_GroupVoice)
+	}
+	return buf
+}
+
+// buildIPSCDataBlock builds an IPSC data packet for a data header or data
+// continuation block, carrying the information octets decoded from the
+// burst: 12 for headers and rate 1/2 blocks, 18 for rate 3/4, and 24 for
+// rate 1. It returns nil if the block does not decode.
+func (t *IPSCTranslator) buildIPSCDataBlock(pkt mmdvm.Packet, ss *streamState, dataType elements.DataType) []byte {
+	switch dataType {
+	case elements.DataTypeRate34:
+		payload, ok := datablock.ExtractRate34(pkt.DMRData)
+		if !ok {
+			slog.Debug("IPSCTranslator: undecodable rate 3/4 block", "src", pkt.Src, "dst", pkt.Dst)
+			return nil
+		}
+		return t.buildIPSCDataPayload(pkt, ss, dataType, payload[:])
+	case elements.DataTypeRate1:
+		payload := datablock.ExtractRate1(pkt.DMRData)
+		return t.buildIPSCDataPayload(pkt, ss, dataType, payload[:])
+	default:
+		payload, ok := bptc.Decode(pkt.DMRData)
+		if !ok {
+			slog.Debug("IPSCTranslator: undecodable data block", "dataType", dataType, "src", pkt.Src, "dst", pkt.Dst)
+			return nil
+		}
+		return t.buildIPSCDataPayload(pkt, ss, dataType, payload[:])
+	}
+}
+
+// buildIPSCDataPayload builds an IPSC data packet carrying payload as its
+// data octets. A 12-octet payload makes a 54-byte packet.
+func (t *IPSCTranslator) buildIPSCDataPayload(pkt mmdvm.Packet, ss *streamState, dataType elements.DataType, payload []byte) []byte {
+	size := uint16(len(payload)) //nolint:gosec // G115: payloads are at most 24 octets
+	buf := make([]byte, 42+size)
 
 	t.buildIPSCHeader(buf, pkt, ss, false, true)
 
-	// RTP header: no marker, payload type 0x5D
-	t.buildRTPHeader(buf, ss, ss.firstPacket, 0x5D)
+	// RTP header: marker on the first packet of the call
+	t.buildRTPHeader(buf, ss, ss.firstPacket, t.rtp.PayloadType)
 
 	// RTP Payload — data burst
-	buf[30] = byte(dataType) // Burst type = DMR data type (e.g. 0x03 for CSBK)
-	buf[31] = 0xC0           // RSSI threshold / parity
-	binary.BigEndian.PutUint16(buf[32:34], 0x000A)
-	buf[34] = 0x80 // RSSI status
+	buf[30] = byte(dataType)                         // Burst type = DMR data type (e.g. 0x03 for CSBK)
+	buf[31] = 0xC0                                   // RSSI threshold / parity
+	binary.BigEndian.PutUint16(buf[32:34], 4+size/2) // Length to follow in words
+	buf[34] = 0x80                                   // RSSI status
 	if pkt.Slot {
 		buf[35] = ipscBurstSlot2 // Slot type/sync
 	} else {
 		buf[35] = ipscBurstSlot1
 	}
-	binary.BigEndian.PutUint16(buf[36:38], 0x0060) // Data size (96 bits = 12 bytes)
+	binary.BigEndian.PutUint16(buf[36:38], size*8) // Data size in bits
 
-	// Bytes 38-49: Extract data from DMR burst via BPTC decode
-	t.burst.DecodeFromBytes(pkt.DMRData)
-	// Use extractFullLCBytes which constructs from packet fields
-	flcBytes := extractFullLCBytes(pkt)
-	copy(buf[38:50], flcBytes[:12])
+	// Bytes 38 on: data octets
+	copy(buf[38:], payload)
 
-	// Bytes 50-53: trailing (zeros)
+	// Last 4 bytes: trailing (zeros)
 	ss.ipscSeq++
 	return buf
 }
@@ -389,13 +915,26 @@ func (t *IPSCTranslator) buildVoiceBurst(pkt mmdvm.Packet, ss *streamState) []by
 	}
 
 	burstIdx := ss.burstIndex % 6
+	if !ss.hasEmbeddedLC {
+		// No header was seen, so code the link control a header would
+		// have been given.
+		ss.embeddedLC = embeddedLC(extractFullLCBytes(pkt, elements.DataTypeVoiceLCHeader))
+		ss.hasEmbeddedLC = true
+	}
+	var lc bptc.Fragments
+	if burstIdx >= 1 && burstIdx <= 4 {
+		if t.burst.HasEmbeddedSignalling {
+			ss.noteEmbeddedFragment(burstIdx, t.burst.PackEmbeddedSignallingData())
+		}
+		lc = ss.superframeLC(burstIdx)
+	}
 
 	var buf []byte
 	switch burstIdx {
 	case 0: // Burst A — sync burst, 52 bytes
 		buf = make([]byte, 52)
 		t.buildIPSCHeader(buf, pkt, ss, false, false)
-		t.buildRTPHeader(buf, ss, false, 0x5D)
+		t.buildRTPHeader(buf, ss, ss.firstPacket, t.rtp.PayloadType)
 
 		buf[30] = slotBurst
 		buf[31] = 0x14 // Length: 20 bytes follow
@@ -405,19 +944,15 @@ func (t *IPSCTranslator) buildVoiceBurst(pkt mmdvm.Packet, ss *streamState) []by
 	case 4: // Burst E — extended with embedded LC, 66 bytes
 		buf = make([]byte, 66)
 		t.buildIPSCHeader(buf, pkt, ss, false, false)
-		t.buildRTPHeader(buf, ss, false, 0x5D)
+		t.buildRTPHeader(buf, ss, ss.firstPacket, t.rtp.PayloadType)
 
 		buf[30] = slotBurst
 		buf[31] = 0x22 // Length: 34 bytes follow
 		buf[32] = 0x16 // Unknown field
 		copy(buf[33:52], ambeData[:])
 
-		// Bytes 52-58: Embedded LC data (7 bytes)
-		// Extract from embedded signalling if available
-		if t.burst.HasEmbeddedSignalling {
-			embData := t.burst.PackEmbeddedSignallingData()
-			copy(buf[52:56], embData[:4])
-		}
+		// Bytes 52-55: the last embedded LC fragment
+		copy(buf[52:56], lc[3][:])
 
 		// Bytes 56-58 or 59-61: Destination repeated
 		buf[59] = byte(pkt.Dst >> 16)
@@ -432,15 +967,18 @@ func (t *IPSCTranslator) buildVoiceBurst(pkt mmdvm.Packet, ss *streamState) []by
 	default: // Bursts B, C, D, F — 57 bytes with embedded signalling
 		buf = make([]byte, 57)
 		t.buildIPSCHeader(buf, pkt, ss, false, false)
-		t.buildRTPHeader(buf, ss, false, 0x5D)
+		t.buildRTPHeader(buf, ss, ss.firstPacket, t.rtp.PayloadType)
 
 		buf[30] = slotBurst
 		buf[31] = 0x19 // Length: 25 bytes follow
 		buf[32] = 0x06 // Unknown field
 		copy(buf[33:52], ambeData[:])
 
-		// Bytes 52-56: Embedded signalling data (5 bytes)
-		if t.burst.HasEmbeddedSignalling {
+		// Bytes 52-55: embedded LC fragment for B-D. Burst F carries
+		// no link control, so its embedded signalling passes through.
+		if burstIdx < 4 {
+			copy(buf[52:56], lc[burstIdx-1][:])
+		} else if t.burst.HasEmbeddedSignalling {
 			embData := t.burst.PackEmbeddedSignallingData()
 			copy(buf[52:56], embData[:4])
 		}
@@ -449,9 +987,35 @@ func (t *IPSCTranslator) buildVoiceBurst(pkt mmdvm.Packet, ss *streamState) []by
 	return buf
 }
 
+// voiceBurstIndex returns the superframe position, 0-5 for A-F, of a
+// voice frame from the master. Sync frames are always A and other frames
+// carry their position, so a lost frame does not shift the ones after
+// it. A frame without a valid position takes the expected one.
+func voiceBurstIndex(pkt mmdvm.Packet, expected int) int {
+	if pkt.FrameType == mmdvmFrameTypeVoiceSync {
+		return 0
+	}
+	if pkt.DTypeOrVSeq >= 1 && pkt.DTypeOrVSeq <= 5 {
+		return int(pkt.DTypeOrVSeq)
+	}
+	return expected
+}
+
+// validIDs reports whether src and dst are addresses a call can carry:
+// neither zero nor wider than the 24 bits IPSC packets hold.
+func validIDs(src, dst uint) bool {
+	return src != 0 && dst != 0 && src <= maxID && dst <= maxID
+}
+
+// countDropped counts a packet refused in direction for reason.
+func (t *IPSCTranslator) countDropped(direction, reason string) {
+	t.sink.IncDropped(direction, reason)
+}
+
 // extractFullLCBytes builds 12 bytes of Full Link Control data
-// from the packet fields, using the dmrgo library's encoder.
-func extractFullLCBytes(pkt mmdvm.Packet) [12]byte {
+// from the packet fields, using the dmrgo library's encoder, with the
+// Reed-Solomon parity masked for dataType.
+func extractFullLCBytes(pkt mmdvm.Packet, dataType elements.DataType) [12]byte {
 	flco := enums.FLCOUnitToUnitVoiceChannelUser
 	if pkt.Dst > math.MaxInt || pkt.Src > math.MaxInt {
 		slog.Error("Full LC address out of range")
@@ -481,7 +1045,80 @@ func extractFullLCBytes(pkt mmdvm.Packet) [12]byte {
 
 	var res [12]byte
 	copy(res[:], encoded)
-	return res
+	return withRS129(res, dataType)
+}
+
+// fullLC returns the full link control for a voice header or terminator
+// toward IPSC. The link control the burst carries is kept, with its
+// service options and feature set, when it decodes, passes its parity
+// check, and addresses the call the packet does; otherwise, as after a
+// rewrite, it is built from the packet fields. A rewritten call keeps the
+// emergency, privacy, and priority service options it was sent with.
+func fullLC(pkt mmdvm.Packet, dataType elements.DataType) [12]byte {
+	lc, ok := bptc.Decode(pkt.DMRData)
+	if !ok || !crc.CheckRS129(lc, lcMask(dataType)) {
+		return extractFullLCBytes(pkt, dataType)
+	}
+	if lcAddresses(lc, pkt) {
+		return lc
+	}
+	rebuilt := extractFullLCBytes(pkt, dataType)
+	if rebuilt == ([12]byte{}) || !isVoiceChannelUser(lc) {
+		return rebuilt
+	}
+	rebuilt[2] = lc[2]
+	return withRS129(rebuilt, dataType)
+}
+
+// isVoiceChannelUser reports whether lc is a group or unit to unit voice
+// channel user link control, whose third octet holds service options.
+func isVoiceChannelUser(lc [12]byte) bool {
+	flco := lc[0] & 0x3F
+	return flco == byte(enums.FLCOGroupVoiceChannelUser) || flco == byte(enums.FLCOUnitToUnitVoiceChannelUser)
+}
+
+// lcMask returns the mask over the Reed-Solomon parity of a full link
+// control sent as dataType. Only terminators differ from headers.
+func lcMask(dataType elements.DataType) uint32 {
+	if dataType == elements.DataTypeTerminatorWithLC {
+		return crc.MaskTerminator
+	}
+	return crc.MaskVoiceHeader
+}
+
+// withRS129 returns flc with its Reed-Solomon parity recomputed for
+// dataType over its first nine octets.
+func withRS129(flc [12]byte, dataType elements.DataType) [12]byte {
+	var lc [9]byte
+	copy(lc[:], flc[:9])
+	return crc.AppendRS129(lc, lcMask(dataType))
+}
+
+// lcAddresses reports whether lc is a voice channel user link control
+// for the call type, source, and destination of pkt.
+func lcAddresses(lc [12]byte, pkt mmdvm.Packet) bool {
+	flco := enums.FLCOUnitToUnitVoiceChannelUser
+	if pkt.GroupCall {
+		flco = enums.FLCOGroupVoiceChannelUser
+	}
+	dst := uint(lc[3])<<16 | uint(lc[4])<<8 | uint(lc[5])
+	src := uint(lc[6])<<16 | uint(lc[7])<<8 | uint(lc[8])
+	return lc[0]&0x3F == byte(flco) && dst == pkt.Dst && src == pkt.Src
+}
+
+// embeddedLC codes the link control of a full LC for voice bursts B to E,
+// so a radio entering late learns the same call as the header announced.
+func embeddedLC(flc [12]byte) bptc.Fragments {
+	var lc [bptc.LCSize]byte
+	copy(lc[:], flc[:bptc.LCSize])
+	return bptc.EncodeEmbeddedLC(lc)
+}
+
+// reverseStreamKey identifies a call from IPSC. Each peer picks its own
+// call controls, so two peers may use the same one at once.
+type reverseStreamKey struct {
+	peerID      uint32
+	callControl uint32
 }
 
 // reverseStreamState tracks per-call state for IPSC→MMDVM translation.
@@ -490,11 +1127,129 @@ type reverseStreamState struct {
 	seq        uint8
 	burstIndex int  // 0-5 → A-F within a superframe
 	started    bool // whether we've seen a voice header
+	voiceSeen  bool // whether a voice burst has followed the header
+	privacy    bool // whether a PI header has been seen
+
+	// rtpSeq is the RTP sequence number of the newest packet of the call,
+	// valid once haveRTPSeq is set. duplicates counts the packets dropped
+	// for not being newer than it.
+	rtpSeq     uint16
+	haveRTPSeq bool
+	duplicates int
+
+	// The peer the call arrived from and its addressing, kept so the
+	// call can be ended on the peer's behalf if the peer goes away.
+	peerID    uint32
+	src, dst  uint
+	groupCall bool
+	slot      bool
+
+	// lastActive is when the call's newest packet arrived.
+	lastActive time.Time
+
+	stats CallStats
+}
+
+// TerminatePeerStreams ends every IPSC→MMDVM call that arrived from
+// peerID, returning a synthesized voice terminator for each so the
+// master sees the call end now instead of timing it out. The calls'
+// state is discarded, so later bursts with the same call control start
+// a new stream.
+func (t *IPSCTranslator) TerminatePeerStreams(peerID uint32) []mmdvm.Packet {
+	return t.terminateStreams(func(key reverseStreamKey) bool { return key.peerID == peerID }, "terminated stream of lost peer")
+}
+
+// TerminateStreams ends every IPSC→MMDVM call, from any peer, the way
+// TerminatePeerStreams does, for when the session with the master they
+// are sent on ends.
+func (t *IPSCTranslator) TerminateStreams() []mmdvm.Packet {
+	return t.terminateStreams(func(reverseStreamKey) bool { return true }, "terminated stream")
+}
+
+// terminateStreams ends the IPSC→MMDVM calls whose key match accepts.
+func (t *IPSCTranslator) terminateStreams(match func(reverseStreamKey) bool, msg string) []mmdvm.Packet {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var results []mmdvm.Packet
+	for key, rss := range t.reverseStreams {
+		if !match(key) {
+			continue
+		}
+		pkt := t.buildMMDVMDataPacket(rss.src, rss.dst, rss.groupCall, rss.slot, rss,
+			elements.DataTypeTerminatorWithLC, nil)
+		results = append(results, pkt)
+		delete(t.reverseStreams, key)
+		t.finishCall(&rss.stats)
+		t.reportActiveStreams()
+		slog.Debug("IPSCTranslator: "+msg,
+			"peerID", key.peerID, "streamID", rss.streamID, "src", rss.src, "dst", rss.dst)
+	}
+
+	t.countToMMDVM(results)
+
+	return results
+}
+
+// newReverseStream starts the state of a call from IPSC under key,
+// evicting the least recently active call if maxStreams are tracked
+// already. Must be called with t.mu held.
+func (t *IPSCTranslator) newReverseStream(key reverseStreamKey, src, dst uint, groupCall, slot bool) *reverseStreamState {
+	if len(t.reverseStreams) >= t.maxStreams {
+		t.evictReverseStream()
+	}
+	t.nextStreamID++
+	if t.nextStreamID == 0 {
+		t.nextStreamID = 1
+	}
+	rss := &reverseStreamState{
+		streamID:   t.nextStreamID,
+		peerID:     key.peerID,
+		src:        src,
+		dst:        dst,
+		groupCall:  groupCall,
+		slot:       slot,
+		lastActive: t.now(),
+		stats: CallStats{
+			StreamID:  t.nextStreamID,
+			Direction: "ipsc_to_mmdvm",
+			Src:       src,
+			Dst:       dst,
+			GroupCall: groupCall,
+			Slot:      slot,
+			Start:     t.now(),
+		},
+	}
+	t.reverseStreams[key] = rss
+	t.reportActiveStreams()
+	return rss
+}
+
+// evictReverseStream discards the least recently active call from IPSC.
+// Must be called with t.mu held.
+func (t *IPSCTranslator) evictReverseStream() {
+	var oldestKey reverseStreamKey
+	var oldest *reverseStreamState
+	for key, rss := range t.reverseStreams {
+		if oldest == nil || rss.lastActive.Before(oldest.lastActive) {
+			oldestKey, oldest = key, rss
+		}
+	}
+	if oldest == nil {
+		return
+	}
+	delete(t.reverseStreams, oldestKey)
+	t.finishCall(&oldest.stats)
+	t.evictions++
+	t.reportActiveStreams()
+	slog.Warn("IPSCTranslator: too many calls from IPSC, evicted the least recently active",
+		"peerID", oldest.peerID, "streamID", oldest.streamID, "src", oldest.src, "dst", oldest.dst,
+		"maxStreams", t.maxStreams, "evictions", t.evictions)
 }
 
 // TranslateToMMDVM converts raw IPSC user packet data into MMDVM DMRD Packets.
 // Returns nil if the packet cannot be translated.
-func (t *IPSCTranslator) TranslateToMMDVM(packetType byte, data []byte) []mmdvm.Packet {
+func (t *IPSCTranslator) TranslateToMMDVM(packetType byte, data []byte) (results []mmdvm.Packet) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -515,9 +1270,21 @@ func (t *IPSCTranslator) TranslateToMMDVM(packetType byte, data []byte) []mmdvm.
 	// Parse the IPSC header
 	src := uint(data[6])<<16 | uint(data[7])<<8 | uint(data[8])
 	dst := uint(data[9])<<16 | uint(data[10])<<8 | uint(data[11])
+	if !validIDs(src, dst) {
+		slog.Debug("IPSCTranslator: dropping packet with an invalid ID", "src", src, "dst", dst)
+		t.countDropped("ipsc_to_mmdvm", "invalid_id")
+		return nil
+	}
 	groupCall := packetType == 0x80 || packetType == 0x83
+	if dst == allCallID || data[12] == ipscCallTypeAllCall {
+		groupCall = true
+		dst = allCallID
+	}
 	callInfo := data[17]
 	slot := (callInfo & 0x20) != 0 // true = TS2
+	if t.swapSlots {
+		slot = !slot
+	}
 	isEnd := (callInfo & 0x40) != 0
 
 	slog.Debug("IPSCTranslator: TranslateToMMDVM",
@@ -525,33 +1292,110 @@ func (t *IPSCTranslator) TranslateToMMDVM(packetType byte, data []byte) []mmdvm.
 		"src", src, "dst", dst, "groupCall", groupCall,
 		"slot", slot, "isEnd", isEnd)
 
-	// Use call control bytes as stream identifier
+	// Use the peer and call control bytes as stream identifier
 	callControl := binary.BigEndian.Uint32(data[13:17])
+	key := reverseStreamKey{peerID: binary.BigEndian.Uint32(data[1:5]), callControl: callControl}
 
-	// Get or create reverse stream state
-	rss, ok := t.reverseStreams[callControl]
-	if !ok {
-		t.nextStreamID++
-		if t.nextStreamID == 0 {
-			t.nextStreamID = 1
-		}
-		rss = &reverseStreamState{
-			streamID: t.nextStreamID,
+	// A transmit interrupt belongs to the call it interrupts.
+	if len(data) >= 50 && isReverseChannelBurst(data[30]) {
+		return t.reverseChannelToMMDVM(src, dst, groupCall, slot, key, data[38:50])
+	}
+
+	// Remote radio commands are checked before they can start a stream.
+	if len(data) >= 50 && data[30] == ipscBurstCSBK {
+		var block csbk.Block
+		copy(block[:], data[38:50])
+		sent, ok := t.admitRemoteCommand("ipsc_to_mmdvm", block)
+		if !ok {
+			return nil
 		}
-		t.reverseStreams[callControl] = rss
-		if t.metrics != nil {
-			t.metrics.TranslatorActiveStreams.WithLabelValues("ipsc_to_mmdvm").Inc()
+		if sent != block {
+			data = slices.Clone(data)
+			copy(data[38:50], sent[:])
 		}
 	}
 
 	// Determine what kind of IPSC burst this is from byte 30
 	burstType := data[30]
 
-	var results []mmdvm.Packet
+	// Get or create reverse stream state
+	rss, ok := t.reverseStreams[key]
+	if !ok {
+		rss = t.newReverseStream(key, src, dst, groupCall, slot)
+	}
+	rss.lastActive = t.now()
+	defer func() { rss.stats.count(len(results) > 0) }()
+
+	// Peers retransmit on lossy links, so a packet no newer than the last
+	// one of its call is a duplicate, or too late to play. Sequence
+	// numbers are only trusted from a well-formed RTP version 2 header.
+	if data[18]>>6 == 2 {
+		seq := binary.BigEndian.Uint16(data[20:22])
+		ahead := int16(seq - rss.rtpSeq) //nolint:gosec // G115: wraparound-aware difference
+		if rss.haveRTPSeq && ahead <= 0 {
+			rss.duplicates++
+			t.sink.IncDropped("ipsc_to_mmdvm", "duplicate")
+			slog.Debug("IPSCTranslator: dropping duplicate IPSC packet",
+				"streamID", rss.streamID, "seq", seq, "last", rss.rtpSeq, "duplicates", rss.duplicates)
+			return nil
+		}
+		if rss.haveRTPSeq {
+			rss.stats.Lost += int(ahead) - 1
+		}
+		rss.rtpSeq, rss.haveRTPSeq = seq, true
+	}
 
-	switch burstType {
-	case ipscBurstVoiceHead:
-		// Voice LC Header — only process the first one (IPSC sends 3)
+	// The end flag ends the call whatever becomes of the packet, so a
+	// call of a single packet leaves no state behind.
+	if isEnd {
+		defer func() {
+			if t.reverseStreams[key] == rss {
+				delete(t.reverseStreams, key)
+				t.finishCall(&rss.stats)
+				t.reportActiveStreams()
+			}
+		}()
+	}
+
+	// Repeaters may reuse a call control for back-to-back calls. Repeated
+	// headers all come before the voice, and a late one is dropped above,
+	// so a header once voice has flowed starts a new call whose
+	// predecessor's terminator was lost. So does a packet with the RTP
+	// marker, which flags the first packet of a talkspurt, in case the
+	// header was lost too. End that call toward the master and give the
+	// new one its own stream; late entry below leads it with a header.
+	marker := data[18]>>6 == 2 && data[19]&0x80 != 0
+	if rss.voiceSeen && (burstType == ipscBurstVoiceHead || marker) {
+		slog.Debug("IPSCTranslator: call control reused without a terminator, starting a new stream",
+			"callControl", callControl, "streamID", rss.streamID)
+		results = append(results, t.buildMMDVMDataPacket(rss.src, rss.dst, rss.groupCall, rss.slot, rss,
+			elements.DataTypeTerminatorWithLC, nil))
+		delete(t.reverseStreams, key)
+		t.finishCall(&rss.stats)
+		t.reportActiveStreams()
+		prev := rss
+		rss = t.newReverseStream(key, src, dst, groupCall, slot)
+		rss.rtpSeq, rss.haveRTPSeq = prev.rtpSeq, prev.haveRTPSeq
+	}
+
+	// Data packets carry no voice, so in them burst type 0x0A is the data
+	// type of a rate 1 block rather than a voice burst.
+	isData := packetType == 0x83 || packetType == 0x84
+
+	switch {
+	case isData && (burstType == byte(elements.DataTypeRate34) || burstType == byte(elements.DataTypeRate1)):
+		// Data continuation blocks too long for a BPTC burst
+		pkt, ok := t.buildMMDVMDataBlock(src, dst, groupCall, slot, rss,
+			elements.DataType(burstType), data)
+		if !ok {
+			slog.Debug("IPSCTranslator: data block too short", "burstType", burstType, "length", len(data))
+			return nil
+		}
+		results = append(results, pkt)
+
+	case burstType == ipscBurstVoiceHead:
+		// Voice LC Header — only process the first one; peers repeat it
+		// as many times as they are configured to
 		if !rss.started {
 			pkt := t.buildMMDVMDataPacket(src, dst, groupCall, slot, rss,
 				elements.DataTypeVoiceLCHeader, data)
@@ -561,28 +1405,49 @@ func (t *IPSCTranslator) TranslateToMMDVM(packetType byte, data []byte) []mmdvm.
 		}
 		// Skip duplicate headers
 
-	case ipscBurstVoiceTerm:
+	case burstType == ipscBurstVoiceTerm:
 		// Voice Terminator
 		pkt := t.buildMMDVMDataPacket(src, dst, groupCall, slot, rss,
 			elements.DataTypeTerminatorWithLC, data)
 		results = append(results, pkt)
 		// Clean up
-		delete(t.reverseStreams, callControl)
-		if t.metrics != nil {
-			t.metrics.TranslatorActiveStreams.WithLabelValues("ipsc_to_mmdvm").Dec()
-		}
+		delete(t.reverseStreams, key)
+		t.finishCall(&rss.stats)
+		t.reportActiveStreams()
 
-	case ipscBurstSlot1, ipscBurstSlot2:
+	case burstType == ipscBurstSlot1, burstType == ipscBurstSlot2:
 		// Voice burst — extract AMBE, FEC-encode, build DMR burst
 		if len(data) < 52 {
 			slog.Debug("IPSCTranslator: voice burst too short", "length", len(data))
 			return nil
 		}
 
+		// Late entry: the call was joined after its headers went by, so
+		// the master would see voice with no LC. Lead with a header built
+		// from the IPSC packet's addressing.
+		if !rss.started {
+			slog.Debug("IPSCTranslator: late entry, synthesizing voice header",
+				"src", src, "dst", dst, "groupCall", groupCall, "slot", slot)
+			pkt := t.buildMMDVMDataPacket(src, dst, groupCall, slot, rss,
+				elements.DataTypeVoiceLCHeader, nil)
+			results = append(results, pkt)
+			rss.started = true
+			rss.burstIndex = 0
+		}
+
 		pkts := t.buildMMDVMVoiceBurst(src, dst, groupCall, slot, rss, data)
 		results = append(results, pkts...)
+		rss.voiceSeen = true
 
-	case ipscBurstCSBK:
+	case burstType == ipscBurstPIHeader && len(data) >= 50:
+		// Privacy indicator header — the call is encrypted, so link
+		// controls synthesized for it carry the privacy service option
+		pkt := t.buildMMDVMDataPacket(src, dst, groupCall, slot, rss,
+			elements.DataTypePIHeader, data)
+		results = append(results, pkt)
+		rss.privacy = true
+
+	case burstType == ipscBurstCSBK:
 		// CSBK or data burst — same 54-byte structure as voice header
 		pkt := t.buildMMDVMDataPacket(src, dst, groupCall, slot, rss,
 			elements.DataTypeCSBK, data)
@@ -592,27 +1457,28 @@ func (t *IPSCTranslator) TranslateToMMDVM(packetType byte, data []byte) []mmdvm.
 		// Treat any other burst type as a generic data packet if it has
 		// the same structure as a voice header (54 bytes with LC data).
 		// The burst type byte maps directly to the DMR data type.
-		if len(data) >= 50 && burstType <= 10 {
-			pkt := t.buildMMDVMDataPacket(src, dst, groupCall, slot, rss,
-				elements.DataType(burstType), data)
-			results = append(results, pkt)
-		} else {
+		switch {
+		case burstType > 10:
 			slog.Debug("IPSCTranslator: unknown IPSC burst type", "burstType", burstType)
+			t.noteUnknownBurst(data)
+			return nil
+		case len(data) < 50:
+			slog.Debug("IPSCTranslator: data burst too short", "burstType", burstType, "length", len(data))
 			return nil
 		}
+		pkt := t.buildMMDVMDataPacket(src, dst, groupCall, slot, rss,
+			elements.DataType(burstType), data)
+		results = append(results, pkt)
 	}
 
-	if isEnd && burstType != ipscBurstVoiceTerm {
-		// End flag set but not a terminator — clean up anyway
-		delete(t.reverseStreams, callControl)
-		if t.metrics != nil {
-			t.metrics.TranslatorActiveStreams.WithLabelValues("ipsc_to_mmdvm").Dec()
-		}
+	if isEnd && burstType != ipscBurstVoiceTerm && rss.started {
+		// A voice call ended without a terminator of its own; end it
+		// toward the master too.
+		results = append(results, t.buildMMDVMDataPacket(src, dst, groupCall, slot, rss,
+			elements.DataTypeTerminatorWithLC, nil))
 	}
 
-	if t.metrics != nil && len(results) > 0 {
-		t.metrics.TranslatorPackets.WithLabelValues("ipsc_to_mmdvm").Add(float64(len(results)))
-	}
+	t.countToMMDVM(results)
 
 	return results
 }
@@ -648,6 +1514,9 @@ func (t *IPSCTranslator) buildMMDVMDataPacket(
 		// Construct from packet fields
 		lcBytes[1] = 0x00
 		lcBytes[2] = 0x20
+		if rss.privacy {
+			lcBytes[2] |= 0x40
+		}
 		lcBytes[3] = byte(dst >> 16)
 		lcBytes[4] = byte(dst >> 8)
 		lcBytes[5] = byte(dst)
@@ -658,21 +1527,55 @@ func (t *IPSCTranslator) buildMMDVMDataPacket(
 
 	// For voice LC headers and terminators, override the FLCO byte to match
 	// the group/private flag from the IPSC packet type.
+	// The Reed-Solomon parity then covers the final octets.
 	if dataType == elements.DataTypeVoiceLCHeader || dataType == elements.DataTypeTerminatorWithLC {
 		if groupCall {
 			lcBytes[0] = byte(enums.FLCOGroupVoiceChannelUser)
 		} else {
 			lcBytes[0] = byte(enums.FLCOUnitToUnitVoiceChannelUser)
 		}
+		lcBytes = withRS129(lcBytes, dataType)
 	}
 	// For CSBK/data types, preserve the payload bytes as-is from the radio
 
-	// Build the 33-byte DMR data burst
-	pkt.DMRData = layer2.BuildLCDataBurst(lcBytes, dataType, 0)
+	// Build the 33-byte DMR data burst, BPTC coding the octets with their
+	// parity over the information bits
+	pkt.DMRData = layer2.BuildLCDataBurst(lcBytes, dataType, t.colorCode)
+	bptc.Insert(&pkt.DMRData, lcBytes)
 
 	return pkt
 }
 
+// buildMMDVMDataBlock builds an MMDVM DMRD packet for a rate 3/4 or rate 1
+// data block, coding the 18 or 24 information octets of the IPSC packet
+// into the burst. It reports false if the IPSC packet is too short.
+func (t *IPSCTranslator) buildMMDVMDataBlock(
+	src, dst uint, groupCall, slot bool,
+	rss *reverseStreamState,
+	dataType elements.DataType,
+	ipscData []byte,
+) (mmdvm.Packet, bool) {
+	size := datablock.Rate1Size
+	if dataType == elements.DataTypeRate34 {
+		size = datablock.Rate34Size
+	}
+	if len(ipscData) < 38+size {
+		return mmdvm.Packet{}, false
+	}
+
+	pkt := t.buildMMDVMDataPacket(src, dst, groupCall, slot, rss, dataType, nil)
+	if dataType == elements.DataTypeRate34 {
+		var payload [datablock.Rate34Size]byte
+		copy(payload[:], ipscData[38:])
+		datablock.InsertRate34(&pkt.DMRData, payload)
+	} else {
+		var payload [datablock.Rate1Size]byte
+		copy(payload[:], ipscData[38:])
+		datablock.InsertRate1(&pkt.DMRData, payload)
+	}
+	return pkt, true
+}
+
 // buildMMDVMVoiceBurst builds MMDVM DMRD packets from an IPSC voice burst.
 // It extracts the 19-byte AMBE payload, FEC-encodes back to DMR format,
 // and reconstructs the full 33-byte DMR burst with proper sync/EMB.
@@ -696,7 +1599,11 @@ func (t *IPSCTranslator) buildMMDVMVoiceBurst(
 	voiceBits := vc.Encode()
 
 	// Determine if this is a sync burst (A) or embedded signalling burst (B-F)
-	burstIdx := rss.burstIndex % 6
+	burstIdx := ipscVoiceBurstIndex(ipscData, rss.burstIndex)
+	if burstIdx != rss.burstIndex {
+		slog.Debug("IPSCTranslator: voice burst out of sequence, resynchronizing",
+			"streamID", rss.streamID, "expected", rss.burstIndex, "got", burstIdx)
+	}
 
 	var burst layer2.Burst
 	burst.VoiceData = vc
@@ -757,17 +1664,34 @@ func (t *IPSCTranslator) buildMMDVMVoiceBurst(
 		DMRData:     dmrData,
 	}
 	rss.seq++
-	rss.burstIndex = (rss.burstIndex + 1) % 6
+	rss.burstIndex = (burstIdx + 1) % 6
 
 	return []mmdvm.Packet{pkt}
 }
 
+// ipscVoiceBurstIndex returns the superframe position, 0-5 for A-F, of a
+// voice burst from IPSC. Bursts A and E have lengths of their own, so
+// they resynchronize the call after a lost burst; B, C, D, and F take the
+// expected position, moved past A or E if that burst was lost.
+func ipscVoiceBurstIndex(ipscData []byte, expected int) int {
+	switch len(ipscData) {
+	case 52: // Burst A
+		return 0
+	case 66: // Burst E
+		return 4
+	case 57: // Bursts B, C, D, F
+		if expected == 0 || expected == 4 {
+			return expected + 1
+		}
+	}
+	return expected
+}
+
 // populateEmbeddedSignalling fills in the embedded signalling fields
 // for voice bursts B-F from the IPSC packet's trailing data.
 func (t *IPSCTranslator) populateEmbeddedSignalling(burst *layer2.Burst, burstIdx int, ipscData []byte) {
-	// Set up a basic embedded signalling with color code 0
 	burst.EmbeddedSignalling = pdu.EmbeddedSignalling{
-		ColorCode:                          0,
+		ColorCode:                          int(t.colorCode),
 		PreemptionAndPowerControlIndicator: false,
 		LCSS:                               enums.ContinuationFragmentLCorCSBK,
 		ParityOK:                           true,
@@ -779,7 +1703,9 @@ func (t *IPSCTranslator) populateEmbeddedSignalling(burst *layer2.Burst, burstId
 		burst.EmbeddedSignalling.LCSS = enums.FirstFragmentLC
 	case 4: // Burst E — last fragment
 		burst.EmbeddedSignalling.LCSS = enums.LastFragmentLCorCSBK
-	default: // Bursts C, D, F — continuation
+	case 5: // Burst F — a single fragment of its own, not part of the LC
+		burst.EmbeddedSignalling.LCSS = enums.SingleFragmentLCorCSBK
+	default: // Bursts C, D — continuation
 		burst.EmbeddedSignalling.LCSS = enums.ContinuationFragmentLCorCSBK
 	}
 
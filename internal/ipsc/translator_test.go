@@ -2,12 +2,21 @@ package ipsc
 
 import (
 	"encoding/binary"
+	"math"
+	"slices"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/USA-RedDragon/dmrgo/dmr/enums"
 	"github.com/USA-RedDragon/dmrgo/dmr/layer2"
 	"github.com/USA-RedDragon/dmrgo/dmr/layer2/elements"
 	"github.com/USA-RedDragon/dmrgo/dmr/layer2/pdu"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/bptc"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/crc"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/csbk"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/datablock"
 	mmdvm "github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
 )
 
@@ -97,7 +106,7 @@ func makeTestMMDVMPacket(groupCall, slot bool, frameType, dtypeOrVSeq uint) mmdv
 func TestTranslateToIPSCNilOnUnknownFrameType(t *testing.T) {
 	t.Parallel()
 	tr := newTestTranslator(t)
-	pkt := makeTestMMDVMPacket(true, false, 3, 0) // frameType=3 is unknown
+	pkt := makeTestMMDVMPacket(true, false, 4, 0) // frameType=4 is unknown
 	result := tr.TranslateToIPSC(pkt)
 	if result != nil {
 		t.Fatalf("expected nil for unknown frame type, got %d packets", len(result))
@@ -115,6 +124,50 @@ func TestTranslateToIPSCVoiceHeaderProduces3Packets(t *testing.T) {
 	}
 }
 
+func TestTranslateToIPSCHeaderRepeats(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		repeats uint
+		want    int
+	}{
+		{"unset", 0, 3},
+		{"one", 1, 1},
+		{"five", 5, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tr := newTestTranslator(t)
+			tr.SetHeaderRepeats(tt.repeats)
+			result := tr.TranslateToIPSC(makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 1))
+			if len(result) != tt.want {
+				t.Fatalf("expected %d voice header packets, got %d", tt.want, len(result))
+			}
+			for i := 1; i < len(result); i++ {
+				prev := binary.BigEndian.Uint16(result[i-1][20:22])
+				if seq := binary.BigEndian.Uint16(result[i][20:22]); seq != prev+1 {
+					t.Fatalf("expected RTP sequence %d for header %d, got %d", prev+1, i, seq)
+				}
+			}
+		})
+	}
+}
+
+func TestTranslateToMMDVMSuppressesHeaderRepeats(t *testing.T) {
+	t.Parallel()
+	tx := newTestTranslator(t)
+	tx.SetHeaderRepeats(5)
+	rx := newTestTranslator(t)
+	headers := 0
+	for _, data := range tx.TranslateToIPSC(makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 1)) {
+		headers += len(rx.TranslateToMMDVM(data[0], data))
+	}
+	if headers != 1 {
+		t.Fatalf("expected 5 repeated headers to reach the master once, got %d", headers)
+	}
+}
+
 func TestTranslateToIPSCVoiceTerminator(t *testing.T) {
 	t.Parallel()
 	tr := newTestTranslator(t)
@@ -131,6 +184,102 @@ func TestTranslateToIPSCVoiceTerminator(t *testing.T) {
 	}
 }
 
+func TestCallControlRingSkipsRecentIDs(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	tr.SeedCallControls([]uint32{5, 6, 7})
+
+	pkt := makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 1)
+	result := tr.TranslateToIPSC(pkt)
+	if len(result) == 0 {
+		t.Fatal("expected voice header packets")
+	}
+	if cc := binary.BigEndian.Uint32(result[0][13:17]); cc != 8 {
+		t.Fatalf("expected call control to continue after seeded IDs (8), got %d", cc)
+	}
+
+	recent := tr.RecentCallControls()
+	if len(recent) != 4 || recent[3] != 8 {
+		t.Fatalf("expected ring [5 6 7 8], got %v", recent)
+	}
+}
+
+func TestCallControlRingBounded(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	seed := make([]uint32, recentCallControlSize+4)
+	for i := range seed {
+		seed[i] = uint32(i + 1) //nolint:gosec // Small test values
+	}
+	tr.SeedCallControls(seed)
+	recent := tr.RecentCallControls()
+	if len(recent) != recentCallControlSize {
+		t.Fatalf("expected ring of %d, got %d", recentCallControlSize, len(recent))
+	}
+	if recent[len(recent)-1] != seed[len(seed)-1] {
+		t.Fatalf("expected newest seeded ID last, got %v", recent)
+	}
+}
+
+func TestCallControlAvoidsWrappedRecentID(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	// Counter is about to wrap onto 1, which is still in the ring.
+	tr.SeedCallControls([]uint32{1, math.MaxUint32})
+
+	tr.mu.Lock()
+	cc := tr.allocateCallControl()
+	tr.mu.Unlock()
+	if cc != 2 {
+		t.Fatalf("expected recent ID 1 to be skipped after wrap, got %d", cc)
+	}
+}
+
+func TestTranslateToIPSCWakeUpAfterIdle(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	tr.SetWakeUp(5 * time.Second)
+	clock := time.Unix(1_700_000_000, 0)
+	tr.now = func() time.Time { return clock }
+
+	call := func(streamID uint) [][]byte {
+		t.Helper()
+		header := makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 1)
+		header.StreamID = streamID
+		result := tr.TranslateToIPSC(header)
+		term := makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 2)
+		term.StreamID = streamID
+		tr.TranslateToIPSC(term)
+		return result
+	}
+	isWakeUp := func(result [][]byte) bool {
+		return len(result) == 4 && len(result[0]) == 18 && result[0][0] == byte(PacketType_RepeaterWakeUp)
+	}
+
+	if first := call(1); !isWakeUp(first) {
+		t.Fatalf("expected the first call to start with a wake-up, got %d packets", len(first))
+	}
+	clock = clock.Add(time.Second)
+	if second := call(2); len(second) != 3 {
+		t.Fatalf("expected no wake-up on a back-to-back call, got %d packets", len(second))
+	}
+	clock = clock.Add(5 * time.Second)
+	if third := call(3); !isWakeUp(third) {
+		t.Fatalf("expected a wake-up after idle, got %d packets", len(third))
+	}
+}
+
+func TestTranslateToIPSCNoWakeUpByDefault(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	pkt := makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 1)
+	for _, p := range tr.TranslateToIPSC(pkt) {
+		if p[0] == byte(PacketType_RepeaterWakeUp) {
+			t.Fatal("expected no wake-up without an idle time")
+		}
+	}
+}
+
 func TestTranslateToIPSCGroupCallFlag(t *testing.T) {
 	t.Parallel()
 	tr := newTestTranslator(t)
@@ -201,6 +350,177 @@ func TestTranslateToIPSCSlotFlag(t *testing.T) {
 	}
 }
 
+// rtpCall translates a voice header and terminator for one new call and
+// returns the first header packet and the terminator packet.
+func rtpCall(t *testing.T, tr *IPSCTranslator, streamID uint) (header, terminator []byte) {
+	t.Helper()
+	pkt := makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, uint(elements.DataTypeVoiceLCHeader))
+	pkt.StreamID = streamID
+	headers := tr.TranslateToIPSC(pkt)
+	pkt.DTypeOrVSeq = uint(elements.DataTypeTerminatorWithLC)
+	terminators := tr.TranslateToIPSC(pkt)
+	if len(headers) != 3 || len(terminators) != 1 {
+		t.Fatalf("expected 3 headers and 1 terminator, got %d and %d", len(headers), len(terminators))
+	}
+	return headers[0], terminators[0]
+}
+
+func TestRTPDefaults(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	// A zero config, as built in code, keeps the defaults.
+	tr.SetRTP(config.IPSCRTP{})
+	header, terminator := rtpCall(t, tr, 1)
+	if header[19] != 0x80|0x5D {
+		t.Fatalf("expected marker and payload type 0x5D, got 0x%02X", header[19])
+	}
+	if terminator[19] != 0x5E {
+		t.Fatalf("expected terminator payload type 0x5E, got 0x%02X", terminator[19])
+	}
+	if ssrc := binary.BigEndian.Uint32(header[26:30]); ssrc != 0 {
+		t.Fatalf("expected SSRC 0, got %d", ssrc)
+	}
+}
+
+func TestRTPPayloadType(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	tr.SetRTP(config.IPSCRTP{PayloadType: 0x60, TerminatorPayloadType: 0x61, SSRCMode: config.RTPSSRCFixed})
+	header, terminator := rtpCall(t, tr, 1)
+	if header[19] != 0x80|0x60 {
+		t.Fatalf("expected marker and payload type 0x60, got 0x%02X", header[19])
+	}
+	if terminator[19] != 0x61 {
+		t.Fatalf("expected terminator payload type 0x61, got 0x%02X", terminator[19])
+	}
+
+	// Data calls use the same payload type.
+	data := tr.BuildDataCall(100, 200, false, false, []DataBlock{{Type: elements.DataTypeCSBK}})
+	if len(data) != 1 || data[0][19]&0x7F != 0x60 {
+		t.Fatalf("expected a data packet with payload type 0x60, got %v", data)
+	}
+}
+
+func TestRTPSSRCModes(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		rtp  config.IPSCRTP
+		want func(first, second uint32) bool
+	}{
+		{"fixed", config.IPSCRTP{PayloadType: 0x5D, TerminatorPayloadType: 0x5E, SSRCMode: config.RTPSSRCFixed, SSRC: 0xDEADBEEF},
+			func(first, second uint32) bool { return first == 0xDEADBEEF && second == 0xDEADBEEF }},
+		{"peer id", config.IPSCRTP{PayloadType: 0x5D, TerminatorPayloadType: 0x5E, SSRCMode: config.RTPSSRCPeerID, SSRC: 0xDEADBEEF},
+			func(first, second uint32) bool { return first == 12345 && second == 12345 }},
+		// Two random 32-bit values collide about once in four billion runs.
+		{"random", config.IPSCRTP{PayloadType: 0x5D, TerminatorPayloadType: 0x5E, SSRCMode: config.RTPSSRCRandom},
+			func(first, second uint32) bool { return first != second }},
+		{"per stream", config.IPSCRTP{PayloadType: 0x5D, TerminatorPayloadType: 0x5E, SSRCMode: config.RTPSSRCPerStream, SSRC: 0xDEADBEEF},
+			func(first, second uint32) bool { return first == 0xDEADBEEF && second == 0xDEADBEF0 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tr := newTestTranslator(t)
+			tr.SetRTP(tt.rtp)
+			firstHeader, firstTerminator := rtpCall(t, tr, 1)
+			secondHeader, _ := rtpCall(t, tr, 2)
+			first := binary.BigEndian.Uint32(firstHeader[26:30])
+			second := binary.BigEndian.Uint32(secondHeader[26:30])
+			if !tt.want(first, second) {
+				t.Fatalf("unexpected SSRCs %#x and %#x", first, second)
+			}
+			if got := binary.BigEndian.Uint32(firstTerminator[26:30]); got != first {
+				t.Fatalf("expected the SSRC to stay %#x for the whole call, got %#x", first, got)
+			}
+		})
+	}
+}
+
+func TestRTPSSRCPerStreamConcurrentCalls(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	tr.SetRTP(config.IPSCRTP{PayloadType: 0x60, TerminatorPayloadType: 0x61, SSRCMode: config.RTPSSRCPerStream, SSRC: 1000})
+
+	// Two calls on different slots, their packets interleaved.
+	first := makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 1)
+	second := makeTestMMDVMPacket(true, true, mmdvmFrameTypeDataSync, 1)
+	second.StreamID = 0x5678
+	voice := func(pkt mmdvm.Packet) mmdvm.Packet {
+		pkt.FrameType, pkt.DTypeOrVSeq = mmdvmFrameTypeVoiceSync, 0
+		pkt.DMRData = makeVoiceDMRData(true)
+		return pkt
+	}
+	ssrcs := map[uint]uint32{}
+	for _, pkt := range []mmdvm.Packet{first, second, voice(first), voice(second)} {
+		for _, data := range tr.TranslateToIPSC(pkt) {
+			if pt := data[19] & 0x7F; pt != 0x60 {
+				t.Fatalf("expected payload type 0x60, got 0x%02X", pt)
+			}
+			ssrc := binary.BigEndian.Uint32(data[26:30])
+			if want, ok := ssrcs[pkt.StreamID]; ok && ssrc != want {
+				t.Fatalf("stream 0x%X: expected SSRC %d for the whole call, got %d", pkt.StreamID, want, ssrc)
+			}
+			ssrcs[pkt.StreamID] = ssrc
+		}
+	}
+	if ssrcs[first.StreamID] != 1000 || ssrcs[second.StreamID] != 1001 {
+		t.Fatalf("expected SSRCs 1000 and 1001, got %d and %d", ssrcs[first.StreamID], ssrcs[second.StreamID])
+	}
+}
+
+func TestSwapSlots(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		swap     bool
+		ipscTS2  bool
+		mmdvmTS2 bool
+	}{
+		{"TS1 unswapped", false, false, false},
+		{"TS2 unswapped", false, true, true},
+		{"TS1 swapped", true, false, true},
+		{"TS2 swapped", true, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tr := newTestTranslator(t)
+			tr.SetSwapSlots(tt.swap)
+
+			// IPSC → MMDVM
+			header := makeTestIPSCPacket(0x80, ipscBurstVoiceHead, true, tt.ipscTS2)
+			result := tr.TranslateToMMDVM(0x80, header)
+			if len(result) != 1 {
+				t.Fatalf("expected 1 packet, got %d", len(result))
+			}
+			if result[0].Slot != tt.mmdvmTS2 {
+				t.Fatalf("expected MMDVM TS2=%t, got %t", tt.mmdvmTS2, result[0].Slot)
+			}
+			streams := tr.ReverseStreams()
+			if len(streams) != 1 || streams[0].Slot != tt.mmdvmTS2 {
+				t.Fatalf("expected the stream on MMDVM TS2=%t, got %+v", tt.mmdvmTS2, streams)
+			}
+			term := tr.TerminatePeerStreams(99999)
+			if len(term) != 1 || term[0].Slot != tt.mmdvmTS2 {
+				t.Fatalf("expected the terminator on MMDVM TS2=%t, got %+v", tt.mmdvmTS2, term)
+			}
+
+			// MMDVM → IPSC: the same call sent back lands on the slot it
+			// came from.
+			pkts := tr.TranslateToIPSC(makeTestMMDVMPacket(true, tt.mmdvmTS2, mmdvmFrameTypeDataSync, 1))
+			if len(pkts) == 0 {
+				t.Fatal("expected packets")
+			}
+			for _, pkt := range pkts {
+				if got := pkt[17]&0x20 != 0; got != tt.ipscTS2 {
+					t.Fatalf("expected IPSC TS2=%t, got callInfo %02X", tt.ipscTS2, pkt[17])
+				}
+			}
+		})
+	}
+}
+
 func TestTranslateToIPSCSrcDstInHeader(t *testing.T) {
 	t.Parallel()
 	tr := newTestTranslator(t)
@@ -239,6 +559,10 @@ func TestTranslateToMMDVMUnsupportedType(t *testing.T) {
 	}
 }
 
+// testRTPSeq is the RTP sequence number of the last packet
+// makeTestIPSCPacket made.
+var testRTPSeq atomic.Uint32
+
 func makeTestIPSCPacket(packetType byte, burstType byte, groupCall, slot bool) []byte {
 	buf := make([]byte, 54)
 	buf[0] = packetType
@@ -275,8 +599,10 @@ func makeTestIPSCPacket(packetType byte, burstType byte, groupCall, slot bool) [
 	}
 	buf[17] = callInfo
 
-	// RTP header stub (bytes 18-29)
+	// RTP header stub (bytes 18-29), each packet taking the next
+	// sequence number so none looks like a retransmission
 	buf[18] = 0x80
+	binary.BigEndian.PutUint16(buf[20:22], uint16(testRTPSeq.Add(1))) //nolint:gosec // G115: wraps like RTP
 
 	// Burst type (byte 30)
 	buf[30] = burstType
@@ -318,13 +644,232 @@ func TestTranslateToMMDVMDuplicateHeaderSkipped(t *testing.T) {
 		t.Fatalf("expected 1 packet for first header, got %d", len(result))
 	}
 
-	// Second header with same call control should be skipped
-	result = tr.TranslateToMMDVM(0x80, data)
+	// A repeat of the header, with the same call control and before any
+	// voice, should be skipped
+	repeat := makeTestIPSCPacket(0x80, ipscBurstVoiceHead, true, false)
+	result = tr.TranslateToMMDVM(0x80, repeat)
 	if len(result) != 0 {
 		t.Fatalf("expected 0 packets for duplicate header, got %d", len(result))
 	}
 }
 
+func TestSeededReverseStreamSkipsDuplicateHeader(t *testing.T) {
+	t.Parallel()
+	before := newTestTranslator(t)
+	header := makeTestIPSCPacket(0x80, ipscBurstVoiceHead, true, false)
+	first := before.TranslateToMMDVM(0x80, header)
+	if len(first) != 1 {
+		t.Fatalf("expected 1 packet for the header, got %d", len(first))
+	}
+
+	// A restart in the middle of the call.
+	after := newTestTranslator(t)
+	after.SeedReverseStreams(before.ReverseStreams())
+
+	if result := after.TranslateToMMDVM(0x80, header); len(result) != 0 {
+		t.Fatalf("expected the repeated header to be skipped after the restart, got %d packets", len(result))
+	}
+	term := makeTestIPSCPacket(0x80, ipscBurstVoiceTerm, true, false)
+	result := after.TranslateToMMDVM(0x80, term)
+	if len(result) != 1 {
+		t.Fatalf("expected 1 packet for the terminator, got %d", len(result))
+	}
+	if result[0].StreamID != first[0].StreamID || result[0].Seq != first[0].Seq+1 {
+		t.Fatalf("expected the call to continue as stream %d seq %d, got stream %d seq %d",
+			first[0].StreamID, first[0].Seq+1, result[0].StreamID, result[0].Seq)
+	}
+	if streams := after.ReverseStreams(); len(streams) != 0 {
+		t.Fatalf("expected the ended call to be forgotten, got %+v", streams)
+	}
+}
+
+func TestTranslateToMMDVMReusedCallControl(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	header := func() []mmdvm.Packet {
+		t.Helper()
+		result := tr.TranslateToMMDVM(0x80, makeTestIPSCPacket(0x80, ipscBurstVoiceHead, true, false))
+		if len(result) == 0 || result[len(result)-1].DTypeOrVSeq != uint(elements.DataTypeVoiceLCHeader) {
+			t.Fatalf("expected the header to start a call, got %d packets", len(result))
+		}
+		return result
+	}
+
+	// header → terminator → header under one call control
+	first := header()[0]
+	term := tr.TranslateToMMDVM(0x80, makeTestIPSCPacket(0x80, ipscBurstVoiceTerm, true, false))
+	if len(term) != 1 || term[0].StreamID != first.StreamID {
+		t.Fatalf("expected the terminator to end stream %d", first.StreamID)
+	}
+	second := header()
+	if len(second) != 1 || second[0].StreamID == first.StreamID {
+		t.Fatalf("expected a second call with a new stream ID, got %+v", second)
+	}
+
+	// header → voice → header, the terminator lost
+	voice := makeTestIPSCPacket(0x80, ipscBurstSlot1, true, false)
+	if result := tr.TranslateToMMDVM(0x80, voice); len(result) != 1 || result[0].StreamID != second[0].StreamID {
+		t.Fatalf("expected voice on stream %d, got %+v", second[0].StreamID, result)
+	}
+	third := header()
+	if len(third) != 2 {
+		t.Fatalf("expected a terminator and a header, got %d packets", len(third))
+	}
+	if third[0].DTypeOrVSeq != uint(elements.DataTypeTerminatorWithLC) || third[0].StreamID != second[0].StreamID {
+		t.Fatalf("expected the unterminated call %d to be ended, got %+v", second[0].StreamID, third[0])
+	}
+	if third[1].StreamID == second[0].StreamID || third[1].StreamID == first.StreamID {
+		t.Fatalf("expected a third stream ID, got %d", third[1].StreamID)
+	}
+	if streams := tr.ReverseStreams(); len(streams) != 1 || uint(streams[0].StreamID) != third[1].StreamID {
+		t.Fatalf("expected only the new call to be in progress, got %+v", streams)
+	}
+}
+
+func TestRTPMarkerStartsCallToMMDVM(t *testing.T) {
+	t.Parallel()
+	marked := func(burstType byte) []byte {
+		data := makeTestIPSCPacket(0x80, burstType, true, false)
+		data[19] |= 0x80
+		return data
+	}
+	header := func(t *testing.T, pkt mmdvm.Packet) {
+		t.Helper()
+		if pkt.FrameType != mmdvmFrameTypeDataSync || pkt.DTypeOrVSeq != uint(elements.DataTypeVoiceLCHeader) {
+			t.Fatalf("expected a voice header, got frame type %d dtype %d", pkt.FrameType, pkt.DTypeOrVSeq)
+		}
+	}
+
+	t.Run("header with marker", func(t *testing.T) {
+		t.Parallel()
+		tr := newTestTranslator(t)
+		result := tr.TranslateToMMDVM(0x80, marked(ipscBurstVoiceHead))
+		if len(result) != 1 {
+			t.Fatalf("expected 1 header packet, got %d", len(result))
+		}
+		header(t, result[0])
+		// The first voice burst after it, marked or not, continues the call.
+		voice := tr.TranslateToMMDVM(0x80, marked(ipscBurstSlot1))
+		if len(voice) != 1 || voice[0].StreamID != result[0].StreamID {
+			t.Fatalf("expected voice on stream %d, got %+v", result[0].StreamID, voice)
+		}
+	})
+
+	t.Run("marker without header", func(t *testing.T) {
+		t.Parallel()
+		tr := newTestTranslator(t)
+		result := tr.TranslateToMMDVM(0x80, marked(ipscBurstSlot1))
+		if len(result) != 2 {
+			t.Fatalf("expected a header and a voice burst, got %d packets", len(result))
+		}
+		header(t, result[0])
+		if result[1].StreamID != result[0].StreamID {
+			t.Fatalf("expected one stream, got %d and %d", result[0].StreamID, result[1].StreamID)
+		}
+	})
+
+	t.Run("marker after voice", func(t *testing.T) {
+		t.Parallel()
+		tr := newTestTranslator(t)
+		first := tr.TranslateToMMDVM(0x80, makeTestIPSCPacket(0x80, ipscBurstVoiceHead, true, false))
+		if len(first) != 1 {
+			t.Fatalf("expected 1 header packet, got %d", len(first))
+		}
+		if voice := tr.TranslateToMMDVM(0x80, makeTestIPSCPacket(0x80, ipscBurstSlot1, true, false)); len(voice) != 1 {
+			t.Fatalf("expected 1 voice packet, got %d", len(voice))
+		}
+		if voice := tr.TranslateToMMDVM(0x80, makeTestIPSCPacket(0x80, ipscBurstSlot1, true, false)); len(voice) != 1 || voice[0].StreamID != first[0].StreamID {
+			t.Fatalf("expected unmarked voice to continue stream %d, got %+v", first[0].StreamID, voice)
+		}
+
+		// The terminator and the next call's header were lost.
+		result := tr.TranslateToMMDVM(0x80, marked(ipscBurstSlot1))
+		if len(result) != 3 {
+			t.Fatalf("expected a terminator, a header, and a voice burst, got %d packets", len(result))
+		}
+		if result[0].DTypeOrVSeq != uint(elements.DataTypeTerminatorWithLC) || result[0].StreamID != first[0].StreamID {
+			t.Fatalf("expected the unterminated call %d to be ended, got %+v", first[0].StreamID, result[0])
+		}
+		header(t, result[1])
+		if result[1].StreamID == first[0].StreamID || result[2].StreamID != result[1].StreamID {
+			t.Fatalf("expected the new call on a new stream, got %d and %d", result[1].StreamID, result[2].StreamID)
+		}
+	})
+}
+
+func TestRTPMarkerOnFirstPacketToIPSC(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		withHeader bool
+	}{
+		{"header first", true},
+		{"voice first", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tr := newTestTranslator(t)
+			var sent [][]byte
+			if tt.withHeader {
+				sent = append(sent, tr.TranslateToIPSC(makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 1))...)
+			}
+			sent = append(sent, sendVoiceFrames(t, tr, 6)...)
+			if len(sent) == 0 {
+				t.Fatal("expected packets toward IPSC")
+			}
+			for i, data := range sent {
+				if marker := data[19]&0x80 != 0; marker != (i == 0) {
+					t.Fatalf("packet %d: expected marker %t, got %t", i, i == 0, marker)
+				}
+			}
+		})
+	}
+}
+
+// makeSequencedIPSCPacket returns an IPSC group voice packet of call
+// 0xD00D with RTP sequence number seq.
+func makeSequencedIPSCPacket(burstType byte, seq uint16) []byte {
+	data := makeTestIPSCPacket(0x80, burstType, true, false)
+	binary.BigEndian.PutUint32(data[13:17], 0xD00D)
+	binary.BigEndian.PutUint16(data[20:22], seq)
+	return data
+}
+
+func TestTranslateToMMDVMDropsDuplicateSequence(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		seqs       []uint16
+		want       []int // packets toward the master for each burst
+		duplicates int
+	}{
+		{"exact duplicate", []uint16{11, 11, 12}, []int{1, 0, 1}, 1},
+		{"reordered by one", []uint16{12, 11, 13}, []int{1, 0, 1}, 1},
+		{"wraparound", []uint16{0xFFFF, 0x0000, 0x0001, 0xFFFF}, []int{1, 1, 1, 0}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tr := newTestTranslator(t)
+			header := makeSequencedIPSCPacket(ipscBurstVoiceHead, tt.seqs[0]-1)
+			if result := tr.TranslateToMMDVM(0x80, header); len(result) != 1 {
+				t.Fatalf("expected the header to start the call, got %d packets", len(result))
+			}
+			for i, seq := range tt.seqs {
+				burst := makeSequencedIPSCPacket(ipscBurstSlot1, seq)
+				if result := tr.TranslateToMMDVM(0x80, burst); len(result) != tt.want[i] {
+					t.Fatalf("burst %d with sequence %d: expected %d packets, got %d", i, seq, tt.want[i], len(result))
+				}
+			}
+			streams := tr.ReverseStreams()
+			if len(streams) != 1 || streams[0].Duplicates != tt.duplicates {
+				t.Fatalf("expected one stream with %d duplicates, got %+v", tt.duplicates, streams)
+			}
+		})
+	}
+}
+
 func TestTranslateToMMDVMVoiceTerminator(t *testing.T) {
 	t.Parallel()
 	tr := newTestTranslator(t)
@@ -344,6 +889,140 @@ func TestTranslateToMMDVMVoiceTerminator(t *testing.T) {
 	}
 }
 
+func TestTerminatePeerStreams(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+
+	header := makeTestIPSCPacket(0x80, ipscBurstVoiceHead, true, true)
+	start := tr.TranslateToMMDVM(0x80, header)
+	if len(start) != 1 {
+		t.Fatalf("expected 1 packet for voice header, got %d", len(start))
+	}
+
+	// A call from another peer must survive.
+	other := makeTestIPSCPacket(0x80, ipscBurstVoiceHead, true, false)
+	binary.BigEndian.PutUint32(other[1:5], 12345)
+	binary.BigEndian.PutUint32(other[13:17], 0xBBBB)
+	tr.TranslateToMMDVM(0x80, other)
+
+	streams := tr.ReverseStreams()
+	if len(streams) != 2 {
+		t.Fatalf("expected 2 active streams, got %d", len(streams))
+	}
+
+	result := tr.TerminatePeerStreams(99999)
+	if len(result) != 1 {
+		t.Fatalf("expected 1 terminator, got %d", len(result))
+	}
+	pkt := result[0]
+	if pkt.FrameType != mmdvmFrameTypeDataSync || pkt.DTypeOrVSeq != 2 {
+		t.Fatalf("expected a terminator with LC, got frame type %d dtype %d", pkt.FrameType, pkt.DTypeOrVSeq)
+	}
+	if pkt.StreamID != start[0].StreamID {
+		t.Fatalf("expected stream ID %d, got %d", start[0].StreamID, pkt.StreamID)
+	}
+	if pkt.Src != 100 || pkt.Dst != 200 || !pkt.GroupCall || !pkt.Slot {
+		t.Fatalf("expected the call's addressing, got src %d dst %d group %t slot %t", pkt.Src, pkt.Dst, pkt.GroupCall, pkt.Slot)
+	}
+	if pkt.Seq != start[0].Seq+1 {
+		t.Fatalf("expected seq %d, got %d", start[0].Seq+1, pkt.Seq)
+	}
+
+	streams = tr.ReverseStreams()
+	if len(streams) != 1 || streams[0].PeerID != 12345 {
+		t.Fatalf("expected only the other peer's stream to remain, got %+v", streams)
+	}
+	if got := tr.TerminatePeerStreams(99999); len(got) != 0 {
+		t.Fatalf("expected no terminators on a second call, got %d", len(got))
+	}
+}
+
+func TestTerminateStreams(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+
+	tr.TranslateToMMDVM(0x80, makeTestIPSCPacket(0x80, ipscBurstVoiceHead, true, true))
+	other := makeTestIPSCPacket(0x80, ipscBurstVoiceHead, true, false)
+	binary.BigEndian.PutUint32(other[1:5], 12345)
+	binary.BigEndian.PutUint32(other[13:17], 0xBBBB)
+	tr.TranslateToMMDVM(0x80, other)
+
+	result := tr.TerminateStreams()
+	if len(result) != 2 {
+		t.Fatalf("expected a terminator for each peer's call, got %d", len(result))
+	}
+	for _, pkt := range result {
+		if pkt.FrameType != mmdvmFrameTypeDataSync || pkt.DTypeOrVSeq != 2 {
+			t.Fatalf("expected a terminator with LC, got frame type %d dtype %d", pkt.FrameType, pkt.DTypeOrVSeq)
+		}
+	}
+	if streams := tr.ReverseStreams(); len(streams) != 0 {
+		t.Fatalf("expected no streams left, got %+v", streams)
+	}
+	if got := tr.TerminateStreams(); len(got) != 0 {
+		t.Fatalf("expected no terminators on a second call, got %d", len(got))
+	}
+}
+
+func TestReverseStreamsEvictLeastRecentlyActive(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	tr.SetMaxStreams(3)
+	clock := time.Unix(1_700_000_000, 0)
+	tr.now = func() time.Time { return clock }
+
+	// send starts or continues the call under callControl a second after
+	// the last packet, returning its stream ID.
+	var rtpSeq uint16
+	send := func(callControl uint32, burstType byte) uint32 {
+		t.Helper()
+		clock = clock.Add(time.Second)
+		rtpSeq++
+		data := makeTestIPSCPacket(0x80, burstType, true, false)
+		binary.BigEndian.PutUint32(data[13:17], callControl)
+		binary.BigEndian.PutUint16(data[20:22], rtpSeq)
+		result := tr.TranslateToMMDVM(0x80, data)
+		if len(result) == 0 {
+			t.Fatalf("expected call 0x%X to be translated", callControl)
+		}
+		return uint32(result[0].StreamID) //nolint:gosec // G115: stream IDs are 32-bit
+	}
+	streamIDs := map[uint32]uint32{}
+	for _, callControl := range []uint32{1, 2, 3} {
+		streamIDs[callControl] = send(callControl, ipscBurstVoiceHead)
+	}
+	// Call 1 is the oldest but still active, leaving call 2 the idlest.
+	send(1, ipscBurstSlot1)
+	streamIDs[4] = send(4, ipscBurstVoiceHead)
+
+	active := map[uint32]bool{}
+	for _, stream := range tr.ReverseStreams() {
+		active[stream.StreamID] = true
+	}
+	if len(active) != 3 {
+		t.Fatalf("expected 3 streams, got %d", len(active))
+	}
+	for _, callControl := range []uint32{1, 3, 4} {
+		if !active[streamIDs[callControl]] {
+			t.Fatalf("expected call %d to survive", callControl)
+		}
+	}
+	if active[streamIDs[2]] {
+		t.Fatal("expected the idlest call to be evicted")
+	}
+	if tr.evictions != 1 {
+		t.Fatalf("expected 1 eviction, got %d", tr.evictions)
+	}
+
+	// The evicted call starts afresh if it resumes.
+	if id := send(2, ipscBurstVoiceHead); id == streamIDs[2] {
+		t.Fatal("expected the evicted call to get a new stream ID")
+	}
+	if tr.evictions != 2 {
+		t.Fatalf("expected 2 evictions, got %d", tr.evictions)
+	}
+}
+
 func TestTranslateToMMDVMPrivateCall(t *testing.T) {
 	t.Parallel()
 	tr := newTestTranslator(t)
@@ -389,7 +1068,7 @@ func TestTranslateToMMDVMEndFlagCleansUp(t *testing.T) {
 
 	// Verify the stream was cleaned up
 	tr.mu.Lock()
-	_, exists := tr.reverseStreams[0xCCCC]
+	_, exists := tr.reverseStreams[reverseStreamKey{peerID: 99999, callControl: 0xCCCC}]
 	tr.mu.Unlock()
 	if exists {
 		t.Fatal("expected reverse stream to be cleaned up after end flag")
@@ -417,7 +1096,7 @@ func TestExtractFullLCBytesGroupCall(t *testing.T) {
 		Src:       100,
 		Dst:       200,
 	}
-	lc := extractFullLCBytes(pkt)
+	lc := extractFullLCBytes(pkt, elements.DataTypeVoiceLCHeader)
 	// First byte should be FLCO for group call (0x00)
 	if lc[0] != 0x00 {
 		t.Fatalf("expected FLCO 0x00 (group), got 0x%02X", lc[0])
@@ -431,7 +1110,7 @@ func TestExtractFullLCBytesPrivateCall(t *testing.T) {
 		Src:       100,
 		Dst:       200,
 	}
-	lc := extractFullLCBytes(pkt)
+	lc := extractFullLCBytes(pkt, elements.DataTypeVoiceLCHeader)
 	// First byte should be FLCO for unit-to-unit (0x03)
 	if lc[0] != 0x03 {
 		t.Fatalf("expected FLCO 0x03 (unit-to-unit), got 0x%02X", lc[0])
@@ -441,7 +1120,7 @@ func TestExtractFullLCBytesPrivateCall(t *testing.T) {
 func TestBuildIPSCHeaderDataPacket(t *testing.T) {
 	t.Parallel()
 	tr := newTestTranslator(t)
-	pkt := makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 3) // CSBK
+	pkt := makeCSBKMMDVMPacket(csbkBlock(csbk.OpcodeBSOutboundActivation))
 	result := tr.TranslateToIPSC(pkt)
 	if len(result) < 1 {
 		t.Fatal("expected at least 1 data packet")
@@ -501,6 +1180,83 @@ func TestBuildRTPHeaderNoMarker(t *testing.T) {
 	}
 }
 
+// sendVoiceFrames sends n voice frames, starting at burst A, on the
+// stream of makeTestMMDVMPacket and returns the IPSC packets produced.
+func sendVoiceFrames(t *testing.T, tr *IPSCTranslator, n int) [][]byte {
+	t.Helper()
+	var out [][]byte
+	for i := range n {
+		pos := i % 6
+		ft := mmdvmFrameTypeVoice
+		if pos == 0 {
+			ft = mmdvmFrameTypeVoiceSync
+		}
+		pkt := makeTestMMDVMPacket(true, false, ft, uint(pos)) //nolint:gosec // G115: pos is in [0,5]
+		pkt.DMRData = makeVoiceDMRData(pos == 0)
+		result := tr.TranslateToIPSC(pkt)
+		if len(result) != 1 {
+			t.Fatalf("frame %d: expected 1 packet, got %d", i, len(result))
+		}
+		out = append(out, result[0])
+	}
+	return out
+}
+
+// checkRTPContinuity verifies that packets carry consecutive RTP sequence
+// numbers starting at firstSeq, timestamps one burst apart, and one SSRC.
+func checkRTPContinuity(t *testing.T, packets [][]byte, firstSeq uint16) {
+	t.Helper()
+	ssrc := binary.BigEndian.Uint32(packets[0][26:30])
+	timestamp := binary.BigEndian.Uint32(packets[0][22:26])
+	for i, data := range packets {
+		wantSeq := firstSeq + uint16(i) //nolint:gosec // G115: wraps by design
+		if got := binary.BigEndian.Uint16(data[20:22]); got != wantSeq {
+			t.Fatalf("packet %d: expected RTP sequence %d, got %d", i, wantSeq, got)
+		}
+		wantTimestamp := timestamp + uint32(i)*rtpTimestampIncrement //nolint:gosec // G115: i is small
+		if got := binary.BigEndian.Uint32(data[22:26]); got != wantTimestamp {
+			t.Fatalf("packet %d: expected RTP timestamp %d, got %d", i, wantTimestamp, got)
+		}
+		if got := binary.BigEndian.Uint32(data[26:30]); got != ssrc {
+			t.Fatalf("packet %d: expected SSRC 0x%08X for the whole call, got 0x%08X", i, ssrc, got)
+		}
+	}
+}
+
+func TestRTPContinuity(t *testing.T) {
+	t.Parallel()
+	for _, mode := range []config.RTPSSRCMode{config.RTPSSRCFixed, config.RTPSSRCRandom} {
+		t.Run(string(mode), func(t *testing.T) {
+			t.Parallel()
+			tr := newTestTranslator(t)
+			tr.SetRTP(config.IPSCRTP{PayloadType: 93, TerminatorPayloadType: 94, SSRCMode: mode, SSRC: 0x1234})
+			header := makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, uint(elements.DataTypeVoiceLCHeader))
+			packets := tr.TranslateToIPSC(header)
+			packets = append(packets, sendVoiceFrames(t, tr, 20)...)
+			if len(packets) != 23 {
+				t.Fatalf("expected 3 headers and 20 voice packets, got %d", len(packets))
+			}
+			checkRTPContinuity(t, packets, 0)
+		})
+	}
+}
+
+func TestRTPSequenceWraps(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	header := makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, uint(elements.DataTypeVoiceLCHeader))
+	tr.TranslateToIPSC(header)
+	tr.mu.Lock()
+	tr.streams[uint32(header.StreamID)].rtpSeq = 0xFFFC
+	tr.mu.Unlock()
+
+	packets := sendVoiceFrames(t, tr, 8)
+	checkRTPContinuity(t, packets, 0xFFFC)
+	if got := binary.BigEndian.Uint16(packets[4][20:22]); got != 0 {
+		t.Fatalf("expected the sequence to wrap to 0, got %d", got)
+	}
+}
+
 func TestMultipleStreamsConcurrent(t *testing.T) {
 	t.Parallel()
 	tr := newTestTranslator(t)
@@ -640,13 +1396,76 @@ func TestBuildVoiceBurstBCDF(t *testing.T) {
 	}
 }
 
-func TestBuildVoiceBurstE(t *testing.T) {
+// Reference voice burst A: three AMBE frames as IPSC carries them, and
+// the same frames FEC-coded into a 33-byte burst around the MS-sourced
+// voice sync.
+var (
+	refAMBEPayload = [19]byte{
+		0xAC, 0xAA, 0x40, 0x20, 0x00, 0x44, 0x00, 0x80, 0x80, 0x23,
+		0x5A, 0x12, 0xC3, 0x91, 0x00, 0x7E, 0x11, 0x4A, 0x00,
+	}
+	refVoiceBurstA = [33]byte{
+		0xC2, 0x86, 0xE8, 0x22, 0x82, 0x94, 0x82, 0x5C, 0xC4, 0x10, 0x75,
+		0x25, 0xE4, 0x47, 0xF7, 0xD5, 0xDD, 0x57, 0xDF, 0xD0, 0x32, 0x09,
+		0xC7, 0xF8, 0x12, 0xE8, 0xD2, 0x5E, 0x25, 0x4B, 0x22, 0x40, 0x84,
+	}
+)
+
+func TestVoiceBurstAMBEToIPSC(t *testing.T) {
 	t.Parallel()
 	tr := newTestTranslator(t)
+	tr.TranslateToIPSC(makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 1))
 
-	// Establish stream
-	header := makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 1)
-	tr.TranslateToIPSC(header)
+	pkt := makeTestMMDVMPacket(true, false, mmdvmFrameTypeVoiceSync, 0)
+	pkt.DMRData = refVoiceBurstA
+	result := tr.TranslateToIPSC(pkt)
+	if len(result) != 1 {
+		t.Fatalf("expected 1 voice burst packet, got %d", len(result))
+	}
+	if got := [19]byte(result[0][33:52]); got != refAMBEPayload {
+		t.Fatalf("expected AMBE payload % X, got % X", refAMBEPayload, got)
+	}
+}
+
+func TestVoiceBurstAMBEToMMDVM(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	tr.TranslateToMMDVM(0x80, makeSequencedIPSCPacket(ipscBurstVoiceHead, 1))
+
+	burstA := makeSequencedIPSCPacket(ipscBurstSlot1, 2)[:52]
+	copy(burstA[33:52], refAMBEPayload[:])
+	result := tr.TranslateToMMDVM(0x80, burstA)
+	if len(result) != 1 {
+		t.Fatalf("expected 1 DMRD packet, got %d", len(result))
+	}
+	if result[0].DMRData != refVoiceBurstA {
+		t.Fatalf("expected burst A % X, got % X", refVoiceBurstA, result[0].DMRData)
+	}
+
+	// Burst B carries the same frames around embedded signalling.
+	burstB := append(makeSequencedIPSCPacket(ipscBurstSlot1, 3), 0, 0, 0)
+	copy(burstB[33:52], refAMBEPayload[:])
+	result = tr.TranslateToMMDVM(0x80, burstB)
+	if len(result) != 1 {
+		t.Fatalf("expected 1 DMRD packet, got %d", len(result))
+	}
+	got := result[0].DMRData
+	if [13]byte(got[:13]) != [13]byte(refVoiceBurstA[:13]) || [13]byte(got[20:]) != [13]byte(refVoiceBurstA[20:]) {
+		t.Fatalf("expected the voice bits of burst A around the embedded signalling, got % X", got)
+	}
+	burst := layer2.NewBurstFromBytes(got)
+	if burst.SyncPattern != enums.EmbeddedSignallingPattern {
+		t.Fatalf("expected embedded signalling, got sync pattern %v", burst.SyncPattern)
+	}
+}
+
+func TestBuildVoiceBurstE(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+
+	// Establish stream
+	header := makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 1)
+	tr.TranslateToIPSC(header)
 
 	// Send bursts A-D to advance burstIndex to 4
 	for i := 0; i < 4; i++ {
@@ -760,6 +1579,94 @@ func TestBuildVoiceBurstWrapsAfterF(t *testing.T) {
 	}
 }
 
+// sendSuperframe sends the header and bursts A-F of a call through tr,
+// skipping the positions in skip, and returns the IPSC header and the
+// voice packets by position.
+func sendSuperframe(t *testing.T, tr *IPSCTranslator, skip ...int) ([]byte, map[int][]byte) {
+	t.Helper()
+	header := makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, uint(elements.DataTypeVoiceLCHeader))
+	header.Src = 3120101
+	header.Dst = 3100
+	headers := tr.TranslateToIPSC(header)
+	if len(headers) == 0 {
+		t.Fatal("expected header packets")
+	}
+
+	bursts := map[int][]byte{}
+	for i := range 6 {
+		if slices.Contains(skip, i) {
+			continue
+		}
+		ft := mmdvmFrameTypeVoice
+		if i == 0 {
+			ft = mmdvmFrameTypeVoiceSync
+		}
+		pkt := makeTestMMDVMPacket(true, false, ft, uint(i)) //nolint:gosec // G115: i is in [0,5]
+		pkt.Src, pkt.Dst = header.Src, header.Dst
+		pkt.DMRData = makeVoiceDMRData(i == 0)
+		result := tr.TranslateToIPSC(pkt)
+		if len(result) != 1 {
+			t.Fatalf("burst %c: expected 1 packet, got %d", 'A'+i, len(result))
+		}
+		bursts[i] = result[0]
+	}
+	return headers[0], bursts
+}
+
+func TestVoiceSuperframeToIPSC(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	header, bursts := sendSuperframe(t, tr)
+
+	// Each position has its own layout: A is the sync burst, E carries
+	// the last LC fragment and the addresses, and B, C, D, and F carry
+	// embedded signalling.
+	wantLen := []int{52, 57, 57, 57, 66, 57}
+	wantField := []byte{0x14, 0x19, 0x19, 0x19, 0x22, 0x19}
+	for i := range 6 {
+		data := bursts[i]
+		if len(data) != wantLen[i] || data[31] != wantField[i] {
+			t.Fatalf("burst %c: expected %d bytes with length field 0x%02X, got %d bytes with 0x%02X",
+				'A'+i, wantLen[i], wantField[i], len(data), data[31])
+		}
+		if data[30] != ipscBurstSlot1 {
+			t.Fatalf("burst %c: expected burst type 0x%02X, got 0x%02X", 'A'+i, ipscBurstSlot1, data[30])
+		}
+	}
+
+	var fragments bptc.Fragments
+	for i := range 4 {
+		copy(fragments[i][:], bursts[i+1][52:56])
+	}
+	lc, ok := bptc.DecodeEmbeddedLC(fragments)
+	if !ok {
+		t.Fatal("expected the embedded LC checksum to verify")
+	}
+	if string(lc[:]) != string(header[38:47]) {
+		t.Fatalf("expected the embedded LC to match the header LC % X, got % X", header[38:47], lc)
+	}
+	dst := uint(lc[3])<<16 | uint(lc[4])<<8 | uint(lc[5])
+	src := uint(lc[6])<<16 | uint(lc[7])<<8 | uint(lc[8])
+	if src != 3120101 || dst != 3100 {
+		t.Fatalf("expected src 3120101 and dst 3100 in the embedded LC, got %d and %d", src, dst)
+	}
+}
+
+func TestVoiceBurstPositionSurvivesLoss(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	_, bursts := sendSuperframe(t, tr, 2)
+
+	// D arrives after C was lost and is still laid out as D.
+	if len(bursts[3]) != 57 || len(bursts[4]) != 66 {
+		t.Fatalf("expected D and E to keep their layouts, got %d and %d bytes", len(bursts[3]), len(bursts[4]))
+	}
+	_, full := sendSuperframe(t, newTestTranslator(t))
+	if string(bursts[3][52:56]) != string(full[3][52:56]) {
+		t.Fatalf("expected D to carry the third LC fragment % X, got % X", full[3][52:56], bursts[3][52:56])
+	}
+}
+
 func TestBuildMMDVMVoiceBurstFromSlot1(t *testing.T) {
 	t.Parallel()
 	tr := newTestTranslator(t)
@@ -817,7 +1724,7 @@ func TestBuildMMDVMVoiceBurstSequencing(t *testing.T) {
 
 	// Send 3 voice bursts and verify sequencing
 	for i := 0; i < 3; i++ {
-		burstData := make([]byte, 52)
+		burstData := make([]byte, ipscVoiceBurstLen(i))
 		copy(burstData[:18], header[:18])
 		binary.BigEndian.PutUint32(burstData[13:17], 0xEEEE)
 		burstData[30] = ipscBurstSlot1
@@ -859,7 +1766,7 @@ func TestBuildMMDVMVoiceBurstWrapsAt6(t *testing.T) {
 
 	// Send 7 voice bursts — the 7th should wrap to burstIndex 0 (voice sync again)
 	for i := 0; i < 7; i++ {
-		burstData := make([]byte, 52)
+		burstData := make([]byte, ipscVoiceBurstLen(i%6))
 		copy(burstData[:18], header[:18])
 		binary.BigEndian.PutUint32(burstData[13:17], 0xFFFF)
 		burstData[30] = ipscBurstSlot1
@@ -949,6 +1856,75 @@ func TestBuildMMDVMVoiceBurstPrivateCall(t *testing.T) {
 	}
 }
 
+func TestTranslateToMMDVMLateEntry(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		packetType byte
+		groupCall  bool
+		flco       byte
+	}{
+		{"group", 0x80, true, byte(enums.FLCOGroupVoiceChannelUser)},
+		{"private", 0x81, false, byte(enums.FLCOUnitToUnitVoiceChannelUser)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tr := newTestTranslator(t)
+
+			// The first packet of the call is a voice burst.
+			burstData := make([]byte, 52)
+			copy(burstData[:30], makeTestIPSCPacket(tt.packetType, ipscBurstSlot1, tt.groupCall, false)[:30])
+			burstData[30] = ipscBurstSlot1
+			burstData[31] = 0x14
+			burstData[32] = 0x40
+
+			result := tr.TranslateToMMDVM(tt.packetType, burstData)
+			if len(result) != 2 {
+				t.Fatalf("expected a header and a voice burst, got %d packets", len(result))
+			}
+			header, voice := result[0], result[1]
+			if header.FrameType != mmdvmFrameTypeDataSync || header.DTypeOrVSeq != uint(elements.DataTypeVoiceLCHeader) {
+				t.Fatalf("expected a voice LC header first, got frame type %d data type %d", header.FrameType, header.DTypeOrVSeq)
+			}
+			if voice.FrameType != mmdvmFrameTypeVoiceSync || voice.DTypeOrVSeq != 0 {
+				t.Fatalf("expected voice to resume at burst A, got frame type %d vseq %d", voice.FrameType, voice.DTypeOrVSeq)
+			}
+			if header.StreamID != voice.StreamID {
+				t.Fatalf("expected one stream, got %d and %d", header.StreamID, voice.StreamID)
+			}
+			if header.Seq != 0 || voice.Seq != 1 {
+				t.Fatalf("expected sequence numbers 0 and 1, got %d and %d", header.Seq, voice.Seq)
+			}
+			lc := bptc.Extract(header.DMRData)
+			if lc[0] != tt.flco {
+				t.Fatalf("expected FLCO 0x%02X, got 0x%02X", tt.flco, lc[0])
+			}
+			if dst := uint(lc[3])<<16 | uint(lc[4])<<8 | uint(lc[5]); dst != 200 {
+				t.Fatalf("expected dst 200 in the LC, got %d", dst)
+			}
+			if src := uint(lc[6])<<16 | uint(lc[7])<<8 | uint(lc[8]); src != 100 {
+				t.Fatalf("expected src 100 in the LC, got %d", src)
+			}
+
+			// A header arriving late, sent before the burst, is not sent
+			// again.
+			seq := binary.BigEndian.Uint16(burstData[20:22])
+			late := makeTestIPSCPacket(tt.packetType, ipscBurstVoiceHead, tt.groupCall, false)
+			binary.BigEndian.PutUint16(late[20:22], seq-1)
+			if result := tr.TranslateToMMDVM(tt.packetType, late); len(result) != 0 {
+				t.Fatalf("expected no second header, got %d packets", len(result))
+			}
+			burstData = append(burstData, 0, 0, 0) // burst B is longer
+			binary.BigEndian.PutUint16(burstData[20:22], seq+1)
+			result = tr.TranslateToMMDVM(tt.packetType, burstData)
+			if len(result) != 1 || result[0].DTypeOrVSeq != 1 {
+				t.Fatalf("expected the call to continue at burst B, got %d packets", len(result))
+			}
+		})
+	}
+}
+
 func TestPopulateEmbeddedSignallingBurstB(t *testing.T) {
 	t.Parallel()
 	tr := newTestTranslator(t)
@@ -1015,8 +1991,8 @@ func TestPopulateEmbeddedSignallingContinuation(t *testing.T) {
 	var burst layer2.Burst
 	burst.HasEmbeddedSignalling = true
 
-	// Bursts C, D, F should all get ContinuationFragmentLCorCSBK
-	for _, idx := range []int{2, 3, 5} {
+	// Bursts C and D should get ContinuationFragmentLCorCSBK
+	for _, idx := range []int{2, 3} {
 		ipscData := make([]byte, 57)
 		tr.populateEmbeddedSignalling(&burst, idx, ipscData)
 		if burst.EmbeddedSignalling.LCSS != enums.ContinuationFragmentLCorCSBK {
@@ -1026,6 +2002,123 @@ func TestPopulateEmbeddedSignallingContinuation(t *testing.T) {
 	}
 }
 
+func TestEmbeddedSignallingToMMDVM(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	tr.SetColorCode(7)
+
+	header := tr.TranslateToMMDVM(0x80, makeSequencedIPSCPacket(ipscBurstVoiceHead, 1))
+	if len(header) != 1 {
+		t.Fatalf("expected 1 header packet, got %d", len(header))
+	}
+	if got := layer2.NewBurstFromBytes(header[0].DMRData).SlotType.ColorCode; got != 7 {
+		t.Fatalf("expected color code 7 in the header's slot type, got %d", got)
+	}
+
+	want := []enums.LCSS{
+		1: enums.FirstFragmentLC,
+		2: enums.ContinuationFragmentLCorCSBK,
+		3: enums.ContinuationFragmentLCorCSBK,
+		4: enums.LastFragmentLCorCSBK,
+		5: enums.SingleFragmentLCorCSBK,
+	}
+	for i := range 6 {
+		result := tr.TranslateToMMDVM(0x80, makeIPSCVoiceBurst(i, uint16(i+2))) //nolint:gosec // G115: i is in [0,5]
+		if len(result) != 1 {
+			t.Fatalf("burst %d: expected 1 packet, got %d", i, len(result))
+		}
+		if i == 0 {
+			continue
+		}
+		emb := layer2.NewBurstFromBytes(result[0].DMRData).EmbeddedSignalling
+		if !emb.ParityOK {
+			t.Fatalf("burst %d: expected a valid QR(16,7) code, got %+v", i, emb)
+		}
+		if emb.ColorCode != 7 || emb.PreemptionAndPowerControlIndicator || emb.LCSS != want[i] {
+			t.Fatalf("burst %d: expected color code 7, no PI, and LCSS %d, got %+v", i, want[i], emb)
+		}
+	}
+}
+
+// ipscVoiceBurstLen returns the length of an IPSC voice burst at
+// superframe position pos.
+func ipscVoiceBurstLen(pos int) int {
+	switch pos {
+	case 0:
+		return 52
+	case 4:
+		return 66
+	default:
+		return 57
+	}
+}
+
+// makeIPSCVoiceBurst returns the voice burst at superframe position pos
+// of the call made by makeSequencedIPSCPacket.
+func makeIPSCVoiceBurst(pos int, seq uint16) []byte {
+	data := make([]byte, ipscVoiceBurstLen(pos))
+	copy(data, makeSequencedIPSCPacket(ipscBurstSlot1, seq))
+	return data
+}
+
+func TestVoiceSequenceToMMDVM(t *testing.T) {
+	t.Parallel()
+	type frame struct {
+		frameType uint
+		vseq      uint
+	}
+	sync := func(vseq uint) frame { return frame{mmdvmFrameTypeVoiceSync, vseq} }
+	voice := func(vseq uint) frame { return frame{mmdvmFrameTypeVoice, vseq} }
+	tests := []struct {
+		name      string
+		positions []int // of the bursts sent, in order
+		want      []frame
+	}{
+		{"superframe", []int{0, 1, 2, 3, 4, 5, 0}, []frame{sync(0), voice(1), voice(2), voice(3), voice(4), voice(5), sync(0)}},
+		{"lost A", []int{1, 2}, []frame{voice(1), voice(2)}},
+		{"lost E", []int{0, 1, 2, 3, 5, 0}, []frame{sync(0), voice(1), voice(2), voice(3), voice(5), sync(0)}},
+		{"lost C", []int{0, 1, 3, 4, 5}, []frame{sync(0), voice(1), voice(2), voice(4), voice(5)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tr := newTestTranslator(t)
+			tr.TranslateToMMDVM(0x80, makeSequencedIPSCPacket(ipscBurstVoiceHead, 1))
+			for i, pos := range tt.positions {
+				result := tr.TranslateToMMDVM(0x80, makeIPSCVoiceBurst(pos, uint16(i+2))) //nolint:gosec // G115: a handful of bursts
+				if len(result) != 1 {
+					t.Fatalf("burst %d: expected 1 packet, got %d", i, len(result))
+				}
+				got := frame{result[0].FrameType, result[0].DTypeOrVSeq}
+				if got != tt.want[i] {
+					t.Fatalf("burst %d: expected frame type %d and sequence %d, got %d and %d",
+						i, tt.want[i].frameType, tt.want[i].vseq, got.frameType, got.vseq)
+				}
+				if result[0].Seq != uint(i+1) {
+					t.Fatalf("burst %d: expected DMRD sequence %d after the header, got %d", i, i+1, result[0].Seq)
+				}
+			}
+		})
+	}
+}
+
+func TestVoiceSequenceWrapsDMRDSequence(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	tr.TranslateToMMDVM(0x80, makeSequencedIPSCPacket(ipscBurstVoiceHead, 1))
+	var last uint
+	for i := range 256 {
+		result := tr.TranslateToMMDVM(0x80, makeIPSCVoiceBurst(i%6, uint16(i+2))) //nolint:gosec // G115: i < 256
+		if len(result) != 1 {
+			t.Fatalf("burst %d: expected 1 packet, got %d", i, len(result))
+		}
+		last = result[0].Seq
+	}
+	if last != 0 {
+		t.Fatalf("expected the DMRD sequence to wrap to 0, got %d", last)
+	}
+}
+
 func TestPopulateEmbeddedSignallingNoEmbeddedData(t *testing.T) {
 	t.Parallel()
 	tr := newTestTranslator(t)
@@ -1050,3 +2143,689 @@ func TestPopulateEmbeddedSignallingNoEmbeddedData(t *testing.T) {
 		}
 	}
 }
+
+// makeDataBlockIPSCPacket returns an IPSC private data packet carrying
+// payload as a block of dataType.
+func makeDataBlockIPSCPacket(dataType elements.DataType, payload []byte) []byte {
+	data := makeTestIPSCPacket(byte(PacketType_PrivateData), byte(dataType), false, false)
+	data = append(data[:38], make([]byte, len(payload)+4)...)
+	copy(data[38:], payload)
+	return data
+}
+
+func TestDataCallRoundTrip(t *testing.T) {
+	t.Parallel()
+	// An SMS: a data header and its blocks, one of each rate.
+	blocks := []struct {
+		dataType elements.DataType
+		payload  []byte
+	}{
+		{elements.DataTypeDataHeader, []byte{0x02, 0xC5, 0x00, 0x00, 0xC8, 0x00, 0x00, 0x64, 0x83, 0x00, 0x1A, 0x2B}},
+		{elements.DataTypeRate12, []byte("Hello from I")},
+		{elements.DataTypeRate34, []byte("PSC, over HBRP to ")},
+		{elements.DataTypeRate1, []byte("the master and back.\x00\x00\x00\x00")},
+	}
+
+	from, to, back := newTestTranslator(t), newTestTranslator(t), newTestTranslator(t)
+	var toMaster []mmdvm.Packet
+	for _, block := range blocks {
+		result := from.TranslateToMMDVM(byte(PacketType_PrivateData), makeDataBlockIPSCPacket(block.dataType, block.payload))
+		if len(result) != 1 {
+			t.Fatalf("%v: expected 1 packet toward the master, got %d", block.dataType, len(result))
+		}
+		pkt := result[0]
+		if pkt.FrameType != mmdvmFrameTypeDataSync || pkt.DTypeOrVSeq != uint(block.dataType) {
+			t.Fatalf("%v: expected data sync with data type %d, got frame type %d data type %d",
+				block.dataType, block.dataType, pkt.FrameType, pkt.DTypeOrVSeq)
+		}
+		if pkt.GroupCall {
+			t.Fatalf("%v: expected a private call", block.dataType)
+		}
+		if len(toMaster) > 0 && pkt.StreamID != toMaster[0].StreamID {
+			t.Fatalf("%v: expected one stream for the call", block.dataType)
+		}
+		toMaster = append(toMaster, pkt)
+	}
+
+	for i, pkt := range toMaster {
+		result := to.TranslateToIPSC(pkt)
+		if len(result) != 1 {
+			t.Fatalf("%v: expected 1 packet toward IPSC, got %d", blocks[i].dataType, len(result))
+		}
+		data := result[0]
+		if data[0] != byte(PacketType_PrivateData) || data[30] != byte(blocks[i].dataType) {
+			t.Fatalf("%v: expected private data with burst type %d, got 0x%02X and %d",
+				blocks[i].dataType, blocks[i].dataType, data[0], data[30])
+		}
+		if got := data[38 : 38+len(blocks[i].payload)]; string(got) != string(blocks[i].payload) {
+			t.Fatalf("%v: expected payload % X, got % X", blocks[i].dataType, blocks[i].payload, got)
+		}
+		if bits := binary.BigEndian.Uint16(data[36:38]); int(bits) != 8*len(blocks[i].payload) {
+			t.Fatalf("%v: expected a data size of %d bits, got %d", blocks[i].dataType, 8*len(blocks[i].payload), bits)
+		}
+
+		again := back.TranslateToMMDVM(data[0], data)
+		if len(again) != 1 || again[0].DMRData != pkt.DMRData {
+			t.Fatalf("%v: expected the burst to survive the round trip", blocks[i].dataType)
+		}
+	}
+}
+
+func TestDataBlockToIPSCUndecodable(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	pkt := makeTestMMDVMPacket(false, false, mmdvmFrameTypeDataSync, uint(elements.DataTypeRate34))
+	datablock.InsertRate34(&pkt.DMRData, [datablock.Rate34Size]byte{0x12, 0x34, 0x56})
+	// Flip the bits of one dibit so it sends a different symbol.
+	pkt.DMRData[4] ^= 0xC0
+	if result := tr.TranslateToIPSC(pkt); result != nil {
+		t.Fatalf("expected an undecodable rate 3/4 block to be dropped, got %d packets", len(result))
+	}
+}
+
+func TestDataBlockToMMDVMTooShort(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	data := makeDataBlockIPSCPacket(elements.DataTypeRate1, make([]byte, 12))
+	if result := tr.TranslateToMMDVM(data[0], data); result != nil {
+		t.Fatalf("expected a short rate 1 block to be dropped, got %d packets", len(result))
+	}
+}
+
+// makeLCHeaderMMDVMPacket returns a voice LC header toward IPSC whose
+// burst carries lc.
+func makeLCHeaderMMDVMPacket(lc [12]byte) mmdvm.Packet {
+	pkt := makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, uint(elements.DataTypeVoiceLCHeader))
+	pkt.DMRData = layer2.BuildLCDataBurst(lc, elements.DataTypeVoiceLCHeader, 0)
+	bptc.Insert(&pkt.DMRData, lc)
+	return pkt
+}
+
+func TestVoiceHeaderKeepsLinkControl(t *testing.T) {
+	t.Parallel()
+	// An emergency group call from 100 to 200.
+	lc := crc.AppendRS129([9]byte{0x00, 0x00, 0x80, 0x00, 0x00, 0xC8, 0x00, 0x00, 0x64}, crc.MaskVoiceHeader)
+	tests := []struct {
+		name string
+		flip int
+	}{
+		{"clean", -1},
+		{"single bit error", 13},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pkt := makeLCHeaderMMDVMPacket(lc)
+			if tt.flip >= 0 {
+				pkt.DMRData[tt.flip/8] ^= 0x80 >> (tt.flip % 8)
+			}
+			result := newTestTranslator(t).TranslateToIPSC(pkt)
+			if len(result) != 3 {
+				t.Fatalf("expected 3 header packets, got %d", len(result))
+			}
+			if got := result[0][38:50]; string(got) != string(lc[:]) {
+				t.Fatalf("expected the link control % X, got % X", lc, got)
+			}
+		})
+	}
+}
+
+func TestVoiceHeaderRebuildsRewrittenLinkControl(t *testing.T) {
+	t.Parallel()
+	lc := crc.AppendRS129([9]byte{0x00, 0x00, 0x80, 0x00, 0x00, 0xC8, 0x00, 0x00, 0x64}, crc.MaskVoiceHeader)
+	pkt := makeLCHeaderMMDVMPacket(lc)
+	// A rewrite rule moved the call to talkgroup 300.
+	pkt.Dst = 300
+	result := newTestTranslator(t).TranslateToIPSC(pkt)
+	if len(result) != 3 {
+		t.Fatalf("expected 3 header packets, got %d", len(result))
+	}
+	// The rebuilt link control keeps the emergency service option.
+	want := extractFullLCBytes(pkt, elements.DataTypeVoiceLCHeader)
+	want[2] = 0x80
+	want = withRS129(want, elements.DataTypeVoiceLCHeader)
+	if got := result[0][38:50]; string(got) != string(want[:]) {
+		t.Fatalf("expected the link control % X built from the packet, got % X", want, got)
+	}
+}
+
+func TestServiceOptionsRoundTrip(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		options byte
+		dst     uint
+	}{
+		{"normal", 0x20, 200},
+		{"emergency", 0xA0, 200},
+		{"priority 3", 0x23, 200},
+		{"normal rewritten", 0x20, 300},
+		{"emergency rewritten", 0xA0, 300},
+		{"emergency priority rewritten", 0xA2, 300},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			lc := crc.AppendRS129([9]byte{0x00, 0x00, tt.options, 0x00, 0x00, 0xC8, 0x00, 0x00, 0x64}, crc.MaskVoiceHeader)
+			pkt := makeLCHeaderMMDVMPacket(lc)
+			pkt.Dst = tt.dst
+			toIPSC := newTestTranslator(t).TranslateToIPSC(pkt)
+			if len(toIPSC) != 3 {
+				t.Fatalf("expected 3 header packets, got %d", len(toIPSC))
+			}
+			if got := toIPSC[0][40]; got != tt.options {
+				t.Fatalf("expected service options 0x%02X toward IPSC, got 0x%02X", tt.options, got)
+			}
+
+			toMMDVM := newTestTranslator(t).TranslateToMMDVM(toIPSC[0][0], toIPSC[0])
+			if len(toMMDVM) != 1 {
+				t.Fatalf("expected 1 header packet, got %d", len(toMMDVM))
+			}
+			back, ok := bptc.Decode(toMMDVM[0].DMRData)
+			if !ok || !crc.CheckRS129(back, crc.MaskVoiceHeader) {
+				t.Fatalf("expected a valid link control, got % X", back)
+			}
+			if back[2] != tt.options {
+				t.Fatalf("expected service options 0x%02X toward the master, got 0x%02X", tt.options, back[2])
+			}
+		})
+	}
+}
+
+func TestVoiceHeaderToMMDVMIsBPTCCoded(t *testing.T) {
+	t.Parallel()
+	data := makeTestIPSCPacket(0x80, ipscBurstVoiceHead, true, false)
+	flc := crc.AppendRS129([9]byte{0x00, 0x00, 0x20, 0x00, 0x00, 0xC8, 0x00, 0x00, 0x64}, crc.MaskVoiceHeader)
+	copy(data[38:50], flc[:])
+	result := newTestTranslator(t).TranslateToMMDVM(0x80, data)
+	if len(result) != 1 {
+		t.Fatalf("expected 1 header packet, got %d", len(result))
+	}
+	lc, ok := bptc.Decode(result[0].DMRData)
+	if !ok {
+		t.Fatal("expected the header burst to carry valid BPTC parity")
+	}
+	if lc != flc {
+		t.Fatalf("expected the link control % X, got % X", flc, lc)
+	}
+}
+
+func TestFullLCParityMasks(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		burstType byte
+		dataType  elements.DataType
+		mask      uint32
+		wrongMask uint32
+	}{
+		{"header", ipscBurstVoiceHead, elements.DataTypeVoiceLCHeader, crc.MaskVoiceHeader, crc.MaskTerminator},
+		{"terminator", ipscBurstVoiceTerm, elements.DataTypeTerminatorWithLC, crc.MaskTerminator, crc.MaskVoiceHeader},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			// Toward IPSC, from a burst whose link control is rebuilt.
+			pkt := makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, uint(tt.dataType))
+			out := newTestTranslator(t).TranslateToIPSC(pkt)
+			var flc [12]byte
+			copy(flc[:], out[len(out)-1][38:50])
+			if !crc.CheckRS129(flc, tt.mask) || crc.CheckRS129(flc, tt.wrongMask) {
+				t.Fatalf("expected % X to carry parity under mask 0x%06X only", flc, tt.mask)
+			}
+
+			// Toward the master, from an IPSC packet whose parity is stale.
+			data := makeTestIPSCPacket(0x80, tt.burstType, true, false)
+			copy(data[38:47], flc[:9])
+			result := newTestTranslator(t).TranslateToMMDVM(0x80, data)
+			if len(result) != 1 {
+				t.Fatalf("expected 1 packet, got %d", len(result))
+			}
+			lc, ok := bptc.Decode(result[0].DMRData)
+			if !ok || lc != flc {
+				t.Fatalf("expected the link control % X, got % X", flc, lc)
+			}
+		})
+	}
+}
+
+func TestAllCallToIPSC(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		dst       uint
+		groupCall bool
+		wantType  byte
+		wantCall  byte
+		wantFLCO  enums.FLCO
+	}{
+		{"talkgroup", 0xFFFFFE, true, 0x80, ipscCallTypeGroup, enums.FLCOGroupVoiceChannelUser},
+		{"private", 0xFFFFFE, false, 0x81, ipscCallTypePrivate, enums.FLCOUnitToUnitVoiceChannelUser},
+		{"all call", allCallID, true, 0x80, ipscCallTypeAllCall, enums.FLCOGroupVoiceChannelUser},
+		{"all call sent as private", allCallID, false, 0x80, ipscCallTypeAllCall, enums.FLCOGroupVoiceChannelUser},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pkt := makeTestMMDVMPacket(tt.groupCall, false, mmdvmFrameTypeDataSync, uint(elements.DataTypeVoiceLCHeader))
+			pkt.Dst = tt.dst
+			result := newTestTranslator(t).TranslateToIPSC(pkt)
+			if len(result) != 3 {
+				t.Fatalf("expected 3 header packets, got %d", len(result))
+			}
+			header := result[0]
+			if header[0] != tt.wantType {
+				t.Fatalf("expected packet type 0x%02X, got 0x%02X", tt.wantType, header[0])
+			}
+			if header[12] != tt.wantCall {
+				t.Fatalf("expected call type 0x%02X, got 0x%02X", tt.wantCall, header[12])
+			}
+			if flco := enums.FLCO(header[38] & 0x3F); flco != tt.wantFLCO {
+				t.Fatalf("expected FLCO %s, got %s", enums.FLCOToName(tt.wantFLCO), enums.FLCOToName(flco))
+			}
+			if dst := uint(header[9])<<16 | uint(header[10])<<8 | uint(header[11]); dst != tt.dst {
+				t.Fatalf("expected destination %d, got %d", tt.dst, dst)
+			}
+		})
+	}
+}
+
+func TestAllCallToMMDVM(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		packetType byte
+		dst        uint
+		callType   byte
+		wantGroup  bool
+		wantDst    uint
+	}{
+		{"talkgroup", 0x80, 0xFFFFFE, ipscCallTypeGroup, true, 0xFFFFFE},
+		{"private", 0x81, 0xFFFFFE, ipscCallTypePrivate, false, 0xFFFFFE},
+		{"all call", 0x80, allCallID, ipscCallTypeAllCall, true, allCallID},
+		{"all call sent as private", 0x81, allCallID, ipscCallTypePrivate, true, allCallID},
+		{"all call type", 0x81, 200, ipscCallTypeAllCall, true, allCallID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := makeTestIPSCPacket(tt.packetType, ipscBurstVoiceHead, tt.packetType == 0x80, false)
+			data[9] = byte(tt.dst >> 16)
+			data[10] = byte(tt.dst >> 8)
+			data[11] = byte(tt.dst)
+			data[12] = tt.callType
+			result := newTestTranslator(t).TranslateToMMDVM(tt.packetType, data)
+			if len(result) != 1 {
+				t.Fatalf("expected 1 header packet, got %d", len(result))
+			}
+			pkt := result[0]
+			if pkt.GroupCall != tt.wantGroup {
+				t.Fatalf("expected GroupCall %t, got %t", tt.wantGroup, pkt.GroupCall)
+			}
+			if pkt.Dst != tt.wantDst {
+				t.Fatalf("expected destination %d, got %d", tt.wantDst, pkt.Dst)
+			}
+			lc, ok := bptc.Decode(pkt.DMRData)
+			if !ok {
+				t.Fatal("expected a decodable link control")
+			}
+			wantFLCO := enums.FLCOUnitToUnitVoiceChannelUser
+			if tt.wantGroup {
+				wantFLCO = enums.FLCOGroupVoiceChannelUser
+			}
+			if flco := enums.FLCO(lc[0] & 0x3F); flco != wantFLCO {
+				t.Fatalf("expected FLCO %s, got %s", enums.FLCOToName(wantFLCO), enums.FLCOToName(flco))
+			}
+		})
+	}
+}
+
+func TestInvalidIDsToIPSC(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		src, dst uint
+		dropped  bool
+	}{
+		{"valid", 100, 200, false},
+		{"largest ID", maxID, maxID, false},
+		{"zero source", 0, 200, true},
+		{"zero destination", 100, 0, true},
+		{"source past 24 bits", maxID + 1, 200, true},
+		{"destination past 24 bits", 100, maxID + 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pkt := makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, uint(elements.DataTypeVoiceLCHeader))
+			pkt.Src, pkt.Dst = tt.src, tt.dst
+			result := newTestTranslator(t).TranslateToIPSC(pkt)
+			if tt.dropped {
+				if result != nil {
+					t.Fatalf("expected the packet to be dropped, got %d packets", len(result))
+				}
+				return
+			}
+			if len(result) != 3 {
+				t.Fatalf("expected 3 header packets, got %d", len(result))
+			}
+			src := uint(result[0][6])<<16 | uint(result[0][7])<<8 | uint(result[0][8])
+			dst := uint(result[0][9])<<16 | uint(result[0][10])<<8 | uint(result[0][11])
+			if src != tt.src || dst != tt.dst {
+				t.Fatalf("expected %d to %d, got %d to %d", tt.src, tt.dst, src, dst)
+			}
+		})
+	}
+}
+
+func TestInvalidIDsToMMDVM(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		src, dst uint
+		dropped  bool
+	}{
+		{"valid", 100, 200, false},
+		{"largest ID", maxID, maxID, false},
+		{"zero source", 0, 200, true},
+		{"zero destination", 100, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := makeTestIPSCPacket(0x80, ipscBurstVoiceHead, true, false)
+			data[6], data[7], data[8] = byte(tt.src>>16), byte(tt.src>>8), byte(tt.src)
+			data[9], data[10], data[11] = byte(tt.dst>>16), byte(tt.dst>>8), byte(tt.dst)
+			tr := newTestTranslator(t)
+			result := tr.TranslateToMMDVM(0x80, data)
+			if tt.dropped {
+				if result != nil {
+					t.Fatalf("expected the packet to be dropped, got %d packets", len(result))
+				}
+				if streams := tr.ReverseStreams(); len(streams) != 0 {
+					t.Fatalf("expected no stream to start, got %d", len(streams))
+				}
+				return
+			}
+			if len(result) != 1 {
+				t.Fatalf("expected 1 header packet, got %d", len(result))
+			}
+			if result[0].Src != tt.src || result[0].Dst != tt.dst {
+				t.Fatalf("expected %d to %d, got %d to %d", tt.src, tt.dst, result[0].Src, result[0].Dst)
+			}
+		})
+	}
+}
+
+// csbkBlock returns a CSBK with opcode addressed to 200 from 100.
+func csbkBlock(opcode byte) csbk.Block {
+	var block csbk.Block
+	content := []byte{0x80 | opcode, 0x00, 0x00, 0x00, 0x00, 0x00, 0xC8, 0x00, 0x00, 0x64}
+	copy(block[:], crc.AppendCCITT16(content, crc.MaskCSBK))
+	return block
+}
+
+// makeCSBKMMDVMPacket returns a group CSBK from the master carrying block.
+func makeCSBKMMDVMPacket(block csbk.Block) mmdvm.Packet {
+	pkt := makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, uint(elements.DataTypeCSBK))
+	pkt.DMRData = layer2.BuildLCDataBurst(block, elements.DataTypeCSBK, 0)
+	bptc.Insert(&pkt.DMRData, block)
+	return pkt
+}
+
+func TestCSBKRoundTrip(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		opcode byte
+	}{
+		{"bs outbound activation", csbk.OpcodeBSOutboundActivation},
+		{"call alert", csbk.OpcodeCallAlert},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			block := csbkBlock(tt.opcode)
+			toIPSC := newTestTranslator(t).TranslateToIPSC(makeCSBKMMDVMPacket(block))
+			if len(toIPSC) != 1 {
+				t.Fatalf("expected 1 IPSC packet, got %d", len(toIPSC))
+			}
+			data := toIPSC[0]
+			if data[30] != ipscBurstCSBK {
+				t.Fatalf("expected burst type 0x%02X, got 0x%02X", ipscBurstCSBK, data[30])
+			}
+			if got := data[38:50]; string(got) != string(block[:]) {
+				t.Fatalf("expected the CSBK % X, got % X", block, got)
+			}
+
+			toMaster := newTestTranslator(t).TranslateToMMDVM(data[0], data)
+			if len(toMaster) != 1 {
+				t.Fatalf("expected 1 packet toward the master, got %d", len(toMaster))
+			}
+			got, ok := bptc.Decode(toMaster[0].DMRData)
+			if !ok || csbk.Block(got) != block {
+				t.Fatalf("expected the CSBK % X back, got % X", block, got)
+			}
+			if op := csbk.Opcode(csbk.Block(got)); op != tt.opcode {
+				t.Fatalf("expected opcode 0x%02X, got 0x%02X", tt.opcode, op)
+			}
+		})
+	}
+}
+
+func TestDataBurstsToMMDVMAreOnAirBursts(t *testing.T) {
+	t.Parallel()
+	block := csbkBlock(csbk.OpcodeCallAlert)
+	lc := extractFullLCBytes(makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 1), elements.DataTypeVoiceLCHeader)
+	tests := []struct {
+		name      string
+		burstType byte
+		payload   []byte // bytes 38 on, or nil to send the packet cut short
+		dataType  elements.DataType
+		addrAt    int // offset of the destination, followed by the source
+	}{
+		{"voice header", ipscBurstVoiceHead, lc[:], elements.DataTypeVoiceLCHeader, 3},
+		{"voice header without link control", ipscBurstVoiceHead, nil, elements.DataTypeVoiceLCHeader, 3},
+		{"terminator", ipscBurstVoiceTerm, nil, elements.DataTypeTerminatorWithLC, 3},
+		{"csbk", ipscBurstCSBK, block[:], elements.DataTypeCSBK, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := makeTestIPSCPacket(0x80, tt.burstType, true, true)
+			if tt.payload == nil {
+				data = data[:38]
+			} else {
+				copy(data[38:50], tt.payload)
+			}
+			result := newTestTranslator(t).TranslateToMMDVM(0x80, data)
+			if len(result) != 1 {
+				t.Fatalf("expected 1 packet, got %d", len(result))
+			}
+			burst := layer2.NewBurstFromBytes(result[0].DMRData)
+			if burst.SyncPattern != enums.BsSourcedData {
+				t.Fatalf("expected a data sync pattern, got %v", burst.SyncPattern)
+			}
+			if !burst.SlotType.ParityOK || burst.SlotType.DataType != tt.dataType {
+				t.Fatalf("expected data type %v, got %v (parity ok %t)", tt.dataType, burst.SlotType.DataType, burst.SlotType.ParityOK)
+			}
+			info, ok := bptc.Decode(result[0].DMRData)
+			if !ok {
+				t.Fatal("expected the burst to carry valid BPTC parity")
+			}
+			dst := uint(info[tt.addrAt])<<16 | uint(info[tt.addrAt+1])<<8 | uint(info[tt.addrAt+2])
+			src := uint(info[tt.addrAt+3])<<16 | uint(info[tt.addrAt+4])<<8 | uint(info[tt.addrAt+5])
+			if src != 100 || dst != 200 {
+				t.Fatalf("expected 100 to 200, got %d to %d", src, dst)
+			}
+		})
+	}
+}
+
+func TestCSBKToIPSCDropsBadCRC(t *testing.T) {
+	t.Parallel()
+	block := csbkBlock(csbk.OpcodeCallAlert)
+	block[11] ^= 0x01
+	if result := newTestTranslator(t).TranslateToIPSC(makeCSBKMMDVMPacket(block)); result != nil {
+		t.Fatalf("expected a CSBK failing its CRC to be dropped, got %d packets", len(result))
+	}
+}
+
+func TestSinglePacketCallToMMDVM(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		data      func() []byte
+		wantTypes []uint // the DTypeOrVSeq of each packet sent
+	}{
+		{"csbk", func() []byte { return makeTestIPSCPacket(0x83, ipscBurstCSBK, true, false) }, []uint{3}},
+		{"voice", func() []byte { return makeIPSCVoiceBurst(0, 1) }, []uint{1, 0, 2}},
+		{"too short", func() []byte { return makeSequencedIPSCPacket(ipscBurstSlot1, 1)[:40] }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tr := newTestTranslator(t)
+			data := tt.data()
+			data[17] |= 0x40 // end flag
+
+			result := tr.TranslateToMMDVM(data[0], data)
+			if len(result) != len(tt.wantTypes) {
+				t.Fatalf("expected %d packets, got %d", len(tt.wantTypes), len(result))
+			}
+			for i, pkt := range result {
+				if pkt.DTypeOrVSeq != tt.wantTypes[i] {
+					t.Fatalf("packet %d: expected type %d, got %d", i, tt.wantTypes[i], pkt.DTypeOrVSeq)
+				}
+			}
+			if n := len(tr.reverseStreams); n != 0 {
+				t.Fatalf("expected no streams left, got %d", n)
+			}
+		})
+	}
+}
+
+func TestSinglePacketCallToIPSC(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+
+	result := tr.TranslateToIPSC(makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, 2))
+	if len(result) != defaultHeaderRepeats+1 {
+		t.Fatalf("expected %d packets, got %d", defaultHeaderRepeats+1, len(result))
+	}
+	callControl := binary.BigEndian.Uint32(result[0][13:17])
+	for i, data := range result {
+		want := byte(ipscBurstVoiceHead)
+		if i == len(result)-1 {
+			want = ipscBurstVoiceTerm
+		}
+		if data[30] != want {
+			t.Fatalf("packet %d: expected burst type 0x%02X, got 0x%02X", i, want, data[30])
+		}
+		if got := binary.BigEndian.Uint32(data[13:17]); got != callControl {
+			t.Fatalf("packet %d: expected call control 0x%08X, got 0x%08X", i, callControl, got)
+		}
+		if marker := data[19]&0x80 != 0; marker != (i == 0) {
+			t.Fatalf("packet %d: expected marker %v, got %v", i, i == 0, marker)
+		}
+	}
+	if result[len(result)-1][17]&0x40 == 0 {
+		t.Fatal("expected the end flag on the terminator")
+	}
+	if n := len(tr.streams); n != 0 {
+		t.Fatalf("expected no streams left, got %d", n)
+	}
+}
+
+// makePIHeaderMMDVMPacket returns a privacy indicator header from the
+// master carrying block.
+func makePIHeaderMMDVMPacket(block [12]byte) mmdvm.Packet {
+	pkt := makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, uint(elements.DataTypePIHeader))
+	pkt.DMRData = layer2.BuildLCDataBurst(block, elements.DataTypePIHeader, 0)
+	bptc.Insert(&pkt.DMRData, block)
+	return pkt
+}
+
+func TestPrivacyRoundTrip(t *testing.T) {
+	t.Parallel()
+	tx := newTestTranslator(t)
+	rx := newTestTranslator(t)
+
+	// A group call from 100 to 200 under basic privacy, key 1.
+	lc := [9]byte{0x00, 0x00, 0x60, 0x00, 0x00, 0xC8, 0x00, 0x00, 0x64}
+	var pi [12]byte
+	copy(pi[:], crc.AppendCCITT16([]byte{0x01 << 5, 0x00, 0x01, 0xDE, 0xAD, 0xBE, 0xEF, 0x00, 0x00, 0xC8}, crc.MaskPIHeader))
+
+	master := []mmdvm.Packet{makeLCHeaderMMDVMPacket(crc.AppendRS129(lc, crc.MaskVoiceHeader)), makePIHeaderMMDVMPacket(pi)}
+	for pos := range 6 {
+		pkt := makeTestMMDVMPacket(true, false, mmdvmFrameTypeVoice, uint(pos)) //nolint:gosec // G115: pos is in [0,5]
+		if pos == 0 {
+			pkt.FrameType = mmdvmFrameTypeVoiceSync
+		}
+		pkt.DMRData = makeVoiceDMRData(pos == 0)
+		master = append(master, pkt)
+	}
+	terminator := makeTestMMDVMPacket(true, false, mmdvmFrameTypeDataSync, uint(elements.DataTypeTerminatorWithLC))
+	terminatorLC := crc.AppendRS129(lc, crc.MaskTerminator)
+	terminator.DMRData = layer2.BuildLCDataBurst(terminatorLC, elements.DataTypeTerminatorWithLC, 0)
+	bptc.Insert(&terminator.DMRData, terminatorLC)
+	master = append(master, terminator)
+
+	var toMMDVM []mmdvm.Packet
+	for _, pkt := range master {
+		for _, data := range tx.TranslateToIPSC(pkt) {
+			if data[30] == byte(elements.DataTypePIHeader) && data[0] != 0x80 {
+				t.Fatalf("expected the PI header sent as a group voice packet, got type 0x%02X", data[0])
+			}
+			toMMDVM = append(toMMDVM, rx.TranslateToMMDVM(data[0], data)...)
+		}
+	}
+
+	var sawPI bool
+	for _, pkt := range toMMDVM {
+		if pkt.FrameType != mmdvmFrameTypeDataSync {
+			continue
+		}
+		block, ok := bptc.Decode(pkt.DMRData)
+		if !ok {
+			t.Fatalf("data type %d: undecodable burst", pkt.DTypeOrVSeq)
+		}
+		switch elements.DataType(pkt.DTypeOrVSeq) {
+		case elements.DataTypePIHeader:
+			sawPI = true
+			if block != pi {
+				t.Fatalf("expected the PI header % X, got % X", pi, block)
+			}
+		case elements.DataTypeVoiceLCHeader, elements.DataTypeTerminatorWithLC:
+			if block[2]&0x40 == 0 {
+				t.Fatalf("data type %d: expected the privacy service option in % X", pkt.DTypeOrVSeq, block)
+			}
+		}
+	}
+	if !sawPI {
+		t.Fatal("expected a PI header toward the master")
+	}
+}
+
+func TestPrivacyLateEntryToMMDVM(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+
+	// The voice header was lost; the PI header says the call is private.
+	pi := makeSequencedIPSCPacket(ipscBurstPIHeader, 1)
+	result := tr.TranslateToMMDVM(0x80, pi)
+	if len(result) != 1 || result[0].DTypeOrVSeq != uint(elements.DataTypePIHeader) {
+		t.Fatalf("expected a PI header, got %+v", result)
+	}
+	result = tr.TranslateToMMDVM(0x80, makeIPSCVoiceBurst(0, 2))
+	if len(result) != 2 || result[0].DTypeOrVSeq != uint(elements.DataTypeVoiceLCHeader) {
+		t.Fatalf("expected a synthesized header and a voice burst, got %d packets", len(result))
+	}
+	lc, ok := bptc.Decode(result[0].DMRData)
+	if !ok || !crc.CheckRS129(lc, crc.MaskVoiceHeader) {
+		t.Fatalf("expected a valid link control, got % X", lc)
+	}
+	if lc[2]&0x40 == 0 {
+		t.Fatalf("expected the privacy service option in % X", lc)
+	}
+}
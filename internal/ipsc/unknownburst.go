@@ -0,0 +1,37 @@
+package ipsc
+
+import (
+	"maps"
+	"slices"
+)
+
+// SetUnknownBurstHandler sets a function to receive each IPSC packet
+// dropped for a burst type the translator does not know, so it can be
+// logged for study. The translator calls it with its lock held, so it
+// must not call back into the translator. A nil handler is none.
+func (t *IPSCTranslator) SetUnknownBurstHandler(handler func(data []byte)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.unknownBurstHandler = handler
+}
+
+// UnknownBurstTypes returns how many packets of each unknown burst type
+// have been dropped.
+func (t *IPSCTranslator) UnknownBurstTypes() map[byte]uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return maps.Clone(t.unknownBursts)
+}
+
+// noteUnknownBurst counts data, a packet of an unknown burst type, and
+// hands it to the unknown burst handler. Must be called with t.mu held.
+func (t *IPSCTranslator) noteUnknownBurst(data []byte) {
+	if t.unknownBursts == nil {
+		t.unknownBursts = make(map[byte]uint64)
+	}
+	t.unknownBursts[data[30]]++
+	t.sink.IncDropped("ipsc_to_mmdvm", "unknown_burst_type")
+	if t.unknownBurstHandler != nil {
+		t.unknownBurstHandler(slices.Clone(data))
+	}
+}
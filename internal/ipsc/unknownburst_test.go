@@ -0,0 +1,44 @@
+package ipsc
+
+import (
+	"bytes"
+	"maps"
+	"testing"
+)
+
+func TestUnknownBurstTypes(t *testing.T) {
+	t.Parallel()
+	tr := newTestTranslator(t)
+	sink := newRecordingSink()
+	tr.SetMetricsSink(sink)
+	var handled [][]byte
+	tr.SetUnknownBurstHandler(func(data []byte) {
+		handled = append(handled, data)
+	})
+
+	var sent [][]byte
+	for _, burstType := range []byte{0x42, 0x42, 0x13} {
+		data := makeTestIPSCPacket(0x80, burstType, true, false)
+		if result := tr.TranslateToMMDVM(0x80, data); result != nil {
+			t.Fatalf("expected burst type 0x%02X to be dropped, got %d packets", burstType, len(result))
+		}
+		sent = append(sent, data)
+	}
+	// A known burst type is not counted.
+	tr.TranslateToMMDVM(0x83, makeTestIPSCPacket(0x83, ipscBurstCSBK, true, false))
+
+	if want := map[byte]uint64{0x42: 2, 0x13: 1}; !maps.Equal(tr.UnknownBurstTypes(), want) {
+		t.Fatalf("expected counts %v, got %v", want, tr.UnknownBurstTypes())
+	}
+	if len(handled) != len(sent) {
+		t.Fatalf("expected the handler called %d times, got %d", len(sent), len(handled))
+	}
+	for i := range sent {
+		if !bytes.Equal(handled[i], sent[i]) {
+			t.Fatalf("packet %d: expected % X, got % X", i, sent[i], handled[i])
+		}
+	}
+	if got := sink.dropped["ipsc_to_mmdvm/unknown_burst_type"]; got != len(sent) {
+		t.Fatalf("expected %d drops counted, got %d", len(sent), got)
+	}
+}
@@ -0,0 +1,63 @@
+package ipsc
+
+import (
+	"net"
+	"time"
+)
+
+// unregisteredLogInterval is how often control requests refused from one
+// unregistered address are logged.
+const unregisteredLogInterval = time.Minute
+
+// registeredSender reports whether env comes from a peer that registered
+// from its address. Unless unregistered peers are allowed, control
+// requests from anyone else are refused, so the peer list is not handed
+// to whoever sends one packet. Refusals are logged at most once per
+// unregisteredLogInterval per address.
+func (s *IPSCServer) registeredSender(env packetEnvelope) bool {
+	if s.cfg.IPSC.AllowUnregistered || env.Addr == nil {
+		return true
+	}
+
+	s.mu.Lock()
+	peer, ok := s.peers[env.PeerID]
+	if ok && peer.RegistrationStatus && peer.Addr != nil && peer.Addr.String() == env.Addr.String() {
+		s.mu.Unlock()
+		return true
+	}
+	log := s.refusalLogDue(env.Addr)
+	s.mu.Unlock()
+
+	if log {
+		s.log.Warn("Ignoring IPSC control request from an unregistered peer",
+			"peer", env.Addr, "peerID", env.PeerID, "packetType", byte(env.Type))
+	}
+	return false
+}
+
+// refusalLogDue reports whether a packet refused from addr is to be
+// logged, which it is at most once per unregisteredLogInterval per
+// address. The caller must hold s.mu.
+func (s *IPSCServer) refusalLogDue(addr *net.UDPAddr) bool {
+	key := addr.String()
+	now := s.now()
+	last, logged := s.unregisteredLogged[key]
+	if logged && now.Sub(last) < unregisteredLogInterval {
+		return false
+	}
+	s.unregisteredLogged[key] = now
+	return true
+}
+
+// forgetUnregisteredLogs drops the addresses whose refusals may be logged
+// again, so sources that stop sending are not remembered forever.
+func (s *IPSCServer) forgetUnregisteredLogs() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for key, last := range s.unregisteredLogged {
+		if now.Sub(last) >= unregisteredLogInterval {
+			delete(s.unregisteredLogged, key)
+		}
+	}
+}
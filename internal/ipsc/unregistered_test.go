@@ -0,0 +1,123 @@
+package ipsc
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestControlRequestsFromUnregisteredPeers(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name              string
+		allowUnregistered bool
+	}{
+		{"strict", false},
+		{"relaxed", true},
+	}
+	requests := []struct {
+		packetType PacketType
+		reply      PacketType
+	}{
+		{PacketType_MasterAliveRequest, PacketType_MasterAliveReply},
+		{PacketType_PeerListRequest, PacketType_PeerListReply},
+		{PacketType_PeerAliveRequest, PacketType_PeerAliveReply},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := testConfig(false, "")
+			cfg.IPSC.AllowUnregistered = tt.allowUnregistered
+			s, _ := newTestServerWithConfig(t, cfg)
+			registered, registeredAddr := listenPeer(t)
+			stranger, strangerAddr := listenPeer(t)
+			s.upsertPeer(100, registeredAddr, 0x6A, [4]byte{})
+
+			for _, req := range requests {
+				// Registered peers are answered either way.
+				if _, err := s.handlePacket(makeControlPacket(req.packetType, 100), registeredAddr); err != nil {
+					t.Fatalf("0x%02X from a registered peer: %v", byte(req.packetType), err)
+				}
+				readPacketType(t, registered, req.reply)
+
+				// So are strangers, and those claiming a registered
+				// peer's ID, only when relaxed.
+				for _, peerID := range []uint32{200, 100} {
+					_, err := s.handlePacket(makeControlPacket(req.packetType, peerID), strangerAddr)
+					if tt.allowUnregistered {
+						if err != nil {
+							t.Fatalf("0x%02X from peer %d: %v", byte(req.packetType), peerID, err)
+						}
+						readPacketType(t, stranger, req.reply)
+						continue
+					}
+					if !errors.Is(err, ErrPacketIgnored) {
+						t.Fatalf("0x%02X from peer %d: expected the request ignored, got %v", byte(req.packetType), peerID, err)
+					}
+					assertNothingReceived(t, stranger)
+				}
+			}
+
+			if !tt.allowUnregistered {
+				s.mu.RLock()
+				_, known := s.peers[200]
+				addr := s.peers[100].Addr.String()
+				s.mu.RUnlock()
+				if known || addr != registeredAddr.String() {
+					t.Fatalf("expected the peers untouched by refused requests, got peer 200 known=%t and peer 100 at %s", known, addr)
+				}
+			}
+		})
+	}
+}
+
+func TestUnregisteredTrafficAddsNoPeer(t *testing.T) {
+	t.Parallel()
+	voice := makeTestIPSCPacket(0x80, ipscBurstSlot1, true, false)
+	binary.BigEndian.PutUint32(voice[1:5], 200)
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"wake-up", makeControlPacket(PacketType_RepeaterWakeUp, 200)},
+		{"voice", voice},
+		{"master alive", makeControlPacket(PacketType_MasterAliveRequest, 200)},
+		{"peer alive", makeControlPacket(PacketType_PeerAliveRequest, 200)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			// Even when keepalives from anyone are answered, only a
+			// registration adds a peer.
+			cfg := testConfig(false, "")
+			cfg.IPSC.AllowUnregistered = true
+			s, _ := newTestServerWithConfig(t, cfg)
+			_, addr := listenPeer(t)
+
+			s.handlePacket(tt.data, addr) //nolint:errcheck // only the peer table matters
+			if got := s.peerCount(); got != 0 {
+				t.Fatalf("expected no peer for an unregistered ID, got %d", got)
+			}
+		})
+	}
+}
+
+func TestForgetUnregisteredLogs(t *testing.T) {
+	t.Parallel()
+	clock := time.Unix(1_700_000_000, 0)
+	s, _ := newTestServerWithUDP(t, false, "")
+	s.now = func() time.Time { return clock }
+	_, addr := listenPeer(t)
+
+	s.handlePacket(makeControlPacket(PacketType_PeerListRequest, 200), addr) //nolint:errcheck // only the log bookkeeping matters
+	s.forgetUnregisteredLogs()
+	if got := len(s.unregisteredLogged); got != 1 {
+		t.Fatalf("expected the address remembered, got %d", got)
+	}
+	clock = clock.Add(unregisteredLogInterval)
+	s.forgetUnregisteredLogs()
+	if got := len(s.unregisteredLogged); got != 0 {
+		t.Fatalf("expected the address forgotten, got %d", got)
+	}
+}
@@ -0,0 +1,71 @@
+// Package jitter provides a ticker whose ticks are spread randomly around
+// its period, so processes started together do not keep sending in step.
+package jitter
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// DefaultFraction is how far intervals stray from the period, either way,
+// as a fraction of it.
+const DefaultFraction = 0.2
+
+// Source returns a pseudo-random number in [0, 1), such as rand.Float64.
+// A Ticker calls it from its own goroutine, so a Source shared between
+// tickers must be safe for concurrent use.
+type Source func() float64
+
+// Interval returns period scaled by a random factor in
+// [1-fraction, 1+fraction).
+func Interval(period time.Duration, fraction float64, source Source) time.Duration {
+	return time.Duration(float64(period) * (1 + fraction*(2*source()-1)))
+}
+
+// Ticker delivers ticks on C at intervals drawn by Interval. Like a
+// time.Ticker, it drops ticks for a slow receiver.
+type Ticker struct {
+	C <-chan time.Time
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewTicker returns a Ticker whose intervals are period give or take
+// fraction of it, drawn from source, or from rand.Float64 if source is
+// nil. It panics if period is not positive.
+func NewTicker(period time.Duration, fraction float64, source Source) *Ticker {
+	if period <= 0 {
+		panic("jitter: non-positive period for NewTicker")
+	}
+	if source == nil {
+		source = rand.Float64
+	}
+	c := make(chan time.Time, 1)
+	t := &Ticker{C: c, stop: make(chan struct{})}
+	go t.run(c, period, fraction, source)
+	return t
+}
+
+func (t *Ticker) run(c chan<- time.Time, period time.Duration, fraction float64, source Source) {
+	timer := time.NewTimer(Interval(period, fraction, source))
+	defer timer.Stop()
+	for {
+		select {
+		case now := <-timer.C:
+			select {
+			case c <- now:
+			default:
+			}
+			timer.Reset(Interval(period, fraction, source))
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+// Stop turns off the ticker. Like time.Ticker.Stop, it does not close C.
+func (t *Ticker) Stop() {
+	t.stopOnce.Do(func() { close(t.stop) })
+}
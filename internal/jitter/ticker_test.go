@@ -0,0 +1,90 @@
+package jitter
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func TestIntervalBounds(t *testing.T) {
+	t.Parallel()
+	const (
+		period   = 10 * time.Second
+		fraction = DefaultFraction
+		samples  = 10000
+	)
+	source := rand.New(rand.NewPCG(1, 2)).Float64
+	lo, hi := time.Duration(float64(period)*(1-fraction)), time.Duration(float64(period)*(1+fraction))
+	var sum time.Duration
+	var below, above int
+	for range samples {
+		d := Interval(period, fraction, source)
+		if d < lo || d >= hi {
+			t.Fatalf("expected an interval in [%v, %v), got %v", lo, hi, d)
+		}
+		sum += d
+		switch {
+		case d < period-period/10:
+			below++
+		case d > period+period/10:
+			above++
+		}
+	}
+	// Uniform over ±20%, the mean is the period and half the intervals
+	// stray more than 10% from it, evenly either way.
+	if mean := sum / samples; mean < period-period/100 || mean > period+period/100 {
+		t.Fatalf("expected a mean within 1%% of %v, got %v", period, mean)
+	}
+	for name, n := range map[string]int{"below": below, "above": above} {
+		if n < samples/5 || n > samples*3/10 {
+			t.Fatalf("expected about a quarter of the intervals %s the period by over 10%%, got %d of %d", name, n, samples)
+		}
+	}
+}
+
+func TestIntervalSpread(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		random   float64
+		fraction float64
+		want     time.Duration
+	}{
+		{"lowest", 0, 0.2, 8 * time.Second},
+		{"middle", 0.5, 0.2, 10 * time.Second},
+		{"no jitter", 0.9, 0, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := Interval(10*time.Second, tt.fraction, func() float64 { return tt.random })
+			if got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTickerTicksUntilStopped(t *testing.T) {
+	t.Parallel()
+	ticker := NewTicker(5*time.Millisecond, DefaultFraction, rand.New(rand.NewPCG(3, 4)).Float64)
+	for range 3 {
+		select {
+		case <-ticker.C:
+		case <-time.After(time.Second):
+			t.Fatal("expected a tick")
+		}
+	}
+	ticker.Stop()
+	ticker.Stop()
+	// A tick may have been sent while stopping; none follow it.
+	select {
+	case <-ticker.C:
+	case <-time.After(20 * time.Millisecond):
+	}
+	select {
+	case <-ticker.C:
+		t.Fatal("expected no ticks once stopped")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,428 @@
+// Package loadgen drives synthetic IPSC traffic through the bridge to
+// answer capacity questions. A set of fake IPSC peers register with the
+// bridge and place concurrent group calls; a fake MMDVM master receives
+// them and every frame is matched against its send time to measure
+// delivery, loss, and latency.
+//
+// The bridge under test is either started in-process or is a running
+// instance whose IPSC address is given as the target and whose MMDVM
+// network is pointed at the fake master.
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/ipsc"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/selftest"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/timeslot"
+)
+
+// Identities used by generated traffic. Every call gets its own source ID
+// so received frames can be attributed to the call that sent them.
+const (
+	bridgeID    = 311870
+	firstPeerID = 311900
+	firstSrc    = 3120100
+	firstTG     = 9000
+	firstStream = 0x10000
+)
+
+// ErrMasterAddressRequired is returned by Run when a target is given
+// without the address the fake master should listen on.
+var ErrMasterAddressRequired = errors.New("a master address is required with a target")
+
+// superframeDuration is the air time of one DMR voice superframe.
+const superframeDuration = 360 * time.Millisecond
+
+// Options tunes a load run.
+type Options struct {
+	// Peers is the number of fake IPSC peers to register. Defaults to 1.
+	Peers int
+	// Calls is the number of concurrent callers. Each caller uses the
+	// peers round-robin and alternates timeslots. Defaults to 1.
+	Calls int
+	// Rounds is the number of calls each caller places back to back.
+	// Defaults to 1.
+	Rounds int
+	// CallDuration is the air time of each call, rounded to whole voice
+	// superframes. Defaults to one superframe.
+	CallDuration time.Duration
+	// Gap is the pause between one caller's consecutive calls.
+	Gap time.Duration
+	// FrameInterval is the spacing between transmitted frames.
+	// Defaults to the 60ms DMR frame cadence.
+	FrameInterval time.Duration
+	// Settle is how long to keep collecting frames after the last call
+	// ends. Defaults to 1s.
+	Settle time.Duration
+
+	// Target is the IPSC address of a running instance. When empty, a
+	// bridge is started in-process.
+	Target string
+	// MasterAddress is where the fake MMDVM master listens. It is
+	// required with Target, and the target must be configured to log in
+	// to it. Defaults to a random loopback port in-process.
+	MasterAddress string
+}
+
+func (o *Options) setDefaults() {
+	if o.Peers <= 0 {
+		o.Peers = 1
+	}
+	if o.Calls <= 0 {
+		o.Calls = 1
+	}
+	if o.Rounds <= 0 {
+		o.Rounds = 1
+	}
+	if o.FrameInterval <= 0 {
+		o.FrameInterval = 60 * time.Millisecond
+	}
+	if o.Settle <= 0 {
+		o.Settle = time.Second
+	}
+	if o.MasterAddress == "" {
+		o.MasterAddress = "127.0.0.1:0"
+	}
+}
+
+func (o *Options) superframes() int {
+	return max(1, int(o.CallDuration/superframeDuration))
+}
+
+// Latency summarizes the send-to-receive delay of delivered frames.
+type Latency struct {
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+// Report is the outcome of a load run.
+type Report struct {
+	Peers          int
+	Calls          int
+	FramesSent     int
+	FramesReceived int
+	Latency        Latency
+	Elapsed        time.Duration
+}
+
+// Lost returns the number of frames that were sent but never received.
+func (r *Report) Lost() int {
+	return max(0, r.FramesSent-r.FramesReceived)
+}
+
+// LossPercent returns Lost as a percentage of FramesSent.
+func (r *Report) LossPercent() float64 {
+	if r.FramesSent == 0 {
+		return 0
+	}
+	return 100 * float64(r.Lost()) / float64(r.FramesSent)
+}
+
+// WriteTo writes a human-readable report to w.
+func (r *Report) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, r.String())
+	return int64(n), err
+}
+
+func (r *Report) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "peers:     %d\n", r.Peers)
+	fmt.Fprintf(&sb, "calls:     %d\n", r.Calls)
+	fmt.Fprintf(&sb, "elapsed:   %s\n", r.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(&sb, "frames:    %d sent, %d received\n", r.FramesSent, r.FramesReceived)
+	fmt.Fprintf(&sb, "loss:      %d (%.2f%%)\n", r.Lost(), r.LossPercent())
+	fmt.Fprintf(&sb, "latency:   p50 %s, p90 %s, p99 %s, max %s\n",
+		r.Latency.P50.Round(time.Microsecond), r.Latency.P90.Round(time.Microsecond),
+		r.Latency.P99.Round(time.Microsecond), r.Latency.Max.Round(time.Microsecond))
+	return sb.String()
+}
+
+// Run generates the configured load and returns the report. The returned
+// error is only non-nil if the harness could not be set up.
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if opts.Target != "" && opts.MasterAddress == "" {
+		return nil, ErrMasterAddressRequired
+	}
+	opts.setDefaults()
+
+	master, err := selftest.ListenFakeMaster(opts.MasterAddress)
+	if err != nil {
+		return nil, err
+	}
+	defer master.Close()
+
+	target := opts.Target
+	if target == "" {
+		stop, addr, err := startInProcess(master.Addr())
+		if err != nil {
+			return nil, err
+		}
+		defer stop()
+		target = addr.String()
+	}
+
+	targetAddr, err := net.ResolveUDPAddr("udp", target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve target: %w", err)
+	}
+
+	select {
+	case <-master.Ready():
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for the bridge to log in to the fake master: %w", ctx.Err())
+	}
+
+	peers, encoders, err := registerPeers(ctx, opts.Peers, targetAddr)
+	defer func() {
+		for _, peer := range peers {
+			_ = peer.Close()
+		}
+	}()
+	if err != nil {
+		return nil, err
+	}
+
+	rec := newRecorder()
+	collectDone := make(chan struct{})
+	collectStop := make(chan struct{})
+	go func() {
+		defer close(collectDone)
+		for {
+			select {
+			case pkt := <-master.Packets():
+				rec.received(pkt, time.Now())
+			case <-collectStop:
+				return
+			}
+		}
+	}()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for c := range opts.Calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := c % len(peers)
+			runCaller(ctx, opts, c, peers[p], encoders[p], rec)
+		}()
+	}
+	wg.Wait()
+	sleep(ctx, opts.Settle)
+	close(collectStop)
+	<-collectDone
+
+	report := rec.report()
+	report.Peers = opts.Peers
+	report.Calls = opts.Calls * opts.Rounds
+	report.Elapsed = time.Since(start)
+	return report, nil
+}
+
+// startInProcess starts an IPSC server and MMDVM client wired together
+// the way the root command does, with the client logging in to master.
+func startInProcess(master string) (func(), *net.UDPAddr, error) {
+	cfg := &config.Config{
+		LogLevel: config.LogLevelInfo,
+		MMDVM: []config.MMDVM{{
+			Name:         "loadgen",
+			Callsign:     "LOADGEN",
+			ID:           bridgeID,
+			ColorCode:    1,
+			MasterServer: master,
+			Password:     "loadgen",
+			PassAllTG:    []int{1, 2},
+			PassAllPC:    []int{1, 2},
+		}},
+		IPSC: config.IPSC{
+			IP: "127.0.0.1",
+		},
+	}
+
+	client := mmdvm.NewMMDVMClient(&cfg.MMDVM[0], nil, mmdvm.TranslatorOptions{})
+	client.SetOutboundTSManager(timeslot.NewManager())
+	server := ipsc.NewIPSCServer(cfg, nil)
+	server.SetBurstHandler(mmdvm.NewBurstRouter([]*mmdvm.MMDVMClient{client}))
+	client.SetIPSCHandler(server.SendUserPacket)
+
+	if err := client.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failed to start MMDVM client: %w", err)
+	}
+	if err := server.Start(); err != nil {
+		client.Stop()
+		return nil, nil, fmt.Errorf("failed to start IPSC server: %w", err)
+	}
+	stop := func() {
+		server.Stop()
+		client.Stop()
+	}
+	return stop, server.Addr(), nil
+}
+
+// registerPeers creates n fake peers, each with a translator stamping its
+// own peer ID, and registers them with target. Peers created before an
+// error are still returned so the caller can close them.
+func registerPeers(ctx context.Context, n int, target *net.UDPAddr) ([]*selftest.FakePeer, []*ipsc.IPSCTranslator, error) {
+	peers := make([]*selftest.FakePeer, 0, n)
+	encoders := make([]*ipsc.IPSCTranslator, 0, n)
+	for i := range n {
+		id := uint32(firstPeerID + i) //nolint:gosec // peer counts are small
+		peer, err := selftest.NewFakePeer(id, target)
+		if err != nil {
+			return peers, nil, err
+		}
+		peers = append(peers, peer)
+		if err := peer.Register(ctx); err != nil {
+			return peers, nil, fmt.Errorf("peer %d failed to register: %w", id, err)
+		}
+		encoder, err := ipsc.NewIPSCTranslator()
+		if err != nil {
+			return peers, nil, fmt.Errorf("failed to create IPSC translator: %w", err)
+		}
+		encoder.SetPeerID(id)
+		// The bridge tells calls apart by call control, which every
+		// translator numbers from one, so give each peer its own range.
+		encoder.SeedCallControls([]uint32{uint32(i) << 16}) //nolint:gosec // peer counts are small
+		encoders = append(encoders, encoder)
+	}
+	return peers, encoders, nil
+}
+
+// runCaller places opts.Rounds calls for caller c, one after another.
+func runCaller(ctx context.Context, opts Options, c int, peer *selftest.FakePeer, encoder *ipsc.IPSCTranslator, rec *recorder) {
+	for round := range opts.Rounds {
+		if round > 0 && !sleep(ctx, opts.Gap) {
+			return
+		}
+		n := uint(c*opts.Rounds + round) //nolint:gosec // call counts are small
+		src := firstSrc + n
+		call := selftest.BuildCall(src, firstTG+uint(c), c%2 == 1, firstStream+n, opts.superframes()) //nolint:gosec // c is small
+		for _, pkt := range call {
+			rec.sent(src, time.Now())
+			for _, data := range encoder.TranslateToIPSC(pkt) {
+				_ = peer.Send(data)
+			}
+			if !sleep(ctx, opts.FrameInterval) {
+				return
+			}
+		}
+	}
+}
+
+// recorder matches received frames to their send times by stream and
+// sequence number. The bridge numbers the frames of each stream it sends
+// to the master from zero, so the frame received with sequence number k
+// on a stream is the k-th frame sent by the call whose source the stream
+// carries, even if frames before it were lost.
+type recorder struct {
+	mu        sync.Mutex
+	sentAt    map[uint][]time.Time   // by call source, in sending order
+	streams   map[uint]*receivedCall // by received stream ID
+	latencies []time.Duration
+	nSent     int
+	nReceived int
+}
+
+// receivedCall follows a stream received by the master: the call it
+// carries, its sequence numbers, which wrap at 256, and the frames
+// already matched, so repeats are counted once.
+type receivedCall struct {
+	src     uint
+	lastSeq uint
+	wraps   uint
+	matched map[uint]bool
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		sentAt:  make(map[uint][]time.Time),
+		streams: make(map[uint]*receivedCall),
+	}
+}
+
+func (r *recorder) sent(src uint, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sentAt[src] = append(r.sentAt[src], at)
+	r.nSent++
+}
+
+func (r *recorder) received(pkt proto.Packet, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	call, ok := r.streams[pkt.StreamID]
+	if !ok {
+		if _, ok := r.sentAt[pkt.Src]; !ok {
+			return
+		}
+		call = &receivedCall{src: pkt.Src, lastSeq: pkt.Seq, matched: make(map[uint]bool)}
+		r.streams[pkt.StreamID] = call
+	}
+	if pkt.Seq < call.lastSeq && call.lastSeq-pkt.Seq > 128 {
+		call.wraps++
+	}
+	call.lastSeq = pkt.Seq
+	k := call.wraps<<8 | pkt.Seq
+	if call.matched[k] {
+		return
+	}
+	call.matched[k] = true
+	r.nReceived++
+	if sent := r.sentAt[call.src]; k < uint(len(sent)) {
+		r.latencies = append(r.latencies, at.Sub(sent[k]))
+	}
+}
+
+func (r *recorder) report() *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	latencies := slices.Clone(r.latencies)
+	slices.Sort(latencies)
+	return &Report{
+		FramesSent:     r.nSent,
+		FramesReceived: r.nReceived,
+		Latency: Latency{
+			P50: percentile(latencies, 0.50),
+			P90: percentile(latencies, 0.90),
+			P99: percentile(latencies, 0.99),
+			Max: percentile(latencies, 1),
+		},
+	}
+}
+
+// percentile returns the p-th percentile (0 < p <= 1) of sorted using the
+// nearest-rank method, or zero if sorted is empty.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// sleep waits for d or until ctx is done. It returns false if ctx ended.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
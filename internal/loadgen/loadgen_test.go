@@ -0,0 +1,68 @@
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunInProcess(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	report, err := Run(ctx, Options{
+		Peers:         2,
+		Calls:         2,
+		CallDuration:  superframeDuration,
+		FrameInterval: 30 * time.Millisecond,
+		Settle:        500 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if report.Calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", report.Calls)
+	}
+	// Each call is a header, six voice bursts, and a terminator.
+	if report.FramesSent != 2*8 {
+		t.Fatalf("expected %d frames sent, got %d", 2*8, report.FramesSent)
+	}
+	if report.FramesReceived != report.FramesSent {
+		t.Fatalf("expected every frame delivered, got %d of %d\n%s", report.FramesReceived, report.FramesSent, report)
+	}
+	if report.Latency.Max <= 0 || report.Latency.P50 > report.Latency.Max {
+		t.Fatalf("implausible latency: %+v", report.Latency)
+	}
+}
+
+func TestRunTargetNeedsMasterAddress(t *testing.T) {
+	t.Parallel()
+	_, err := Run(context.Background(), Options{Target: "127.0.0.1:50000"})
+	if !errors.Is(err, ErrMasterAddressRequired) {
+		t.Fatalf("expected %v, got %v", ErrMasterAddressRequired, err)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	t.Parallel()
+	sorted := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0.50, 5},
+		{0.90, 9},
+		{0.99, 10},
+		{1, 10},
+	}
+	for _, tt := range tests {
+		if got := percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if got := percentile(nil, 0.5); got != 0 {
+		t.Errorf("percentile of empty = %v, want 0", got)
+	}
+}
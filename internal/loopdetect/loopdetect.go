@@ -0,0 +1,134 @@
+// Package loopdetect breaks call loops between networks. With several
+// masters and rewrite rules, a call carried from one network to the IPSC
+// peers can come back from another network, or from the same one, as a
+// new stream. Stream IDs differ on every hop, so the detector instead
+// fingerprints each call by its source, destination, call type and slot
+// as seen on the IPSC side, and refuses a new stream whose fingerprint
+// matches a call started in the opposite direction within the window.
+package loopdetect
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/expiry"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/metrics"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+)
+
+// DefaultWindow is how long after a call starts a matching call in the
+// opposite direction is treated as its reflection.
+const DefaultWindow = 5 * time.Second
+
+// streamTimeout is how long the decision for a stream is remembered after
+// its last packet, in case its terminator is lost.
+const streamTimeout = 3 * time.Second
+
+// DMR frame type and data type values used to detect call termination.
+const (
+	frameTypeDataSync     uint = 2
+	dtypeTerminatorWithLC uint = 2
+)
+
+// Direction is the way a call is carried.
+type Direction uint8
+
+const (
+	// ToIPSC is a call from a master to the IPSC peers.
+	ToIPSC Direction = iota
+	// ToMaster is a call from the IPSC peers to a master.
+	ToMaster
+)
+
+func (d Direction) String() string {
+	if d == ToIPSC {
+		return "to_ipsc"
+	}
+	return "to_master"
+}
+
+func (d Direction) opposite() Direction {
+	if d == ToIPSC {
+		return ToMaster
+	}
+	return ToIPSC
+}
+
+// fingerprint identifies a call independently of its stream ID.
+type fingerprint struct {
+	src, dst  uint
+	groupCall bool
+	slot      bool
+}
+
+type streamKey struct {
+	direction Direction
+	network   string
+	streamID  uint
+}
+
+// Detector remembers recent calls in both directions. It is shared by
+// every MMDVM client.
+type Detector struct {
+	window  time.Duration
+	metrics *metrics.Metrics
+	// origins holds, per direction, the network each recent call was
+	// carried to or from.
+	origins [2]*expiry.Map[fingerprint, string]
+	// streams holds whether each stream in progress was admitted.
+	streams *expiry.Map[streamKey, bool]
+}
+
+// New returns a Detector with the given window, or DefaultWindow if it is
+// not positive.
+func New(window time.Duration) *Detector {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Detector{
+		window:  window,
+		origins: [2]*expiry.Map[fingerprint, string]{expiry.New[fingerprint, string](), expiry.New[fingerprint, string]()},
+		streams: expiry.New[streamKey, bool](),
+	}
+}
+
+// SetMetrics configures the metrics collector for this detector.
+func (d *Detector) SetMetrics(m *metrics.Metrics) {
+	d.metrics = m
+}
+
+// Admit reports whether pkt, carried in direction by network, may pass.
+// pkt must carry the IPSC-side addresses: after rewriting toward IPSC,
+// before rewriting toward a master. The first packet of a stream decides
+// for the whole stream.
+func (d *Detector) Admit(direction Direction, network string, pkt proto.Packet) bool {
+	key := streamKey{direction: direction, network: network, streamID: pkt.StreamID}
+	end := pkt.FrameType == frameTypeDataSync && pkt.DTypeOrVSeq == dtypeTerminatorWithLC
+
+	admitted, ok := d.streams.Get(key)
+	if !ok {
+		admitted = d.start(direction, network, pkt)
+	}
+	if end {
+		d.streams.Delete(key)
+	} else {
+		d.streams.Set(key, admitted, streamTimeout)
+	}
+	return admitted
+}
+
+// start decides on a new stream and, if it is admitted, records it.
+func (d *Detector) start(direction Direction, network string, pkt proto.Packet) bool {
+	fp := fingerprint{src: pkt.Src, dst: pkt.Dst, groupCall: pkt.GroupCall, slot: pkt.Slot}
+	if origin, looped := d.origins[direction.opposite()].Get(fp); looped {
+		slog.Warn("Suppressed call loop",
+			"network", network, "origin", origin, "direction", direction,
+			"src", pkt.Src, "dst", pkt.Dst, "groupCall", pkt.GroupCall, "slot", pkt.Slot, "streamID", pkt.StreamID)
+		if d.metrics != nil {
+			d.metrics.MMDVMLoopsSuppressed.WithLabelValues(network, origin).Inc()
+		}
+		return false
+	}
+	d.origins[direction].Set(fp, network, d.window)
+	return true
+}
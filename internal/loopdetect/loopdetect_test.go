@@ -0,0 +1,90 @@
+package loopdetect
+
+import (
+	"testing"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+)
+
+func voice(src, dst, streamID uint) proto.Packet {
+	return proto.Packet{Src: src, Dst: dst, GroupCall: true, StreamID: streamID}
+}
+
+func terminator(src, dst, streamID uint) proto.Packet {
+	pkt := voice(src, dst, streamID)
+	pkt.FrameType = frameTypeDataSync
+	pkt.DTypeOrVSeq = dtypeTerminatorWithLC
+	return pkt
+}
+
+func TestReflectedCallSuppressed(t *testing.T) {
+	t.Parallel()
+	d := New(time.Second)
+
+	// A call from network A is carried to IPSC, and comes back from IPSC
+	// as a new stream headed for network B.
+	if !d.Admit(ToIPSC, "A", voice(3120101, 91, 1)) {
+		t.Fatal("expected the original call to pass")
+	}
+	if d.Admit(ToMaster, "B", voice(3120101, 91, 2)) {
+		t.Fatal("expected the reflected call to be suppressed")
+	}
+	// The whole reflected stream stays suppressed, up to its terminator.
+	if d.Admit(ToMaster, "B", voice(3120101, 91, 2)) || d.Admit(ToMaster, "B", terminator(3120101, 91, 2)) {
+		t.Fatal("expected every packet of the reflected stream to be suppressed")
+	}
+	// The original keeps flowing.
+	if !d.Admit(ToIPSC, "A", voice(3120101, 91, 1)) {
+		t.Fatal("expected the original call to keep passing")
+	}
+}
+
+func TestReflectionBackIntoSourceNetworkSuppressed(t *testing.T) {
+	t.Parallel()
+	d := New(time.Second)
+
+	// IPSC → B, and B's network links back to A, which sends it to IPSC.
+	if !d.Admit(ToMaster, "B", voice(3120101, 91, 7)) {
+		t.Fatal("expected the original call to pass")
+	}
+	if d.Admit(ToIPSC, "A", voice(3120101, 91, 8)) {
+		t.Fatal("expected the call reflected back toward IPSC to be suppressed")
+	}
+}
+
+func TestLegitimateCallsPass(t *testing.T) {
+	t.Parallel()
+	d := New(time.Second)
+	if !d.Admit(ToIPSC, "A", voice(3120101, 91, 1)) {
+		t.Fatal("expected the original call to pass")
+	}
+	d.Admit(ToIPSC, "A", terminator(3120101, 91, 1))
+
+	tests := []struct {
+		name string
+		dir  Direction
+		pkt  proto.Packet
+	}{
+		{"quick reply from another source", ToMaster, voice(3120102, 91, 2)},
+		{"same source to another talkgroup", ToMaster, voice(3120101, 92, 3)},
+		{"same source and talkgroup on the other slot", ToMaster, proto.Packet{Src: 3120101, Dst: 91, GroupCall: true, Slot: true, StreamID: 4}},
+		{"same direction again", ToIPSC, voice(3120101, 91, 5)},
+	}
+	for _, tt := range tests {
+		if !d.Admit(tt.dir, "B", tt.pkt) {
+			t.Fatalf("%s: expected the call to pass", tt.name)
+		}
+	}
+}
+
+func TestWindowExpires(t *testing.T) {
+	t.Parallel()
+	d := New(50 * time.Millisecond)
+	d.Admit(ToIPSC, "A", voice(3120101, 91, 1))
+	d.Admit(ToIPSC, "A", terminator(3120101, 91, 1))
+	time.Sleep(100 * time.Millisecond)
+	if !d.Admit(ToMaster, "B", voice(3120101, 91, 2)) {
+		t.Fatal("expected a call after the window to pass")
+	}
+}
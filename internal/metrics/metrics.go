@@ -15,11 +15,18 @@ type Metrics struct {
 	registry *prometheus.Registry
 
 	// IPSC Server
-	IPSCPacketsReceived *prometheus.CounterVec
-	IPSCPacketsSent     prometheus.Counter
-	IPSCPeersRegistered prometheus.Gauge
-	IPSCAuthFailures    prometheus.Counter
-	IPSCUDPErrors       *prometheus.CounterVec
+	IPSCPacketsReceived     *prometheus.CounterVec
+	IPSCPacketsSent         prometheus.Counter
+	IPSCPeersRegistered     prometheus.Gauge
+	IPSCAuthFailures        prometheus.Counter
+	IPSCUDPErrors           *prometheus.CounterVec
+	IPSCARSRegistrations    *prometheus.CounterVec
+	IPSCRadioChecksAnswered prometheus.Counter
+	IPSCPeerPacketsSkipped  *prometheus.CounterVec
+	IPSCPacketsRateLimited  prometheus.Counter
+	IPSCPacketsDropped      *prometheus.CounterVec
+	IPSCPeersRefused        prometheus.Counter
+	IPSCSocketRecoveries    prometheus.Counter
 
 	// MMDVM Client
 	MMDVMConnectionState *prometheus.GaugeVec
@@ -29,6 +36,9 @@ type Metrics struct {
 	MMDVMPacketsReceived *prometheus.CounterVec
 	MMDVMPacketsSent     *prometheus.CounterVec
 	MMDVMPacketsDropped  *prometheus.CounterVec
+	MMDVMActiveMaster    *prometheus.GaugeVec
+	MMDVMMasterSwitches  *prometheus.CounterVec
+	MMDVMLoopsSuppressed *prometheus.CounterVec
 
 	// Rewrite
 	MMDVMRewriteMatches *prometheus.CounterVec
@@ -37,10 +47,14 @@ type Metrics struct {
 	TimeslotActiveCalls     *prometheus.GaugeVec
 	TimeslotPacketsBuffered *prometheus.CounterVec
 	TimeslotTimeouts        *prometheus.CounterVec
+	TimeslotBusyCalls       *prometheus.CounterVec
 
 	// Translator
-	TranslatorActiveStreams *prometheus.GaugeVec
-	TranslatorPackets       *prometheus.CounterVec
+	TranslatorActiveStreams   *prometheus.GaugeVec
+	TranslatorPackets         *prometheus.CounterVec
+	TranslatorReverseChannel  *prometheus.CounterVec
+	TranslatorCommandsBlocked *prometheus.CounterVec
+	TranslatorPacketsDropped  *prometheus.CounterVec
 }
 
 // NewMetrics creates and registers all application metrics with a
@@ -75,6 +89,34 @@ func NewMetrics() *Metrics {
 			Name: "ipsc_udp_errors_total",
 			Help: "Total IPSC UDP errors by direction.",
 		}, []string{"direction"}),
+		IPSCARSRegistrations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ipsc_ars_registrations_total",
+			Help: "Total ARS registrations intercepted by action taken.",
+		}, []string{"action"}),
+		IPSCRadioChecksAnswered: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "ipsc_radio_checks_answered_total",
+			Help: "Total radio checks to a local ID acknowledged by the IPSC server.",
+		}),
+		IPSCPeerPacketsSkipped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ipsc_peer_packets_skipped_total",
+			Help: "Total packets not sent to an IPSC peer because its advertised capabilities exclude them, by peer and reason.",
+		}, []string{"peer", "reason"}),
+		IPSCPacketsRateLimited: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "ipsc_packets_rate_limited_total",
+			Help: "Total IPSC packets dropped because their source address sent more than the rate limit allows.",
+		}),
+		IPSCPacketsDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ipsc_packets_dropped_total",
+			Help: "Total received IPSC packets not passed on, by reason: ignored by policy or refused as invalid.",
+		}, []string{"reason"}),
+		IPSCPeersRefused: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "ipsc_peers_refused_total",
+			Help: "Total IPSC registrations from new peer IDs refused because ipsc.max-peers peers were registered.",
+		}),
+		IPSCSocketRecoveries: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "ipsc_socket_recoveries_total",
+			Help: "Total IPSC sockets replaced after failing.",
+		}),
 
 		// MMDVM Client
 		MMDVMConnectionState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
@@ -106,6 +148,18 @@ func NewMetrics() *Metrics {
 			Name: "mmdvm_packets_dropped_total",
 			Help: "Total MMDVM packets dropped by reason.",
 		}, []string{"network", "reason"}),
+		MMDVMActiveMaster: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "mmdvm_active_master",
+			Help: "Whether a master is the active one for its network (1=active, 0=standby).",
+		}, []string{"network", "master"}),
+		MMDVMMasterSwitches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mmdvm_master_switches_total",
+			Help: "Total switches between masters by reason.",
+		}, []string{"network", "reason"}),
+		MMDVMLoopsSuppressed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mmdvm_call_loops_suppressed_total",
+			Help: "Total calls refused as reflections of a call carried the other way, by network and the network the call came from.",
+		}, []string{"network", "origin"}),
 
 		// Rewrite
 		MMDVMRewriteMatches: prometheus.NewCounterVec(prometheus.CounterOpts{
@@ -126,6 +180,10 @@ func NewMetrics() *Metrics {
 			Name: "timeslot_timeouts_total",
 			Help: "Total timeslot call timeouts.",
 		}, []string{"slot", "direction"}),
+		TimeslotBusyCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "timeslot_busy_calls_total",
+			Help: "Total calls arriving on a busy timeslot by busy policy outcome.",
+		}, []string{"slot", "direction", "outcome"}),
 
 		// Translator
 		TranslatorActiveStreams: prometheus.NewGaugeVec(prometheus.GaugeOpts{
@@ -136,6 +194,18 @@ func NewMetrics() *Metrics {
 			Name: "translator_packets_total",
 			Help: "Total packets translated by direction.",
 		}, []string{"direction"}),
+		TranslatorReverseChannel: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "translator_reverse_channel_bursts_total",
+			Help: "Total reverse-channel (transmit interrupt) bursts by direction and outcome: forwarded, dropped by policy, or dropped for lack of a call to attach to.",
+		}, []string{"direction", "outcome"}),
+		TranslatorCommandsBlocked: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "translator_remote_commands_blocked_total",
+			Help: "Total radio stun, revive, and kill commands blocked by the remote command policy.",
+		}, []string{"direction", "command"}),
+		TranslatorPacketsDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "translator_packets_dropped_total",
+			Help: "Total packets the translator refused by direction and reason.",
+		}, []string{"direction", "reason"}),
 	}
 
 	reg.MustRegister(
@@ -144,6 +214,13 @@ func NewMetrics() *Metrics {
 		m.IPSCPeersRegistered,
 		m.IPSCAuthFailures,
 		m.IPSCUDPErrors,
+		m.IPSCARSRegistrations,
+		m.IPSCRadioChecksAnswered,
+		m.IPSCPeerPacketsSkipped,
+		m.IPSCPacketsRateLimited,
+		m.IPSCPacketsDropped,
+		m.IPSCPeersRefused,
+		m.IPSCSocketRecoveries,
 		m.MMDVMConnectionState,
 		m.MMDVMReconnects,
 		m.MMDVMAuthFailures,
@@ -151,12 +228,19 @@ func NewMetrics() *Metrics {
 		m.MMDVMPacketsReceived,
 		m.MMDVMPacketsSent,
 		m.MMDVMPacketsDropped,
+		m.MMDVMActiveMaster,
+		m.MMDVMMasterSwitches,
+		m.MMDVMLoopsSuppressed,
 		m.MMDVMRewriteMatches,
 		m.TimeslotActiveCalls,
 		m.TimeslotPacketsBuffered,
 		m.TimeslotTimeouts,
+		m.TimeslotBusyCalls,
 		m.TranslatorActiveStreams,
 		m.TranslatorPackets,
+		m.TranslatorReverseChannel,
+		m.TranslatorCommandsBlocked,
+		m.TranslatorPacketsDropped,
 	)
 
 	return m
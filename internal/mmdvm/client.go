@@ -1,20 +1,25 @@
 package mmdvm
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net"
+	"runtime/debug"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dscp"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/expiry"
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/ipsc"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/jitter"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/loopdetect"
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/metrics"
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/rewrite"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/specialid"
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/timeslot"
 )
 
@@ -22,21 +27,45 @@ type MMDVMClient struct {
 	cfg          *config.MMDVM
 	metrics      *metrics.Metrics
 	started      atomic.Bool
+	lifecycleMu  sync.Mutex // serializes Start and Stop
+	doneMu       sync.Mutex // protects done across restarts
 	done         chan struct{}
 	stopOnce     sync.Once
 	wg           sync.WaitGroup
 	tx_chan      chan proto.Packet
+	flushReq     chan chan struct{} // Close's requests to forwardTX to report the queue sent
+	txPending    atomic.Int32       // datagrams queued on connTX and not yet written
+	pacer        *pacer             // paces voice to the master; nil sends it unpaced
 	conn         net.Conn
 	connMu       sync.Mutex // protects conn
 	state        atomic.Uint32
 	connRX       chan []byte
 	connTX       chan []byte
 	keepAlive    time.Duration
+	jitter       jitter.Source    // spreads the pings of clients started together
+	now          func() time.Time // times pings and pongs, time.Now when nil
+	pingTicker   tickerFunc       // schedules pings, a jittered ticker when nil
+	dscpSetter   dscp.Setter      // marks the socket, through setsockopt when nil
+	dial         dialFunc         // opens sockets to masters, through net.Dialer when nil
+	resolve      resolveFunc      // looks up master host names, through net.DefaultResolver when nil
 	timeout      time.Duration
 	lastPing     atomic.Int64 // UnixNano — last MSTPONG received
 	lastPingSent atomic.Int64 // UnixNano — last RPTPING sent
-	ipscHandler  func(data []byte)
-	translator   *ipsc.IPSCTranslator
+	// How long a master's host name may take to resolve; zero uses
+	// defaultResolveTimeout. lastDialed is the address of the last
+	// connection, avoided on the next while the name has others, and is
+	// protected by connMu.
+	resolveTimeout time.Duration
+	lastDialed     net.IP
+	// Ping liveness: whether the last ping awaits its pong, how many in a
+	// row went unanswered, and the round trip times of those answered.
+	pongPending    atomic.Bool
+	missedPongs    atomic.Uint32
+	maxMissedPongs uint32
+	lastRTT        atomic.Int64 // time.Duration
+	smoothedRTT    atomic.Int64 // time.Duration
+	ipscHandler    func(data []byte)
+	translator     ipsc.Translator
 
 	// Rewrite rules built from config, applied to packets
 	// flowing through this network.
@@ -49,17 +78,91 @@ type MMDVMClient struct {
 	// direction. inboundTSMgr is per-client for the IPSC→MMDVM direction.
 	outboundTSMgr *timeslot.Manager
 	inboundTSMgr  *timeslot.Manager
+
+	// loops is shared across all clients and refuses calls that are
+	// reflections of a call carried the other way.
+	loops *loopdetect.Detector
+
+	// specialIDs, if set, blocks or locally handles calls from the
+	// master to special IDs before the rewrite rules see them.
+	specialIDs *specialid.Table
+
+	// swapSlots exchanges TS1 and TS2 between the IPSC and MMDVM sides.
+	swapSlots bool
+
+	// Hot-standby failover between the configured masters.
+	masters          *masterSet
+	failbackInterval time.Duration
+	minHold          time.Duration
+	nakThreshold     int
+	probeTimeout     time.Duration
+	watchdogRunning  atomic.Bool
+	loginSent        atomic.Int64 // UnixNano — start of the current handshake
+	pingRunning      atomic.Bool
+
+	// Handshake rejections in a row, touched only by the handler
+	// goroutine, and the policy for them; zero values use the defaults.
+	rejections    int
+	maxRejections int
+	rejectBackoff time.Duration
+	failureMu     sync.Mutex
+	lastFailure   error
+	// closedDelay is the wait before logging in again to a master that
+	// announced it was closing; zero uses defaultClosedDelay.
+	closedDelay time.Duration
+
+	// Streams from the active master currently being delivered toward
+	// IPSC, keyed by stream ID, so they can be terminated on switchover.
+	// A stream quiet for longer than streamIdle is over and left alone;
+	// zero uses defaultStreamIdle.
+	streamsMu     sync.Mutex
+	activeStreams map[uint]trackedStream
+	streamIdle    time.Duration
+
+	// Radio checks delivered toward IPSC and awaiting an answer.
+	radioChecks *expiry.Map[radioCheckKey, struct{}]
+
+	// Sequence number last sent on each stream toward the master, keyed
+	// by stream ID. Only forwardTX touches it.
+	txStreams *expiry.Map[uint, uint8]
+
+	// Traffic counters and state observer reported through Stats and
+	// SetStateHandler.
+	packetsSent     atomic.Uint64
+	packetsReceived atomic.Uint64
+	packetsDropped  atomic.Uint64
+	stateHandler    func(from, to State)
+	panicHandler    func(recovered any, stack []byte)
+
+	// Credentials of the current session and those UpdateCredentials
+	// changed them to, nil while they are the config's or unchanged, and
+	// the request to the handler to log in with the changed ones.
+	credsMu      sync.Mutex
+	creds        *credentials
+	pendingCreds *credentials
+	reloginReq   chan struct{}
+
+	// Channels returned by Subscribe, sent every state change.
+	subsMu      sync.Mutex
+	subscribers []chan StateChange
 }
 
-type state uint8
+// State is the connection state of an MMDVMClient.
+type State uint8
 
 const (
-	STATE_IDLE state = iota
+	STATE_IDLE State = iota
 	STATE_SENT_LOGIN
 	STATE_SENT_AUTH
 	STATE_SENT_RPTC
 	STATE_READY
 	STATE_TIMEOUT
+	// STATE_AUTH_FAILED is terminal: the master rejected the handshake
+	// too many times in a row, and the client waits to be restarted.
+	STATE_AUTH_FAILED
+	// STATE_CLOSED is terminal: the client was closed with Close and
+	// cannot be started again.
+	STATE_CLOSED
 )
 
 const (
@@ -69,10 +172,71 @@ const (
 // DMR frame type and data type constants for call termination detection.
 const (
 	frameTypeDataSync     uint = 2 // FrameType value for data sync (header/terminator)
+	frameTypeReverseChan  uint = 3 // FrameType value for reverse-channel (TX interrupt) bursts
 	dtypeTerminatorWithLC uint = 2 // DataType value for Terminator with Link Control
 )
 
-func NewMMDVMClient(cfg *config.MMDVM, m *metrics.Metrics) *MMDVMClient {
+// TranslatorOptions configure the translator a client creates for its
+// calls to and from IPSC. The zero value keeps the translator's defaults.
+type TranslatorOptions struct {
+	// SwapSlots exchanges TS1 and TS2 between the IPSC side and the
+	// master. Rewrite rules and timeslot arbitration work on the
+	// MMDVM-side slot.
+	SwapSlots bool
+	// RTP sets the RTP header values of packets sent to IPSC.
+	RTP config.IPSCRTP
+	// ReverseChannel sets whether reverse-channel bursts are forwarded or
+	// dropped.
+	ReverseChannel config.ReverseChannelPolicy
+	// RemoteCommands sets which radio stun, revive, and kill commands are
+	// carried. A zero Policy carries them without inspecting them.
+	RemoteCommands config.IPSCRemoteCommands
+	// WakeUpIdle is how long the repeater must be idle before a call
+	// toward it starts with a wake-up packet. Zero never sends one.
+	WakeUpIdle time.Duration
+	// HeaderRepeats is how many copies of each voice header are sent to
+	// IPSC.
+	HeaderRepeats uint
+	// MaxStreams is how many calls from IPSC are tracked at once.
+	MaxStreams uint
+	// UnknownBurstHandler, if set, receives each packet from IPSC dropped
+	// for an unknown burst type.
+	UnknownBurstHandler func(data []byte)
+}
+
+// TranslatorOptionsFromConfig returns the translator options set in the
+// ipsc section of the config.
+func TranslatorOptionsFromConfig(cfg config.IPSC) TranslatorOptions {
+	return TranslatorOptions{
+		SwapSlots:      cfg.SwapSlots,
+		RTP:            cfg.RTP,
+		ReverseChannel: cfg.ReverseChannel,
+		RemoteCommands: cfg.RemoteCommands,
+		WakeUpIdle:     time.Duration(cfg.WakeUpIdle) * time.Second,
+		HeaderRepeats:  cfg.HeaderRepeats,
+		MaxStreams:     cfg.MaxStreams,
+	}
+}
+
+// apply configures t with the options.
+func (o TranslatorOptions) apply(t *ipsc.IPSCTranslator) {
+	t.SetSwapSlots(o.SwapSlots)
+	t.SetRTP(o.RTP)
+	t.SetReverseChannel(o.ReverseChannel)
+	if o.RemoteCommands.Policy != "" {
+		t.SetRemoteCommands(o.RemoteCommands)
+	}
+	t.SetWakeUp(o.WakeUpIdle)
+	t.SetHeaderRepeats(o.HeaderRepeats)
+	t.SetMaxStreams(o.MaxStreams)
+	if o.UnknownBurstHandler != nil {
+		t.SetUnknownBurstHandler(o.UnknownBurstHandler)
+	}
+}
+
+// NewMMDVMClient returns a client for the master in cfg, translating calls
+// to and from IPSC as opts direct.
+func NewMMDVMClient(cfg *config.MMDVM, m *metrics.Metrics, opts TranslatorOptions) *MMDVMClient {
 	tx_chan := make(chan proto.Packet, 256)
 	translator, err := ipsc.NewIPSCTranslator()
 	if err != nil {
@@ -83,12 +247,41 @@ func NewMMDVMClient(cfg *config.MMDVM, m *metrics.Metrics) *MMDVMClient {
 		metrics:      m,
 		done:         make(chan struct{}),
 		tx_chan:      tx_chan,
+		flushReq:     make(chan chan struct{}),
+		pacer:        newPacer(),
 		connRX:       make(chan []byte, 16),
 		connTX:       make(chan []byte, 16),
-		keepAlive:    5 * time.Second,
-		timeout:      15 * time.Second,
-		translator:   translator,
+		keepAlive:    cfg.KeepAliveInterval(),
+		timeout:      cfg.SessionTimeout(),
 		inboundTSMgr: timeslot.NewManager(),
+		radioChecks:  expiry.New[radioCheckKey, struct{}](),
+		txStreams:    expiry.New[uint, uint8](),
+		reloginReq:   make(chan struct{}, 1),
+
+		masters:          newMasterSet(cfg.MasterServers()),
+		failbackInterval: defaultFailbackInterval,
+		minHold:          defaultMinHold,
+		nakThreshold:     defaultNAKThreshold,
+		probeTimeout:     defaultProbeTimeout,
+		maxMissedPongs:   defaultMaxMissedPongs,
+	}
+	if cfg.MaxMissedPongs > 0 {
+		c.maxMissedPongs = uint32(cfg.MaxMissedPongs) //nolint:gosec // Small config value
+	}
+	if cfg.Failover.FailbackInterval > 0 {
+		c.failbackInterval = time.Duration(cfg.Failover.FailbackInterval) * time.Second
+	}
+	if cfg.Failover.MinHold > 0 {
+		c.minHold = time.Duration(cfg.Failover.MinHold) * time.Second
+	}
+	if cfg.Failover.NAKThreshold > 0 {
+		c.nakThreshold = int(cfg.Failover.NAKThreshold) //nolint:gosec // Small config value
+	}
+	c.swapSlots = opts.SwapSlots
+	if translator != nil {
+		translator.SetColorCode(cfg.ColorCode)
+		opts.apply(translator)
+		c.translator = translator
 	}
 	c.state.Store(uint32(STATE_IDLE))
 	c.buildRewriteRules()
@@ -106,95 +299,109 @@ func (h *MMDVMClient) Name() string {
 	return h.cfg.Name
 }
 
-// buildRewriteRules constructs the rewrite rule chains from config.
-// For each TGRewrite config entry, two rules are created:
-//   - rfRewrite: fromSlot/fromTG → toSlot/toTG (for RF→Net direction)
-//   - netRewrite: toSlot/toTG → fromSlot/fromTG (reverse, for Net→RF direction)
-//
-// PCRewrite only creates an RF rewrite (outbound).
-// TypeRewrite only creates an RF rewrite (outbound).
-// SrcRewrite only creates a Net rewrite (inbound).
-func (h *MMDVMClient) buildRewriteRules() {
-	name := h.cfg.Name
+// SetTranslator replaces the translator the client was created with. The
+// TranslatorOptions the client was created with configured only that
+// translator, so t must be configured directly; only swapping the slots
+// applies to the client's own rewrite rules and timeslot arbitration. It
+// must be called before Start.
+func (h *MMDVMClient) SetTranslator(t ipsc.Translator) {
+	h.translator = t
+}
 
-	for _, cfg := range h.cfg.TGRewrites {
-		rng := cfg.Range
-		if rng == 0 {
-			rng = 1
-		}
-		h.rfRewrites = append(h.rfRewrites, &rewrite.TGRewrite{
-			Name: name, FromSlot: cfg.FromSlot, FromTG: cfg.FromTG,
-			ToSlot: cfg.ToSlot, ToTG: cfg.ToTG, Range: rng,
-		})
-		// Reverse direction
-		h.netRewrites = append(h.netRewrites, &rewrite.TGRewrite{
-			Name: name, FromSlot: cfg.ToSlot, FromTG: cfg.ToTG,
-			ToSlot: cfg.FromSlot, ToTG: cfg.FromTG, Range: rng,
-		})
-	}
+// ipscTranslator returns the client's translator if it is the IPSC one,
+// or nil.
+func (h *MMDVMClient) ipscTranslator() *ipsc.IPSCTranslator {
+	t, _ := h.translator.(*ipsc.IPSCTranslator)
+	return t
+}
 
-	for _, cfg := range h.cfg.PCRewrites {
-		rng := cfg.Range
-		if rng == 0 {
-			rng = 1
-		}
-		h.rfRewrites = append(h.rfRewrites, &rewrite.PCRewrite{
-			Name: name, FromSlot: cfg.FromSlot, FromID: cfg.FromID,
-			ToSlot: cfg.ToSlot, ToID: cfg.ToID, Range: rng,
-		})
+// RecentCallControls returns the call-control IDs this client's translator
+// has recently allocated, oldest first, for persisting across restarts.
+func (h *MMDVMClient) RecentCallControls() []uint32 {
+	t := h.ipscTranslator()
+	if t == nil {
+		return nil
 	}
+	return t.RecentCallControls()
+}
 
-	for _, cfg := range h.cfg.TypeRewrites {
-		rng := cfg.Range
-		if rng == 0 {
-			rng = 1
-		}
-		h.rfRewrites = append(h.rfRewrites, &rewrite.TypeRewrite{
-			Name: name, FromSlot: cfg.FromSlot, FromTG: cfg.FromTG,
-			ToSlot: cfg.ToSlot, ToID: cfg.ToID, Range: rng,
-		})
+// SeedCallControls restores call-control IDs saved by a previous run.
+func (h *MMDVMClient) SeedCallControls(ids []uint32) {
+	if t := h.ipscTranslator(); t != nil {
+		t.SeedCallControls(ids)
 	}
+}
 
-	for _, cfg := range h.cfg.SrcRewrites {
-		rng := cfg.Range
-		if rng == 0 {
-			rng = 1
-		}
-		h.netRewrites = append(h.netRewrites, &rewrite.SrcRewrite{
-			Name: name, FromSlot: cfg.FromSlot, FromID: cfg.FromID,
-			ToSlot: cfg.ToSlot, ToID: cfg.ToID, Range: rng,
-		})
+// ReverseStreams returns the calls from IPSC this client's translator has
+// in progress, for persisting across restarts.
+func (h *MMDVMClient) ReverseStreams() []ipsc.ReverseStream {
+	t := h.ipscTranslator()
+	if t == nil {
+		return nil
 	}
+	return t.ReverseStreams()
+}
 
-	for _, slot := range h.cfg.PassAllTG {
-		if slot < 0 {
-			continue
-		}
-		s := uint(slot) //nolint:gosec
-		r := &rewrite.PassAllTG{Name: name, Slot: s}
-		h.passallRewrites = append(h.passallRewrites, r)
-		h.netRewrites = append(h.netRewrites, &rewrite.PassAllTG{Name: name, Slot: s})
-	}
-	for _, slot := range h.cfg.PassAllPC {
-		if slot < 0 {
-			continue
-		}
-		s := uint(slot) //nolint:gosec
-		r := &rewrite.PassAllPC{Name: name, Slot: s}
-		h.passallRewrites = append(h.passallRewrites, r)
-		h.netRewrites = append(h.netRewrites, &rewrite.PassAllPC{Name: name, Slot: s})
+// SeedReverseStreams restores calls from IPSC saved by a previous run.
+func (h *MMDVMClient) SeedReverseStreams(streams []ipsc.ReverseStream) {
+	if t := h.ipscTranslator(); t != nil {
+		t.SeedReverseStreams(streams)
 	}
 }
 
+// buildRewriteRules constructs the rewrite rule chains from config.
+func (h *MMDVMClient) buildRewriteRules() {
+	rules := rewrite.NewSet(h.cfg.Name, rewrite.Config{
+		TGRewrites:   h.cfg.TGRewrites,
+		PCRewrites:   h.cfg.PCRewrites,
+		TypeRewrites: h.cfg.TypeRewrites,
+		SrcRewrites:  h.cfg.SrcRewrites,
+		PassAllTG:    h.cfg.PassAllTG,
+		PassAllPC:    h.cfg.PassAllPC,
+	})
+	h.rfRewrites = rules.RF
+	h.netRewrites = rules.Net
+	h.passallRewrites = rules.PassAll
+}
+
+// Start connects to the active master and begins the login handshake.
+// Calling Start on a running client does nothing; a stopped client can be
+// started again.
 func (h *MMDVMClient) Start() error {
+	h.lifecycleMu.Lock()
+	defer h.lifecycleMu.Unlock()
+	if h.started.Load() {
+		return nil
+	}
+	if h.State() == STATE_CLOSED {
+		return ErrClosed
+	}
+	select {
+	case <-h.done:
+		// Stopped before. The old goroutines have exited, so the client
+		// can be reset for a fresh session.
+		h.doneMu.Lock()
+		h.done = make(chan struct{})
+		h.doneMu.Unlock()
+		h.stopOnce = sync.Once{}
+	default:
+	}
+
+	// Credentials changed while stopped are used from the start.
+	select {
+	case <-h.reloginReq:
+	default:
+	}
+	h.applyCredentials()
 	if h.translator != nil {
-		h.translator.SetPeerID(h.cfg.ID)
+		h.translator.SetPeerID(h.radioID())
 	}
 
 	slog.Info("Connecting to MMDVM server", "network", h.cfg.Name)
 
 	if h.metrics != nil {
 		h.metrics.MMDVMConnectionState.WithLabelValues(h.cfg.Name).Set(1)
+		h.metrics.MMDVMActiveMaster.WithLabelValues(h.cfg.Name, h.ActiveMaster()).Set(1)
 	}
 
 	err := h.connect()
@@ -204,26 +411,44 @@ func (h *MMDVMClient) Start() error {
 
 	h.started.Store(true)
 
-	h.wg.Add(5)
+	h.wg.Add(4)
 	go h.handler()
 	go h.rx()
 	go h.tx()
 	go h.forwardTX()
-	go h.handshakeWatchdog()
+	h.startHandshakeWatchdog()
+	if h.canFailover() {
+		h.wg.Add(1)
+		go h.failback()
+	}
 
-	h.state.Store(uint32(STATE_SENT_LOGIN))
+	h.rejections = 0
+	h.loginSent.Store(time.Now().UnixNano())
+	h.setState(STATE_SENT_LOGIN, "start")
 	h.sendLogin()
 
 	return nil
 }
 
 func (h *MMDVMClient) connect() error {
-	var err error
-	var d net.Dialer
-	conn, err := d.DialContext(context.Background(), "udp", h.cfg.MasterServer)
+	local, err := h.cfg.LocalAddr()
+	if err != nil {
+		return err
+	}
+	master := h.ActiveMaster()
+	addr, err := h.resolveMaster(master)
+	if err != nil {
+		return err
+	}
+	slog.Info("Dialing MMDVM master", "network", h.cfg.Name, "master", master, "address", addr)
+	conn, err := h.dialer()(local, addr)
 	if err != nil {
 		return err
 	}
+	if err := dscp.Mark(h.dscpSetter, conn, h.cfg.DSCP); err != nil {
+		conn.Close()
+		return err
+	}
 	h.connMu.Lock()
 	h.conn = conn
 	h.connMu.Unlock()
@@ -232,8 +457,37 @@ func (h *MMDVMClient) connect() error {
 
 const rptAck = "RPTACK"
 
+// SetPanicHandler registers fn to be called when one of the client's
+// goroutines panics, instead of crashing the process. fn must not block;
+// the client is left for the caller to stop and restart. It must be
+// called before Start.
+func (h *MMDVMClient) SetPanicHandler(fn func(recovered any, stack []byte)) {
+	h.panicHandler = fn
+}
+
+// recoverPanic reports a panic to the panic handler. It must be deferred
+// directly. Without a handler the panic propagates as usual.
+func (h *MMDVMClient) recoverPanic() {
+	if h.panicHandler == nil {
+		return
+	}
+	if r := recover(); r != nil {
+		h.panicHandler(r, debug.Stack())
+	}
+}
+
+// doneChan returns the channel closed by Stop. Callers outside the
+// client's own goroutines must use it instead of reading done directly,
+// since Start replaces the channel on a restart.
+func (h *MMDVMClient) doneChan() <-chan struct{} {
+	h.doneMu.Lock()
+	defer h.doneMu.Unlock()
+	return h.done
+}
+
 func (h *MMDVMClient) handler() {
 	defer h.wg.Done()
+	defer h.recoverPanic()
 	for {
 		select {
 		case data := <-h.connRX:
@@ -243,6 +497,8 @@ func (h *MMDVMClient) handler() {
 				continue
 			}
 			h.handleState(data)
+		case <-h.reloginReq:
+			h.relogin()
 		case <-h.done:
 			return
 		}
@@ -251,6 +507,15 @@ func (h *MMDVMClient) handler() {
 
 func (h *MMDVMClient) handleState(data []byte) {
 	currentState := h.state.Load()
+	if isMasterClosing(data, h.radioID()) {
+		switch currentState {
+		case uint32(STATE_SENT_LOGIN), uint32(STATE_SENT_AUTH), uint32(STATE_SENT_RPTC), uint32(STATE_READY):
+			h.handleMasterClosing()
+		default:
+			slog.Debug("Ignoring MSTCL from MMDVM server while not connected", "network", h.cfg.Name)
+		}
+		return
+	}
 	switch currentState {
 	case uint32(STATE_IDLE):
 		slog.Info("Got data from MMDVM server while idle", "network", h.cfg.Name)
@@ -264,6 +529,8 @@ func (h *MMDVMClient) handleState(data []byte) {
 		h.handleReady(data)
 	case uint32(STATE_TIMEOUT):
 		slog.Info("Got data from MMDVM server while in timeout state", "network", h.cfg.Name)
+	case uint32(STATE_AUTH_FAILED):
+		slog.Debug("Ignoring data from MMDVM server after giving up", "network", h.cfg.Name)
 	}
 }
 
@@ -276,26 +543,32 @@ func (h *MMDVMClient) handleSentLogin(data []byte) {
 		slog.Info("Connected. Authenticating", "network", h.cfg.Name)
 		random := data[len(data)-4:]
 		h.sendRPTK(random)
-		h.state.Store(uint32(STATE_SENT_AUTH))
-	} else {
+		h.setState(STATE_SENT_AUTH, "login-accepted")
+	} else if isNAK(data) {
 		slog.Info("Server rejected login request", "network", h.cfg.Name)
-		time.Sleep(1 * time.Second)
+		if h.handshakeRejected(ErrLoginRejected) {
+			return
+		}
 		h.sendLogin()
+	} else {
+		slog.Debug("Ignoring packet while logging in", "network", h.cfg.Name, "data", data)
 	}
 }
 
 func (h *MMDVMClient) handleSentAuth(data []byte) {
 	if len(data) >= 6 && string(data[:6]) == rptAck {
 		slog.Info("Authenticated. Sending configuration", "network", h.cfg.Name)
-		h.state.Store(uint32(STATE_SENT_RPTC))
+		h.setState(STATE_SENT_RPTC, "authenticated")
 		h.sendRPTC()
-	} else if len(data) >= 6 && string(data[:6]) == "RPTNAK" {
+	} else if isNAK(data) {
 		slog.Info("Password rejected", "network", h.cfg.Name)
 		if h.metrics != nil {
 			h.metrics.MMDVMAuthFailures.WithLabelValues(h.cfg.Name).Inc()
 		}
-		h.state.Store(uint32(STATE_SENT_LOGIN))
-		time.Sleep(1 * time.Second)
+		if h.handshakeRejected(ErrPasswordRejected) {
+			return
+		}
+		h.setState(STATE_SENT_LOGIN, "password-rejected")
 		h.sendLogin()
 	}
 }
@@ -303,15 +576,31 @@ func (h *MMDVMClient) handleSentAuth(data []byte) {
 func (h *MMDVMClient) handleSentRPTC(data []byte) {
 	if len(data) >= 6 && string(data[:6]) == rptAck {
 		slog.Info("Config accepted, starting ping routine", "network", h.cfg.Name)
-		h.state.Store(uint32(STATE_READY))
+		h.setState(STATE_READY, "config-accepted")
+		h.rejections = 0
+		h.pongPending.Store(false)
+		h.missedPongs.Store(0)
+		// Every session needs the options again, so they are sent on
+		// each reconnect too.
+		h.sendRPTO()
+		if h.masters != nil {
+			h.masters.resetNAKs()
+		}
 		if h.metrics != nil {
 			h.metrics.MMDVMConnectionState.WithLabelValues(h.cfg.Name).Set(2)
 		}
-		h.wg.Add(1)
-		go h.ping()
-	} else if len(data) >= 6 && string(data[:6]) == "MSTNAK" {
+		// After a switchover the previous ping routine may still be
+		// running; it picks up the new session instead of starting twice.
+		h.lastPing.Store(h.clock().UnixNano())
+		if h.pingRunning.CompareAndSwap(false, true) {
+			h.wg.Add(1)
+			go h.ping()
+		}
+	} else if isNAK(data) {
 		slog.Info("Configuration rejected", "network", h.cfg.Name)
-		time.Sleep(1 * time.Second)
+		if h.handshakeRejected(ErrConfigRejected) {
+			return
+		}
 		h.sendRPTC()
 	}
 }
@@ -320,15 +609,28 @@ func (h *MMDVMClient) handleReady(data []byte) {
 	switch string(data[:4]) {
 	case "MSTP":
 		if len(data) >= 7 && string(data[:7]) == "MSTPONG" {
-			now := time.Now()
-			if h.metrics != nil {
-				sent := time.Unix(0, h.lastPingSent.Load())
-				if !sent.IsZero() {
-					h.metrics.MMDVMPingRTT.WithLabelValues(h.cfg.Name).Observe(now.Sub(sent).Seconds())
-				}
+			now := h.clock()
+			// A pong with no ping awaiting it is late or repeated, and
+			// says nothing about the round trip.
+			if h.pongPending.CompareAndSwap(true, false) {
+				h.recordRTT(now.Sub(time.Unix(0, h.lastPingSent.Load())))
 			}
+			h.missedPongs.Store(0)
 			h.lastPing.Store(now.UnixNano())
 		}
+	case "RPTA":
+		// The master acknowledges the options.
+		slog.Debug("MMDVM master acknowledged", "network", h.cfg.Name)
+	case "MSTN":
+		if isNAK(data) {
+			// The master no longer knows the session, or has banned
+			// the radio ID; logging in again tells which.
+			slog.Warn("MMDVM master ended the session, reconnecting", "network", h.cfg.Name)
+			h.setLastFailure(ErrSessionRejected)
+			if !h.handleNAK() {
+				h.reconnect("nak")
+			}
+		}
 	case "RPTS":
 		if len(data) >= 7 && string(data[:7]) == "RPTSBKN" {
 			slog.Info("Server requested a roaming beacon transmission", "network", h.cfg.Name)
@@ -339,13 +641,19 @@ func (h *MMDVMClient) handleReady(data []byte) {
 			slog.Info("Error unpacking packet", "network", h.cfg.Name)
 			return
 		}
+		h.packetsReceived.Add(1)
 		if h.metrics != nil {
 			h.metrics.MMDVMPacketsReceived.WithLabelValues(h.cfg.Name).Inc()
 		}
 		slog.Debug("MMDVM DMRD received", "network", h.cfg.Name, "packet", packet)
 
+		if h.handleSpecialID(packet) {
+			return
+		}
+
 		if !rewrite.Apply(h.netRewrites, &packet) {
 			slog.Debug("MMDVM DMRD dropped (no rewrite rule matched)", "network", h.cfg.Name)
+			h.packetsDropped.Add(1)
 			if h.metrics != nil {
 				h.metrics.MMDVMPacketsDropped.WithLabelValues(h.cfg.Name, "no_rewrite").Inc()
 			}
@@ -354,42 +662,72 @@ func (h *MMDVMClient) handleReady(data []byte) {
 
 		slog.Debug("MMDVM DMRD after rewrite", "network", h.cfg.Name, "packet", packet)
 
-		// Timeslot arbitration: buffer competing calls, deliver FIFO.
-		isTerminator := packet.FrameType == frameTypeDataSync && packet.DTypeOrVSeq == dtypeTerminatorWithLC
-		if h.outboundTSMgr != nil {
-			if !h.outboundTSMgr.Submit(packet.Slot, packet.StreamID, h.cfg.Name, packet) {
-				slog.Debug("MMDVM DMRD buffered (timeslot busy)",
-					"network", h.cfg.Name, "slot", packet.Slot, "streamID", packet.StreamID)
-				if h.metrics != nil {
-					h.metrics.MMDVMPacketsDropped.WithLabelValues(h.cfg.Name, "timeslot_busy").Inc()
-				}
-				return
+		h.deliverToIPSC(packet)
+	default:
+		slog.Info("Got unknown packet from MMDVM server", "network", h.cfg.Name, "data", data)
+	}
+}
+
+// deliverToIPSC arbitrates the timeslot for a rewritten packet from the
+// master and forwards it toward IPSC, releasing any buffered calls once
+// the stream terminates.
+func (h *MMDVMClient) deliverToIPSC(packet proto.Packet) {
+	if packet.FrameType == frameTypeReverseChan {
+		// An interrupt belongs to a call already holding the slot. It
+		// neither claims the slot nor counts as a call of its own.
+		h.translateAndForwardToIPSC(packet)
+		return
+	}
+	if !h.admitLoopFree(loopdetect.ToIPSC, packet) {
+		slog.Debug("MMDVM DMRD dropped (call loop)", "network", h.cfg.Name, "streamID", packet.StreamID)
+		return
+	}
+	// Timeslot arbitration: buffer competing calls, deliver FIFO.
+	isTerminator := packet.FrameType == frameTypeDataSync && packet.DTypeOrVSeq == dtypeTerminatorWithLC
+	if h.outboundTSMgr != nil {
+		if !h.outboundTSMgr.Submit(packet.Slot, packet.StreamID, h.cfg.Name, packet) {
+			slog.Debug("MMDVM DMRD buffered (timeslot busy)",
+				"network", h.cfg.Name, "slot", packet.Slot, "streamID", packet.StreamID)
+			h.packetsDropped.Add(1)
+			if h.metrics != nil {
+				h.metrics.MMDVMPacketsDropped.WithLabelValues(h.cfg.Name, "timeslot_busy").Inc()
 			}
+			return
 		}
+	}
 
-		h.translateAndForwardToIPSC(packet)
+	h.trackStream(packet, isTerminator)
+	h.translateAndForwardToIPSC(packet)
 
-		if isTerminator && h.outboundTSMgr != nil {
-			h.drainPendingOutbound(packet.Slot, packet.StreamID)
-		}
-	default:
-		slog.Info("Got unknown packet from MMDVM server", "network", h.cfg.Name, "data", data)
+	if isTerminator && h.outboundTSMgr != nil {
+		h.drainPendingOutbound(packet.Slot, packet.StreamID)
 	}
 }
 
 func (h *MMDVMClient) ping() {
 	defer h.wg.Done()
-	ticker := time.NewTicker(h.keepAlive)
-	defer ticker.Stop()
+	defer h.recoverPanic()
+	defer h.pingRunning.Store(false)
+	ticks, stop := h.startPingTicker()
+	defer stop()
 	h.sendPing()
-	h.lastPing.Store(time.Now().UnixNano())
+	h.lastPing.Store(h.clock().UnixNano())
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticks:
+			if State(h.state.Load()&0xFF) != STATE_READY { //nolint:gosec
+				// A switchover is re-running the handshake; pings
+				// resume once the new session is ready.
+				continue
+			}
 			lastPingTime := time.Unix(0, h.lastPing.Load())
-			if time.Now().After(lastPingTime.Add(h.timeout)) {
+			if h.clock().After(lastPingTime.Add(h.timeout)) {
 				slog.Info("Connection timed out", "network", h.cfg.Name)
-				h.reconnect()
+				h.handleConnectionLoss()
+				return
+			}
+			if h.pongPending.Load() && h.pongMissed() {
+				h.handleConnectionLoss()
 				return
 			}
 			h.sendPing()
@@ -399,25 +737,53 @@ func (h *MMDVMClient) ping() {
 	}
 }
 
+// startHandshakeWatchdog starts handshakeWatchdog unless one is
+// already watching the current handshake.
+func (h *MMDVMClient) startHandshakeWatchdog() {
+	select {
+	case <-h.done:
+		return
+	default:
+	}
+	if h.watchdogRunning.CompareAndSwap(false, true) {
+		h.wg.Add(1)
+		go h.handshakeWatchdog()
+	}
+}
+
 // handshakeWatchdog monitors the login/auth/config handshake and
-// triggers a reconnect if the client doesn't reach STATE_READY
-// within the timeout period. Once STATE_READY is reached the ping()
-// goroutine takes over liveness monitoring.
+// triggers a reconnect (or failover) if the client doesn't reach
+// STATE_READY within the timeout period. Once STATE_READY is reached
+// the ping() goroutine takes over liveness monitoring.
 func (h *MMDVMClient) handshakeWatchdog() {
 	defer h.wg.Done()
-	ticker := time.NewTicker(h.timeout)
-	defer ticker.Stop()
+	defer h.recoverPanic()
+	defer h.watchdogRunning.Store(false)
+	timer := time.NewTimer(h.timeout)
+	defer timer.Stop()
 	for {
 		select {
-		case <-ticker.C:
-			st := state(h.state.Load() & 0xFF) //nolint:gosec
+		case <-timer.C:
+			st := State(h.state.Load() & 0xFF) //nolint:gosec
 			if st == STATE_READY {
 				// Handshake completed, ping() is now responsible.
 				return
 			}
+			if st == STATE_AUTH_FAILED {
+				// The client gave up; only a restart tries again.
+				return
+			}
+			// A switchover may have restarted the handshake since
+			// the timer was set; give the new one its full timeout.
+			loginSent := time.Unix(0, h.loginSent.Load())
+			if wait := time.Until(loginSent.Add(h.timeout)); wait > 0 {
+				timer.Reset(wait)
+				continue
+			}
 			slog.Warn("Handshake timed out, reconnecting", "network", h.cfg.Name, "state", st)
-			h.reconnect()
+			h.handleConnectionLoss()
 			// Stay in the loop to watch the next handshake attempt.
+			timer.Reset(h.timeout)
 		case <-h.done:
 			return
 		}
@@ -425,9 +791,10 @@ func (h *MMDVMClient) handshakeWatchdog() {
 }
 
 // reconnect closes the current connection, dials a new one, and
-// sends a fresh login. It is safe to call from any goroutine.
-func (h *MMDVMClient) reconnect() {
-	h.state.Store(uint32(STATE_TIMEOUT))
+// sends a fresh login. reason is reported with the state changes. It is
+// safe to call from any goroutine.
+func (h *MMDVMClient) reconnect(reason string) {
+	h.setState(STATE_TIMEOUT, reason)
 	if h.metrics != nil {
 		h.metrics.MMDVMConnectionState.WithLabelValues(h.cfg.Name).Set(0)
 		h.metrics.MMDVMReconnects.WithLabelValues(h.cfg.Name).Inc()
@@ -442,22 +809,21 @@ func (h *MMDVMClient) reconnect() {
 	if err := h.connect(); err != nil {
 		slog.Error("Error reconnecting to MMDVM server", "network", h.cfg.Name, "error", err)
 	}
-	h.state.Store(uint32(STATE_SENT_LOGIN))
+	h.loginSent.Store(time.Now().UnixNano())
+	h.setState(STATE_SENT_LOGIN, reason)
 	h.sendLogin()
 }
 
 func (h *MMDVMClient) tx() {
 	defer h.wg.Done()
+	defer h.recoverPanic()
 	for {
 		select {
 		case <-h.done:
 			return
 		case data := <-h.connTX:
-			h.connMu.Lock()
 			slog.Debug("sending packet", "data", fmt.Sprintf("% X", data), "strdata", string(data), "network", h.cfg.Name)
-			_, err := h.conn.Write(data)
-			h.connMu.Unlock()
-			if err != nil {
+			if err := h.write(data); err != nil {
 				if errors.Is(err, net.ErrClosed) {
 					// Connection was closed by reconnect();
 					// re-queue the data so it is sent on the
@@ -466,6 +832,7 @@ func (h *MMDVMClient) tx() {
 					case h.connTX <- data:
 					default:
 						slog.Warn("connTX full, dropping packet during reconnect", "network", h.cfg.Name)
+						h.txPending.Add(-1)
 					}
 					select {
 					case <-time.After(100 * time.Millisecond):
@@ -475,14 +842,24 @@ func (h *MMDVMClient) tx() {
 					}
 				}
 				slog.Error("Error writing to MMDVM server", "network", h.cfg.Name, "error", err)
-				continue
 			}
+			h.txPending.Add(-1)
 		}
 	}
 }
 
+// write sends data on the current connection. connMu is released even
+// if the write panics, so a recovered panic in tx() cannot block Stop.
+func (h *MMDVMClient) write(data []byte) error {
+	h.connMu.Lock()
+	defer h.connMu.Unlock()
+	_, err := h.conn.Write(data)
+	return err
+}
+
 func (h *MMDVMClient) rx() {
 	defer h.wg.Done()
+	defer h.recoverPanic()
 	for {
 		h.connMu.Lock()
 		conn := h.conn
@@ -517,12 +894,27 @@ func (h *MMDVMClient) rx() {
 	}
 }
 
+// Stop logs out from the master and waits for the client's goroutines to
+// exit. It is safe to call more than once.
 func (h *MMDVMClient) Stop() {
+	h.lifecycleMu.Lock()
+	defer h.lifecycleMu.Unlock()
+	h.stop()
+	// Wait for all goroutines to finish.
+	h.wg.Wait()
+}
+
+// stop signals the client's goroutines to exit and logs out from the
+// master. The disconnect is the last thing written: the socket is closed
+// under the same lock. Must be called with lifecycleMu held.
+func (h *MMDVMClient) stop() {
 	h.stopOnce.Do(func() {
 		slog.Info("Stopping MMDVM client", "network", h.cfg.Name)
 
 		// Signal all goroutines to stop.
+		h.doneMu.Lock()
 		close(h.done)
+		h.doneMu.Unlock()
 
 		// Send the disconnect message directly on the wire (best-effort).
 		h.connMu.Lock()
@@ -534,16 +926,13 @@ func (h *MMDVMClient) Stop() {
 
 		h.started.Store(false)
 	})
-
-	// Wait for all goroutines to finish.
-	h.wg.Wait()
 }
 
 // sendRPTCLDirect writes the disconnect message directly on the connection.
 // Must be called with connMu held.
 func (h *MMDVMClient) sendRPTCLDirect() {
 	hexid := make([]byte, 8)
-	copy(hexid, []byte(fmt.Sprintf("%08x", h.cfg.ID)))
+	copy(hexid, []byte(fmt.Sprintf("%08x", h.radioID())))
 	data := make([]byte, len("RPTCL")+8)
 	n := copy(data, "RPTCL")
 	copy(data[n:], hexid)
@@ -554,21 +943,80 @@ func (h *MMDVMClient) sendRPTCLDirect() {
 
 func (h *MMDVMClient) forwardTX() {
 	defer h.wg.Done()
+	defer h.recoverPanic()
+	if h.pacer != nil {
+		// Frames still queued belong to a session that is over.
+		defer h.pacer.reset()
+	}
+	// Close waits on these until everything it queued has been sent.
+	var flushed []chan struct{}
 	for {
+		var due <-chan time.Time
+		paced := false
+		if h.pacer != nil {
+			var wait time.Duration
+			if wait, paced = h.pacer.wait(time.Now()); paced {
+				due = time.After(wait)
+			}
+		}
+		if len(flushed) > 0 && !paced && len(h.tx_chan) == 0 {
+			for _, ack := range flushed {
+				close(ack)
+			}
+			flushed = nil
+		}
 		select {
 		case <-h.done:
 			return
+		case ack := <-h.flushReq:
+			flushed = append(flushed, ack)
 		case pkt := <-h.tx_chan:
-			h.sendPacket(pkt)
+			// Numbered before pacing, so frames the pacer drops leave
+			// the gap in Seq the master detects loss from.
+			pkt = h.numberTX(pkt)
+			if h.pacer == nil || !isVoiceFrame(pkt) {
+				h.sendPacket(pkt)
+				continue
+			}
+			if dropped, overrun := h.pacer.push(pkt); overrun {
+				slog.Debug("MMDVM DMRD dropped (pacing queue full)", "network", h.cfg.Name, "streamID", dropped.StreamID)
+				h.packetsDropped.Add(1)
+				if h.metrics != nil {
+					h.metrics.MMDVMPacketsDropped.WithLabelValues(h.cfg.Name, "pacing_overrun").Inc()
+				}
+			}
+			h.sendPaced()
+		case <-due:
+			h.sendPaced()
 		}
 	}
 }
 
+// sendPaced sends the voice frames the pacer has due.
+func (h *MMDVMClient) sendPaced() {
+	for _, pkt := range h.pacer.release(time.Now()) {
+		h.sendPacket(pkt)
+	}
+}
+
+// SetPacing turns on or off pacing voice to the master at one frame per
+// 60 ms on each slot, which is on by default. With it off, as tests and
+// load generators may want, frames are sent as soon as they are
+// translated. It must be called before Start.
+func (h *MMDVMClient) SetPacing(enabled bool) {
+	if !enabled {
+		h.pacer = nil
+	} else if h.pacer == nil {
+		h.pacer = newPacer()
+	}
+}
+
 // translateAndForwardToIPSC converts a proto.Packet to IPSC and sends it.
 func (h *MMDVMClient) translateAndForwardToIPSC(packet proto.Packet) {
 	if h.ipscHandler != nil && h.translator != nil {
 		ipscPackets := h.translator.TranslateToIPSC(packet)
 		for _, ipscData := range ipscPackets {
+			h.notePendingRadioCheck(ipscData)
 			h.ipscHandler(ipscData)
 		}
 	}
@@ -591,8 +1039,10 @@ func (h *MMDVMClient) drainPendingOutbound(slot bool, streamID uint) {
 			if !ok {
 				continue
 			}
+			isTerminator := pkt.FrameType == frameTypeDataSync && pkt.DTypeOrVSeq == dtypeTerminatorWithLC
+			h.trackStream(pkt, isTerminator)
 			h.translateAndForwardToIPSC(pkt)
-			if pkt.FrameType == frameTypeDataSync && pkt.DTypeOrVSeq == dtypeTerminatorWithLC {
+			if isTerminator {
 				hasTerminator = true
 				nextStreamID = pkt.StreamID
 			}
@@ -606,8 +1056,8 @@ func (h *MMDVMClient) drainPendingOutbound(slot bool, streamID uint) {
 
 // drainPendingInbound delivers buffered pending calls on the given slot
 // after the active stream terminates (IPSC→MMDVM direction). Returns
-// false if the done channel was signaled.
-func (h *MMDVMClient) drainPendingInbound(slot bool, streamID uint) bool {
+// false if done was signaled.
+func (h *MMDVMClient) drainPendingInbound(done <-chan struct{}, slot bool, streamID uint) bool {
 	currentStreamID := streamID
 	for {
 		buffered := h.inboundTSMgr.Release(slot, currentStreamID)
@@ -623,7 +1073,7 @@ func (h *MMDVMClient) drainPendingInbound(slot bool, streamID uint) bool {
 			}
 			select {
 			case h.tx_chan <- pkt:
-			case <-h.done:
+			case <-done:
 				return false
 			}
 			if pkt.FrameType == frameTypeDataSync && pkt.DTypeOrVSeq == dtypeTerminatorWithLC {
@@ -649,6 +1099,24 @@ func (h *MMDVMClient) SetOutboundTSManager(mgr *timeslot.Manager) {
 	h.outboundTSMgr = mgr
 }
 
+// SetLoopDetector sets the loop detector shared by all clients.
+func (h *MMDVMClient) SetLoopDetector(d *loopdetect.Detector) {
+	h.loops = d
+}
+
+// admitLoopFree reports whether pkt may be carried in direction without
+// closing a loop, counting it as dropped otherwise.
+func (h *MMDVMClient) admitLoopFree(direction loopdetect.Direction, pkt proto.Packet) bool {
+	if h.loops == nil || h.loops.Admit(direction, h.cfg.Name, pkt) {
+		return true
+	}
+	h.packetsDropped.Add(1)
+	if h.metrics != nil {
+		h.metrics.MMDVMPacketsDropped.WithLabelValues(h.cfg.Name, "loop").Inc()
+	}
+	return false
+}
+
 // MatchesRules checks whether the given IPSC data would match this client's
 // rewrite rules without translating or modifying any state. It extracts
 // routing-relevant fields (src, dst, groupCall, slot) directly from the
@@ -664,6 +1132,9 @@ func (h *MMDVMClient) MatchesRules(packetType byte, data []byte, passallOnly boo
 		GroupCall: packetType == 0x80 || packetType == 0x83,
 		Slot:      (data[17] & 0x20) != 0,
 	}
+	if h.swapSlots {
+		probe.Slot = !probe.Slot
+	}
 	if passallOnly {
 		return rewrite.Apply(h.passallRewrites, &probe)
 	}
@@ -679,18 +1150,62 @@ func (h *MMDVMClient) HandleIPSCBurst(packetType byte, data []byte, addr *net.UD
 	if !h.started.Load() {
 		return false
 	}
+	done := h.doneChan()
 	slog.Debug("HandleIPSCBurst: received IPSC burst", "network", h.cfg.Name, "type", packetType, "from", addr, "length", len(data))
 
-	packets := h.translator.TranslateToMMDVM(packetType, data)
+	return h.forwardToMaster(done, h.translator.TranslateToMMDVM(packetType, data), false)
+}
+
+// HandleUnroutedIPSCBurst handles an IPSC burst that matched no network's
+// rewrite rules, for the network configured to take such traffic. Bursts
+// this network's own rules match are rewritten as usual; the rest are
+// passed to the master unchanged.
+func (h *MMDVMClient) HandleUnroutedIPSCBurst(packetType byte, data []byte, addr *net.UDPAddr) bool {
+	if !h.started.Load() {
+		return false
+	}
+	done := h.doneChan()
+	slog.Debug("HandleIPSCBurst: received unrouted IPSC burst", "network", h.cfg.Name, "type", packetType, "from", addr, "length", len(data))
+
+	return h.forwardToMaster(done, h.translator.TranslateToMMDVM(packetType, data), true)
+}
+
+// HandlePeerLost ends the calls the given IPSC peer was sending to this
+// master, which the peer can no longer end itself.
+func (h *MMDVMClient) HandlePeerLost(peerID uint32) {
+	if !h.started.Load() {
+		return
+	}
+	done := h.doneChan()
+	if h.translator == nil {
+		return
+	}
+	packets := h.translator.TerminatePeerStreams(peerID)
+	if len(packets) == 0 {
+		return
+	}
+	slog.Info("Ending calls of lost IPSC peer", "network", h.cfg.Name, "peerID", peerID, "calls", len(packets))
+	// The calls may have reached this master as unrouted traffic, so
+	// their terminators go out whether or not the rules match them.
+	h.forwardToMaster(done, packets, true)
+}
+
+// forwardToMaster rewrites and arbitrates packets translated from IPSC
+// and queues them for the master. Packets no rewrite rule matches are
+// dropped, or passed unchanged if unrouted is set. It reports whether any
+// were queued.
+func (h *MMDVMClient) forwardToMaster(done <-chan struct{}, packets []proto.Packet, unrouted bool) bool {
 	matched := false
 	for _, pkt := range packets {
 		slog.Debug("HandleIPSCBurst: pre-rewrite", "network", h.cfg.Name, "src", pkt.Src, "dst", pkt.Dst, "groupCall", pkt.GroupCall, "slot", pkt.Slot)
+		ipscSide := pkt
 		// Apply RF→Net rewrite rules (outbound to this master).
 		// Try specific rewrites first; if none match, try passall
 		// rules as a fallback.
 		if !rewrite.Apply(h.rfRewrites, &pkt) {
-			if !rewrite.Apply(h.passallRewrites, &pkt) {
+			if !rewrite.Apply(h.passallRewrites, &pkt) && !unrouted {
 				slog.Debug("HandleIPSCBurst: dropped (no rewrite rule matched)", "network", h.cfg.Name)
+				h.packetsDropped.Add(1)
 				if h.metrics != nil {
 					h.metrics.MMDVMPacketsDropped.WithLabelValues(h.cfg.Name, "no_rewrite").Inc()
 				}
@@ -699,12 +1214,31 @@ func (h *MMDVMClient) HandleIPSCBurst(packetType byte, data []byte, addr *net.UD
 		}
 		slog.Debug("HandleIPSCBurst: post-rewrite", "network", h.cfg.Name, "src", pkt.Src, "dst", pkt.Dst, "groupCall", pkt.GroupCall, "slot", pkt.Slot)
 
+		// An interrupt belongs to a call already holding the slot, so it
+		// skips loop detection and arbitration.
+		if pkt.FrameType == frameTypeReverseChan {
+			matched = true
+			select {
+			case h.tx_chan <- pkt:
+			case <-done:
+				return matched
+			}
+			continue
+		}
+
+		// Loop detection compares calls as they appear on the IPSC side.
+		if !h.admitLoopFree(loopdetect.ToMaster, ipscSide) {
+			slog.Debug("HandleIPSCBurst: dropped (call loop)", "network", h.cfg.Name, "streamID", pkt.StreamID)
+			continue
+		}
+
 		// Timeslot arbitration: buffer competing calls, deliver FIFO.
 		isTerminator := pkt.FrameType == frameTypeDataSync && pkt.DTypeOrVSeq == dtypeTerminatorWithLC
 		if h.inboundTSMgr != nil {
 			if !h.inboundTSMgr.Submit(pkt.Slot, pkt.StreamID, "ipsc", pkt) {
 				slog.Debug("HandleIPSCBurst: buffered (timeslot busy)",
 					"network", h.cfg.Name, "slot", pkt.Slot, "streamID", pkt.StreamID)
+				h.packetsDropped.Add(1)
 				if h.metrics != nil {
 					h.metrics.MMDVMPacketsDropped.WithLabelValues(h.cfg.Name, "timeslot_busy").Inc()
 				}
@@ -716,12 +1250,12 @@ func (h *MMDVMClient) HandleIPSCBurst(packetType byte, data []byte, addr *net.UD
 
 		select {
 		case h.tx_chan <- pkt:
-		case <-h.done:
+		case <-done:
 			return matched
 		}
 
 		if isTerminator && h.inboundTSMgr != nil {
-			if !h.drainPendingInbound(pkt.Slot, pkt.StreamID) {
+			if !h.drainPendingInbound(done, pkt.Slot, pkt.StreamID) {
 				return matched
 			}
 		}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"net"
 	"strings"
@@ -12,9 +13,17 @@ import (
 	"time"
 
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dmr/csbk"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/dscp"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/expiry"
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/ipsc"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/loopdetect"
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/rewrite"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/parrot"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/specialid"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/testutil"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/timeslot"
 )
 
 // Test protocol tag constants to avoid goconst warnings.
@@ -23,6 +32,7 @@ const (
 	tagRPTCL   = "RPTCL"
 	tagRPTC    = "RPTC"
 	tagRPTK    = "RPTK"
+	tagRPTO    = "RPTO"
 	tagRPTPING = "RPTPING"
 	tagDMRD    = "DMRD"
 )
@@ -56,12 +66,16 @@ func newTestClient(t *testing.T) *MMDVMClient {
 		t.Fatalf("NewIPSCTranslator: %v", err)
 	}
 	client := &MMDVMClient{
-		cfg:        cfg,
-		connTX:     make(chan []byte, 16),
-		connRX:     make(chan []byte, 16),
-		tx_chan:    make(chan proto.Packet, 16),
-		done:       make(chan struct{}),
-		translator: translator,
+		cfg:         cfg,
+		connTX:      make(chan []byte, 16),
+		connRX:      make(chan []byte, 16),
+		tx_chan:     make(chan proto.Packet, 16),
+		flushReq:    make(chan chan struct{}),
+		done:        make(chan struct{}),
+		translator:  translator,
+		radioChecks: expiry.New[radioCheckKey, struct{}](),
+		txStreams:   expiry.New[uint, uint8](),
+		reloginReq:  make(chan struct{}, 1),
 	}
 	client.state.Store(uint32(STATE_IDLE))
 	return client
@@ -92,7 +106,7 @@ func TestStateConstants(t *testing.T) {
 func TestNewMMDVMClient(t *testing.T) {
 	t.Parallel()
 	cfg := testMMDVMConfig()
-	client := NewMMDVMClient(cfg, nil)
+	client := NewMMDVMClient(cfg, nil, TranslatorOptions{})
 	if client == nil {
 		t.Fatal("expected non-nil client")
 	}
@@ -116,6 +130,20 @@ func TestNewMMDVMClient(t *testing.T) {
 	}
 }
 
+func TestNewMMDVMClientKeepAliveAndTimeout(t *testing.T) {
+	t.Parallel()
+	cfg := testMMDVMConfig()
+	cfg.KeepAlive = 30
+	cfg.Timeout = 90
+	client := NewMMDVMClient(cfg, nil, TranslatorOptions{})
+	if client.keepAlive != 30*time.Second {
+		t.Fatalf("expected 30s keepalive, got %v", client.keepAlive)
+	}
+	if client.timeout != 90*time.Second {
+		t.Fatalf("expected 90s timeout, got %v", client.timeout)
+	}
+}
+
 func TestSendLoginPacket(t *testing.T) {
 	t.Parallel()
 	client := newTestClient(t)
@@ -203,6 +231,63 @@ func TestSendRPTCPacket(t *testing.T) {
 	}
 }
 
+func TestSendRPTOPacket(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.cfg.Options = "TS1_1=9;TS2_1=3100"
+	client.sendRPTO()
+
+	data := <-client.connTX
+	if string(data[:4]) != tagRPTO {
+		t.Fatalf("expected RPTO prefix, got %q", string(data[:4]))
+	}
+	// Binary ID (big-endian uint32) at offset 4, as in RPTC
+	if gotID := binary.BigEndian.Uint32(data[4:8]); gotID != client.cfg.ID {
+		t.Fatalf("expected ID %d, got %d", client.cfg.ID, gotID)
+	}
+	if got := string(data[8:]); got != client.cfg.Options {
+		t.Fatalf("expected options %q, got %q", client.cfg.Options, got)
+	}
+}
+
+func TestSendRPTOEmptySendsNothing(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.sendRPTO()
+
+	select {
+	case data := <-client.connTX:
+		t.Fatalf("expected nothing sent, got %q", data)
+	default:
+	}
+}
+
+func TestHandlerSentRPTCAcceptedSendsOptions(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.cfg.Options = "TS2_1=91"
+	client.keepAlive = time.Hour
+	client.timeout = time.Hour
+	client.state.Store(uint32(STATE_SENT_RPTC))
+
+	client.wg.Add(1)
+	go client.handler()
+	defer func() {
+		close(client.done)
+		client.wg.Wait()
+	}()
+
+	client.connRX <- []byte("RPTACK__________")
+	select {
+	case data := <-client.connTX:
+		if string(data[:4]) != tagRPTO || string(data[8:]) != "TS2_1=91" {
+			t.Fatalf("expected RPTO with the options, got %q", data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for RPTO")
+	}
+}
+
 func TestSendRPTKPacket(t *testing.T) {
 	t.Parallel()
 	client := newTestClient(t)
@@ -363,7 +448,7 @@ func udpPair(t *testing.T) (*net.UDPConn, *MMDVMClient) {
 	cfg := testMMDVMConfig()
 	cfg.MasterServer = fmt.Sprintf("127.0.0.1:%d", srvAddr.Port)
 
-	client := NewMMDVMClient(cfg, nil)
+	client := NewMMDVMClient(cfg, nil, TranslatorOptions{})
 	if err := client.connect(); err != nil {
 		serverConn.Close()
 		t.Fatalf("connect: %v", err)
@@ -406,7 +491,7 @@ func TestConnectBadAddress(t *testing.T) {
 	t.Parallel()
 	cfg := testMMDVMConfig()
 	cfg.MasterServer = "this-is-not-a-valid-address:::::999999"
-	client := NewMMDVMClient(cfg, nil)
+	client := NewMMDVMClient(cfg, nil, TranslatorOptions{})
 
 	err := client.connect()
 	if err == nil {
@@ -565,7 +650,7 @@ func TestHandlerSentLoginMSTACK(t *testing.T) {
 	}
 
 	//nolint:gosec // G115: test-only, state values fit in uint8
-	if state(client.state.Load()) != STATE_SENT_AUTH {
+	if State(client.state.Load()) != STATE_SENT_AUTH {
 		t.Fatalf("expected STATE_SENT_AUTH, got %d", client.state.Load())
 	}
 
@@ -620,7 +705,7 @@ func TestHandlerSentAuthMSTACK(t *testing.T) {
 	}
 
 	//nolint:gosec // G115: test-only, state values fit in uint8
-	if state(client.state.Load()) != STATE_SENT_RPTC {
+	if State(client.state.Load()) != STATE_SENT_RPTC {
 		t.Fatalf("expected STATE_SENT_RPTC, got %d", client.state.Load())
 	}
 
@@ -650,7 +735,7 @@ func TestHandlerSentAuthMSTNAK(t *testing.T) {
 	}
 
 	//nolint:gosec // G115: test-only, state values fit in uint8
-	if state(client.state.Load()) != STATE_SENT_LOGIN {
+	if State(client.state.Load()) != STATE_SENT_LOGIN {
 		t.Fatalf("expected STATE_SENT_LOGIN, got %d", client.state.Load())
 	}
 
@@ -703,7 +788,7 @@ func TestHandlerSentRPTCAccepted(t *testing.T) {
 	}
 
 	//nolint:gosec // G115: test-only, state values fit in uint8
-	if state(client.state.Load()) != STATE_READY {
+	if State(client.state.Load()) != STATE_READY {
 		t.Fatalf("expected STATE_READY, got %d", client.state.Load())
 	}
 
@@ -912,7 +997,7 @@ func TestHandlerTimeoutState(t *testing.T) {
 	time.Sleep(50 * time.Millisecond)
 
 	//nolint:gosec // G115: test-only, state values fit in uint8
-	if state(client.state.Load()) != STATE_TIMEOUT {
+	if State(client.state.Load()) != STATE_TIMEOUT {
 		t.Fatalf("expected state to remain TIMEOUT, got %d", client.state.Load())
 	}
 
@@ -1021,7 +1106,7 @@ func TestPingTimeoutReconnects(t *testing.T) {
 	}
 
 	//nolint:gosec // G115: test-only, state values fit in uint8
-	if state(client.state.Load()) != STATE_SENT_LOGIN {
+	if State(client.state.Load()) != STATE_SENT_LOGIN {
 		t.Fatalf("expected STATE_SENT_LOGIN after timeout, got %d", client.state.Load())
 	}
 
@@ -1229,6 +1314,245 @@ func TestHandleIPSCBurstUnsupportedType(t *testing.T) {
 	}
 }
 
+func TestHandleIPSCBurstHandsBurstToTranslator(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.started.Store(true)
+	client.rfRewrites = []rewrite.Rule{
+		&rewrite.TGRewrite{Name: "test", FromSlot: 1, FromTG: 1, ToSlot: 1, ToTG: 1, Range: 999999},
+	}
+	translated := proto.Packet{Signature: tagDMRD, Src: 100, Dst: 200, GroupCall: true, FrameType: 2, DTypeOrVSeq: 1, StreamID: 0x1234}
+	fake := &testutil.Translator{
+		ToMMDVM: func(byte, []byte) []proto.Packet { return []proto.Packet{translated} },
+	}
+	client.SetTranslator(fake)
+
+	data := []byte{0x80, 0x00, 0x00, 0x00, 0x01}
+	if !client.HandleIPSCBurst(0x80, data, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}) {
+		t.Fatal("expected the translated packet to be forwarded")
+	}
+
+	got := fake.FromIPSC()
+	if len(got) != 1 || got[0].Type != 0x80 || !bytes.Equal(got[0].Data, data) {
+		t.Fatalf("expected the burst to be handed to the translator, got %+v", got)
+	}
+	select {
+	case pkt := <-client.tx_chan:
+		if pkt.StreamID != translated.StreamID {
+			t.Fatalf("expected stream %X, got %X", translated.StreamID, pkt.StreamID)
+		}
+	default:
+		t.Fatal("expected the translated packet to be queued for the master")
+	}
+}
+
+func TestHandlerHandsDMRDToTranslator(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.state.Store(uint32(STATE_READY))
+	client.netRewrites = []rewrite.Rule{
+		&rewrite.TGRewrite{Name: "test", FromSlot: 1, FromTG: 1, ToSlot: 1, ToTG: 1, Range: 999999},
+	}
+	fake := &testutil.Translator{
+		ToIPSC: func(proto.Packet) [][]byte { return [][]byte{{0x80, 0x01}} },
+	}
+	client.SetTranslator(fake)
+	received := make(chan []byte, 1)
+	client.SetIPSCHandler(func(data []byte) { received <- data })
+
+	client.wg.Add(1)
+	go client.handler()
+	defer func() {
+		close(client.done)
+		client.wg.Wait()
+	}()
+
+	pkt := proto.Packet{Signature: tagDMRD, Src: 100, Dst: 200, Repeater: 3001, GroupCall: true, FrameType: 2, DTypeOrVSeq: 1, StreamID: 0x5555}
+	client.connRX <- pkt.Encode()
+
+	select {
+	case data := <-received:
+		if !bytes.Equal(data, []byte{0x80, 0x01}) {
+			t.Fatalf("expected the translator's output, got % X", data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the translated packet")
+	}
+	got := fake.FromMMDVM()
+	if len(got) != 1 || got[0].StreamID != 0x5555 || got[0].Src != 100 || got[0].Dst != 200 {
+		t.Fatalf("expected the DMRD packet to be handed to the translator, got %+v", got)
+	}
+}
+
+func TestPrunedPeerCallIsTerminated(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.started.Store(true)
+	client.inboundTSMgr = timeslot.NewManager()
+	client.passallRewrites = []rewrite.Rule{
+		&rewrite.TGRewrite{Name: "test", FromSlot: 1, FromTG: 1, ToSlot: 1, ToTG: 1, Range: 999999},
+	}
+
+	server := ipsc.NewIPSCServer(&config.Config{IPSC: config.IPSC{IP: "127.0.0.1", PeerTimeout: 1}}, nil)
+	server.SetLocalID(311860)
+	server.SetBurstHandler(NewBurstRouter([]*MMDVMClient{client}))
+	server.SetPeerLostHandler(NewPeerLostRouter([]*MMDVMClient{client}))
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer server.Stop()
+
+	peer, err := net.DialUDP("udp", nil, server.Addr())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer peer.Close()
+
+	// Register as peer 1, then start a group call to TG 200 on TS1 and
+	// go silent without a terminator.
+	register := []byte{byte(ipsc.PacketType_MasterRegisterRequest), 0x00, 0x00, 0x00, 0x01}
+	if _, err := peer.Write(register); err != nil {
+		t.Fatalf("write register: %v", err)
+	}
+	header := make([]byte, 54)
+	header[0] = 0x80
+	binary.BigEndian.PutUint32(header[1:5], 1)
+	header[8] = 100
+	header[11] = 200
+	header[12] = 0x02
+	binary.BigEndian.PutUint32(header[13:17], 0xAABB)
+	header[18] = 0x80
+	header[30] = 0x01
+	if _, err := peer.Write(header); err != nil {
+		t.Fatalf("write header: %v", err)
+	}
+
+	var start proto.Packet
+	select {
+	case start = <-client.tx_chan:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the call to start")
+	}
+
+	select {
+	case pkt := <-client.tx_chan:
+		if pkt.FrameType != frameTypeDataSync || pkt.DTypeOrVSeq != dtypeTerminatorWithLC {
+			t.Fatalf("expected a terminator, got frame type %d dtype %d", pkt.FrameType, pkt.DTypeOrVSeq)
+		}
+		if pkt.StreamID != start.StreamID || pkt.Src != 100 || pkt.Dst != 200 {
+			t.Fatalf("expected the terminator to end stream %d, got stream %d src %d dst %d", start.StreamID, pkt.StreamID, pkt.Src, pkt.Dst)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the pruned peer's call to be terminated")
+	}
+
+	if streams := client.ipscTranslator().ReverseStreams(); len(streams) != 0 {
+		t.Fatalf("expected no streams left, got %+v", streams)
+	}
+	if !client.inboundTSMgr.Submit(false, start.StreamID+1, "ipsc", nil) {
+		t.Fatal("expected the timeslot to be free for the next call")
+	}
+	if peers := server.Peers(); len(peers) != 0 {
+		t.Fatalf("expected the peer to be pruned, got %d peers", len(peers))
+	}
+}
+
+func TestHandlePeerLostIgnoresOtherPeers(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.started.Store(true)
+	client.passallRewrites = []rewrite.Rule{
+		&rewrite.TGRewrite{Name: "test", FromSlot: 1, FromTG: 1, ToSlot: 1, ToTG: 1, Range: 999999},
+	}
+
+	header := make([]byte, 54)
+	header[0] = 0x80
+	binary.BigEndian.PutUint32(header[1:5], 1)
+	header[8] = 100
+	header[11] = 200
+	binary.BigEndian.PutUint32(header[13:17], 0xAABB)
+	header[30] = 0x01
+	client.HandleIPSCBurst(0x80, header, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234})
+	<-client.tx_chan
+
+	client.HandlePeerLost(2)
+	select {
+	case pkt := <-client.tx_chan:
+		t.Fatalf("expected nothing sent for an unrelated peer, got %+v", pkt)
+	default:
+	}
+	if streams := client.ipscTranslator().ReverseStreams(); len(streams) != 1 || streams[0].PeerID != 1 {
+		t.Fatalf("expected peer 1's stream to remain, got %+v", streams)
+	}
+}
+
+func TestHandlePeerLostAsksTranslatorToEndCalls(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.started.Store(true)
+	client.passallRewrites = []rewrite.Rule{
+		&rewrite.TGRewrite{Name: "test", FromSlot: 1, FromTG: 1, ToSlot: 1, ToTG: 1, Range: 999999},
+	}
+	term := proto.Packet{Signature: tagDMRD, Src: 100, Dst: 200, GroupCall: true, FrameType: frameTypeDataSync, DTypeOrVSeq: dtypeTerminatorWithLC, StreamID: 0x1234}
+	fake := &testutil.Translator{
+		TerminatePeer: func(uint32) []proto.Packet { return []proto.Packet{term} },
+	}
+	client.SetTranslator(fake)
+
+	client.HandlePeerLost(7)
+	if got := fake.PeersTerminated(); len(got) != 1 || got[0] != 7 {
+		t.Fatalf("expected peer 7's calls to be ended, got %v", got)
+	}
+	select {
+	case pkt := <-client.tx_chan:
+		if pkt.StreamID != term.StreamID {
+			t.Fatalf("expected stream %X, got %X", term.StreamID, pkt.StreamID)
+		}
+	default:
+		t.Fatal("expected the translator's terminator to be queued for the master")
+	}
+}
+
+func TestSwapSlotsRoutesAndArbitratesOnMMDVMSlot(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.started.Store(true)
+	client.inboundTSMgr = timeslot.NewManager()
+	client.swapSlots = true
+	TranslatorOptions{SwapSlots: true}.apply(client.ipscTranslator())
+	// Only TS2 toward the master is allowed.
+	client.rfRewrites = []rewrite.Rule{
+		&rewrite.TGRewrite{Name: "test", FromSlot: 2, FromTG: 200, ToSlot: 2, ToTG: 200, Range: 1},
+	}
+
+	// A TS1 group call to TG 200 from the repeater.
+	header := make([]byte, 54)
+	header[0] = 0x80
+	binary.BigEndian.PutUint32(header[1:5], 1)
+	header[8] = 100
+	header[11] = 200
+	binary.BigEndian.PutUint32(header[13:17], 0xAABB)
+	header[30] = 0x01
+
+	if !client.MatchesRules(0x80, header, false) {
+		t.Fatal("expected the TS2 rule to match a swapped TS1 call")
+	}
+	if !client.HandleIPSCBurst(0x80, header, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}) {
+		t.Fatal("expected the burst to be forwarded")
+	}
+	pkt := <-client.tx_chan
+	if !pkt.Slot {
+		t.Fatal("expected the call on TS2 toward the master")
+	}
+	// The call holds TS2, not TS1, in the inbound arbitration.
+	if client.inboundTSMgr.Submit(true, pkt.StreamID+1, "ipsc", nil) {
+		t.Fatal("expected TS2 to be busy")
+	}
+	if !client.inboundTSMgr.Submit(false, pkt.StreamID+2, "ipsc", nil) {
+		t.Fatal("expected TS1 to be free")
+	}
+}
+
 // --- Stop() tests ---
 
 func TestStopIdempotent(t *testing.T) {
@@ -1320,7 +1644,7 @@ func TestStartAndFullHandshake(t *testing.T) {
 	cfg := testMMDVMConfig()
 	cfg.MasterServer = fmt.Sprintf("127.0.0.1:%d", srvAddr.Port)
 
-	client := NewMMDVMClient(cfg, nil)
+	client := NewMMDVMClient(cfg, nil, TranslatorOptions{})
 	client.keepAlive = 200 * time.Millisecond
 	client.timeout = 5 * time.Second
 
@@ -1383,13 +1707,13 @@ func TestStartAndFullHandshake(t *testing.T) {
 	deadline := time.Now().Add(2 * time.Second)
 	for time.Now().Before(deadline) {
 		//nolint:gosec // G115: test-only, state values fit in uint8
-		if state(client.state.Load()) == STATE_READY {
+		if State(client.state.Load()) == STATE_READY {
 			break
 		}
 		time.Sleep(10 * time.Millisecond)
 	}
 	//nolint:gosec // G115: test-only, state values fit in uint8
-	if state(client.state.Load()) != STATE_READY {
+	if State(client.state.Load()) != STATE_READY {
 		t.Fatalf("expected STATE_READY, got %d", client.state.Load())
 	}
 
@@ -1603,7 +1927,7 @@ loop:
 func TestStateTransitionOrder(t *testing.T) {
 	t.Parallel()
 	// Verify the numeric order of states
-	states := []state{STATE_IDLE, STATE_SENT_LOGIN, STATE_SENT_AUTH, STATE_SENT_RPTC, STATE_READY, STATE_TIMEOUT}
+	states := []State{STATE_IDLE, STATE_SENT_LOGIN, STATE_SENT_AUTH, STATE_SENT_RPTC, STATE_READY, STATE_TIMEOUT}
 	for i := 0; i < len(states)-1; i++ {
 		if states[i] >= states[i+1] {
 			t.Fatalf("state %d should be less than state %d", states[i], states[i+1])
@@ -1631,3 +1955,301 @@ func TestStartedFlag(t *testing.T) {
 		t.Fatal("expected started=false after reset")
 	}
 }
+
+// radioCheckBurst returns an IPSC private data packet on TS2 carrying f.
+func radioCheckBurst(f csbk.ExtFunction) []byte {
+	data := make([]byte, 54)
+	data[0] = byte(ipsc.PacketType_PrivateData)
+	binary.BigEndian.PutUint32(data[1:5], 1)
+	data[6], data[7], data[8] = byte(f.Src>>16), byte(f.Src>>8), byte(f.Src)
+	data[9], data[10], data[11] = byte(f.Dst>>16), byte(f.Dst>>8), byte(f.Dst)
+	binary.BigEndian.PutUint32(data[13:17], 0xCCDD)
+	data[17] = 0x60
+	data[18] = 0x80
+	data[30] = 0x03
+	block := f.Encode()
+	copy(data[38:50], block[:])
+	return data
+}
+
+func TestRadioCheckAckRoutedToRequestingMaster(t *testing.T) {
+	t.Parallel()
+	var clients []*MMDVMClient
+	for range 2 {
+		client := newTestClient(t)
+		client.started.Store(true)
+		client.passallRewrites = []rewrite.Rule{&rewrite.PassAllPC{Name: "test", Slot: 2}}
+		client.SetIPSCHandler(func([]byte) {})
+		clients = append(clients, client)
+	}
+	route := NewBurstRouter(clients)
+	addr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
+
+	// The second master asks radio 3120101 on the IPSC side for a radio
+	// check on behalf of 3120102.
+	check := csbk.ExtFunction{FID: 0x10, Function: csbk.FunctionRadioCheck, Dst: 3120101, Src: 3120102}
+	remote, err := ipsc.NewIPSCTranslator()
+	if err != nil {
+		t.Fatalf("NewIPSCTranslator: %v", err)
+	}
+	request := remote.TranslateToMMDVM(byte(ipsc.PacketType_PrivateData), radioCheckBurst(check))
+	if len(request) != 1 {
+		t.Fatalf("expected 1 MMDVM packet, got %d", len(request))
+	}
+	clients[1].translateAndForwardToIPSC(request[0])
+
+	// Both masters' rules accept the answer; it must go to the one that
+	// asked even though the first would otherwise win.
+	route(byte(ipsc.PacketType_PrivateData), radioCheckBurst(check.Ack()), addr)
+	select {
+	case pkt := <-clients[1].tx_chan:
+		if pkt.Src != uint(check.Dst) || pkt.Dst != uint(check.Src) || pkt.GroupCall {
+			t.Fatalf("expected the acknowledgment from %d to %d, got %+v", check.Dst, check.Src, pkt)
+		}
+	case <-clients[0].tx_chan:
+		t.Fatal("expected the acknowledgment to go to the requesting master")
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the acknowledgment")
+	}
+
+	// The check is answered, so a repeated answer follows the rules again.
+	route(byte(ipsc.PacketType_PrivateData), radioCheckBurst(check.Ack()), addr)
+	select {
+	case <-clients[0].tx_chan:
+	case <-clients[1].tx_chan:
+		t.Fatal("expected a repeated acknowledgment to follow the rules")
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the repeated acknowledgment")
+	}
+}
+
+func TestReflectedCallIsNotForwardedToAnotherMaster(t *testing.T) {
+	t.Parallel()
+	loops := loopdetect.New(loopdetect.DefaultWindow)
+	var clients []*MMDVMClient
+	for _, name := range []string{"A", "B"} {
+		client := newTestClient(t)
+		client.cfg.Name = name
+		client.started.Store(true)
+		client.passallRewrites = []rewrite.Rule{
+			&rewrite.TGRewrite{Name: "test", FromSlot: 1, FromTG: 1, ToSlot: 1, ToTG: 1, Range: 999999},
+		}
+		client.SetLoopDetector(loops)
+		clients = append(clients, client)
+	}
+	addr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
+	header := func(src byte, callControl uint32) []byte {
+		data := make([]byte, 54)
+		data[0] = 0x80
+		binary.BigEndian.PutUint32(data[1:5], 1)
+		data[8] = src
+		data[11] = 200
+		binary.BigEndian.PutUint32(data[13:17], callControl)
+		data[18] = 0x80
+		data[30] = 0x01
+		return data
+	}
+
+	// Network A sends 100 → TG 200 on TS1 toward IPSC.
+	clients[0].deliverToIPSC(proto.Packet{
+		Signature: "DMRD", Src: 100, Dst: 200, GroupCall: true,
+		FrameType: frameTypeDataSync, DTypeOrVSeq: 1, StreamID: 1,
+	})
+
+	// The same call comes back from IPSC under a new call control.
+	clients[1].HandleIPSCBurst(0x80, header(100, 0xAA01), addr)
+	select {
+	case pkt := <-clients[1].tx_chan:
+		t.Fatalf("expected the reflected call to be suppressed, got %+v", pkt)
+	default:
+	}
+	if got := clients[1].packetsDropped.Load(); got != 1 {
+		t.Fatalf("expected 1 packet dropped, got %d", got)
+	}
+
+	// A quick reply from another radio is a new call.
+	clients[1].HandleIPSCBurst(0x80, header(101, 0xAA02), addr)
+	select {
+	case pkt := <-clients[1].tx_chan:
+		if pkt.Src != 101 || pkt.Dst != 200 {
+			t.Fatalf("expected the reply from 101 to TG 200, got %+v", pkt)
+		}
+	default:
+		t.Fatal("expected the reply to be forwarded")
+	}
+}
+
+func TestParrotAnswersCallsFromEitherSide(t *testing.T) {
+	t.Parallel()
+	cfg := &config.Config{Parrot: config.Parrot{Enabled: true, ID: 9990, MaxDuration: 5}}
+	echo := parrot.New(cfg.Parrot)
+	table := specialid.New(cfg)
+	table.Register(config.SpecialIDHandlerParrot, func(side string, pkt proto.Packet, send func(proto.Packet) bool) bool {
+		return echo.Handle(side, pkt, send)
+	})
+	client := newTestClient(t)
+	client.started.Store(true)
+	client.SetSpecialIDs(table)
+	delivered := make(chan []byte, 8)
+	client.SetIPSCHandler(func(data []byte) { delivered <- data })
+
+	// From the master: a private call to the parrot comes back to the
+	// master from the parrot, and is not delivered to IPSC.
+	header := proto.Packet{
+		Signature: "DMRD", Src: 3120101, Dst: 9990, Slot: true,
+		FrameType: frameTypeDataSync, DTypeOrVSeq: 1, StreamID: 77,
+	}
+	terminator := header
+	terminator.DTypeOrVSeq = dtypeTerminatorWithLC
+	for _, pkt := range []proto.Packet{header, terminator} {
+		client.handleReady(pkt.Encode())
+	}
+	for i := range 2 {
+		select {
+		case pkt := <-client.tx_chan:
+			if pkt.Src != 9990 || pkt.Dst != 3120101 || pkt.GroupCall || pkt.StreamID == 77 {
+				t.Fatalf("frame %d: expected a new stream from 9990 to 3120101, got %+v", i, pkt)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timed out waiting for playback frame %d", i)
+		}
+	}
+	select {
+	case data := <-delivered:
+		t.Fatalf("expected the parrot call not to reach IPSC, got % X", data)
+	default:
+	}
+
+	// From the repeater: the call is taken ahead of the router.
+	forwarded := make(chan struct{}, 2)
+	handler, err := NewSpecialIDBurstHandler(table, ipsc.NewIPSCServer(&config.Config{}, nil), 311860, config.IPSCRTP{},
+		func(byte, []byte, *net.UDPAddr) { forwarded <- struct{}{} })
+	if err != nil {
+		t.Fatalf("NewSpecialIDBurstHandler: %v", err)
+	}
+	burst := make([]byte, 54)
+	burst[0] = 0x81
+	binary.BigEndian.PutUint32(burst[1:5], 1)
+	burst[6], burst[7], burst[8] = 0x2F, 0x9B, 0xE5   // 3120101
+	burst[9], burst[10], burst[11] = 0x00, 0x27, 0x06 // 9990
+	burst[30] = 0x01
+	handler(0x81, burst, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234})
+	burst[11] = 0x07
+	handler(0x81, burst, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234})
+	select {
+	case <-forwarded:
+	case <-time.After(time.Second):
+		t.Fatal("expected a call to another ID to be routed")
+	}
+	select {
+	case <-forwarded:
+		t.Fatal("expected the call to the parrot not to be routed")
+	default:
+	}
+}
+
+func TestSpecialIDsBlockedBeforeRewrites(t *testing.T) {
+	t.Parallel()
+	table := specialid.New(&config.Config{SpecialIDs: []config.SpecialID{
+		{FromID: 4000, Direction: config.SpecialIDDirectionToIPSC, Action: config.SpecialIDActionBlock},
+		{FromID: 9, Direction: config.SpecialIDDirectionToMaster, Action: config.SpecialIDActionBlock},
+	}})
+	client := newTestClient(t)
+	client.started.Store(true)
+	client.SetSpecialIDs(table)
+	client.netRewrites = []rewrite.Rule{
+		&rewrite.PCRewrite{Name: "test", FromSlot: 1, FromID: 1, ToSlot: 1, ToID: 1, Range: 999999},
+	}
+	delivered := make(chan []byte, 8)
+	client.SetIPSCHandler(func(data []byte) { delivered <- data })
+
+	// From the master: a private call to 4000 is dropped even though a
+	// rewrite rule would pass it, and a call to another ID is delivered.
+	for _, dst := range []uint{4000, 4001} {
+		header := proto.Packet{
+			Signature: "DMRD", Src: 3120101, Dst: dst,
+			FrameType: frameTypeDataSync, DTypeOrVSeq: 1, StreamID: dst,
+		}
+		client.handleReady(header.Encode())
+	}
+	select {
+	case data := <-delivered:
+		if dst := uint(data[9])<<16 | uint(data[10])<<8 | uint(data[11]); dst != 4001 {
+			t.Fatalf("expected only the call to 4001 to reach IPSC, got a call to %d", dst)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the call to 4001 to reach IPSC")
+	}
+	if got := client.packetsDropped.Load(); got != 1 {
+		t.Fatalf("expected 1 dropped packet, got %d", got)
+	}
+
+	// From the repeater: a call to 9 is blocked ahead of the router.
+	forwarded := make(chan uint, 2)
+	handler, err := NewSpecialIDBurstHandler(table, ipsc.NewIPSCServer(&config.Config{}, nil), 311860, config.IPSCRTP{},
+		func(_ byte, data []byte, _ *net.UDPAddr) { forwarded <- uint(data[11]) })
+	if err != nil {
+		t.Fatalf("NewSpecialIDBurstHandler: %v", err)
+	}
+	burst := make([]byte, 54)
+	burst[0] = 0x80
+	burst[30] = 0x01
+	for _, dst := range []byte{9, 10} {
+		burst[11] = dst
+		handler(0x80, burst, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234})
+	}
+	if got := <-forwarded; got != 10 {
+		t.Fatalf("expected only the call to 10 to be routed, got a call to %d", got)
+	}
+	select {
+	case got := <-forwarded:
+		t.Fatalf("expected nothing else routed, got a call to %d", got)
+	default:
+	}
+}
+
+func TestConnectMarksDSCP(t *testing.T) {
+	t.Parallel()
+	refusal := errors.New("operation not permitted")
+	tests := []struct {
+		name    string
+		dscp    uint8
+		err     error
+		wantTOS int
+	}{
+		{"unmarked", 0, nil, 0},
+		{"expedited forwarding", 46, nil, 0xB8},
+		{"refused", 46, refusal, 0xB8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			serverConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			if err != nil {
+				t.Fatalf("listen: %v", err)
+			}
+			defer serverConn.Close()
+			cfg := testMMDVMConfig()
+			cfg.MasterServer = serverConn.LocalAddr().String()
+			cfg.DSCP = tt.dscp
+			client := NewMMDVMClient(cfg, nil, TranslatorOptions{})
+			setter := &testutil.DSCPSetter{Err: tt.err}
+			client.dscpSetter = setter
+
+			err = client.connect()
+			if tt.err != nil {
+				if !errors.Is(err, dscp.ErrRefused) || !errors.Is(err, tt.err) {
+					t.Fatalf("expected the refusal reported, got %v", err)
+				}
+				if client.conn != nil {
+					t.Fatal("expected no connection kept")
+				}
+			} else if err != nil {
+				t.Fatalf("connect: %v", err)
+			}
+			if got := setter.TOS(); got != tt.wantTOS {
+				t.Fatalf("expected ToS 0x%02X, got 0x%02X", tt.wantTOS, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package mmdvm
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrClosed is returned by Start once the client has been closed.
+var ErrClosed = errors.New("MMDVM client closed")
+
+// flushPoll is how often Close checks whether the socket writer has
+// written everything queued.
+const flushPoll = 5 * time.Millisecond
+
+// Close logs out from the master gracefully, for shutdown. It stops taking
+// traffic from IPSC, sends what is already queued for the master until ctx
+// is done, dropping the rest, and then sends RPTCL so the master ends the
+// session at once instead of timing it out and refusing a quick login
+// again. The client is left in STATE_CLOSED and cannot be started again.
+// The error is ctx's if it ended the wait. Like Stop, it is safe to call
+// more than once.
+func (h *MMDVMClient) Close(ctx context.Context) error {
+	h.lifecycleMu.Lock()
+	defer h.lifecycleMu.Unlock()
+	var err error
+	if h.started.Load() {
+		// HandleIPSCBurst takes nothing more from here on.
+		h.started.Store(false)
+		err = h.flushTX(ctx)
+	}
+	h.stop()
+	h.wg.Wait()
+	h.setState(STATE_CLOSED, "closed")
+	if h.metrics != nil {
+		h.metrics.MMDVMConnectionState.WithLabelValues(h.cfg.Name).Set(0)
+	}
+	return err
+}
+
+// flushTX waits until forwardTX has passed on everything queued for the
+// master, pacing included, and the socket writer has sent it, or until
+// ctx is done.
+func (h *MMDVMClient) flushTX(ctx context.Context) error {
+	if h.flushReq != nil {
+		ack := make(chan struct{})
+		select {
+		case h.flushReq <- ack:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		select {
+		case <-ack:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	ticker := time.NewTicker(flushPoll)
+	defer ticker.Stop()
+	for h.txPending.Load() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
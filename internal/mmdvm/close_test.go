@@ -0,0 +1,130 @@
+package mmdvm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startedClient returns a client started against a fake master, and the
+// master's socket.
+func startedClient(t *testing.T) (*net.UDPConn, *MMDVMClient) {
+	t.Helper()
+	serverConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() {
+		serverConn.Close()
+	})
+	cfg := testMMDVMConfig()
+	srvAddr, ok := serverConn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		t.Fatal("expected *net.UDPAddr from LocalAddr")
+	}
+	cfg.MasterServer = fmt.Sprintf("127.0.0.1:%d", srvAddr.Port)
+	client := NewMMDVMClient(cfg, nil, TranslatorOptions{})
+	if err := client.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(client.Stop)
+	return serverConn, client
+}
+
+// received reads every datagram the master got until none arrives for a
+// while, and returns their tags, or the datagrams themselves if unknown.
+func received(t *testing.T, serverConn *net.UDPConn) []string {
+	t.Helper()
+	var tags []string
+	buf := make([]byte, 1500)
+	for {
+		if err := serverConn.SetReadDeadline(time.Now().Add(200 * time.Millisecond)); err != nil {
+			t.Fatalf("SetReadDeadline: %v", err)
+		}
+		n, _, err := serverConn.ReadFromUDP(buf)
+		if err != nil {
+			return tags
+		}
+		tag := string(buf[:n])
+		for _, known := range []string{tagRPTCL, tagRPTL, tagRPTPING, tagDMRD} {
+			if strings.HasPrefix(tag, known) {
+				tag = known
+				break
+			}
+		}
+		tags = append(tags, tag)
+	}
+}
+
+func TestCloseSendsQueuedThenRPTCL(t *testing.T) {
+	t.Parallel()
+	serverConn, client := startedClient(t)
+	for seq := range uint(3) {
+		client.tx_chan <- voiceFrame(false, seq)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := client.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	want := []string{tagRPTL, tagDMRD, tagDMRD, tagDMRD, tagRPTCL}
+	if tags := received(t, serverConn); !slices.Equal(tags, want) {
+		t.Fatalf("expected %q, got %q", want, tags)
+	}
+	if got := client.State(); got != STATE_CLOSED {
+		t.Fatalf("expected state closed, got %s", got)
+	}
+}
+
+func TestCloseDropsQueueAtDeadline(t *testing.T) {
+	t.Parallel()
+	serverConn, client := startedClient(t)
+	// Paced, ten frames take over half a second.
+	for seq := range uint(10) {
+		client.tx_chan <- voiceFrame(false, seq)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*frameInterval)
+	defer cancel()
+	if err := client.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to end the flush, got %v", err)
+	}
+
+	tags := received(t, serverConn)
+	if len(tags) == 0 || tags[len(tags)-1] != tagRPTCL {
+		t.Fatalf("expected RPTCL last, got %q", tags)
+	}
+	frames := 0
+	for _, tag := range tags {
+		if tag == tagDMRD {
+			frames++
+		}
+	}
+	if frames == 0 || frames >= 10 {
+		t.Fatalf("expected some frames sent and the rest dropped, got %d sent", frames)
+	}
+}
+
+func TestClosedClientCannotStart(t *testing.T) {
+	t.Parallel()
+	_, client := startedClient(t)
+	if err := client.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := client.Close(context.Background()); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+	if err := client.Start(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected %v, got %v", ErrClosed, err)
+	}
+	if client.HandleIPSCBurst(0x80, make([]byte, 54), nil) {
+		t.Fatal("expected a closed client to take no traffic")
+	}
+}
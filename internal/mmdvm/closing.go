@@ -0,0 +1,65 @@
+package mmdvm
+
+import (
+	"encoding/binary"
+	"log/slog"
+	"time"
+)
+
+// defaultClosedDelay is how long the client waits before logging in again
+// to a master that announced it was closing, used when a client leaves
+// closedDelay unset. It is longer than a reconnect after a timeout, since
+// the master said it was going away.
+const defaultClosedDelay = 5 * time.Second
+
+// isMasterClosing reports whether data is an MSTCL telling repeater id
+// that the master is closing. An MSTCL without an ID is for every
+// repeater.
+func isMasterClosing(data []byte, id uint32) bool {
+	if len(data) < len("MSTCL") || string(data[:len("MSTCL")]) != "MSTCL" {
+		return false
+	}
+	rest := data[len("MSTCL"):]
+	if len(rest) < 4 {
+		return true
+	}
+	return binary.BigEndian.Uint32(rest) == id
+}
+
+// handleMasterClosing leaves the session with a master that announced it
+// is closing, instead of waiting for its pings to time out. Calls in
+// flight from it are ended and pings stop at once. With a standby the
+// client fails over straight away; otherwise it logs in again after
+// closedDelay.
+func (h *MMDVMClient) handleMasterClosing() {
+	slog.Warn("MMDVM master is closing", "network", h.cfg.Name, "master", h.ActiveMaster())
+	h.terminateStreams()
+	// Out of STATE_READY, the ping routine stops sending pings.
+	h.setState(STATE_TIMEOUT, "closed")
+	if h.metrics != nil {
+		h.metrics.MMDVMConnectionState.WithLabelValues(h.cfg.Name).Set(0)
+	}
+	if h.canFailover() {
+		h.switchMaster(h.masters.next(), "closed")
+		return
+	}
+	delay := h.closedDelay
+	if delay <= 0 {
+		delay = defaultClosedDelay
+	}
+	slog.Info("Reconnecting to MMDVM master", "network", h.cfg.Name, "delay", delay)
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		defer h.recoverPanic()
+		select {
+		case <-time.After(delay):
+		case <-h.done:
+			return
+		}
+		h.reconnect("closed")
+		// The watchdog exits once a session is ready, so make sure one
+		// is watching the new handshake.
+		h.startHandshakeWatchdog()
+	}()
+}
@@ -0,0 +1,159 @@
+package mmdvm
+
+import (
+	"encoding/binary"
+	"sync"
+	"testing"
+	"time"
+)
+
+func mstcl(id uint32) []byte {
+	data := make([]byte, len("MSTCL")+4)
+	n := copy(data, "MSTCL")
+	binary.BigEndian.PutUint32(data[n:], id)
+	return data
+}
+
+func TestIsMasterClosing(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"our ID", mstcl(311860), true},
+		{"no ID", []byte("MSTCL"), true},
+		{"other ID", mstcl(311861), false},
+		{"NAK", []byte("MSTNAK__________"), false},
+		{"short", []byte("MSTC"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := isMasterClosing(tt.data, 311860); got != tt.want {
+				t.Fatalf("isMasterClosing(%q) = %v, want %v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+// readyTestClient returns a client in a session, pinging every 20ms, with
+// its handler and ping routine running. configure sets the client up
+// before they start.
+func readyTestClient(t *testing.T, configure func(*MMDVMClient)) *MMDVMClient {
+	t.Helper()
+	client := newTestClient(t)
+	client.closedDelay = time.Hour
+	configure(client)
+	client.keepAlive = 20 * time.Millisecond
+	client.timeout = time.Hour
+	client.state.Store(uint32(STATE_READY))
+	client.wg.Add(2)
+	client.pingRunning.Store(true)
+	go client.handler()
+	go client.ping()
+	t.Cleanup(func() {
+		close(client.done)
+		client.wg.Wait()
+	})
+	expectSent(t, client, tagRPTPING)
+	return client
+}
+
+func TestMSTCLLeavesTheSession(t *testing.T) {
+	t.Parallel()
+	var (
+		mu          sync.Mutex
+		transitions [][2]State
+	)
+	client := readyTestClient(t, func(client *MMDVMClient) {
+		client.SetStateHandler(func(from, to State) {
+			mu.Lock()
+			defer mu.Unlock()
+			transitions = append(transitions, [2]State{from, to})
+		})
+	})
+
+	client.connRX <- mstcl(client.cfg.ID)
+	deadline := time.Now().Add(2 * time.Second)
+	for client.State() != STATE_TIMEOUT {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the session left, state %s", client.State())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	mu.Lock()
+	got := transitions
+	mu.Unlock()
+	if len(got) != 1 || got[0] != [2]State{STATE_READY, STATE_TIMEOUT} {
+		t.Fatalf("expected one change from ready to timeout, got %v", got)
+	}
+
+	// A ping queued before the MSTCL may still be in flight; none after.
+	select {
+	case <-client.connTX:
+	default:
+	}
+	expectNothingSent(t, client)
+}
+
+func TestMSTCLReconnectsAfterDelay(t *testing.T) {
+	t.Parallel()
+	client := readyTestClient(t, func(client *MMDVMClient) {
+		client.closedDelay = 50 * time.Millisecond
+	})
+
+	start := time.Now()
+	client.connRX <- mstcl(client.cfg.ID)
+	for {
+		select {
+		case data := <-client.connTX:
+			if string(data[:len(tagRPTPING)]) == tagRPTPING {
+				continue
+			}
+			if string(data[:len(tagRPTL)]) != tagRPTL {
+				t.Fatalf("expected RPTL, got %q", data)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for the login")
+		}
+		break
+	}
+	if waited := time.Since(start); waited < client.closedDelay {
+		t.Fatalf("expected the login after %v, came after %v", client.closedDelay, waited)
+	}
+	if got := client.State(); got != STATE_SENT_LOGIN {
+		t.Fatalf("expected state sent-login, got %s", got)
+	}
+}
+
+func TestMSTCLForAnotherRepeaterIgnored(t *testing.T) {
+	t.Parallel()
+	client := readyTestClient(t, func(*MMDVMClient) {})
+	client.connRX <- mstcl(client.cfg.ID + 1)
+	time.Sleep(50 * time.Millisecond)
+	if got := client.State(); got != STATE_READY {
+		t.Fatalf("expected state to remain ready, got %s", got)
+	}
+}
+
+func TestMSTCLFailsOverToStandby(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.timeout = time.Hour
+	client.closedDelay = time.Hour
+	client.masters = newMasterSet([]string{"127.0.0.1:62030", "127.0.0.1:62031"})
+	client.state.Store(uint32(STATE_READY))
+	client.wg.Add(1)
+	go client.handler()
+	defer func() {
+		close(client.done)
+		client.wg.Wait()
+	}()
+
+	client.connRX <- mstcl(client.cfg.ID)
+	expectSent(t, client, tagRPTL)
+	if got := client.ActiveMaster(); got != "127.0.0.1:62031" {
+		t.Fatalf("expected the standby active, got %s", got)
+	}
+}
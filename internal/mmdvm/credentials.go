@@ -0,0 +1,115 @@
+package mmdvm
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// reloginFlushTimeout bounds how long a re-login waits for the terminators
+// of in-flight calls to reach the master before logging out.
+const reloginFlushTimeout = time.Second
+
+// credentials are the radio ID and password the client logs in with.
+type credentials struct {
+	id       uint32
+	password string
+}
+
+// credentials returns the credentials of the current session, those from
+// the config until UpdateCredentials changes them.
+func (h *MMDVMClient) credentials() credentials {
+	h.credsMu.Lock()
+	defer h.credsMu.Unlock()
+	if h.creds != nil {
+		return *h.creds
+	}
+	return credentials{id: h.cfg.ID, password: h.cfg.Password}
+}
+
+// radioID returns the radio ID of the current session.
+func (h *MMDVMClient) radioID() uint32 {
+	return h.credentials().id
+}
+
+// UpdateCredentials changes the radio ID and password the client logs in
+// with, keeping the current one of either left zero or empty. A running
+// client ends the calls it is sending to the master, logs out with RPTCL,
+// and logs in again with the new credentials, even after having given up
+// on the old ones; a stopped client uses them when next started. It is
+// safe to call from any goroutine.
+func (h *MMDVMClient) UpdateCredentials(id uint32, password string) {
+	h.credsMu.Lock()
+	next := h.pendingCreds
+	if next == nil {
+		cur := credentials{id: h.cfg.ID, password: h.cfg.Password}
+		if h.creds != nil {
+			cur = *h.creds
+		}
+		next = &cur
+	}
+	if id != 0 {
+		next.id = id
+	}
+	if password != "" {
+		next.password = password
+	}
+	h.pendingCreds = next
+	h.credsMu.Unlock()
+
+	// One request covers any number of changes made before the handler
+	// gets to it.
+	select {
+	case h.reloginReq <- struct{}{}:
+	default:
+	}
+}
+
+// applyCredentials makes changed credentials, if any, those of the
+// session.
+func (h *MMDVMClient) applyCredentials() {
+	h.credsMu.Lock()
+	defer h.credsMu.Unlock()
+	if h.pendingCreds != nil {
+		h.creds = h.pendingCreds
+		h.pendingCreds = nil
+	}
+}
+
+// relogin moves the session to changed credentials. It ends the calls in
+// flight toward the master and waits for their terminators to go out, so
+// the master does not hold them open past the RPTCL that ends the old
+// session, and then logs in again on the same socket. It runs on the
+// handler goroutine.
+func (h *MMDVMClient) relogin() {
+	h.credsMu.Lock()
+	changed := h.pendingCreds != nil
+	h.credsMu.Unlock()
+	if !changed {
+		// Start took the change already.
+		return
+	}
+	if h.translator != nil {
+		if packets := h.translator.TerminateStreams(); len(packets) > 0 {
+			slog.Info("Ending calls to MMDVM master before logging in again", "network", h.cfg.Name, "calls", len(packets))
+			h.forwardToMaster(h.done, packets, true)
+			ctx, cancel := context.WithTimeout(context.Background(), reloginFlushTimeout)
+			if err := h.flushTX(ctx); err != nil {
+				slog.Warn("Gave up waiting for calls to the MMDVM master to end", "network", h.cfg.Name, "error", err)
+			}
+			cancel()
+		}
+	}
+	// The old session is closed under its own radio ID.
+	h.sendRPTCL()
+	h.applyCredentials()
+	if h.translator != nil {
+		h.translator.SetPeerID(h.radioID())
+	}
+	slog.Info("Credentials changed, logging in to MMDVM master again", "network", h.cfg.Name, "id", h.radioID())
+	h.rejections = 0
+	h.loginSent.Store(time.Now().UnixNano())
+	h.setState(STATE_SENT_LOGIN, "credentials")
+	h.sendLogin()
+	h.startHandshakeWatchdog()
+}
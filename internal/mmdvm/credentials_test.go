@@ -0,0 +1,212 @@
+package mmdvm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/rewrite"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/testutil"
+)
+
+// sessionClient returns a client in a session with the master, its handler
+// and forwardTX running and nothing sent yet.
+func sessionClient(t *testing.T) *MMDVMClient {
+	t.Helper()
+	return startSession(t, newTestClient(t))
+}
+
+// startSession puts client in a session with the master and starts its
+// handler and forwardTX.
+func startSession(t *testing.T, client *MMDVMClient) *MMDVMClient {
+	t.Helper()
+	client.keepAlive = time.Hour
+	client.timeout = time.Hour
+	client.state.Store(uint32(STATE_READY))
+	client.started.Store(true)
+	client.wg.Add(2)
+	go client.handler()
+	go client.forwardTX()
+	t.Cleanup(func() {
+		close(client.done)
+		client.wg.Wait()
+	})
+	return client
+}
+
+// nextSent returns the next datagram queued for the master.
+func nextSent(t *testing.T, client *MMDVMClient) []byte {
+	t.Helper()
+	select {
+	case data := <-client.connTX:
+		return data
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a datagram to the master")
+		return nil
+	}
+}
+
+// decodeSent decodes a DMRD datagram queued for the master.
+func decodeSent(t *testing.T, data []byte) proto.Packet {
+	t.Helper()
+	pkt, ok := proto.Decode(data)
+	if !ok {
+		t.Fatalf("expected DMRD, got %q", data)
+	}
+	return pkt
+}
+
+// expectSentID expects tag sent carrying radio ID id.
+func expectSentID(t *testing.T, client *MMDVMClient, tag string, id uint32) {
+	t.Helper()
+	data := nextSent(t, client)
+	if !bytes.HasPrefix(data, []byte(tag)) || len(data) < len(tag)+4 {
+		t.Fatalf("expected %s, got %q", tag, data)
+	}
+	if got := binary.BigEndian.Uint32(data[len(tag):]); got != id {
+		t.Fatalf("expected %s from %d, got %d", tag, id, got)
+	}
+}
+
+func TestUpdateCredentialsSignsWithNewPassword(t *testing.T) {
+	t.Parallel()
+	client := sessionClient(t)
+	id := client.cfg.ID
+
+	client.UpdateCredentials(0, "n3w-s3cret")
+	expectSentID(t, client, tagRPTCL, id)
+	expectSentID(t, client, tagRPTL, id)
+	if got := client.State(); got != STATE_SENT_LOGIN {
+		t.Fatalf("expected state sent-login, got %s", got)
+	}
+
+	random := []byte{0x12, 0x34, 0x56, 0x78}
+	client.connRX <- append([]byte(rptAck), random...)
+	data := nextSent(t, client)
+	if len(data) != 40 || string(data[:4]) != "RPTK" {
+		t.Fatalf("expected RPTK, got %q", data)
+	}
+	want := sha256.Sum256(append(append([]byte(nil), random...), "n3w-s3cret"...))
+	if !bytes.Equal(data[8:], want[:]) {
+		t.Fatalf("expected the token signed with the new password, got % X", data[8:])
+	}
+}
+
+func TestUpdateCredentialsLogsOutUnderOldID(t *testing.T) {
+	t.Parallel()
+	client := sessionClient(t)
+	old := client.cfg.ID
+
+	client.UpdateCredentials(311861, "")
+	expectSentID(t, client, tagRPTCL, old)
+	expectSentID(t, client, tagRPTL, 311861)
+
+	random := []byte{0x01, 0x02, 0x03, 0x04}
+	client.connRX <- append([]byte(rptAck), random...)
+	data := nextSent(t, client)
+	if got := binary.BigEndian.Uint32(data[4:8]); got != 311861 {
+		t.Fatalf("expected RPTK from 311861, got %d", got)
+	}
+	want := sha256.Sum256(append(append([]byte(nil), random...), client.cfg.Password...))
+	if !bytes.Equal(data[8:], want[:]) {
+		t.Fatalf("expected the token signed with the unchanged password, got % X", data[8:])
+	}
+	if client.cfg.ID != old {
+		t.Fatalf("expected the config left alone, got ID %d", client.cfg.ID)
+	}
+}
+
+func TestUpdateCredentialsEndsCallsBeforeRPTCL(t *testing.T) {
+	t.Parallel()
+	client := sessionClient(t)
+	client.passallRewrites = []rewrite.Rule{
+		&rewrite.TGRewrite{Name: "test", FromSlot: 1, FromTG: 1, ToSlot: 1, ToTG: 1, Range: 999999},
+	}
+
+	header := make([]byte, 54)
+	header[0] = 0x80
+	binary.BigEndian.PutUint32(header[1:5], 1)
+	header[8] = 100
+	header[11] = 200
+	binary.BigEndian.PutUint32(header[13:17], 0xAABB)
+	header[30] = 0x01
+	client.HandleIPSCBurst(0x80, header, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234})
+	start := decodeSent(t, nextSent(t, client))
+
+	client.UpdateCredentials(0, "n3w-s3cret")
+	term := decodeSent(t, nextSent(t, client))
+	if term.FrameType != frameTypeDataSync || term.DTypeOrVSeq != dtypeTerminatorWithLC {
+		t.Fatalf("expected a terminator, got frame type %d dtype %d", term.FrameType, term.DTypeOrVSeq)
+	}
+	if term.StreamID != start.StreamID {
+		t.Fatalf("expected the terminator to end stream %d, got %d", start.StreamID, term.StreamID)
+	}
+	expectSent(t, client, tagRPTCL)
+	expectSent(t, client, tagRPTL)
+	if streams := client.ipscTranslator().ReverseStreams(); len(streams) != 0 {
+		t.Fatalf("expected no streams left, got %+v", streams)
+	}
+}
+
+func TestUpdateCredentialsEndsCallsOfAnyTranslator(t *testing.T) {
+	t.Parallel()
+	term := proto.Packet{Signature: tagDMRD, Src: 100, Dst: 200, GroupCall: true, FrameType: frameTypeDataSync, DTypeOrVSeq: dtypeTerminatorWithLC, StreamID: 0x1234}
+	fake := &testutil.Translator{
+		TerminateAll: func() []proto.Packet { return []proto.Packet{term} },
+	}
+	client := newTestClient(t)
+	client.SetTranslator(fake)
+	startSession(t, client)
+
+	client.UpdateCredentials(0, "n3w-s3cret")
+	if got := decodeSent(t, nextSent(t, client)); got.StreamID != term.StreamID {
+		t.Fatalf("expected the translator's terminator first, got stream %X", got.StreamID)
+	}
+	expectSent(t, client, tagRPTCL)
+	expectSent(t, client, tagRPTL)
+	if got := fake.AllTerminated(); got != 1 {
+		t.Fatalf("expected every call ended once, got %d", got)
+	}
+	if id, ok := fake.PeerID(); !ok || id != client.cfg.ID {
+		t.Fatalf("expected the translator's peer ID set to %d, got %d, %v", client.cfg.ID, id, ok)
+	}
+}
+
+func TestUpdateCredentialsWhileStoppedAppliesOnStart(t *testing.T) {
+	t.Parallel()
+	serverConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer serverConn.Close()
+	cfg := testMMDVMConfig()
+	cfg.MasterServer = serverConn.LocalAddr().String()
+	client := NewMMDVMClient(cfg, nil, TranslatorOptions{})
+	client.UpdateCredentials(311861, "n3w-s3cret")
+	if err := client.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer client.Stop()
+
+	var got []string
+	buf := make([]byte, 1500)
+	for {
+		if err := serverConn.SetReadDeadline(time.Now().Add(200 * time.Millisecond)); err != nil {
+			t.Fatalf("SetReadDeadline: %v", err)
+		}
+		n, _, err := serverConn.ReadFromUDP(buf)
+		if err != nil {
+			break
+		}
+		got = append(got, string(buf[:n]))
+	}
+	// One login under the new ID, and no logout from a session it never had.
+	if len(got) != 1 || !strings.HasPrefix(got[0], tagRPTL) || binary.BigEndian.Uint32([]byte(got[0][len(tagRPTL):])) != 311861 {
+		t.Fatalf("expected a single RPTL from 311861, got %q", got)
+	}
+}
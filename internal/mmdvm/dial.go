@@ -0,0 +1,89 @@
+package mmdvm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// dialFunc opens a UDP socket to a master at addr, sending from local, or
+// from an address the kernel picks if local is nil.
+type dialFunc func(local *net.UDPAddr, addr string) (net.Conn, error)
+
+// dialUDP is the dialFunc used outside tests.
+func dialUDP(local *net.UDPAddr, addr string) (net.Conn, error) {
+	d := net.Dialer{}
+	if local != nil {
+		// A nil *net.UDPAddr in the interface would not read as unset.
+		d.LocalAddr = local
+	}
+	return d.DialContext(context.Background(), "udp", addr)
+}
+
+func (h *MMDVMClient) dialer() dialFunc {
+	if h.dial == nil {
+		return dialUDP
+	}
+	return h.dial
+}
+
+// defaultResolveTimeout bounds each lookup of a master's host name.
+const defaultResolveTimeout = 5 * time.Second
+
+// errNoAddresses is returned when a master's host name resolves to
+// nothing.
+var errNoAddresses = errors.New("no addresses found")
+
+// resolveFunc looks up the addresses of host.
+type resolveFunc func(ctx context.Context, host string) ([]net.IP, error)
+
+func lookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	return net.DefaultResolver.LookupIP(ctx, "ip", host)
+}
+
+// resolveMaster returns the ip:port to dial for master, looking its host
+// name up afresh on every connection so masters behind dynamic or
+// round-robin DNS are followed. When the name has several addresses, the
+// one dialed last is passed over, as the connection to it has just been
+// lost.
+func (h *MMDVMClient) resolveMaster(master string) (string, error) {
+	host, port, err := net.SplitHostPort(master)
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(host) != nil {
+		return master, nil
+	}
+
+	timeout := h.resolveTimeout
+	if timeout == 0 {
+		timeout = defaultResolveTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	resolve := h.resolve
+	if resolve == nil {
+		resolve = lookupIP
+	}
+	ips, err := resolve(ctx, host)
+	if err == nil && len(ips) == 0 {
+		err = errNoAddresses
+	}
+	if err != nil {
+		return "", fmt.Errorf("resolving MMDVM master %s: %w", host, err)
+	}
+
+	h.connMu.Lock()
+	defer h.connMu.Unlock()
+	chosen := ips[0]
+	for _, ip := range ips {
+		if !ip.Equal(h.lastDialed) {
+			chosen = ip
+			break
+		}
+	}
+	h.lastDialed = chosen
+	return net.JoinHostPort(chosen.String(), port), nil
+}
@@ -0,0 +1,241 @@
+package mmdvm
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+)
+
+// recordingDialer records the local and remote address of every socket
+// dialed and hands back one end of a pipe.
+type recordingDialer struct {
+	mu      sync.Mutex
+	locals  []*net.UDPAddr
+	remotes []string
+}
+
+func (d *recordingDialer) dial(local *net.UDPAddr, addr string) (net.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.locals = append(d.locals, local)
+	d.remotes = append(d.remotes, addr)
+	conn, peer := net.Pipe()
+	_ = peer.Close()
+	return conn, nil
+}
+
+func (d *recordingDialer) dialed() []*net.UDPAddr {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]*net.UDPAddr(nil), d.locals...)
+}
+
+func (d *recordingDialer) dialedAddrs() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.remotes...)
+}
+
+func TestConnectBindsAcrossReconnects(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.cfg.BindAddress = "192.0.2.10:62031"
+	client.cfg.MasterServer = "198.51.100.1:62031"
+	d := &recordingDialer{}
+	client.dial = d.dial
+
+	if err := client.connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	client.reconnect("test")
+
+	locals := d.dialed()
+	if len(locals) != 2 {
+		t.Fatalf("expected two dials, got %d", len(locals))
+	}
+	for i, local := range locals {
+		if local == nil || local.String() != "192.0.2.10:62031" {
+			t.Fatalf("dial %d: expected the bind address, got %v", i, local)
+		}
+	}
+}
+
+func TestConnectWithoutBindAddressLetsKernelPick(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.cfg.MasterServer = "198.51.100.1:62031"
+	d := &recordingDialer{}
+	client.dial = d.dial
+
+	if err := client.connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if locals := d.dialed(); len(locals) != 1 || locals[0] != nil {
+		t.Fatalf("expected one dial with no local address, got %v", locals)
+	}
+}
+
+func TestStartFailsOnInvalidBindAddress(t *testing.T) {
+	t.Parallel()
+	cfg := testMMDVMConfig()
+	cfg.MasterServer = "198.51.100.1:62031"
+	cfg.BindAddress = "gateway.example.com"
+	client := NewMMDVMClient(cfg, nil, TranslatorOptions{})
+	d := &recordingDialer{}
+	client.dial = d.dial
+
+	err := client.Start()
+	if !errors.Is(err, config.ErrInvalidMMDVMBindAddress) {
+		t.Fatalf("expected %v, got %v", config.ErrInvalidMMDVMBindAddress, err)
+	}
+	if locals := d.dialed(); len(locals) != 0 {
+		t.Fatalf("expected no dial, got %v", locals)
+	}
+	if got := client.State(); got != STATE_IDLE {
+		t.Fatalf("expected state idle, got %s", got)
+	}
+}
+
+func TestProbeBindsAddressOnly(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.cfg.BindAddress = "192.0.2.10:62031"
+	d := &recordingDialer{}
+	client.dial = d.dial
+
+	if client.probeMaster("198.51.100.2:62031") {
+		t.Fatal("expected the probe to fail on a closed pipe")
+	}
+	locals := d.dialed()
+	if len(locals) != 1 || locals[0] == nil || locals[0].String() != "192.0.2.10:0" {
+		t.Fatalf("expected the probe from the bind address on any port, got %v", locals)
+	}
+}
+
+// rotatingResolver answers each lookup with the next of answers, starting
+// over after the last, and counts the lookups.
+type rotatingResolver struct {
+	answers [][]string
+	lookups atomic.Int32
+}
+
+func (r *rotatingResolver) resolve(_ context.Context, host string) ([]net.IP, error) {
+	if host != "master.example.com" {
+		return nil, errors.New("unexpected host " + host)
+	}
+	answer := r.answers[int(r.lookups.Add(1)-1)%len(r.answers)]
+	ips := make([]net.IP, 0, len(answer))
+	for _, a := range answer {
+		ips = append(ips, net.ParseIP(a))
+	}
+	return ips, nil
+}
+
+// dialMasterTimes connects client, then reconnects it until it has dialed
+// n times, and returns the addresses dialed.
+func dialMasterTimes(t *testing.T, client *MMDVMClient, n int) []string {
+	t.Helper()
+	d := &recordingDialer{}
+	client.dial = d.dial
+	if err := client.connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	for range n - 1 {
+		client.reconnect("test")
+	}
+	return d.dialedAddrs()
+}
+
+func TestConnectResolvesMasterEachTime(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		answers [][]string
+		want    []string
+	}{
+		{
+			"address changes",
+			[][]string{{"192.0.2.1"}, {"192.0.2.2"}, {"192.0.2.3"}},
+			[]string{"192.0.2.1:62031", "192.0.2.2:62031", "192.0.2.3:62031"},
+		},
+		{
+			"last address passed over",
+			[][]string{{"192.0.2.1", "192.0.2.2"}},
+			[]string{"192.0.2.1:62031", "192.0.2.2:62031", "192.0.2.1:62031"},
+		},
+		{
+			"round-robin order followed",
+			[][]string{{"192.0.2.1", "192.0.2.2"}, {"192.0.2.2", "192.0.2.1"}, {"192.0.2.1", "192.0.2.2"}},
+			[]string{"192.0.2.1:62031", "192.0.2.2:62031", "192.0.2.1:62031"},
+		},
+		{
+			"only address reused",
+			[][]string{{"192.0.2.1"}},
+			[]string{"192.0.2.1:62031", "192.0.2.1:62031", "192.0.2.1:62031"},
+		},
+		{
+			"IPv6",
+			[][]string{{"2001:db8::1", "2001:db8::2"}},
+			[]string{"[2001:db8::1]:62031", "[2001:db8::2]:62031", "[2001:db8::1]:62031"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			client := newTestClient(t)
+			client.cfg.MasterServer = "master.example.com:62031"
+			r := &rotatingResolver{answers: tt.answers}
+			client.resolve = r.resolve
+
+			got := dialMasterTimes(t, client, len(tt.want))
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("expected %v dialed, got %v", tt.want, got)
+			}
+			if n := int(r.lookups.Load()); n != len(tt.want) {
+				t.Fatalf("expected a lookup per connection, got %d for %d", n, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestConnectSkipsLookupForAddress(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.cfg.MasterServer = "198.51.100.1:62031"
+	r := &rotatingResolver{answers: [][]string{{"192.0.2.1"}}}
+	client.resolve = r.resolve
+
+	if got := dialMasterTimes(t, client, 2); !slices.Equal(got, []string{"198.51.100.1:62031", "198.51.100.1:62031"}) {
+		t.Fatalf("expected the configured address dialed, got %v", got)
+	}
+	if n := r.lookups.Load(); n != 0 {
+		t.Fatalf("expected no lookups, got %d", n)
+	}
+}
+
+func TestConnectResolveTimeout(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.cfg.MasterServer = "master.example.com:62031"
+	client.resolveTimeout = 20 * time.Millisecond
+	client.resolve = func(ctx context.Context, _ string) ([]net.IP, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	d := &recordingDialer{}
+	client.dial = d.dial
+
+	if err := client.connect(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the lookup to time out, got %v", err)
+	}
+	if got := d.dialedAddrs(); len(got) != 0 {
+		t.Fatalf("expected no dial, got %v", got)
+	}
+}
@@ -0,0 +1,313 @@
+package mmdvm
+
+import (
+	"encoding/binary"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/timeslot"
+)
+
+// Failover policy defaults, used when the config leaves a value unset.
+const (
+	defaultFailbackInterval = 60 * time.Second
+	defaultMinHold          = 120 * time.Second
+	defaultNAKThreshold     = 3
+	defaultProbeTimeout     = 2 * time.Second
+)
+
+// masterSet tracks the ordered list of masters a client may connect to,
+// which one is currently active, and how the active one is behaving.
+// Index 0 is the primary; higher indices are standbys in priority order.
+type masterSet struct {
+	mu       sync.Mutex
+	addrs    []string
+	active   int
+	since    time.Time // when the active master was selected
+	switches uint64
+	naks     int // consecutive NAKs from the active master
+}
+
+func newMasterSet(addrs []string) *masterSet {
+	return &masterSet{
+		addrs: addrs,
+		since: time.Now(),
+	}
+}
+
+func (s *masterSet) activeAddr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addrs[s.active]
+}
+
+func (s *masterSet) activeIndex() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active
+}
+
+func (s *masterSet) addr(i int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addrs[i]
+}
+
+func (s *masterSet) len() int {
+	return len(s.addrs)
+}
+
+// held returns how long the active master has been selected.
+func (s *masterSet) held() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Since(s.since)
+}
+
+// next returns the index of the master after the active one, wrapping
+// back to the primary after the last standby.
+func (s *masterSet) next() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return (s.active + 1) % len(s.addrs)
+}
+
+// switchTo makes master i active and returns the address of the master
+// it replaced.
+func (s *masterSet) switchTo(i int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev := s.addrs[s.active]
+	s.active = i
+	s.since = time.Now()
+	s.switches++
+	s.naks = 0
+	return prev
+}
+
+func (s *masterSet) switchCount() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.switches
+}
+
+// recordNAK counts a NAK from the active master and returns the number
+// of consecutive NAKs seen so far.
+func (s *masterSet) recordNAK() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.naks++
+	return s.naks
+}
+
+func (s *masterSet) resetNAKs() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.naks = 0
+}
+
+// ActiveMaster returns the address of the master this client is
+// currently connected (or connecting) to.
+func (h *MMDVMClient) ActiveMaster() string {
+	if h.masters == nil {
+		return h.cfg.MasterServer
+	}
+	return h.masters.activeAddr()
+}
+
+// MasterSwitches returns how many times this client has switched masters.
+func (h *MMDVMClient) MasterSwitches() uint64 {
+	if h.masters == nil {
+		return 0
+	}
+	return h.masters.switchCount()
+}
+
+// canFailover reports whether a standby master is available.
+func (h *MMDVMClient) canFailover() bool {
+	return h.masters != nil && h.masters.len() > 1
+}
+
+// handleConnectionLoss is called when the active master stops answering.
+// With standbys configured it fails over to the next master, otherwise it
+// reconnects to the same one.
+func (h *MMDVMClient) handleConnectionLoss() {
+	if h.canFailover() {
+		h.switchMaster(h.masters.next(), "timeout")
+		return
+	}
+	h.reconnect("timeout")
+}
+
+// handleNAK records a NAK from the active master. It returns true if the
+// NAK threshold was reached and the client failed over, in which case the
+// caller must not retry against the old master.
+func (h *MMDVMClient) handleNAK() bool {
+	if !h.canFailover() {
+		return false
+	}
+	if h.masters.recordNAK() < h.nakThreshold {
+		return false
+	}
+	h.switchMaster(h.masters.next(), "nak")
+	return true
+}
+
+// switchMaster terminates any calls in flight from the current master,
+// makes master i active, and reconnects to it.
+func (h *MMDVMClient) switchMaster(i int, reason string) {
+	h.terminateStreams()
+	prev := h.masters.switchTo(i)
+	next := h.masters.activeAddr()
+	slog.Warn("Switching MMDVM master", "network", h.cfg.Name, "from", prev, "to", next, "reason", reason)
+	if h.metrics != nil {
+		h.metrics.MMDVMActiveMaster.WithLabelValues(h.cfg.Name, prev).Set(0)
+		h.metrics.MMDVMActiveMaster.WithLabelValues(h.cfg.Name, next).Set(1)
+		h.metrics.MMDVMMasterSwitches.WithLabelValues(h.cfg.Name, reason).Inc()
+	}
+	h.reconnect(reason)
+	// The watchdog exits once a session is ready, so make sure one is
+	// watching the handshake with the new master.
+	h.startHandshakeWatchdog()
+}
+
+// failback periodically probes higher-priority masters while a standby
+// is active, and switches back to the first one that answers once the
+// standby has been held for at least minHold.
+func (h *MMDVMClient) failback() {
+	defer h.wg.Done()
+	defer h.recoverPanic()
+	ticker := time.NewTicker(h.failbackInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			active := h.masters.activeIndex()
+			if active == 0 || h.masters.held() < h.minHold {
+				continue
+			}
+			for i := 0; i < active; i++ {
+				if h.probeMaster(h.masters.addr(i)) {
+					h.switchMaster(i, "failback")
+					break
+				}
+			}
+		case <-h.done:
+			return
+		}
+	}
+}
+
+// probeMaster sends a login to addr on a separate socket and reports
+// whether the master acknowledged it. The probe login is closed again
+// so the master does not hold a half-open session.
+func (h *MMDVMClient) probeMaster(addr string) bool {
+	// The probe goes from the bind address too, but on a port of its own,
+	// as the session holds any configured one.
+	local, err := h.cfg.LocalAddr()
+	if err != nil {
+		return false
+	}
+	if local != nil {
+		local = &net.UDPAddr{IP: local.IP}
+	}
+	conn, err := h.dialer()(local, addr)
+	if err != nil {
+		slog.Debug("Failback probe failed", "network", h.cfg.Name, "master", addr, "error", err)
+		return false
+	}
+	defer conn.Close()
+
+	login := make([]byte, len("RPTL")+4)
+	n := copy(login, "RPTL")
+	binary.BigEndian.PutUint32(login[n:], h.radioID())
+	if _, err := conn.Write(login); err != nil {
+		slog.Debug("Failback probe failed", "network", h.cfg.Name, "master", addr, "error", err)
+		return false
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(h.probeTimeout)); err != nil {
+		return false
+	}
+	buf := make([]byte, 64)
+	n, err = conn.Read(buf)
+	if err != nil || n < len(rptAck) || string(buf[:len(rptAck)]) != rptAck {
+		slog.Debug("Failback probe not acknowledged", "network", h.cfg.Name, "master", addr)
+		return false
+	}
+
+	closeMsg := make([]byte, len("RPTCL")+4)
+	n = copy(closeMsg, "RPTCL")
+	binary.BigEndian.PutUint32(closeMsg[n:], h.radioID())
+	_, _ = conn.Write(closeMsg)
+	return true
+}
+
+// defaultStreamIdle is how long a stream toward IPSC may go quiet before
+// it is taken to be over. It is the timeslot manager's call timeout, after
+// which the slot goes to the next call, so a stream whose terminator was
+// lost is not ended again long after the fact.
+const defaultStreamIdle = timeslot.DefaultTimeout
+
+// trackedStream is the latest packet of a stream delivered toward IPSC
+// and when it was delivered.
+type trackedStream struct {
+	last proto.Packet
+	seen time.Time
+}
+
+// trackStream remembers the latest packet of each stream being delivered
+// toward IPSC so the call can be terminated if the master changes. Only
+// streams admitted to their timeslot are tracked; those buffered, refused
+// or discarded by the timeslot manager never reached IPSC.
+func (h *MMDVMClient) trackStream(packet proto.Packet, isTerminator bool) {
+	h.streamsMu.Lock()
+	defer h.streamsMu.Unlock()
+	if isTerminator {
+		delete(h.activeStreams, packet.StreamID)
+		return
+	}
+	if h.activeStreams == nil {
+		h.activeStreams = make(map[uint]trackedStream)
+	}
+	h.activeStreams[packet.StreamID] = trackedStream{last: packet, seen: time.Now()}
+}
+
+// terminateStreams synthesizes a voice terminator for every stream still
+// in flight toward IPSC, so repeaters don't hang on a call the new master
+// will never finish. Streams quiet for longer than streamIdle are already
+// over and left alone.
+func (h *MMDVMClient) terminateStreams() {
+	h.streamsMu.Lock()
+	streams := h.activeStreams
+	h.activeStreams = nil
+	h.streamsMu.Unlock()
+
+	idle := h.streamIdle
+	if idle == 0 {
+		idle = defaultStreamIdle
+	}
+	for _, stream := range streams {
+		if time.Since(stream.seen) > idle {
+			continue
+		}
+		last := stream.last
+		terminator := proto.Packet{
+			Signature:   "DMRD",
+			Seq:         last.Seq + 1,
+			Src:         last.Src,
+			Dst:         last.Dst,
+			Repeater:    last.Repeater,
+			Slot:        last.Slot,
+			GroupCall:   last.GroupCall,
+			FrameType:   frameTypeDataSync,
+			DTypeOrVSeq: dtypeTerminatorWithLC,
+			StreamID:    last.StreamID,
+		}
+		slog.Debug("Terminating in-flight stream", "network", h.cfg.Name, "streamID", last.StreamID, "slot", last.Slot)
+		h.deliverToIPSC(terminator)
+	}
+}
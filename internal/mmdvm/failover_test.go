@@ -0,0 +1,420 @@
+package mmdvm
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/timeslot"
+)
+
+// Behaviors for failoverMaster.
+const (
+	masterSilent int32 = iota // never answers
+	masterAck                 // accepts every login
+	masterNAK                 // rejects every login
+)
+
+// failoverMaster is a minimal MMDVM master whose behavior can be changed
+// while a client is connected to it.
+type failoverMaster struct {
+	conn     *net.UDPConn
+	behavior atomic.Int32
+	wg       sync.WaitGroup
+
+	logins  atomic.Int32 // RPTL packets received
+	configs atomic.Int32 // RPTC packets received (handshakes completed)
+}
+
+func newFailoverMaster(t *testing.T, behavior int32) *failoverMaster {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	m := &failoverMaster{conn: conn}
+	m.behavior.Store(behavior)
+	m.wg.Add(1)
+	go m.serve()
+	t.Cleanup(func() {
+		m.conn.Close()
+		m.wg.Wait()
+	})
+	return m
+}
+
+func (m *failoverMaster) addr() string {
+	return m.conn.LocalAddr().String()
+}
+
+func (m *failoverMaster) serve() {
+	defer m.wg.Done()
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := m.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		data := buf[:n]
+		if n >= 4 && string(data[:4]) == tagRPTL {
+			m.logins.Add(1)
+		}
+		switch m.behavior.Load() {
+		case masterSilent:
+			continue
+		case masterNAK:
+			if n >= 4 && string(data[:4]) == tagRPTL {
+				_, _ = m.conn.WriteToUDP([]byte("MSTNAK__________"), addr)
+			}
+			continue
+		}
+		switch {
+		case n >= 7 && string(data[:7]) == tagRPTPING:
+			_, _ = m.conn.WriteToUDP([]byte("MSTPONG_________"), addr)
+		case n >= 5 && string(data[:5]) == tagRPTCL:
+		case n >= 4 && string(data[:4]) == tagRPTL:
+			_, _ = m.conn.WriteToUDP([]byte("RPTACKabcd"), addr)
+		case n >= 4 && string(data[:4]) == tagRPTK:
+			_, _ = m.conn.WriteToUDP([]byte("RPTACK__________"), addr)
+		case n >= 4 && string(data[:4]) == tagRPTC:
+			m.configs.Add(1)
+			_, _ = m.conn.WriteToUDP([]byte("RPTACK__________"), addr)
+		}
+	}
+}
+
+func newFailoverClient(t *testing.T, masters ...*failoverMaster) *MMDVMClient {
+	t.Helper()
+	cfg := testMMDVMConfig()
+	for _, m := range masters {
+		cfg.Masters = append(cfg.Masters, m.addr())
+	}
+	client := NewMMDVMClient(cfg, nil, TranslatorOptions{})
+	client.keepAlive = 50 * time.Millisecond
+	client.timeout = 300 * time.Millisecond
+	client.failbackInterval = 50 * time.Millisecond
+	client.minHold = 0
+	client.probeTimeout = 100 * time.Millisecond
+	t.Cleanup(client.Stop)
+	return client
+}
+
+// waitReadyOn waits until the client has completed a handshake with addr.
+func waitReadyOn(t *testing.T, client *MMDVMClient, addr string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		//nolint:gosec // G115: test-only, state values fit in uint8
+		if client.ActiveMaster() == addr && State(client.state.Load()) == STATE_READY {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("client not ready on %s (active %s, state %d)", addr, client.ActiveMaster(), client.state.Load())
+}
+
+func TestMasterServersFallsBackToMasterServer(t *testing.T) {
+	t.Parallel()
+	cfg := testMMDVMConfig()
+	cfg.MasterServer = "127.0.0.1:62031"
+	client := NewMMDVMClient(cfg, nil, TranslatorOptions{})
+	if client.ActiveMaster() != "127.0.0.1:62031" {
+		t.Fatalf("expected master-server to be active, got %s", client.ActiveMaster())
+	}
+	if client.canFailover() {
+		t.Fatal("expected no failover with a single master")
+	}
+}
+
+func TestFailoverConfigDefaults(t *testing.T) {
+	t.Parallel()
+	cfg := testMMDVMConfig()
+	cfg.Failover = config.FailoverConfig{FailbackInterval: 5, NAKThreshold: 7}
+	client := NewMMDVMClient(cfg, nil, TranslatorOptions{})
+	if client.failbackInterval != 5*time.Second {
+		t.Fatalf("expected 5s failback interval, got %s", client.failbackInterval)
+	}
+	if client.minHold != defaultMinHold {
+		t.Fatalf("expected default min hold, got %s", client.minHold)
+	}
+	if client.nakThreshold != 7 {
+		t.Fatalf("expected NAK threshold 7, got %d", client.nakThreshold)
+	}
+}
+
+func TestFailoverOnTimeout(t *testing.T) {
+	t.Parallel()
+	primary := newFailoverMaster(t, masterSilent)
+	secondary := newFailoverMaster(t, masterAck)
+	client := newFailoverClient(t, primary, secondary)
+	client.failbackInterval = time.Hour
+
+	if err := client.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	waitReadyOn(t, client, secondary.addr())
+	if primary.logins.Load() == 0 {
+		t.Fatal("expected the primary to be tried first")
+	}
+	if got := client.MasterSwitches(); got != 1 {
+		t.Fatalf("expected 1 switch, got %d", got)
+	}
+}
+
+func TestFailoverOnRepeatedNAK(t *testing.T) {
+	t.Parallel()
+	primary := newFailoverMaster(t, masterNAK)
+	secondary := newFailoverMaster(t, masterAck)
+	client := newFailoverClient(t, primary, secondary)
+	client.timeout = 10 * time.Second // rule out the timeout path
+	client.nakThreshold = 2
+	client.failbackInterval = time.Hour
+
+	if err := client.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	waitReadyOn(t, client, secondary.addr())
+	if got := primary.logins.Load(); got != 2 {
+		t.Fatalf("expected 2 logins to the primary before failover, got %d", got)
+	}
+	if got := client.MasterSwitches(); got != 1 {
+		t.Fatalf("expected 1 switch, got %d", got)
+	}
+}
+
+func TestFailbackToPrimary(t *testing.T) {
+	t.Parallel()
+	primary := newFailoverMaster(t, masterSilent)
+	secondary := newFailoverMaster(t, masterAck)
+	client := newFailoverClient(t, primary, secondary)
+
+	if err := client.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitReadyOn(t, client, secondary.addr())
+
+	primary.behavior.Store(masterAck)
+	waitReadyOn(t, client, primary.addr())
+	if primary.configs.Load() == 0 {
+		t.Fatal("expected a full handshake with the primary after failback")
+	}
+	if got := client.MasterSwitches(); got != 2 {
+		t.Fatalf("expected 2 switches, got %d", got)
+	}
+}
+
+func TestFailbackRespectsMinHold(t *testing.T) {
+	t.Parallel()
+	primary := newFailoverMaster(t, masterSilent)
+	secondary := newFailoverMaster(t, masterAck)
+	client := newFailoverClient(t, primary, secondary)
+	client.minHold = time.Hour
+
+	if err := client.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitReadyOn(t, client, secondary.addr())
+
+	primary.behavior.Store(masterAck)
+	time.Sleep(300 * time.Millisecond)
+	if client.ActiveMaster() != secondary.addr() {
+		t.Fatalf("expected to stay on the secondary during min hold, got %s", client.ActiveMaster())
+	}
+}
+
+func TestSwitchoverTerminatesInFlightStreams(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+
+	var mu sync.Mutex
+	var sent [][]byte
+	client.SetIPSCHandler(func(data []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, data)
+	})
+
+	voice := proto.Packet{
+		Signature: "DMRD",
+		Src:       1234567,
+		Dst:       91,
+		Slot:      true,
+		GroupCall: true,
+		FrameType: 1,
+		StreamID:  42,
+	}
+	client.deliverToIPSC(voice)
+
+	mu.Lock()
+	before := len(sent)
+	mu.Unlock()
+
+	client.terminateStreams()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != before+1 {
+		t.Fatalf("expected 1 synthesized terminator, got %d packets", len(sent)-before)
+	}
+	term := sent[len(sent)-1]
+	if term[17]&0x40 == 0 {
+		t.Fatalf("expected call end flag in call info, got 0x%02X", term[17])
+	}
+	if term[17]&0x20 == 0 {
+		t.Fatal("expected terminator on TS2")
+	}
+	if src := uint(term[6])<<16 | uint(term[7])<<8 | uint(term[8]); src != voice.Src {
+		t.Fatalf("expected src %d, got %d", voice.Src, src)
+	}
+
+	// A second switchover has nothing left to terminate.
+	client.terminateStreams()
+	if len(sent) != before+1 {
+		t.Fatal("expected no further terminators")
+	}
+}
+
+func TestSwitchoverLeavesIdleStreams(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.streamIdle = 50 * time.Millisecond
+	var sent atomic.Int32
+	client.SetIPSCHandler(func([]byte) { sent.Add(1) })
+
+	client.deliverToIPSC(proto.Packet{
+		Signature: "DMRD",
+		Src:       1234567,
+		Dst:       91,
+		Slot:      true,
+		GroupCall: true,
+		FrameType: 1,
+		StreamID:  42,
+	})
+
+	// The call's terminator was lost, and it has been quiet for longer
+	// than a call may be.
+	time.Sleep(2 * client.streamIdle)
+	before := sent.Load()
+	client.terminateStreams()
+	if got := sent.Load() - before; got != 0 {
+		t.Fatalf("expected no terminator for an idle stream, got %d packets", got)
+	}
+}
+
+func TestSwitchoverLeavesBufferedStreams(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.outboundTSMgr = timeslot.NewManager()
+
+	var mu sync.Mutex
+	var sent [][]byte
+	client.SetIPSCHandler(func(data []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, data)
+	})
+
+	voice := proto.Packet{
+		Signature: "DMRD",
+		Src:       1234567,
+		Dst:       91,
+		Slot:      true,
+		GroupCall: true,
+		FrameType: 1,
+		StreamID:  42,
+	}
+	client.deliverToIPSC(voice)
+	// A second call on the busy slot is buffered and has not reached
+	// IPSC.
+	buffered := voice
+	buffered.Src = 7654321
+	buffered.StreamID = 43
+	client.deliverToIPSC(buffered)
+
+	mu.Lock()
+	before := len(sent)
+	mu.Unlock()
+	client.terminateStreams()
+
+	mu.Lock()
+	defer mu.Unlock()
+	// Ending the first call releases the buffered one, which must not be
+	// ended as well.
+	var terminators [][]byte
+	for _, data := range sent[before:] {
+		if data[30] == 0x02 {
+			terminators = append(terminators, data)
+		}
+	}
+	if len(terminators) != 1 {
+		t.Fatalf("expected 1 synthesized terminator, got %d", len(terminators))
+	}
+	term := terminators[0]
+	if src := uint(term[6])<<16 | uint(term[7])<<8 | uint(term[8]); src != voice.Src {
+		t.Fatalf("expected the terminator for the call on the slot from %d, got %d", voice.Src, src)
+	}
+}
+
+func TestStartStopRestart(t *testing.T) {
+	t.Parallel()
+	master := newFailoverMaster(t, masterAck)
+	client := newFailoverClient(t, master)
+
+	for i := range 2 {
+		if err := client.Start(); err != nil {
+			t.Fatalf("Start #%d: %v", i+1, err)
+		}
+		// A second Start on a running client is a no-op.
+		if err := client.Start(); err != nil {
+			t.Fatalf("repeated Start #%d: %v", i+1, err)
+		}
+		waitReadyOn(t, client, master.addr())
+		client.Stop()
+		client.Stop()
+	}
+	if got := master.configs.Load(); got != 2 {
+		t.Fatalf("expected 2 handshakes, got %d", got)
+	}
+}
+
+func TestPanicHandlerRecoversGoroutinePanic(t *testing.T) {
+	t.Parallel()
+	master := newFailoverMaster(t, masterAck)
+	client := newFailoverClient(t, master)
+
+	panics := make(chan any, 1)
+	client.SetPanicHandler(func(recovered any, _ []byte) {
+		select {
+		case panics <- recovered:
+		default:
+		}
+	})
+	if err := client.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitReadyOn(t, client, master.addr())
+
+	// A nil connection makes the next write panic inside tx().
+	client.connMu.Lock()
+	conn := client.conn
+	client.conn = nil
+	client.connMu.Unlock()
+	defer conn.Close()
+	client.sendPing()
+
+	select {
+	case <-panics:
+	case <-time.After(2 * time.Second):
+		t.Fatal("panic was not reported")
+	}
+}
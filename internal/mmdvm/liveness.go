@@ -0,0 +1,79 @@
+package mmdvm
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/jitter"
+)
+
+// defaultMaxMissedPongs is how many pings in a row may go unanswered
+// before the master is considered lost, used when the config leaves
+// max-missed-pongs unset.
+const defaultMaxMissedPongs = 3
+
+// tickerFunc starts a ticker firing about every period, returning its
+// channel and the function that stops it.
+type tickerFunc func(period time.Duration) (<-chan time.Time, func())
+
+// clock returns the time pings and pongs are timed by.
+func (h *MMDVMClient) clock() time.Time {
+	if h.now != nil {
+		return h.now()
+	}
+	return time.Now()
+}
+
+// startPingTicker starts the ticker sending a ping every keepalive,
+// jittered so clients started together do not ping in step.
+func (h *MMDVMClient) startPingTicker() (<-chan time.Time, func()) {
+	if h.pingTicker != nil {
+		return h.pingTicker(h.keepAlive)
+	}
+	ticker := jitter.NewTicker(h.keepAlive, jitter.DefaultFraction, h.jitter)
+	return ticker.C, ticker.Stop
+}
+
+// recordRTT records the round trip time of a ping the master answered,
+// smoothing it as TCP does, with a gain of 1/8.
+func (h *MMDVMClient) recordRTT(rtt time.Duration) {
+	h.lastRTT.Store(int64(rtt))
+	smoothed := time.Duration(h.smoothedRTT.Load())
+	if smoothed == 0 {
+		smoothed = rtt
+	} else {
+		smoothed += (rtt - smoothed) / 8
+	}
+	h.smoothedRTT.Store(int64(smoothed))
+	if h.metrics != nil {
+		h.metrics.MMDVMPingRTT.WithLabelValues(h.cfg.Name).Observe(rtt.Seconds())
+	}
+}
+
+// pongMissed counts a ping left unanswered until the next one is due. It
+// reports whether that makes too many in a row.
+func (h *MMDVMClient) pongMissed() bool {
+	missed := h.missedPongs.Add(1)
+	limit := h.maxMissedPongs
+	if limit == 0 {
+		limit = defaultMaxMissedPongs
+	}
+	if missed < limit {
+		slog.Debug("MMDVM master missed a pong", "network", h.cfg.Name, "missed", missed)
+		return false
+	}
+	slog.Warn("MMDVM master stopped answering pings, reconnecting", "network", h.cfg.Name, "missed", missed)
+	return true
+}
+
+// LastRTT returns the round trip time of the last ping the master
+// answered, or zero if it has answered none.
+func (h *MMDVMClient) LastRTT() time.Duration {
+	return time.Duration(h.lastRTT.Load())
+}
+
+// MissedPongs returns how many pings in a row the master has left
+// unanswered.
+func (h *MMDVMClient) MissedPongs() uint32 {
+	return h.missedPongs.Load()
+}
@@ -0,0 +1,214 @@
+package mmdvm
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// answerPings plays the master for client: it answers the first answered
+// pings after delay and ignores the rest, reporting each ping on pings.
+// Anything else the client sends is passed on to other.
+func answerPings(client *MMDVMClient, answered int, delay time.Duration, pings chan<- int, other chan<- []byte) {
+	n := 0
+	for {
+		select {
+		case data := <-client.connTX:
+			if len(data) < len(tagRPTPING) || string(data[:len(tagRPTPING)]) != tagRPTPING {
+				other <- data
+				continue
+			}
+			n++
+			if n <= answered {
+				time.Sleep(delay)
+				client.connRX <- []byte("MSTPONG_________")
+			}
+			pings <- n
+		case <-client.done:
+			return
+		}
+	}
+}
+
+func TestPongsTrackRTTAndMisses(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.keepAlive = 30 * time.Millisecond
+	client.timeout = time.Hour
+	client.maxMissedPongs = 3
+	client.state.Store(uint32(STATE_READY))
+	client.wg.Add(2)
+	client.pingRunning.Store(true)
+	go client.handler()
+	go client.ping()
+	defer func() {
+		close(client.done)
+		client.wg.Wait()
+	}()
+
+	pings := make(chan int, 16)
+	other := make(chan []byte, 16)
+	go answerPings(client, 2, 5*time.Millisecond, pings, other)
+
+	// Two answered pings, then the third goes unanswered.
+	for want := 1; want <= 3; want++ {
+		select {
+		case n := <-pings:
+			if n != want {
+				t.Fatalf("expected ping %d, got %d", want, n)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for ping %d", want)
+		}
+	}
+	if rtt := client.LastRTT(); rtt < 5*time.Millisecond {
+		t.Fatalf("expected an RTT of at least 5ms, got %v", rtt)
+	}
+	if rtt := client.Stats().RTT; rtt < 5*time.Millisecond {
+		t.Fatalf("expected a smoothed RTT of at least 5ms, got %v", rtt)
+	}
+
+	// The fourth ping is sent after one miss, the fifth after two.
+	for want := uint32(1); want <= 2; want++ {
+		select {
+		case <-pings:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for a ping")
+		}
+		if got := client.MissedPongs(); got != want {
+			t.Fatalf("expected %d missed pongs, got %d", want, got)
+		}
+	}
+
+	// The third miss in a row ends the session.
+	select {
+	case data := <-other:
+		if string(data[:len(tagRPTL)]) != tagRPTL {
+			t.Fatalf("expected RPTL, got %q", data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the reconnect")
+	}
+	if got := client.State(); got != STATE_SENT_LOGIN {
+		t.Fatalf("expected state sent-login, got %s", got)
+	}
+	select {
+	case n := <-pings:
+		t.Fatalf("expected no ping after the reconnect, got ping %d", n)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestPongResetsMisses(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.state.Store(uint32(STATE_READY))
+	client.missedPongs.Store(2)
+	client.wg.Add(1)
+	go client.handler()
+	defer func() {
+		close(client.done)
+		client.wg.Wait()
+	}()
+
+	client.sendPing()
+	<-client.connTX
+	client.connRX <- []byte("MSTPONG_________")
+	deadline := time.Now().Add(2 * time.Second)
+	for client.MissedPongs() != 0 || client.LastRTT() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the misses reset and an RTT, got %d and %v", client.MissedPongs(), client.LastRTT())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// A repeated pong has no ping to time.
+	rtt := client.LastRTT()
+	client.connRX <- []byte("MSTPONG_________")
+	time.Sleep(20 * time.Millisecond)
+	if got := client.LastRTT(); got != rtt {
+		t.Fatalf("expected the RTT kept at %v, got %v", rtt, got)
+	}
+}
+
+func TestRecordRTTSmooths(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.recordRTT(80 * time.Millisecond)
+	client.recordRTT(160 * time.Millisecond)
+	if got := client.LastRTT(); got != 160*time.Millisecond {
+		t.Fatalf("expected the last RTT 160ms, got %v", got)
+	}
+	if got := client.Stats().RTT; got != 90*time.Millisecond {
+		t.Fatalf("expected the smoothed RTT 90ms, got %v", got)
+	}
+}
+
+func TestPingFollowsConfiguredKeepAliveAndTimeout(t *testing.T) {
+	t.Parallel()
+	serverConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer serverConn.Close()
+	cfg := testMMDVMConfig()
+	cfg.MasterServer = serverConn.LocalAddr().String()
+	cfg.KeepAlive = 7
+	cfg.Timeout = 20
+	client := NewMMDVMClient(cfg, nil, TranslatorOptions{})
+	if err := client.connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	var mu sync.Mutex
+	now := time.Unix(1_000_000, 0)
+	client.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	// tick moves the clock on by d and lets the ping routine look at it.
+	ticks := make(chan time.Time)
+	tick := func(d time.Duration) {
+		mu.Lock()
+		now = now.Add(d)
+		at := now
+		mu.Unlock()
+		ticks <- at
+	}
+	periods := make(chan time.Duration, 1)
+	client.pingTicker = func(period time.Duration) (<-chan time.Time, func()) {
+		periods <- period
+		return ticks, func() {}
+	}
+
+	client.state.Store(uint32(STATE_READY))
+	client.pingRunning.Store(true)
+	client.wg.Add(1)
+	go client.ping()
+	defer func() {
+		close(client.done)
+		client.wg.Wait()
+	}()
+
+	if period := <-periods; period != 7*time.Second {
+		t.Fatalf("expected pings every 7s, got %v", period)
+	}
+	expectSent(t, client, tagRPTPING)
+
+	// An answered ping keeps the session up.
+	tick(7 * time.Second)
+	expectSent(t, client, tagRPTPING)
+	client.handleReady([]byte("MSTPONG_________"))
+
+	// Unanswered pings end it once the timeout has passed since the last
+	// pong, and not before.
+	tick(19 * time.Second)
+	expectSent(t, client, tagRPTPING)
+	tick(2 * time.Second)
+	expectSent(t, client, tagRPTL)
+	if got := client.State(); got != STATE_SENT_LOGIN {
+		t.Fatalf("expected state sent-login, got %s", got)
+	}
+}
@@ -0,0 +1,135 @@
+package mmdvm
+
+import (
+	"slices"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+)
+
+const (
+	// frameInterval is the DMR frame cadence at which voice is released
+	// to the master.
+	frameInterval = 60 * time.Millisecond
+	// pacingDepth is how many voice frames may wait on a slot, about a
+	// second of audio, before the oldest are dropped.
+	pacingDepth = 16
+	// frameTypeVoice and frameTypeVoiceSync are the FrameType values of
+	// voice bursts, and dtypeVoiceLCHeader the DataType of a voice header.
+	frameTypeVoice     uint = 0
+	frameTypeVoiceSync uint = 1
+	dtypeVoiceLCHeader uint = 1
+)
+
+// isVoiceFrame reports whether pkt belongs to a voice call: a voice
+// burst, or the header or terminator framing one. Everything else,
+// including data calls and reverse-channel bursts, goes out unpaced.
+func isVoiceFrame(pkt proto.Packet) bool {
+	switch pkt.FrameType {
+	case frameTypeVoice, frameTypeVoiceSync:
+		return true
+	case frameTypeDataSync:
+		return pkt.DTypeOrVSeq == dtypeVoiceLCHeader || pkt.DTypeOrVSeq == dtypeTerminatorWithLC
+	default:
+		return false
+	}
+}
+
+// isVoiceBurst reports whether pkt is a voice burst, as opposed to the
+// header or terminator framing a call.
+func isVoiceBurst(pkt proto.Packet) bool {
+	return pkt.FrameType == frameTypeVoice || pkt.FrameType == frameTypeVoiceSync
+}
+
+// pacer holds voice frames bound for the master and releases at most one
+// per frame interval on each slot, so bursts produced faster than real
+// time do not overrun the master's jitter buffer. It is not safe for
+// concurrent use; the client's forwardTX goroutine owns it.
+type pacer struct {
+	interval time.Duration
+	depth    int
+	// queues and next are indexed by slot: 0 for TS1, 1 for TS2. next
+	// is when the slot may release its next frame.
+	queues [2][]proto.Packet
+	next   [2]time.Time
+}
+
+func newPacer() *pacer {
+	return &pacer{interval: frameInterval, depth: pacingDepth}
+}
+
+func slotIndex(slot bool) int {
+	if slot {
+		return 1
+	}
+	return 0
+}
+
+// push queues a voice frame. If its slot's queue is full, a frame is
+// dropped to make room and returned: the oldest voice burst, or pkt
+// itself if it is a voice burst and none is queued, so the header and
+// terminator framing a call get through. Only a queue of nothing but
+// headers and terminators loses its oldest.
+func (p *pacer) push(pkt proto.Packet) (dropped proto.Packet, overrun bool) {
+	i := slotIndex(pkt.Slot)
+	queue := p.queues[i]
+	if len(queue) < p.depth {
+		p.queues[i] = append(queue, pkt)
+		return proto.Packet{}, false
+	}
+	victim := slices.IndexFunc(queue, isVoiceBurst)
+	if victim < 0 {
+		if isVoiceBurst(pkt) {
+			return pkt, true
+		}
+		victim = 0
+	}
+	dropped = queue[victim]
+	p.queues[i] = append(slices.Delete(queue, victim, victim+1), pkt)
+	return dropped, true
+}
+
+// release returns the frames due at now, at most one per slot.
+func (p *pacer) release(now time.Time) []proto.Packet {
+	var due []proto.Packet
+	for i := range p.queues {
+		if len(p.queues[i]) == 0 || now.Before(p.next[i]) {
+			continue
+		}
+		due = append(due, p.queues[i][0])
+		p.queues[i] = p.queues[i][1:]
+		// Keep the cadence of a slot that is keeping up; after a gap,
+		// or a late wake-up, count from now instead.
+		start := p.next[i]
+		if now.Sub(start) >= p.interval {
+			start = now
+		}
+		p.next[i] = start.Add(p.interval)
+	}
+	return due
+}
+
+// reset drops every queued frame.
+func (p *pacer) reset() {
+	p.queues = [2][]proto.Packet{}
+	p.next = [2]time.Time{}
+}
+
+// wait returns how long after now the next queued frame is due, and false
+// if none is queued.
+func (p *pacer) wait(now time.Time) (time.Duration, bool) {
+	var (
+		soonest time.Duration
+		queued  bool
+	)
+	for i := range p.queues {
+		if len(p.queues[i]) == 0 {
+			continue
+		}
+		d := max(p.next[i].Sub(now), 0)
+		if !queued || d < soonest {
+			soonest, queued = d, true
+		}
+	}
+	return soonest, queued
+}
@@ -0,0 +1,264 @@
+package mmdvm
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+)
+
+func voiceFrame(slot bool, seq uint) proto.Packet {
+	return proto.Packet{Signature: "DMRD", Seq: seq, Slot: slot, FrameType: frameTypeVoice, StreamID: 1}
+}
+
+func TestIsVoiceFrame(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		pkt  proto.Packet
+		want bool
+	}{
+		{"voice", proto.Packet{FrameType: frameTypeVoice, DTypeOrVSeq: 3}, true},
+		{"voice sync", proto.Packet{FrameType: frameTypeVoiceSync}, true},
+		{"voice header", proto.Packet{FrameType: frameTypeDataSync, DTypeOrVSeq: dtypeVoiceLCHeader}, true},
+		{"terminator", proto.Packet{FrameType: frameTypeDataSync, DTypeOrVSeq: dtypeTerminatorWithLC}, true},
+		{"CSBK", proto.Packet{FrameType: frameTypeDataSync, DTypeOrVSeq: 3}, false},
+		{"data header", proto.Packet{FrameType: frameTypeDataSync, DTypeOrVSeq: 6}, false},
+		{"reverse channel", proto.Packet{FrameType: frameTypeReverseChan}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := isVoiceFrame(tt.pkt); got != tt.want {
+				t.Fatalf("isVoiceFrame() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPacerReleasesOneFramePerInterval(t *testing.T) {
+	t.Parallel()
+	p := newPacer()
+	start := time.Unix(1000, 0)
+	if _, queued := p.wait(start); queued {
+		t.Fatal("expected nothing queued")
+	}
+	for seq := range uint(3) {
+		p.push(voiceFrame(false, seq))
+	}
+
+	// The first frame goes at once, the rest one interval apart.
+	for seq := range uint(3) {
+		now := start.Add(time.Duration(seq) * frameInterval)
+		if wait, queued := p.wait(now); !queued || wait != 0 {
+			t.Fatalf("frame %d: expected one due, got %v, %v", seq, wait, queued)
+		}
+		due := p.release(now)
+		if len(due) != 1 || due[0].Seq != seq {
+			t.Fatalf("frame %d: expected it released, got %+v", seq, due)
+		}
+		if seq < 2 {
+			if due := p.release(now.Add(frameInterval - time.Millisecond)); len(due) != 0 {
+				t.Fatalf("frame %d: expected nothing due before the interval, got %+v", seq+1, due)
+			}
+		}
+	}
+	if _, queued := p.wait(start.Add(time.Second)); queued {
+		t.Fatal("expected the queue drained")
+	}
+}
+
+func TestPacerKeepsCadenceWhenWokenLate(t *testing.T) {
+	t.Parallel()
+	p := newPacer()
+	start := time.Unix(1000, 0)
+	for seq := range uint(3) {
+		p.push(voiceFrame(false, seq))
+	}
+	p.release(start)
+
+	// Woken 5 ms late, the next frame is still due on the original beat.
+	p.release(start.Add(frameInterval + 5*time.Millisecond))
+	if wait, _ := p.wait(start.Add(frameInterval + 5*time.Millisecond)); wait != frameInterval-5*time.Millisecond {
+		t.Fatalf("expected the third frame %v later, got %v", frameInterval-5*time.Millisecond, wait)
+	}
+}
+
+func TestPacerSlotsAreIndependent(t *testing.T) {
+	t.Parallel()
+	p := newPacer()
+	now := time.Unix(1000, 0)
+	p.push(voiceFrame(false, 1))
+	p.push(voiceFrame(false, 2))
+	p.push(voiceFrame(true, 1))
+
+	due := p.release(now)
+	if len(due) != 2 || due[0].Slot || !due[1].Slot {
+		t.Fatalf("expected one frame from each slot, got %+v", due)
+	}
+	if wait, queued := p.wait(now); !queued || wait != frameInterval {
+		t.Fatalf("expected the second TS1 frame in %v, got %v, %v", frameInterval, wait, queued)
+	}
+}
+
+func TestPacerDropsOldestWhenFull(t *testing.T) {
+	t.Parallel()
+	p := newPacer()
+	p.depth = 3
+	for seq := range uint(3) {
+		if _, overrun := p.push(voiceFrame(true, seq)); overrun {
+			t.Fatalf("frame %d: expected room in the queue", seq)
+		}
+	}
+	dropped, overrun := p.push(voiceFrame(true, 3))
+	if !overrun || dropped.Seq != 0 {
+		t.Fatalf("expected frame 0 dropped, got %+v, %v", dropped, overrun)
+	}
+
+	now := time.Unix(1000, 0)
+	var released []uint
+	for i := range 4 {
+		for _, pkt := range p.release(now.Add(time.Duration(i) * frameInterval)) {
+			released = append(released, pkt.Seq)
+		}
+	}
+	if len(released) != 3 || released[0] != 1 || released[2] != 3 {
+		t.Fatalf("expected frames 1 to 3 released, got %v", released)
+	}
+}
+
+func TestPacerKeepsHeadersAndTerminatorsWhenFull(t *testing.T) {
+	t.Parallel()
+	header := proto.Packet{Seq: 10, FrameType: frameTypeDataSync, DTypeOrVSeq: dtypeVoiceLCHeader}
+	terminator := proto.Packet{Seq: 20, FrameType: frameTypeDataSync, DTypeOrVSeq: dtypeTerminatorWithLC}
+	tests := []struct {
+		name        string
+		queued      []proto.Packet
+		pushed      proto.Packet
+		wantDropped uint
+		wantQueue   []uint
+	}{
+		{"header before voice", []proto.Packet{header, voiceFrame(false, 1), voiceFrame(false, 2)}, voiceFrame(false, 3), 1, []uint{10, 2, 3}},
+		{"terminator into voice", []proto.Packet{header, voiceFrame(false, 1), voiceFrame(false, 2)}, terminator, 1, []uint{10, 2, 20}},
+		{"voice behind framing", []proto.Packet{header, terminator, header}, voiceFrame(false, 3), 3, []uint{10, 20, 10}},
+		{"framing only", []proto.Packet{header, terminator, header}, terminator, 10, []uint{20, 10, 20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p := newPacer()
+			p.depth = len(tt.queued)
+			for _, pkt := range tt.queued {
+				p.push(pkt)
+			}
+			dropped, overrun := p.push(tt.pushed)
+			if !overrun || dropped.Seq != tt.wantDropped {
+				t.Fatalf("expected frame %d dropped, got %+v, %v", tt.wantDropped, dropped, overrun)
+			}
+			var queue []uint
+			for _, pkt := range p.queues[0] {
+				queue = append(queue, pkt.Seq)
+			}
+			if !slices.Equal(queue, tt.wantQueue) {
+				t.Fatalf("expected %v queued, got %v", tt.wantQueue, queue)
+			}
+		})
+	}
+}
+
+func TestForwardTXOverrunLeavesSeqGap(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.pacer = newPacer()
+	client.pacer.depth = 3
+	// Queued before forwardTX starts, so it takes them faster than the
+	// pacer lets them out.
+	for range 6 {
+		client.tx_chan <- voiceFrame(true, 0)
+	}
+	client.wg.Add(1)
+	go client.forwardTX()
+	defer func() {
+		close(client.done)
+		client.wg.Wait()
+	}()
+
+	// Frame 0 goes at once and 1 and 2 are dropped for 3 to 5.
+	var sent []uint
+	for range 4 {
+		select {
+		case data := <-client.connTX:
+			pkt, _ := proto.Decode(data)
+			sent = append(sent, pkt.Seq)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for a frame, got %v", sent)
+		}
+	}
+	if sent[0] != 0 || sent[1] != 3 || sent[3] != 5 {
+		t.Fatalf("expected Seq 0 then 3 to 5, leaving a gap for the dropped frames, got %v", sent)
+	}
+	if got := client.Stats().PacketsDropped; got != 2 {
+		t.Fatalf("expected 2 frames dropped, got %d", got)
+	}
+}
+
+func TestForwardTXPacesVoiceButNotData(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.pacer = newPacer()
+	client.wg.Add(1)
+	go client.forwardTX()
+	defer func() {
+		close(client.done)
+		client.wg.Wait()
+	}()
+
+	start := time.Now()
+	for seq := range uint(3) {
+		client.tx_chan <- voiceFrame(false, seq)
+	}
+	csbk := proto.Packet{Signature: "DMRD", FrameType: frameTypeDataSync, DTypeOrVSeq: 3, StreamID: 2}
+	client.tx_chan <- csbk
+
+	var order []proto.Packet
+	for range 4 {
+		select {
+		case data := <-client.connTX:
+			pkt, _ := proto.Decode(data)
+			order = append(order, pkt)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for a frame")
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 2*frameInterval {
+		t.Fatalf("expected three voice frames to take at least %v, took %v", 2*frameInterval, elapsed)
+	}
+	// The CSBK overtakes the queued voice.
+	if order[0].StreamID != 1 || order[1].StreamID != 2 || order[3].StreamID != 1 || order[3].Seq != 2 {
+		t.Fatalf("expected voice 0, the CSBK, then voice 1 and 2, got %+v", order)
+	}
+}
+
+func TestSetPacingOffSendsAtOnce(t *testing.T) {
+	t.Parallel()
+	client := NewMMDVMClient(testMMDVMConfig(), nil, TranslatorOptions{})
+	client.SetPacing(false)
+	client.wg.Add(1)
+	go client.forwardTX()
+	defer func() {
+		close(client.done)
+		client.wg.Wait()
+	}()
+
+	for seq := range uint(3) {
+		client.tx_chan <- voiceFrame(false, seq)
+	}
+	for seq := range 3 {
+		select {
+		case <-client.connTX:
+		case <-time.After(frameInterval / 2):
+			t.Fatalf("expected frame %d sent at once", seq)
+		}
+	}
+}
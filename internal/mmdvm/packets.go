@@ -4,19 +4,35 @@ import (
 	"crypto/sha256"
 	"encoding/binary"
 	"fmt"
-	"time"
 
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
 )
 
+// queue hands data to the tx goroutine. Once the client is stopped it
+// gives up instead of blocking, so a tx goroutine lost to a panic cannot
+// wedge the rest of the client.
+func (h *MMDVMClient) queue(data []byte) {
+	h.txPending.Add(1)
+	select {
+	case h.connTX <- data:
+		return
+	default:
+	}
+	select {
+	case h.connTX <- data:
+	case <-h.doneChan():
+		h.txPending.Add(-1)
+	}
+}
+
 func (h *MMDVMClient) sendLogin() {
 	var (
 		data = make([]byte, len("RPTL")+4)
 		n    = copy(data, "RPTL")
 	)
-	binary.BigEndian.PutUint32(data[n:], h.cfg.ID)
+	binary.BigEndian.PutUint32(data[n:], h.radioID())
 
-	h.connTX <- data
+	h.queue(data)
 }
 
 func (h *MMDVMClient) sendRPTCL() {
@@ -24,14 +40,14 @@ func (h *MMDVMClient) sendRPTCL() {
 		data = make([]byte, len("RPTCL")+4)
 		n    = copy(data, "RPTCL")
 	)
-	binary.BigEndian.PutUint32(data[n:], h.cfg.ID)
-	h.connTX <- data
+	binary.BigEndian.PutUint32(data[n:], h.radioID())
+	h.queue(data)
 }
 
 func (h *MMDVMClient) sendRPTC() {
 	str := []byte("RPTC") // 0:4
 	str = append(str, make([]byte, 4)...)
-	binary.BigEndian.PutUint32(str[4:], h.cfg.ID) // 4:8
+	binary.BigEndian.PutUint32(str[4:], h.radioID()) // 4:8
 
 	// Apply defaults for fields the config library may not handle.
 	slots := h.cfg.Slots
@@ -54,21 +70,34 @@ func (h *MMDVMClient) sendRPTC() {
 	str = append(str, []byte(fmt.Sprintf("%-40s", "20210921"))...)                // 222:262
 	str = append(str, []byte(fmt.Sprintf("%-40s", "MMDVM_MMDVM_HS_Dual_Hat"))...) // 262:302
 
-	h.connTX <- str
+	h.queue(str)
+}
+
+// sendRPTO sends the configured options string, if there is one.
+func (h *MMDVMClient) sendRPTO() {
+	if h.cfg.Options == "" {
+		return
+	}
+	str := []byte("RPTO") // 0:4
+	str = append(str, make([]byte, 4)...)
+	binary.BigEndian.PutUint32(str[4:], h.radioID()) // 4:8
+	str = append(str, h.cfg.Options...)              // 8:
+
+	h.queue(str)
 }
 
 func (h *MMDVMClient) sendRPTK(random []byte) {
 	// Generate a sha256 hash of the random data and the password
 	s256 := sha256.New()
 	s256.Write(random)
-	s256.Write([]byte(h.cfg.Password))
+	s256.Write([]byte(h.credentials().password))
 	token := s256.Sum(nil)
 
 	buf := make([]byte, 40)
 	copy(buf[0:4], "RPTK")
-	binary.BigEndian.PutUint32(buf[4:8], h.cfg.ID)
+	binary.BigEndian.PutUint32(buf[4:8], h.radioID())
 	copy(buf[8:], token)
-	h.connTX <- buf
+	h.queue(buf)
 }
 
 func (h *MMDVMClient) sendPing() {
@@ -76,16 +105,18 @@ func (h *MMDVMClient) sendPing() {
 		data = make([]byte, len("RPTPING")+4)
 		n    = copy(data, "RPTPING")
 	)
-	binary.BigEndian.PutUint32(data[n:], h.cfg.ID)
-	h.lastPingSent.Store(time.Now().UnixNano())
-	h.connTX <- data
+	binary.BigEndian.PutUint32(data[n:], h.radioID())
+	h.lastPingSent.Store(h.clock().UnixNano())
+	h.pongPending.Store(true)
+	h.queue(data)
 }
 
 func (h *MMDVMClient) sendPacket(packet proto.Packet) {
 	data := make([]byte, 53)
 	copy(data, packet.Encode())
+	h.packetsSent.Add(1)
 	if h.metrics != nil {
 		h.metrics.MMDVMPacketsSent.WithLabelValues(h.cfg.Name).Inc()
 	}
-	h.connTX <- data
+	h.queue(data)
 }
@@ -0,0 +1,38 @@
+package mmdvm
+
+import (
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/ipsc"
+)
+
+// radioCheckTimeout is how long a client waits for the answer to a radio
+// check it delivered toward IPSC.
+const radioCheckTimeout = 10 * time.Second
+
+// radioCheckKey identifies a radio check by its IPSC-side IDs.
+type radioCheckKey struct {
+	target, requester uint32
+}
+
+// notePendingRadioCheck remembers a radio check this client delivered
+// toward IPSC, so the target's answer is routed back to this master
+// rather than to whichever network the rewrite rules pick.
+func (h *MMDVMClient) notePendingRadioCheck(ipscData []byte) {
+	check, ok := ipsc.RadioCheck(ipscData[0], ipscData)
+	if !ok {
+		return
+	}
+	h.radioChecks.Set(radioCheckKey{target: check.Dst, requester: check.Src}, struct{}{}, radioCheckTimeout)
+}
+
+// ClaimRadioCheckAck reports whether an IPSC burst answers a radio check
+// this client delivered, and forgets the check if so.
+func (h *MMDVMClient) ClaimRadioCheckAck(packetType byte, data []byte) bool {
+	ack, ok := ipsc.RadioCheckAck(packetType, data)
+	if !ok {
+		return false
+	}
+	_, pending := h.radioChecks.Take(radioCheckKey{target: ack.Src, requester: ack.Dst})
+	return pending
+}
@@ -0,0 +1,101 @@
+package mmdvm
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Why the master last turned the client away, as returned by LastFailure.
+var (
+	ErrLoginRejected    = errors.New("login rejected, check the radio ID")
+	ErrPasswordRejected = errors.New("password rejected")
+	ErrConfigRejected   = errors.New("configuration rejected")
+	ErrSessionRejected  = errors.New("session ended by the master, the radio ID may be banned")
+)
+
+// Handshake rejection policy defaults, used when a client leaves a value
+// unset.
+const (
+	defaultMaxRejections = 5
+	defaultRejectBackoff = time.Second
+	maxRejectBackoff     = 30 * time.Second
+)
+
+// isNAK reports whether data is a master's refusal.
+func isNAK(data []byte) bool {
+	return len(data) >= 6 && (string(data[:6]) == "MSTNAK" || string(data[:6]) == "RPTNAK")
+}
+
+// LastFailure returns why the master last rejected the client, naming the
+// master, or nil if it never has.
+func (h *MMDVMClient) LastFailure() error {
+	h.failureMu.Lock()
+	defer h.failureMu.Unlock()
+	return h.lastFailure
+}
+
+func (h *MMDVMClient) setLastFailure(reason error) {
+	h.failureMu.Lock()
+	defer h.failureMu.Unlock()
+	h.lastFailure = fmt.Errorf("%s: %w", h.ActiveMaster(), reason)
+}
+
+// rejectionLimit returns how many handshake rejections in a row the client
+// takes before giving up: maxRejections from each master.
+func (h *MMDVMClient) rejectionLimit() int {
+	limit := h.maxRejections
+	if limit <= 0 {
+		limit = defaultMaxRejections
+	}
+	if h.masters != nil && h.masters.len() > 1 {
+		limit *= h.masters.len()
+	}
+	return limit
+}
+
+// handshakeRejected records that the master refused the handshake for
+// reason. It returns true if the caller must not retry: the client gave
+// up, failed over to another master, or was stopped while backing off.
+// Otherwise it has waited out a backoff that doubles with each rejection
+// in a row.
+func (h *MMDVMClient) handshakeRejected(reason error) bool {
+	h.setLastFailure(reason)
+	h.rejections++
+	if h.rejections >= h.rejectionLimit() {
+		h.giveUp()
+		return true
+	}
+	if h.handleNAK() {
+		return true
+	}
+	backoff := h.rejectBackoff
+	if backoff <= 0 {
+		backoff = defaultRejectBackoff
+	}
+	backoff <<= h.rejections - 1
+	if backoff > maxRejectBackoff || backoff <= 0 {
+		backoff = maxRejectBackoff
+	}
+	slog.Info("Retrying MMDVM login", "network", h.cfg.Name, "backoff", backoff, "attempt", h.rejections)
+	select {
+	case <-time.After(backoff):
+	case <-h.done:
+		return true
+	}
+	// The retry is a fresh handshake for the watchdog to time.
+	h.loginSent.Store(time.Now().UnixNano())
+	return false
+}
+
+// giveUp stops the handshake for good after too many rejections. The
+// client stays in STATE_AUTH_FAILED until it is restarted.
+func (h *MMDVMClient) giveUp() {
+	slog.Error("MMDVM master keeps rejecting the login, giving up",
+		"network", h.cfg.Name, "rejections", h.rejections, "reason", h.LastFailure())
+	h.setState(STATE_AUTH_FAILED, "rejected")
+	if h.metrics != nil {
+		h.metrics.MMDVMConnectionState.WithLabelValues(h.cfg.Name).Set(0)
+	}
+}
@@ -0,0 +1,177 @@
+package mmdvm
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// expectSent waits for the client to send a packet starting with tag.
+func expectSent(t *testing.T, client *MMDVMClient, tag string) {
+	t.Helper()
+	select {
+	case data := <-client.connTX:
+		if len(data) < len(tag) || string(data[:len(tag)]) != tag {
+			t.Fatalf("expected %s, got %q", tag, data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for %s", tag)
+	}
+}
+
+func expectNothingSent(t *testing.T, client *MMDVMClient) {
+	t.Helper()
+	select {
+	case data := <-client.connTX:
+		t.Fatalf("expected nothing sent, got %q", data)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestMSTNAKInEachState(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		state     State
+		wantSent  string
+		wantState State
+		wantErr   error
+	}{
+		{"login", STATE_SENT_LOGIN, tagRPTL, STATE_SENT_LOGIN, ErrLoginRejected},
+		{"auth", STATE_SENT_AUTH, tagRPTL, STATE_SENT_LOGIN, ErrPasswordRejected},
+		{"config", STATE_SENT_RPTC, tagRPTC, STATE_SENT_RPTC, ErrConfigRejected},
+		{"ready", STATE_READY, tagRPTL, STATE_SENT_LOGIN, ErrSessionRejected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			client := newTestClient(t)
+			client.rejectBackoff = time.Millisecond
+			client.state.Store(uint32(tt.state))
+			client.wg.Add(1)
+			go client.handler()
+			defer func() {
+				close(client.done)
+				client.wg.Wait()
+			}()
+
+			client.connRX <- []byte("MSTNAK__________")
+			expectSent(t, client, tt.wantSent)
+			if got := client.State(); got != tt.wantState {
+				t.Fatalf("expected state %s, got %s", tt.wantState, got)
+			}
+			if err := client.LastFailure(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected LastFailure to be %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRepeatedRejectionsGiveUp(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.rejectBackoff = time.Millisecond
+	client.maxRejections = 3
+	client.state.Store(uint32(STATE_SENT_LOGIN))
+	client.wg.Add(1)
+	go client.handler()
+	defer func() {
+		close(client.done)
+		client.wg.Wait()
+	}()
+
+	for range client.maxRejections - 1 {
+		client.connRX <- []byte("MSTNAK__________")
+		expectSent(t, client, tagRPTL)
+	}
+	client.connRX <- []byte("MSTNAK__________")
+	deadline := time.Now().Add(2 * time.Second)
+	for client.State() != STATE_AUTH_FAILED {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the client to give up, state %s", client.State())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	expectNothingSent(t, client)
+	if err := client.LastFailure(); !errors.Is(err, ErrLoginRejected) {
+		t.Fatalf("expected LastFailure to be %v, got %v", ErrLoginRejected, err)
+	}
+
+	// Having given up, the client ignores the master.
+	client.connRX <- []byte("RPTACK12345678")
+	expectNothingSent(t, client)
+	if got := client.State(); got != STATE_AUTH_FAILED {
+		t.Fatalf("expected state to remain auth-failed, got %s", got)
+	}
+}
+
+func TestRejectionCountResetsWhenReady(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.rejectBackoff = time.Millisecond
+	client.maxRejections = 2
+	client.keepAlive = time.Hour
+	client.timeout = time.Hour
+	client.state.Store(uint32(STATE_SENT_RPTC))
+	client.wg.Add(1)
+	go client.handler()
+	defer func() {
+		close(client.done)
+		client.wg.Wait()
+	}()
+
+	client.connRX <- []byte("MSTNAK__________")
+	expectSent(t, client, tagRPTC)
+	client.connRX <- []byte("RPTACK__________")
+	expectSent(t, client, tagRPTPING)
+
+	// Ready again, so one more rejection is not the last straw.
+	client.setState(STATE_SENT_LOGIN, "test")
+	client.connRX <- []byte("MSTNAK__________")
+	expectSent(t, client, tagRPTL)
+	if got := client.State(); got != STATE_SENT_LOGIN {
+		t.Fatalf("expected state sent-login, got %s", got)
+	}
+}
+
+func TestLoginIgnoresUnrelatedPackets(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.state.Store(uint32(STATE_SENT_LOGIN))
+	client.wg.Add(1)
+	go client.handler()
+	defer func() {
+		close(client.done)
+		client.wg.Wait()
+	}()
+
+	client.connRX <- []byte("MSTPONG_________")
+	expectNothingSent(t, client)
+	if err := client.LastFailure(); err != nil {
+		t.Fatalf("expected no failure, got %v", err)
+	}
+}
+
+func TestStopDuringRejectionBackoff(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.rejectBackoff = time.Hour
+	client.state.Store(uint32(STATE_SENT_LOGIN))
+	client.wg.Add(1)
+	go client.handler()
+
+	client.connRX <- []byte("MSTNAK__________")
+	time.Sleep(50 * time.Millisecond)
+	close(client.done)
+	waited := make(chan struct{})
+	go func() {
+		client.wg.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler did not stop during the backoff")
+	}
+	expectNothingSent(t, client)
+}
@@ -0,0 +1,107 @@
+package rewrite
+
+import (
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+)
+
+// Config is the rewrite rule configuration of one network or bridge.
+type Config struct {
+	TGRewrites   []config.TGRewriteConfig
+	PCRewrites   []config.PCRewriteConfig
+	TypeRewrites []config.TypeRewriteConfig
+	SrcRewrites  []config.SrcRewriteConfig
+	PassAllTG    []int
+	PassAllPC    []int
+}
+
+// Set holds the rule chains built from a Config.
+type Set struct {
+	RF      []Rule // RF→Net
+	Net     []Rule // Net→RF
+	PassAll []Rule // PassAll fallback for RF→Net
+}
+
+// Empty reports whether no rules were configured.
+func (s Set) Empty() bool {
+	return len(s.RF) == 0 && len(s.Net) == 0 && len(s.PassAll) == 0
+}
+
+// NewSet constructs the rewrite rule chains from config.
+// For each TGRewrite config entry, two rules are created:
+//   - RF: fromSlot/fromTG → toSlot/toTG (for RF→Net direction)
+//   - Net: toSlot/toTG → fromSlot/fromTG (reverse, for Net→RF direction)
+//
+// PCRewrite only creates an RF rewrite (outbound).
+// TypeRewrite only creates an RF rewrite (outbound).
+// SrcRewrite only creates a Net rewrite (inbound).
+func NewSet(name string, cfg Config) Set {
+	var s Set
+
+	for _, c := range cfg.TGRewrites {
+		rng := c.Range
+		if rng == 0 {
+			rng = 1
+		}
+		s.RF = append(s.RF, &TGRewrite{
+			Name: name, FromSlot: c.FromSlot, FromTG: c.FromTG,
+			ToSlot: c.ToSlot, ToTG: c.ToTG, Range: rng,
+		})
+		// Reverse direction
+		s.Net = append(s.Net, &TGRewrite{
+			Name: name, FromSlot: c.ToSlot, FromTG: c.ToTG,
+			ToSlot: c.FromSlot, ToTG: c.FromTG, Range: rng,
+		})
+	}
+
+	for _, c := range cfg.PCRewrites {
+		rng := c.Range
+		if rng == 0 {
+			rng = 1
+		}
+		s.RF = append(s.RF, &PCRewrite{
+			Name: name, FromSlot: c.FromSlot, FromID: c.FromID,
+			ToSlot: c.ToSlot, ToID: c.ToID, Range: rng,
+		})
+	}
+
+	for _, c := range cfg.TypeRewrites {
+		rng := c.Range
+		if rng == 0 {
+			rng = 1
+		}
+		s.RF = append(s.RF, &TypeRewrite{
+			Name: name, FromSlot: c.FromSlot, FromTG: c.FromTG,
+			ToSlot: c.ToSlot, ToID: c.ToID, Range: rng,
+		})
+	}
+
+	for _, c := range cfg.SrcRewrites {
+		rng := c.Range
+		if rng == 0 {
+			rng = 1
+		}
+		s.Net = append(s.Net, &SrcRewrite{
+			Name: name, FromSlot: c.FromSlot, FromID: c.FromID,
+			ToSlot: c.ToSlot, ToID: c.ToID, Range: rng,
+		})
+	}
+
+	for _, slot := range cfg.PassAllTG {
+		if slot < 0 {
+			continue
+		}
+		sl := uint(slot) //nolint:gosec
+		s.PassAll = append(s.PassAll, &PassAllTG{Name: name, Slot: sl})
+		s.Net = append(s.Net, &PassAllTG{Name: name, Slot: sl})
+	}
+	for _, slot := range cfg.PassAllPC {
+		if slot < 0 {
+			continue
+		}
+		sl := uint(slot) //nolint:gosec
+		s.PassAll = append(s.PassAll, &PassAllPC{Name: name, Slot: sl})
+		s.Net = append(s.Net, &PassAllPC{Name: name, Slot: sl})
+	}
+
+	return s
+}
@@ -0,0 +1,46 @@
+package rewrite
+
+import (
+	"testing"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+)
+
+func TestNewSet(t *testing.T) {
+	t.Parallel()
+	s := NewSet("test", Config{
+		TGRewrites:  []config.TGRewriteConfig{{FromSlot: 1, FromTG: 9, ToSlot: 2, ToTG: 90}},
+		PCRewrites:  []config.PCRewriteConfig{{FromSlot: 1, FromID: 100, ToSlot: 1, ToID: 200, Range: 1}},
+		SrcRewrites: []config.SrcRewriteConfig{{FromSlot: 1, FromID: 1234, ToSlot: 1, ToID: 9, Range: 1}},
+		PassAllTG:   []int{2, -1},
+	})
+	if len(s.RF) != 2 {
+		t.Fatalf("expected 2 RF rules, got %d", len(s.RF))
+	}
+	// TG reverse + src + passall TG
+	if len(s.Net) != 3 {
+		t.Fatalf("expected 3 Net rules, got %d", len(s.Net))
+	}
+	if len(s.PassAll) != 1 {
+		t.Fatalf("expected 1 PassAll rule, got %d", len(s.PassAll))
+	}
+
+	// A zero range is treated as a single TG.
+	pkt := groupPkt(1, 9)
+	if !Apply(s.RF, pkt) {
+		t.Fatal("expected TG 9 to match")
+	}
+	if pkt.Dst != 90 || !pkt.Slot {
+		t.Fatalf("expected TS2/TG90, got slot=%v dst=%d", pkt.Slot, pkt.Dst)
+	}
+	if Apply(s.RF, groupPkt(1, 10)) {
+		t.Fatal("expected TG 10 not to match")
+	}
+}
+
+func TestNewSetEmpty(t *testing.T) {
+	t.Parallel()
+	if !NewSet("test", Config{}).Empty() {
+		t.Fatal("expected an empty set with no rules configured")
+	}
+}
@@ -0,0 +1,62 @@
+package mmdvm
+
+import (
+	"net"
+)
+
+// NewBurstRouter returns an IPSC burst handler that routes each burst to
+// the first client whose rewrite rules match it (DMRGateway semantics).
+// If no specific rule matches, the first client with a matching passall
+// rule wins. Bursts matching no client are dropped. The answer to a radio
+// check goes back to the client that delivered the check.
+func NewBurstRouter(clients []*MMDVMClient) func(packetType byte, data []byte, addr *net.UDPAddr) {
+	return NewDefaultBurstRouter(clients, nil)
+}
+
+// NewDefaultBurstRouter returns an IPSC burst handler that routes as
+// NewBurstRouter does, except that bursts matching no client go unchanged
+// to fallback. A nil fallback drops them.
+func NewDefaultBurstRouter(clients []*MMDVMClient, fallback *MMDVMClient) func(packetType byte, data []byte, addr *net.UDPAddr) {
+	return func(packetType byte, data []byte, addr *net.UDPAddr) {
+		for _, client := range clients {
+			if client.ClaimRadioCheckAck(packetType, data) {
+				dataCopy := make([]byte, len(data))
+				copy(dataCopy, data)
+				client.HandleIPSCBurst(packetType, dataCopy, addr)
+				return
+			}
+		}
+		for _, client := range clients {
+			if client.MatchesRules(packetType, data, false) {
+				dataCopy := make([]byte, len(data))
+				copy(dataCopy, data)
+				client.HandleIPSCBurst(packetType, dataCopy, addr)
+				return
+			}
+		}
+		for _, client := range clients {
+			if client.MatchesRules(packetType, data, true) {
+				dataCopy := make([]byte, len(data))
+				copy(dataCopy, data)
+				client.HandleIPSCBurst(packetType, dataCopy, addr)
+				return
+			}
+		}
+		if fallback != nil {
+			dataCopy := make([]byte, len(data))
+			copy(dataCopy, data)
+			fallback.HandleUnroutedIPSCBurst(packetType, dataCopy, addr)
+		}
+	}
+}
+
+// NewPeerLostRouter returns an IPSC peer-lost handler that tells every
+// client to end the calls the peer had in progress. Only the client that
+// carried a call holds state for it, so the others do nothing.
+func NewPeerLostRouter(clients []*MMDVMClient) func(peerID uint32) {
+	return func(peerID uint32) {
+		for _, client := range clients {
+			client.HandlePeerLost(peerID)
+		}
+	}
+}
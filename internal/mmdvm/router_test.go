@@ -0,0 +1,108 @@
+package mmdvm
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/rewrite"
+)
+
+// groupVoiceHeader returns an IPSC group voice header from 100 to tg on
+// TS1, on a call told apart by callControl.
+func groupVoiceHeader(tg uint32, callControl byte) []byte {
+	data := make([]byte, 54)
+	data[0] = 0x80
+	data[4] = 0x01
+	data[8] = 0x64
+	data[9] = byte(tg >> 16)
+	data[10] = byte(tg >> 8)
+	data[11] = byte(tg)
+	data[12] = 0x02
+	data[16] = callControl
+	data[18] = 0x80
+	data[30] = 0x01
+	return data
+}
+
+// routingTestClients returns two networks, BM carrying TG 91 and TGIF
+// carrying TG 31665, each passed on as TG 9 on TS1, and a third, Default,
+// with no rules.
+func routingTestClients(t *testing.T) []*MMDVMClient {
+	t.Helper()
+	var clients []*MMDVMClient
+	for _, network := range []struct {
+		name string
+		tg   uint
+	}{{"BM", 91}, {"TGIF", 31665}, {"Default", 0}} {
+		client := newTestClient(t)
+		client.cfg.Name = network.name
+		client.started.Store(true)
+		if network.tg != 0 {
+			client.rfRewrites = []rewrite.Rule{
+				&rewrite.TGRewrite{Name: network.name, FromSlot: 1, FromTG: network.tg, ToSlot: 1, ToTG: 9, Range: 1},
+			}
+		}
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// routedTo returns the index of the client that got a packet, and the
+// packet.
+func routedTo(t *testing.T, clients []*MMDVMClient) (int, uint) {
+	t.Helper()
+	deadline := time.After(time.Second)
+	for {
+		for i, client := range clients {
+			select {
+			case pkt := <-client.tx_chan:
+				return i, pkt.Dst
+			default:
+			}
+		}
+		select {
+		case <-deadline:
+			return -1, 0
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func TestBurstRouterRoutesByTalkgroup(t *testing.T) {
+	t.Parallel()
+	clients := routingTestClients(t)
+	route := NewDefaultBurstRouter(clients[:2], clients[2])
+	addr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
+
+	tests := []struct {
+		tg      uint32
+		want    int
+		wantDst uint
+	}{
+		{91, 0, 9},
+		{31665, 1, 9},
+		// No network's rules match, so the call goes unchanged to the
+		// default network.
+		{3100, 2, 3100},
+	}
+	for i, tt := range tests {
+		route(0x80, groupVoiceHeader(tt.tg, byte(i+1)), addr)
+		got, dst := routedTo(t, clients)
+		if got != tt.want || dst != tt.wantDst {
+			t.Fatalf("TG %d: expected network %d as TG %d, got network %d as TG %d", tt.tg, tt.want, tt.wantDst, got, dst)
+		}
+	}
+}
+
+func TestBurstRouterDropsUnmatchedWithoutDefault(t *testing.T) {
+	t.Parallel()
+	clients := routingTestClients(t)
+	route := NewBurstRouter(clients)
+	addr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
+
+	route(0x80, groupVoiceHeader(3100, 1), addr)
+	if got, _ := routedTo(t, clients); got != -1 {
+		t.Fatalf("expected the call dropped, went to network %d", got)
+	}
+}
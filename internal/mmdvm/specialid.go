@@ -0,0 +1,94 @@
+package mmdvm
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/ipsc"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/specialid"
+)
+
+// SetSpecialIDs makes the client apply table to calls from its master,
+// as the master addresses them, before its rewrite rules. Calls taken by
+// a local handler are answered back to the master.
+func (h *MMDVMClient) SetSpecialIDs(table *specialid.Table) {
+	h.specialIDs = table
+}
+
+// handleSpecialID applies the special-ID table to a packet from the
+// master and reports whether it was blocked or taken by a local handler.
+func (h *MMDVMClient) handleSpecialID(packet proto.Packet) bool {
+	if h.specialIDs == nil {
+		return false
+	}
+	done := h.doneChan()
+	verdict := h.specialIDs.Evaluate(specialid.ToIPSC, "mmdvm/"+h.cfg.Name, packet, func(pkt proto.Packet) bool {
+		select {
+		case h.tx_chan <- pkt:
+			return true
+		case <-done:
+			return false
+		}
+	})
+	if verdict == specialid.Block {
+		h.packetsDropped.Add(1)
+		if h.metrics != nil {
+			h.metrics.MMDVMPacketsDropped.WithLabelValues(h.cfg.Name, "special_id").Inc()
+		}
+	}
+	return verdict != specialid.Pass
+}
+
+// NewSpecialIDBurstHandler returns an IPSC burst handler that applies
+// table to calls from the repeater, as the repeater addresses them, and
+// passes the bursts it neither blocks nor handles locally to next. Local
+// handlers answer the IPSC peers through server under localID, with the
+// given RTP settings.
+func NewSpecialIDBurstHandler(table *specialid.Table, server *ipsc.IPSCServer, localID uint32, rtp config.IPSCRTP, next func(packetType byte, data []byte, addr *net.UDPAddr)) (func(packetType byte, data []byte, addr *net.UDPAddr), error) {
+	// Local handlers get translators of their own so their streams
+	// never mix with those of the masters.
+	rx, err := ipsc.NewIPSCTranslator()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create IPSC translator for special IDs: %w", err)
+	}
+	tx, err := ipsc.NewIPSCTranslator()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create IPSC translator for special IDs: %w", err)
+	}
+	tx.SetPeerID(localID)
+	tx.SetRTP(rtp)
+	send := func(pkt proto.Packet) bool {
+		for _, data := range tx.TranslateToIPSC(pkt) {
+			server.SendUserPacket(data)
+		}
+		return true
+	}
+
+	return func(packetType byte, data []byte, addr *net.UDPAddr) {
+		if len(data) < 18 {
+			next(packetType, data, addr)
+			return
+		}
+		dst := uint(data[9])<<16 | uint(data[10])<<8 | uint(data[11])
+		action, handler := table.Lookup(specialid.ToMaster, dst)
+		switch action {
+		case config.SpecialIDActionBlock:
+			slog.Debug("Blocked call to special ID", "side", "ipsc", "direction", specialid.ToMaster, "peer", addr, "dst", dst)
+			return
+		case config.SpecialIDActionLocal:
+			taken := false
+			for _, pkt := range rx.TranslateToMMDVM(packetType, data) {
+				if handler("ipsc", pkt, send) {
+					taken = true
+				}
+			}
+			if taken {
+				return
+			}
+		}
+		next(packetType, data, addr)
+	}, nil
+}
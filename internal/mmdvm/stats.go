@@ -0,0 +1,95 @@
+package mmdvm
+
+import "time"
+
+func (s State) String() string {
+	switch s {
+	case STATE_IDLE:
+		return "idle"
+	case STATE_SENT_LOGIN:
+		return "sent-login"
+	case STATE_SENT_AUTH:
+		return "sent-auth"
+	case STATE_SENT_RPTC:
+		return "sent-config"
+	case STATE_READY:
+		return "ready"
+	case STATE_TIMEOUT:
+		return "timeout"
+	case STATE_AUTH_FAILED:
+		return "auth-failed"
+	case STATE_CLOSED:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// Stats is a point-in-time summary of a client's connection and traffic.
+type Stats struct {
+	State          State
+	ActiveMaster   string
+	MasterSwitches uint64
+	// PacketsSent counts DMRD packets sent to the master.
+	PacketsSent uint64
+	// PacketsReceived counts DMRD packets received from the master.
+	PacketsReceived uint64
+	// PacketsDropped counts DMRD packets in either direction that matched
+	// no rewrite rule or were held back by timeslot arbitration.
+	PacketsDropped uint64
+	// LastPong is when the master last answered a ping.
+	LastPong time.Time
+	// RTT is the smoothed round trip time of pings, zero until the
+	// master answers one.
+	RTT time.Duration
+	// MissedPongs counts the pings in a row the master left unanswered.
+	MissedPongs uint32
+}
+
+// Stats returns the client's current connection state and counters.
+func (h *MMDVMClient) Stats() Stats {
+	var lastPong time.Time
+	if ns := h.lastPing.Load(); ns != 0 {
+		lastPong = time.Unix(0, ns)
+	}
+	return Stats{
+		State:           h.State(),
+		ActiveMaster:    h.ActiveMaster(),
+		MasterSwitches:  h.MasterSwitches(),
+		PacketsSent:     h.packetsSent.Load(),
+		PacketsReceived: h.packetsReceived.Load(),
+		PacketsDropped:  h.packetsDropped.Load(),
+		LastPong:        lastPong,
+		RTT:             time.Duration(h.smoothedRTT.Load()),
+		MissedPongs:     h.MissedPongs(),
+	}
+}
+
+// State returns the client's current connection state.
+func (h *MMDVMClient) State() State {
+	return State(h.state.Load() & 0xFF) //nolint:gosec // state values fit in uint8
+}
+
+// SetStateHandler registers a function called on every connection state
+// change. The client carries calls only in STATE_READY, so a change from
+// it tells that calls cannot be forwarded for now. It runs on the
+// client's goroutines and must not block. It must be set before Start.
+func (h *MMDVMClient) SetStateHandler(handler func(from, to State)) {
+	h.stateHandler = handler
+}
+
+// setState moves the client to state s, telling the state handler and
+// subscribers why.
+func (h *MMDVMClient) setState(s State, reason string) {
+	// Holding subsMu across the swap publishes changes in the order they
+	// were made.
+	h.subsMu.Lock()
+	prev := State(h.state.Swap(uint32(s)) & 0xFF) //nolint:gosec // state values fit in uint8
+	if prev != s {
+		h.publish(StateChange{Old: prev, New: s, Time: time.Now(), Reason: reason})
+	}
+	h.subsMu.Unlock()
+	if h.stateHandler != nil && prev != s {
+		h.stateHandler(prev, s)
+	}
+}
@@ -0,0 +1,95 @@
+package mmdvm
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+)
+
+func TestStateString(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		state State
+		want  string
+	}{
+		{STATE_IDLE, "idle"},
+		{STATE_SENT_LOGIN, "sent-login"},
+		{STATE_SENT_AUTH, "sent-auth"},
+		{STATE_SENT_RPTC, "sent-config"},
+		{STATE_READY, "ready"},
+		{STATE_TIMEOUT, "timeout"},
+		{State(42), "unknown"},
+	}
+	for _, tt := range tests {
+		if got := tt.state.String(); got != tt.want {
+			t.Errorf("State(%d).String() = %q, want %q", tt.state, got, tt.want)
+		}
+	}
+}
+
+func TestStateHandlerReportsTransitions(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+
+	var mu sync.Mutex
+	var transitions [][2]State
+	client.SetStateHandler(func(from, to State) {
+		mu.Lock()
+		defer mu.Unlock()
+		transitions = append(transitions, [2]State{from, to})
+	})
+
+	client.setState(STATE_SENT_LOGIN, "test")
+	client.setState(STATE_SENT_LOGIN, "test") // no change, not reported
+	client.setState(STATE_READY, "test")
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := [][2]State{{STATE_IDLE, STATE_SENT_LOGIN}, {STATE_SENT_LOGIN, STATE_READY}}
+	if len(transitions) != len(want) {
+		t.Fatalf("expected %d transitions, got %v", len(want), transitions)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Fatalf("transition %d: expected %v, got %v", i, want[i], transitions[i])
+		}
+	}
+	if client.State() != STATE_READY {
+		t.Fatalf("expected ready, got %s", client.State())
+	}
+}
+
+func TestStatsCountsPackets(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.state.Store(uint32(STATE_READY))
+	client.netRewrites = nil // drop everything from the master
+
+	client.wg.Add(1)
+	go client.handler()
+
+	pkt := proto.Packet{Signature: "DMRD", Src: 1, Dst: 2, GroupCall: true}
+	client.connRX <- pkt.Encode()
+	client.sendPacket(pkt)
+	<-client.connTX
+
+	deadline := time.Now().Add(2 * time.Second)
+	for client.Stats().PacketsDropped == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(client.done)
+	client.wg.Wait()
+
+	stats := client.Stats()
+	if stats.PacketsSent != 1 || stats.PacketsReceived != 1 || stats.PacketsDropped != 1 {
+		t.Fatalf("unexpected counters: %+v", stats)
+	}
+	if stats.State != STATE_READY {
+		t.Fatalf("expected ready, got %s", stats.State)
+	}
+	if stats.ActiveMaster != client.cfg.MasterServer {
+		t.Fatalf("expected active master %q, got %q", client.cfg.MasterServer, stats.ActiveMaster)
+	}
+}
@@ -0,0 +1,47 @@
+package mmdvm
+
+import "time"
+
+// stateChangeBuffer is how many state changes a subscriber may fall
+// behind by before the oldest are dropped.
+const stateChangeBuffer = 16
+
+// StateChange is a connection state change reported to subscribers.
+type StateChange struct {
+	Old, New State
+	Time     time.Time
+	// Reason says what caused the change, such as "config-accepted",
+	// "timeout", "nak", or "closed".
+	Reason string
+}
+
+// Subscribe returns a channel on which every later connection state change
+// is sent. A subscriber that falls behind loses the oldest changes rather
+// than holding up the client. The channel is never closed. It is safe to
+// call at any time.
+func (h *MMDVMClient) Subscribe() <-chan StateChange {
+	ch := make(chan StateChange, stateChangeBuffer)
+	h.subsMu.Lock()
+	defer h.subsMu.Unlock()
+	h.subscribers = append(h.subscribers, ch)
+	return ch
+}
+
+// publish sends change to every subscriber, dropping a subscriber's
+// oldest change if its channel is full. Must be called with h.subsMu
+// held, which makes it the only sender.
+func (h *MMDVMClient) publish(change StateChange) {
+	for _, ch := range h.subscribers {
+		select {
+		case ch <- change:
+			continue
+		default:
+		}
+		select {
+		case <-ch:
+		default:
+		}
+		// Nothing else sends, so there is room now.
+		ch <- change
+	}
+}
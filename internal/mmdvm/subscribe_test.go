@@ -0,0 +1,90 @@
+package mmdvm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscribeReportsHandshake(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	client.keepAlive = time.Hour
+	client.timeout = time.Hour
+	changes := client.Subscribe()
+	client.setState(STATE_SENT_LOGIN, "start")
+	client.wg.Add(1)
+	go client.handler()
+	defer func() {
+		close(client.done)
+		client.wg.Wait()
+	}()
+
+	client.connRX <- []byte("RPTACK12345678")
+	client.connRX <- []byte("RPTACK__________")
+	client.connRX <- []byte("RPTACK__________")
+	// The master forgets the session, so the client logs in again.
+	client.connRX <- []byte("MSTNAK__________")
+
+	want := []StateChange{
+		{Old: STATE_IDLE, New: STATE_SENT_LOGIN, Reason: "start"},
+		{Old: STATE_SENT_LOGIN, New: STATE_SENT_AUTH, Reason: "login-accepted"},
+		{Old: STATE_SENT_AUTH, New: STATE_SENT_RPTC, Reason: "authenticated"},
+		{Old: STATE_SENT_RPTC, New: STATE_READY, Reason: "config-accepted"},
+		{Old: STATE_READY, New: STATE_TIMEOUT, Reason: "nak"},
+		{Old: STATE_TIMEOUT, New: STATE_SENT_LOGIN, Reason: "nak"},
+	}
+	var last time.Time
+	for i, w := range want {
+		select {
+		case got := <-changes:
+			if got.Old != w.Old || got.New != w.New || got.Reason != w.Reason {
+				t.Fatalf("change %d: expected %s to %s (%s), got %s to %s (%s)", i, w.Old, w.New, w.Reason, got.Old, got.New, got.Reason)
+			}
+			if got.Time.Before(last) {
+				t.Fatalf("change %d: expected times in order, got %v after %v", i, got.Time, last)
+			}
+			last = got.Time
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for change %d", i)
+		}
+	}
+}
+
+func TestSubscribeDropsOldestForSlowSubscriber(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	slow := client.Subscribe()
+	fast := client.Subscribe()
+
+	// Alternate states so each call is a change.
+	const changes = stateChangeBuffer + 4
+	for i := range changes {
+		if i%2 == 0 {
+			client.setState(STATE_SENT_LOGIN, "test")
+		} else {
+			client.setState(STATE_IDLE, "test")
+		}
+		<-fast
+	}
+
+	if got := len(slow); got != stateChangeBuffer {
+		t.Fatalf("expected %d changes buffered, got %d", stateChangeBuffer, got)
+	}
+	// The first four were dropped, so the oldest kept is the fifth, a
+	// change to sent-login.
+	if got := <-slow; got.New != STATE_SENT_LOGIN || got.Old != STATE_IDLE {
+		t.Fatalf("expected the oldest kept change idle to sent-login, got %s to %s", got.Old, got.New)
+	}
+}
+
+func TestSubscribeIgnoresUnchangedState(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	changes := client.Subscribe()
+	client.setState(STATE_IDLE, "test")
+	select {
+	case got := <-changes:
+		t.Fatalf("expected no change reported, got %s to %s", got.Old, got.New)
+	default:
+	}
+}
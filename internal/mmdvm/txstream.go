@@ -0,0 +1,32 @@
+package mmdvm
+
+import (
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+)
+
+// txStreamIdle is how long a stream sent to the master may go quiet
+// before its sequence state is forgotten, for calls whose terminator was
+// lost.
+const txStreamIdle = 3 * time.Second
+
+// numberTX stamps pkt with the next sequence number of its stream. Masters
+// detect loss from gaps in Seq within a StreamID, so a stream is numbered
+// from 0 at its first packet, normally the header, and counts up modulo
+// 256. A repeated header continues the count rather than restarting it.
+// The stream is forgotten once its terminator is sent, or after
+// txStreamIdle without traffic.
+func (h *MMDVMClient) numberTX(pkt proto.Packet) proto.Packet {
+	seq, ok := h.txStreams.Get(pkt.StreamID)
+	if ok {
+		seq++
+	}
+	pkt.Seq = uint(seq)
+	if pkt.FrameType == frameTypeDataSync && pkt.DTypeOrVSeq == dtypeTerminatorWithLC {
+		h.txStreams.Delete(pkt.StreamID)
+	} else {
+		h.txStreams.Set(pkt.StreamID, seq, txStreamIdle)
+	}
+	return pkt
+}
@@ -0,0 +1,87 @@
+package mmdvm
+
+import (
+	"testing"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+)
+
+func voiceHeader(streamID uint) proto.Packet {
+	return proto.Packet{Signature: tagDMRD, Seq: 7, FrameType: frameTypeDataSync, DTypeOrVSeq: dtypeVoiceLCHeader, StreamID: streamID}
+}
+
+func voiceTerminator(streamID uint) proto.Packet {
+	return proto.Packet{Signature: tagDMRD, FrameType: frameTypeDataSync, DTypeOrVSeq: dtypeTerminatorWithLC, StreamID: streamID}
+}
+
+// sentSeq numbers and sends pkt through client as forwardTX does, and
+// returns the Seq it went out with.
+func sentSeq(t *testing.T, client *MMDVMClient, pkt proto.Packet) uint {
+	t.Helper()
+	client.sendPacket(client.numberTX(pkt))
+	decoded, ok := proto.Decode(<-client.connTX)
+	if !ok {
+		t.Fatal("failed to decode sent packet")
+	}
+	return decoded.Seq
+}
+
+func TestNumberTXNumbersStream(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+
+	if got := sentSeq(t, client, voiceHeader(1)); got != 0 {
+		t.Fatalf("expected the header sent as Seq 0, got %d", got)
+	}
+	for i := range uint(10) {
+		// The translator's Seq is ignored.
+		pkt := proto.Packet{Signature: tagDMRD, Seq: 0, FrameType: frameTypeVoice, DTypeOrVSeq: i % 6, StreamID: 1}
+		if got := sentSeq(t, client, pkt); got != i+1 {
+			t.Fatalf("frame %d: expected Seq %d, got %d", i, i+1, got)
+		}
+	}
+	if got := sentSeq(t, client, voiceTerminator(1)); got != 11 {
+		t.Fatalf("expected the terminator sent as Seq 11, got %d", got)
+	}
+	if n := client.txStreams.Len(); n != 0 {
+		t.Fatalf("expected the stream forgotten after its terminator, %d left", n)
+	}
+
+	// A new call starts over.
+	if got := sentSeq(t, client, voiceHeader(2)); got != 0 {
+		t.Fatalf("expected the next call's header sent as Seq 0, got %d", got)
+	}
+}
+
+func TestNumberTXStreamsNumberedApart(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	sentSeq(t, client, voiceHeader(1))
+	sentSeq(t, client, voiceHeader(1))
+	if got := sentSeq(t, client, voiceHeader(2)); got != 0 {
+		t.Fatalf("expected the second stream to start at Seq 0, got %d", got)
+	}
+	// A repeated header continues its stream's count.
+	if got := sentSeq(t, client, voiceTerminator(1)); got != 2 {
+		t.Fatalf("expected the first stream's terminator sent as Seq 2, got %d", got)
+	}
+	if n := client.txStreams.Len(); n != 1 {
+		t.Fatalf("expected only the second stream tracked, got %d", n)
+	}
+}
+
+func TestNumberTXSeqWraps(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(t)
+	sentSeq(t, client, voiceHeader(1))
+	for range 254 {
+		sentSeq(t, client, proto.Packet{Signature: tagDMRD, FrameType: frameTypeVoice, StreamID: 1})
+	}
+	frame := proto.Packet{Signature: tagDMRD, FrameType: frameTypeVoice, StreamID: 1}
+	if got := sentSeq(t, client, frame); got != 255 {
+		t.Fatalf("expected Seq 255, got %d", got)
+	}
+	if got := sentSeq(t, client, frame); got != 0 {
+		t.Fatalf("expected Seq to wrap to 0, got %d", got)
+	}
+}
@@ -0,0 +1,51 @@
+package netsetup
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// capNetAdmin is the bit index of CAP_NET_ADMIN in a Linux capability set.
+const capNetAdmin = 12
+
+var ErrInsufficientPrivileges = errors.New("insufficient privileges to configure network interface")
+
+var errNoCapEff = errors.New("CapEff not found")
+
+// parseCapEff extracts the effective capability mask from the contents
+// of /proc/<pid>/status.
+func parseCapEff(r io.Reader) (uint64, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || key != "CapEff" {
+			continue
+		}
+		caps, err := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid CapEff value %q: %w", strings.TrimSpace(value), err)
+		}
+		return caps, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("error reading process status: %w", err)
+	}
+	return 0, errNoCapEff
+}
+
+func hasCapability(caps uint64, capability uint) bool {
+	return caps&(1<<capability) != 0
+}
+
+func insufficientPrivilegesError(name string) error {
+	return fmt.Errorf("%w: configuring interface %s requires CAP_NET_ADMIN. "+
+		"Run ipsc2mmdvm as root, grant the capability with "+
+		"`sudo setcap cap_net_admin+ep $(which ipsc2mmdvm)` or "+
+		"`AmbientCapabilities=CAP_NET_ADMIN` in the systemd unit, "+
+		"or assign the address to the interface yourself and set ipsc.bind-only",
+		ErrInsufficientPrivileges, name)
+}
@@ -0,0 +1,84 @@
+package netsetup
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseCapEff(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		status  string
+		want    uint64
+		wantErr bool
+	}{
+		{
+			name:   "root",
+			status: "Name:\tipsc2mmdvm\nCapInh:\t0000000000000000\nCapPrm:\t000001ffffffffff\nCapEff:\t000001ffffffffff\n",
+			want:   0x000001ffffffffff,
+		},
+		{
+			name:   "unprivileged",
+			status: "Name:\tipsc2mmdvm\nCapEff:\t0000000000000000\nCapBnd:\t000001ffffffffff\n",
+			want:   0,
+		},
+		{
+			name:   "net admin only",
+			status: "CapEff:\t0000000000001000\n",
+			want:   1 << capNetAdmin,
+		},
+		{
+			name:    "missing",
+			status:  "Name:\tipsc2mmdvm\nCapPrm:\t0000000000000000\n",
+			wantErr: true,
+		},
+		{
+			name:    "malformed",
+			status:  "CapEff:\tzzzz\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := parseCapEff(strings.NewReader(tt.status))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got caps 0x%x", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("expected 0x%x, got 0x%x", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHasCapability(t *testing.T) {
+	t.Parallel()
+	if !hasCapability(1<<capNetAdmin, capNetAdmin) {
+		t.Fatal("expected CAP_NET_ADMIN to be set")
+	}
+	if hasCapability(1<<(capNetAdmin+1), capNetAdmin) {
+		t.Fatal("expected CAP_NET_ADMIN to be unset")
+	}
+}
+
+func TestInsufficientPrivilegesError(t *testing.T) {
+	t.Parallel()
+	err := insufficientPrivilegesError("eth0")
+	if !errors.Is(err, ErrInsufficientPrivileges) {
+		t.Fatalf("expected ErrInsufficientPrivileges, got %v", err)
+	}
+	for _, hint := range []string{"eth0", "CAP_NET_ADMIN", "setcap", "AmbientCapabilities", "ipsc.bind-only"} {
+		if !strings.Contains(err.Error(), hint) {
+			t.Fatalf("expected error to mention %q, got %q", hint, err.Error())
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// Package netsetup configures the host network interface the IPSC server
+// listens on. Interface management is only implemented on Linux; on other
+// platforms interfaces can be looked up but every change returns
+// ErrUnsupportedPlatform at runtime, and callers fall back to binding to an
+// address the operator has assigned themselves.
+package netsetup
+
+import (
+	"errors"
+	"net"
+)
+
+var ErrUnsupportedPlatform = errors.New("network interface configuration is unsupported on this platform")
+
+// ErrLinkExists is returned by CreateDummy when an interface with the name
+// already exists.
+var ErrLinkExists = errors.New("interface already exists")
+
+// NetworkSetup manages the network interface used for IPSC traffic.
+type NetworkSetup interface {
+	// LinkExists reports whether an interface with the given name exists.
+	LinkExists(name string) (bool, error)
+	// EnsureAddress replaces any addresses on the interface with ip/maskBits
+	// and brings the interface up.
+	EnsureAddress(name string, ip net.IP, maskBits int) error
+	// CreateDummy creates a dummy interface with the given name, failing
+	// with ErrLinkExists if there already is one.
+	CreateDummy(name string) error
+	// Delete removes the interface with the given name.
+	Delete(name string) error
+}
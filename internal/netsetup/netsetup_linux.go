@@ -0,0 +1,136 @@
+//go:build linux
+
+package netsetup
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/vishvananda/netlink"
+)
+
+type netlinkSetup struct{}
+
+// New returns the NetworkSetup for the current platform.
+func New() NetworkSetup {
+	return netlinkSetup{}
+}
+
+func (netlinkSetup) LinkExists(name string) (bool, error) {
+	_, err := netlink.LinkByName(name)
+	if err != nil {
+		var notFound netlink.LinkNotFoundError
+		if errors.As(err, &notFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("cannot look up interface %s: %w", name, err)
+	}
+	return true, nil
+}
+
+func (netlinkSetup) EnsureAddress(name string, ip net.IP, maskBits int) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return fmt.Errorf("cannot find interface %s: %w", name, err)
+	}
+
+	existingAddrs, err := netlink.AddrList(link, netlink.FAMILY_ALL)
+	if err != nil {
+		return fmt.Errorf("cannot list addresses on interface %s: %w", name, err)
+	}
+
+	// Nothing to do if the interface is already up with the wanted address,
+	// which lets unprivileged runs proceed against a pre-configured interface.
+	if addressConfigured(link.Attrs().Flags, existingAddrs, ip, maskBits) {
+		return nil
+	}
+
+	if err := checkNetAdmin(name); err != nil {
+		return err
+	}
+
+	// Remove any existing addresses from the interface
+	for i := range existingAddrs {
+		if err := netlink.AddrDel(link, &existingAddrs[i]); err != nil {
+			return fmt.Errorf("cannot remove address %s from interface %s: %w", existingAddrs[i].IPNet, name, err)
+		}
+	}
+
+	if err := netlink.AddrReplace(link, &netlink.Addr{IPNet: &net.IPNet{IP: ip, Mask: net.CIDRMask(maskBits, 32)}}); err != nil {
+		return fmt.Errorf("cannot add IP address to interface %s: %w", name, err)
+	}
+
+	if link.Attrs().Flags&net.FlagUp == 0 {
+		if err := netlink.LinkSetUp(link); err != nil {
+			return fmt.Errorf("cannot set interface up %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (netlinkSetup) CreateDummy(name string) error {
+	if err := checkNetAdmin(name); err != nil {
+		return err
+	}
+	if err := netlink.LinkAdd(&netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: name}}); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("cannot create dummy interface %s: %w", name, ErrLinkExists)
+		}
+		return fmt.Errorf("cannot create dummy interface %s: %w", name, err)
+	}
+	return nil
+}
+
+func (netlinkSetup) Delete(name string) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return fmt.Errorf("cannot find interface %s: %w", name, err)
+	}
+	if err := checkNetAdmin(name); err != nil {
+		return err
+	}
+	if err := netlink.LinkDel(link); err != nil {
+		return fmt.Errorf("cannot delete interface %s: %w", name, err)
+	}
+	return nil
+}
+
+// addressConfigured reports whether an interface with the given flags and
+// addresses is up and already carries ip/maskBits.
+func addressConfigured(flags net.Flags, addrs []netlink.Addr, ip net.IP, maskBits int) bool {
+	if flags&net.FlagUp == 0 {
+		return false
+	}
+	for _, addr := range addrs {
+		if addr.IPNet == nil || !addr.IP.Equal(ip) {
+			continue
+		}
+		if ones, bits := addr.Mask.Size(); ones == maskBits && bits == 32 {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNetAdmin fails with an actionable error when the process lacks
+// CAP_NET_ADMIN. If the capability set cannot be determined the check is
+// skipped and the netlink call is left to fail on its own.
+func checkNetAdmin(name string) error {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	caps, err := parseCapEff(f)
+	if err != nil {
+		return nil
+	}
+	if !hasCapability(caps, capNetAdmin) {
+		return insufficientPrivilegesError(name)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+//go:build linux
+
+package netsetup
+
+import (
+	"net"
+	"testing"
+
+	"github.com/vishvananda/netlink"
+)
+
+func TestLinkExistsLoopback(t *testing.T) {
+	t.Parallel()
+	exists, err := New().LinkExists("lo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !exists {
+		t.Fatal("expected loopback interface to exist")
+	}
+}
+
+func TestLinkExistsMissing(t *testing.T) {
+	t.Parallel()
+	exists, err := New().LinkExists("nonexistent_iface_xyz")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exists {
+		t.Fatal("expected nonexistent interface to be reported missing")
+	}
+}
+
+func TestAddressConfigured(t *testing.T) {
+	t.Parallel()
+	ip := net.ParseIP("10.10.250.1")
+	configured := []netlink.Addr{
+		{IPNet: &net.IPNet{IP: net.ParseIP("10.10.250.1").To4(), Mask: net.CIDRMask(24, 32)}},
+	}
+	tests := []struct {
+		name  string
+		flags net.Flags
+		addrs []netlink.Addr
+		mask  int
+		want  bool
+	}{
+		{"up with address", net.FlagUp, configured, 24, true},
+		{"down with address", 0, configured, 24, false},
+		{"wrong mask", net.FlagUp, configured, 16, false},
+		{"no addresses", net.FlagUp, nil, 24, false},
+		{"other address", net.FlagUp, []netlink.Addr{
+			{IPNet: &net.IPNet{IP: net.ParseIP("10.10.251.1").To4(), Mask: net.CIDRMask(24, 32)}},
+		}, 24, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := addressConfigured(tt.flags, tt.addrs, ip, tt.mask); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEnsureAddressSkipsConfiguredInterface(t *testing.T) {
+	t.Parallel()
+	// Loopback is always up with 127.0.0.1/8, so this must succeed without
+	// touching the interface, even when run unprivileged.
+	if err := New().EnsureAddress("lo", net.ParseIP("127.0.0.1"), 8); err != nil {
+		t.Fatalf("expected configured interface to be skipped, got %v", err)
+	}
+}
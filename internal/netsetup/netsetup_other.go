@@ -0,0 +1,8 @@
+//go:build !linux
+
+package netsetup
+
+// New returns the NetworkSetup for the current platform.
+func New() NetworkSetup {
+	return newUnsupported()
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package netsetup
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestNewIsUnsupported(t *testing.T) {
+	t.Parallel()
+	err := New().EnsureAddress("lo", net.ParseIP("10.10.250.1"), 24)
+	if !errors.Is(err, ErrUnsupportedPlatform) {
+		t.Fatalf("expected ErrUnsupportedPlatform, got %v", err)
+	}
+}
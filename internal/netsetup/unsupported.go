@@ -0,0 +1,48 @@
+package netsetup
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+)
+
+// unsupported is the NetworkSetup used on platforms without netlink.
+// It can look interfaces up through the standard library, which is
+// enough for bind-only mode, but cannot change them. It is compiled
+// everywhere so its behavior can be tested on any host.
+type unsupported struct {
+	goos string
+}
+
+func newUnsupported() NetworkSetup {
+	return unsupported{goos: runtime.GOOS}
+}
+
+func (u unsupported) err() error {
+	return fmt.Errorf("%w (%s)", ErrUnsupportedPlatform, u.goos)
+}
+
+func (u unsupported) LinkExists(name string) (bool, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return false, fmt.Errorf("failed to list interfaces: %w", err)
+	}
+	for _, iface := range ifaces {
+		if iface.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (u unsupported) EnsureAddress(string, net.IP, int) error {
+	return u.err()
+}
+
+func (u unsupported) CreateDummy(string) error {
+	return u.err()
+}
+
+func (u unsupported) Delete(string) error {
+	return u.err()
+}
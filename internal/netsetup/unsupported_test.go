@@ -0,0 +1,45 @@
+package netsetup
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestUnsupportedReturnsPlatformError(t *testing.T) {
+	t.Parallel()
+	n := unsupported{goos: "plan9"}
+
+	errs := []error{
+		n.EnsureAddress("eth0", net.ParseIP("10.10.250.1"), 24),
+		n.CreateDummy("ipsc0"),
+		n.Delete("ipsc0"),
+	}
+	for i, err := range errs {
+		if !errors.Is(err, ErrUnsupportedPlatform) {
+			t.Fatalf("call %d: expected ErrUnsupportedPlatform, got %v", i, err)
+		}
+		if !strings.Contains(err.Error(), "unsupported on this platform (plan9)") {
+			t.Fatalf("call %d: unexpected error message %q", i, err.Error())
+		}
+	}
+}
+
+func TestUnsupportedLinkExistsUsesStdlib(t *testing.T) {
+	t.Parallel()
+	ifaces, err := net.Interfaces()
+	if err != nil || len(ifaces) == 0 {
+		t.Skipf("no interfaces to look up: %v", err)
+	}
+	n := unsupported{goos: "windows"}
+
+	exists, err := n.LinkExists(ifaces[0].Name)
+	if err != nil || !exists {
+		t.Fatalf("expected %q to exist, got %t, %v", ifaces[0].Name, exists, err)
+	}
+	exists, err = n.LinkExists("ipsc-does-not-exist")
+	if err != nil || exists {
+		t.Fatalf("expected a missing interface to report false, got %t, %v", exists, err)
+	}
+}
@@ -0,0 +1,156 @@
+// Package parrot implements an echo service. A call addressed to the
+// parrot is recorded and, once it ends, played back to the caller so
+// they can hear how their audio reaches the network.
+package parrot
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+)
+
+const (
+	// FrameInterval is the spacing of bursts in a DMR call.
+	FrameInterval = 60 * time.Millisecond
+	// replayDelay is the pause between the end of a call and its
+	// playback, which gives the caller time to unkey.
+	replayDelay = time.Second
+	// idleTimeout is how long a recording without a terminator is kept.
+	idleTimeout = 3 * time.Second
+)
+
+// DMR frame type and data type values used to detect call boundaries and
+// encryption.
+const (
+	frameTypeDataSync     uint = 2
+	dtypePIHeader         uint = 0
+	dtypeTerminatorWithLC uint = 2
+)
+
+// Sender delivers one played-back packet toward the side the call came
+// from. It reports false if the side has gone away, which stops the
+// playback.
+type Sender func(proto.Packet) bool
+
+type recordingKey struct {
+	side     string
+	streamID uint
+}
+
+type recording struct {
+	packets   []proto.Packet
+	lastSeen  time.Time
+	encrypted bool
+	truncated bool
+}
+
+// Parrot records calls to its target and plays them back.
+type Parrot struct {
+	target    uint
+	group     bool
+	maxFrames int
+
+	mu         sync.Mutex
+	recordings map[recordingKey]*recording
+
+	// sleep paces playback. Tests replace it to observe the pacing.
+	sleep func(time.Duration)
+}
+
+// New returns a Parrot configured by cfg.
+func New(cfg config.Parrot) *Parrot {
+	return &Parrot{
+		target:     uint(cfg.ID),
+		group:      cfg.Group,
+		maxFrames:  int(time.Duration(cfg.MaxDuration) * time.Second / FrameInterval), //nolint:gosec // Bounded by config
+		recordings: map[recordingKey]*recording{},
+		sleep:      time.Sleep,
+	}
+}
+
+// Matches reports whether pkt is addressed to the parrot.
+func (p *Parrot) Matches(pkt proto.Packet) bool {
+	return pkt.Dst == p.target && pkt.GroupCall == p.group
+}
+
+// Handle records pkt if it is addressed to the parrot, and reports whether
+// it was. side names where the call came from. Once the call's terminator
+// arrives the recording is played back through send on a goroutine of its
+// own. Encrypted calls are taken but not recorded.
+func (p *Parrot) Handle(side string, pkt proto.Packet, send Sender) bool {
+	if !p.Matches(pkt) {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for key, rec := range p.recordings {
+		if now.Sub(rec.lastSeen) > idleTimeout {
+			slog.Debug("Parrot discarded a call that never ended", "side", key.side, "streamID", key.streamID)
+			delete(p.recordings, key)
+		}
+	}
+
+	key := recordingKey{side: side, streamID: pkt.StreamID}
+	rec, ok := p.recordings[key]
+	if !ok {
+		rec = &recording{}
+		p.recordings[key] = rec
+	}
+	rec.lastSeen = now
+
+	dataSync := pkt.FrameType == frameTypeDataSync
+	end := dataSync && pkt.DTypeOrVSeq == dtypeTerminatorWithLC
+	switch {
+	case dataSync && pkt.DTypeOrVSeq == dtypePIHeader:
+		if !rec.encrypted {
+			slog.Info("Parrot refused an encrypted call", "side", side, "src", pkt.Src)
+		}
+		rec.encrypted = true
+		rec.packets = nil
+	case rec.encrypted:
+	case end || len(rec.packets) < p.maxFrames:
+		rec.packets = append(rec.packets, pkt)
+	case !rec.truncated:
+		slog.Info("Parrot reached the maximum recording length", "side", side, "src", pkt.Src)
+		rec.truncated = true
+	}
+
+	if end {
+		delete(p.recordings, key)
+		if !rec.encrypted && len(rec.packets) > 0 {
+			go p.playback(side, rec.packets, send)
+		}
+	}
+	return true
+}
+
+// playback sends a recorded call back from the parrot to its caller,
+// paced like the original, under a new stream ID.
+func (p *Parrot) playback(side string, packets []proto.Packet, send Sender) {
+	caller := packets[0].Src
+	streamID := uint(rand.Uint32()) //nolint:gosec // Stream IDs need not be unpredictable
+	slog.Info("Parrot playing back call", "side", side, "caller", caller, "frames", len(packets))
+
+	p.sleep(replayDelay)
+	for i, pkt := range packets {
+		if i > 0 {
+			p.sleep(FrameInterval)
+		}
+		pkt.Seq = uint(i) & 0xFF
+		pkt.StreamID = streamID
+		pkt.Src = p.target
+		if !p.group {
+			pkt.Dst = caller
+		}
+		if !send(pkt) {
+			return
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package parrot
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+)
+
+const (
+	testCaller   = 3120101
+	testStreamID = 42
+)
+
+// pacing records the delays a parrot waits for instead of sleeping.
+type pacing struct {
+	mu     sync.Mutex
+	delays []time.Duration
+}
+
+func (p *pacing) sleep(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.delays = append(p.delays, d)
+}
+
+func newTestParrot(cfg config.Parrot) (*Parrot, *pacing) {
+	p := New(cfg)
+	pace := &pacing{}
+	p.sleep = pace.sleep
+	return p, pace
+}
+
+// call returns a voice call from testCaller to dst: a voice LC header,
+// voice bursts A–F, and a terminator.
+func call(dst uint, group bool, superframes int) []proto.Packet {
+	base := proto.Packet{Signature: "DMRD", Src: testCaller, Dst: dst, GroupCall: group, Slot: true, StreamID: testStreamID}
+	header := base
+	header.FrameType = frameTypeDataSync
+	header.DTypeOrVSeq = 1
+	packets := []proto.Packet{header}
+	for range superframes {
+		for burst := range uint(6) {
+			pkt := base
+			pkt.FrameType = 0
+			if burst == 0 {
+				pkt.FrameType = 1
+			}
+			pkt.DTypeOrVSeq = burst
+			packets = append(packets, pkt)
+		}
+	}
+	terminator := base
+	terminator.FrameType = frameTypeDataSync
+	terminator.DTypeOrVSeq = dtypeTerminatorWithLC
+	return append(packets, terminator)
+}
+
+// record feeds packets to the parrot and returns the playback.
+func record(t *testing.T, p *Parrot, packets []proto.Packet) []proto.Packet {
+	t.Helper()
+	played := make(chan proto.Packet, len(packets))
+	for _, pkt := range packets {
+		if !p.Handle("ipsc", pkt, func(pkt proto.Packet) bool {
+			played <- pkt
+			return true
+		}) {
+			t.Fatal("expected the parrot to take the call")
+		}
+	}
+	var out []proto.Packet
+	for {
+		select {
+		case pkt := <-played:
+			out = append(out, pkt)
+		case <-time.After(200 * time.Millisecond):
+			return out
+		}
+	}
+}
+
+func TestPlaybackPrivateCall(t *testing.T) {
+	t.Parallel()
+	p, pace := newTestParrot(config.Parrot{ID: 9990, MaxDuration: 30})
+	recorded := call(9990, false, 2)
+	played := record(t, p, recorded)
+
+	if len(played) != len(recorded) {
+		t.Fatalf("expected %d frames played back, got %d", len(recorded), len(played))
+	}
+	streamID := played[0].StreamID
+	if streamID == testStreamID {
+		t.Fatal("expected a new stream ID")
+	}
+	for i, pkt := range played {
+		if pkt.Src != 9990 || pkt.Dst != testCaller || pkt.GroupCall {
+			t.Fatalf("frame %d: expected 9990 → %d private, got %d → %d group=%t", i, testCaller, pkt.Src, pkt.Dst, pkt.GroupCall)
+		}
+		if pkt.StreamID != streamID || pkt.Seq != uint(i) {
+			t.Fatalf("frame %d: expected stream %d seq %d, got stream %d seq %d", i, streamID, i, pkt.StreamID, pkt.Seq)
+		}
+		if !pkt.Slot || pkt.FrameType != recorded[i].FrameType || pkt.DTypeOrVSeq != recorded[i].DTypeOrVSeq {
+			t.Fatalf("frame %d: expected the recorded frame back on its slot", i)
+		}
+	}
+
+	pace.mu.Lock()
+	defer pace.mu.Unlock()
+	if len(pace.delays) != len(recorded) || pace.delays[0] != replayDelay {
+		t.Fatalf("expected a %s pause then one delay per frame, got %v", replayDelay, pace.delays)
+	}
+	for _, d := range pace.delays[1:] {
+		if d != FrameInterval {
+			t.Fatalf("expected frames %s apart, got %v", FrameInterval, pace.delays)
+		}
+	}
+}
+
+func TestPlaybackGroupCallStaysOnTalkgroup(t *testing.T) {
+	t.Parallel()
+	p, _ := newTestParrot(config.Parrot{ID: 9990, Group: true, MaxDuration: 30})
+	if p.Handle("ipsc", call(9990, false, 1)[0], func(proto.Packet) bool { return true }) {
+		t.Fatal("expected a private call to a group parrot to be ignored")
+	}
+	played := record(t, p, call(9990, true, 1))
+	if len(played) == 0 {
+		t.Fatal("expected a playback")
+	}
+	for _, pkt := range played {
+		if pkt.Src != 9990 || pkt.Dst != 9990 || !pkt.GroupCall {
+			t.Fatalf("expected playback from 9990 on TG 9990, got %d → %d group=%t", pkt.Src, pkt.Dst, pkt.GroupCall)
+		}
+	}
+}
+
+func TestMaxRecordingLength(t *testing.T) {
+	t.Parallel()
+	p, _ := newTestParrot(config.Parrot{ID: 9990, MaxDuration: 1})
+	// 1s holds 16 frames; the call has 1 + 30 + 1.
+	played := record(t, p, call(9990, false, 5))
+	if len(played) != p.maxFrames+1 {
+		t.Fatalf("expected %d frames and the terminator, got %d", p.maxFrames, len(played))
+	}
+	last := played[len(played)-1]
+	if last.FrameType != frameTypeDataSync || last.DTypeOrVSeq != dtypeTerminatorWithLC {
+		t.Fatal("expected a truncated playback to still end with the terminator")
+	}
+}
+
+func TestRefusesEncryptedCall(t *testing.T) {
+	t.Parallel()
+	p, _ := newTestParrot(config.Parrot{ID: 9990, MaxDuration: 30})
+	packets := call(9990, false, 1)
+	pi := packets[0]
+	pi.DTypeOrVSeq = dtypePIHeader
+	packets = append([]proto.Packet{packets[0], pi}, packets[1:]...)
+	if played := record(t, p, packets); len(played) != 0 {
+		t.Fatalf("expected no playback of an encrypted call, got %d frames", len(played))
+	}
+}
+
+func TestIgnoresOtherCalls(t *testing.T) {
+	t.Parallel()
+	p, _ := newTestParrot(config.Parrot{ID: 9990, MaxDuration: 30})
+	for _, pkt := range call(91, true, 1) {
+		if p.Handle("ipsc", pkt, func(proto.Packet) bool { return true }) {
+			t.Fatal("expected a call to another destination to be left alone")
+		}
+	}
+}
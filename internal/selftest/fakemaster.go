@@ -0,0 +1,150 @@
+package selftest
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+)
+
+// FakeMaster is a minimal in-process MMDVM master. It accepts any login,
+// answers pings, records every DMRD packet the client sends, and can push
+// DMRD packets back to the client.
+type FakeMaster struct {
+	conn *net.UDPConn
+	wg   sync.WaitGroup
+
+	mu     sync.Mutex
+	client *net.UDPAddr
+
+	ready     chan struct{}
+	readyOnce sync.Once
+	packets   chan proto.Packet
+}
+
+// NewFakeMaster starts a fake master listening on a random loopback port.
+func NewFakeMaster() (*FakeMaster, error) {
+	return ListenFakeMaster("127.0.0.1:0")
+}
+
+// ListenFakeMaster starts a fake master listening on address, so an
+// instance running elsewhere can be configured to log in to it.
+func ListenFakeMaster(address string) (*FakeMaster, error) {
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving fake master address: %w", err)
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error starting fake master: %w", err)
+	}
+	m := &FakeMaster{
+		conn:    conn,
+		ready:   make(chan struct{}),
+		packets: make(chan proto.Packet, 256),
+	}
+	m.wg.Add(1)
+	go m.handler()
+	return m, nil
+}
+
+// Addr returns the host:port the fake master is listening on.
+func (m *FakeMaster) Addr() string {
+	return m.conn.LocalAddr().String()
+}
+
+// Ready is closed once a client has completed the login handshake.
+func (m *FakeMaster) Ready() <-chan struct{} {
+	return m.ready
+}
+
+// Packets returns the DMRD packets received from the client.
+func (m *FakeMaster) Packets() <-chan proto.Packet {
+	return m.packets
+}
+
+// Send delivers a DMRD packet to the logged-in client.
+func (m *FakeMaster) Send(pkt proto.Packet) error {
+	m.mu.Lock()
+	client := m.client
+	m.mu.Unlock()
+	if client == nil {
+		return errors.New("no client connected to fake master")
+	}
+	if _, err := m.conn.WriteToUDP(pkt.Encode(), client); err != nil {
+		return fmt.Errorf("error sending to client: %w", err)
+	}
+	return nil
+}
+
+// Close stops the fake master and waits for its receive loop to exit.
+func (m *FakeMaster) Close() error {
+	err := m.conn.Close()
+	m.wg.Wait()
+	return err
+}
+
+func (m *FakeMaster) handler() {
+	defer m.wg.Done()
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := m.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		data := make([]byte, n)
+		copy(data, buf[:n])
+		m.handlePacket(data, addr)
+	}
+}
+
+func (m *FakeMaster) handlePacket(data []byte, addr *net.UDPAddr) {
+	if len(data) < 4 {
+		return
+	}
+
+	m.mu.Lock()
+	m.client = addr
+	m.mu.Unlock()
+
+	switch {
+	case hasPrefix(data, "RPTCL"):
+		// Client disconnecting, nothing to answer.
+	case hasPrefix(data, "RPTPING"):
+		m.reply(append([]byte("MSTPONG"), data[7:]...), addr)
+	case hasPrefix(data, "RPTL"):
+		salt := make([]byte, 4)
+		if _, err := rand.Read(salt); err != nil {
+			return
+		}
+		m.reply(append([]byte("RPTACK"), salt...), addr)
+	case hasPrefix(data, "RPTK"):
+		m.reply(append([]byte("RPTACK"), data[4:8]...), addr)
+	case hasPrefix(data, "RPTC"):
+		m.reply(append([]byte("RPTACK"), data[4:8]...), addr)
+		m.readyOnce.Do(func() { close(m.ready) })
+	case hasPrefix(data, "DMRD"):
+		pkt, ok := proto.Decode(data)
+		if !ok {
+			return
+		}
+		select {
+		case m.packets <- pkt:
+		default:
+		}
+	}
+}
+
+func (m *FakeMaster) reply(data []byte, addr *net.UDPAddr) {
+	_, _ = m.conn.WriteToUDP(data, addr)
+}
+
+func hasPrefix(data []byte, prefix string) bool {
+	return len(data) >= len(prefix) && string(data[:len(prefix)]) == prefix
+}
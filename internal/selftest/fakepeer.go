@@ -0,0 +1,117 @@
+package selftest
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/ipsc"
+)
+
+// FakePeer is a minimal in-process IPSC peer. It registers with a master,
+// records every user packet the master sends it, and can transmit raw
+// IPSC user packets to the master.
+type FakePeer struct {
+	id     uint32
+	master *net.UDPAddr
+	conn   *net.UDPConn
+	wg     sync.WaitGroup
+
+	registered     chan struct{}
+	registeredOnce sync.Once
+	packets        chan []byte
+}
+
+// NewFakePeer creates a fake peer with the given ID that talks to master.
+func NewFakePeer(id uint32, master *net.UDPAddr) (*FakePeer, error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+	if err != nil {
+		return nil, fmt.Errorf("error starting fake peer: %w", err)
+	}
+	p := &FakePeer{
+		id:         id,
+		master:     master,
+		conn:       conn,
+		registered: make(chan struct{}),
+		packets:    make(chan []byte, 256),
+	}
+	p.wg.Add(1)
+	go p.handler()
+	return p, nil
+}
+
+// Register sends a MasterRegisterRequest and waits for the reply.
+func (p *FakePeer) Register(ctx context.Context) error {
+	const (
+		modeDigitalBothSlots = 0x6A
+		flagsCapabilities    = 0x0D
+	)
+	data := make([]byte, 10)
+	data[0] = byte(ipsc.PacketType_MasterRegisterRequest)
+	binary.BigEndian.PutUint32(data[1:5], p.id)
+	data[5] = modeDigitalBothSlots
+	data[9] = flagsCapabilities
+	if err := p.Send(data); err != nil {
+		return err
+	}
+	select {
+	case <-p.registered:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for registration reply: %w", ctx.Err())
+	}
+}
+
+// Send transmits a raw IPSC packet to the master.
+func (p *FakePeer) Send(data []byte) error {
+	if _, err := p.conn.WriteToUDP(data, p.master); err != nil {
+		return fmt.Errorf("error sending to master: %w", err)
+	}
+	return nil
+}
+
+// Packets returns the user (voice/data) packets received from the master.
+func (p *FakePeer) Packets() <-chan []byte {
+	return p.packets
+}
+
+// Close stops the fake peer and waits for its receive loop to exit.
+func (p *FakePeer) Close() error {
+	err := p.conn.Close()
+	p.wg.Wait()
+	return err
+}
+
+func (p *FakePeer) handler() {
+	defer p.wg.Done()
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := p.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		if n < 1 {
+			continue
+		}
+		data := make([]byte, n)
+		copy(data, buf[:n])
+
+		switch ipsc.PacketType(data[0]) {
+		case ipsc.PacketType_MasterRegisterReply:
+			p.registeredOnce.Do(func() { close(p.registered) })
+		case ipsc.PacketType_GroupVoice, ipsc.PacketType_PrivateVoice,
+			ipsc.PacketType_GroupData, ipsc.PacketType_PrivateData:
+			select {
+			case p.packets <- data:
+			default:
+			}
+		default:
+		}
+	}
+}
@@ -0,0 +1,405 @@
+// Package selftest runs the whole bridge in-process against fakes: a real
+// IPSCServer, IPSCTranslator, and MMDVMClient are started on loopback, a
+// fake MMDVM master accepts the client's login, and a fake IPSC peer
+// registers with the server. A call is then pushed through in each
+// direction and the delivered frames are checked.
+//
+// It serves both as the release smoke test and as a field diagnostic:
+// if the self-test passes, the binary is translating correctly.
+package selftest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/USA-RedDragon/dmrgo/dmr/enums"
+	"github.com/USA-RedDragon/dmrgo/dmr/layer2"
+	"github.com/USA-RedDragon/dmrgo/dmr/layer2/elements"
+	"github.com/USA-RedDragon/dmrgo/dmr/layer2/pdu"
+	l3elements "github.com/USA-RedDragon/dmrgo/dmr/layer3/elements"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/ipsc"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/timeslot"
+)
+
+// Identities used by the self-test. They are arbitrary but valid 24-bit
+// subscriber IDs so they survive the 3-byte IPSC address fields.
+const (
+	bridgeID = 311860
+	peerID   = 311861
+
+	ipscCallSrc = 3120001
+	ipscCallDst = 91
+	mmdvmSrc    = 3120002
+	mmdvmDst    = 3100
+)
+
+// DMR frame type and data type values used to build and check calls.
+const (
+	frameTypeVoice     uint = 0
+	frameTypeVoiceSync uint = 1
+	frameTypeDataSync  uint = 2
+
+	ipscBurstVoiceTerm byte = 0x02
+	ipscCallInfoEnd    byte = 0x40
+)
+
+// Options tunes the self-test.
+type Options struct {
+	// FrameInterval is the spacing between transmitted frames.
+	// Defaults to the 60ms DMR frame cadence.
+	FrameInterval time.Duration
+	// Settle is how long to keep collecting frames after a terminator
+	// to catch stragglers and duplicates. Defaults to 250ms.
+	Settle time.Duration
+}
+
+// Check is the outcome of one self-test assertion.
+type Check struct {
+	Name   string
+	Passed bool
+	Detail string
+}
+
+// Report collects the checks performed by a self-test run.
+type Report struct {
+	Checks []Check
+}
+
+// Passed returns true if every check passed.
+func (r *Report) Passed() bool {
+	for _, c := range r.Checks {
+		if !c.Passed {
+			return false
+		}
+	}
+	return len(r.Checks) > 0
+}
+
+// WriteTo writes a human-readable pass/fail report to w.
+func (r *Report) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, r.String())
+	return int64(n), err
+}
+
+func (r *Report) String() string {
+	var sb strings.Builder
+	for _, c := range r.Checks {
+		status := "PASS"
+		if !c.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&sb, "[%s] %s: %s\n", status, c.Name, c.Detail)
+	}
+	if r.Passed() {
+		sb.WriteString("self-test passed\n")
+	} else {
+		sb.WriteString("self-test FAILED\n")
+	}
+	return sb.String()
+}
+
+func (r *Report) add(name string, passed bool, format string, args ...any) {
+	r.Checks = append(r.Checks, Check{Name: name, Passed: passed, Detail: fmt.Sprintf(format, args...)})
+}
+
+// Run starts the bridge components against the fakes, runs one call in
+// each direction, and returns the report. All sockets and goroutines are
+// cleaned up before it returns. The returned error is only non-nil if the
+// harness itself could not be set up; translation failures are reported
+// as failed checks.
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if opts.FrameInterval <= 0 {
+		opts.FrameInterval = 60 * time.Millisecond
+	}
+	if opts.Settle <= 0 {
+		opts.Settle = 250 * time.Millisecond
+	}
+
+	report := &Report{}
+
+	master, err := NewFakeMaster()
+	if err != nil {
+		return nil, err
+	}
+	defer master.Close()
+
+	cfg := &config.Config{
+		LogLevel: config.LogLevelInfo,
+		MMDVM: []config.MMDVM{{
+			Name:         "selftest",
+			Callsign:     "SELFTEST",
+			ID:           bridgeID,
+			ColorCode:    1,
+			MasterServer: master.Addr(),
+			Password:     "selftest",
+			PassAllTG:    []int{1, 2},
+			PassAllPC:    []int{1, 2},
+		}},
+		IPSC: config.IPSC{
+			IP: "127.0.0.1",
+		},
+	}
+
+	client := mmdvm.NewMMDVMClient(&cfg.MMDVM[0], nil, mmdvm.TranslatorOptions{})
+	client.SetOutboundTSManager(timeslot.NewManager())
+	server := ipsc.NewIPSCServer(cfg, nil)
+	server.SetBurstHandler(mmdvm.NewBurstRouter([]*mmdvm.MMDVMClient{client}))
+	client.SetIPSCHandler(server.SendUserPacket)
+
+	if err := client.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start MMDVM client: %w", err)
+	}
+	defer client.Stop()
+
+	if err := server.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start IPSC server: %w", err)
+	}
+	defer server.Stop()
+
+	select {
+	case <-master.Ready():
+		report.add("MMDVM login", true, "client completed login/auth/config handshake")
+	case <-ctx.Done():
+		report.add("MMDVM login", false, "client did not complete the handshake: %v", ctx.Err())
+		return report, nil
+	}
+
+	peer, err := NewFakePeer(peerID, server.Addr())
+	if err != nil {
+		return nil, err
+	}
+	defer peer.Close()
+
+	if err := peer.Register(ctx); err != nil {
+		report.add("IPSC registration", false, "%v", err)
+		return report, nil
+	}
+	report.add("IPSC registration", true, "peer %d registered", peerID)
+
+	runIPSCToMMDVM(ctx, opts, report, peer, master)
+	runMMDVMToIPSC(ctx, opts, report, peer, master)
+
+	return report, nil
+}
+
+// runIPSCToMMDVM transmits a call from the fake peer and checks what the
+// fake master receives.
+func runIPSCToMMDVM(ctx context.Context, opts Options, report *Report, peer *FakePeer, master *FakeMaster) {
+	const name = "IPSC→MMDVM"
+
+	call := BuildCall(ipscCallSrc, ipscCallDst, false, 0x1001, 1)
+
+	// Encode the call the way a repeater would, using a translator that
+	// stamps the fake peer's ID.
+	encoder, err := ipsc.NewIPSCTranslator()
+	if err != nil {
+		report.add(name+" encode", false, "%v", err)
+		return
+	}
+	encoder.SetPeerID(peerID)
+	for _, pkt := range call {
+		for _, data := range encoder.TranslateToIPSC(pkt) {
+			if err := peer.Send(data); err != nil {
+				report.add(name+" send", false, "%v", err)
+				return
+			}
+			if !sleep(ctx, opts.FrameInterval) {
+				break
+			}
+		}
+	}
+
+	var received []proto.Packet
+	deadline := time.NewTimer(opts.Settle + time.Duration(len(call))*opts.FrameInterval)
+	defer deadline.Stop()
+	sawTerminator := false
+collect:
+	for {
+		select {
+		case pkt := <-master.Packets():
+			received = append(received, pkt)
+			if pkt.FrameType == frameTypeDataSync && pkt.DTypeOrVSeq == uint(elements.DataTypeTerminatorWithLC) && !sawTerminator {
+				sawTerminator = true
+				deadline.Reset(opts.Settle)
+			}
+		case <-deadline.C:
+			break collect
+		case <-ctx.Done():
+			break collect
+		}
+	}
+
+	report.add(name+" frame count", len(received) == len(call),
+		"received %d of %d frames", len(received), len(call))
+
+	mismatched := 0
+	for _, pkt := range received {
+		if pkt.Src != ipscCallSrc || pkt.Dst != ipscCallDst {
+			mismatched++
+		}
+	}
+	report.add(name+" src/dst", len(received) > 0 && mismatched == 0,
+		"%d of %d frames carried src=%d dst=%d", len(received)-mismatched, len(received), ipscCallSrc, ipscCallDst)
+
+	report.add(name+" terminator", sawTerminator, "terminator delivered: %t", sawTerminator)
+}
+
+// runMMDVMToIPSC transmits a call from the fake master and checks what the
+// fake peer receives.
+func runMMDVMToIPSC(ctx context.Context, opts Options, report *Report, peer *FakePeer, master *FakeMaster) {
+	const name = "MMDVM→IPSC"
+
+	call := BuildCall(mmdvmSrc, mmdvmDst, true, 0x2002, 1)
+
+	// The number of IPSC packets a translator produces for this call is
+	// the number the peer should receive.
+	reference, err := ipsc.NewIPSCTranslator()
+	if err != nil {
+		report.add(name+" encode", false, "%v", err)
+		return
+	}
+	expected := 0
+	for _, pkt := range call {
+		expected += len(reference.TranslateToIPSC(pkt))
+	}
+
+	for _, pkt := range call {
+		if err := master.Send(pkt); err != nil {
+			report.add(name+" send", false, "%v", err)
+			return
+		}
+		if !sleep(ctx, opts.FrameInterval) {
+			break
+		}
+	}
+
+	var received [][]byte
+	deadline := time.NewTimer(opts.Settle + time.Duration(expected)*opts.FrameInterval)
+	defer deadline.Stop()
+	sawTerminator := false
+collect:
+	for {
+		select {
+		case data := <-peer.Packets():
+			received = append(received, data)
+			if len(data) > 30 && data[30] == ipscBurstVoiceTerm && data[17]&ipscCallInfoEnd != 0 && !sawTerminator {
+				sawTerminator = true
+				deadline.Reset(opts.Settle)
+			}
+		case <-deadline.C:
+			break collect
+		case <-ctx.Done():
+			break collect
+		}
+	}
+
+	report.add(name+" frame count", len(received) == expected,
+		"received %d of %d packets", len(received), expected)
+
+	mismatched := 0
+	for _, data := range received {
+		if len(data) < 12 {
+			mismatched++
+			continue
+		}
+		src := uint(data[6])<<16 | uint(data[7])<<8 | uint(data[8])
+		dst := uint(data[9])<<16 | uint(data[10])<<8 | uint(data[11])
+		if src != mmdvmSrc || dst != mmdvmDst {
+			mismatched++
+		}
+	}
+	report.add(name+" src/dst", len(received) > 0 && mismatched == 0,
+		"%d of %d packets carried src=%d dst=%d", len(received)-mismatched, len(received), mmdvmSrc, mmdvmDst)
+
+	report.add(name+" terminator", sawTerminator, "terminator delivered: %t", sawTerminator)
+}
+
+// BuildCall returns a complete group voice call as DMRD packets: a voice
+// LC header, the given number of superframes of voice bursts A-F, and a
+// terminator. slot is true for TS2.
+func BuildCall(src, dst uint, slot bool, streamID uint, superframes int) []proto.Packet {
+	flc := pdu.FullLinkControl{
+		FLCO:           enums.FLCOGroupVoiceChannelUser,
+		FeatureSetID:   enums.StandardizedFID,
+		ServiceOptions: l3elements.ServiceOptions{},
+		GroupAddress:   int(dst), //nolint:gosec // self-test IDs are 24-bit
+		SourceAddress:  int(src), //nolint:gosec // self-test IDs are 24-bit
+	}
+	var lc [12]byte
+	if encoded, err := flc.Encode(); err == nil {
+		copy(lc[:], encoded)
+	}
+
+	base := proto.Packet{
+		Signature: "DMRD",
+		Src:       src,
+		Dst:       dst,
+		Repeater:  bridgeID,
+		Slot:      slot,
+		GroupCall: true,
+		StreamID:  streamID,
+	}
+
+	call := make([]proto.Packet, 0, 2+6*superframes)
+
+	header := base
+	header.FrameType = frameTypeDataSync
+	header.DTypeOrVSeq = uint(elements.DataTypeVoiceLCHeader)
+	header.DMRData = layer2.BuildLCDataBurst(lc, elements.DataTypeVoiceLCHeader, 1)
+	call = append(call, header)
+
+	voiceBursts := []enums.VoiceBurstType{
+		enums.VoiceBurstA, enums.VoiceBurstB, enums.VoiceBurstC,
+		enums.VoiceBurstD, enums.VoiceBurstE, enums.VoiceBurstF,
+	}
+	for range superframes {
+		for i, vb := range voiceBursts {
+			burst := layer2.Burst{VoiceBurst: vb}
+			voice := base
+			voice.DTypeOrVSeq = uint(i) //nolint:gosec // bounded by len(voiceBursts)
+			if vb == enums.VoiceBurstA {
+				burst.SyncPattern = enums.BsSourcedVoice
+				voice.FrameType = frameTypeVoiceSync
+			} else {
+				burst.SyncPattern = enums.EmbeddedSignallingPattern
+				burst.HasEmbeddedSignalling = true
+				burst.EmbeddedSignalling = pdu.EmbeddedSignalling{
+					ColorCode: 1,
+					LCSS:      enums.ContinuationFragmentLCorCSBK,
+				}
+				voice.FrameType = frameTypeVoice
+			}
+			voice.DMRData = burst.Encode()
+			call = append(call, voice)
+		}
+	}
+
+	terminator := base
+	terminator.FrameType = frameTypeDataSync
+	terminator.DTypeOrVSeq = uint(elements.DataTypeTerminatorWithLC)
+	terminator.DMRData = layer2.BuildLCDataBurst(lc, elements.DataTypeTerminatorWithLC, 1)
+	call = append(call, terminator)
+
+	for i := range call {
+		call[i].Seq = uint(i)
+	}
+	return call
+}
+
+// sleep waits for d or until ctx is done. It returns false if ctx ended.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,89 @@
+package selftest
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunPasses(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	report, err := Run(ctx, Options{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !report.Passed() {
+		t.Fatalf("expected self-test to pass:\n%s", report)
+	}
+
+	want := []string{
+		"MMDVM login",
+		"IPSC registration",
+		"IPSC→MMDVM frame count",
+		"IPSC→MMDVM src/dst",
+		"IPSC→MMDVM terminator",
+		"MMDVM→IPSC frame count",
+		"MMDVM→IPSC src/dst",
+		"MMDVM→IPSC terminator",
+	}
+	if len(report.Checks) != len(want) {
+		t.Fatalf("expected %d checks, got %d:\n%s", len(want), len(report.Checks), report)
+	}
+	for i, name := range want {
+		if report.Checks[i].Name != name {
+			t.Errorf("check %d: expected %q, got %q", i, name, report.Checks[i].Name)
+		}
+	}
+	if !strings.HasSuffix(report.String(), "self-test passed\n") {
+		t.Fatalf("expected passing summary line, got:\n%s", report)
+	}
+}
+
+func TestRunCleansUpGoroutines(t *testing.T) {
+	// Not parallel: counts process-wide goroutines.
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := Run(ctx, Options{FrameInterval: 20 * time.Millisecond}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	// Burst handlers run on short-lived goroutines; give them a moment.
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Fatalf("goroutines leaked: before=%d after=%d", before, after)
+	}
+}
+
+func TestReportFailedCheck(t *testing.T) {
+	t.Parallel()
+	r := &Report{}
+	r.add("one", true, "ok")
+	r.add("two", false, "got %d", 3)
+	if r.Passed() {
+		t.Fatal("expected report with a failed check to fail")
+	}
+	out := r.String()
+	if !strings.Contains(out, "[PASS] one: ok") || !strings.Contains(out, "[FAIL] two: got 3") {
+		t.Fatalf("unexpected report output:\n%s", out)
+	}
+	if !strings.HasSuffix(out, "self-test FAILED\n") {
+		t.Fatalf("expected failing summary line, got:\n%s", out)
+	}
+}
+
+func TestEmptyReportFails(t *testing.T) {
+	t.Parallel()
+	if (&Report{}).Passed() {
+		t.Fatal("expected empty report to fail")
+	}
+}
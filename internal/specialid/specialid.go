@@ -0,0 +1,153 @@
+// Package specialid applies the policy table for calls to reserved
+// network IDs, such as 4000 (disconnect) or 9 (local), whose meaning is
+// specific to each network. The table is consulted before rewrite rules
+// and blocks a call, passes it on, or gives it to a local service such as
+// the parrot.
+package specialid
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+)
+
+// Direction is the way a call travels through the bridge.
+type Direction int
+
+const (
+	// ToIPSC is a call from a master toward the repeater.
+	ToIPSC Direction = iota
+	// ToMaster is a call from the repeater toward a master.
+	ToMaster
+)
+
+func (d Direction) String() string {
+	if d == ToIPSC {
+		return "to_ipsc"
+	}
+	return "to_master"
+}
+
+// Verdict is the outcome of evaluating a packet against the table.
+type Verdict int
+
+const (
+	// Pass sends the packet on to the rewrite rules.
+	Pass Verdict = iota
+	// Block drops the packet.
+	Block
+	// Handled means a local handler took the packet.
+	Handled
+)
+
+// Handler is a local service answering calls to special IDs. It offers
+// pkt, which arrived from side, to the service and reports whether the
+// service took it. Replies go back to side through send.
+type Handler func(side string, pkt proto.Packet, send func(proto.Packet) bool) bool
+
+// DefaultRules returns the table used when none is configured: the
+// disconnect and status IDs 4000 to 5000 are kept away from the
+// repeater, and everything else passes.
+func DefaultRules() []config.SpecialID {
+	return []config.SpecialID{
+		{FromID: 4000, ToID: 5000, Direction: config.SpecialIDDirectionToIPSC, Action: config.SpecialIDActionBlock},
+	}
+}
+
+// Table holds the special-ID rules and the local handlers they dispatch
+// to. The first rule matching a call's destination and direction decides.
+type Table struct {
+	rules []config.SpecialID
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+// New returns the table configured by cfg, or the default table if cfg
+// has no special-ID rules. With the parrot enabled, its ID is handled
+// locally ahead of every other rule.
+func New(cfg *config.Config) *Table {
+	rules := cfg.SpecialIDs
+	if len(rules) == 0 {
+		rules = DefaultRules()
+	}
+	if cfg.Parrot.Enabled {
+		rules = append([]config.SpecialID{{
+			FromID:  cfg.Parrot.ID,
+			Action:  config.SpecialIDActionLocal,
+			Handler: config.SpecialIDHandlerParrot,
+		}}, rules...)
+	}
+	return &Table{
+		rules:    rules,
+		handlers: map[string]Handler{},
+	}
+}
+
+// Register makes h the handler for local rules naming it.
+func (t *Table) Register(name string, h Handler) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handlers[name] = h
+}
+
+// Lookup returns the action for a call to dst travelling in direction,
+// and for local rules the handler to offer it to. A local rule whose
+// handler is not registered, and a call no rule matches, pass.
+func (t *Table) Lookup(direction Direction, dst uint) (config.SpecialIDAction, Handler) {
+	for _, rule := range t.rules {
+		if !matches(rule, direction, dst) {
+			continue
+		}
+		if rule.Action != config.SpecialIDActionLocal {
+			return rule.Action, nil
+		}
+		t.mu.RLock()
+		h := t.handlers[rule.Handler]
+		t.mu.RUnlock()
+		if h == nil {
+			return config.SpecialIDActionPass, nil
+		}
+		return config.SpecialIDActionLocal, h
+	}
+	return config.SpecialIDActionPass, nil
+}
+
+// Evaluate applies the table to pkt, which arrived from side travelling
+// in direction. A packet taken by a local handler is Handled; one the
+// handler declines passes.
+func (t *Table) Evaluate(direction Direction, side string, pkt proto.Packet, send func(proto.Packet) bool) Verdict {
+	action, h := t.Lookup(direction, pkt.Dst)
+	switch action {
+	case config.SpecialIDActionBlock:
+		slog.Debug("Blocked call to special ID", "side", side, "direction", direction, "src", pkt.Src, "dst", pkt.Dst)
+		return Block
+	case config.SpecialIDActionLocal:
+		if h(side, pkt, send) {
+			return Handled
+		}
+	}
+	return Pass
+}
+
+// matches reports whether rule applies to a call to dst travelling in
+// direction.
+func matches(rule config.SpecialID, direction Direction, dst uint) bool {
+	last := rule.ToID
+	if last == 0 {
+		last = rule.FromID
+	}
+	if dst < uint(rule.FromID) || dst > uint(last) {
+		return false
+	}
+	switch rule.Direction {
+	case config.SpecialIDDirectionToIPSC:
+		return direction == ToIPSC
+	case config.SpecialIDDirectionToMaster:
+		return direction == ToMaster
+	default:
+		return true
+	}
+}
@@ -0,0 +1,113 @@
+package specialid
+
+import (
+	"testing"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+)
+
+func callTo(dst uint) proto.Packet {
+	return proto.Packet{Signature: "DMRD", Src: 3120101, Dst: dst, StreamID: 1}
+}
+
+func noSend(proto.Packet) bool { return true }
+
+func TestDefaultTable(t *testing.T) {
+	t.Parallel()
+	table := New(&config.Config{})
+	tests := []struct {
+		name      string
+		direction Direction
+		dst       uint
+		want      Verdict
+	}{
+		{"disconnect toward IPSC", ToIPSC, 4000, Block},
+		{"status toward IPSC", ToIPSC, 5000, Block},
+		{"inside the range toward IPSC", ToIPSC, 4500, Block},
+		{"disconnect toward master", ToMaster, 4000, Pass},
+		{"below the range", ToIPSC, 3999, Pass},
+		{"above the range", ToIPSC, 5001, Pass},
+		{"local", ToIPSC, 9, Pass},
+		{"all call", ToIPSC, 16777215, Pass},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := table.Evaluate(tt.direction, "test", callTo(tt.dst), noSend); got != tt.want {
+				t.Fatalf("expected verdict %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConfiguredTableReplacesDefaults(t *testing.T) {
+	t.Parallel()
+	table := New(&config.Config{SpecialIDs: []config.SpecialID{
+		{FromID: 9, Action: config.SpecialIDActionBlock},
+		{FromID: 4000, Direction: config.SpecialIDDirectionToIPSC, Action: config.SpecialIDActionPass},
+		{FromID: 1, ToID: 9999, Direction: config.SpecialIDDirectionToMaster, Action: config.SpecialIDActionBlock},
+	}})
+	tests := []struct {
+		direction Direction
+		dst       uint
+		want      Verdict
+	}{
+		{ToIPSC, 9, Block},
+		{ToMaster, 9, Block},
+		{ToIPSC, 4000, Pass},
+		// No longer blocked once the defaults are replaced.
+		{ToIPSC, 5000, Pass},
+		{ToMaster, 5000, Block},
+		{ToMaster, 10000, Pass},
+	}
+	for _, tt := range tests {
+		if got := table.Evaluate(tt.direction, "test", callTo(tt.dst), noSend); got != tt.want {
+			t.Errorf("%s to %d: expected verdict %d, got %d", tt.direction, tt.dst, tt.want, got)
+		}
+	}
+}
+
+func TestLocalHandler(t *testing.T) {
+	t.Parallel()
+	cfg := &config.Config{Parrot: config.Parrot{Enabled: true, ID: 9990}}
+	table := New(cfg)
+
+	// Without a registered handler the call passes.
+	if got := table.Evaluate(ToMaster, "ipsc", callTo(9990), noSend); got != Pass {
+		t.Fatalf("expected an unhandled local call to pass, got %d", got)
+	}
+
+	var taken []string
+	table.Register(config.SpecialIDHandlerParrot, func(side string, pkt proto.Packet, send func(proto.Packet) bool) bool {
+		if pkt.GroupCall {
+			return false
+		}
+		taken = append(taken, side)
+		return send(pkt)
+	})
+	sent := 0
+	send := func(proto.Packet) bool {
+		sent++
+		return true
+	}
+	if got := table.Evaluate(ToMaster, "ipsc", callTo(9990), send); got != Handled {
+		t.Fatalf("expected the call to be handled, got %d", got)
+	}
+	if got := table.Evaluate(ToIPSC, "mmdvm/test", callTo(9990), send); got != Handled {
+		t.Fatalf("expected the call to be handled, got %d", got)
+	}
+	declined := callTo(9990)
+	declined.GroupCall = true
+	if got := table.Evaluate(ToIPSC, "mmdvm/test", declined, send); got != Pass {
+		t.Fatalf("expected a declined call to pass, got %d", got)
+	}
+	if len(taken) != 2 || taken[0] != "ipsc" || taken[1] != "mmdvm/test" || sent != 2 {
+		t.Fatalf("expected the handler to take one call from each side, got %v and %d sends", taken, sent)
+	}
+
+	// The defaults still apply after the parrot's rule.
+	if got := table.Evaluate(ToIPSC, "mmdvm/test", callTo(4000), send); got != Block {
+		t.Fatalf("expected the defaults to follow the parrot rule, got %d", got)
+	}
+}
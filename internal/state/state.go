@@ -0,0 +1,115 @@
+// Package state persists a small snapshot of runtime bookkeeping (the
+// peers of each IPSC server, recently used call-control IDs, and the calls from IPSC in
+// progress) so a restart can pick up
+// where the previous process left off instead of waiting for every
+// peer to re-register.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshotVersion is bumped whenever the snapshot format changes
+// incompatibly. Snapshots with another version are treated as corrupt.
+const snapshotVersion = 1
+
+var (
+	ErrSnapshotCorrupt = errors.New("state snapshot is corrupt")
+	ErrSnapshotStale   = errors.New("state snapshot is stale")
+)
+
+// Snapshot is the persisted runtime state.
+type Snapshot struct {
+	Version int       `json:"version"`
+	SavedAt time.Time `json:"saved_at"`
+	// Peers holds the registered peers of the first IPSC server, and
+	// ServerPeers those of each other IPSC server, keyed by its name.
+	Peers       []Peer            `json:"peers"`
+	ServerPeers map[string][]Peer `json:"server_peers,omitempty"`
+	// CallControls holds the recently used IPSC call-control IDs of each
+	// MMDVM network's translator, keyed by network name, oldest first.
+	CallControls map[string][]uint32 `json:"call_controls,omitempty"`
+	// Streams holds the calls from IPSC each MMDVM network's translator
+	// had in progress, keyed by network name.
+	Streams map[string][]Stream `json:"streams,omitempty"`
+}
+
+// Peer is the persisted form of a registered IPSC peer.
+type Peer struct {
+	ID    uint32  `json:"id"`
+	Addr  string  `json:"addr"`
+	Mode  byte    `json:"mode"`
+	Flags [4]byte `json:"flags"`
+}
+
+// Stream is the persisted form of a call from IPSC in progress.
+type Stream struct {
+	CallControl uint32 `json:"call_control"`
+	StreamID    uint32 `json:"stream_id"`
+	Seq         uint8  `json:"seq"`
+	HeaderSent  bool   `json:"header_sent"`
+	PeerID      uint32 `json:"peer_id"`
+	Src         uint   `json:"src"`
+	Dst         uint   `json:"dst"`
+	GroupCall   bool   `json:"group_call"`
+	Slot        bool   `json:"slot"`
+}
+
+// Save writes the snapshot to path atomically, stamping it with the
+// current version and time.
+func Save(path string, snap *Snapshot) error {
+	snap.Version = snapshotVersion
+	snap.SavedAt = time.Now()
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding state snapshot: %w", err)
+	}
+
+	// Write to a temporary file and rename so a crash mid-write never
+	// leaves a truncated snapshot behind.
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating state snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // No-op once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing state snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing state snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error replacing state snapshot: %w", err)
+	}
+	return nil
+}
+
+// Load reads the snapshot at path. It returns ErrSnapshotCorrupt if the
+// file cannot be decoded and ErrSnapshotStale if it is older than maxAge.
+// A maxAge of zero disables the age check. A missing file is reported as
+// an error wrapping os.ErrNotExist.
+func Load(path string, maxAge time.Duration) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading state snapshot: %w", err)
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSnapshotCorrupt, err)
+	}
+	if snap.Version != snapshotVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrSnapshotCorrupt, snap.Version)
+	}
+	if maxAge > 0 && time.Since(snap.SavedAt) > maxAge {
+		return nil, fmt.Errorf("%w: saved %s ago", ErrSnapshotStale, time.Since(snap.SavedAt).Round(time.Second))
+	}
+	return &snap, nil
+}
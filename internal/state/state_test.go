@@ -0,0 +1,121 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "state.json")
+	snap := &Snapshot{
+		Peers: []Peer{
+			{ID: 311860, Addr: "10.10.250.2:50000", Mode: 0x6A, Flags: [4]byte{0, 0, 0, 0x0D}},
+			{ID: 311861, Addr: "10.10.250.3:50001", Mode: 0x6A, Flags: [4]byte{0, 0, 0, 0x1D}},
+		},
+		ServerPeers: map[string][]Peer{
+			"second": {{ID: 311862, Addr: "10.10.251.2:50000", Mode: 0x6A, Flags: [4]byte{0, 0, 0, 0x0D}}},
+		},
+		CallControls: map[string][]uint32{"BM": {7, 8, 9}},
+		Streams: map[string][]Stream{
+			"BM": {{CallControl: 0xAAAA, StreamID: 3, Seq: 12, HeaderSent: true}},
+		},
+	}
+	if err := Save(path, snap); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, err := Load(path, time.Hour)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(got.Peers) != 2 || got.Peers[1] != snap.Peers[1] {
+		t.Fatalf("peers did not round-trip: %+v", got.Peers)
+	}
+	if peers := got.ServerPeers["second"]; len(peers) != 1 || peers[0] != snap.ServerPeers["second"][0] {
+		t.Fatalf("server peers did not round-trip: %+v", got.ServerPeers)
+	}
+	if cc := got.CallControls["BM"]; len(cc) != 3 || cc[2] != 9 {
+		t.Fatalf("call controls did not round-trip: %v", got.CallControls)
+	}
+	if streams := got.Streams["BM"]; len(streams) != 1 || streams[0] != snap.Streams["BM"][0] {
+		t.Fatalf("streams did not round-trip: %v", got.Streams)
+	}
+	if got.SavedAt.IsZero() {
+		t.Fatal("expected SavedAt to be set")
+	}
+}
+
+func TestSaveReplacesExisting(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	if err := Save(path, &Snapshot{Peers: []Peer{{ID: 1}}}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := Save(path, &Snapshot{Peers: []Peer{{ID: 2}}}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := Load(path, 0)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(got.Peers) != 1 || got.Peers[0].ID != 2 {
+		t.Fatalf("expected the latest snapshot, got %+v", got.Peers)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected no leftover temp files, got %d entries", len(entries))
+	}
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := Load(path, 0); !errors.Is(err, ErrSnapshotCorrupt) {
+		t.Fatalf("expected ErrSnapshotCorrupt, got %v", err)
+	}
+}
+
+func TestLoadWrongVersion(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99}`), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := Load(path, 0); !errors.Is(err, ErrSnapshotCorrupt) {
+		t.Fatalf("expected ErrSnapshotCorrupt, got %v", err)
+	}
+}
+
+func TestLoadStale(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "state.json")
+	data := []byte(`{"version": 1, "saved_at": "2000-01-01T00:00:00Z"}`)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := Load(path, time.Hour); !errors.Is(err, ErrSnapshotStale) {
+		t.Fatalf("expected ErrSnapshotStale, got %v", err)
+	}
+	if _, err := Load(path, 0); err != nil {
+		t.Fatalf("expected age check to be disabled with zero max age, got %v", err)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	t.Parallel()
+	_, err := Load(filepath.Join(t.TempDir(), "missing.json"), 0)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected os.ErrNotExist, got %v", err)
+	}
+}
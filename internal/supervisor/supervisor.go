@@ -0,0 +1,293 @@
+// Package supervisor keeps long-lived components running in separate
+// failure domains. A panic in one component is recovered, logged with
+// its stack, and answered by restarting that component with backoff
+// while the others keep running. A component that keeps crashing
+// exhausts its restart budget, and the supervisor escalates so the
+// process can exit and let its service manager intervene.
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// ErrRestartBudgetExceeded is reported on Fatal when a component has
+// been restarted more than the budget allows within the window.
+var ErrRestartBudgetExceeded = errors.New("restart budget exceeded")
+
+// ErrPanicked is returned when a component's Start panics.
+var ErrPanicked = errors.New("component panicked")
+
+// Component is a long-lived part of the process. Start and Stop must be
+// idempotent, and a stopped component must be able to start again.
+type Component interface {
+	Start() error
+	Stop()
+	// SetPanicHandler registers a function that the component calls,
+	// without blocking, when one of its goroutines panics.
+	SetPanicHandler(fn func(recovered any, stack []byte))
+}
+
+const (
+	defaultWindow     = time.Hour
+	defaultMinBackoff = time.Second
+	defaultMaxBackoff = 30 * time.Second
+)
+
+// Options tunes restart behavior.
+type Options struct {
+	// MaxRestarts is how many times a single component may be restarted
+	// within Window. The next crash escalates. Zero escalates on the
+	// first crash.
+	MaxRestarts int
+	// Window is the period over which restarts are counted. Defaults to
+	// one hour.
+	Window time.Duration
+	// MinBackoff is the delay before the first restart. Each further
+	// restart within the window doubles it, up to MaxBackoff. Defaults
+	// to 1s and 30s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+type fault struct {
+	recovered any
+	stack     []byte
+}
+
+type supervised struct {
+	name      string
+	component Component
+	faults    chan fault
+
+	mu       sync.Mutex
+	restarts []time.Time // within the window, oldest first
+	total    int
+}
+
+// Supervisor starts, watches, and restarts a set of components.
+type Supervisor struct {
+	opts       Options
+	components []*supervised
+	fatal      chan error
+
+	mu       sync.Mutex
+	started  bool
+	done     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// New returns a supervisor with no components.
+func New(opts Options) *Supervisor {
+	if opts.Window <= 0 {
+		opts.Window = defaultWindow
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = defaultMinBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaultMaxBackoff
+	}
+	opts.MaxBackoff = max(opts.MaxBackoff, opts.MinBackoff)
+	return &Supervisor{
+		opts:  opts,
+		fatal: make(chan error, 1),
+		done:  make(chan struct{}),
+	}
+}
+
+// Add registers c under name, which identifies it in logs. Components are
+// started in the order they are added and stopped in reverse. Add must be
+// called before Start.
+func (s *Supervisor) Add(name string, c Component) {
+	sc := &supervised{
+		name:      name,
+		component: c,
+		faults:    make(chan fault, 1),
+	}
+	c.SetPanicHandler(func(recovered any, stack []byte) {
+		// Only the first of several concurrent panics matters; the
+		// restart replaces every goroutine of the component anyway.
+		select {
+		case sc.faults <- fault{recovered: recovered, stack: stack}:
+		default:
+		}
+	})
+	s.components = append(s.components, sc)
+}
+
+// Start starts every component and begins watching them. If a component
+// fails to start, the ones already started are stopped and the error is
+// returned. Calling Start again does nothing.
+func (s *Supervisor) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return nil
+	}
+	for i, sc := range s.components {
+		if err := start(sc.component); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				stop(s.components[j])
+			}
+			return fmt.Errorf("failed to start %s: %w", sc.name, err)
+		}
+	}
+	s.started = true
+	for _, sc := range s.components {
+		s.wg.Add(1)
+		go s.watch(sc)
+	}
+	return nil
+}
+
+// Stop stops watching and stops every component in reverse order. It is
+// safe to call more than once; a stopped supervisor cannot be restarted.
+func (s *Supervisor) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.started {
+		return
+	}
+	for i := len(s.components) - 1; i >= 0; i-- {
+		stop(s.components[i])
+	}
+	s.started = false
+}
+
+// Fatal receives an error wrapping ErrRestartBudgetExceeded when a
+// component has crashed too often. The supervisor stops restarting that
+// component; the caller is expected to shut down and exit.
+func (s *Supervisor) Fatal() <-chan error {
+	return s.fatal
+}
+
+// Restarts returns how many times the named component has been restarted.
+func (s *Supervisor) Restarts(name string) int {
+	for _, sc := range s.components {
+		if sc.name == name {
+			sc.mu.Lock()
+			defer sc.mu.Unlock()
+			return sc.total
+		}
+	}
+	return 0
+}
+
+func (s *Supervisor) watch(sc *supervised) {
+	defer s.wg.Done()
+	for {
+		select {
+		case f := <-sc.faults:
+			slog.Error("Component panicked", "component", sc.name, "panic", f.recovered, "stack", string(f.stack))
+			if !s.restart(sc) {
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// restart stops sc and starts it again after a backoff, retrying failed
+// starts. It returns false if the supervisor is stopping or the restart
+// budget is exhausted.
+func (s *Supervisor) restart(sc *supervised) bool {
+	stop(sc)
+	// Drop panics from the instance that was just stopped.
+	select {
+	case <-sc.faults:
+	default:
+	}
+
+	for {
+		backoff, ok := s.reserveRestart(sc)
+		if !ok {
+			err := fmt.Errorf("%w: %s restarted %d times within %s", ErrRestartBudgetExceeded, sc.name, s.opts.MaxRestarts, s.opts.Window)
+			slog.Error("Giving up on component", "component", sc.name, "error", err)
+			select {
+			case s.fatal <- err:
+			default:
+			}
+			return false
+		}
+
+		slog.Warn("Restarting component", "component", sc.name, "backoff", backoff)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-s.done:
+			timer.Stop()
+			return false
+		}
+
+		err := start(sc.component)
+		if err == nil {
+			slog.Info("Component restarted", "component", sc.name)
+			return true
+		}
+		slog.Error("Failed to restart component", "component", sc.name, "error", err)
+		stop(sc)
+	}
+}
+
+// reserveRestart records a restart of sc if the budget allows one, and
+// returns the backoff to wait before it.
+func (s *Supervisor) reserveRestart(sc *supervised) (time.Duration, bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	now := time.Now()
+	cutoff := now.Add(-s.opts.Window)
+	recent := sc.restarts[:0]
+	for _, t := range sc.restarts {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	sc.restarts = recent
+	if len(sc.restarts) >= s.opts.MaxRestarts {
+		return 0, false
+	}
+
+	backoff := s.opts.MinBackoff
+	for range len(sc.restarts) {
+		backoff *= 2
+		if backoff >= s.opts.MaxBackoff {
+			backoff = s.opts.MaxBackoff
+			break
+		}
+	}
+	sc.restarts = append(sc.restarts, now)
+	sc.total++
+	return backoff, true
+}
+
+// start calls c.Start, turning a panic into an error.
+func start(c Component) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v\n%s", ErrPanicked, r, debug.Stack())
+		}
+	}()
+	return c.Start()
+}
+
+// stop calls sc's Stop, logging rather than propagating a panic.
+func stop(sc *supervised) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Component panicked while stopping", "component", sc.name, "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+	sc.component.Stop()
+}
@@ -0,0 +1,231 @@
+package supervisor
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeComponent panics on demand from a goroutine of its own, the way a
+// receive loop would.
+type fakeComponent struct {
+	mu           sync.Mutex
+	running      bool
+	panicHandler func(any, []byte)
+	startErr     error
+
+	starts atomic.Int32
+	stops  atomic.Int32
+}
+
+func (f *fakeComponent) Start() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.running {
+		return nil
+	}
+	f.starts.Add(1)
+	if f.startErr != nil {
+		return f.startErr
+	}
+	f.running = true
+	return nil
+}
+
+func (f *fakeComponent) Stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.running {
+		return
+	}
+	f.stops.Add(1)
+	f.running = false
+}
+
+func (f *fakeComponent) SetPanicHandler(fn func(any, []byte)) {
+	f.panicHandler = fn
+}
+
+func (f *fakeComponent) isRunning() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.running
+}
+
+// crash panics on a new goroutine and reports it like a component would.
+func (f *fakeComponent) crash() {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() {
+			if r := recover(); r != nil {
+				f.panicHandler(r, []byte("stack"))
+			}
+		}()
+		panic("boom")
+	}()
+	<-done
+}
+
+func testOptions(maxRestarts int) Options {
+	return Options{
+		MaxRestarts: maxRestarts,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  4 * time.Millisecond,
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func TestRestartsPanickingComponent(t *testing.T) {
+	t.Parallel()
+	sup := New(testOptions(3))
+	flaky := &fakeComponent{}
+	steady := &fakeComponent{}
+	sup.Add("flaky", flaky)
+	sup.Add("steady", steady)
+	if err := sup.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer sup.Stop()
+
+	for i := range 2 {
+		flaky.crash()
+		waitFor(t, "restart", func() bool { return sup.Restarts("flaky") == i+1 && flaky.isRunning() })
+	}
+	if got := flaky.starts.Load(); got != 3 {
+		t.Fatalf("expected 3 starts of the flaky component, got %d", got)
+	}
+	if got := steady.starts.Load(); got != 1 || !steady.isRunning() {
+		t.Fatalf("expected the steady component to be left alone, got %d starts", got)
+	}
+	if got := sup.Restarts("steady"); got != 0 {
+		t.Fatalf("expected no restarts of the steady component, got %d", got)
+	}
+	select {
+	case err := <-sup.Fatal():
+		t.Fatalf("unexpected escalation: %v", err)
+	default:
+	}
+}
+
+func TestEscalatesWhenBudgetExceeded(t *testing.T) {
+	t.Parallel()
+	sup := New(testOptions(2))
+	flaky := &fakeComponent{}
+	sup.Add("flaky", flaky)
+	if err := sup.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer sup.Stop()
+
+	for i := range 2 {
+		flaky.crash()
+		waitFor(t, "restart", func() bool { return sup.Restarts("flaky") == i+1 && flaky.isRunning() })
+	}
+	flaky.crash()
+
+	select {
+	case err := <-sup.Fatal():
+		if !errors.Is(err, ErrRestartBudgetExceeded) {
+			t.Fatalf("expected %v, got %v", ErrRestartBudgetExceeded, err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected escalation")
+	}
+	if flaky.isRunning() {
+		t.Fatal("expected the component to stay stopped after escalation")
+	}
+	if got := sup.Restarts("flaky"); got != 2 {
+		t.Fatalf("expected 2 restarts, got %d", got)
+	}
+}
+
+func TestRestartBudgetWindow(t *testing.T) {
+	t.Parallel()
+	opts := testOptions(1)
+	opts.Window = 50 * time.Millisecond
+	sup := New(opts)
+	flaky := &fakeComponent{}
+	sup.Add("flaky", flaky)
+	if err := sup.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer sup.Stop()
+
+	for i := range 3 {
+		flaky.crash()
+		waitFor(t, "restart", func() bool { return sup.Restarts("flaky") == i+1 && flaky.isRunning() })
+		// Let the previous restart age out of the window.
+		time.Sleep(2 * opts.Window)
+	}
+	select {
+	case err := <-sup.Fatal():
+		t.Fatalf("unexpected escalation: %v", err)
+	default:
+	}
+}
+
+func TestBackoffDoubles(t *testing.T) {
+	t.Parallel()
+	sup := New(Options{MaxRestarts: 10, MinBackoff: time.Second, MaxBackoff: 5 * time.Second})
+	sc := &supervised{}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		got, ok := sup.reserveRestart(sc)
+		if !ok {
+			t.Fatalf("restart %d: expected budget to allow it", i+1)
+		}
+		if got != w {
+			t.Fatalf("restart %d: expected backoff %s, got %s", i+1, w, got)
+		}
+	}
+}
+
+func TestStartFailureStopsStartedComponents(t *testing.T) {
+	t.Parallel()
+	sup := New(testOptions(1))
+	first := &fakeComponent{}
+	broken := &fakeComponent{startErr: errors.New("bind failed")}
+	sup.Add("first", first)
+	sup.Add("broken", broken)
+
+	if err := sup.Start(); err == nil {
+		t.Fatal("expected Start to fail")
+	}
+	if first.isRunning() || first.stops.Load() != 1 {
+		t.Fatal("expected the first component to be stopped again")
+	}
+}
+
+func TestStartStopIdempotent(t *testing.T) {
+	t.Parallel()
+	sup := New(testOptions(1))
+	c := &fakeComponent{}
+	sup.Add("c", c)
+	for range 2 {
+		if err := sup.Start(); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+	}
+	sup.Stop()
+	sup.Stop()
+	if got := c.starts.Load(); got != 1 {
+		t.Fatalf("expected 1 start, got %d", got)
+	}
+	if got := c.stops.Load(); got != 1 {
+		t.Fatalf("expected 1 stop, got %d", got)
+	}
+}
@@ -0,0 +1,39 @@
+package testutil
+
+import (
+	"sync"
+	"syscall"
+)
+
+// DSCPSetter is a dscp.Setter that records the marks set through it
+// instead of setting them, and fails with Err if it is set. It is safe for
+// concurrent use.
+type DSCPSetter struct {
+	Err error
+
+	mu         sync.Mutex
+	tos, class int
+}
+
+// SetTOS records tos.
+func (f *DSCPSetter) SetTOS(_ syscall.RawConn, tos int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tos = tos
+	return f.Err
+}
+
+// SetTrafficClass records class.
+func (f *DSCPSetter) SetTrafficClass(_ syscall.RawConn, class int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.class = class
+	return f.Err
+}
+
+// TOS returns the last ToS set, or 0 if none was.
+func (f *DSCPSetter) TOS() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.tos
+}
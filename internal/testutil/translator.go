@@ -0,0 +1,139 @@
+// Package testutil holds fakes shared by the tests of several packages.
+package testutil
+
+import (
+	"sync"
+
+	mmdvm "github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+)
+
+// IPSCPacket is an IPSC packet handed to a Translator.
+type IPSCPacket struct {
+	Type byte
+	Data []byte
+}
+
+// Translator is an ipsc.Translator that records what it is handed. It
+// translates and terminates nothing unless ToIPSC, ToMMDVM, TerminatePeer
+// or TerminateAll is set. It is safe for concurrent use.
+type Translator struct {
+	// ToIPSC and ToMMDVM, if set, produce the results of TranslateToIPSC
+	// and TranslateToMMDVM, and TerminatePeer and TerminateAll those of
+	// TerminatePeerStreams and TerminateStreams. They must be set before
+	// the translator is used.
+	ToIPSC        func(pkt mmdvm.Packet) [][]byte
+	ToMMDVM       func(packetType byte, data []byte) []mmdvm.Packet
+	TerminatePeer func(peerID uint32) []mmdvm.Packet
+	TerminateAll  func() []mmdvm.Packet
+
+	mu              sync.Mutex
+	fromMMDVM       []mmdvm.Packet
+	fromIPSC        []IPSCPacket
+	cleanedUp       []uint32
+	peersTerminated []uint32
+	allTerminated   int
+	peerID          uint32
+	peerIDSet       bool
+}
+
+// TranslateToIPSC records pkt.
+func (t *Translator) TranslateToIPSC(pkt mmdvm.Packet) [][]byte {
+	t.mu.Lock()
+	t.fromMMDVM = append(t.fromMMDVM, pkt)
+	t.mu.Unlock()
+	if t.ToIPSC == nil {
+		return nil
+	}
+	return t.ToIPSC(pkt)
+}
+
+// TranslateToMMDVM records a copy of data.
+func (t *Translator) TranslateToMMDVM(packetType byte, data []byte) []mmdvm.Packet {
+	t.mu.Lock()
+	t.fromIPSC = append(t.fromIPSC, IPSCPacket{Type: packetType, Data: append([]byte(nil), data...)})
+	t.mu.Unlock()
+	if t.ToMMDVM == nil {
+		return nil
+	}
+	return t.ToMMDVM(packetType, data)
+}
+
+// CleanupStream records streamID.
+func (t *Translator) CleanupStream(streamID uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cleanedUp = append(t.cleanedUp, streamID)
+}
+
+// TerminatePeerStreams records peerID.
+func (t *Translator) TerminatePeerStreams(peerID uint32) []mmdvm.Packet {
+	t.mu.Lock()
+	t.peersTerminated = append(t.peersTerminated, peerID)
+	t.mu.Unlock()
+	if t.TerminatePeer == nil {
+		return nil
+	}
+	return t.TerminatePeer(peerID)
+}
+
+// TerminateStreams records that every stream was terminated.
+func (t *Translator) TerminateStreams() []mmdvm.Packet {
+	t.mu.Lock()
+	t.allTerminated++
+	t.mu.Unlock()
+	if t.TerminateAll == nil {
+		return nil
+	}
+	return t.TerminateAll()
+}
+
+// SetPeerID records peerID.
+func (t *Translator) SetPeerID(peerID uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.peerID = peerID
+	t.peerIDSet = true
+}
+
+// FromMMDVM returns the packets handed to TranslateToIPSC, in order.
+func (t *Translator) FromMMDVM() []mmdvm.Packet {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]mmdvm.Packet(nil), t.fromMMDVM...)
+}
+
+// FromIPSC returns the packets handed to TranslateToMMDVM, in order.
+func (t *Translator) FromIPSC() []IPSCPacket {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]IPSCPacket(nil), t.fromIPSC...)
+}
+
+// CleanedUp returns the stream IDs handed to CleanupStream, in order.
+func (t *Translator) CleanedUp() []uint32 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]uint32(nil), t.cleanedUp...)
+}
+
+// PeersTerminated returns the peer IDs handed to TerminatePeerStreams,
+// in order.
+func (t *Translator) PeersTerminated() []uint32 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]uint32(nil), t.peersTerminated...)
+}
+
+// AllTerminated returns how many times TerminateStreams was called.
+func (t *Translator) AllTerminated() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.allTerminated
+}
+
+// PeerID returns the last peer ID set, and whether one was set at all.
+func (t *Translator) PeerID() (uint32, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.peerID, t.peerIDSet
+}
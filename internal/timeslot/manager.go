@@ -4,7 +4,8 @@
 // audio on the same timeslot simultaneously, the first call is delivered
 // immediately while subsequent calls are buffered in memory. When the
 // active call terminates (or times out), buffered calls are delivered
-// in FIFO order.
+// in FIFO order. A BusyPolicy can instead reject calls arriving on a busy
+// slot, or hold just one of them until the slot frees.
 package timeslot
 
 import (
@@ -13,6 +14,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/metrics"
 )
 
@@ -21,6 +23,27 @@ import (
 // a voice terminator packet is lost.
 const DefaultTimeout = 3 * time.Second
 
+// queueIdle is how long a call held by BusyQueue may send nothing before
+// it is taken to have ended while waiting. Voice frames arrive every 60ms.
+const queueIdle = 500 * time.Millisecond
+
+// BusyPolicy is what happens to a call that arrives on a slot held by
+// another call.
+type BusyPolicy int
+
+const (
+	// BusyBuffer buffers every waiting call in full and delivers them in
+	// turn. This is the default.
+	BusyBuffer BusyPolicy = iota
+	// BusyReject drops the waiting call for as long as it lasts.
+	BusyReject
+	// BusyQueue holds the first packet of one waiting call, normally its
+	// header, and starts the call live when the slot frees. Frames sent
+	// while it waits are dropped, and a call that waits too long is
+	// rejected. Calls arriving while one is held are rejected.
+	BusyQueue
+)
+
 // activeCall tracks a single in-progress call on one timeslot.
 type activeCall struct {
 	streamID uint
@@ -34,12 +57,15 @@ type pendingStream struct {
 	streamID uint
 	network  string
 	packets  []any
+	queuedAt time.Time
+	lastSeen time.Time
 }
 
 // slotState tracks the active call and any pending calls on one timeslot.
 type slotState struct {
 	active  *activeCall
-	pending []*pendingStream // FIFO queue of waiting calls
+	pending []*pendingStream   // FIFO queue of waiting calls
+	refused map[uint]time.Time // streams turned away, by last packet seen
 }
 
 // Manager arbitrates access to DMR timeslots. Two timeslots exist
@@ -49,11 +75,13 @@ type slotState struct {
 //
 // Create one Manager per traffic direction that needs isolation.
 type Manager struct {
-	mu        sync.Mutex
-	slots     [2]*slotState // [0] = TS1 (Slot=false), [1] = TS2 (Slot=true)
-	timeout   time.Duration
-	metrics   *metrics.Metrics
-	direction string // "inbound" or "outbound" (for metric labels)
+	mu         sync.Mutex
+	slots      [2]*slotState // [0] = TS1 (Slot=false), [1] = TS2 (Slot=true)
+	timeout    time.Duration
+	busyPolicy BusyPolicy
+	maxWait    time.Duration // longest a call is held by BusyQueue
+	metrics    *metrics.Metrics
+	direction  string // "inbound" or "outbound" (for metric labels)
 }
 
 // NewManager creates a Manager with the default timeout.
@@ -69,6 +97,28 @@ func (m *Manager) SetMetrics(met *metrics.Metrics, direction string) {
 	m.direction = direction
 }
 
+// SetBusyPolicy sets what happens to calls arriving on a busy slot. maxWait
+// is how long BusyQueue holds a call before rejecting it.
+func (m *Manager) SetBusyPolicy(policy BusyPolicy, maxWait time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.busyPolicy = policy
+	m.maxWait = maxWait
+}
+
+// SetBusyPolicyFromConfig sets the busy policy configured for calls
+// toward the peers of an IPSC server.
+func (m *Manager) SetBusyPolicyFromConfig(cfg config.IPSC) {
+	policy := BusyBuffer
+	switch cfg.BusyPolicy {
+	case config.BusyPolicyReject:
+		policy = BusyReject
+	case config.BusyPolicyQueue:
+		policy = BusyQueue
+	}
+	m.SetBusyPolicy(policy, time.Duration(cfg.BusyQueueTimeout)*time.Second)
+}
+
 // slotIndex converts the boolean Slot flag to an array index.
 func slotIndex(slot bool) int {
 	if slot {
@@ -112,7 +162,8 @@ func (ss *slotState) findPending(streamID uint) *pendingStream {
 // Submit adds a packet to the timeslot manager. If the stream is active
 // (owns the slot), returns true and the caller should process the packet
 // immediately. If the slot is busy with another call, the packet is
-// buffered in memory and returns false — the caller should not process it.
+// buffered in memory, or dropped as the busy policy directs, and returns
+// false — the caller should not process it.
 //
 // When the active call has timed out, all pending streams are discarded
 // (they're stale) and the new stream becomes active.
@@ -124,6 +175,11 @@ func (m *Manager) Submit(slot bool, streamID uint, network string, packet any) b
 	ss := m.getOrCreateSlot(idx)
 	now := time.Now()
 
+	if m.isRefused(ss, streamID, now) {
+		// A call turned away stays away, even once the slot frees.
+		return false
+	}
+
 	if ss.active == nil {
 		// Slot is free — claim it.
 		ss.active = &activeCall{
@@ -165,6 +221,15 @@ func (m *Manager) Submit(slot bool, streamID uint, network string, packet any) b
 		return true
 	}
 
+	switch m.busyPolicy {
+	case BusyReject:
+		m.refuse(ss, slot, streamID, network, now, "rejected")
+		return false
+	case BusyQueue:
+		m.queue(ss, slot, streamID, network, packet, now)
+		return false
+	}
+
 	// Slot is busy — buffer the packet in a pending stream.
 	ps := ss.findPending(streamID)
 	if ps == nil {
@@ -202,6 +267,10 @@ func (m *Manager) Release(slot bool, streamID uint) []any {
 		"slot", slot, "streamID", streamID, "network", ss.active.network,
 		"pendingCount", len(ss.pending))
 
+	if m.busyPolicy == BusyQueue {
+		m.expirePending(ss, slot, time.Now())
+	}
+
 	if len(ss.pending) == 0 {
 		// No pending streams — slot is free.
 		ss.active = nil
@@ -222,5 +291,97 @@ func (m *Manager) Release(slot bool, streamID uint) []any {
 	slog.Debug("timeslot activating pending stream",
 		"slot", slot, "streamID", next.streamID, "network", next.network,
 		"bufferedPackets", len(next.packets))
+	if m.busyPolicy == BusyQueue {
+		m.countBusy(slot, "started")
+	}
 	return next.packets
 }
+
+// queue holds the first packet of a call arriving on a busy slot under
+// BusyQueue, or drops the packet if the call is already held. Must be
+// called with mu held.
+func (m *Manager) queue(ss *slotState, slot bool, streamID uint, network string, packet any, now time.Time) {
+	m.expirePending(ss, slot, now)
+	if m.isRefused(ss, streamID, now) {
+		return
+	}
+	if ps := ss.findPending(streamID); ps != nil {
+		ps.lastSeen = now
+		return
+	}
+	if len(ss.pending) > 0 {
+		m.refuse(ss, slot, streamID, network, now, "rejected")
+		return
+	}
+	ss.pending = append(ss.pending, &pendingStream{
+		streamID: streamID,
+		network:  network,
+		packets:  []any{packet},
+		queuedAt: now,
+		lastSeen: now,
+	})
+	slog.Debug("timeslot busy, queueing new stream",
+		"slot", slot, "activeStream", ss.active.streamID,
+		"pendingStream", streamID, "network", network)
+	m.countBusy(slot, "queued")
+}
+
+// expirePending turns away held calls that have waited longer than
+// maxWait and forgets ones that ended while waiting. Must be called with
+// mu held.
+func (m *Manager) expirePending(ss *slotState, slot bool, now time.Time) {
+	kept := ss.pending[:0]
+	for _, ps := range ss.pending {
+		switch {
+		case now.Sub(ps.lastSeen) > queueIdle:
+			slog.Debug("timeslot dropping queued stream that ended while waiting",
+				"slot", slot, "streamID", ps.streamID, "network", ps.network)
+			m.countBusy(slot, "abandoned")
+		case now.Sub(ps.queuedAt) > m.maxWait:
+			m.refuse(ss, slot, ps.streamID, ps.network, now, "expired")
+		default:
+			kept = append(kept, ps)
+		}
+	}
+	clear(ss.pending[len(kept):])
+	ss.pending = kept
+}
+
+// refuse turns a stream away for as long as it keeps sending, so that it
+// does not take the slot mid-call once the slot frees. Must be called with
+// mu held.
+func (m *Manager) refuse(ss *slotState, slot bool, streamID uint, network string, now time.Time, outcome string) {
+	if ss.refused == nil {
+		ss.refused = make(map[uint]time.Time)
+	}
+	for id, lastSeen := range ss.refused {
+		if now.Sub(lastSeen) > m.timeout {
+			delete(ss.refused, id)
+		}
+	}
+	ss.refused[streamID] = now
+	slog.Debug("timeslot busy, turning away stream",
+		"slot", slot, "streamID", streamID, "network", network, "outcome", outcome)
+	m.countBusy(slot, outcome)
+}
+
+// isRefused reports whether streamID was turned away and is still
+// sending, noting the packet. Must be called with mu held.
+func (m *Manager) isRefused(ss *slotState, streamID uint, now time.Time) bool {
+	lastSeen, ok := ss.refused[streamID]
+	if !ok {
+		return false
+	}
+	if now.Sub(lastSeen) > m.timeout {
+		delete(ss.refused, streamID)
+		return false
+	}
+	ss.refused[streamID] = now
+	return true
+}
+
+func (m *Manager) countBusy(slot bool, outcome string) {
+	if m.metrics != nil {
+		m.metrics.TimeslotBusyCalls.WithLabelValues(slotLabel(slot), m.direction, outcome).Inc()
+	}
+}
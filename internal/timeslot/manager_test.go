@@ -303,3 +303,83 @@ func TestSubmit_SameStreamDoesNotDuplicate(t *testing.T) {
 		t.Fatalf("expected 3 buffered packets from stream 200, got %d", len(buffered))
 	}
 }
+
+func TestBusyReject(t *testing.T) {
+	m := NewManager()
+	m.SetBusyPolicy(BusyReject, 0)
+	m.Submit(false, 100, "net1", "a-header")
+	if m.Submit(false, 200, "net2", "b-header") {
+		t.Fatal("second stream on busy slot should be rejected")
+	}
+	m.Submit(false, 200, "net2", "b-voice")
+
+	if buffered := m.Release(false, 100); buffered != nil {
+		t.Fatalf("rejected stream should not be buffered, got %v", buffered)
+	}
+	// The slot is free, but the rejected call must not take it mid-call.
+	if m.Submit(false, 200, "net2", "b-voice") {
+		t.Fatal("rejected stream should stay rejected once the slot frees")
+	}
+	if !m.Submit(false, 300, "net3", "c-header") {
+		t.Fatal("a new stream should take the free slot")
+	}
+}
+
+func TestBusyQueue_StartsAfterActiveEnds(t *testing.T) {
+	m := NewManager()
+	m.SetBusyPolicy(BusyQueue, time.Second)
+	m.Submit(false, 100, "net1", "a-header")
+	if m.Submit(false, 200, "net2", "b-header") {
+		t.Fatal("second stream on busy slot should be queued")
+	}
+	m.Submit(false, 200, "net2", "b-voice1")
+	if m.Submit(false, 300, "net3", "c-header") {
+		t.Fatal("third stream should be rejected while one is queued")
+	}
+
+	buffered := m.Release(false, 100)
+	if len(buffered) != 1 || buffered[0].(string) != "b-header" {
+		t.Fatalf("expected only the queued header, got %v", buffered)
+	}
+	if !m.Submit(false, 200, "net2", "b-voice2") {
+		t.Fatal("queued stream should continue live once started")
+	}
+	if m.Submit(false, 300, "net3", "c-voice") {
+		t.Fatal("rejected stream should stay rejected")
+	}
+}
+
+func TestBusyQueue_Expiry(t *testing.T) {
+	m := NewManager()
+	m.SetBusyPolicy(BusyQueue, 20*time.Millisecond)
+	m.Submit(false, 100, "net1", "a-header")
+	m.Submit(false, 200, "net2", "b-header")
+
+	time.Sleep(30 * time.Millisecond)
+	m.Submit(false, 100, "net1", "a-voice")
+	if m.Submit(false, 200, "net2", "b-voice") {
+		t.Fatal("expired stream should not be delivered")
+	}
+	if buffered := m.Release(false, 100); buffered != nil {
+		t.Fatalf("expired stream should not start, got %v", buffered)
+	}
+	if m.Submit(false, 200, "net2", "b-voice") {
+		t.Fatal("expired stream should not take the free slot mid-call")
+	}
+}
+
+func TestBusyQueue_EndedWhileWaiting(t *testing.T) {
+	m := NewManager()
+	m.SetBusyPolicy(BusyQueue, 10*time.Second)
+	m.Submit(false, 100, "net1", "a-header")
+	m.Submit(false, 200, "net2", "b-header")
+	m.Submit(false, 200, "net2", "b-terminator")
+
+	time.Sleep(queueIdle + 20*time.Millisecond)
+	if buffered := m.Release(false, 100); buffered != nil {
+		t.Fatalf("stream that ended while waiting should not start, got %v", buffered)
+	}
+	if !m.Submit(false, 300, "net3", "c-header") {
+		t.Fatal("slot should be free")
+	}
+}
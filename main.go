@@ -8,6 +8,7 @@ import (
 	"github.com/USA-RedDragon/configulator"
 	"github.com/USA-RedDragon/ipsc2mmdvm/cmd"
 	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/spf13/pflag"
 )
 
 // https://goreleaser.com/cookbooks/using-main.version/
@@ -21,6 +22,11 @@ var (
 func main() {
 	rootCmd := cmd.NewCommand(version, commit)
 
+	// The ipsc section is one IPSC server or a list of them, which
+	// configulator cannot read from the config file as a whole, so it has
+	// a configulator of its own, pointed at each entry in turn. Each gets
+	// a flag set of its own, as either fails on a flag it does not know.
+	flags := pflag.NewFlagSet("config", pflag.ContinueOnError)
 	c := configulator.New[config.Config]().
 		WithEnvironmentVariables(&configulator.EnvironmentVariableOptions{
 			Separator: "_",
@@ -28,9 +34,17 @@ func main() {
 		WithFile(&configulator.FileOptions{
 			Paths: []string{"config.yaml"},
 		}).
-		WithPFlags(rootCmd.Flags(), nil)
+		WithPFlags(flags, nil)
+	ipscFlags := pflag.NewFlagSet("ipsc", pflag.ContinueOnError)
+	ipsc := configulator.New[config.IPSCSection]().
+		WithEnvironmentVariables(&configulator.EnvironmentVariableOptions{
+			Separator: "_",
+		}).
+		WithPFlags(ipscFlags, nil)
+	rootCmd.Flags().AddFlagSet(flags)
+	rootCmd.Flags().AddFlagSet(ipscFlags)
 
-	rootCmd.SetContext(c.WithContext(context.TODO()))
+	rootCmd.SetContext(cmd.WithIPSCSection(c.WithContext(context.TODO()), ipsc))
 
 	if err := rootCmd.Execute(); err != nil {
 		slog.Error("Encountered an error.", "error", err.Error())
@@ -0,0 +1,87 @@
+package ipsc_test
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/pkg/ipsc"
+	"github.com/USA-RedDragon/ipsc2mmdvm/pkg/mmdvm"
+)
+
+func ExampleNewServer() {
+	server, err := ipsc.NewServer(ipsc.ServerConfig{
+		IP:     "127.0.0.1",
+		Port:   0, // pick a free port
+		PeerID: 311860,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := server.Start(); err != nil {
+		log.Fatal(err)
+	}
+	defer server.Stop()
+
+	fmt.Println("listening:", server.Addr() != nil)
+	fmt.Println("peers:", len(server.Peers()))
+	// Output:
+	// listening: true
+	// peers: 0
+}
+
+func ExampleNewServer_invalid() {
+	_, err := ipsc.NewServer(ipsc.ServerConfig{IP: "not-an-ip"})
+	fmt.Println(errors.Is(err, ipsc.ErrInvalidConfig))
+	// Output: true
+}
+
+func ExampleTranslator_ToMMDVM() {
+	translator, err := ipsc.NewTranslator()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Packets that are too short or not voice/data are not translated.
+	fmt.Println(translator.ToMMDVM(ipsc.PacketGroupVoice, []byte{0x80, 0x00}) == nil)
+	// Output: true
+}
+
+// Example_repeater relays every call heard from registered repeaters to a
+// DMR master, and calls from the master back to the repeaters.
+func Example_repeater() {
+	server, err := ipsc.NewServer(ipsc.ServerConfig{
+		IP:     "10.10.250.1",
+		Port:   50000,
+		PeerID: 311860,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	client, err := mmdvm.NewClient(mmdvm.Config{
+		Name:     "BrandMeister",
+		Callsign: "N0CALL",
+		ID:       311860,
+		Masters:  []string{"3104.master.brandmeister.network:62031"},
+		Password: "passw0rd",
+		Rules:    mmdvm.Rules{PassAllTG: []int{1, 2}, PassAllPC: []int{1, 2}},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	server.OnBurst(func(packetType ipsc.PacketType, data []byte, _ *net.UDPAddr) {
+		client.HandleIPSCBurst(byte(packetType), data)
+	})
+	client.OnIPSC(server.Send)
+
+	if err := client.Start(); err != nil {
+		log.Fatal(err)
+	}
+	defer client.Stop()
+	if err := server.Start(); err != nil {
+		log.Fatal(err)
+	}
+	defer server.Stop()
+}
@@ -0,0 +1,134 @@
+package ipsc
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/ipsc"
+)
+
+// ErrInvalidConfig is returned by NewServer when the configuration is
+// incomplete or out of range.
+var ErrInvalidConfig = errors.New("invalid IPSC server configuration")
+
+// ServerConfig configures a Server.
+type ServerConfig struct {
+	// IP and Port are the UDP address to listen on. Port 0 picks a free
+	// port; see Server.Addr.
+	IP   string
+	Port uint16
+	// PeerID is the ID the server identifies itself with to registering
+	// peers.
+	PeerID uint32
+	// AuthKey enables IPSC authentication when set. It is a hex string of
+	// up to 40 characters.
+	AuthKey string
+
+	// Interface, when set, has the server assign IP/SubnetMask to that
+	// network interface on Start. This needs CAP_NET_ADMIN. Leave it empty
+	// when the address is already configured.
+	Interface  string
+	SubnetMask int
+}
+
+// Peer is a repeater known to the server.
+type Peer struct {
+	ID       uint32
+	Addr     *net.UDPAddr
+	Mode     byte
+	Flags    [4]byte
+	LastSeen time.Time
+	// Provisional peers were restored from a previous run and have not
+	// been heard from since.
+	Provisional bool
+}
+
+// Server is an IPSC master that repeaters register with.
+type Server struct {
+	s *ipsc.IPSCServer
+}
+
+// NewServer creates a server from cfg. It does not listen until Start is
+// called.
+func NewServer(cfg ServerConfig) (*Server, error) {
+	if net.ParseIP(cfg.IP) == nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, config.ErrInvalidIPSCIP)
+	}
+	if len(cfg.AuthKey) > 40 {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, config.ErrInvalidIPSCAuthKey)
+	}
+	if _, err := hex.DecodeString(strings.Repeat("0", len(cfg.AuthKey)%2) + cfg.AuthKey); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, config.ErrInvalidIPSCAuthKey)
+	}
+	if cfg.Interface != "" && (cfg.SubnetMask < 1 || cfg.SubnetMask > 32) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, config.ErrInvalidIPSCSubnetMask)
+	}
+	internal := &config.Config{
+		IPSC: config.IPSC{
+			Interface:  cfg.Interface,
+			Port:       cfg.Port,
+			IP:         cfg.IP,
+			SubnetMask: cfg.SubnetMask,
+			Auth: config.IPSCAuth{
+				Enabled: cfg.AuthKey != "",
+				Key:     cfg.AuthKey,
+			},
+		},
+	}
+	s := ipsc.NewIPSCServer(internal, nil)
+	s.SetLocalID(cfg.PeerID)
+	return &Server{s: s}, nil
+}
+
+// Start configures the interface, if requested, and starts listening.
+func (s *Server) Start() error {
+	return s.s.Start()
+}
+
+// Stop closes the socket and waits for the server to shut down.
+func (s *Server) Stop() {
+	s.s.Stop()
+}
+
+// Addr returns the address the server is listening on, or nil before
+// Start.
+func (s *Server) Addr() *net.UDPAddr {
+	return s.s.Addr()
+}
+
+// Peers returns a snapshot of the known peers.
+func (s *Server) Peers() []Peer {
+	internal := s.s.Peers()
+	peers := make([]Peer, 0, len(internal))
+	for _, p := range internal {
+		peers = append(peers, Peer{
+			ID:          p.ID,
+			Addr:        p.Addr,
+			Mode:        p.Mode,
+			Flags:       p.Flags,
+			LastSeen:    p.LastSeen,
+			Provisional: p.Provisional,
+		})
+	}
+	return peers
+}
+
+// OnBurst registers fn to receive every voice or data packet a peer
+// sends. fn runs on the goroutine receiving that peer's packets, in the
+// order they arrived, so it must not block; it owns data. It must be
+// registered before Start.
+func (s *Server) OnBurst(fn func(packetType PacketType, data []byte, from *net.UDPAddr)) {
+	s.s.SetBurstHandler(func(packetType byte, data []byte, addr *net.UDPAddr) {
+		fn(PacketType(packetType), data, addr)
+	})
+}
+
+// Send transmits a raw IPSC user packet to every registered peer.
+func (s *Server) Send(data []byte) {
+	s.s.SendUserPacket(data)
+}
@@ -0,0 +1,57 @@
+// Package ipsc is the public API for the Motorola IP Site Connect (IPSC)
+// side of ipsc2mmdvm: a master that repeaters register with, and a
+// translator between IPSC user packets and MMDVM DMRD packets.
+//
+// The exported surface of this package follows semantic versioning. The
+// internal packages behind it may change at any time.
+package ipsc
+
+import (
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/ipsc"
+	"github.com/USA-RedDragon/ipsc2mmdvm/pkg/mmdvm"
+)
+
+// PacketType is the first byte of an IPSC packet.
+type PacketType = ipsc.PacketType
+
+// User packet types carried between peers.
+const (
+	PacketGroupVoice   = ipsc.PacketType_GroupVoice
+	PacketPrivateVoice = ipsc.PacketType_PrivateVoice
+	PacketGroupData    = ipsc.PacketType_GroupData
+	PacketPrivateData  = ipsc.PacketType_PrivateData
+)
+
+// Translator converts between IPSC user packets and MMDVM DMRD packets.
+// It keeps per-stream state (RTP sequence and timestamp, call control),
+// so use one Translator per direction and per destination system.
+// A Translator is safe for concurrent use.
+type Translator struct {
+	t *ipsc.IPSCTranslator
+}
+
+// NewTranslator creates a translator.
+func NewTranslator() (*Translator, error) {
+	t, err := ipsc.NewIPSCTranslator()
+	if err != nil {
+		return nil, err
+	}
+	return &Translator{t: t}, nil
+}
+
+// SetPeerID sets the peer ID stamped on IPSC packets produced by ToIPSC.
+func (t *Translator) SetPeerID(id uint32) {
+	t.t.SetPeerID(id)
+}
+
+// ToIPSC converts a DMRD packet into the IPSC packets that carry it. A
+// single DMRD packet may produce zero, one, or several IPSC packets.
+func (t *Translator) ToIPSC(pkt mmdvm.Packet) [][]byte {
+	return t.t.TranslateToIPSC(pkt)
+}
+
+// ToMMDVM converts a raw IPSC user packet into DMRD packets. It returns
+// nil if the packet cannot be translated.
+func (t *Translator) ToMMDVM(packetType PacketType, data []byte) []mmdvm.Packet {
+	return t.t.TranslateToMMDVM(byte(packetType), data)
+}
@@ -0,0 +1,295 @@
+package mmdvm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/config"
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm"
+)
+
+// ErrInvalidConfig is returned by NewClient when the configuration is
+// incomplete or out of range. The returned error wraps it together with
+// a description of the offending setting.
+var ErrInvalidConfig = errors.New("invalid MMDVM client configuration")
+
+// Why the master last turned a Client away, as wrapped by LastFailure.
+var (
+	ErrLoginRejected    = mmdvm.ErrLoginRejected
+	ErrPasswordRejected = mmdvm.ErrPasswordRejected
+	ErrConfigRejected   = mmdvm.ErrConfigRejected
+	ErrSessionRejected  = mmdvm.ErrSessionRejected
+)
+
+// ErrClosed is returned by Start after Close.
+var ErrClosed = mmdvm.ErrClosed
+
+// Config configures a Client.
+type Config struct {
+	// Name identifies the network in logs.
+	Name     string
+	Callsign string
+	// ID is the repeater ID the client logs in with. It is also stamped
+	// as the peer ID on IPSC packets the client produces.
+	ID uint32
+	// Masters lists master host:port addresses in priority order. The
+	// first entry is the primary; the rest are hot standbys.
+	Masters  []string
+	Password string
+
+	// Repeater details reported to the master during login.
+	RXFreq      uint // Hz
+	TXFreq      uint // Hz
+	TXPower     uint8
+	ColorCode   uint8
+	Latitude    float64
+	Longitude   float64
+	Height      uint16 // meters
+	Location    string
+	Description string
+	URL         string
+	// Slots is the active timeslot bitmask (1=TS1, 2=TS2, 3=both).
+	// Zero means both.
+	Slots byte
+	// Options is sent to the master once it accepts the configuration,
+	// for masters that take static talkgroups and such. Empty sends
+	// none.
+	Options string
+	// BindAddress is the local ip or ip:port to send to the master from.
+	// Empty lets the operating system pick. Start fails if it is invalid.
+	BindAddress string
+
+	// Rules decides which traffic is exchanged with the master. With no
+	// rules, nothing is forwarded in either direction.
+	Rules Rules
+}
+
+// Rules are DMRGateway-style rewrite rules. The first matching rule wins.
+type Rules struct {
+	TG   []TGRewrite
+	PC   []PCRewrite
+	Type []TypeRewrite
+	Src  []SrcRewrite
+	// PassAllTG and PassAllPC list timeslots (1 or 2) on which all group
+	// or private calls pass through unchanged.
+	PassAllTG []int
+	PassAllPC []int
+}
+
+// TGRewrite maps group calls from one slot/talkgroup range to another.
+// It applies toward the master, and in reverse from the master.
+type TGRewrite struct {
+	FromSlot, FromTG, ToSlot, ToTG, Range uint
+}
+
+// PCRewrite maps private calls toward the master by destination ID.
+type PCRewrite struct {
+	FromSlot, FromID, ToSlot, ToID, Range uint
+}
+
+// TypeRewrite converts group calls toward the master into private calls.
+type TypeRewrite struct {
+	FromSlot, FromTG, ToSlot, ToID, Range uint
+}
+
+// SrcRewrite remaps calls from the master by source ID.
+type SrcRewrite struct {
+	FromSlot, FromID, ToSlot, ToID, Range uint
+}
+
+// State is the connection state of a Client.
+type State = mmdvm.State
+
+// Connection states, in the order a login progresses through them.
+const (
+	StateIdle       = mmdvm.STATE_IDLE
+	StateSentLogin  = mmdvm.STATE_SENT_LOGIN
+	StateSentAuth   = mmdvm.STATE_SENT_AUTH
+	StateSentConfig = mmdvm.STATE_SENT_RPTC
+	StateReady      = mmdvm.STATE_READY
+	StateTimeout    = mmdvm.STATE_TIMEOUT
+	// StateAuthFailed is entered after the master rejected the login too
+	// many times in a row. The client stays there until restarted.
+	StateAuthFailed = mmdvm.STATE_AUTH_FAILED
+	// StateClosed is entered once the client is closed with Close.
+	StateClosed = mmdvm.STATE_CLOSED
+)
+
+// StateChange is a connection state change sent to subscribers, with when
+// and why it happened.
+type StateChange = mmdvm.StateChange
+
+// Stats is a point-in-time summary of a Client's connection and traffic.
+type Stats struct {
+	State           State
+	ActiveMaster    string
+	MasterSwitches  uint64
+	PacketsSent     uint64
+	PacketsReceived uint64
+	PacketsDropped  uint64
+	LastPong        time.Time
+	// RTT is the smoothed round trip time of pings to the master.
+	RTT time.Duration
+	// MissedPongs counts the pings in a row the master left unanswered.
+	MissedPongs uint32
+}
+
+// Client is a connection to one DMR master.
+type Client struct {
+	client *mmdvm.MMDVMClient
+}
+
+// NewClient creates a client from cfg. The client does not connect until
+// Start is called.
+func NewClient(cfg Config) (*Client, error) {
+	internal := cfg.internal()
+	if len(cfg.Masters) == 0 {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, config.ErrInvalidMMDVMMasterServer)
+	}
+	if err := internal.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	return &Client{client: mmdvm.NewMMDVMClient(internal, nil, mmdvm.TranslatorOptions{})}, nil
+}
+
+func (cfg Config) internal() *config.MMDVM {
+	c := &config.MMDVM{
+		Name:        cfg.Name,
+		Callsign:    cfg.Callsign,
+		ID:          cfg.ID,
+		RXFreq:      cfg.RXFreq,
+		TXFreq:      cfg.TXFreq,
+		TXPower:     cfg.TXPower,
+		ColorCode:   cfg.ColorCode,
+		Latitude:    cfg.Latitude,
+		Longitude:   cfg.Longitude,
+		Height:      cfg.Height,
+		Location:    cfg.Location,
+		Description: cfg.Description,
+		URL:         cfg.URL,
+		Slots:       cfg.Slots,
+		Options:     cfg.Options,
+		BindAddress: cfg.BindAddress,
+		Masters:     cfg.Masters,
+		Password:    cfg.Password,
+		PassAllTG:   cfg.Rules.PassAllTG,
+		PassAllPC:   cfg.Rules.PassAllPC,
+	}
+	if c.Slots == 0 {
+		c.Slots = 3
+	}
+	if len(cfg.Masters) > 0 {
+		c.MasterServer = cfg.Masters[0]
+	}
+	for _, r := range cfg.Rules.TG {
+		c.TGRewrites = append(c.TGRewrites, config.TGRewriteConfig{
+			FromSlot: r.FromSlot, FromTG: r.FromTG, ToSlot: r.ToSlot, ToTG: r.ToTG, Range: max(r.Range, 1),
+		})
+	}
+	for _, r := range cfg.Rules.PC {
+		c.PCRewrites = append(c.PCRewrites, config.PCRewriteConfig{
+			FromSlot: r.FromSlot, FromID: r.FromID, ToSlot: r.ToSlot, ToID: r.ToID, Range: max(r.Range, 1),
+		})
+	}
+	for _, r := range cfg.Rules.Type {
+		c.TypeRewrites = append(c.TypeRewrites, config.TypeRewriteConfig{
+			FromSlot: r.FromSlot, FromTG: r.FromTG, ToSlot: r.ToSlot, ToID: r.ToID, Range: max(r.Range, 1),
+		})
+	}
+	for _, r := range cfg.Rules.Src {
+		c.SrcRewrites = append(c.SrcRewrites, config.SrcRewriteConfig{
+			FromSlot: r.FromSlot, FromID: r.FromID, ToSlot: r.ToSlot, ToID: r.ToID, Range: max(r.Range, 1),
+		})
+	}
+	return c
+}
+
+// Start connects to the primary master and begins the login handshake.
+// It returns once the socket is open; use State or OnStateChange to learn
+// when the client is ready.
+func (c *Client) Start() error {
+	return c.client.Start()
+}
+
+// Stop logs out from the master and waits for the client to shut down.
+func (c *Client) Stop() {
+	c.client.Stop()
+}
+
+// Close logs out from the master for good: it sends the traffic already
+// queued until ctx is done, then tells the master the session is over.
+// The client cannot be started again.
+func (c *Client) Close(ctx context.Context) error {
+	return c.client.Close(ctx)
+}
+
+// UpdateCredentials changes the radio ID and password the client logs in
+// with; a zero id or empty password keeps the current one. A running
+// client ends its calls to the master, logs out, and logs in again with
+// them. It is safe to call from any goroutine.
+func (c *Client) UpdateCredentials(id uint32, password string) {
+	c.client.UpdateCredentials(id, password)
+}
+
+// Name returns the configured network name.
+func (c *Client) Name() string {
+	return c.client.Name()
+}
+
+// State returns the current connection state.
+func (c *Client) State() State {
+	return c.client.State()
+}
+
+// Stats returns the current connection state and traffic counters.
+func (c *Client) Stats() Stats {
+	s := c.client.Stats()
+	return Stats{
+		State:           s.State,
+		ActiveMaster:    s.ActiveMaster,
+		MasterSwitches:  s.MasterSwitches,
+		PacketsSent:     s.PacketsSent,
+		PacketsReceived: s.PacketsReceived,
+		PacketsDropped:  s.PacketsDropped,
+		LastPong:        s.LastPong,
+		RTT:             s.RTT,
+		MissedPongs:     s.MissedPongs,
+	}
+}
+
+// LastFailure returns why the master last rejected the client, wrapping
+// one of ErrLoginRejected, ErrPasswordRejected, ErrConfigRejected, or
+// ErrSessionRejected, or nil if it never has.
+func (c *Client) LastFailure() error {
+	return c.client.LastFailure()
+}
+
+// OnStateChange registers fn to be called on every connection state
+// change. fn runs on the client's goroutines and must not block. It must
+// be registered before Start.
+func (c *Client) OnStateChange(fn func(from, to State)) {
+	c.client.SetStateHandler(fn)
+}
+
+// Subscribe returns a channel receiving every later connection state
+// change. A subscriber that falls behind loses the oldest changes instead
+// of blocking the client. The channel is never closed.
+func (c *Client) Subscribe() <-chan StateChange {
+	return c.client.Subscribe()
+}
+
+// OnIPSC registers fn to receive traffic from the master, already
+// translated into raw IPSC user packets. It must be registered before
+// Start.
+func (c *Client) OnIPSC(fn func(data []byte)) {
+	c.client.SetIPSCHandler(fn)
+}
+
+// HandleIPSCBurst translates a raw IPSC user packet and, if it matches
+// the client's rules, sends it to the master. It reports whether any
+// part of the burst was forwarded.
+func (c *Client) HandleIPSCBurst(packetType byte, data []byte) bool {
+	return c.client.HandleIPSCBurst(packetType, data, nil)
+}
@@ -0,0 +1,53 @@
+package mmdvm_test
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/USA-RedDragon/ipsc2mmdvm/pkg/mmdvm"
+)
+
+func ExampleDecode() {
+	pkt := mmdvm.Packet{
+		Signature: "DMRD",
+		Src:       3120001,
+		Dst:       91,
+		Slot:      true,
+		GroupCall: true,
+		StreamID:  0x1001,
+	}
+
+	decoded, ok := mmdvm.Decode(pkt.Encode())
+	fmt.Println(ok, decoded.Src, decoded.Dst, decoded.GroupCall, decoded.StreamID)
+	// Output: true 3120001 91 true 4097
+}
+
+func ExampleNewClient_invalid() {
+	_, err := mmdvm.NewClient(mmdvm.Config{Name: "BrandMeister"})
+	fmt.Println(errors.Is(err, mmdvm.ErrInvalidConfig))
+	// Output: true
+}
+
+func ExampleClient_OnStateChange() {
+	client, err := mmdvm.NewClient(mmdvm.Config{
+		Name:     "BrandMeister",
+		Callsign: "N0CALL",
+		ID:       311860,
+		Masters:  []string{"3104.master.brandmeister.network:62031", "3102.master.brandmeister.network:62031"},
+		Password: "passw0rd",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client.OnStateChange(func(from, to mmdvm.State) {
+		log.Printf("%s: %s -> %s", client.Name(), from, to)
+		if to == mmdvm.StateReady {
+			log.Printf("logged in to %s", client.Stats().ActiveMaster)
+		}
+	})
+
+	fmt.Println(client.State())
+	// Output: idle
+}
@@ -0,0 +1,22 @@
+// Package mmdvm is the public API for connecting to DMR masters that speak
+// the MMDVM homebrew repeater protocol (HBRP). It wraps the client used by
+// ipsc2mmdvm so other programs can log in to a master, exchange DMRD
+// packets with it, and observe the connection.
+//
+// The exported surface of this package follows semantic versioning. The
+// internal packages behind it may change at any time.
+package mmdvm
+
+import (
+	"github.com/USA-RedDragon/ipsc2mmdvm/internal/mmdvm/proto"
+)
+
+// Packet is a decoded MMDVM DMRD packet. It is the common representation
+// that IPSC bursts are translated to and from.
+type Packet = proto.Packet
+
+// Decode parses a raw DMRD packet. It returns false if data is not a
+// DMRD packet of valid length.
+func Decode(data []byte) (Packet, bool) {
+	return proto.Decode(data)
+}